@@ -12,6 +12,8 @@ import (
 // WhatsAppClient es la interfaz para enviar mensajes
 type WhatsAppClient interface {
 	SendMessage(to string, text string) error
+	SendImage(to string, imageURL string, caption string) error
+	SendDocument(to string, documentURL string, filename string, caption string) error
 }
 
 // NewClientFromEnv crea el cliente según WHATSAPP_PROVIDER
@@ -44,6 +46,11 @@ func (m *MockClient) SendImage(to string, imageURL string, caption string) error
 	return nil
 }
 
+func (m *MockClient) SendDocument(to string, documentURL string, filename string, caption string) error {
+	log.Printf("[MOCK] Enviando documento a %s: %s como %s (caption: %s)\n", to, documentURL, filename, caption)
+	return nil
+}
+
 // ----------------- Node.js Script Client -----------------
 
 type NodeScriptClient struct {
@@ -76,3 +83,17 @@ func (n *NodeScriptClient) SendImage(to string, imageURL string, caption string)
 	log.Printf("Script de Node.js para imagen ejecutado exitosamente para %s. Output: %s\n", to, string(output))
 	return nil
 }
+
+func (n *NodeScriptClient) SendDocument(to string, documentURL string, filename string, caption string) error {
+	if n.ScriptPath == "" {
+		return errors.New("ruta del script de Node.js no configurada")
+	}
+	// El script distingue el documento de la imagen por el cuarto argumento.
+	cmd := exec.Command("node", n.ScriptPath, to, documentURL, caption, filename)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("error ejecutando el script de Node.js para enviar documento: %v\nOutput: %s", err, string(output))
+	}
+	log.Printf("Script de Node.js para documento ejecutado exitosamente para %s. Output: %s\n", to, string(output))
+	return nil
+}
@@ -0,0 +1,608 @@
+// Package admin expone la API del personal para revisar apelaciones de strikes,
+// desbloquear clientes y consultar su historial. Cada acción queda en la
+// bitácora de auditoría del cliente.
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"example.com/whatsapp-integration/calificaciones"
+	"example.com/whatsapp-integration/liquidacion"
+	"example.com/whatsapp-integration/payments"
+	"example.com/whatsapp-integration/promociones"
+	"example.com/whatsapp-integration/sellos"
+	"example.com/whatsapp-integration/store"
+)
+
+// Clientes aplica las decisiones del personal. Lo implementa bot.StateMachine,
+// que además avisa al cliente por WhatsApp.
+type Clientes interface {
+	ResolverApelacion(ctx context.Context, apelacionID int, aceptar bool, resolucion, operador string) (*store.Apelacion, error)
+	Desbloquear(ctx context.Context, telefono, operador, motivo string, anularStrikes bool) (*store.Cliente, error)
+}
+
+// API agrupa los endpoints de administración.
+type API struct {
+	store    store.Store
+	clientes Clientes
+	pagos    *payments.Service // opcional: verificación de transferencias
+
+	liquidaciones *liquidacion.Servicio // opcional: corte de caja por ruta
+	sellos        *sellos.Servicio      // opcional: casos de sello violado
+
+	calificaciones *calificaciones.Servicio // opcional: reporte de calificaciones
+}
+
+func NewAPI(s store.Store, clientes Clientes) *API {
+	return &API{store: s, clientes: clientes}
+}
+
+// SetPagos habilita la revisión de comprobantes y la conciliación bancaria.
+func (a *API) SetPagos(p *payments.Service) {
+	a.pagos = p
+}
+
+// SetLiquidaciones habilita el corte de caja de los repartidores.
+func (a *API) SetLiquidaciones(l *liquidacion.Servicio) {
+	a.liquidaciones = l
+}
+
+// SetSellos habilita la atención de los reportes de sello violado.
+func (a *API) SetSellos(s *sellos.Servicio) {
+	a.sellos = s
+}
+
+// SetCalificaciones habilita el reporte semanal de calificaciones.
+func (a *API) SetCalificaciones(c *calificaciones.Servicio) {
+	a.calificaciones = c
+}
+
+// Handler regresa las rutas bajo /admin/, protegidas con
+// "Authorization: Bearer <token>":
+//
+//	GET  /admin/apelaciones?estado=abierta
+//	POST /admin/apelaciones/resolver   {"apelacion_id", "aceptar", "resolucion", "operador"}
+//	POST /admin/clientes/desbloquear   {"telefono", "motivo", "operador", "anular_strikes"}
+//	GET  /admin/clientes/historial?telefono=...
+//	GET  /admin/promociones
+//	POST /admin/promociones            campos de store.Promocion; con "ID" actualiza
+//	GET  /admin/comprobantes?estado=pendiente
+//	GET  /admin/comprobantes/archivo?id=...
+//	POST /admin/comprobantes/revisar   {"comprobante_id", "aprobar", "nota", "operador"}
+//	POST /admin/pagos/estado-cuenta    CSV del banco; concilia transferencias
+//	GET  /admin/liquidaciones?fecha=2006-01-02&formato=csv
+//	POST /admin/liquidaciones/registrar {"ruta_id", "efectivo_recibido", "cajero", "nota"}
+//	GET  /admin/sellos?estado=pendiente&supervisor=...&vencidos=1
+//	GET  /admin/sellos/caso?id=...
+//	GET  /admin/sellos/foto?id=...
+//	POST /admin/sellos/asignar         {"reporte_id", "supervisor", "tipo_reporte"}
+//	POST /admin/sellos/resolver        {"reporte_id", "procede", "resolucion", "supervisor"}
+//	GET  /admin/calificaciones?desde=2006-01-02&hasta=2006-01-02&formato=csv
+func (a *API) Handler(token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/admin/apelaciones", a.handleApelaciones)
+	mux.HandleFunc("/admin/apelaciones/resolver", a.handleResolver)
+	mux.HandleFunc("/admin/clientes/desbloquear", a.handleDesbloquear)
+	mux.HandleFunc("/admin/clientes/historial", a.handleHistorial)
+	mux.HandleFunc("/admin/promociones", a.handlePromociones)
+	mux.HandleFunc("/admin/comprobantes", a.handleComprobantes)
+	mux.HandleFunc("/admin/comprobantes/archivo", a.handleArchivoComprobante)
+	mux.HandleFunc("/admin/comprobantes/revisar", a.handleRevisarComprobante)
+	mux.HandleFunc("/admin/pagos/estado-cuenta", a.handleEstadoCuenta)
+	mux.HandleFunc("/admin/liquidaciones", a.handleLiquidaciones)
+	mux.HandleFunc("/admin/liquidaciones/registrar", a.handleRegistrarLiquidacion)
+	mux.HandleFunc("/admin/sellos", a.handleSellos)
+	mux.HandleFunc("/admin/sellos/caso", a.handleCasoSello)
+	mux.HandleFunc("/admin/sellos/foto", a.handleFotoSello)
+	mux.HandleFunc("/admin/sellos/asignar", a.handleAsignarSello)
+	mux.HandleFunc("/admin/sellos/resolver", a.handleResolverSello)
+	mux.HandleFunc("/admin/calificaciones", a.handleCalificaciones)
+	return conToken(token, mux)
+}
+
+func (a *API) handleApelaciones(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+		return
+	}
+	apelaciones, err := a.store.GetApelaciones(r.Context(), r.URL.Query().Get("estado"))
+	if err != nil {
+		log.Printf("Error consultando apelaciones: %v\n", err)
+		http.Error(w, "Error interno", http.StatusInternalServerError)
+		return
+	}
+	responderJSON(w, map[string]interface{}{"apelaciones": apelaciones})
+}
+
+func (a *API) handleResolver(w http.ResponseWriter, r *http.Request) {
+	var p struct {
+		ApelacionID int    `json:"apelacion_id"`
+		Aceptar     bool   `json:"aceptar"`
+		Resolucion  string `json:"resolucion"`
+		Operador    string `json:"operador"`
+	}
+	if !leerPeticion(w, r, &p) {
+		return
+	}
+	if p.ApelacionID == 0 || p.Operador == "" {
+		http.Error(w, "apelacion_id y operador son obligatorios", http.StatusBadRequest)
+		return
+	}
+
+	apelacion, err := a.clientes.ResolverApelacion(r.Context(), p.ApelacionID, p.Aceptar, p.Resolucion, p.Operador)
+	if err != nil {
+		log.Printf("Error resolviendo apelación %d: %v\n", p.ApelacionID, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	responderJSON(w, apelacion)
+}
+
+func (a *API) handleDesbloquear(w http.ResponseWriter, r *http.Request) {
+	var p struct {
+		Telefono      string `json:"telefono"`
+		Motivo        string `json:"motivo"`
+		Operador      string `json:"operador"`
+		AnularStrikes bool   `json:"anular_strikes"`
+	}
+	if !leerPeticion(w, r, &p) {
+		return
+	}
+	if p.Telefono == "" || p.Operador == "" || p.Motivo == "" {
+		http.Error(w, "telefono, motivo y operador son obligatorios", http.StatusBadRequest)
+		return
+	}
+
+	cliente, err := a.clientes.Desbloquear(r.Context(), p.Telefono, p.Operador, p.Motivo, p.AnularStrikes)
+	if err != nil {
+		log.Printf("Error desbloqueando cliente %s: %v\n", p.Telefono, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	responderJSON(w, map[string]interface{}{
+		"telefono":  cliente.NumeroTelefono,
+		"bloqueado": cliente.Bloqueado,
+		"strikes":   cliente.Strikes,
+	})
+}
+
+func (a *API) handleHistorial(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+		return
+	}
+	ctx := r.Context()
+	cliente, err := a.store.GetClientePorTelefono(ctx, r.URL.Query().Get("telefono"))
+	if err != nil {
+		log.Printf("Error consultando cliente: %v\n", err)
+		http.Error(w, "Error interno", http.StatusInternalServerError)
+		return
+	}
+	if cliente == nil {
+		http.Error(w, "Cliente no encontrado", http.StatusNotFound)
+		return
+	}
+
+	strikes, err := a.store.GetStrikesCliente(ctx, cliente.ID)
+	if err != nil {
+		log.Printf("Error consultando strikes del cliente %d: %v\n", cliente.ID, err)
+		http.Error(w, "Error interno", http.StatusInternalServerError)
+		return
+	}
+	auditoria, err := a.store.GetAuditoriaCliente(ctx, cliente.ID)
+	if err != nil {
+		log.Printf("Error consultando auditoría del cliente %d: %v\n", cliente.ID, err)
+		http.Error(w, "Error interno", http.StatusInternalServerError)
+		return
+	}
+	responderJSON(w, map[string]interface{}{
+		"telefono":  cliente.NumeroTelefono,
+		"bloqueado": cliente.Bloqueado,
+		"strikes":   strikes,
+		"auditoria": auditoria,
+	})
+}
+
+// handlePromociones lista los códigos (GET) o da de alta y actualiza uno (POST).
+func (a *API) handlePromociones(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		promos, err := a.store.GetPromociones(r.Context())
+		if err != nil {
+			log.Printf("Error consultando promociones: %v\n", err)
+			http.Error(w, "Error interno", http.StatusInternalServerError)
+			return
+		}
+		responderJSON(w, map[string]interface{}{"promociones": promos})
+		return
+	}
+
+	var promo store.Promocion
+	if !leerPeticion(w, r, &promo) {
+		return
+	}
+	promo.Codigo = strings.ToUpper(strings.TrimSpace(promo.Codigo))
+	if err := promociones.Validar(&promo); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var err error
+	if promo.ID == 0 {
+		err = a.store.CrearPromocion(r.Context(), &promo)
+	} else {
+		err = a.store.ActualizarPromocion(r.Context(), &promo)
+	}
+	if err != nil {
+		log.Printf("Error guardando promoción %s: %v\n", promo.Codigo, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	responderJSON(w, promo)
+}
+
+func (a *API) handleComprobantes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+		return
+	}
+	comprobantes, err := a.store.GetComprobantesPago(r.Context(), r.URL.Query().Get("estado"))
+	if err != nil {
+		log.Printf("Error consultando comprobantes: %v\n", err)
+		http.Error(w, "Error interno", http.StatusInternalServerError)
+		return
+	}
+	responderJSON(w, map[string]interface{}{"comprobantes": comprobantes})
+}
+
+// handleArchivoComprobante muestra la foto o PDF para verificarlo.
+func (a *API) handleArchivoComprobante(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+		return
+	}
+	id, _ := strconv.Atoi(r.URL.Query().Get("id"))
+	comprobante, err := a.store.GetComprobantePago(r.Context(), id)
+	if err != nil {
+		log.Printf("Error consultando comprobante %d: %v\n", id, err)
+		http.Error(w, "Error interno", http.StatusInternalServerError)
+		return
+	}
+	if comprobante == nil {
+		http.Error(w, "Comprobante no encontrado", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", comprobante.MimeType)
+	http.ServeFile(w, r, comprobante.Archivo)
+}
+
+func (a *API) handleRevisarComprobante(w http.ResponseWriter, r *http.Request) {
+	if a.pagos == nil {
+		http.Error(w, "Pagos no configurados", http.StatusNotFound)
+		return
+	}
+	var p struct {
+		ComprobanteID int    `json:"comprobante_id"`
+		Aprobar       bool   `json:"aprobar"`
+		Nota          string `json:"nota"`
+		Operador      string `json:"operador"`
+	}
+	if !leerPeticion(w, r, &p) {
+		return
+	}
+	if p.ComprobanteID == 0 || p.Operador == "" {
+		http.Error(w, "comprobante_id y operador son obligatorios", http.StatusBadRequest)
+		return
+	}
+
+	comprobante, err := a.pagos.ReviewReceipt(r.Context(), p.ComprobanteID, p.Aprobar, p.Operador, p.Nota, time.Now())
+	if err != nil {
+		log.Printf("Error revisando comprobante %d: %v\n", p.ComprobanteID, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	responderJSON(w, comprobante)
+}
+
+// handleEstadoCuenta recibe el CSV del banco en el cuerpo y regresa qué
+// depósitos se conciliaron y cuáles quedan para revisión manual.
+func (a *API) handleEstadoCuenta(w http.ResponseWriter, r *http.Request) {
+	if a.pagos == nil {
+		http.Error(w, "Pagos no configurados", http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+		return
+	}
+	resultado, err := a.pagos.ImportStatement(r.Context(), http.MaxBytesReader(w, r.Body, 10<<20), time.Now())
+	if err != nil {
+		log.Printf("Error importando estado de cuenta: %v\n", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	responderJSON(w, resultado)
+}
+
+// handleLiquidaciones regresa el corte del día por repartidor; con
+// formato=csv lo regresa como hoja de cálculo.
+func (a *API) handleLiquidaciones(w http.ResponseWriter, r *http.Request) {
+	if a.liquidaciones == nil {
+		http.Error(w, "Liquidaciones no configuradas", http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+		return
+	}
+	fecha := a.liquidaciones.Hoy()
+	if v := r.URL.Query().Get("fecha"); v != "" {
+		var err error
+		if fecha, err = time.Parse("2006-01-02", v); err != nil {
+			http.Error(w, "fecha debe tener el formato AAAA-MM-DD", http.StatusBadRequest)
+			return
+		}
+	}
+
+	reportes, err := a.liquidaciones.Reporte(r.Context(), fecha)
+	if err != nil {
+		log.Printf("Error calculando liquidaciones del %s: %v\n", fecha.Format("2006-01-02"), err)
+		http.Error(w, "Error interno", http.StatusInternalServerError)
+		return
+	}
+	if r.URL.Query().Get("formato") == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="liquidaciones-%s.csv"`, fecha.Format("2006-01-02")))
+		if err := liquidacion.EscribirCSV(w, reportes); err != nil {
+			log.Printf("Error escribiendo CSV de liquidaciones: %v\n", err)
+		}
+		return
+	}
+	responderJSON(w, map[string]interface{}{"fecha": fecha.Format("2006-01-02"), "repartidores": reportes})
+}
+
+// handleRegistrarLiquidacion guarda el efectivo que recibió el cajero y
+// regresa el corte con la diferencia.
+func (a *API) handleRegistrarLiquidacion(w http.ResponseWriter, r *http.Request) {
+	if a.liquidaciones == nil {
+		http.Error(w, "Liquidaciones no configuradas", http.StatusNotFound)
+		return
+	}
+	var p struct {
+		RutaID           int      `json:"ruta_id"`
+		EfectivoRecibido *float64 `json:"efectivo_recibido"`
+		Cajero           string   `json:"cajero"`
+		Nota             string   `json:"nota"`
+	}
+	if !leerPeticion(w, r, &p) {
+		return
+	}
+	if p.RutaID == 0 || p.EfectivoRecibido == nil || *p.EfectivoRecibido < 0 || p.Cajero == "" {
+		http.Error(w, "ruta_id, efectivo_recibido y cajero son obligatorios", http.StatusBadRequest)
+		return
+	}
+
+	corte, err := a.liquidaciones.Liquidar(r.Context(), p.RutaID, *p.EfectivoRecibido, p.Cajero, p.Nota)
+	switch {
+	case errors.Is(err, liquidacion.ErrRutaNoEncontrada):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, liquidacion.ErrYaLiquidada):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case err != nil:
+		log.Printf("Error liquidando ruta %d: %v\n", p.RutaID, err)
+		http.Error(w, "Error interno", http.StatusInternalServerError)
+	default:
+		responderJSON(w, corte)
+	}
+}
+
+// handleCalificaciones regresa el reporte por repartidor y semana. Sin
+// fechas cubre las últimas cuatro semanas.
+func (a *API) handleCalificaciones(w http.ResponseWriter, r *http.Request) {
+	if a.calificaciones == nil {
+		http.Error(w, "Calificaciones no configuradas", http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+		return
+	}
+	hasta := time.Now()
+	desde := a.calificaciones.InicioSemana(hasta).AddDate(0, 0, -21)
+	for _, f := range []struct {
+		nombre string
+		fecha  *time.Time
+	}{{"desde", &desde}, {"hasta", &hasta}} {
+		if v := r.URL.Query().Get(f.nombre); v != "" {
+			t, err := time.Parse("2006-01-02", v)
+			if err != nil {
+				http.Error(w, f.nombre+" debe tener el formato AAAA-MM-DD", http.StatusBadRequest)
+				return
+			}
+			*f.fecha = t
+		}
+	}
+
+	reportes, err := a.calificaciones.Reporte(r.Context(), desde, hasta)
+	if err != nil {
+		log.Printf("Error calculando reporte de calificaciones: %v\n", err)
+		http.Error(w, "Error interno", http.StatusInternalServerError)
+		return
+	}
+	if r.URL.Query().Get("formato") == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="calificaciones-%s-%s.csv"`, desde.Format("2006-01-02"), hasta.Format("2006-01-02")))
+		if err := calificaciones.EscribirCSV(w, reportes); err != nil {
+			log.Printf("Error escribiendo CSV de calificaciones: %v\n", err)
+		}
+		return
+	}
+	responderJSON(w, map[string]interface{}{
+		"desde":   desde.Format("2006-01-02"),
+		"hasta":   hasta.Format("2006-01-02"),
+		"semanas": reportes,
+	})
+}
+
+// handleSellos lista los casos; con vencidos=1 solo los que pasaron su SLA.
+func (a *API) handleSellos(w http.ResponseWriter, r *http.Request) {
+	if a.sellos == nil {
+		http.Error(w, "Reportes de sello no configurados", http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	casos, err := a.sellos.Listar(r.Context(), q.Get("estado"), q.Get("supervisor"), q.Get("vencidos") == "1", time.Now())
+	if err != nil {
+		log.Printf("Error consultando reportes de sello: %v\n", err)
+		http.Error(w, "Error interno", http.StatusInternalServerError)
+		return
+	}
+	responderJSON(w, map[string]interface{}{"reportes": casos})
+}
+
+func (a *API) handleCasoSello(w http.ResponseWriter, r *http.Request) {
+	if a.sellos == nil {
+		http.Error(w, "Reportes de sello no configurados", http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+		return
+	}
+	id, _ := strconv.Atoi(r.URL.Query().Get("id"))
+	caso, err := a.sellos.Caso(r.Context(), id, time.Now())
+	if err != nil {
+		log.Printf("Error consultando reporte de sello %d: %v\n", id, err)
+		http.Error(w, "Error interno", http.StatusInternalServerError)
+		return
+	}
+	if caso == nil {
+		http.Error(w, "Reporte no encontrado", http.StatusNotFound)
+		return
+	}
+	responderJSON(w, caso)
+}
+
+// handleFotoSello muestra una foto adjunta a un reporte.
+func (a *API) handleFotoSello(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+		return
+	}
+	id, _ := strconv.Atoi(r.URL.Query().Get("id"))
+	foto, err := a.store.GetFotoReporteSello(r.Context(), id)
+	if err != nil {
+		log.Printf("Error consultando foto %d: %v\n", id, err)
+		http.Error(w, "Error interno", http.StatusInternalServerError)
+		return
+	}
+	if foto == nil {
+		http.Error(w, "Foto no encontrada", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", foto.MimeType)
+	http.ServeFile(w, r, foto.Archivo)
+}
+
+func (a *API) handleAsignarSello(w http.ResponseWriter, r *http.Request) {
+	if a.sellos == nil {
+		http.Error(w, "Reportes de sello no configurados", http.StatusNotFound)
+		return
+	}
+	var p struct {
+		ReporteID   int    `json:"reporte_id"`
+		Supervisor  string `json:"supervisor"`
+		TipoReporte string `json:"tipo_reporte"`
+	}
+	if !leerPeticion(w, r, &p) {
+		return
+	}
+	if p.ReporteID == 0 || p.Supervisor == "" {
+		http.Error(w, "reporte_id y supervisor son obligatorios", http.StatusBadRequest)
+		return
+	}
+
+	reporte, err := a.sellos.Asignar(r.Context(), p.ReporteID, p.Supervisor, p.TipoReporte, time.Now())
+	responderCasoSello(w, p.ReporteID, reporte, err)
+}
+
+// handleResolverSello cierra el caso y le manda la resolución al cliente.
+func (a *API) handleResolverSello(w http.ResponseWriter, r *http.Request) {
+	if a.sellos == nil {
+		http.Error(w, "Reportes de sello no configurados", http.StatusNotFound)
+		return
+	}
+	var p struct {
+		ReporteID  int    `json:"reporte_id"`
+		Procede    bool   `json:"procede"`
+		Resolucion string `json:"resolucion"`
+		Supervisor string `json:"supervisor"`
+	}
+	if !leerPeticion(w, r, &p) {
+		return
+	}
+	if p.ReporteID == 0 || p.Supervisor == "" || strings.TrimSpace(p.Resolucion) == "" {
+		http.Error(w, "reporte_id, resolucion y supervisor son obligatorios", http.StatusBadRequest)
+		return
+	}
+
+	reporte, err := a.sellos.Resolver(r.Context(), p.ReporteID, p.Procede, strings.TrimSpace(p.Resolucion), p.Supervisor, time.Now())
+	responderCasoSello(w, p.ReporteID, reporte, err)
+}
+
+func responderCasoSello(w http.ResponseWriter, id int, reporte *store.ReporteSello, err error) {
+	switch {
+	case errors.Is(err, sellos.ErrReporteNoEncontrado):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, sellos.ErrReporteCerrado):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, sellos.ErrTipoReporte):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case err != nil:
+		log.Printf("Error actualizando reporte de sello %d: %v\n", id, err)
+		http.Error(w, "Error interno", http.StatusInternalServerError)
+	default:
+		responderJSON(w, reporte)
+	}
+}
+
+// leerPeticion exige POST con cuerpo JSON; si falla ya respondió el error.
+func leerPeticion(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+		return false
+	}
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		http.Error(w, "Petición inválida", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func conToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recibido := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token == "" || subtle.ConstantTimeCompare([]byte(recibido), []byte(token)) != 1 {
+			http.Error(w, "No autorizado", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func responderJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,261 @@
+// Package alertas avisa al repartidor asignado y al punto de venta de los
+// pedidos que no deben cobrarse o entregarse: sellos violados y
+// cancelaciones. Cada alerta sale por WhatsApp al repartidor, por un webhook
+// firmado al punto de venta y por eventos al tablero de despacho, y escala a
+// los supervisores si nadie la reconoce a tiempo.
+package alertas
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"example.com/whatsapp-integration/store"
+)
+
+// Tipos de alerta.
+const (
+	TipoSelloViolado    = "sello_violado"
+	TipoPedidoCancelado = "pedido_cancelado"
+)
+
+// Estados de una alerta.
+const (
+	EstadoPendiente  = "pendiente"
+	EstadoEscalada   = "escalada" // nadie la reconoció a tiempo; sigue abierta
+	EstadoReconocida = "reconocida"
+)
+
+// EscalarPorDefecto es cuánto se espera el reconocimiento antes de escalar
+// si no se configura ALERTAS_ESCALAR_MINUTOS.
+const EscalarPorDefecto = 5 * time.Minute
+
+var ErrAlertaNoEncontrada = errors.New("alerta no encontrada")
+
+// Config son los destinos y plazos de las alertas.
+type Config struct {
+	WebhookURL     string // punto de venta; vacío lo deshabilita
+	WebhookSecreto string
+	Escalar        time.Duration
+	Supervisores   []string // teléfonos que reciben las alertas escaladas
+}
+
+// ConfigDesdeEnv lee ALERTAS_WEBHOOK_URL, ALERTAS_WEBHOOK_SECRETO,
+// ALERTAS_ESCALAR_MINUTOS (por defecto 5) y ALERTAS_SUPERVISORES (teléfonos
+// separados por comas).
+func ConfigDesdeEnv() Config {
+	cfg := Config{
+		WebhookURL:     os.Getenv("ALERTAS_WEBHOOK_URL"),
+		WebhookSecreto: os.Getenv("ALERTAS_WEBHOOK_SECRETO"),
+		Escalar:        EscalarPorDefecto,
+	}
+	if v, err := strconv.Atoi(os.Getenv("ALERTAS_ESCALAR_MINUTOS")); err == nil && v > 0 {
+		cfg.Escalar = time.Duration(v) * time.Minute
+	}
+	for _, t := range strings.Split(os.Getenv("ALERTAS_SUPERVISORES"), ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			cfg.Supervisores = append(cfg.Supervisores, t)
+		}
+	}
+	return cfg
+}
+
+// Notificador envía mensajes de WhatsApp.
+type Notificador interface {
+	SendMessage(to, message string) error
+}
+
+// Servicio emite, reconoce y escala las alertas.
+type Servicio struct {
+	store   store.Store
+	cfg     Config
+	loc     *time.Location
+	sender  Notificador
+	client  *http.Client // webhook del punto de venta
+	tablero *tablero
+}
+
+func NewServicio(s store.Store, cfg Config, loc *time.Location) *Servicio {
+	if loc == nil {
+		loc = time.Local
+	}
+	if cfg.Escalar <= 0 {
+		cfg.Escalar = EscalarPorDefecto
+	}
+	return &Servicio{
+		store:   s,
+		cfg:     cfg,
+		loc:     loc,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		tablero: newTablero(),
+	}
+}
+
+// SetSender habilita los avisos por WhatsApp al repartidor y a los supervisores.
+func (s *Servicio) SetSender(n Notificador) {
+	s.sender = n
+}
+
+// Emitir registra la alerta del pedido y la manda por todos los canales. El
+// repartidor es el del camión de la ruta del pedido; si el pedido aún no
+// está en ruta, solo se avisa al punto de venta y al tablero.
+func (s *Servicio) Emitir(ctx context.Context, tipo string, pedido *store.Pedido, referenciaID int, detalle string, ahora time.Time) (*store.Alerta, error) {
+	repartidor, err := s.repartidorDelPedido(ctx, pedido.ID)
+	if err != nil {
+		return nil, err
+	}
+	alerta := &store.Alerta{
+		Tipo:         tipo,
+		PedidoID:     pedido.ID,
+		ReferenciaID: referenciaID,
+		Mensaje:      s.mensaje(ctx, tipo, pedido, detalle),
+		Estado:       EstadoPendiente,
+		CreatedAt:    ahora,
+	}
+	if repartidor != nil {
+		alerta.RepartidorID = repartidor.ID
+	}
+	if err := s.store.CrearAlerta(ctx, alerta); err != nil {
+		return nil, err
+	}
+	log.Printf("Alerta %d (%s) del pedido %d para el repartidor %d\n", alerta.ID, tipo, pedido.ID, alerta.RepartidorID)
+
+	s.tablero.publicar(eventoDe(EventoAlerta, alerta))
+	s.avisarRepartidor(alerta, repartidor, ahora)
+	s.entregarPOS(ctx, alerta, EventoAlerta, ahora)
+	if err := s.store.ActualizarAlerta(ctx, alerta); err != nil {
+		return alerta, err
+	}
+	return alerta, nil
+}
+
+func (s *Servicio) mensaje(ctx context.Context, tipo string, pedido *store.Pedido, detalle string) string {
+	destino := pedido.Direccion
+	if cliente, err := s.store.GetClientePorID(ctx, pedido.ClienteID); err == nil && cliente != nil {
+		destino = strings.TrimSpace(cliente.Nombre + ", " + pedido.Direccion)
+	}
+
+	var msg string
+	switch tipo {
+	case TipoSelloViolado:
+		msg = fmt.Sprintf("🚨 *SELLO VIOLADO - Pedido #%d*\n%s\n\nEl cliente reportó el sello violado. *NO REALICES EL COBRO.*", pedido.ID, destino)
+	case TipoPedidoCancelado:
+		msg = fmt.Sprintf("🚫 *PEDIDO CANCELADO - Pedido #%d*\n%s\n\n*No lo entregues.*", pedido.ID, destino)
+	default:
+		msg = fmt.Sprintf("⚠️ *Alerta del pedido #%d*\n%s", pedido.ID, destino)
+	}
+	if detalle != "" {
+		msg += "\n" + detalle
+	}
+	return msg
+}
+
+// repartidorDelPedido es el repartidor activo del camión de la ruta que
+// lleva el pedido, o nil si el pedido no está en ruta.
+func (s *Servicio) repartidorDelPedido(ctx context.Context, pedidoID int) (*store.Repartidor, error) {
+	parada, err := s.store.GetParadaPorPedido(ctx, pedidoID)
+	if err != nil || parada == nil {
+		return nil, err
+	}
+	ruta, err := s.store.GetRuta(ctx, parada.RutaID)
+	if err != nil || ruta == nil {
+		return nil, err
+	}
+	repartidores, err := s.store.GetRepartidores(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range repartidores {
+		if r.Activo && r.CamionID == ruta.CamionID {
+			return r, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *Servicio) avisarRepartidor(alerta *store.Alerta, repartidor *store.Repartidor, ahora time.Time) {
+	if s.sender == nil || repartidor == nil || repartidor.Telefono == "" {
+		return
+	}
+	msg := fmt.Sprintf("%s\n\nResponde *ENTERADO* para confirmar que la recibiste.", alerta.Mensaje)
+	if err := s.sender.SendMessage(repartidor.Telefono, msg); err != nil {
+		log.Printf("Error enviando alerta %d al repartidor %d: %v\n", alerta.ID, repartidor.ID, err)
+		return
+	}
+	alerta.EnviadaRepartidor = &ahora
+}
+
+// Reconocer cierra la alerta. quien es el repartidor o el despachador.
+func (s *Servicio) Reconocer(ctx context.Context, id int, quien string, ahora time.Time) (*store.Alerta, error) {
+	alerta, err := s.store.GetAlerta(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if alerta == nil {
+		return nil, ErrAlertaNoEncontrada
+	}
+	if alerta.Estado == EstadoReconocida {
+		return alerta, nil
+	}
+	if err := s.reconocer(ctx, alerta, quien, ahora); err != nil {
+		return nil, err
+	}
+	return alerta, nil
+}
+
+// ReconocerRepartidor cierra las alertas abiertas del repartidor con ese
+// teléfono. Regresa nil si el teléfono no es de un repartidor.
+func (s *Servicio) ReconocerRepartidor(ctx context.Context, telefono string, ahora time.Time) (*store.Repartidor, int, error) {
+	repartidor, err := s.store.GetRepartidorPorTelefono(ctx, telefono)
+	if err != nil || repartidor == nil {
+		return nil, 0, err
+	}
+	abiertas, err := s.Abiertas(ctx)
+	if err != nil {
+		return repartidor, 0, err
+	}
+	n := 0
+	for _, a := range abiertas {
+		if a.RepartidorID != repartidor.ID {
+			continue
+		}
+		if err := s.reconocer(ctx, a, repartidor.Nombre, ahora); err != nil {
+			return repartidor, n, err
+		}
+		n++
+	}
+	return repartidor, n, nil
+}
+
+func (s *Servicio) reconocer(ctx context.Context, alerta *store.Alerta, quien string, ahora time.Time) error {
+	alerta.Estado = EstadoReconocida
+	alerta.ReconocidaPor = quien
+	alerta.ReconocidaEn = &ahora
+	if err := s.store.ActualizarAlerta(ctx, alerta); err != nil {
+		return err
+	}
+	s.tablero.publicar(eventoDe(EventoReconocida, alerta))
+	s.entregarPOS(ctx, alerta, EventoReconocida, ahora)
+	return nil
+}
+
+// Abiertas regresa las alertas que nadie ha reconocido.
+func (s *Servicio) Abiertas(ctx context.Context) ([]*store.Alerta, error) {
+	var abiertas []*store.Alerta
+	for _, estado := range []string{EstadoPendiente, EstadoEscalada} {
+		alertas, err := s.store.GetAlertasPorEstado(ctx, estado)
+		if err != nil {
+			return nil, err
+		}
+		abiertas = append(abiertas, alertas...)
+	}
+	sort.Slice(abiertas, func(i, j int) bool { return abiertas[i].ID < abiertas[j].ID })
+	return abiertas, nil
+}
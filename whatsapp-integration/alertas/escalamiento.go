@@ -0,0 +1,86 @@
+package alertas
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"example.com/whatsapp-integration/store"
+)
+
+// IniciarEscalamiento revisa cada intervalo las alertas sin reconocer. Se
+// detiene al cancelar ctx.
+func (s *Servicio) IniciarEscalamiento(ctx context.Context, intervalo time.Duration) {
+	go func() {
+		ticker := time.NewTicker(intervalo)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ahora := <-ticker.C:
+				if err := s.Revisar(ctx, ahora); err != nil {
+					log.Printf("Error revisando alertas sin reconocer: %v\n", err)
+				}
+			}
+		}
+	}()
+}
+
+// Revisar reintenta los envíos que fallaron y escala a los supervisores las
+// alertas que nadie reconoció dentro del plazo.
+func (s *Servicio) Revisar(ctx context.Context, ahora time.Time) error {
+	abiertas, err := s.Abiertas(ctx)
+	if err != nil {
+		return err
+	}
+	for _, alerta := range abiertas {
+		cambio := false
+		if alerta.EntregadaPOS == nil && s.cfg.WebhookURL != "" {
+			s.entregarPOS(ctx, alerta, EventoAlerta, ahora)
+			cambio = alerta.EntregadaPOS != nil
+		}
+		if alerta.EnviadaRepartidor == nil && alerta.RepartidorID != 0 {
+			repartidor, err := s.store.GetRepartidor(ctx, alerta.RepartidorID)
+			if err != nil {
+				return err
+			}
+			s.avisarRepartidor(alerta, repartidor, ahora)
+			cambio = cambio || alerta.EnviadaRepartidor != nil
+		}
+		if alerta.EscaladaEn == nil && ahora.Sub(alerta.CreatedAt) >= s.cfg.Escalar {
+			s.escalar(ctx, alerta, ahora)
+			cambio = true
+		}
+		if cambio {
+			if err := s.store.ActualizarAlerta(ctx, alerta); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// escalar avisa a los supervisores, al tablero y al punto de venta. La
+// alerta sigue abierta hasta que alguien la reconozca.
+func (s *Servicio) escalar(ctx context.Context, alerta *store.Alerta, ahora time.Time) {
+	alerta.Estado = EstadoEscalada
+	alerta.EscaladaEn = &ahora
+	log.Printf("Alerta %d del pedido %d escalada: sin reconocer después de %s\n", alerta.ID, alerta.PedidoID, s.cfg.Escalar)
+
+	motivo := "el repartidor no la ha confirmado"
+	if alerta.RepartidorID == 0 {
+		motivo = "el pedido no tiene repartidor asignado"
+	}
+	if s.sender != nil {
+		msg := fmt.Sprintf("⏰ *Alerta #%d sin reconocer* (%s)\n\n%s", alerta.ID, motivo, alerta.Mensaje)
+		for _, telefono := range s.cfg.Supervisores {
+			if err := s.sender.SendMessage(telefono, msg); err != nil {
+				log.Printf("Error escalando alerta %d a %s: %v\n", alerta.ID, telefono, err)
+			}
+		}
+	}
+	s.tablero.publicar(eventoDe(EventoEscalada, alerta))
+	s.entregarPOS(ctx, alerta, EventoEscalada, ahora)
+}
@@ -0,0 +1,258 @@
+package alertas
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// latidoTablero mantiene viva la conexión de eventos detrás de proxies.
+const latidoTablero = 25 * time.Second
+
+// tablero reparte los eventos a los despachadores conectados.
+type tablero struct {
+	mu           sync.Mutex
+	suscriptores map[chan Evento]struct{}
+}
+
+func newTablero() *tablero {
+	return &tablero{suscriptores: make(map[chan Evento]struct{})}
+}
+
+func (t *tablero) suscribir() chan Evento {
+	ch := make(chan Evento, 16)
+	t.mu.Lock()
+	t.suscriptores[ch] = struct{}{}
+	t.mu.Unlock()
+	return ch
+}
+
+func (t *tablero) cancelar(ch chan Evento) {
+	t.mu.Lock()
+	delete(t.suscriptores, ch)
+	t.mu.Unlock()
+}
+
+// publicar no bloquea: un despachador que no alcanza a leer pierde el
+// evento, pero al reconectarse recibe otra vez las alertas abiertas.
+func (t *tablero) publicar(e Evento) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for ch := range t.suscriptores {
+		select {
+		case ch <- e:
+		default:
+			log.Printf("Tablero de despacho saturado; se descartó el evento %s de la alerta %d\n", e.Evento, e.ID)
+		}
+	}
+}
+
+// Handler regresa el tablero de despacho. El token va en
+// "Authorization: Bearer <token>" o en ?token=, porque EventSource no manda
+// encabezados:
+//
+//	GET  /despacho/            página con las alertas en vivo
+//	GET  /despacho/alertas     alertas sin reconocer
+//	GET  /despacho/eventos     server-sent events: alerta, escalada, reconocida
+//	POST /despacho/reconocer   {"alerta_id", "operador"}
+func (s *Servicio) Handler(token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/despacho/", s.handlePagina)
+	mux.HandleFunc("/despacho/alertas", s.handleAlertas)
+	mux.HandleFunc("/despacho/eventos", s.handleEventos)
+	mux.HandleFunc("/despacho/reconocer", s.handleReconocer)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recibido := r.URL.Query().Get("token")
+		if recibido == "" {
+			recibido = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		}
+		if token == "" || subtle.ConstantTimeCompare([]byte(recibido), []byte(token)) != 1 {
+			http.Error(w, "No autorizado", http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func (s *Servicio) handlePagina(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/despacho/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, paginaTablero)
+}
+
+func (s *Servicio) handleAlertas(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+		return
+	}
+	abiertas, err := s.Abiertas(r.Context())
+	if err != nil {
+		log.Printf("Error consultando alertas abiertas: %v\n", err)
+		http.Error(w, "Error interno", http.StatusInternalServerError)
+		return
+	}
+	eventos := make([]Evento, 0, len(abiertas))
+	for _, a := range abiertas {
+		eventos = append(eventos, eventoDe(EventoAlerta, a))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"alertas": eventos})
+}
+
+// handleEventos manda primero las alertas abiertas y después cada evento
+// conforme ocurre.
+func (s *Servicio) handleEventos(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming no soportado", http.StatusInternalServerError)
+		return
+	}
+	ch := s.tablero.suscribir()
+	defer s.tablero.cancelar(ch)
+
+	abiertas, err := s.Abiertas(r.Context())
+	if err != nil {
+		log.Printf("Error consultando alertas abiertas: %v\n", err)
+		http.Error(w, "Error interno", http.StatusInternalServerError)
+		return
+	}
+	// El servidor principal tiene WriteTimeout; sin quitarlo aquí, la
+	// conexión se corta a los pocos segundos y el tablero se reconecta.
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("No se pudo quitar el límite de escritura del tablero: %v\n", err)
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	for _, a := range abiertas {
+		escribirEvento(w, eventoDe(EventoAlerta, a))
+	}
+	flusher.Flush()
+
+	latido := time.NewTicker(latidoTablero)
+	defer latido.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e := <-ch:
+			escribirEvento(w, e)
+			flusher.Flush()
+		case <-latido.C:
+			fmt.Fprint(w, ": latido\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+func escribirEvento(w http.ResponseWriter, e Evento) {
+	datos, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Evento, datos)
+}
+
+func (s *Servicio) handleReconocer(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+		return
+	}
+	var p struct {
+		AlertaID int    `json:"alerta_id"`
+		Operador string `json:"operador"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil || p.AlertaID == 0 || strings.TrimSpace(p.Operador) == "" {
+		http.Error(w, "alerta_id y operador son obligatorios", http.StatusBadRequest)
+		return
+	}
+
+	alerta, err := s.Reconocer(r.Context(), p.AlertaID, strings.TrimSpace(p.Operador), time.Now())
+	switch {
+	case errors.Is(err, ErrAlertaNoEncontrada):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case err != nil:
+		log.Printf("Error reconociendo alerta %d: %v\n", p.AlertaID, err)
+		http.Error(w, "Error interno", http.StatusInternalServerError)
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(eventoDe(EventoReconocida, alerta))
+	}
+}
+
+const paginaTablero = `<!DOCTYPE html>
+<html lang="es"><head><meta charset="utf-8"><title>Despacho</title>
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<style>
+body { font-family: sans-serif; margin: 0; padding: 12px; background: #f4f4f4; }
+h1 { font-size: 20px; margin: 4px 0 12px; }
+#conexion { font-size: 13px; color: #555; }
+.tarjeta { background: #fff; border-radius: 8px; padding: 12px; margin-bottom: 12px; box-shadow: 0 1px 3px rgba(0,0,0,.15); border-left: 6px solid #d98e04; }
+.tarjeta.escalada { border-left-color: #b00020; }
+.tarjeta.reconocida { border-left-color: #0a7d3b; opacity: .55; }
+pre { white-space: pre-wrap; font-family: inherit; margin: 0 0 6px; }
+.estado { font-size: 13px; color: #555; }
+button { font-size: 15px; padding: 8px 14px; background: #0a7d3b; color: #fff; border: 0; border-radius: 6px; }
+</style></head>
+<body>
+<h1>Alertas de despacho</h1>
+<p id="conexion">Conectando…</p>
+<div id="alertas"></div>
+<script>
+const token = new URLSearchParams(location.search).get('token') || '';
+const lista = document.getElementById('alertas');
+const conexion = document.getElementById('conexion');
+
+function mostrar(a) {
+  let el = document.getElementById('alerta-' + a.id);
+  if (!el) {
+    el = document.createElement('div');
+    el.id = 'alerta-' + a.id;
+    lista.prepend(el);
+  }
+  el.className = 'tarjeta ' + a.estado;
+  el.textContent = '';
+  const texto = document.createElement('pre');
+  texto.textContent = a.mensaje;
+  el.appendChild(texto);
+  const estado = document.createElement('p');
+  estado.className = 'estado';
+  estado.textContent = '#' + a.id + ' · ' + new Date(a.created_at).toLocaleTimeString() + ' · ' + a.estado +
+    (a.reconocida_por ? ' por ' + a.reconocida_por : '');
+  el.appendChild(estado);
+  if (a.estado !== 'reconocida') {
+    const boton = document.createElement('button');
+    boton.textContent = 'Reconocer';
+    boton.onclick = () => reconocer(a.id);
+    el.appendChild(boton);
+  }
+}
+
+function reconocer(id) {
+  const operador = prompt('¿Quién reconoce la alerta?');
+  if (!operador) return;
+  fetch('/despacho/reconocer?token=' + encodeURIComponent(token), {
+    method: 'POST',
+    headers: {'Content-Type': 'application/json'},
+    body: JSON.stringify({alerta_id: id, operador: operador})
+  });
+}
+
+const fuente = new EventSource('/despacho/eventos?token=' + encodeURIComponent(token));
+fuente.onopen = () => { conexion.textContent = 'En vivo'; };
+fuente.onerror = () => { conexion.textContent = 'Reconectando…'; };
+for (const tipo of ['alerta', 'escalada', 'reconocida']) {
+  fuente.addEventListener(tipo, (m) => mostrar(JSON.parse(m.data)));
+}
+</script>
+</body></html>
+`
@@ -0,0 +1,96 @@
+package alertas
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/whatsapp-integration/store"
+)
+
+// storeAlertas solo implementa lo que usa el tablero; el resto del Store
+// queda nil y haría panic si se llamara.
+type storeAlertas struct {
+	store.Store
+	alertas []*store.Alerta
+}
+
+func (s *storeAlertas) GetAlertasPorEstado(ctx context.Context, estado string) ([]*store.Alerta, error) {
+	var res []*store.Alerta
+	for _, a := range s.alertas {
+		if estado == "" || a.Estado == estado {
+			res = append(res, a)
+		}
+	}
+	return res, nil
+}
+
+// TestEventosSobrevivenWriteTimeout mantiene el stream abierto más allá del
+// WriteTimeout del servidor, como en main.go, y espera recibir un evento.
+func TestEventosSobrevivenWriteTimeout(t *testing.T) {
+	const writeTimeout = 200 * time.Millisecond
+
+	s := NewServicio(&storeAlertas{alertas: []*store.Alerta{
+		{ID: 1, Tipo: TipoSelloViolado, PedidoID: 7, Mensaje: "abierta", Estado: EstadoPendiente},
+	}}, Config{}, time.UTC)
+
+	srv := httptest.NewUnstartedServer(s.Handler("tok"))
+	srv.Config.WriteTimeout = writeTimeout
+	srv.Start()
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/despacho/eventos?token=tok", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+
+	eventos := make(chan string, 8)
+	go func() {
+		sc := bufio.NewScanner(resp.Body)
+		for sc.Scan() {
+			if strings.HasPrefix(sc.Text(), "event: ") {
+				eventos <- strings.TrimPrefix(sc.Text(), "event: ")
+			}
+		}
+		close(eventos)
+	}()
+
+	if e := <-eventos; e != EventoAlerta {
+		t.Fatalf("primer evento = %q, se esperaba la alerta abierta", e)
+	}
+
+	time.Sleep(3 * writeTimeout)
+	s.tablero.publicar(Evento{Evento: EventoEscalada, ID: 1})
+
+	select {
+	case e, ok := <-eventos:
+		if !ok {
+			t.Fatal("el servidor cerró el stream al pasar el WriteTimeout")
+		}
+		if e != EventoEscalada {
+			t.Fatalf("evento = %q, se esperaba %q", e, EventoEscalada)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no llegó el evento después del WriteTimeout")
+	}
+}
+
+func TestEventosSinToken(t *testing.T) {
+	s := NewServicio(&storeAlertas{}, Config{}, time.UTC)
+	rec := httptest.NewRecorder()
+	s.Handler("tok").ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/despacho/eventos?token=otro", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, se esperaba 401", rec.Code)
+	}
+}
@@ -0,0 +1,110 @@
+package alertas
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"example.com/whatsapp-integration/store"
+)
+
+// Encabezados del webhook al punto de venta. La firma es HMAC-SHA256 en
+// hexadecimal de "<timestamp>.<cuerpo>" con ALERTAS_WEBHOOK_SECRETO.
+const (
+	HeaderFirma     = "X-Alertas-Firma"
+	HeaderTimestamp = "X-Alertas-Timestamp"
+)
+
+// Eventos que reciben el punto de venta y el tablero.
+const (
+	EventoAlerta     = "alerta"
+	EventoEscalada   = "escalada"
+	EventoReconocida = "reconocida"
+)
+
+// Evento es el cuerpo del webhook y de los eventos del tablero.
+type Evento struct {
+	Evento        string    `json:"evento"`
+	ID            int       `json:"id"`
+	Tipo          string    `json:"tipo"`
+	PedidoID      int       `json:"pedido_id"`
+	RepartidorID  int       `json:"repartidor_id,omitempty"`
+	ReferenciaID  int       `json:"referencia_id,omitempty"`
+	Mensaje       string    `json:"mensaje"`
+	Estado        string    `json:"estado"`
+	ReconocidaPor string    `json:"reconocida_por,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+func eventoDe(evento string, a *store.Alerta) Evento {
+	return Evento{
+		Evento:        evento,
+		ID:            a.ID,
+		Tipo:          a.Tipo,
+		PedidoID:      a.PedidoID,
+		RepartidorID:  a.RepartidorID,
+		ReferenciaID:  a.ReferenciaID,
+		Mensaje:       a.Mensaje,
+		Estado:        a.Estado,
+		ReconocidaPor: a.ReconocidaPor,
+		CreatedAt:     a.CreatedAt,
+	}
+}
+
+// Firmar calcula la firma del webhook, para que el punto de venta la verifique.
+func Firmar(secreto, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secreto))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// entregarPOS manda el evento al punto de venta. Solo la alerta inicial
+// cuenta como entregada con un 2xx; si falla, la revisión periódica la
+// reintenta.
+func (s *Servicio) entregarPOS(ctx context.Context, alerta *store.Alerta, evento string, ahora time.Time) {
+	if s.cfg.WebhookURL == "" {
+		return
+	}
+	if err := s.enviarWebhook(ctx, eventoDe(evento, alerta), ahora); err != nil {
+		log.Printf("Error entregando alerta %d (%s) al punto de venta: %v\n", alerta.ID, evento, err)
+		return
+	}
+	if evento == EventoAlerta {
+		alerta.EntregadaPOS = &ahora
+	}
+}
+
+func (s *Servicio) enviarWebhook(ctx context.Context, evento Evento, ahora time.Time) error {
+	body, err := json.Marshal(evento)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(ahora.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderFirma, Firmar(s.cfg.WebhookSecreto, timestamp, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("el punto de venta respondió %s", resp.Status)
+	}
+	return nil
+}
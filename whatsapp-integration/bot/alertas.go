@@ -0,0 +1,70 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"example.com/whatsapp-integration/alertas"
+	"example.com/whatsapp-integration/store"
+)
+
+// SetAlertas habilita las alertas al repartidor y al punto de venta. Sin
+// ellas los sellos violados y las cancelaciones solo quedan en el log.
+func (sm *StateMachine) SetAlertas(a *alertas.Servicio) {
+	sm.alertas = a
+}
+
+// handleEnterado reconoce las alertas abiertas del repartidor que escribe.
+// Regresa false si el teléfono no es de un repartidor, para que el mensaje
+// siga el flujo normal.
+func (sm *StateMachine) handleEnterado(ctx context.Context, telefono string) (bool, error) {
+	if sm.alertas == nil {
+		return false, nil
+	}
+	repartidor, n, err := sm.alertas.ReconocerRepartidor(ctx, telefono, time.Now())
+	if err != nil {
+		return repartidor != nil, fmt.Errorf("error reconociendo alertas del repartidor: %w", err)
+	}
+	if repartidor == nil {
+		return false, nil
+	}
+	if n == 0 {
+		return true, sm.sender.SendMessage(telefono, "No tienes alertas pendientes.")
+	}
+	return true, sm.sender.SendMessage(telefono, fmt.Sprintf("✅ Gracias, %s. Confirmamos que recibiste %d alerta(s).", repartidor.Nombre, n))
+}
+
+// NotificarAlertaARepartidor avisa al repartidor y al punto de venta que el
+// pedido del reporte no debe cobrarse.
+func (sm *StateMachine) NotificarAlertaARepartidor(ctx context.Context, cliente *store.Cliente, reporte *store.ReporteSello) {
+	if reporte.PedidoID == nil {
+		fmt.Printf("Error crítico: el reporte de sello %d del cliente %s no tiene pedido activo para alertar al repartidor.\n", reporte.ID, cliente.NumeroTelefono)
+		return
+	}
+	if sm.alertas == nil {
+		fmt.Printf("[ALERTA OPERADOR] Cliente %s (Tel: %s) ha reportado un SELLO VIOLADO para el Pedido #%d (reporte #%d). Instrucción: NO REALIZAR EL COBRO.\n",
+			cliente.Nombre, cliente.NumeroTelefono, *reporte.PedidoID, reporte.ID)
+		return
+	}
+
+	pedido, err := sm.store.GetPedido(ctx, *reporte.PedidoID)
+	if err != nil || pedido == nil {
+		fmt.Printf("Error buscando el pedido %d del reporte de sello %d: %v\n", *reporte.PedidoID, reporte.ID, err)
+		return
+	}
+	detalle := fmt.Sprintf("Reporte #%d: %s", reporte.ID, reporte.Descripcion)
+	if _, err := sm.alertas.Emitir(ctx, alertas.TipoSelloViolado, pedido, reporte.ID, detalle, time.Now()); err != nil {
+		fmt.Printf("Error emitiendo la alerta del reporte de sello %d: %v\n", reporte.ID, err)
+	}
+}
+
+// alertarCancelacion avisa que el pedido cancelado ya no debe entregarse.
+func (sm *StateMachine) alertarCancelacion(ctx context.Context, pedido *store.Pedido, motivo string) {
+	if sm.alertas == nil {
+		return
+	}
+	if _, err := sm.alertas.Emitir(ctx, alertas.TipoPedidoCancelado, pedido, 0, "Motivo: "+motivo, time.Now()); err != nil {
+		fmt.Printf("Error emitiendo la alerta de cancelación del pedido %d: %v\n", pedido.ID, err)
+	}
+}
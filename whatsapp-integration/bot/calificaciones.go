@@ -0,0 +1,125 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"example.com/whatsapp-integration/calificaciones"
+	"example.com/whatsapp-integration/store"
+)
+
+// preguntaCalificacion numera las opciones con las estrellas que valen.
+const preguntaCalificacion = "¿Cómo calificarías nuestro servicio?\n" +
+	"5. ⭐⭐⭐⭐⭐ Excelente\n" +
+	"4. ⭐⭐⭐⭐ Muy bueno\n" +
+	"3. ⭐⭐⭐ Regular\n" +
+	"2. ⭐⭐ Malo\n" +
+	"1. ⭐ Muy malo\n\n" +
+	"Responde con el número o NO si prefieres no calificar."
+
+// SetCalificaciones comparte el servicio con la API de administración, que
+// es la que arma el reporte semanal.
+func (sm *StateMachine) SetCalificaciones(c *calificaciones.Servicio) {
+	sm.calificaciones = c
+}
+
+// pedidoConfirmado es el pedido cuya entrega el cliente acaba de confirmar;
+// la calificación y el comentario siempre se guardan sobre ese pedido.
+func (sm *StateMachine) pedidoConfirmado(ctx context.Context, clienteID int) (*store.Pedido, error) {
+	confirmacion, err := sm.store.GetConfirmacionEntregaConfirmada(ctx, clienteID)
+	if err != nil || confirmacion == nil {
+		return nil, err
+	}
+	pedido, err := sm.store.GetPedido(ctx, confirmacion.PedidoID)
+	if err != nil {
+		return nil, fmt.Errorf("error buscando pedido %d para calificar: %w", confirmacion.PedidoID, err)
+	}
+	return pedido, nil
+}
+
+// handleCalificacion guarda las estrellas del pedido confirmado y ofrece
+// dejar un comentario.
+func (sm *StateMachine) handleCalificacion(ctx context.Context, telefono, mensaje string) error {
+	texto := strings.ToUpper(strings.TrimSpace(mensaje))
+	if texto == "NO" {
+		sm.sender.SendMessage(telefono, "Está bien. ¡Gracias por tu preferencia!")
+		return sm.actualizarEstado(ctx, telefono, EstadoInicial)
+	}
+	puntuacion, err := strconv.Atoi(texto)
+	if err != nil {
+		puntuacion = strings.Count(mensaje, "⭐")
+	}
+	if puntuacion < 1 || puntuacion > 5 {
+		return sm.sender.SendMessage(telefono, preguntaCalificacion)
+	}
+
+	cliente, err := sm.store.GetClientePorTelefono(ctx, telefono)
+	if err != nil {
+		return fmt.Errorf("error buscando cliente para la calificación: %w", err)
+	}
+	if cliente == nil {
+		return sm.actualizarEstado(ctx, telefono, EstadoInicial)
+	}
+	pedido, err := sm.pedidoConfirmado(ctx, cliente.ID)
+	if err != nil {
+		return err
+	}
+	if pedido == nil {
+		sm.sender.SendMessage(telefono, "No encontramos un pedido entregado para calificar. ¡Gracias de todos modos!")
+		return sm.actualizarEstado(ctx, telefono, EstadoInicial)
+	}
+
+	c, err := sm.calificaciones.Registrar(ctx, cliente, pedido, puntuacion, time.Now())
+	if errors.Is(err, calificaciones.ErrYaCalificado) {
+		sm.sender.SendMessage(telefono, fmt.Sprintf("Ya habías calificado tu pedido #%d con %d ⭐. ¡Gracias!", pedido.ID, c.Puntuacion))
+		return sm.actualizarEstado(ctx, telefono, EstadoInicial)
+	}
+	if err != nil {
+		sm.sender.SendMessage(telefono, "No pudimos guardar tu calificación. Por favor, inténtalo de nuevo en unos minutos.")
+		return err
+	}
+
+	msg := "¡Gracias por tu calificación! " + strings.Repeat("⭐", puntuacion) + "\n\n" +
+		"¿Quieres dejarnos un comentario? Escríbelo o responde NO."
+	if sm.calificaciones.Baja(puntuacion) {
+		msg = "Lamentamos que tu experiencia no haya sido buena. Un supervisor revisará tu caso y se comunicará contigo.\n\n" +
+			"¿Qué fue lo que pasó? Escríbenos tu comentario o responde NO."
+	}
+	if err := sm.sender.SendMessage(telefono, msg); err != nil {
+		return err
+	}
+	return sm.actualizarEstado(ctx, telefono, EstadoComentandoServicio)
+}
+
+// handleComentarioServicio agrega el comentario a la calificación del
+// pedido confirmado.
+func (sm *StateMachine) handleComentarioServicio(ctx context.Context, telefono, mensaje string) error {
+	if texto := strings.ToUpper(strings.TrimSpace(mensaje)); texto == "NO" || texto == "" {
+		sm.sender.SendMessage(telefono, "¡Gracias por tu preferencia!")
+		return sm.actualizarEstado(ctx, telefono, EstadoInicial)
+	}
+
+	cliente, err := sm.store.GetClientePorTelefono(ctx, telefono)
+	if err != nil {
+		return fmt.Errorf("error buscando cliente para el comentario: %w", err)
+	}
+	if cliente == nil {
+		return sm.actualizarEstado(ctx, telefono, EstadoInicial)
+	}
+	pedido, err := sm.pedidoConfirmado(ctx, cliente.ID)
+	if err != nil {
+		return err
+	}
+	if pedido != nil {
+		if _, err := sm.calificaciones.Comentar(ctx, pedido.ID, mensaje); err != nil && !errors.Is(err, calificaciones.ErrCalificacionNoEncontrada) {
+			sm.sender.SendMessage(telefono, "No pudimos guardar tu comentario. Por favor, inténtalo de nuevo en unos minutos.")
+			return err
+		}
+	}
+	sm.sender.SendMessage(telefono, "¡Gracias por tu comentario! Nos ayuda a mejorar nuestro servicio.")
+	return sm.actualizarEstado(ctx, telefono, EstadoInicial)
+}
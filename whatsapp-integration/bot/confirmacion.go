@@ -0,0 +1,291 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"example.com/whatsapp-integration/store"
+)
+
+// Estados de una confirmación de entrega.
+const (
+	ConfirmacionPorEnviar      = "por_enviar" // el cliente estaba a media conversación
+	ConfirmacionPendiente      = "pendiente"
+	ConfirmacionConfirmada     = "confirmada"
+	ConfirmacionAutoconfirmada = "autoconfirmada" // el cliente no respondió a tiempo
+	ConfirmacionRechazada      = "rechazada"      // el cliente dice que no lo recibió
+)
+
+func plazoConfirmacionDesdeEnv() time.Duration {
+	if minutos, err := strconv.Atoi(os.Getenv("CONFIRMACION_ENTREGA_MINUTOS")); err == nil && minutos > 0 {
+		return time.Duration(minutos) * time.Minute
+	}
+	return 60 * time.Minute
+}
+
+// conversacionLibre indica si se puede preguntar por la entrega sin
+// interrumpir otro flujo del cliente.
+func conversacionLibre(estado string) bool {
+	switch estado {
+	case "", EstadoInicial, EstadoEsperandoOpcion, EstadoCilindroRecoleccion, EstadoCilindroEntrega, EstadoConfirmandoEntrega:
+		return true
+	}
+	return false
+}
+
+// SolicitarConfirmacion le pregunta al cliente si recibió el pedido que el
+// repartidor acaba de entregar. Si el cliente está a media conversación no
+// se le interrumpe: la pregunta queda en espera y el plazo para
+// autoconfirmar empieza hasta que se le envía.
+func (sm *StateMachine) SolicitarConfirmacion(ctx context.Context, pedidoID int) error {
+	pedido, err := sm.store.GetPedido(ctx, pedidoID)
+	if err != nil {
+		return fmt.Errorf("error buscando pedido %d para confirmar la entrega: %w", pedidoID, err)
+	}
+	if pedido == nil {
+		return fmt.Errorf("pedido %d no encontrado", pedidoID)
+	}
+	cliente, err := sm.store.GetClientePorID(ctx, pedido.ClienteID)
+	if err != nil {
+		return fmt.Errorf("error buscando cliente del pedido %d: %w", pedidoID, err)
+	}
+	if cliente == nil {
+		return fmt.Errorf("cliente %d del pedido %d no encontrado", pedido.ClienteID, pedidoID)
+	}
+
+	mu := sm.getUserMutex(cliente.NumeroTelefono)
+	mu.Lock()
+	defer mu.Unlock()
+
+	ahora := time.Now()
+	confirmacion := &store.ConfirmacionEntrega{
+		PedidoID:  pedido.ID,
+		ClienteID: cliente.ID,
+		Estado:    ConfirmacionPorEnviar,
+		Vence:     ahora,
+	}
+	if err := sm.store.CrearConfirmacionEntrega(ctx, confirmacion); err != nil {
+		return err
+	}
+	if !conversacionLibre(cliente.EstadoConversacion) {
+		fmt.Printf("Cliente %s en %s; la confirmación del pedido %d se enviará al terminar\n", cliente.NumeroTelefono, cliente.EstadoConversacion, pedido.ID)
+		return nil
+	}
+	return sm.enviarConfirmacion(ctx, cliente.NumeroTelefono, confirmacion, ahora)
+}
+
+// enviarConfirmacion manda la pregunta y desde ese momento corre el plazo.
+// Si el envío falla, la confirmación sigue en espera y se reintenta.
+func (sm *StateMachine) enviarConfirmacion(ctx context.Context, telefono string, confirmacion *store.ConfirmacionEntrega, ahora time.Time) error {
+	msg := fmt.Sprintf("📦 *¿Recibiste tu pedido #%d?*\n\n"+
+		"El repartidor lo marcó como entregado.\n"+
+		"1. Sí, lo recibí\n"+
+		"2. No lo recibí\n\n"+
+		"Si no respondes en %d minutos, daremos la entrega por confirmada.",
+		confirmacion.PedidoID, int(sm.plazoConfirmacion.Minutes()))
+	if err := sm.sender.SendMessage(telefono, msg); err != nil {
+		return err
+	}
+	confirmacion.Estado = ConfirmacionPendiente
+	confirmacion.Vence = ahora.Add(sm.plazoConfirmacion)
+	if err := sm.store.ActualizarConfirmacionEntrega(ctx, confirmacion); err != nil {
+		return err
+	}
+	return sm.actualizarEstado(ctx, telefono, EstadoConfirmandoEntrega)
+}
+
+// handleConfirmacionEntrega responde la confirmación pendiente del cliente:
+// con sí pasa a calificar el servicio; con no abre un caso ligado al pedido
+// y lo pone en disputa.
+func (sm *StateMachine) handleConfirmacionEntrega(ctx context.Context, telefono, mensaje string) error {
+	var recibido bool
+	switch strings.ToUpper(strings.TrimSpace(mensaje)) {
+	case "1", "SI", "SÍ":
+		recibido = true
+	case "2", "NO":
+	default:
+		return sm.sender.SendMessage(telefono,
+			"Por favor responde:\n1. Sí\n2. No")
+	}
+
+	cliente, err := sm.store.GetClientePorTelefono(ctx, telefono)
+	if err != nil {
+		return fmt.Errorf("error buscando cliente para confirmar la entrega: %w", err)
+	}
+	if cliente == nil {
+		return sm.actualizarEstado(ctx, telefono, EstadoInicial)
+	}
+	confirmacion, err := sm.store.GetConfirmacionEntregaPendiente(ctx, cliente.ID)
+	if err != nil {
+		return err
+	}
+	if confirmacion == nil {
+		sm.sender.SendMessage(telefono, "No tienes entregas pendientes de confirmar.")
+		return sm.actualizarEstado(ctx, telefono, EstadoInicial)
+	}
+	pedido, err := sm.store.GetPedido(ctx, confirmacion.PedidoID)
+	if err != nil {
+		return fmt.Errorf("error buscando pedido %d para confirmar la entrega: %w", confirmacion.PedidoID, err)
+	}
+	ahora := time.Now()
+	confirmacion.RespondidaEn = &ahora
+
+	if recibido {
+		confirmacion.Estado = ConfirmacionConfirmada
+		if err := sm.store.ActualizarConfirmacionEntrega(ctx, confirmacion); err != nil {
+			return err
+		}
+		if pedido != nil && pedido.Estado != EstadoPedidoEntregado {
+			pedido.Estado = EstadoPedidoEntregado
+			if err := sm.store.ActualizarPedido(ctx, pedido); err != nil {
+				return err
+			}
+		}
+		if err := sm.sender.SendMessage(telefono, "¡Gracias por confirmar la entrega!\n"+preguntaCalificacion); err != nil {
+			return err
+		}
+		return sm.actualizarEstado(ctx, telefono, EstadoCalificandoServicio)
+	}
+
+	descripcion := fmt.Sprintf("El cliente indicó que no recibió el pedido #%d que el repartidor marcó como entregado.", confirmacion.PedidoID)
+	reporte, err := sm.sellos.Disputar(ctx, cliente, pedido, descripcion, ahora)
+	if err != nil {
+		sm.sender.SendMessage(telefono, "No pudimos registrar tu reporte. Por favor, inténtalo de nuevo en unos minutos.")
+		return err
+	}
+	confirmacion.Estado = ConfirmacionRechazada
+	confirmacion.ReporteID = reporte.ID
+	if err := sm.store.ActualizarConfirmacionEntrega(ctx, confirmacion); err != nil {
+		return err
+	}
+
+	msg := fmt.Sprintf("⚠️ *Reporte #%d recibido*\n\n"+
+		"Lamentamos el inconveniente. Tu caso quedó registrado para el pedido #%d y un supervisor lo revisará a más tardar %s.\n\n"+
+		"Por favor, cuéntanos qué pasó con la entrega.",
+		reporte.ID, confirmacion.PedidoID, sm.textoVencimiento(*reporte.VenceSLA, ahora))
+	if err := sm.sender.SendMessage(telefono, msg); err != nil {
+		return err
+	}
+	return sm.actualizarEstado(ctx, telefono, EstadoReportandoSello)
+}
+
+// IniciarAutoconfirmacion revisa cada intervalo las confirmaciones de
+// entrega en espera y las vencidas. Se detiene al cancelar ctx.
+func (sm *StateMachine) IniciarAutoconfirmacion(ctx context.Context, intervalo time.Duration) {
+	go func() {
+		ticker := time.NewTicker(intervalo)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ahora := <-ticker.C:
+				if err := sm.EnviarConfirmacionesPendientes(ctx, ahora); err != nil {
+					fmt.Printf("Error enviando confirmaciones de entrega: %v\n", err)
+				}
+				if err := sm.AutoconfirmarEntregas(ctx, ahora); err != nil {
+					fmt.Printf("Error autoconfirmando entregas: %v\n", err)
+				}
+			}
+		}
+	}()
+}
+
+// EnviarConfirmacionesPendientes manda las preguntas que quedaron en espera
+// a los clientes que ya terminaron la otra conversación.
+func (sm *StateMachine) EnviarConfirmacionesPendientes(ctx context.Context, ahora time.Time) error {
+	enEspera, err := sm.store.GetConfirmacionesEntregaPorEnviar(ctx)
+	if err != nil {
+		return err
+	}
+	for _, c := range enEspera {
+		cliente, err := sm.store.GetClientePorID(ctx, c.ClienteID)
+		if err != nil {
+			return fmt.Errorf("error buscando cliente %d para confirmar la entrega: %w", c.ClienteID, err)
+		}
+		if cliente == nil {
+			continue
+		}
+		if err := sm.enviarConfirmacionEnEspera(ctx, cliente.NumeroTelefono, c.ID, ahora); err != nil {
+			fmt.Printf("Error enviando confirmación de entrega del pedido %d: %v\n", c.PedidoID, err)
+		}
+	}
+	return nil
+}
+
+func (sm *StateMachine) enviarConfirmacionEnEspera(ctx context.Context, telefono string, id int, ahora time.Time) error {
+	mu := sm.getUserMutex(telefono)
+	mu.Lock()
+	defer mu.Unlock()
+
+	cliente, err := sm.store.GetClientePorTelefono(ctx, telefono)
+	if err != nil || cliente == nil || !conversacionLibre(cliente.EstadoConversacion) {
+		return err
+	}
+	// Si ya hay otra pregunta abierta, esta espera su turno.
+	if cliente.EstadoConversacion == EstadoConfirmandoEntrega {
+		return nil
+	}
+	confirmacion, err := sm.store.GetConfirmacionEntrega(ctx, id)
+	if err != nil || confirmacion == nil || confirmacion.Estado != ConfirmacionPorEnviar {
+		return err
+	}
+	return sm.enviarConfirmacion(ctx, telefono, confirmacion, ahora)
+}
+
+// AutoconfirmarEntregas da por recibidos los pedidos cuyo cliente no
+// respondió a tiempo y, si seguía en la pregunta, lo regresa al inicio.
+func (sm *StateMachine) AutoconfirmarEntregas(ctx context.Context, ahora time.Time) error {
+	vencidas, err := sm.store.GetConfirmacionesEntregaVencidas(ctx, ahora)
+	if err != nil {
+		return err
+	}
+	for _, confirmacion := range vencidas {
+		if err := sm.autoconfirmar(ctx, confirmacion); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (sm *StateMachine) autoconfirmar(ctx context.Context, vencida *store.ConfirmacionEntrega) error {
+	cliente, err := sm.store.GetClientePorID(ctx, vencida.ClienteID)
+	if err != nil {
+		return fmt.Errorf("error buscando cliente %d para autoconfirmar la entrega: %w", vencida.ClienteID, err)
+	}
+	if cliente != nil {
+		mu := sm.getUserMutex(cliente.NumeroTelefono)
+		mu.Lock()
+		defer mu.Unlock()
+		if cliente, err = sm.store.GetClientePorID(ctx, vencida.ClienteID); err != nil {
+			return err
+		}
+	}
+	// El cliente pudo responder mientras se esperaba el mutex.
+	confirmacion, err := sm.store.GetConfirmacionEntrega(ctx, vencida.ID)
+	if err != nil || confirmacion == nil || confirmacion.Estado != ConfirmacionPendiente {
+		return err
+	}
+
+	confirmacion.Estado = ConfirmacionAutoconfirmada
+	if err := sm.store.ActualizarConfirmacionEntrega(ctx, confirmacion); err != nil {
+		return err
+	}
+	fmt.Printf("Entrega del pedido %d autoconfirmada\n", confirmacion.PedidoID)
+	if cliente == nil || cliente.EstadoConversacion != EstadoConfirmandoEntrega {
+		return nil
+	}
+	otra, err := sm.store.GetConfirmacionEntregaPendiente(ctx, cliente.ID)
+	if err != nil || otra != nil {
+		return err
+	}
+
+	sm.sender.SendMessage(cliente.NumeroTelefono, fmt.Sprintf(
+		"Como no recibimos respuesta, dimos por recibido tu pedido #%d. ¡Gracias por tu preferencia!\n"+
+			"Si hubo algún problema con tu entrega, escríbenos.", confirmacion.PedidoID))
+	return sm.actualizarEstado(ctx, cliente.NumeroTelefono, EstadoInicial)
+}
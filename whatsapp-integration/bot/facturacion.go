@@ -0,0 +1,309 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"example.com/whatsapp-integration/facturacion"
+	"example.com/whatsapp-integration/store"
+)
+
+// Claves de los datos temporales del flujo de factura.
+const (
+	datoFacturaPedidos = "factura_pedidos" // []int ofrecidos en el menú
+	datoFacturaPedido  = "factura_pedido"  // ID del pedido a facturar
+	datoFacturaDatos   = "factura_datos"   // *store.DatosFiscales en captura
+)
+
+// SetFacturacion habilita el comando FACTURA para los pedidos entregados.
+func (sm *StateMachine) SetFacturacion(f *facturacion.Servicio) {
+	sm.facturacion = f
+}
+
+// handleFactura responde el comando global FACTURA: elige el pedido
+// entregado y sigue con los datos fiscales.
+func (sm *StateMachine) handleFactura(ctx context.Context, telefono string) error {
+	if sm.facturacion == nil {
+		sm.sender.SendMessage(telefono, "Por ahora no emitimos facturas por este medio. Comunícate con nosotros para solicitarla.")
+		return nil
+	}
+	cliente, err := sm.store.GetClientePorTelefono(ctx, telefono)
+	if err != nil {
+		return fmt.Errorf("error buscando cliente para facturar: %w", err)
+	}
+	if cliente == nil {
+		sm.sender.SendMessage(telefono, "No pudimos encontrar tus datos. Por favor, inicia la conversación para registrarte.")
+		return nil
+	}
+	sm.session.ClienteActual = cliente
+	sm.limpiarFactura(telefono)
+
+	pedidos, err := sm.facturacion.PedidosFacturables(ctx, cliente.ID, time.Now())
+	if err != nil {
+		return err
+	}
+	switch len(pedidos) {
+	case 0:
+		sm.sender.SendMessage(telefono, "No tienes pedidos entregados pendientes de facturar. Puedes pedir la factura de un pedido después de recibirlo.")
+		return nil
+	case 1:
+		sm.guardarDatoTemp(telefono, datoFacturaPedido, pedidos[0].ID)
+		return sm.pedirDatosFiscales(ctx, telefono, cliente.ID)
+	}
+
+	ids := make([]int, len(pedidos))
+	msg := "🧾 *Factura*\n\n¿De qué pedido necesitas la factura?\n"
+	for i, p := range pedidos {
+		ids[i] = p.ID
+		msg += fmt.Sprintf("\n%d. Pedido #%d del %s por $%.2f", i+1, p.ID, p.CreatedAt.In(sm.loc).Format("02/01/2006"), p.TotalAPagar())
+	}
+	sm.guardarDatoTemp(telefono, datoFacturaPedidos, ids)
+	sm.sender.SendMessage(telefono, msg+"\n\nResponde con el número o escribe CANCELAR.")
+	return sm.actualizarEstado(ctx, telefono, EstadoFacturaPedido)
+}
+
+func (sm *StateMachine) handleFacturaPedido(ctx context.Context, telefono, mensaje string) error {
+	if cancelarFactura(mensaje) {
+		return sm.salirDeFactura(ctx, telefono)
+	}
+	ids, ok := sm.datoTemp(telefono, datoFacturaPedidos).([]int)
+	if !ok {
+		return sm.reiniciarFactura(ctx, telefono)
+	}
+	opcion, err := strconv.Atoi(strings.TrimSpace(mensaje))
+	if err != nil || opcion < 1 || opcion > len(ids) {
+		sm.sender.SendMessage(telefono, "Por favor, responde con el número de uno de los pedidos.")
+		return nil
+	}
+	sm.guardarDatoTemp(telefono, datoFacturaPedido, ids[opcion-1])
+	return sm.pedirDatosFiscales(ctx, telefono, sm.session.ClienteActual.ID)
+}
+
+// pedirDatosFiscales ofrece los datos guardados del cliente o empieza la
+// captura con el RFC.
+func (sm *StateMachine) pedirDatosFiscales(ctx context.Context, telefono string, clienteID int) error {
+	datos, err := sm.store.GetDatosFiscales(ctx, clienteID)
+	if err != nil {
+		return err
+	}
+	if datos == nil {
+		sm.guardarDatoTemp(telefono, datoFacturaDatos, &store.DatosFiscales{ClienteID: clienteID})
+		sm.sender.SendMessage(telefono, "Escribe tu RFC tal como aparece en tu Constancia de Situación Fiscal.")
+		return sm.actualizarEstado(ctx, telefono, EstadoFacturaRFC)
+	}
+
+	sm.guardarDatoTemp(telefono, datoFacturaDatos, datos)
+	sm.sender.SendMessage(telefono, fmt.Sprintf("¿Facturamos con estos datos?\n\n%s\n\n1. Sí, facturar\n2. Actualizar datos", resumenDatosFiscales(datos)))
+	return sm.actualizarEstado(ctx, telefono, EstadoFacturaConfirmarDatos)
+}
+
+func (sm *StateMachine) handleFacturaConfirmarDatos(ctx context.Context, telefono, mensaje string) error {
+	if cancelarFactura(mensaje) {
+		return sm.salirDeFactura(ctx, telefono)
+	}
+	switch strings.TrimSpace(mensaje) {
+	case "1":
+		return sm.emitirFactura(ctx, telefono)
+	case "2":
+		datos, ok := sm.datoTemp(telefono, datoFacturaDatos).(*store.DatosFiscales)
+		if !ok {
+			return sm.reiniciarFactura(ctx, telefono)
+		}
+		sm.guardarDatoTemp(telefono, datoFacturaDatos, &store.DatosFiscales{ClienteID: datos.ClienteID})
+		sm.sender.SendMessage(telefono, "Escribe tu RFC tal como aparece en tu Constancia de Situación Fiscal.")
+		return sm.actualizarEstado(ctx, telefono, EstadoFacturaRFC)
+	}
+	sm.sender.SendMessage(telefono, "Por favor, responde 1 para facturar o 2 para actualizar tus datos.")
+	return nil
+}
+
+func (sm *StateMachine) handleFacturaRFC(ctx context.Context, telefono, mensaje string) error {
+	if cancelarFactura(mensaje) {
+		return sm.salirDeFactura(ctx, telefono)
+	}
+	rfc := facturacion.NormalizarRFC(mensaje)
+	if rfc == facturacion.RFCPublicoGeneral || rfc == facturacion.RFCExtranjero {
+		sm.sender.SendMessage(telefono, "Ese es el RFC genérico de público en general; no hace falta pedir factura con él. Escribe tu RFC personal o el de tu empresa.")
+		return nil
+	}
+	if err := facturacion.ValidarRFC(rfc); err != nil {
+		sm.sender.SendMessage(telefono, fmt.Sprintf("El RFC %s no es válido: %v. Revísalo y escríbelo de nuevo.", rfc, err))
+		return nil
+	}
+	datos, ok := sm.datoTemp(telefono, datoFacturaDatos).(*store.DatosFiscales)
+	if !ok {
+		return sm.reiniciarFactura(ctx, telefono)
+	}
+	datos.RFC = rfc
+
+	sm.sender.SendMessage(telefono, "Escribe tu nombre o razón social tal como aparece en tu Constancia de Situación Fiscal, sin el régimen societario (por ejemplo, sin \"S.A. de C.V.\").")
+	return sm.actualizarEstado(ctx, telefono, EstadoFacturaRazonSocial)
+}
+
+func (sm *StateMachine) handleFacturaRazonSocial(ctx context.Context, telefono, mensaje string) error {
+	if cancelarFactura(mensaje) {
+		return sm.salirDeFactura(ctx, telefono)
+	}
+	nombre := facturacion.NormalizarRazonSocial(mensaje)
+	if len([]rune(nombre)) < 3 {
+		sm.sender.SendMessage(telefono, "Por favor, escribe tu nombre o razón social completo.")
+		return nil
+	}
+	datos, ok := sm.datoTemp(telefono, datoFacturaDatos).(*store.DatosFiscales)
+	if !ok {
+		return sm.reiniciarFactura(ctx, telefono)
+	}
+	datos.RazonSocial = nombre
+
+	sm.sender.SendMessage(telefono, "Escribe el código postal de tu domicilio fiscal (5 dígitos).")
+	return sm.actualizarEstado(ctx, telefono, EstadoFacturaCP)
+}
+
+func (sm *StateMachine) handleFacturaCP(ctx context.Context, telefono, mensaje string) error {
+	if cancelarFactura(mensaje) {
+		return sm.salirDeFactura(ctx, telefono)
+	}
+	cp := strings.TrimSpace(mensaje)
+	if err := facturacion.ValidarCodigoPostal(cp); err != nil {
+		sm.sender.SendMessage(telefono, "El código postal debe tener 5 dígitos. Escríbelo de nuevo.")
+		return nil
+	}
+	datos, ok := sm.datoTemp(telefono, datoFacturaDatos).(*store.DatosFiscales)
+	if !ok {
+		return sm.reiniciarFactura(ctx, telefono)
+	}
+	datos.CodigoPostal = cp
+
+	msg := "¿Cuál es tu régimen fiscal?\n"
+	for i, r := range facturacion.RegimenesPara(datos.RFC) {
+		msg += fmt.Sprintf("\n%d. %s - %s", i+1, r.Clave, r.Descripcion)
+	}
+	sm.sender.SendMessage(telefono, msg)
+	return sm.actualizarEstado(ctx, telefono, EstadoFacturaRegimen)
+}
+
+func (sm *StateMachine) handleFacturaRegimen(ctx context.Context, telefono, mensaje string) error {
+	if cancelarFactura(mensaje) {
+		return sm.salirDeFactura(ctx, telefono)
+	}
+	datos, ok := sm.datoTemp(telefono, datoFacturaDatos).(*store.DatosFiscales)
+	if !ok {
+		return sm.reiniciarFactura(ctx, telefono)
+	}
+	regimenes := facturacion.RegimenesPara(datos.RFC)
+	opcion, err := strconv.Atoi(strings.TrimSpace(mensaje))
+	if err != nil || opcion < 1 || opcion > len(regimenes) {
+		sm.sender.SendMessage(telefono, "Por favor, responde con el número de tu régimen fiscal.")
+		return nil
+	}
+	datos.RegimenFiscal = regimenes[opcion-1].Clave
+
+	// Sin obligaciones fiscales solo se puede facturar sin efectos fiscales.
+	if datos.RegimenFiscal == "616" {
+		datos.UsoCFDI = "S01"
+		return sm.guardarDatosFiscales(ctx, telefono, datos)
+	}
+	msg := "¿Qué uso le darás a la factura?\n"
+	for i, u := range facturacion.UsosCFDI {
+		msg += fmt.Sprintf("\n%d. %s - %s", i+1, u.Clave, u.Descripcion)
+	}
+	sm.sender.SendMessage(telefono, msg)
+	return sm.actualizarEstado(ctx, telefono, EstadoFacturaUsoCFDI)
+}
+
+func (sm *StateMachine) handleFacturaUsoCFDI(ctx context.Context, telefono, mensaje string) error {
+	if cancelarFactura(mensaje) {
+		return sm.salirDeFactura(ctx, telefono)
+	}
+	opcion, err := strconv.Atoi(strings.TrimSpace(mensaje))
+	if err != nil || opcion < 1 || opcion > len(facturacion.UsosCFDI) {
+		sm.sender.SendMessage(telefono, "Por favor, responde con el número del uso de la factura.")
+		return nil
+	}
+	datos, ok := sm.datoTemp(telefono, datoFacturaDatos).(*store.DatosFiscales)
+	if !ok {
+		return sm.reiniciarFactura(ctx, telefono)
+	}
+	datos.UsoCFDI = facturacion.UsosCFDI[opcion-1].Clave
+	return sm.guardarDatosFiscales(ctx, telefono, datos)
+}
+
+// guardarDatosFiscales valida y guarda los datos capturados para las
+// siguientes facturas y emite la del pedido elegido.
+func (sm *StateMachine) guardarDatosFiscales(ctx context.Context, telefono string, datos *store.DatosFiscales) error {
+	if err := facturacion.ValidarDatosFiscales(datos); err != nil {
+		sm.sender.SendMessage(telefono, fmt.Sprintf("Tus datos fiscales no son válidos: %v. Escribe FACTURA para capturarlos de nuevo.", err))
+		return sm.salirDeFactura(ctx, telefono)
+	}
+	if err := sm.store.GuardarDatosFiscales(ctx, datos); err != nil {
+		return err
+	}
+	return sm.emitirFactura(ctx, telefono)
+}
+
+// emitirFactura timbra la factura del pedido elegido y se la envía al
+// cliente.
+func (sm *StateMachine) emitirFactura(ctx context.Context, telefono string) error {
+	pedidoID, _ := sm.datoTemp(telefono, datoFacturaPedido).(int)
+	datos, ok := sm.datoTemp(telefono, datoFacturaDatos).(*store.DatosFiscales)
+	if !ok {
+		return sm.reiniciarFactura(ctx, telefono)
+	}
+	pedido, err := sm.store.GetPedido(ctx, pedidoID)
+	if err != nil {
+		return err
+	}
+	if pedido == nil || pedido.ClienteID != datos.ClienteID {
+		sm.sender.SendMessage(telefono, "No encontramos el pedido a facturar. Escribe FACTURA para intentarlo de nuevo.")
+		return sm.salirDeFactura(ctx, telefono)
+	}
+
+	factura, err := sm.facturacion.Facturar(ctx, pedido, datos, time.Now())
+	if errors.Is(err, facturacion.ErrPedidoNoEntregado) {
+		sm.sender.SendMessage(telefono, "Solo podemos facturar pedidos entregados.")
+		return sm.salirDeFactura(ctx, telefono)
+	}
+	if err != nil {
+		fmt.Printf("Error facturando pedido %d: %v\n", pedido.ID, err)
+		sm.sender.SendMessage(telefono, "No pudimos emitir tu factura en este momento. Tus datos quedaron guardados; escribe FACTURA más tarde para intentarlo de nuevo.")
+		return sm.salirDeFactura(ctx, telefono)
+	}
+
+	sm.sender.SendMessage(telefono, fmt.Sprintf("✅ Tu factura del pedido #%d quedó emitida a nombre de %s. Te la enviamos a continuación.", pedido.ID, datos.RazonSocial))
+	if err := sm.facturacion.Enviar(ctx, telefono, factura); err != nil {
+		fmt.Printf("Error enviando factura del pedido %d: %v\n", pedido.ID, err)
+	}
+	return sm.salirDeFactura(ctx, telefono)
+}
+
+// reiniciarFactura atiende un paso del flujo cuando la sesión ya no tiene los
+// datos capturados, por ejemplo después de reiniciar el servidor.
+func (sm *StateMachine) reiniciarFactura(ctx context.Context, telefono string) error {
+	sm.sender.SendMessage(telefono, "Se perdió la captura de tu factura. Escribe FACTURA para empezar de nuevo.")
+	return sm.salirDeFactura(ctx, telefono)
+}
+
+// salirDeFactura termina el flujo de factura y regresa al menú inicial.
+func (sm *StateMachine) salirDeFactura(ctx context.Context, telefono string) error {
+	sm.limpiarFactura(telefono)
+	return sm.actualizarEstado(ctx, telefono, EstadoInicial)
+}
+
+func (sm *StateMachine) limpiarFactura(telefono string) {
+	sm.borrarDatosTemp(telefono, datoFacturaPedidos, datoFacturaPedido, datoFacturaDatos)
+}
+
+func cancelarFactura(mensaje string) bool {
+	return strings.ToUpper(strings.TrimSpace(mensaje)) == "CANCELAR"
+}
+
+func resumenDatosFiscales(d *store.DatosFiscales) string {
+	return fmt.Sprintf("RFC: %s\nNombre: %s\nC.P.: %s\nRégimen: %s %s\nUso: %s %s",
+		d.RFC, d.RazonSocial, d.CodigoPostal,
+		d.RegimenFiscal, facturacion.DescripcionRegimen(d.RegimenFiscal),
+		d.UsoCFDI, facturacion.DescripcionUso(d.UsoCFDI))
+}
@@ -1,4 +1,3 @@
-
 package bot
 
 import (
@@ -8,20 +7,47 @@ import (
 
 func (sm *StateMachine) handleConfirmacionFinal(ctx context.Context, telefono string) error {
 	pedido := sm.session.PedidoEnCurso
+	cantidad := fmt.Sprintf("%.2f Lts", pedido.CantidadLitros)
+	if esCilindro(pedido.TipoServicio) {
+		cantidad = fmt.Sprintf("%d cilindro(s)", pedido.CantidadCilindros)
+	}
+	horario := ""
+	if pedido.HorarioPreferido != "" {
+		horario = fmt.Sprintf("  - *Horario de Entrega:* %s\n", pedido.HorarioPreferido)
+	}
+	total := ""
+	if lineas := sm.aplicarDescuentos(ctx, telefono, pedido); len(lineas) > 0 {
+		total = fmt.Sprintf("  - *Subtotal:* $%.2f\n", pedido.CantidadDinero)
+		for _, l := range lineas {
+			total += fmt.Sprintf("  - *%s:* -$%.2f\n", l.concepto, l.monto)
+		}
+	}
+	total += fmt.Sprintf("  - *Total a Pagar:* $%.2f\n", pedido.TotalAPagar())
+	opciones := "1. Sí, confirmar\n2. No, cancelar\n3. Programar para otra fecha"
+	if saldo := sm.puntosCanjeables(ctx, telefono); saldo > 0 {
+		opciones += fmt.Sprintf("\n4. Usar mis puntos (tienes %d)", saldo)
+	}
+	if sm.promociones != nil && sm.datoTemp(telefono, datoCodigoPromocion) == nil {
+		opciones += "\n\n¿Tienes un código de promoción? Escríbelo aquí."
+	}
 	resumen := fmt.Sprintf(
 		"📝 *Resumen de tu Pedido*\n\n"+
 			"  - *Servicio:* %s\n"+
-			"  - *Cantidad:* %.2f Lts\n"+
-			"  - *Total a Pagar:* $%.2f\n"+
+			"  - *Cantidad:* %s\n"+
+			"%s"+
 			"  - *Método de Pago:* %s\n"+
-			"  - *Dirección de Entrega:* %s\n\n"+
-			"*Importante:* Nuestro repartidor solo podrá esperar un máximo de 10 minutos en tu domicilio.\n\n"+
-			"¿Confirmas tu pedido?\n1. Sí, confirmar\n2. No, cancelar",
+			"  - *Dirección de Entrega:* %s\n"+
+			"%s\n"+
+			"*Importante:* Nuestro repartidor solo podrá esperar un máximo de %d minutos en tu domicilio.\n\n"+
+			"¿Confirmas tu pedido?\n%s",
 		pedido.TipoServicio,
-		pedido.CantidadLitros,
-		pedido.CantidadDinero,
-		pedido.MetodoPago,
+		cantidad,
+		total,
+		sm.etiquetaPago(pedido.MetodoPago),
 		pedido.Direccion,
+		horario,
+		int(sm.espera.Minutes()),
+		opciones,
 	)
 
 	if err := sm.sender.SendMessage(telefono, resumen); err != nil {
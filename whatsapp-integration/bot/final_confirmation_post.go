@@ -1,9 +1,7 @@
-
 package bot
 
 import (
 	"context"
-	"fmt"
 )
 
 func (sm *StateMachine) handleConfirmacionFinalPost(ctx context.Context, telefono string, mensaje string) error {
@@ -17,18 +15,58 @@ func (sm *StateMachine) handleConfirmacionFinalPost(ctx context.Context, telefon
 			pedido.Estado = "pendiente"
 		}
 
-		if err := sm.store.CrearPedido(ctx, pedido); err != nil {
-			return fmt.Errorf("error al guardar el pedido en la base de datos: %w", err)
+		// El pedido y la capacidad del horario elegido se guardan juntos antes de
+		// canjear puntos o usar el código; si el horario se llenó mientras el
+		// cliente confirmaba, no queda registro y se ofrecen otros horarios con
+		// los mismos descuentos.
+		guardado, err := sm.crearPedido(ctx, telefono, pedido)
+		if err != nil {
+			return err
+		}
+		if !guardado {
+			sm.sender.SendMessage(telefono, "Lo sentimos, el horario que elegiste se acaba de llenar. Por favor, elige otro.")
+			return sm.handleSeleccionSlot(ctx, telefono, "")
+		}
+		if err := sm.canjearPuntos(ctx, telefono, pedido); err != nil {
+			return err
 		}
+		if err := sm.registrarPromocion(ctx, telefono, pedido); err != nil {
+			return err
+		}
+		sm.limpiarDescuentos(telefono)
 		msg := "¡Tu pedido ha sido confirmado! En breve recibirás una notificación sobre la entrega."
+		instrucciones, err := sm.iniciarPago(ctx, pedido)
+		if err != nil {
+			return err
+		}
+		if instrucciones != "" {
+			msg += "\n\n" + instrucciones
+		}
 		sm.sender.SendMessage(telefono, msg)
 		return sm.actualizarEstado(ctx, telefono, EstadoInicial)
 	case "2": // No, cancelar
+		sm.limpiarDescuentos(telefono)
 		msg := "Tu pedido ha sido cancelado. Puedes iniciar uno nuevo cuando quieras."
 		sm.sender.SendMessage(telefono, msg)
 		return sm.actualizarEstado(ctx, telefono, EstadoInicial)
+	case "3": // Programar entrega futura o recurrente
+		return sm.handleProgramarFecha(ctx, telefono, "")
+	case "4": // Canjear puntos
+		if sm.datoTemp(telefono, datoCanjearPuntos) == true {
+			sm.sender.SendMessage(telefono, "Tus puntos ya están aplicados en este pedido.")
+			return nil
+		}
+		if sm.puntosCanjeables(ctx, telefono) == 0 {
+			sm.sender.SendMessage(telefono, "No tienes puntos suficientes para canjear en este pedido.")
+			return nil
+		}
+		sm.guardarDatoTemp(telefono, datoCanjearPuntos, true)
+		return sm.handleConfirmacionFinal(ctx, telefono)
 	default:
-		sm.sender.SendMessage(telefono, "Opción no válida. Por favor, responde 1 para confirmar o 2 para cancelar.")
+		if sm.promociones != nil && pareceCodigo(mensaje) {
+			return sm.handleCodigoPromocion(ctx, telefono, mensaje)
+		}
+		sm.sender.SendMessage(telefono, "Opción no válida. Por favor, responde 1 para confirmar, 2 para cancelar o 3 para programar.")
 		return nil
 	}
 }
@@ -0,0 +1,66 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"example.com/whatsapp-integration/lealtad"
+	"example.com/whatsapp-integration/store"
+)
+
+// SetLealtad reemplaza el programa de niveles. Sin llamarlo se usa
+// lealtad.ProgramaPorDefecto().
+func (sm *StateMachine) SetLealtad(p *lealtad.Programa) {
+	if p != nil {
+		sm.lealtad = p
+	}
+}
+
+// beneficios regresa los beneficios del nivel del cliente en sesión.
+func (sm *StateMachine) beneficios() lealtad.Beneficios {
+	if sm.session.ClienteActual == nil {
+		return sm.lealtad.Base().Beneficios
+	}
+	return sm.lealtad.Beneficios(sm.session.ClienteActual.Categoria)
+}
+
+// lineaDescuento es un renglón del resumen que reduce el total.
+type lineaDescuento struct {
+	concepto string
+	monto    float64
+}
+
+// aplicarDescuentos recalcula pedido.Descuento desde cero, así que se puede
+// llamar cada vez que se muestra el resumen. Regresa los renglones a mostrar.
+func (sm *StateMachine) aplicarDescuentos(ctx context.Context, telefono string, pedido *store.Pedido) []lineaDescuento {
+	var lineas []lineaDescuento
+	if pct := sm.beneficios().DescuentoPorcentaje; pct > 0 && pedido.CantidadDinero > 0 {
+		lineas = append(lineas, lineaDescuento{
+			concepto: fmt.Sprintf("Descuento %s (%.0f%%)", sm.session.ClienteActual.Categoria, pct),
+			monto:    redondear(pedido.CantidadDinero * pct / 100),
+		})
+	}
+
+	pedido.Descuento = 0
+	for _, l := range lineas {
+		pedido.Descuento += l.monto
+	}
+	if l := sm.lineaPromocion(ctx, telefono, pedido, pedido.CantidadDinero-pedido.Descuento); l != nil {
+		lineas = append(lineas, *l)
+		pedido.Descuento += l.monto
+	}
+	// Los puntos cubren lo que queda después de los demás descuentos.
+	if l := sm.lineaPuntos(ctx, telefono, pedido.CantidadDinero-pedido.Descuento); l != nil {
+		lineas = append(lineas, *l)
+		pedido.Descuento += l.monto
+	}
+	if pedido.Descuento > pedido.CantidadDinero {
+		pedido.Descuento = pedido.CantidadDinero
+	}
+	return lineas
+}
+
+func redondear(monto float64) float64 {
+	return math.Round(monto*100) / 100
+}
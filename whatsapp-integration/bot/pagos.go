@@ -0,0 +1,81 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"example.com/whatsapp-integration/payments"
+	"example.com/whatsapp-integration/store"
+)
+
+// SetPagos habilita la elección del método de pago según la zona del pedido.
+// Sin servicio de pagos todos los pedidos se cobran en efectivo.
+func (sm *StateMachine) SetPagos(p *payments.Service) {
+	sm.pagos = p
+}
+
+// metodosPago regresa los métodos que se ofrecen en la zona del pedido.
+func (sm *StateMachine) metodosPago(pedido *store.Pedido) []payments.PaymentProvider {
+	if sm.pagos == nil {
+		return nil
+	}
+	return sm.pagos.Available(sm.zonaPedido(pedido))
+}
+
+// etiquetaPago es el nombre del método de pago para el resumen.
+func (sm *StateMachine) etiquetaPago(metodo string) string {
+	if sm.pagos == nil {
+		return metodo
+	}
+	return sm.pagos.Label(metodo)
+}
+
+// iniciarPago registra el pago pendiente del pedido recién confirmado y
+// regresa las instrucciones para el cliente.
+func (sm *StateMachine) iniciarPago(ctx context.Context, pedido *store.Pedido) (string, error) {
+	if sm.pagos == nil {
+		return "", nil
+	}
+	_, instrucciones, err := sm.pagos.Start(ctx, pedido)
+	if err != nil {
+		return "", fmt.Errorf("error registrando el pago del pedido %d: %w", pedido.ID, err)
+	}
+	return instrucciones.Message, nil
+}
+
+// ProcessAttachment atiende las fotos y PDFs que manda el cliente. Durante
+// un reporte de sello se adjuntan al caso; si no, se toman como comprobante
+// de la transferencia pendiente de su pedido más reciente.
+func (sm *StateMachine) ProcessAttachment(ctx context.Context, telefono, mediaURL, mimeType string) error {
+	mu := sm.getUserMutex(telefono)
+	mu.Lock()
+	defer mu.Unlock()
+
+	cliente, err := sm.store.GetClientePorTelefono(ctx, telefono)
+	if err != nil {
+		return fmt.Errorf("error buscando cliente: %w", err)
+	}
+	if cliente != nil && cliente.EstadoConversacion == EstadoEsperandoFotoSello {
+		return sm.adjuntarFotoSello(ctx, cliente, mediaURL, mimeType)
+	}
+	if cliente == nil || sm.pagos == nil {
+		return sm.sender.SendMessage(telefono, "Recibimos tu archivo, pero por este medio solo podemos atender mensajes de texto.")
+	}
+
+	pedido, err := sm.pagos.PendingTransfer(ctx, cliente.ID)
+	if err != nil {
+		return err
+	}
+	if pedido == nil {
+		return sm.sender.SendMessage(telefono, "Recibimos tu archivo, pero no tienes pedidos con pago por transferencia pendiente.")
+	}
+	if _, err := sm.pagos.SubmitReceipt(ctx, pedido, mediaURL, mimeType); err != nil {
+		if errors.Is(err, payments.ErrReceiptType) {
+			return sm.sender.SendMessage(telefono, "Solo podemos recibir comprobantes en foto (JPG o PNG) o en PDF. Por favor, envíalo de nuevo.")
+		}
+		sm.sender.SendMessage(telefono, "No pudimos recibir tu comprobante. Por favor, inténtalo de nuevo en unos minutos.")
+		return err
+	}
+	return sm.sender.SendMessage(telefono, fmt.Sprintf("Recibimos tu comprobante del pedido #%d. Lo verificaremos y te avisaremos en cuanto se confirme tu pago.", pedido.ID))
+}
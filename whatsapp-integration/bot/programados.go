@@ -0,0 +1,431 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"example.com/whatsapp-integration/store"
+)
+
+// Frecuencias de un pedido programado
+const (
+	FrecuenciaUnica   = "unica"
+	FrecuenciaSemanal = "semanal"
+	FrecuenciaMensual = "mensual"
+)
+
+const formatoFechaCliente = "02/01/2006"
+
+func (sm *StateMachine) handleProgramarFecha(ctx context.Context, telefono, mensaje string) error {
+	if sm.session.ClienteActual.EstadoConversacion != EstadoProgramandoFecha {
+		msg := "📅 ¿Para qué fecha quieres programar tu entrega?\n\nEscríbela en formato DD/MM/AAAA (ej. " +
+			sm.hoy().AddDate(0, 0, 1).Format(formatoFechaCliente) + ")."
+		if err := sm.sender.SendMessage(telefono, msg); err != nil {
+			return err
+		}
+		return sm.actualizarEstado(ctx, telefono, EstadoProgramandoFecha)
+	}
+
+	fecha, err := time.ParseInLocation(formatoFechaCliente, strings.TrimSpace(mensaje), sm.loc)
+	if err != nil {
+		sm.sender.SendMessage(telefono, "No entendí la fecha. Por favor, escríbela en formato DD/MM/AAAA.")
+		return nil
+	}
+	if !fecha.After(sm.hoy()) {
+		sm.sender.SendMessage(telefono, "La fecha debe ser a partir de mañana. Por favor, elige otra fecha.")
+		return nil
+	}
+
+	sm.guardarDatoTemp(telefono, "programado_fecha", fecha)
+	return sm.handleProgramarHorario(ctx, telefono, "")
+}
+
+func (sm *StateMachine) handleProgramarHorario(ctx context.Context, telefono, mensaje string) error {
+	if sm.session.ClienteActual.EstadoConversacion != EstadoProgramandoHorario {
+		msg := "¿En qué horario prefieres recibirlo?\n1. Mañana (9am - 1pm)\n2. Tarde (2pm - 6pm)"
+		if err := sm.sender.SendMessage(telefono, msg); err != nil {
+			return err
+		}
+		return sm.actualizarEstado(ctx, telefono, EstadoProgramandoHorario)
+	}
+
+	switch mensaje {
+	case "1":
+		sm.guardarDatoTemp(telefono, "programado_horario", "Mañana")
+	case "2":
+		sm.guardarDatoTemp(telefono, "programado_horario", "Tarde")
+	default:
+		sm.sender.SendMessage(telefono, "Opción no válida. Por favor, elige 1 para Mañana o 2 para Tarde.")
+		return nil
+	}
+	return sm.handleProgramarRecurrencia(ctx, telefono, "")
+}
+
+func (sm *StateMachine) handleProgramarRecurrencia(ctx context.Context, telefono, mensaje string) error {
+	fecha, _ := sm.datoTemp(telefono, "programado_fecha").(time.Time)
+
+	if sm.session.ClienteActual.EstadoConversacion != EstadoProgramandoRecurrencia {
+		msg := fmt.Sprintf("¿Con qué frecuencia quieres recibir este pedido?\n\n"+
+			"1. Solo el %s\n"+
+			"2. Cada cierto número de semanas\n"+
+			"3. Cada mes, el día %d",
+			fecha.Format(formatoFechaCliente), fecha.Day())
+		if err := sm.sender.SendMessage(telefono, msg); err != nil {
+			return err
+		}
+		return sm.actualizarEstado(ctx, telefono, EstadoProgramandoRecurrencia)
+	}
+
+	switch mensaje {
+	case "1":
+		return sm.guardarPedidoProgramado(ctx, telefono, FrecuenciaUnica, 0, 0)
+	case "2":
+		return sm.handleProgramarSemanas(ctx, telefono, "")
+	case "3":
+		return sm.guardarPedidoProgramado(ctx, telefono, FrecuenciaMensual, 0, fecha.Day())
+	default:
+		sm.sender.SendMessage(telefono, "Opción no válida. Por favor, elige 1, 2 o 3.")
+		return nil
+	}
+}
+
+func (sm *StateMachine) handleProgramarSemanas(ctx context.Context, telefono, mensaje string) error {
+	if sm.session.ClienteActual.EstadoConversacion != EstadoProgramandoSemanas {
+		msg := "¿Cada cuántas semanas quieres recibirlo? (escribe un número del 1 al 12)"
+		if err := sm.sender.SendMessage(telefono, msg); err != nil {
+			return err
+		}
+		return sm.actualizarEstado(ctx, telefono, EstadoProgramandoSemanas)
+	}
+
+	semanas, err := strconv.Atoi(strings.TrimSpace(mensaje))
+	if err != nil || semanas < 1 || semanas > 12 {
+		sm.sender.SendMessage(telefono, "Por favor, escribe un número de semanas entre 1 y 12.")
+		return nil
+	}
+	return sm.guardarPedidoProgramado(ctx, telefono, FrecuenciaSemanal, semanas, 0)
+}
+
+// guardarPedidoProgramado convierte el pedido en curso en una plantilla que el
+// scheduler materializa en la fecha elegida.
+func (sm *StateMachine) guardarPedidoProgramado(ctx context.Context, telefono, frecuencia string, semanas, diaMes int) error {
+	pedido := sm.session.PedidoEnCurso
+	fecha, _ := sm.datoTemp(telefono, "programado_fecha").(time.Time)
+	horario, _ := sm.datoTemp(telefono, "programado_horario").(string)
+
+	programado := &store.PedidoProgramado{
+		ClienteID:         sm.session.ClienteActual.ID,
+		TipoServicio:      pedido.TipoServicio,
+		CantidadLitros:    pedido.CantidadLitros,
+		CantidadCilindros: pedido.CantidadCilindros,
+		MetodoPago:        pedido.MetodoPago,
+		Direccion:         pedido.Direccion,
+		Latitud:           pedido.Latitud,
+		Longitud:          pedido.Longitud,
+		MapaURL:           pedido.MapaURL,
+		StreetViewURL:     pedido.StreetViewURL,
+		ColorFachada:      pedido.ColorFachada,
+		ColorPuerta:       pedido.ColorPuerta,
+		HorarioPreferido:  horario,
+		Frecuencia:        frecuencia,
+		IntervaloSemanas:  semanas,
+		DiaMes:            diaMes,
+		ProximaFecha:      fecha,
+		Activo:            true,
+	}
+	if err := sm.store.CrearPedidoProgramado(ctx, programado); err != nil {
+		return fmt.Errorf("error guardando pedido programado: %w", err)
+	}
+
+	msg := fmt.Sprintf("✅ *Entrega Programada*\n\nTu pedido llegará el %s en horario de %s (%s).\n\n"+
+		"La tarde anterior te enviaremos un recordatorio; si no lo necesitas, responde *OMITIR*.",
+		fecha.Format(formatoFechaCliente), strings.ToLower(horario), describirFrecuencia(programado))
+	sm.sender.SendMessage(telefono, msg)
+	// La plantilla no lleva descuentos ni horario elegido: cada entrega se
+	// cobra y se agenda al generarse.
+	sm.limpiarDescuentos(telefono)
+	sm.borrarDatosTemp(telefono, "programado_fecha", "programado_horario", "slot_elegido")
+	return sm.actualizarEstado(ctx, telefono, EstadoInicial)
+}
+
+// handleOmitirProgramado salta la siguiente entrega programada del cliente.
+func (sm *StateMachine) handleOmitirProgramado(ctx context.Context, telefono string) error {
+	cliente, err := sm.store.GetClientePorTelefono(ctx, telefono)
+	if err != nil {
+		return fmt.Errorf("error buscando cliente para omitir entrega: %w", err)
+	}
+	if cliente == nil {
+		return nil
+	}
+
+	programados, err := sm.store.GetPedidosProgramadosPorCliente(ctx, cliente.ID)
+	if err != nil {
+		return fmt.Errorf("error consultando pedidos programados: %w", err)
+	}
+
+	hoy := sm.hoy()
+	for _, p := range programados {
+		fecha := enZona(p.ProximaFecha, sm.loc)
+		if !fecha.After(hoy) || p.OmitirProxima {
+			continue
+		}
+
+		p.OmitirProxima = true
+		if err := sm.store.ActualizarPedidoProgramado(ctx, p); err != nil {
+			return fmt.Errorf("error omitiendo pedido programado %d: %w", p.ID, err)
+		}
+
+		msg := fmt.Sprintf("Listo, no realizaremos la entrega programada del %s.", fecha.Format(formatoFechaCliente))
+		if siguiente, ok := siguienteFechaProgramada(p, fecha); ok {
+			msg += fmt.Sprintf(" Tu siguiente entrega será el %s.", siguiente.Format(formatoFechaCliente))
+		}
+		sm.sender.SendMessage(telefono, msg)
+		return nil
+	}
+
+	sm.sender.SendMessage(telefono, "No tienes entregas programadas próximas para omitir.")
+	return nil
+}
+
+// EnviarRecordatoriosProgramados avisa la tarde anterior a cada cliente con una
+// entrega programada para el día siguiente y le ofrece omitirla.
+func (sm *StateMachine) EnviarRecordatoriosProgramados(ctx context.Context, ahora time.Time) error {
+	manana := inicioDelDia(ahora).AddDate(0, 0, 1)
+	programados, err := sm.store.GetPedidosProgramadosHasta(ctx, manana)
+	if err != nil {
+		return fmt.Errorf("error consultando pedidos programados: %w", err)
+	}
+
+	for _, p := range programados {
+		if !enZona(p.ProximaFecha, ahora.Location()).Equal(manana) || p.RecordatorioEnviado || p.OmitirProxima {
+			continue
+		}
+
+		cliente, err := sm.store.GetClientePorID(ctx, p.ClienteID)
+		if err != nil || cliente == nil {
+			fmt.Printf("No se encontró el cliente %d del pedido programado %d: %v\n", p.ClienteID, p.ID, err)
+			continue
+		}
+
+		msg := fmt.Sprintf("⏰ *Recordatorio*\n\nMañana %s entregaremos tu pedido programado (%s) en horario de %s.\n\n"+
+			"Si esta vez no lo necesitas, responde *OMITIR*.",
+			manana.Format(formatoFechaCliente), p.TipoServicio, strings.ToLower(p.HorarioPreferido))
+		if err := sm.sender.SendMessage(cliente.NumeroTelefono, msg); err != nil {
+			fmt.Printf("Error enviando recordatorio a %s: %v\n", cliente.NumeroTelefono, err)
+			continue
+		}
+
+		p.RecordatorioEnviado = true
+		if err := sm.store.ActualizarPedidoProgramado(ctx, p); err != nil {
+			return fmt.Errorf("error marcando recordatorio del pedido programado %d: %w", p.ID, err)
+		}
+	}
+	return nil
+}
+
+// MaterializarPedidosProgramados crea los Pedido en estado pendiente para las
+// entregas programadas del día. Debe correr antes del corte de las 5:00 AM.
+func (sm *StateMachine) MaterializarPedidosProgramados(ctx context.Context, ahora time.Time) error {
+	hoy := inicioDelDia(ahora)
+	programados, err := sm.store.GetPedidosProgramadosHasta(ctx, hoy)
+	if err != nil {
+		return fmt.Errorf("error consultando pedidos programados: %w", err)
+	}
+
+	for _, p := range programados {
+		if p.OmitirProxima {
+			fmt.Printf("Pedido programado %d omitido por el cliente para el %s\n", p.ID, p.ProximaFecha.Format("2006-01-02"))
+		} else if err := sm.materializarPedidoProgramado(ctx, p, hoy); err != nil {
+			fmt.Printf("Error materializando pedido programado %d: %v\n", p.ID, err)
+			continue
+		}
+
+		// Avanzar a la siguiente ocurrencia posterior a hoy.
+		fecha := enZona(p.ProximaFecha, ahora.Location())
+		activo := true
+		for !fecha.After(hoy) && activo {
+			fecha, activo = siguienteFechaProgramada(p, fecha)
+		}
+		p.ProximaFecha = fecha
+		p.Activo = activo
+		p.OmitirProxima = false
+		p.RecordatorioEnviado = false
+		if err := sm.store.ActualizarPedidoProgramado(ctx, p); err != nil {
+			return fmt.Errorf("error actualizando pedido programado %d: %w", p.ID, err)
+		}
+	}
+	return nil
+}
+
+func (sm *StateMachine) materializarPedidoProgramado(ctx context.Context, p *store.PedidoProgramado, hoy time.Time) error {
+	cliente, err := sm.store.GetClientePorID(ctx, p.ClienteID)
+	if err != nil {
+		return err
+	}
+	if cliente == nil {
+		return fmt.Errorf("cliente %d no encontrado", p.ClienteID)
+	}
+
+	pedido, motivo := construirPedidoRepetido(&store.Pedido{
+		TipoServicio:      p.TipoServicio,
+		CantidadLitros:    p.CantidadLitros,
+		CantidadCilindros: p.CantidadCilindros,
+		Direccion:         p.Direccion,
+		Latitud:           p.Latitud,
+		Longitud:          p.Longitud,
+		MapaURL:           p.MapaURL,
+		StreetViewURL:     p.StreetViewURL,
+		ColorFachada:      p.ColorFachada,
+		ColorPuerta:       p.ColorPuerta,
+	})
+	if pedido == nil {
+		sm.sender.SendMessage(cliente.NumeroTelefono,
+			fmt.Sprintf("No pudimos generar tu entrega programada de hoy: %s. Escríbenos para hacer un nuevo pedido.", motivo))
+		return fmt.Errorf("pedido programado %d no disponible: %s", p.ID, motivo)
+	}
+
+	pedido.ClienteID = p.ClienteID
+	pedido.HorarioPreferido = p.HorarioPreferido
+	if p.MetodoPago != "" {
+		pedido.MetodoPago = p.MetodoPago
+	}
+	if pedido.TipoServicio == "cilindro_recarga" {
+		pedido.Estado = EstadoPedidoPendienteRecoleccion
+	} else {
+		pedido.Estado = EstadoPedidoPendiente
+	}
+	aviso, err := sm.crearPedidoProgramado(ctx, pedido, hoy)
+	if err != nil {
+		return err
+	}
+	if pedido.ID == 0 {
+		sm.sender.SendMessage(cliente.NumeroTelefono,
+			"No hay cupo hoy para tu entrega programada. Volveremos a intentarlo mañana; si la necesitas antes, escríbenos.")
+		return fmt.Errorf("pedido programado %d sin cupo el %s", p.ID, hoy.Format("2006-01-02"))
+	}
+
+	// Tarjeta y transferencia pasan por el mismo cobro que el pedido interactivo.
+	instrucciones, err := sm.iniciarPago(ctx, pedido)
+	if err != nil {
+		return err
+	}
+
+	msg := fmt.Sprintf("📦 Tu entrega programada de hoy quedó registrada (pedido #%d). Total a pagar: $%.2f.",
+		pedido.ID, pedido.CantidadDinero)
+	if aviso != "" {
+		msg += "\n\n" + aviso
+	}
+	if instrucciones != "" {
+		msg += "\n\n" + instrucciones
+	}
+	sm.sender.SendMessage(cliente.NumeroTelefono, msg)
+	return nil
+}
+
+// crearPedidoProgramado guarda el pedido de una entrega programada. Con slots
+// reserva una ventana de hoy, de preferencia en el horario que eligió el
+// cliente, y regresa el aviso de cambio si se usó otra. Si ninguna ventana
+// tiene cupo no guarda el pedido y su ID queda en cero.
+func (sm *StateMachine) crearPedidoProgramado(ctx context.Context, pedido *store.Pedido, hoy time.Time) (string, error) {
+	if sm.slots == nil {
+		return "", sm.store.CrearPedido(ctx, pedido)
+	}
+
+	disponibles, err := sm.slots.DelDia(ctx, sm.zonaPedido(pedido), pedido.CantidadLitros, hoy)
+	if err != nil {
+		return "", fmt.Errorf("error consultando horarios del pedido programado: %w", err)
+	}
+	sort.SliceStable(disponibles, func(i, j int) bool {
+		return enHorario(disponibles[i].Ventana, pedido.HorarioPreferido) && !enHorario(disponibles[j].Ventana, pedido.HorarioPreferido)
+	})
+
+	preferido := pedido.HorarioPreferido
+	for _, slot := range disponibles {
+		pedido.HorarioPreferido = slot.Etiqueta()
+		err := sm.slots.CrearPedido(ctx, slot, pedido)
+		if errors.Is(err, store.ErrSlotSinCapacidad) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("error guardando pedido programado con su horario: %w", err)
+		}
+		if enHorario(slot.Ventana, preferido) {
+			return "", nil
+		}
+		return fmt.Sprintf("El horario de la %s ya estaba lleno; te asignamos %s-%s.",
+			strings.ToLower(preferido), slot.Ventana.HoraInicio, slot.Ventana.HoraFin), nil
+	}
+	pedido.HorarioPreferido = preferido
+	return "", nil
+}
+
+// enHorario indica si la ventana cae en el horario Mañana o Tarde que eligió
+// el cliente al programar. Sin horario cualquier ventana sirve.
+func enHorario(v *store.VentanaEntrega, horario string) bool {
+	switch horario {
+	case "Mañana":
+		return v.HoraInicio < "13:00"
+	case "Tarde":
+		return v.HoraInicio >= "13:00"
+	}
+	return true
+}
+
+// siguienteFechaProgramada calcula la ocurrencia posterior a fecha. Regresa
+// false cuando el pedido no se repite.
+func siguienteFechaProgramada(p *store.PedidoProgramado, fecha time.Time) (time.Time, bool) {
+	switch p.Frecuencia {
+	case FrecuenciaSemanal:
+		semanas := p.IntervaloSemanas
+		if semanas < 1 {
+			semanas = 1
+		}
+		return fecha.AddDate(0, 0, 7*semanas), true
+	case FrecuenciaMensual:
+		dia := p.DiaMes
+		if dia < 1 {
+			dia = fecha.Day()
+		}
+		// El día 0 del mes subsecuente es el último día del mes siguiente.
+		ultimoDia := time.Date(fecha.Year(), fecha.Month()+2, 0, 0, 0, 0, 0, fecha.Location()).Day()
+		if dia > ultimoDia {
+			dia = ultimoDia
+		}
+		return time.Date(fecha.Year(), fecha.Month()+1, dia, 0, 0, 0, 0, fecha.Location()), true
+	default:
+		return fecha, false
+	}
+}
+
+func describirFrecuencia(p *store.PedidoProgramado) string {
+	switch p.Frecuencia {
+	case FrecuenciaSemanal:
+		if p.IntervaloSemanas == 1 {
+			return "cada semana"
+		}
+		return fmt.Sprintf("cada %d semanas", p.IntervaloSemanas)
+	case FrecuenciaMensual:
+		return fmt.Sprintf("el día %d de cada mes", p.DiaMes)
+	default:
+		return "una sola vez"
+	}
+}
+
+func (sm *StateMachine) hoy() time.Time {
+	return inicioDelDia(time.Now().In(sm.loc))
+}
+
+func inicioDelDia(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// enZona reinterpreta una fecha leída de la base (medianoche UTC) como
+// medianoche en la zona horaria de operación.
+func enZona(fecha time.Time, loc *time.Location) time.Time {
+	return time.Date(fecha.Year(), fecha.Month(), fecha.Day(), 0, 0, 0, 0, loc)
+}
@@ -0,0 +1,122 @@
+package bot
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/whatsapp-integration/slots"
+	"example.com/whatsapp-integration/store"
+)
+
+// storeProgramar solo implementa lo que usa guardar una entrega programada; el
+// resto del Store queda nil y haría panic si se llamara.
+type storeProgramar struct {
+	store.Store
+	programados []*store.PedidoProgramado
+	estado      string
+}
+
+func (s *storeProgramar) CrearPedidoProgramado(ctx context.Context, p *store.PedidoProgramado) error {
+	s.programados = append(s.programados, p)
+	return nil
+}
+
+func (s *storeProgramar) ActualizarEstadoCliente(ctx context.Context, telefono, estado string) error {
+	s.estado = estado
+	return nil
+}
+
+// TestGuardarProgramadoLimpiaDatos no deja descuentos ni la fecha elegida para
+// el siguiente pedido del cliente.
+func TestGuardarProgramadoLimpiaDatos(t *testing.T) {
+	const telefono = "5215550000001"
+	st := &storeProgramar{}
+	sm := NewStateMachine(st, &senderPrueba{}, nil)
+	sm.session.ClienteActual = &store.Cliente{ID: 1, NumeroTelefono: telefono}
+	sm.session.PedidoEnCurso = &store.Pedido{TipoServicio: "estacionario", CantidadLitros: 100}
+	sm.guardarDatoTemp(telefono, "programado_fecha", time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC))
+	sm.guardarDatoTemp(telefono, "programado_horario", "Tarde")
+	sm.guardarDatoTemp(telefono, datoCanjearPuntos, true)
+	sm.guardarDatoTemp(telefono, datoCodigoPromocion, "BIENVENIDA")
+
+	if err := sm.guardarPedidoProgramado(context.Background(), telefono, FrecuenciaUnica, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if len(st.programados) != 1 || st.programados[0].HorarioPreferido != "Tarde" || st.estado != EstadoInicial {
+		t.Fatalf("programados = %+v, estado = %q", st.programados, st.estado)
+	}
+	for _, clave := range []string{"programado_fecha", "programado_horario", datoCanjearPuntos, datoCodigoPromocion} {
+		if v := sm.datoTemp(telefono, clave); v != nil {
+			t.Errorf("dato %q = %v, debe limpiarse", clave, v)
+		}
+	}
+}
+
+// TestMaterializarReservaSlot genera las entregas programadas en el horario
+// elegido, pasa a otra ventana del día si está llena y no crea el pedido si ya
+// no hay cupo.
+func TestMaterializarReservaSlot(t *testing.T) {
+	db, err := store.NewSQLiteStore(store.Config{Database: filepath.Join(t.TempDir(), "programados.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	hoy := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC) // lunes
+	for _, v := range []*store.VentanaEntrega{
+		{DiaSemana: 1, HoraInicio: "09:00", HoraFin: "13:00", Camiones: 1, MaxPedidosCamion: 1, Activo: true},
+		{DiaSemana: 1, HoraInicio: "14:00", HoraFin: "18:00", Camiones: 1, MaxPedidosCamion: 1, Activo: true},
+	} {
+		if err := db.CrearVentanaEntrega(ctx, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cliente := &store.Cliente{NumeroTelefono: "5215550000001", Nombre: "Ana"}
+	if err := db.CrearCliente(ctx, cliente); err != nil {
+		t.Fatal(err)
+	}
+
+	sender := &senderPrueba{}
+	sm := NewStateMachine(db, sender, nil)
+	sm.SetSlots(slots.NewService(db, time.UTC))
+	programado := &store.PedidoProgramado{ClienteID: cliente.ID, TipoServicio: "estacionario", CantidadLitros: 100, HorarioPreferido: "Mañana"}
+
+	casos := []struct {
+		nombre  string
+		horario string // etiqueta guardada en el pedido; vacía si no se creó
+		aviso   string
+	}{
+		{"entra en su horario", "Lun 02/03 09:00-13:00", "quedó registrada"},
+		{"mañana llena pasa a la tarde", "Lun 02/03 14:00-18:00", "ya estaba lleno; te asignamos 14:00-18:00"},
+		{"sin cupo no se crea", "", "No hay cupo hoy"},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			sender.mensajes = nil
+			err := sm.materializarPedidoProgramado(ctx, programado, hoy)
+			if len(sender.mensajes) != 1 || !strings.Contains(sender.mensajes[0], c.aviso) {
+				t.Fatalf("avisos = %q, se esperaba %q", sender.mensajes, c.aviso)
+			}
+			if c.horario == "" {
+				if err == nil {
+					t.Fatal("materializarPedidoProgramado() sin cupo debe regresar error para reintentar")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			pedido, err := db.GetUltimoPedidoActivo(ctx, cliente.ID)
+			if err != nil || pedido == nil || pedido.HorarioPreferido != c.horario {
+				t.Fatalf("pedido = %+v, %v; se esperaba el horario %q", pedido, err, c.horario)
+			}
+			if reserva, err := db.GetReservaSlot(ctx, pedido.ID); err != nil || reserva == nil {
+				t.Fatalf("reserva = %+v, %v; el pedido debe tener su slot", reserva, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"example.com/whatsapp-integration/promociones"
+	"example.com/whatsapp-integration/store"
+)
+
+// Claves de los datos temporales del código de promoción en el resumen del pedido.
+const (
+	datoCodigoPromocion    = "codigo_promocion"    // código que escribió el cliente
+	datoPromocion          = "promocion"           // *store.Promocion validada
+	datoDescuentoPromocion = "descuento_promocion" // descuento calculado en el último resumen
+)
+
+// patronCodigo reconoce un posible código de promoción: letras y números, sin
+// espacios, con al menos una letra para no confundirlo con una opción del menú.
+var patronCodigo = regexp.MustCompile(`^[A-Za-z0-9_-]*[A-Za-z][A-Za-z0-9_-]*$`)
+
+// SetPromociones habilita los códigos de promoción en el resumen del pedido.
+func (sm *StateMachine) SetPromociones(p *promociones.Servicio) {
+	sm.promociones = p
+}
+
+func pareceCodigo(mensaje string) bool {
+	mensaje = strings.TrimSpace(mensaje)
+	return len(mensaje) >= 3 && len(mensaje) <= 30 && patronCodigo.MatchString(mensaje)
+}
+
+// handleCodigoPromocion valida el código que el cliente escribió en el resumen
+// y, si aplica, vuelve a mostrar el resumen con el descuento.
+func (sm *StateMachine) handleCodigoPromocion(ctx context.Context, telefono, mensaje string) error {
+	codigo := strings.ToUpper(strings.TrimSpace(mensaje))
+	pedido := sm.session.PedidoEnCurso
+
+	// Aquí solo se valida; el monto final lo calcula el resumen después de
+	// los demás descuentos.
+	_, _, err := sm.promociones.Aplicar(ctx, codigo, sm.session.ClienteActual, pedido, sm.zonaPedido(pedido), pedido.CantidadDinero, time.Now())
+
+	var noAplica *promociones.ErrNoAplica
+	if errors.As(err, &noAplica) {
+		sm.sender.SendMessage(telefono, fmt.Sprintf("❌ No pudimos aplicar el código %s. %s\n\nPuedes escribir otro código o elegir una opción del resumen.", codigo, noAplica.Motivo))
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error validando código de promoción %s: %w", codigo, err)
+	}
+
+	sm.guardarDatoTemp(telefono, datoCodigoPromocion, codigo)
+	return sm.handleConfirmacionFinal(ctx, telefono)
+}
+
+// lineaPromocion recalcula el descuento del código elegido sobre lo que queda
+// por pagar. Si el código dejó de aplicar se descarta.
+func (sm *StateMachine) lineaPromocion(ctx context.Context, telefono string, pedido *store.Pedido, restante float64) *lineaDescuento {
+	sm.borrarDatosTemp(telefono, datoPromocion, datoDescuentoPromocion)
+	codigo, _ := sm.datoTemp(telefono, datoCodigoPromocion).(string)
+	if sm.promociones == nil || codigo == "" {
+		return nil
+	}
+
+	promo, descuento, err := sm.promociones.Aplicar(ctx, codigo, sm.session.ClienteActual, pedido, sm.zonaPedido(pedido), restante, time.Now())
+	if err != nil {
+		fmt.Printf("Código %s descartado para el cliente %d: %v\n", codigo, sm.session.ClienteActual.ID, err)
+		sm.borrarDatosTemp(telefono, datoCodigoPromocion)
+		return nil
+	}
+	sm.guardarDatoTemp(telefono, datoPromocion, promo)
+	sm.guardarDatoTemp(telefono, datoDescuentoPromocion, descuento)
+	return &lineaDescuento{concepto: fmt.Sprintf("Código %s", promo.Codigo), monto: descuento}
+}
+
+// registrarPromocion liga el código aplicado con el pedido recién guardado.
+func (sm *StateMachine) registrarPromocion(ctx context.Context, telefono string, pedido *store.Pedido) error {
+	promo, _ := sm.datoTemp(telefono, datoPromocion).(*store.Promocion)
+	descuento, _ := sm.datoTemp(telefono, datoDescuentoPromocion).(float64)
+	if sm.promociones == nil || promo == nil {
+		return nil
+	}
+	if err := sm.promociones.RegistrarUso(ctx, promo, pedido, descuento); err != nil {
+		return fmt.Errorf("error registrando código %s en el pedido %d: %w", promo.Codigo, pedido.ID, err)
+	}
+	return nil
+}
+
+// limpiarDescuentos olvida los puntos y el código elegidos al terminar o
+// iniciar un resumen.
+func (sm *StateMachine) limpiarDescuentos(telefono string) {
+	sm.borrarDatosTemp(telefono, datoCanjearPuntos, datoPuntosCanje, datoCodigoPromocion, datoPromocion, datoDescuentoPromocion)
+}
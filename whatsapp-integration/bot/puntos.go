@@ -0,0 +1,112 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+
+	"example.com/whatsapp-integration/puntos"
+	"example.com/whatsapp-integration/store"
+)
+
+// Claves de los datos temporales del canje de puntos en el resumen del pedido.
+const (
+	datoCanjearPuntos = "canjear_puntos" // el cliente eligió usar sus puntos
+	datoPuntosCanje   = "puntos_canje"   // puntos a descontar al confirmar
+)
+
+// maxMovimientosPuntos limita el historial que se envía por WhatsApp.
+const maxMovimientosPuntos = 10
+
+// SetPuntos habilita el monedero de puntos: el comando PUNTOS y el canje en el
+// resumen del pedido.
+func (sm *StateMachine) SetPuntos(p *puntos.Servicio) {
+	sm.puntos = p
+}
+
+// handlePuntos responde el comando global PUNTOS con el saldo y los últimos
+// movimientos.
+func (sm *StateMachine) handlePuntos(ctx context.Context, telefono string) error {
+	if sm.puntos == nil {
+		sm.sender.SendMessage(telefono, "Por ahora no contamos con programa de puntos.")
+		return nil
+	}
+	cliente, err := sm.store.GetClientePorTelefono(ctx, telefono)
+	if err != nil {
+		return fmt.Errorf("error buscando cliente para consultar puntos: %w", err)
+	}
+	if cliente == nil {
+		sm.sender.SendMessage(telefono, "No pudimos encontrar tus datos. Por favor, inicia la conversación para registrarte.")
+		return nil
+	}
+
+	saldo, err := sm.puntos.Saldo(ctx, cliente.ID)
+	if err != nil {
+		return err
+	}
+	movimientos, err := sm.puntos.Historial(ctx, cliente.ID, maxMovimientosPuntos)
+	if err != nil {
+		return err
+	}
+
+	cfg := sm.puntos.Config()
+	msg := fmt.Sprintf("⭐ *Tus puntos*\n\nSaldo: *%d puntos* (equivalen a $%.2f)\n", saldo, float64(saldo)*cfg.ValorPunto)
+	if saldo < cfg.MinimoCanje {
+		msg += fmt.Sprintf("Puedes canjearlos a partir de %d puntos.\n", cfg.MinimoCanje)
+	} else {
+		msg += "Puedes usarlos como descuento al confirmar tu próximo pedido.\n"
+	}
+	if len(movimientos) > 0 {
+		msg += "\n*Últimos movimientos:*\n"
+		for _, m := range movimientos {
+			msg += fmt.Sprintf("%s  %+d  %s\n", m.CreatedAt.In(sm.loc).Format("02/01/2006"), m.Puntos, m.Descripcion)
+		}
+	}
+	sm.sender.SendMessage(telefono, msg)
+	return nil
+}
+
+// lineaPuntos calcula el descuento por puntos sobre lo que queda por pagar
+// después de los demás descuentos. Solo aplica si el cliente eligió canjear.
+func (sm *StateMachine) lineaPuntos(ctx context.Context, telefono string, restante float64) *lineaDescuento {
+	sm.borrarDatosTemp(telefono, datoPuntosCanje)
+	if sm.puntos == nil || sm.datoTemp(telefono, datoCanjearPuntos) != true {
+		return nil
+	}
+	saldo, err := sm.puntos.Saldo(ctx, sm.session.ClienteActual.ID)
+	if err != nil {
+		fmt.Printf("Error consultando saldo de puntos del cliente %d: %v\n", sm.session.ClienteActual.ID, err)
+		return nil
+	}
+	usados, descuento := sm.puntos.Descuento(saldo, restante)
+	if usados == 0 {
+		return nil
+	}
+	sm.guardarDatoTemp(telefono, datoPuntosCanje, usados)
+	return &lineaDescuento{concepto: fmt.Sprintf("Puntos canjeados (%d)", usados), monto: descuento}
+}
+
+// puntosCanjeables regresa el saldo si alcanza para ofrecer el canje y el
+// cliente todavía no lo eligió.
+func (sm *StateMachine) puntosCanjeables(ctx context.Context, telefono string) int {
+	if sm.puntos == nil || sm.datoTemp(telefono, datoCanjearPuntos) == true {
+		return 0
+	}
+	saldo, err := sm.puntos.Saldo(ctx, sm.session.ClienteActual.ID)
+	if err != nil || saldo <= 0 || saldo < sm.puntos.Config().MinimoCanje {
+		return 0
+	}
+	return saldo
+}
+
+// canjearPuntos registra el canje elegido en el resumen una vez que el pedido
+// ya tiene ID.
+func (sm *StateMachine) canjearPuntos(ctx context.Context, telefono string, pedido *store.Pedido) error {
+	usados, _ := sm.datoTemp(telefono, datoPuntosCanje).(int)
+	if sm.puntos == nil || usados == 0 {
+		return nil
+	}
+	if err := sm.puntos.Canjear(ctx, pedido, usados); err != nil {
+		return fmt.Errorf("error canjeando puntos del pedido %d: %w", pedido.ID, err)
+	}
+	return nil
+}
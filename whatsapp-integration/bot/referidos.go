@@ -0,0 +1,77 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+
+	"example.com/whatsapp-integration/referidos"
+	"example.com/whatsapp-integration/store"
+)
+
+// SetReferidos habilita el comando INVITAR y la atribución de clientes nuevos
+// que llegan con un código de referido.
+func (sm *StateMachine) SetReferidos(r *referidos.Servicio) {
+	sm.referidos = r
+}
+
+// handleInvitar responde el comando global INVITAR con el código y la liga
+// personal del cliente.
+func (sm *StateMachine) handleInvitar(ctx context.Context, telefono string) error {
+	if sm.referidos == nil {
+		sm.sender.SendMessage(telefono, "Por ahora no contamos con programa de invitaciones.")
+		return nil
+	}
+	cliente, err := sm.store.GetClientePorTelefono(ctx, telefono)
+	if err != nil {
+		return fmt.Errorf("error buscando cliente para invitar: %w", err)
+	}
+	if cliente == nil {
+		sm.sender.SendMessage(telefono, "No pudimos encontrar tus datos. Por favor, inicia la conversación para registrarte.")
+		return nil
+	}
+
+	codigo, err := sm.referidos.Codigo(ctx, cliente.ID)
+	if err != nil {
+		return err
+	}
+	invitados, recompensados, err := sm.referidos.Resumen(ctx, cliente.ID)
+	if err != nil {
+		return err
+	}
+
+	cfg := sm.referidos.Config()
+	msg := fmt.Sprintf("🤝 *Invita y gana*\n\nTu código de invitación es *%s*.\n", codigo)
+	if liga := sm.referidos.Liga(codigo); liga != "" {
+		msg += fmt.Sprintf("Comparte esta liga; el mensaje ya lleva tu código:\n%s\n", liga)
+	} else {
+		msg += "Pide a tus conocidos que nos escriban con tu código en su primer mensaje.\n"
+	}
+	msg += fmt.Sprintf("\nCuando reciban su primer pedido, tú ganas %d puntos y ellos %d.", cfg.PuntosReferente, cfg.PuntosReferido)
+	if invitados > 0 {
+		msg += fmt.Sprintf("\n\nHas invitado a %d persona(s); %d ya recibieron su primer pedido.", invitados, recompensados)
+	}
+	sm.sender.SendMessage(telefono, msg)
+	return nil
+}
+
+// atribuirReferido busca un código de referido en el primer mensaje del
+// cliente nuevo. Regresa el saludo adicional para el cliente, o "".
+func (sm *StateMachine) atribuirReferido(ctx context.Context, cliente *store.Cliente, mensaje string) string {
+	if sm.referidos == nil {
+		return ""
+	}
+	referente, err := sm.referidos.Atribuir(ctx, cliente, mensaje)
+	if err != nil {
+		fmt.Printf("Error registrando referido del cliente %d: %v\n", cliente.ID, err)
+		return ""
+	}
+	if referente == nil {
+		return ""
+	}
+	cfg := sm.referidos.Config()
+	saludo := "Vienes invitado por uno de nuestros clientes"
+	if referente.Nombre != "" {
+		saludo = fmt.Sprintf("Vienes invitado por %s", referente.Nombre)
+	}
+	return fmt.Sprintf("%s. Con tu primer pedido entregado recibirás %d puntos de bienvenida. 🎁\n\n", saludo, cfg.PuntosReferido)
+}
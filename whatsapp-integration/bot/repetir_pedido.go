@@ -0,0 +1,131 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"example.com/whatsapp-integration/store"
+)
+
+// datoRepetirCilindro guarda el pedido anterior de tipo "cilindro" mientras el
+// cliente elige si lo repite como recarga o como canje.
+const datoRepetirCilindro = "repetir_cilindro"
+
+// handleRepetirPedido reconstruye el último pedido del cliente con los precios
+// vigentes y lo lleva por la confirmación de dirección, el horario Premium y el
+// resumen final, igual que un pedido nuevo.
+func (sm *StateMachine) handleRepetirPedido(ctx context.Context, telefono string, anterior *store.Pedido) error {
+	// Los pedidos anteriores a recarga y canje solo decían "cilindro": se
+	// pregunta cuál de los dos repetir y se conserva la cantidad.
+	if anterior.TipoServicio == "cilindro" {
+		sm.guardarDatoTemp(telefono, datoRepetirCilindro, anterior)
+		sm.sender.SendMessage(telefono, fmt.Sprintf("Tu pedido anterior fue de %d cilindro(s).", anterior.CantidadCilindros))
+		return sm.handleCilindroOpcion(ctx, telefono, "")
+	}
+
+	nuevoPedido, motivo := construirPedidoRepetido(anterior)
+	if nuevoPedido == nil {
+		msg := fmt.Sprintf("Lo sentimos, no es posible repetir tu pedido anterior: %s.\n\nVamos a armar uno nuevo.", motivo)
+		sm.sender.SendMessage(telefono, msg)
+		return sm.handleTipoServicio(ctx, telefono, "")
+	}
+
+	nuevoPedido.ClienteID = sm.session.ClienteActual.ID
+	sm.session.PedidoEnCurso = nuevoPedido
+
+	var detalle string
+	if esCilindro(nuevoPedido.TipoServicio) {
+		detalle = fmt.Sprintf("  - *Cantidad:* %d cilindro(s)\n  - *Precio por Cilindro:* $%.2f\n",
+			nuevoPedido.CantidadCilindros, nuevoPedido.PrecioUnitario)
+	} else {
+		detalle = fmt.Sprintf("  - *Cantidad:* %.0f Lts\n  - *Precio por Litro:* $%.2f\n",
+			nuevoPedido.CantidadLitros, nuevoPedido.PrecioUnitario)
+	}
+	msg := fmt.Sprintf(
+		"🔁 *Repetir Pedido*\n\n"+
+			"Preparamos tu pedido con los precios actualizados:\n\n"+
+			"  - *Servicio:* %s\n"+
+			"%s"+
+			"  - *Total:* $%.2f",
+		nuevoPedido.TipoServicio,
+		detalle,
+		nuevoPedido.CantidadDinero,
+	)
+	if anterior.PrecioUnitario > 0 && anterior.PrecioUnitario != nuevoPedido.PrecioUnitario {
+		msg += fmt.Sprintf("\n\n_El precio cambió desde tu último pedido ($%.2f)._", anterior.PrecioUnitario)
+	}
+	if err := sm.sender.SendMessage(telefono, msg); err != nil {
+		return err
+	}
+
+	// Sin dirección guardada hay que pedirla; si existe, solo se confirma.
+	if strings.TrimSpace(nuevoPedido.Direccion) == "" {
+		return sm.handleDireccion(ctx, telefono, "")
+	}
+	return sm.handleConfirmacionDireccion(ctx, telefono, "")
+}
+
+// construirPedidoRepetido arma un pedido nuevo a partir de las cantidades del
+// pedido anterior y los precios actuales. Si el servicio ya no se ofrece o no
+// tiene precio vigente regresa nil junto con el motivo para el cliente.
+func construirPedidoRepetido(anterior *store.Pedido) (*store.Pedido, string) {
+	precio, ok := precioActual(anterior.TipoServicio)
+	if !ok {
+		return nil, fmt.Sprintf("el servicio \"%s\" ya no está disponible", anterior.TipoServicio)
+	}
+
+	nuevo := &store.Pedido{
+		TipoServicio:           anterior.TipoServicio,
+		Direccion:              anterior.Direccion,
+		Latitud:                anterior.Latitud,
+		Longitud:               anterior.Longitud,
+		MapaURL:                anterior.MapaURL,
+		StreetViewURL:          anterior.StreetViewURL,
+		RequiereRevisionManual: anterior.RequiereRevisionManual,
+		ColorFachada:           anterior.ColorFachada,
+		ColorPuerta:            anterior.ColorPuerta,
+		CodigoRojo:             anterior.CodigoRojo,
+		MetodoPago:             "efectivo",
+		PrecioUnitario:         precio,
+	}
+
+	if esCilindro(anterior.TipoServicio) {
+		if anterior.CantidadCilindros <= 0 {
+			return nil, "no encontramos cuántos cilindros pediste"
+		}
+		nuevo.CantidadCilindros = anterior.CantidadCilindros
+		nuevo.CantidadDinero = float64(nuevo.CantidadCilindros) * precio
+		return nuevo, ""
+	}
+
+	litros := anterior.CantidadLitros
+	if litros <= 0 && anterior.CantidadDinero > 0 && anterior.PrecioUnitario > 0 {
+		// Pedidos antiguos por monto pueden no tener los litros registrados.
+		litros = anterior.CantidadDinero / anterior.PrecioUnitario
+	}
+	if litros <= 0 {
+		return nil, "no encontramos la cantidad de litros de tu pedido anterior"
+	}
+	nuevo.CantidadLitros = litros
+	nuevo.CantidadDinero = litros * precio
+	return nuevo, ""
+}
+
+// precioActual regresa el precio unitario vigente para un tipo de servicio.
+func precioActual(tipoServicio string) (float64, bool) {
+	var precio float64
+	switch tipoServicio {
+	case "estacionario":
+		precio = getPrecioGasLitro()
+	case "cilindro_recarga", "cilindro_canje":
+		precio = getPrecioCilindro()
+	default:
+		return 0, false
+	}
+	return precio, precio > 0
+}
+
+func esCilindro(tipoServicio string) bool {
+	return strings.HasPrefix(tipoServicio, "cilindro")
+}
@@ -0,0 +1,66 @@
+package bot
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"example.com/whatsapp-integration/store"
+)
+
+// storeRepetir solo implementa lo que usa repetir un pedido; el resto del
+// Store queda nil y haría panic si se llamara.
+type storeRepetir struct {
+	store.Store
+	estado string
+}
+
+func (s *storeRepetir) ActualizarEstadoCliente(ctx context.Context, telefono, estado string) error {
+	s.estado = estado
+	return nil
+}
+
+// TestRepetirCilindroLegado pregunta si el pedido "cilindro" anterior era
+// recarga o canje y lo repite con la misma cantidad.
+func TestRepetirCilindroLegado(t *testing.T) {
+	t.Setenv("PRECIO_CILINDRO", "450")
+	const telefono = "5215550000001"
+	anterior := &store.Pedido{TipoServicio: "cilindro", CantidadCilindros: 2, Direccion: "Calle 1"}
+
+	casos := []struct {
+		nombre string
+		opcion string
+		tipo   string
+	}{
+		{"recarga", "1", "cilindro_recarga"},
+		{"canje", "2", "cilindro_canje"},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			st := &storeRepetir{}
+			sender := &senderPrueba{}
+			sm := NewStateMachine(st, sender, nil)
+			sm.session.ClienteActual = &store.Cliente{ID: 1, NumeroTelefono: telefono}
+
+			ctx := context.Background()
+			if err := sm.handleRepetirPedido(ctx, telefono, anterior); err != nil {
+				t.Fatal(err)
+			}
+			if st.estado != EstadoCilindroOpcion || !strings.Contains(strings.Join(sender.mensajes, "\n"), "2 cilindro(s)") {
+				t.Fatalf("estado = %q, avisos = %q; se esperaba la pregunta de recarga o canje", st.estado, sender.mensajes)
+			}
+
+			sm.session.ClienteActual.EstadoConversacion = st.estado
+			if err := sm.handleCilindroOpcion(ctx, telefono, c.opcion); err != nil {
+				t.Fatal(err)
+			}
+			pedido := sm.session.PedidoEnCurso
+			if pedido == nil || pedido.TipoServicio != c.tipo || pedido.CantidadCilindros != 2 || pedido.CantidadDinero != 900 {
+				t.Fatalf("pedido = %+v, se esperaban 2 cilindros de %s por $900", pedido, c.tipo)
+			}
+			if st.estado != EstadoConfirmandoDireccion || sm.datoTemp(telefono, datoRepetirCilindro) != nil {
+				t.Fatalf("estado = %q; se esperaba confirmar la dirección sin datos pendientes", st.estado)
+			}
+		})
+	}
+}
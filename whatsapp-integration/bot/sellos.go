@@ -0,0 +1,152 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"example.com/whatsapp-integration/sellos"
+	"example.com/whatsapp-integration/store"
+)
+
+// instruccionNoPago es lo que el cliente le muestra al repartidor cuando el
+// sello está violado.
+const instruccionNoPago = "Recuerda que al no aceptar el pedido por un sello violado, *no debes realizar el pago*. Por favor, muestra este mensaje a nuestro repartidor como confirmación."
+
+// SetSellos comparte el servicio de casos con la API de administración, que
+// es la que los resuelve y avisa al cliente.
+func (sm *StateMachine) SetSellos(s *sellos.Servicio) {
+	sm.sellos = s
+}
+
+// handleReporteSello abre el caso. Con "REPORTAR SELLO" es un sello violado;
+// si no, es otro problema con la entrega.
+func (sm *StateMachine) handleReporteSello(ctx context.Context, telefono, mensaje string) error {
+	cliente, err := sm.store.GetClientePorTelefono(ctx, telefono)
+	if err != nil {
+		return fmt.Errorf("error buscando cliente para el reporte: %w", err)
+	}
+	if cliente == nil {
+		sm.sender.SendMessage(telefono, "No pudimos encontrar tus datos. Por favor, inicia la conversación para registrarte.")
+		return nil
+	}
+
+	tipo := sellos.TipoOtro
+	if strings.Contains(strings.ToUpper(mensaje), "REPORTAR SELLO") {
+		tipo = sellos.TipoSelloViolado
+	}
+	ahora := time.Now()
+	reporte, err := sm.sellos.Abrir(ctx, cliente, tipo, strings.TrimSpace(mensaje), ahora)
+	if err != nil {
+		sm.sender.SendMessage(telefono, "No pudimos registrar tu reporte. Por favor, inténtalo de nuevo en unos minutos.")
+		return err
+	}
+
+	msg := fmt.Sprintf("⚠️ *Reporte #%d recibido*\n\n", reporte.ID)
+	if reporte.PedidoID != nil {
+		msg += fmt.Sprintf("Tu caso quedó registrado para el pedido #%d con prioridad alta.\n", *reporte.PedidoID)
+	} else {
+		msg += "Tu caso quedó registrado con prioridad alta.\n"
+	}
+	msg += fmt.Sprintf("Un supervisor lo revisará y te responderá a más tardar %s.", sm.textoVencimiento(*reporte.VenceSLA, ahora))
+	if err := sm.sender.SendMessage(telefono, msg); err != nil {
+		return err
+	}
+
+	if tipo == sellos.TipoSelloViolado {
+		if err := sm.sender.SendMessage(telefono, instruccionNoPago); err != nil {
+			return err
+		}
+		sm.NotificarAlertaARepartidor(ctx, cliente, reporte)
+	}
+
+	if err := sm.sender.SendMessage(telefono, "¿Deseas enviar fotos para adjuntar al reporte?\n1. Sí\n2. No"); err != nil {
+		return err
+	}
+	return sm.actualizarEstado(ctx, telefono, EstadoEsperandoFotoSello)
+}
+
+// handleDetalleReporte agrega lo que cuenta el cliente al caso que se le
+// acaba de abrir, por ejemplo al negar que recibió su pedido. Si ya no hay
+// un caso abierto, abre uno nuevo.
+func (sm *StateMachine) handleDetalleReporte(ctx context.Context, telefono, mensaje string) error {
+	cliente, err := sm.store.GetClientePorTelefono(ctx, telefono)
+	if err != nil {
+		return fmt.Errorf("error buscando cliente para el reporte: %w", err)
+	}
+	if cliente == nil {
+		return sm.handleReporteSello(ctx, telefono, mensaje)
+	}
+	reporte, err := sm.store.GetUltimoReporteSello(ctx, cliente.ID)
+	if err != nil {
+		return err
+	}
+	if reporte == nil {
+		return sm.handleReporteSello(ctx, telefono, mensaje)
+	}
+
+	if _, err := sm.sellos.Detallar(ctx, reporte.ID, mensaje); err != nil {
+		if errors.Is(err, sellos.ErrReporteCerrado) {
+			return sm.handleReporteSello(ctx, telefono, mensaje)
+		}
+		sm.sender.SendMessage(telefono, "No pudimos guardar tu mensaje. Por favor, inténtalo de nuevo en unos minutos.")
+		return err
+	}
+	msg := fmt.Sprintf("Agregamos tu mensaje al reporte #%d.\n\n¿Deseas enviar fotos para adjuntar al reporte?\n1. Sí\n2. No", reporte.ID)
+	if err := sm.sender.SendMessage(telefono, msg); err != nil {
+		return err
+	}
+	return sm.actualizarEstado(ctx, telefono, EstadoEsperandoFotoSello)
+}
+
+// handleFotoSello atiende el texto mientras el cliente puede mandar fotos;
+// las fotos mismas llegan por ProcessAttachment.
+func (sm *StateMachine) handleFotoSello(ctx context.Context, telefono, mensaje string) error {
+	switch strings.ToUpper(strings.TrimSpace(mensaje)) {
+	case "1", "SI", "SÍ":
+		return sm.sender.SendMessage(telefono, "Por favor, envía las fotos del sello o del tanque. Cuando termines, escribe LISTO.")
+	case "2", "NO", "LISTO":
+		sm.sender.SendMessage(telefono, "Gracias. Te avisaremos por este medio en cuanto tu caso quede resuelto.")
+		return sm.actualizarEstado(ctx, telefono, EstadoInicial)
+	default:
+		return sm.sender.SendMessage(telefono, "Envía la foto como imagen o escribe LISTO para terminar tu reporte.")
+	}
+}
+
+// adjuntarFotoSello agrega la foto al último reporte del cliente.
+func (sm *StateMachine) adjuntarFotoSello(ctx context.Context, cliente *store.Cliente, mediaURL, mimeType string) error {
+	telefono := cliente.NumeroTelefono
+	reporte, err := sm.store.GetUltimoReporteSello(ctx, cliente.ID)
+	if err != nil {
+		return err
+	}
+	if reporte == nil {
+		sm.sender.SendMessage(telefono, "No encontramos tu reporte. Escribe REPORTAR SELLO para registrarlo.")
+		return sm.actualizarEstado(ctx, telefono, EstadoInicial)
+	}
+
+	if _, err := sm.sellos.AgregarFoto(ctx, reporte.ID, mediaURL, mimeType); err != nil {
+		switch {
+		case errors.Is(err, sellos.ErrTipoFoto):
+			return sm.sender.SendMessage(telefono, "Solo podemos recibir fotos (JPG o PNG). Por favor, envíala de nuevo.")
+		case errors.Is(err, sellos.ErrReporteCerrado):
+			sm.sender.SendMessage(telefono, fmt.Sprintf("Tu reporte #%d ya fue cerrado, así que no pudimos agregar la foto.", reporte.ID))
+			return sm.actualizarEstado(ctx, telefono, EstadoInicial)
+		}
+		sm.sender.SendMessage(telefono, "No pudimos recibir tu foto. Por favor, inténtalo de nuevo en unos minutos.")
+		return err
+	}
+	return sm.sender.SendMessage(telefono, fmt.Sprintf("Foto agregada a tu reporte #%d. Puedes enviar otra o escribir LISTO para terminar.", reporte.ID))
+}
+
+// textoVencimiento es el plazo del SLA como se le dice al cliente.
+func (sm *StateMachine) textoVencimiento(vence, ahora time.Time) string {
+	vence = vence.In(sm.loc)
+	ahora = ahora.In(sm.loc)
+	if vence.YearDay() == ahora.YearDay() && vence.Year() == ahora.Year() {
+		return "hoy a las " + vence.Format("15:04")
+	}
+	return "el " + vence.Format("02/01/2006") + " a las " + vence.Format("15:04")
+}
@@ -0,0 +1,192 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"example.com/whatsapp-integration/delivery"
+	"example.com/whatsapp-integration/slots"
+	"example.com/whatsapp-integration/store"
+	"example.com/whatsapp-integration/zonas"
+)
+
+// maxSlotsOfrecidos limita la lista de horarios que se envía por WhatsApp.
+const maxSlotsOfrecidos = 6
+
+// SetSlots habilita la selección de horarios con capacidad. Sin servicio de
+// slots el bot conserva el horario Mañana/Tarde para los niveles que lo tienen
+// como beneficio.
+func (sm *StateMachine) SetSlots(s *slots.Service) {
+	sm.slots = s
+}
+
+// continuarDespuesDeDireccion sigue una vez confirmada la dirección: con la
+// zona ya conocida se elige el método de pago.
+func (sm *StateMachine) continuarDespuesDeDireccion(ctx context.Context, telefono string) error {
+	sm.borrarDatosTemp(telefono, "slot_elegido")
+	sm.limpiarDescuentos(telefono)
+	return sm.handlePago(ctx, telefono, "")
+}
+
+// continuarDespuesDePago decide el siguiente paso una vez elegido el método de
+// pago: elegir horario (slot o beneficio de nivel) o ir directo al resumen.
+func (sm *StateMachine) continuarDespuesDePago(ctx context.Context, telefono string) error {
+	if sm.slots != nil {
+		return sm.handleSeleccionSlot(ctx, telefono, "")
+	}
+	if sm.beneficios().ElegirHorario {
+		return sm.handleHorarioPremium(ctx, telefono, "")
+	}
+	return sm.handleConfirmacionFinal(ctx, telefono)
+}
+
+func (sm *StateMachine) handleSeleccionSlot(ctx context.Context, telefono, mensaje string) error {
+	if sm.session.ClienteActual.EstadoConversacion != EstadoEsperandoSlot {
+		pedido := sm.session.PedidoEnCurso
+		disponibles, err := sm.slots.Disponibles(ctx, sm.zonaPedido(pedido), pedido.CantidadLitros, time.Now())
+		if err != nil {
+			return fmt.Errorf("error consultando horarios disponibles: %w", err)
+		}
+		if len(disponibles) == 0 {
+			sm.sender.SendMessage(telefono, "Por ahora no hay horarios con cupo en los próximos días. Te entregaremos en el primer espacio disponible.")
+			return sm.handleConfirmacionFinal(ctx, telefono)
+		}
+		if len(disponibles) > maxSlotsOfrecidos {
+			disponibles = disponibles[:maxSlotsOfrecidos]
+		}
+		sm.guardarDatoTemp(telefono, "slots_ofrecidos", disponibles)
+
+		var b strings.Builder
+		b.WriteString("🕒 Elige el horario de entrega:\n")
+		for i, slot := range disponibles {
+			fmt.Fprintf(&b, "\n%d. %s", i+1, slot.Etiqueta())
+		}
+		if err := sm.sender.SendMessage(telefono, b.String()); err != nil {
+			return err
+		}
+		return sm.actualizarEstado(ctx, telefono, EstadoEsperandoSlot)
+	}
+
+	ofrecidos, _ := sm.datoTemp(telefono, "slots_ofrecidos").([]slots.Slot)
+	opcion, err := strconv.Atoi(strings.TrimSpace(mensaje))
+	if err != nil || opcion < 1 || opcion > len(ofrecidos) {
+		sm.sender.SendMessage(telefono, fmt.Sprintf("Opción no válida. Por favor, elige un número del 1 al %d.", len(ofrecidos)))
+		return nil
+	}
+
+	elegido := ofrecidos[opcion-1]
+	sm.guardarDatoTemp(telefono, "slot_elegido", elegido)
+	sm.session.PedidoEnCurso.HorarioPreferido = elegido.Etiqueta()
+	return sm.handleConfirmacionFinal(ctx, telefono)
+}
+
+// crearPedido guarda el pedido y, si el cliente eligió horario, su reserva en
+// la misma transacción. Regresa false sin guardar nada si el horario se llenó
+// mientras el cliente confirmaba.
+func (sm *StateMachine) crearPedido(ctx context.Context, telefono string, pedido *store.Pedido) (bool, error) {
+	elegido, ok := sm.datoTemp(telefono, "slot_elegido").(slots.Slot)
+	if sm.slots == nil || !ok {
+		if err := sm.store.CrearPedido(ctx, pedido); err != nil {
+			return false, fmt.Errorf("error al guardar el pedido en la base de datos: %w", err)
+		}
+		return true, nil
+	}
+
+	err := sm.slots.CrearPedido(ctx, elegido, pedido)
+	if errors.Is(err, store.ErrSlotSinCapacidad) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error al guardar el pedido con su horario: %w", err)
+	}
+	sm.borrarDatosTemp(telefono, "slot_elegido")
+	return true, nil
+}
+
+// SetZonas habilita las ventanas de entrega por zona.
+func (sm *StateMachine) SetZonas(z *zonas.Mapa) {
+	sm.zonas = z
+}
+
+// zonaPedido regresa la zona de reparto del pedido. Sin zonas definidas, o si
+// el pedido no tiene coordenadas, se usan las ventanas generales.
+func (sm *StateMachine) zonaPedido(pedido *store.Pedido) string {
+	if pedido.Latitud == 0 && pedido.Longitud == 0 {
+		return ""
+	}
+	return sm.zonas.Zona(pedido.Latitud, pedido.Longitud)
+}
+
+// EstadoPedidos cambia el estado de un pedido y avisa a los observadores
+// (pagos, puntos, referidos) y al cliente. Lo implementa delivery.MapsService.
+type EstadoPedidos interface {
+	UpdateDeliveryStatus(ctx context.Context, pedidoID int, status string, location delivery.Location) error
+}
+
+// SetEstadoPedidos hace que las cancelaciones pasen por los observadores de
+// estado. Sin él solo se actualiza el pedido.
+func (sm *StateMachine) SetEstadoPedidos(e EstadoPedidos) {
+	sm.pedidos = e
+}
+
+// CancelarPedido cancela un pedido y libera la capacidad que tenía reservada.
+// Los observadores de estado reembolsan el pago y reponen los puntos.
+func (sm *StateMachine) CancelarPedido(ctx context.Context, pedido *store.Pedido, motivo string) error {
+	pedido.Estado = EstadoPedidoCancelado
+	var err error
+	if sm.pedidos != nil {
+		err = sm.pedidos.UpdateDeliveryStatus(ctx, pedido.ID, pedido.Estado, delivery.Location{})
+	} else {
+		err = sm.store.ActualizarPedido(ctx, pedido)
+	}
+	if err != nil {
+		return fmt.Errorf("error cancelando pedido %d: %w", pedido.ID, err)
+	}
+	fmt.Printf("Pedido %d cancelado: %s\n", pedido.ID, motivo)
+	sm.alertarCancelacion(ctx, pedido, motivo)
+
+	if sm.slots != nil {
+		if err := sm.slots.Liberar(ctx, pedido.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handleCancelarPedido permite al cliente cancelar su pedido activo mientras no
+// haya salido a ruta.
+func (sm *StateMachine) handleCancelarPedido(ctx context.Context, telefono string) error {
+	cliente, err := sm.store.GetClientePorTelefono(ctx, telefono)
+	if err != nil {
+		return fmt.Errorf("error buscando cliente para cancelar pedido: %w", err)
+	}
+	if cliente == nil {
+		return nil
+	}
+
+	pedido, err := sm.store.GetUltimoPedidoActivo(ctx, cliente.ID)
+	if err != nil {
+		return fmt.Errorf("error buscando pedido activo: %w", err)
+	}
+	if pedido == nil {
+		sm.sender.SendMessage(telefono, "No tienes ningún pedido activo para cancelar.")
+		return nil
+	}
+	if pedido.Estado != EstadoPedidoPendiente && pedido.Estado != EstadoPedidoPendienteRecoleccion {
+		sm.sender.SendMessage(telefono, fmt.Sprintf("Tu pedido #%d ya está en proceso (%s) y no puede cancelarse por este medio. Por favor, comunícate con nosotros.", pedido.ID, pedido.Estado))
+		return nil
+	}
+
+	if err := sm.CancelarPedido(ctx, pedido, "cancelado por el cliente"); err != nil {
+		return err
+	}
+	if sm.pedidos == nil {
+		// Con el seguimiento de entregas el aviso lo manda UpdateDeliveryStatus.
+		sm.sender.SendMessage(telefono, fmt.Sprintf("Tu pedido #%d ha sido cancelado.", pedido.ID))
+	}
+	return nil
+}
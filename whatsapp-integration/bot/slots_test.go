@@ -0,0 +1,60 @@
+package bot
+
+import (
+	"context"
+	"testing"
+
+	"example.com/whatsapp-integration/delivery"
+	"example.com/whatsapp-integration/store"
+)
+
+// storeCancelar solo implementa lo que usa la cancelación del cliente; el
+// resto del Store queda nil y haría panic si se llamara.
+type storeCancelar struct {
+	store.Store
+	cliente *store.Cliente
+	pedido  *store.Pedido
+}
+
+func (s *storeCancelar) GetClientePorTelefono(ctx context.Context, telefono string) (*store.Cliente, error) {
+	return s.cliente, nil
+}
+
+func (s *storeCancelar) GetUltimoPedidoActivo(ctx context.Context, clienteID int) (*store.Pedido, error) {
+	return s.pedido, nil
+}
+
+type estadoPrueba struct {
+	cambios []string
+}
+
+func (e *estadoPrueba) UpdateDeliveryStatus(ctx context.Context, pedidoID int, status string, location delivery.Location) error {
+	e.cambios = append(e.cambios, status)
+	return nil
+}
+
+// TestCancelarPedidoPasaPorObservadores cancela a través del cambio de estado,
+// que reembolsa, repone puntos y avisa al cliente, sin repetir el aviso.
+func TestCancelarPedidoPasaPorObservadores(t *testing.T) {
+	st := &storeCancelar{
+		cliente: &store.Cliente{ID: 1},
+		pedido:  &store.Pedido{ID: 7, ClienteID: 1, Estado: EstadoPedidoPendiente},
+	}
+	sender := &senderPrueba{}
+	estados := &estadoPrueba{}
+	sm := NewStateMachine(st, sender, nil)
+	sm.SetEstadoPedidos(estados)
+
+	if err := sm.handleCancelarPedido(context.Background(), "5215550000001"); err != nil {
+		t.Fatal(err)
+	}
+	if len(estados.cambios) != 1 || estados.cambios[0] != EstadoPedidoCancelado {
+		t.Fatalf("cambios de estado = %v, se esperaba solo cancelado", estados.cambios)
+	}
+	if st.pedido.Estado != EstadoPedidoCancelado {
+		t.Fatalf("pedido = %q, se esperaba cancelado", st.pedido.Estado)
+	}
+	if len(sender.mensajes) != 0 {
+		t.Fatalf("avisos = %q, el aviso lo manda el cambio de estado", sender.mensajes)
+	}
+}
@@ -2,7 +2,6 @@ package bot
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"os"
 	"strconv"
@@ -10,63 +9,95 @@ import (
 	"sync"
 	"time"
 
+	"example.com/whatsapp-integration/alertas"
+	"example.com/whatsapp-integration/calificaciones"
+	"example.com/whatsapp-integration/delivery"
+	"example.com/whatsapp-integration/facturacion"
+	"example.com/whatsapp-integration/lealtad"
 	"example.com/whatsapp-integration/maps"
+	"example.com/whatsapp-integration/payments"
+	"example.com/whatsapp-integration/promociones"
+	"example.com/whatsapp-integration/puntos"
+	"example.com/whatsapp-integration/referidos"
+	"example.com/whatsapp-integration/scheduler"
+	"example.com/whatsapp-integration/sellos"
+	"example.com/whatsapp-integration/slots"
 	"example.com/whatsapp-integration/store"
+	"example.com/whatsapp-integration/zonas"
 )
 
 // Estados de la conversación
 const (
 	// Estados iniciales
-	EstadoInicial           = "INICIO"
-	EstadoEsperandoOpcion   = "ESPERANDO_OPCION_INICIAL"
-	EstadoEsperandoNombre   = "ESPERANDO_NOMBRE_NUEVO"
+	EstadoInicial         = "INICIO"
+	EstadoEsperandoOpcion = "ESPERANDO_OPCION_INICIAL"
+	EstadoEsperandoNombre = "ESPERANDO_NOMBRE_NUEVO"
 
 	// Estado para primer registro: foto de la casa
-	EstadoEsperandoFotoCasa     = "ESPERANDO_FOTO_CASA"      // Pregunta inicial: ¿puedes enviar foto? (1=Sí 2=No)
-	EstadoConfirmandoFotoCasa   = "CONFIRMANDO_FOTO_CASA"    // Después de recibir foto, confirmar si es la casa
+	EstadoEsperandoFotoCasa   = "ESPERANDO_FOTO_CASA"   // Pregunta inicial: ¿puedes enviar foto? (1=Sí 2=No)
+	EstadoConfirmandoFotoCasa = "CONFIRMANDO_FOTO_CASA" // Después de recibir foto, confirmar si es la casa
 
 	// Estados para tipo de servicio
-	EstadoEsperandoTipo     = "ESPERANDO_TIPO_SERVICIO"
+	EstadoEsperandoTipo = "ESPERANDO_TIPO_SERVICIO"
 
 	// Estados para estacionario
-	EstadoEstacionarioMenu        = "ESPERANDO_OPCION_ESTACIONARIO" // Litros, Dinero o Tabulador
-	EstadoEstacionarioLts        = "ESPERANDO_LITROS_ESTACIONARIO"
-	EstadoEstacionarioDinero     = "ESPERANDO_DINERO_ESTACIONARIO"
-	EstadoEstacionarioTabuladorCapacidad = "ESPERANDO_CAPACIDAD_TABULADOR"
+	EstadoEstacionarioMenu                = "ESPERANDO_OPCION_ESTACIONARIO" // Litros, Dinero o Tabulador
+	EstadoEstacionarioLts                 = "ESPERANDO_LITROS_ESTACIONARIO"
+	EstadoEstacionarioDinero              = "ESPERANDO_DINERO_ESTACIONARIO"
+	EstadoEstacionarioTabuladorCapacidad  = "ESPERANDO_CAPACIDAD_TABULADOR"
 	EstadoEstacionarioTabuladorPorcentaje = "ESPERANDO_PORCENTAJE_TABULADOR"
-	EstadoEstacionarioConfirmacion = "CONFIRMANDO_PEDIDO_ESTACIONARIO"
+	EstadoEstacionarioConfirmacion        = "CONFIRMANDO_PEDIDO_ESTACIONARIO"
 
 	// Estados para cilindro
-	EstadoCilindroOpcion         = "ESPERANDO_OPCION_CILINDRO"      // Recarga o Canje
+	EstadoCilindroOpcion         = "ESPERANDO_OPCION_CILINDRO" // Recarga o Canje
 	EstadoCilindroCantidad       = "ESPERANDO_CANTIDAD_CILINDRO"
-	EstadoCilindroConfirmacionQR = "CONFIRMANDO_QR_CILINDRO"        // Cliente confirma QR
-	EstadoCilindroRecoleccion    = "ESPERANDO_RECOLECCION"          // Esperando que operador recoja
-	EstadoCilindroEntrega        = "ESPERANDO_ENTREGA"              // En ruta de regreso
+	EstadoCilindroConfirmacionQR = "CONFIRMANDO_QR_CILINDRO" // Cliente confirma QR
+	EstadoCilindroRecoleccion    = "ESPERANDO_RECOLECCION"   // Esperando que operador recoja
+	EstadoCilindroEntrega        = "ESPERANDO_ENTREGA"       // En ruta de regreso
 
 	// Estados de pago y dirección
-	EstadoEsperandoPago         = "ESPERANDO_METODO_PAGO"
-	EstadoEsperandoDireccion    = "ESPERANDO_DIRECCION"
-	EstadoConfirmandoDireccion  = "CONFIRMANDO_DIRECCION"          // Con Maps/Street View
-	EstadoConfirmandoPedidoFinal = "CONFIRMANDO_PEDIDO_FINAL"
-	EstadoEsperandoColorFachada = "ESPERANDO_COLOR_FACHADA"
-	EstadoEsperandoColorPuerta = "ESPERANDO_COLOR_PUERTA"
+	EstadoEsperandoPago           = "ESPERANDO_METODO_PAGO"
+	EstadoEsperandoDireccion      = "ESPERANDO_DIRECCION"
+	EstadoConfirmandoDireccion    = "CONFIRMANDO_DIRECCION" // Con Maps/Street View
+	EstadoConfirmandoPedidoFinal  = "CONFIRMANDO_PEDIDO_FINAL"
+	EstadoEsperandoColorFachada   = "ESPERANDO_COLOR_FACHADA"
+	EstadoEsperandoColorPuerta    = "ESPERANDO_COLOR_PUERTA"
 	EstadoEsperandoHorarioPremium = "ESPERANDO_HORARIO_PREMIUM"
+	EstadoEsperandoSlot           = "ESPERANDO_SLOT_ENTREGA"
+
+	// Estados para entregas programadas
+	EstadoProgramandoFecha       = "PROGRAMANDO_FECHA"
+	EstadoProgramandoHorario     = "PROGRAMANDO_HORARIO"
+	EstadoProgramandoRecurrencia = "PROGRAMANDO_RECURRENCIA"
+	EstadoProgramandoSemanas     = "PROGRAMANDO_SEMANAS"
 
 	// Estados especiales
-	EstadoReportandoSello      = "REPORTANDO_SELLO"               // Cliente reporta sello violado
-	EstadoEsperandoFotoSello   = "ESPERANDO_FOTO_SELLO"          // Opcional: foto del sello
-	EstadoConfirmandoEntrega   = "CONFIRMANDO_ENTREGA"           // Cliente confirma recepción
+	EstadoReportandoSello     = "REPORTANDO_SELLO"     // Cliente describe el problema con su entrega
+	EstadoEsperandoFotoSello  = "ESPERANDO_FOTO_SELLO" // Opcional: fotos para el reporte
+	EstadoConfirmandoEntrega  = "CONFIRMANDO_ENTREGA"  // Cliente confirma recepción
+	EstadoCalificandoServicio = "CALIFICANDO_SERVICIO" // Cliente califica la entrega de 1 a 5 estrellas
+	EstadoComentandoServicio  = "COMENTANDO_SERVICIO"  // Opcional: comentario sobre la entrega
+	EstadoApelandoStrike      = "APELANDO_STRIKE"      // Cliente explica su apelación
+
+	// Estados para factura de un pedido entregado
+	EstadoFacturaPedido         = "FACTURA_PEDIDO"          // Elige el pedido a facturar
+	EstadoFacturaConfirmarDatos = "FACTURA_CONFIRMAR_DATOS" // Usa los datos fiscales guardados o los actualiza
+	EstadoFacturaRFC            = "FACTURA_RFC"
+	EstadoFacturaRazonSocial    = "FACTURA_RAZON_SOCIAL"
+	EstadoFacturaCP             = "FACTURA_CODIGO_POSTAL"
+	EstadoFacturaRegimen        = "FACTURA_REGIMEN"
+	EstadoFacturaUsoCFDI        = "FACTURA_USO_CFDI"
 )
 
 // Estados de Pedido
 const (
-	EstadoPedidoPendiente        = "pendiente"
+	EstadoPedidoPendiente            = "pendiente"
 	EstadoPedidoPendienteRecoleccion = "pendiente_recoleccion"
-	EstadoPedidoEnPlanta         = "en_planta"
-	EstadoPedidoEnRecarga        = "en_recarga"
-	EstadoPedidoEnRuta           = "en_ruta"
-	EstadoPedidoEntregado        = "entregado"
-	EstadoPedidoCancelado        = "cancelado"
+	EstadoPedidoEnPlanta             = "en_planta"
+	EstadoPedidoEnRecarga            = "en_recarga"
+	EstadoPedidoEnRuta               = "en_ruta"
+	EstadoPedidoEntregado            = "entregado"
+	EstadoPedidoCancelado            = "cancelado"
 )
 
 // WhatsAppSender es una interfaz para enviar mensajes
@@ -77,12 +108,30 @@ type WhatsAppSender interface {
 
 // StateMachine maneja la lógica de estados del bot
 type StateMachine struct {
-	store        store.Store
-	sender       WhatsAppSender
-	mapsClient   *maps.Client
-	session      *Session // mantiene datos temporales entre estados
-	userMutexes  map[string]*sync.Mutex
-	mapMutex     sync.Mutex
+	store             store.Store
+	sender            WhatsAppSender
+	mapsClient        *maps.Client
+	session           *Session                 // mantiene datos temporales entre estados
+	loc               *time.Location           // zona horaria de operación
+	slots             *slots.Service           // opcional: horarios con capacidad
+	pedidos           EstadoPedidos            // opcional: cambios de estado con observadores
+	zonas             *zonas.Mapa              // opcional: zonas de reparto
+	vigencia          time.Duration            // tiempo que cuenta cada strike
+	espera            time.Duration            // tiempo que el repartidor espera en el domicilio
+	plazoConfirmacion time.Duration            // espera antes de autoconfirmar una entrega
+	lealtad           *lealtad.Programa        // niveles de cliente y sus beneficios
+	puntos            *puntos.Servicio         // opcional: monedero de puntos
+	promociones       *promociones.Servicio    // opcional: códigos de descuento
+	referidos         *referidos.Servicio      // opcional: códigos de invitación
+	pagos             *payments.Service        // opcional: métodos de pago por zona
+	facturacion       *facturacion.Servicio    // opcional: facturas de pedidos entregados
+	sellos            *sellos.Servicio         // casos de sello violado
+	calificaciones    *calificaciones.Servicio // calificación de cada entrega
+	alertas           *alertas.Servicio        // opcional: alertas al repartidor y al punto de venta
+	userMutexes       map[string]*sync.Mutex
+	mapMutex          sync.Mutex
+	datosTemp         map[string]map[string]interface{} // datos de los flujos por teléfono
+	datosMutex        sync.Mutex
 }
 
 // Session mantiene datos temporales entre estados
@@ -93,11 +142,20 @@ type Session struct {
 }
 
 func NewStateMachine(s store.Store, sender WhatsAppSender, mapsClient *maps.Client) *StateMachine {
+	loc := scheduler.ZonaHorariaDesdeEnv()
 	return &StateMachine{
-		store:       s,
-		sender:      sender,
-		mapsClient:  mapsClient,
-		userMutexes: make(map[string]*sync.Mutex),
+		store:             s,
+		sender:            sender,
+		mapsClient:        mapsClient,
+		loc:               loc,
+		vigencia:          vigenciaStrikesDesdeEnv(),
+		espera:            delivery.DefaultWaitTime,
+		plazoConfirmacion: plazoConfirmacionDesdeEnv(),
+		lealtad:           lealtad.ProgramaPorDefecto(),
+		sellos:            sellos.NewServicio(s, sellos.ConfigDesdeEnv(), loc),
+		calificaciones:    calificaciones.NewServicio(s, calificaciones.ConfigDesdeEnv(), loc),
+		userMutexes:       make(map[string]*sync.Mutex),
+		datosTemp:         make(map[string]map[string]interface{}),
 		session: &Session{
 			DatosTemp: make(map[string]interface{}),
 		},
@@ -111,30 +169,62 @@ func (sm *StateMachine) ProcessMessage(ctx context.Context, telefono, mensaje st
 	mu.Lock()
 	defer mu.Unlock()
 
+	// Buscar o crear cliente
+	cliente, err := sm.store.GetClientePorTelefono(ctx, telefono)
+	if err != nil {
+		return fmt.Errorf("error buscando cliente: %w", err)
+	}
+
+	// Los strikes vencidos dejan de contar y pueden levantar el bloqueo.
+	if cliente != nil && (cliente.Strikes > 0 || cliente.Bloqueado) {
+		if err := sm.revisarVencimientoStrikes(ctx, cliente); err != nil {
+			fmt.Printf("Error revisando strikes del cliente %s: %v\n", telefono, err)
+		}
+	}
+
+	// Si el cliente existe, verificar si está bloqueado antes de atender
+	// cualquier comando. Solo puede apelar.
+	apelar := strings.ToUpper(strings.TrimSpace(mensaje)) == "APELAR"
+	if cliente != nil && cliente.Bloqueado && cliente.EstadoConversacion != EstadoApelandoStrike && !apelar {
+		sm.sender.SendMessage(telefono, "Tu número ha sido bloqueado por incumplir nuestras políticas. No puedes realizar nuevos pedidos.\n\nSi crees que es un error, escribe APELAR.")
+		return nil // Terminar la conversación.
+	}
+
 	// Comandos globales que interrumpen el flujo normal
 	if strings.ToLower(mensaje) == "estado" {
 		return sm.handleEstadoPedido(ctx, telefono)
 	}
+	if strings.ToLower(strings.TrimSpace(mensaje)) == "puntos" {
+		return sm.handlePuntos(ctx, telefono)
+	}
+	if strings.ToUpper(strings.TrimSpace(mensaje)) == "INVITAR" {
+		return sm.handleInvitar(ctx, telefono)
+	}
+	if strings.ToUpper(strings.TrimSpace(mensaje)) == "ENTERADO" {
+		if atendido, err := sm.handleEnterado(ctx, telefono); atendido {
+			return err
+		}
+	}
 	if strings.Contains(strings.ToUpper(mensaje), "REPORTAR SELLO") {
 		return sm.handleReporteSello(ctx, telefono, mensaje)
 	}
-
-	// Buscar o crear cliente
-	cliente, err := sm.store.GetClientePorTelefono(ctx, telefono)
-	if err != nil {
-		return fmt.Errorf("error buscando cliente: %w", err)
+	if strings.ToUpper(strings.TrimSpace(mensaje)) == "OMITIR" {
+		return sm.handleOmitirProgramado(ctx, telefono)
 	}
-
-	// Si el cliente existe, verificar si está bloqueado.
-	if cliente != nil && cliente.Bloqueado {
-		sm.sender.SendMessage(telefono, "Tu número ha sido bloqueado por incumplir nuestras políticas. No puedes realizar nuevos pedidos.")
-		return nil // Terminar la conversación.
+	if strings.ToUpper(strings.TrimSpace(mensaje)) == "CANCELAR PEDIDO" {
+		return sm.handleCancelarPedido(ctx, telefono)
+	}
+	if apelar {
+		return sm.handleApelacion(ctx, telefono, mensaje)
+	}
+	if strings.ToUpper(strings.TrimSpace(mensaje)) == "FACTURA" {
+		return sm.handleFactura(ctx, telefono)
 	}
 
 	if cliente == nil {
 		// Nuevo cliente: solicitar el nombre.
 		cliente = &store.Cliente{
-			NumeroTelefono:    telefono,
+			NumeroTelefono:     telefono,
 			EstadoConversacion: EstadoEsperandoNombre,
 		}
 		if err := sm.store.CrearCliente(ctx, cliente); err != nil {
@@ -142,7 +232,8 @@ func (sm *StateMachine) ProcessMessage(ctx context.Context, telefono, mensaje st
 		}
 
 		sm.session.ClienteActual = cliente
-		msg := "¡Bienvenido! Para registrarte, por favor escribe tu nombre completo, empezando por tu apellido paterno. Ejemplo: Pérez López Juan."
+		msg := "¡Bienvenido! " + sm.atribuirReferido(ctx, cliente, mensaje) +
+			"Para registrarte, por favor escribe tu nombre completo, empezando por tu apellido paterno. Ejemplo: Pérez López Juan."
 		if err := sm.sender.SendMessage(telefono, msg); err != nil {
 			return fmt.Errorf("error enviando saludo a nuevo cliente: %w", err)
 		}
@@ -159,10 +250,10 @@ func (sm *StateMachine) handleState(ctx context.Context, telefono, mensaje, esta
 	switch estado {
 	case EstadoInicial:
 		err = sm.handleInicial(ctx, telefono)
-	
+
 	case EstadoEsperandoOpcion:
 		err = sm.handleOpcionInicial(ctx, telefono, mensaje)
-	
+
 	case EstadoEsperandoNombre:
 		err = sm.handleNombre(ctx, telefono, mensaje)
 
@@ -171,49 +262,49 @@ func (sm *StateMachine) handleState(ctx context.Context, telefono, mensaje, esta
 
 	case EstadoConfirmandoFotoCasa:
 		err = sm.handleConfirmacionFotoCasa(ctx, telefono, mensaje)
-	
+
 	case EstadoEsperandoTipo:
 		err = sm.handleTipoServicio(ctx, telefono, mensaje)
-	
+
 	case EstadoEstacionarioMenu:
 		err = sm.handleEstacionarioMenu(ctx, telefono, mensaje)
-	
+
 	case EstadoEstacionarioLts:
 		err = sm.handleEstacionarioLitros(ctx, telefono, mensaje)
-	
+
 	case EstadoEstacionarioDinero:
 		err = sm.handleEstacionarioDinero(ctx, telefono, mensaje)
-	
+
 	case EstadoEstacionarioTabuladorCapacidad:
 		err = sm.handleTabuladorCapacidad(ctx, telefono, mensaje)
-	
+
 	case EstadoEstacionarioTabuladorPorcentaje:
 		err = sm.handleTabuladorPorcentaje(ctx, telefono, mensaje)
 
 	case EstadoEstacionarioConfirmacion:
 		err = sm.handleEstacionarioConfirmacion(ctx, telefono, mensaje)
-	
+
 	case EstadoCilindroOpcion:
 		err = sm.handleCilindroOpcion(ctx, telefono, mensaje)
-	
+
 	case EstadoCilindroCantidad:
 		err = sm.handleCilindroCantidad(ctx, telefono, mensaje)
-	
+
 	case EstadoCilindroConfirmacionQR:
 		err = sm.handleConfirmacionQR(ctx, telefono, mensaje)
-	
+
 	case EstadoEsperandoPago:
 		err = sm.handlePago(ctx, telefono, mensaje)
-	
+
 	case EstadoEsperandoDireccion:
 		err = sm.handleDireccion(ctx, telefono, mensaje)
-	
+
 	case EstadoConfirmandoDireccion:
 		err = sm.handleConfirmacionDireccion(ctx, telefono, mensaje)
 
 	case EstadoConfirmandoPedidoFinal:
 		err = sm.handleConfirmacionFinalPost(ctx, telefono, mensaje)
-	
+
 	case EstadoEsperandoColorFachada:
 		err = sm.handleColorFachada(ctx, telefono, mensaje)
 
@@ -222,16 +313,61 @@ func (sm *StateMachine) handleState(ctx context.Context, telefono, mensaje, esta
 
 	case EstadoEsperandoHorarioPremium:
 		err = sm.handleHorarioPremium(ctx, telefono, mensaje)
-	
+
+	case EstadoEsperandoSlot:
+		err = sm.handleSeleccionSlot(ctx, telefono, mensaje)
+
+	case EstadoProgramandoFecha:
+		err = sm.handleProgramarFecha(ctx, telefono, mensaje)
+
+	case EstadoProgramandoHorario:
+		err = sm.handleProgramarHorario(ctx, telefono, mensaje)
+
+	case EstadoProgramandoRecurrencia:
+		err = sm.handleProgramarRecurrencia(ctx, telefono, mensaje)
+
+	case EstadoProgramandoSemanas:
+		err = sm.handleProgramarSemanas(ctx, telefono, mensaje)
+
 	case EstadoReportandoSello:
-		err = sm.handleReporteSello(ctx, telefono, mensaje)
-	
+		err = sm.handleDetalleReporte(ctx, telefono, mensaje)
+
 	case EstadoEsperandoFotoSello:
 		err = sm.handleFotoSello(ctx, telefono, mensaje)
-	
+
 	case EstadoConfirmandoEntrega:
 		err = sm.handleConfirmacionEntrega(ctx, telefono, mensaje)
-		
+
+	case EstadoCalificandoServicio:
+		err = sm.handleCalificacion(ctx, telefono, mensaje)
+
+	case EstadoComentandoServicio:
+		err = sm.handleComentarioServicio(ctx, telefono, mensaje)
+
+	case EstadoApelandoStrike:
+		err = sm.handleApelacion(ctx, telefono, mensaje)
+
+	case EstadoFacturaPedido:
+		err = sm.handleFacturaPedido(ctx, telefono, mensaje)
+
+	case EstadoFacturaConfirmarDatos:
+		err = sm.handleFacturaConfirmarDatos(ctx, telefono, mensaje)
+
+	case EstadoFacturaRFC:
+		err = sm.handleFacturaRFC(ctx, telefono, mensaje)
+
+	case EstadoFacturaRazonSocial:
+		err = sm.handleFacturaRazonSocial(ctx, telefono, mensaje)
+
+	case EstadoFacturaCP:
+		err = sm.handleFacturaCP(ctx, telefono, mensaje)
+
+	case EstadoFacturaRegimen:
+		err = sm.handleFacturaRegimen(ctx, telefono, mensaje)
+
+	case EstadoFacturaUsoCFDI:
+		err = sm.handleFacturaUsoCFDI(ctx, telefono, mensaje)
+
 	default:
 		err = fmt.Errorf("estado no manejado: %s", estado)
 	}
@@ -253,10 +389,14 @@ func (sm *StateMachine) handleInicial(ctx context.Context, telefono string) erro
 
 	var msg string
 	if pedido != nil {
-		msg = fmt.Sprintf("¡Hola %s!\n\nElige una opción:\n\n1. Repetir pedido anterior:\n   - %s\n   - %.0f Lts\n   - %s\n\n2. Nuevo pedido (mismo domicilio)\n3. Actualizar datos",
+		cantidad := fmt.Sprintf("%.0f Lts", pedido.CantidadLitros)
+		if esCilindro(pedido.TipoServicio) {
+			cantidad = fmt.Sprintf("%d cilindro(s)", pedido.CantidadCilindros)
+		}
+		msg = fmt.Sprintf("¡Hola %s!\n\nElige una opción:\n\n1. Repetir pedido anterior:\n   - %s\n   - %s\n   - %s\n\n2. Nuevo pedido (mismo domicilio)\n3. Actualizar datos",
 			sm.session.ClienteActual.Nombre,
 			pedido.TipoServicio,
-			pedido.CantidadLitros,
+			cantidad,
 			pedido.Direccion)
 	} else {
 		msg = fmt.Sprintf("¡Hola %s! Veo que aún no tienes pedidos con nosotros.\n\nElige una opción:\n\n1. Hacer un nuevo pedido\n2. Actualizar mis datos", sm.session.ClienteActual.Nombre)
@@ -292,32 +432,7 @@ func (sm *StateMachine) handleOpcionInicial(ctx context.Context, telefono, mensa
 	// Flujo para clientes CON pedidos previos
 	switch mensaje {
 	case "1": // Repetir pedido
-		precioActualLitro := getPrecioGasLitro()
-		nuevoPedido := *pedido
-		nuevoPedido.ID = 0 // Es un nuevo registro en la BD.
-		nuevoPedido.Estado = "pendiente"
-		nuevoPedido.PrecioUnitario = precioActualLitro
-		nuevoPedido.CantidadDinero = nuevoPedido.CantidadLitros * precioActualLitro // Recalcular total.
-
-		if err := sm.store.CrearPedido(ctx, &nuevoPedido); err != nil {
-			return err
-		}
-
-		msg := fmt.Sprintf(
-			"✅ *Pedido Confirmado*\n\n"+
-				"Hemos registrado la repetición de tu último pedido con los precios actualizados:\n\n"+
-				"  - *Servicio:* %s\n"+
-				"  - *Cantidad:* %.0f Lts\n"+
-				"  - *Precio por Litro:* $%.2f\n"+
-				"  - *Total a Pagar:* $%.2f\n\n"+
-				"En breve, nuestro equipo te confirmará la entrega.",
-			nuevoPedido.TipoServicio,
-			nuevoPedido.CantidadLitros,
-			nuevoPedido.PrecioUnitario,
-			nuevoPedido.CantidadDinero,
-		)
-		sm.sender.SendMessage(telefono, msg)
-		return sm.actualizarEstado(ctx, telefono, EstadoInicial)
+		return sm.handleRepetirPedido(ctx, telefono, pedido)
 
 	case "2": // Nuevo pedido
 		return sm.handleTipoServicio(ctx, telefono, mensaje)
@@ -379,6 +494,7 @@ func (sm *StateMachine) handleTipoServicio(ctx context.Context, telefono, mensaj
 			ClienteID:    sm.session.ClienteActual.ID,
 			TipoServicio: "cilindro",
 		}
+		sm.borrarDatosTemp(telefono, datoRepetirCilindro)
 		// El siguiente paso es preguntar si es recarga o canje.
 		return sm.handleCilindroOpcion(ctx, telefono, "")
 	default:
@@ -459,8 +575,8 @@ func (sm *StateMachine) handleTabuladorCapacidad(ctx context.Context, telefono,
 
 	sm.session.DatosTemp["capacidad_total"] = capacidad
 	msg := fmt.Sprintf(
-		"¿Qué porcentaje de llenado deseas?\n"+
-			"(recomendado: 85%%)\n\n"+
+		"¿Qué porcentaje de llenado deseas?\n" +
+			"(recomendado: 85%%)\n\n" +
 			"Ingresa un número entre 1 y 100")
 
 	if err := sm.sender.SendMessage(telefono, msg); err != nil {
@@ -472,8 +588,9 @@ func (sm *StateMachine) handleTabuladorCapacidad(ctx context.Context, telefono,
 func (sm *StateMachine) handleEstacionarioConfirmacion(ctx context.Context, telefono, mensaje string) error {
 	switch mensaje {
 	case "1":
-		// Pedido confirmado, pasar al pago
-		return sm.handlePago(ctx, telefono, "")
+		// Pedido confirmado, pasar a la dirección; el método de pago se elige
+		// con la zona ya conocida.
+		return sm.handleDireccion(ctx, telefono, "")
 	case "2":
 		// Pedido cancelado, volver al menú de estacionario
 		sm.sender.SendMessage(telefono, "Pedido cancelado. Volviendo al menú de tanque estacionario.")
@@ -536,18 +653,27 @@ func (sm *StateMachine) handleCilindroOpcion(ctx context.Context, telefono, mens
 	}
 
 	// Una vez que el usuario responde, procesamos la opción.
+	var tipo string
 	opcion := strings.TrimSpace(mensaje)
 	switch opcion {
 	case "1": // Recarga
-		sm.session.PedidoEnCurso.TipoServicio = "cilindro_recarga"
-		return sm.handleCilindroCantidad(ctx, telefono, "") // Pasar a pedir cantidad
+		tipo = "cilindro_recarga"
 	case "2": // Canje
-		sm.session.PedidoEnCurso.TipoServicio = "cilindro_canje"
-		return sm.handleCilindroCantidad(ctx, telefono, "") // Pasar a pedir cantidad
+		tipo = "cilindro_canje"
 	default:
 		sm.sender.SendMessage(telefono, "Opción no válida. Por favor, responde 1 para Recarga o 2 para Canje.")
 		return nil // No cambiamos de estado.
 	}
+
+	// Al repetir un pedido de cilindro sin tipo ya se conoce la cantidad.
+	if anterior, ok := sm.datoTemp(telefono, datoRepetirCilindro).(*store.Pedido); ok {
+		sm.borrarDatosTemp(telefono, datoRepetirCilindro)
+		repetido := *anterior
+		repetido.TipoServicio = tipo
+		return sm.handleRepetirPedido(ctx, telefono, &repetido)
+	}
+	sm.session.PedidoEnCurso.TipoServicio = tipo
+	return sm.handleCilindroCantidad(ctx, telefono, "") // Pasar a pedir cantidad
 }
 
 func (sm *StateMachine) handleCilindroCantidad(ctx context.Context, telefono, mensaje string) error {
@@ -590,14 +716,17 @@ func (sm *StateMachine) handleCilindroCantidad(ctx context.Context, telefono, me
 		return sm.actualizarEstado(ctx, telefono, EstadoInicial)
 	}
 
-	// Si es canje, continuar al flujo de pago directamente.
-	return sm.handlePago(ctx, telefono, "")
+	// Si es canje, calcular el total y continuar a la dirección directamente.
+	precioCilindro := getPrecioCilindro()
+	sm.session.PedidoEnCurso.PrecioUnitario = precioCilindro
+	sm.session.PedidoEnCurso.CantidadDinero = float64(cantidad) * precioCilindro
+	return sm.handleDireccion(ctx, telefono, "")
 }
 
 func (sm *StateMachine) handleConfirmacionQR(ctx context.Context, telefono, mensaje string) error {
 	switch strings.ToUpper(mensaje) {
 	case "1", "SI", "SÍ":
-		return sm.handlePago(ctx, telefono, "1") // Default a efectivo
+		return sm.handleDireccion(ctx, telefono, "")
 	case "2", "NO":
 		return sm.handleTipoServicio(ctx, telefono, "CILINDRO")
 	default:
@@ -606,146 +735,59 @@ func (sm *StateMachine) handleConfirmacionQR(ctx context.Context, telefono, mens
 	}
 }
 
-func (sm *StateMachine) handleFotoSello(ctx context.Context, telefono, mensaje string) error {
-	// En un caso real, aquí se procesaría el mensaje para extraer la imagen.
-	// Por ahora, simulamos la recepción y confirmamos al usuario.
-	confirmacion := "Hemos recibido la imagen y la hemos añadido a tu reporte. Un supervisor se pondrá en contacto contigo a la brevedad."
-	if err := sm.sender.SendMessage(telefono, confirmacion); err != nil {
-		fmt.Printf("Error al enviar confirmación de foto de sello: %v\n", err)
-	}
-
-	// Enviar la instrucción sobre el no pago.
-	instruccion := "Recuerda que al no aceptar el pedido por un sello violado, *no debes realizar el pago*. Por favor, muestra este mensaje a nuestro repartidor como confirmación."
-	if err := sm.sender.SendMessage(telefono, instruccion); err != nil {
-		return err
-	}
+func (sm *StateMachine) actualizarEstado(ctx context.Context, telefono, nuevoEstado string) error {
+	return sm.store.ActualizarEstadoCliente(ctx, telefono, nuevoEstado)
+}
 
-	// Simular la alerta interna para el repartidor.
-	sm.NotificarAlertaARepartidor(ctx, telefono)
+// --- Implementaciones pendientes para Fases Futuras ---
 
-	// El reporte ya fue creado, así que volvemos al estado inicial.
+func (sm *StateMachine) handleFotoCasa(ctx context.Context, telefono, mensaje string) error {
+	sm.sender.SendMessage(telefono, "Función de foto de casa pendiente.")
 	return sm.actualizarEstado(ctx, telefono, EstadoInicial)
 }
 
-func (sm *StateMachine) handleConfirmacionEntrega(ctx context.Context, telefono, mensaje string) error {
-	switch strings.ToUpper(mensaje) {
-	case "1", "SI", "SÍ":
-		sm.session.PedidoEnCurso.Estado = "entregado"
-		if err := sm.store.ActualizarPedido(ctx, sm.session.PedidoEnCurso); err != nil {
-			return err
-		}
-
-		msg := "¡Gracias por confirmar la entrega!\n" +
-			"¿Deseas calificar nuestro servicio?\n" +
-			"1. ⭐⭐⭐⭐⭐ Excelente\n" +
-			"2. ⭐⭐⭐⭐ Muy bueno\n" +
-			"3. ⭐⭐⭐ Regular\n" +
-			"4. ⭐⭐ Malo\n" +
-			"5. ⭐ Muy malo"
+func (sm *StateMachine) handleConfirmacionFotoCasa(ctx context.Context, telefono, mensaje string) error {
+	sm.sender.SendMessage(telefono, "Función de confirmación de foto pendiente.")
+	return sm.actualizarEstado(ctx, telefono, EstadoInicial)
+}
 
-		if err := sm.sender.SendMessage(telefono, msg); err != nil {
-			return err
+func (sm *StateMachine) handlePago(ctx context.Context, telefono, mensaje string) error {
+	pedido := sm.session.PedidoEnCurso
+	metodos := sm.metodosPago(pedido)
+
+	// Con un solo método se asigna automáticamente y se informa al cliente.
+	if len(metodos) <= 1 {
+		pedido.MetodoPago = "efectivo"
+		msg := "El pago se realizará en efectivo al momento de la entrega."
+		if len(metodos) == 1 {
+			pedido.MetodoPago = metodos[0].Method()
+			msg = fmt.Sprintf("Forma de pago: %s.", metodos[0].Label())
 		}
-		return sm.actualizarEstado(ctx, telefono, EstadoInicial)
-
-	case "2", "NO":
-		msg := "Por favor indícanos qué problema tuviste con la entrega.\n" +
-			"Un supervisor revisará tu caso inmediatamente."
-
 		if err := sm.sender.SendMessage(telefono, msg); err != nil {
 			return err
 		}
-		return sm.actualizarEstado(ctx, telefono, EstadoReportandoSello)
-
-	default:
-		return sm.sender.SendMessage(telefono,
-			"Por favor responde:\n1. Sí\n2. No")
+		return sm.continuarDespuesDePago(ctx, telefono)
 	}
-}
-
-func (sm *StateMachine) handleReporteSello(ctx context.Context, telefono, mensaje string) error {
-	// Este manejador tiene dos responsabilidades:
-	// 1. Crear el reporte inicial y hacer la pregunta sobre la foto.
-	// 2. Procesar la respuesta a esa pregunta (Sí/No).
 
-	// Si el estado actual NO es esperar la foto, significa que este es el
-	// primer llamado para reportar. Creamos el reporte y hacemos la pregunta.
-	if sm.session.ClienteActual.EstadoConversacion != EstadoEsperandoFotoSello {
-		reporte := &store.ReporteSello{
-			ClienteID:    sm.session.ClienteActual.ID,
-			Estado:       "pendiente",
-			Descripcion:  "Reporte de sello violado",
-			FechaReporte: time.Now(),
-		}
-		if err := sm.store.CrearReporteSello(ctx, reporte); err != nil {
-			return err
+	// Si el estado no es el de esperar método de pago, hacemos la pregunta.
+	if sm.session.ClienteActual.EstadoConversacion != EstadoEsperandoPago {
+		msg := "¿Cómo deseas pagar?\n"
+		for i, m := range metodos {
+			msg += fmt.Sprintf("%d. %s\n", i+1, m.Label())
 		}
-
-		msg := "⚠️ *Reporte Recibido*\n\n" +
-			"Tu caso ha sido registrado con prioridad alta.\n" +
-			"Un supervisor se comunicará contigo en breve.\n\n" +
-			"¿Deseas enviar una foto del sello para adjuntar al reporte?\n" +
-			"1. Sí\n2. No"
-
 		if err := sm.sender.SendMessage(telefono, msg); err != nil {
 			return err
 		}
-		// Cambiamos el estado para que la siguiente respuesta se procese aquí.
-		return sm.actualizarEstado(ctx, telefono, EstadoEsperandoFotoSello)
+		return sm.actualizarEstado(ctx, telefono, EstadoEsperandoPago)
 	}
 
-	// Si el estado YA es esperar la foto, procesamos la respuesta "1" o "2".
-	switch mensaje {
-	case "1":
-		sm.sender.SendMessage(telefono, "Por favor, envía la foto del sello.")
-		// Mantenemos el estado en EstadoEsperandoFotoSello, para que el siguiente
-		// mensaje (la foto) sea procesado por handleFotoSello.
-		return nil
-	case "2":
-		sm.sender.SendMessage(telefono, "Entendido. Tu reporte ha sido registrado sin foto.")
-
-		// Enviar la instrucción sobre el no pago.
-		instruccion := "Recuerda que al no aceptar el pedido por un sello violado, *no debes realizar el pago*. Por favor, muestra este mensaje a nuestro repartidor como confirmación."
-		if err := sm.sender.SendMessage(telefono, instruccion); err != nil {
-			return err
-		}
-
-		// Simular la alerta interna para el repartidor.
-		sm.NotificarAlertaARepartidor(ctx, telefono)
-
-		return sm.actualizarEstado(ctx, telefono, EstadoInicial)
-	default:
-		sm.sender.SendMessage(telefono, "Opción no válida. Por favor, responde 1 para Sí o 2 para No.")
+	opcion, err := strconv.Atoi(strings.TrimSpace(mensaje))
+	if err != nil || opcion < 1 || opcion > len(metodos) {
+		sm.sender.SendMessage(telefono, fmt.Sprintf("Opción no válida. Por favor, responde con un número del 1 al %d.", len(metodos)))
 		return nil
 	}
-}
-
-func (sm *StateMachine) actualizarEstado(ctx context.Context, telefono, nuevoEstado string) error {
-	return sm.store.ActualizarEstadoCliente(ctx, telefono, nuevoEstado)
-}
-
-// --- Implementaciones pendientes para Fases Futuras ---
-
-func (sm *StateMachine) handleFotoCasa(ctx context.Context, telefono, mensaje string) error {
-	sm.sender.SendMessage(telefono, "Función de foto de casa pendiente.")
-	return sm.actualizarEstado(ctx, telefono, EstadoInicial)
-}
-
-func (sm *StateMachine) handleConfirmacionFotoCasa(ctx context.Context, telefono, mensaje string) error {
-	sm.sender.SendMessage(telefono, "Función de confirmación de foto pendiente.")
-	return sm.actualizarEstado(ctx, telefono, EstadoInicial)
-}
-
-func (sm *StateMachine) handlePago(ctx context.Context, telefono, mensaje string) error {
-	// Asignar "Efectivo" automáticamente e informar al cliente.
-	sm.session.PedidoEnCurso.MetodoPago = "efectivo"
-	msg := "El pago se realizará en efectivo al momento de la entrega."
-	if err := sm.sender.SendMessage(telefono, msg); err != nil {
-		return err
-	}
-
-	// Siguiente paso: pedir la dirección.
-	return sm.handleDireccion(ctx, telefono, "")
+	pedido.MetodoPago = metodos[opcion-1].Method()
+	return sm.continuarDespuesDePago(ctx, telefono)
 }
 
 func (sm *StateMachine) handleDireccion(ctx context.Context, telefono, mensaje string) error {
@@ -808,12 +850,8 @@ func (sm *StateMachine) handleConfirmacionDireccion(ctx context.Context, telefon
 	// Si ya estamos en el estado, procesamos la respuesta.
 	switch mensaje {
 	case "1":
-		// La dirección es correcta. Verificar si el cliente es Premium.
-		if sm.session.ClienteActual.Categoria == "Premium" {
-			return sm.handleHorarioPremium(ctx, telefono, "")
-		}
-		// Si no es Premium, ir a la confirmación final.
-		return sm.handleConfirmacionFinal(ctx, telefono)
+		// La dirección es correcta. Elegir horario (slots o Premium) o ir al resumen.
+		return sm.continuarDespuesDeDireccion(ctx, telefono)
 	case "2":
 		// El usuario quiere cambiar la dirección, pedimos más detalles.
 		return sm.handleColorFachada(ctx, telefono, "")
@@ -862,15 +900,13 @@ func (sm *StateMachine) handleColorPuerta(ctx context.Context, telefono, mensaje
 	sm.session.PedidoEnCurso.ColorPuerta = mensaje
 
 	sm.sender.SendMessage(telefono, "¡Perfecto! Hemos añadido los colores a tu dirección.")
-	return sm.handleConfirmacionFinal(ctx, telefono)
+	return sm.continuarDespuesDeDireccion(ctx, telefono)
 }
 
-// AsignarStrike aplica un strike a un cliente y le notifica.
-// Si el cliente alcanza los 3 strikes, es bloqueado.
 func (sm *StateMachine) handleHorarioPremium(ctx context.Context, telefono, mensaje string) error {
 	// Si el estado no es el de esperar horario, hacemos la pregunta.
 	if sm.session.ClienteActual.EstadoConversacion != EstadoEsperandoHorarioPremium {
-		msg := "Como cliente Premium, puedes elegir tu horario de entrega.\n\n¿Prefieres:\n1. Mañana (9am - 1pm)\n2. Tarde (2pm - 6pm)"
+		msg := fmt.Sprintf("Como cliente %s, puedes elegir tu horario de entrega.\n\n¿Prefieres:\n1. Mañana (9am - 1pm)\n2. Tarde (2pm - 6pm)", sm.session.ClienteActual.Categoria)
 		if err := sm.sender.SendMessage(telefono, msg); err != nil {
 			return err
 		}
@@ -892,79 +928,6 @@ func (sm *StateMachine) handleHorarioPremium(ctx context.Context, telefono, mens
 	return sm.handleConfirmacionFinal(ctx, telefono)
 }
 
-func (sm *StateMachine) PromocionarClienteAPremium(ctx context.Context, telefono string) error {
-	cliente, err := sm.store.GetClientePorTelefono(ctx, telefono)
-	if err != nil {
-		return fmt.Errorf("no se pudo encontrar al cliente %s para promocionarlo: %w", telefono, err)
-	}
-	if cliente == nil {
-		return fmt.Errorf("intento de promocionar a un cliente no existente: %s", telefono)
-	}
-
-	// Si ya es Premium, no hacer nada.
-	if cliente.Categoria == "Premium" {
-		return nil
-	}
-
-	cliente.Categoria = "Premium"
-
-	if err := sm.store.ActualizarCliente(ctx, cliente); err != nil {
-		return fmt.Errorf("error al actualizar al cliente %s a Premium: %w", telefono, err)
-	}
-
-	// Notificar al cliente de su nuevo estatus.
-	msg := "¡Felicidades! Gracias a tu lealtad, has sido ascendido a Cliente Premium. A partir de ahora, podrás elegir un horario de entrega preferido para tus pedidos."
-	if err := sm.sender.SendMessage(telefono, msg); err != nil {
-		fmt.Printf("Error al enviar mensaje de promoción a %s: %v\n", telefono, err)
-	}
-
-	return nil
-}
-
-// GenerarRutaDiaria simula el corte de las 5:00 AM para generar la ruta del día.
-func (sm *StateMachine) GenerarRutaDiaria(ctx context.Context) {
-	pedidos, err := sm.store.GetPedidosPorEstado(ctx, "pendiente")
-	if err != nil {
-		fmt.Printf("Error al generar la ruta diaria: %v\n", err)
-		return
-	}
-
-	if len(pedidos) == 0 {
-		fmt.Println("No hay pedidos pendientes para la ruta de hoy.")
-		return
-	}
-
-	// Estructura para la salida JSON.
-	type PuntoDeEntrega struct {
-		PedidoID  int     `json:"pedido_id"`
-		Direccion string  `json:"direccion"`
-		Latitud   float64 `json:"latitud"`
-		Longitud  float64 `json:"longitud"`
-	}
-
-	puntos := make([]PuntoDeEntrega, 0, len(pedidos))
-	for _, p := range pedidos {
-		puntos = append(puntos, PuntoDeEntrega{
-			PedidoID:  p.ID,
-			Direccion: p.Direccion,
-			Latitud:   p.Latitud,
-			Longitud:  p.Longitud,
-		})
-	}
-
-	// Convertir a JSON.
-	jsonData, err := json.MarshalIndent(puntos, "", "  ")
-	if err != nil {
-		fmt.Printf("Error al generar JSON para la ruta: %v\n", err)
-		return
-	}
-
-	// Imprimir el JSON en el log.
-	fmt.Println("--- CORTE 5:00 AM - RUTA DEL DÍA (JSON) ---")
-	fmt.Println(string(jsonData))
-	fmt.Println("-----------------------------------------")
-}
-
 // NotificarLlegadaAPlanta envía un mensaje al cliente informando que su cilindro llegó a la planta.
 func (sm *StateMachine) NotificarLlegadaAPlanta(ctx context.Context, clienteID int, telefono string) error {
 	pedido, err := sm.store.GetUltimoPedidoActivo(ctx, clienteID)
@@ -1048,42 +1011,25 @@ func (sm *StateMachine) NotificarPedidoEnRuta(ctx context.Context, clienteID int
 	return nil
 }
 
-// NotificarAlertaARepartidor simula el envío de una alerta al punto de venta o al repartidor.
-func (sm *StateMachine) NotificarAlertaARepartidor(ctx context.Context, telefono string) {
-	cliente, err := sm.store.GetClientePorTelefono(ctx, telefono)
-	if err != nil || cliente == nil {
-		fmt.Printf("Error crítico: no se pudo encontrar al cliente %s para notificar alerta a repartidor.\n", telefono)
-		return
-	}
+func getPrecioGasLitro() float64 {
+	return getPrecioDesdeEnv("PRECIO_GAS_LITRO", 12.50)
+}
 
-	pedido, err := sm.store.GetUltimoPedidoActivo(ctx, cliente.ID)
-	if err != nil || pedido == nil {
-		fmt.Printf("Error crítico: no se pudo encontrar el pedido activo para el cliente %s.\n", telefono)
-		return
-	}
-
-	logMsg := fmt.Sprintf(
-		"[ALERTA OPERADOR] Cliente %s (Tel: %s) ha reportado un SELLO VIOLADO para el Pedido #%d. Instrucción: NO REALIZAR EL COBRO.",
-		cliente.Nombre,
-		telefono,
-		pedido.ID,
-	)
-	// En un sistema real, esto se enviaría a una API del punto de venta.
-	// Por ahora, lo imprimimos en el log del sistema.
-	fmt.Println(logMsg)
+func getPrecioCilindro() float64 {
+	return getPrecioDesdeEnv("PRECIO_CILINDRO", 480.00)
 }
 
-func getPrecioGasLitro() float64 {
-	precioStr := os.Getenv("PRECIO_GAS_LITRO")
+func getPrecioDesdeEnv(variable string, porDefecto float64) float64 {
+	precioStr := os.Getenv(variable)
 	if precioStr == "" {
-		fmt.Println("ADVERTENCIA: La variable de entorno PRECIO_GAS_LITRO no está configurada. Usando precio por defecto de 12.50.")
-		return 12.50
+		fmt.Printf("ADVERTENCIA: La variable de entorno %s no está configurada. Usando precio por defecto de %.2f.\n", variable, porDefecto)
+		return porDefecto
 	}
 
 	precio, err := strconv.ParseFloat(precioStr, 64)
 	if err != nil {
-		fmt.Printf("ADVERTENCIA: Error al parsear PRECIO_GAS_LITRO ('%s'). Usando precio por defecto de 12.50.\n", precioStr)
-		return 12.50
+		fmt.Printf("ADVERTENCIA: Error al parsear %s ('%s'). Usando precio por defecto de %.2f.\n", variable, precioStr, porDefecto)
+		return porDefecto
 	}
 
 	return precio
@@ -1101,45 +1047,33 @@ func (sm *StateMachine) getUserMutex(telefono string) *sync.Mutex {
 	return mu
 }
 
-func (sm *StateMachine) AsignarStrike(ctx context.Context, telefono string) error {
-	cliente, err := sm.store.GetClientePorTelefono(ctx, telefono)
-	if err != nil {
-		return fmt.Errorf("no se pudo encontrar al cliente %s para asignarle un strike: %w", telefono, err)
-	}
-	if cliente == nil {
-		return fmt.Errorf("intento de asignar strike a un cliente no existente: %s", telefono)
-	}
-
-	// Incrementar strike
-	cliente.Strikes++
+// datoTemp regresa un dato temporal del flujo en curso del cliente. A
+// diferencia de Session.DatosTemp, no se comparte entre clientes.
+func (sm *StateMachine) datoTemp(telefono, clave string) interface{} {
+	sm.datosMutex.Lock()
+	defer sm.datosMutex.Unlock()
+	return sm.datosTemp[telefono][clave]
+}
 
-	// Si llega a 3 strikes, bloquearlo.
-	if cliente.Strikes >= 3 {
-		cliente.Bloqueado = true
-		msg := fmt.Sprintf(
-			"Has acumulado %d strikes por no atender a nuestro repartidor. Tu número ha sido bloqueado y ya no podrás realizar pedidos por este medio.",
-			cliente.Strikes,
-		)
-		if err := sm.sender.SendMessage(telefono, msg); err != nil {
-			// Loggear el error, pero continuar para guardar el estado de bloqueo.
-			fmt.Printf("Error al enviar mensaje de bloqueo a %s: %v\n", telefono, err)
-		}
-	} else {
-		// Notificar del strike y reagendamiento.
-		msg := fmt.Sprintf(
-			"Hola %s. No pudimos completar tu entrega porque no se atendió a nuestro repartidor en el tiempo límite de 10 minutos. Se te ha asignado un strike (%d de 3).\n\nTu pedido ha sido reagendado para mañana. Acumular 3 strikes resultará en el bloqueo de tu número.",
-			cliente.Nombre,
-			cliente.Strikes,
-		)
-		if err := sm.sender.SendMessage(telefono, msg); err != nil {
-			fmt.Printf("Error al enviar mensaje de strike a %s: %v\n", telefono, err)
-		}
+func (sm *StateMachine) guardarDatoTemp(telefono, clave string, valor interface{}) {
+	sm.datosMutex.Lock()
+	defer sm.datosMutex.Unlock()
+	datos, ok := sm.datosTemp[telefono]
+	if !ok {
+		datos = make(map[string]interface{})
+		sm.datosTemp[telefono] = datos
 	}
+	datos[clave] = valor
+}
 
-	// Guardar los cambios en la base de datos.
-	if err := sm.store.ActualizarCliente(ctx, cliente); err != nil {
-		return fmt.Errorf("error al actualizar al cliente %s con el nuevo strike: %w", telefono, err)
+func (sm *StateMachine) borrarDatosTemp(telefono string, claves ...string) {
+	sm.datosMutex.Lock()
+	defer sm.datosMutex.Unlock()
+	datos := sm.datosTemp[telefono]
+	for _, clave := range claves {
+		delete(datos, clave)
+	}
+	if len(datos) == 0 {
+		delete(sm.datosTemp, telefono)
 	}
-
-	return nil
 }
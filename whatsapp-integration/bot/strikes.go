@@ -0,0 +1,329 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"example.com/whatsapp-integration/store"
+)
+
+// MaxStrikes es el número de strikes vigentes con el que se bloquea al cliente.
+const MaxStrikes = 3
+
+// MotivoNoAtendido es el motivo del strike por no atender al repartidor.
+const MotivoNoAtendido = "no_atendido"
+
+// Estados de una apelación
+const (
+	ApelacionAbierta   = "abierta"
+	ApelacionAceptada  = "aceptada"
+	ApelacionRechazada = "rechazada"
+)
+
+// vigenciaStrikesDesdeEnv lee STRIKES_VIGENCIA_DIAS; por defecto 90 días.
+func vigenciaStrikesDesdeEnv() time.Duration {
+	if dias, err := strconv.Atoi(os.Getenv("STRIKES_VIGENCIA_DIAS")); err == nil && dias > 0 {
+		return time.Duration(dias) * 24 * time.Hour
+	}
+	return 90 * 24 * time.Hour
+}
+
+// SetEspera fija cuánto espera el repartidor en el domicilio (ESPERA_MINUTOS)
+// para anunciarlo en el resumen del pedido.
+func (sm *StateMachine) SetEspera(d time.Duration) {
+	if d > 0 {
+		sm.espera = d
+	}
+}
+
+// AsignarStrike registra un strike con su motivo y pedido, y le notifica al
+// cliente. Con MaxStrikes vigentes el cliente queda bloqueado. Se llama desde
+// la espera en el domicilio, así que toma el mutex del cliente para no pisar
+// un mensaje que se esté procesando.
+func (sm *StateMachine) AsignarStrike(ctx context.Context, telefono, motivo string, pedidoID int) error {
+	mu := sm.getUserMutex(telefono)
+	mu.Lock()
+	defer mu.Unlock()
+
+	cliente, err := sm.store.GetClientePorTelefono(ctx, telefono)
+	if err != nil {
+		return fmt.Errorf("no se pudo encontrar al cliente %s para asignarle un strike: %w", telefono, err)
+	}
+	if cliente == nil {
+		return fmt.Errorf("intento de asignar strike a un cliente no existente: %s", telefono)
+	}
+
+	ahora := time.Now()
+	strike := &store.Strike{
+		ClienteID: cliente.ID,
+		Motivo:    motivo,
+		ExpiraEn:  ahora.Add(sm.vigencia),
+	}
+	if pedidoID != 0 {
+		strike.PedidoID = &pedidoID
+	}
+	if err := sm.store.CrearStrike(ctx, strike); err != nil {
+		return fmt.Errorf("error al registrar el strike del cliente %s: %w", telefono, err)
+	}
+	sm.auditar(ctx, cliente.ID, "strike", "sistema", fmt.Sprintf("strike %d por %s (pedido %d)", strike.ID, motivo, pedidoID))
+
+	vigentes, _, err := sm.strikesVigentes(ctx, cliente.ID, ahora)
+	if err != nil {
+		return err
+	}
+	cliente.Strikes = len(vigentes)
+
+	if cliente.Strikes >= MaxStrikes {
+		cliente.Bloqueado = true
+		sm.auditar(ctx, cliente.ID, "bloqueo", "sistema", fmt.Sprintf("%d strikes vigentes", cliente.Strikes))
+		msg := fmt.Sprintf(
+			"Has acumulado %d strikes por no atender a nuestro repartidor. Tu número ha sido bloqueado y ya no podrás realizar pedidos por este medio.\n\nSi crees que es un error, escribe APELAR.",
+			cliente.Strikes,
+		)
+		if err := sm.sender.SendMessage(telefono, msg); err != nil {
+			// Loggear el error, pero continuar para guardar el estado de bloqueo.
+			fmt.Printf("Error al enviar mensaje de bloqueo a %s: %v\n", telefono, err)
+		}
+	} else {
+		msg := fmt.Sprintf(
+			"Hola %s. No pudimos completar tu entrega porque no se atendió a nuestro repartidor en el tiempo de espera. Se te ha asignado un strike (%d de %d) que vence el %s.\n\nAcumular %d strikes resultará en el bloqueo de tu número. Si crees que es un error, escribe APELAR.",
+			cliente.Nombre,
+			cliente.Strikes,
+			MaxStrikes,
+			strike.ExpiraEn.In(sm.loc).Format(formatoFechaCliente),
+			MaxStrikes,
+		)
+		if err := sm.sender.SendMessage(telefono, msg); err != nil {
+			fmt.Printf("Error al enviar mensaje de strike a %s: %v\n", telefono, err)
+		}
+	}
+
+	// Guardar los cambios en la base de datos.
+	if err := sm.store.ActualizarCliente(ctx, cliente); err != nil {
+		return fmt.Errorf("error al actualizar al cliente %s con el nuevo strike: %w", telefono, err)
+	}
+	return nil
+}
+
+// strikesVigentes regresa los strikes que cuentan hoy y el historial completo.
+func (sm *StateMachine) strikesVigentes(ctx context.Context, clienteID int, ahora time.Time) (vigentes, todos []*store.Strike, err error) {
+	todos, err = sm.store.GetStrikesCliente(ctx, clienteID)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, s := range todos {
+		if s.Vigente(ahora) {
+			vigentes = append(vigentes, s)
+		}
+	}
+	return vigentes, todos, nil
+}
+
+// revisarVencimientoStrikes actualiza el conteo con los strikes vencidos y
+// desbloquea al cliente si ya no llega al límite. Los bloqueos anteriores a los
+// strikes con fecha (sin registros) solo los levanta un administrador.
+func (sm *StateMachine) revisarVencimientoStrikes(ctx context.Context, cliente *store.Cliente) error {
+	vigentes, todos, err := sm.strikesVigentes(ctx, cliente.ID, time.Now())
+	if err != nil {
+		return err
+	}
+	if len(todos) == 0 {
+		return nil
+	}
+	desbloquear := cliente.Bloqueado && len(vigentes) < MaxStrikes
+	if len(vigentes) == cliente.Strikes && !desbloquear {
+		return nil
+	}
+
+	cliente.Strikes = len(vigentes)
+	if desbloquear {
+		cliente.Bloqueado = false
+		sm.auditar(ctx, cliente.ID, "desbloqueo", "sistema", "strikes vencidos")
+	}
+	return sm.store.ActualizarCliente(ctx, cliente)
+}
+
+// handleApelacion abre un caso para que el personal revise el último strike
+// vigente del cliente. Primero pide la explicación y luego la registra.
+func (sm *StateMachine) handleApelacion(ctx context.Context, telefono, mensaje string) error {
+	cliente, err := sm.store.GetClientePorTelefono(ctx, telefono)
+	if err != nil {
+		return fmt.Errorf("error buscando cliente: %w", err)
+	}
+	if cliente == nil {
+		sm.sender.SendMessage(telefono, "No encontramos tu registro. Escribe cualquier mensaje para comenzar.")
+		return nil
+	}
+
+	vigentes, _, err := sm.strikesVigentes(ctx, cliente.ID, time.Now())
+	if err != nil {
+		return err
+	}
+	if len(vigentes) == 0 && !cliente.Bloqueado {
+		sm.sender.SendMessage(telefono, "No tienes strikes vigentes que apelar.")
+		return sm.actualizarEstado(ctx, telefono, EstadoInicial)
+	}
+
+	if cliente.EstadoConversacion != EstadoApelandoStrike {
+		abiertas, err := sm.store.GetApelaciones(ctx, ApelacionAbierta)
+		if err != nil {
+			return err
+		}
+		for _, a := range abiertas {
+			if a.ClienteID == cliente.ID {
+				sm.sender.SendMessage(telefono, fmt.Sprintf("Ya tienes una apelación en revisión (#%d). Te avisaremos cuando se resuelva.", a.ID))
+				return nil
+			}
+		}
+		sm.sender.SendMessage(telefono, "Cuéntanos en un mensaje qué pasó con tu entrega. Un supervisor revisará tu caso.")
+		return sm.actualizarEstado(ctx, telefono, EstadoApelandoStrike)
+	}
+
+	texto := strings.TrimSpace(mensaje)
+	if texto == "" || strings.ToUpper(texto) == "APELAR" {
+		sm.sender.SendMessage(telefono, "Por favor, escribe tu explicación en un mensaje.")
+		return nil
+	}
+
+	apelacion := &store.Apelacion{
+		ClienteID: cliente.ID,
+		Mensaje:   texto,
+		Estado:    ApelacionAbierta,
+	}
+	if len(vigentes) > 0 {
+		apelacion.StrikeID = &vigentes[0].ID // el más reciente
+	}
+	if err := sm.store.CrearApelacion(ctx, apelacion); err != nil {
+		return err
+	}
+	sm.auditar(ctx, cliente.ID, "apelacion", telefono, fmt.Sprintf("apelación %d abierta", apelacion.ID))
+
+	sm.sender.SendMessage(telefono, fmt.Sprintf("Recibimos tu apelación #%d. Un supervisor la revisará y te responderemos por este medio.", apelacion.ID))
+	return sm.actualizarEstado(ctx, telefono, EstadoInicial)
+}
+
+// ResolverApelacion cierra la apelación. Si se acepta, el strike apelado se
+// anula y el cliente se desbloquea cuando deja de llegar al límite.
+func (sm *StateMachine) ResolverApelacion(ctx context.Context, apelacionID int, aceptar bool, resolucion, operador string) (*store.Apelacion, error) {
+	apelacion, err := sm.store.GetApelacion(ctx, apelacionID)
+	if err != nil {
+		return nil, err
+	}
+	if apelacion == nil {
+		return nil, fmt.Errorf("apelación %d no encontrada", apelacionID)
+	}
+	if apelacion.Estado != ApelacionAbierta {
+		return nil, fmt.Errorf("la apelación %d ya fue resuelta", apelacionID)
+	}
+	cliente, err := sm.store.GetClientePorID(ctx, apelacion.ClienteID)
+	if err != nil {
+		return nil, err
+	}
+	if cliente == nil {
+		return nil, fmt.Errorf("cliente %d no encontrado", apelacion.ClienteID)
+	}
+
+	ahora := time.Now()
+	apelacion.Estado = ApelacionRechazada
+	if aceptar {
+		apelacion.Estado = ApelacionAceptada
+	}
+	apelacion.Resolucion = resolucion
+	apelacion.ResueltaPor = operador
+	apelacion.ResueltaEn = &ahora
+	if err := sm.store.ActualizarApelacion(ctx, apelacion); err != nil {
+		return nil, err
+	}
+
+	msg := fmt.Sprintf("Tu apelación #%d fue rechazada.", apelacion.ID)
+	if aceptar {
+		if apelacion.StrikeID != nil {
+			if err := sm.store.AnularStrike(ctx, *apelacion.StrikeID); err != nil {
+				return nil, err
+			}
+		}
+		if err := sm.recalcularStrikes(ctx, cliente); err != nil {
+			return nil, err
+		}
+		msg = fmt.Sprintf("Tu apelación #%d fue aceptada y el strike fue retirado. Strikes vigentes: %d de %d.", apelacion.ID, cliente.Strikes, MaxStrikes)
+	}
+	if resolucion != "" {
+		msg += "\n\n" + resolucion
+	}
+	sm.auditar(ctx, cliente.ID, "apelacion_"+apelacion.Estado, operador, fmt.Sprintf("apelación %d: %s", apelacion.ID, resolucion))
+
+	if err := sm.sender.SendMessage(cliente.NumeroTelefono, msg); err != nil {
+		fmt.Printf("Error al avisar resolución de apelación a %s: %v\n", cliente.NumeroTelefono, err)
+	}
+	return apelacion, nil
+}
+
+// Desbloquear levanta el bloqueo de un cliente. Con anularStrikes también se
+// anulan sus strikes vigentes para que el siguiente no lo vuelva a bloquear.
+func (sm *StateMachine) Desbloquear(ctx context.Context, telefono, operador, motivo string, anularStrikes bool) (*store.Cliente, error) {
+	cliente, err := sm.store.GetClientePorTelefono(ctx, telefono)
+	if err != nil {
+		return nil, err
+	}
+	if cliente == nil {
+		return nil, fmt.Errorf("cliente %s no encontrado", telefono)
+	}
+
+	if anularStrikes {
+		vigentes, _, err := sm.strikesVigentes(ctx, cliente.ID, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range vigentes {
+			if err := sm.store.AnularStrike(ctx, s.ID); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	cliente.Bloqueado = false
+	if err := sm.recalcularStrikes(ctx, cliente); err != nil {
+		return nil, err
+	}
+	detalle := motivo
+	if anularStrikes {
+		detalle += " (strikes anulados)"
+	}
+	sm.auditar(ctx, cliente.ID, "desbloqueo", operador, detalle)
+
+	if err := sm.sender.SendMessage(telefono, "Tu número fue desbloqueado. Ya puedes volver a hacer pedidos con nosotros."); err != nil {
+		fmt.Printf("Error al avisar desbloqueo a %s: %v\n", telefono, err)
+	}
+	return cliente, nil
+}
+
+// recalcularStrikes guarda el conteo de strikes vigentes y desbloquea al
+// cliente si ya no llega al límite.
+func (sm *StateMachine) recalcularStrikes(ctx context.Context, cliente *store.Cliente) error {
+	vigentes, _, err := sm.strikesVigentes(ctx, cliente.ID, time.Now())
+	if err != nil {
+		return err
+	}
+	cliente.Strikes = len(vigentes)
+	if cliente.Strikes < MaxStrikes {
+		cliente.Bloqueado = false
+	}
+	return sm.store.ActualizarCliente(ctx, cliente)
+}
+
+// auditar deja constancia de una acción sobre el cliente; un error solo se registra en el log.
+func (sm *StateMachine) auditar(ctx context.Context, clienteID int, accion, operador, detalle string) {
+	registro := &store.AuditoriaCliente{
+		ClienteID: clienteID,
+		Accion:    accion,
+		Operador:  operador,
+		Detalle:   detalle,
+	}
+	if err := sm.store.RegistrarAuditoria(ctx, registro); err != nil {
+		fmt.Printf("Error registrando auditoría (%s) del cliente %d: %v\n", accion, clienteID, err)
+	}
+}
@@ -0,0 +1,129 @@
+package bot
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"example.com/whatsapp-integration/store"
+)
+
+// storeStrikes solo implementa lo que usan los strikes; el resto del Store
+// queda nil y haría panic si se llamara.
+type storeStrikes struct {
+	store.Store
+	cliente     *store.Cliente
+	strikes     []*store.Strike
+	actualizado bool
+	auditoria   []string
+}
+
+func (s *storeStrikes) GetClientePorTelefono(ctx context.Context, telefono string) (*store.Cliente, error) {
+	return s.cliente, nil
+}
+
+func (s *storeStrikes) GetStrikesCliente(ctx context.Context, clienteID int) ([]*store.Strike, error) {
+	return s.strikes, nil
+}
+
+func (s *storeStrikes) CrearStrike(ctx context.Context, strike *store.Strike) error {
+	strike.ID = len(s.strikes) + 1
+	s.strikes = append(s.strikes, strike)
+	return nil
+}
+
+func (s *storeStrikes) ActualizarCliente(ctx context.Context, cliente *store.Cliente) error {
+	s.actualizado = true
+	return nil
+}
+
+func (s *storeStrikes) RegistrarAuditoria(ctx context.Context, registro *store.AuditoriaCliente) error {
+	s.auditoria = append(s.auditoria, registro.Accion)
+	return nil
+}
+
+type senderPrueba struct {
+	mensajes []string
+}
+
+func (s *senderPrueba) SendMessage(to, text string) error {
+	s.mensajes = append(s.mensajes, text)
+	return nil
+}
+
+func (s *senderPrueba) SendImage(to, imageURL, caption string) error {
+	return nil
+}
+
+func TestRevisarVencimientoStrikes(t *testing.T) {
+	vencido := &store.Strike{ExpiraEn: time.Now().Add(-time.Hour)}
+	vigente := &store.Strike{ExpiraEn: time.Now().Add(time.Hour)}
+	anulado := &store.Strike{ExpiraEn: time.Now().Add(time.Hour), Anulado: true}
+
+	casos := []struct {
+		nombre      string
+		cliente     store.Cliente
+		strikes     []*store.Strike
+		strikesFin  int
+		bloqueado   bool
+		actualizado bool
+	}{
+		{"bloqueo anterior sin historial", store.Cliente{Strikes: 3, Bloqueado: true}, nil, 3, true, false},
+		{"conteo al día", store.Cliente{Strikes: 1}, []*store.Strike{vigente, vencido}, 1, false, false},
+		{"un strike vencido deja de contar", store.Cliente{Strikes: 2}, []*store.Strike{vigente, vencido}, 1, false, true},
+		{"el anulado no cuenta", store.Cliente{Strikes: 2}, []*store.Strike{vigente, anulado}, 1, false, true},
+		{"vence uno y se levanta el bloqueo", store.Cliente{Strikes: 3, Bloqueado: true}, []*store.Strike{vigente, vigente, vencido}, 2, false, true},
+		{"sigue bloqueado con tres vigentes", store.Cliente{Strikes: 3, Bloqueado: true}, []*store.Strike{vigente, vigente, vigente}, 3, true, false},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			cliente := c.cliente
+			cliente.ID = 1
+			st := &storeStrikes{cliente: &cliente, strikes: c.strikes}
+			sm := NewStateMachine(st, &senderPrueba{}, nil)
+
+			if err := sm.revisarVencimientoStrikes(context.Background(), &cliente); err != nil {
+				t.Fatal(err)
+			}
+			if cliente.Strikes != c.strikesFin || cliente.Bloqueado != c.bloqueado {
+				t.Fatalf("strikes = %d, bloqueado = %v; se esperaba %d, %v", cliente.Strikes, cliente.Bloqueado, c.strikesFin, c.bloqueado)
+			}
+			if st.actualizado != c.actualizado {
+				t.Fatalf("actualizado = %v, se esperaba %v", st.actualizado, c.actualizado)
+			}
+		})
+	}
+}
+
+// TestAsignarStrikeBloqueaConVigentes cuenta solo los strikes vigentes: un
+// tercer strike con uno vencido en el historial no bloquea.
+func TestAsignarStrikeBloqueaConVigentes(t *testing.T) {
+	ctx := context.Background()
+	vencido := &store.Strike{ID: 1, ExpiraEn: time.Now().Add(-time.Hour)}
+	vigente := &store.Strike{ID: 2, ExpiraEn: time.Now().Add(time.Hour)}
+
+	cliente := &store.Cliente{ID: 1, Nombre: "Ana", Strikes: 1}
+	st := &storeStrikes{cliente: cliente, strikes: []*store.Strike{vencido, vigente}}
+	sm := NewStateMachine(st, &senderPrueba{}, nil)
+
+	if err := sm.AsignarStrike(ctx, "5215550000001", MotivoNoAtendido, 7); err != nil {
+		t.Fatal(err)
+	}
+	if cliente.Strikes != 2 || cliente.Bloqueado {
+		t.Fatalf("strikes = %d, bloqueado = %v; se esperaban 2 sin bloqueo", cliente.Strikes, cliente.Bloqueado)
+	}
+	nuevo := st.strikes[len(st.strikes)-1]
+	if nuevo.PedidoID == nil || *nuevo.PedidoID != 7 || !nuevo.ExpiraEn.After(time.Now().Add(sm.vigencia-time.Minute)) {
+		t.Fatalf("strike = %+v, se esperaba el pedido 7 y la vigencia configurada", nuevo)
+	}
+
+	if err := sm.AsignarStrike(ctx, "5215550000001", MotivoNoAtendido, 8); err != nil {
+		t.Fatal(err)
+	}
+	if cliente.Strikes != MaxStrikes || !cliente.Bloqueado {
+		t.Fatalf("strikes = %d, bloqueado = %v; se esperaba el bloqueo", cliente.Strikes, cliente.Bloqueado)
+	}
+	if got := st.auditoria[len(st.auditoria)-1]; got != "bloqueo" {
+		t.Fatalf("última auditoría = %q, se esperaba bloqueo", got)
+	}
+}
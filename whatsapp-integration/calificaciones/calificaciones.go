@@ -0,0 +1,200 @@
+// Package calificaciones guarda la calificación que el cliente da a cada
+// entrega, ligada al pedido y al repartidor que lo entregó, y avisa a un
+// supervisor cuando la calificación es baja.
+package calificaciones
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"example.com/whatsapp-integration/store"
+)
+
+// UmbralPorDefecto es la calificación más alta que se escala a un
+// supervisor si no se configura CALIFICACIONES_UMBRAL.
+const UmbralPorDefecto = 2
+
+var (
+	ErrPuntuacion               = errors.New("la calificación debe ser de 1 a 5 estrellas")
+	ErrYaCalificado             = errors.New("el pedido ya fue calificado")
+	ErrCalificacionNoEncontrada = errors.New("calificación no encontrada")
+)
+
+// Config son los parámetros del escalamiento.
+type Config struct {
+	Umbral       int      // estrellas; esta calificación o menos se escala
+	Supervisores []string // teléfonos; vacío solo deja la calificación en el log
+}
+
+// ConfigDesdeEnv lee CALIFICACIONES_UMBRAL (por defecto 2) y
+// CALIFICACIONES_SUPERVISORES (teléfonos separados por comas).
+func ConfigDesdeEnv() Config {
+	cfg := Config{Umbral: UmbralPorDefecto}
+	if v, err := strconv.Atoi(os.Getenv("CALIFICACIONES_UMBRAL")); err == nil && v >= 1 && v <= 5 {
+		cfg.Umbral = v
+	}
+	for _, t := range strings.Split(os.Getenv("CALIFICACIONES_SUPERVISORES"), ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			cfg.Supervisores = append(cfg.Supervisores, t)
+		}
+	}
+	return cfg
+}
+
+// Notificador envía mensajes de WhatsApp.
+type Notificador interface {
+	SendMessage(to, message string) error
+}
+
+// Servicio registra las calificaciones y arma el reporte semanal.
+type Servicio struct {
+	store  store.Store
+	cfg    Config
+	loc    *time.Location
+	sender Notificador
+}
+
+func NewServicio(s store.Store, cfg Config, loc *time.Location) *Servicio {
+	if loc == nil {
+		loc = time.Local
+	}
+	if cfg.Umbral < 1 || cfg.Umbral > 5 {
+		cfg.Umbral = UmbralPorDefecto
+	}
+	return &Servicio{store: s, cfg: cfg, loc: loc}
+}
+
+// SetSender habilita el aviso a los supervisores de las calificaciones bajas.
+func (s *Servicio) SetSender(n Notificador) {
+	s.sender = n
+}
+
+// Baja indica si la calificación se escala a un supervisor.
+func (s *Servicio) Baja(puntuacion int) bool {
+	return puntuacion <= s.cfg.Umbral
+}
+
+// Registrar guarda la calificación del pedido con el repartidor que lo
+// entregó. Si es baja, la escala a un supervisor.
+func (s *Servicio) Registrar(ctx context.Context, cliente *store.Cliente, pedido *store.Pedido, puntuacion int, ahora time.Time) (*store.Calificacion, error) {
+	if puntuacion < 1 || puntuacion > 5 {
+		return nil, ErrPuntuacion
+	}
+	existente, err := s.store.GetCalificacionPedido(ctx, pedido.ID)
+	if err != nil {
+		return nil, err
+	}
+	if existente != nil {
+		return existente, ErrYaCalificado
+	}
+	repartidor, err := s.repartidorDelPedido(ctx, pedido.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error buscando repartidor del pedido %d: %w", pedido.ID, err)
+	}
+
+	c := &store.Calificacion{
+		PedidoID:   pedido.ID,
+		ClienteID:  cliente.ID,
+		Puntuacion: puntuacion,
+		CreatedAt:  ahora,
+	}
+	if repartidor != nil {
+		c.RepartidorID = repartidor.ID
+	}
+	if err := s.store.CrearCalificacion(ctx, c); err != nil {
+		return nil, err
+	}
+	log.Printf("Pedido %d calificado con %d estrellas (repartidor %d)\n", pedido.ID, puntuacion, c.RepartidorID)
+
+	if s.Baja(puntuacion) {
+		s.escalar(c, cliente, repartidor, ahora)
+		if err := s.store.ActualizarCalificacion(ctx, c); err != nil {
+			return c, err
+		}
+	}
+	return c, nil
+}
+
+// Comentar agrega el comentario del cliente a la calificación del pedido.
+// Si la calificación se escaló, el supervisor también lo recibe.
+func (s *Servicio) Comentar(ctx context.Context, pedidoID int, comentario string) (*store.Calificacion, error) {
+	c, err := s.store.GetCalificacionPedido(ctx, pedidoID)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, ErrCalificacionNoEncontrada
+	}
+	c.Comentario = strings.TrimSpace(comentario)
+	if err := s.store.ActualizarCalificacion(ctx, c); err != nil {
+		return nil, err
+	}
+	if c.EscaladaA != "" && s.sender != nil {
+		msg := fmt.Sprintf("💬 Comentario del cliente sobre el pedido #%d (%d ⭐):\n\"%s\"", c.PedidoID, c.Puntuacion, c.Comentario)
+		if err := s.sender.SendMessage(c.EscaladaA, msg); err != nil {
+			log.Printf("Error enviando comentario de la calificación %d al supervisor: %v\n", c.ID, err)
+		}
+	}
+	return c, nil
+}
+
+// escalar avisa a un supervisor; se reparten por turnos según el ID de la
+// calificación.
+func (s *Servicio) escalar(c *store.Calificacion, cliente *store.Cliente, repartidor *store.Repartidor, ahora time.Time) {
+	if len(s.cfg.Supervisores) == 0 || s.sender == nil {
+		log.Printf("Calificación baja (%d estrellas) del pedido %d sin supervisor a quien escalar\n", c.Puntuacion, c.PedidoID)
+		return
+	}
+	supervisor := s.cfg.Supervisores[c.ID%len(s.cfg.Supervisores)]
+	nombreRepartidor := "sin asignar"
+	if repartidor != nil {
+		nombreRepartidor = repartidor.Nombre
+	}
+	msg := fmt.Sprintf("⚠️ *Calificación baja: %d ⭐*\n\nPedido #%d\nCliente: %s (%s)\nRepartidor: %s\n\nPor favor, comunícate con el cliente para atender su caso.",
+		c.Puntuacion, c.PedidoID, cliente.Nombre, cliente.NumeroTelefono, nombreRepartidor)
+	if err := s.sender.SendMessage(supervisor, msg); err != nil {
+		log.Printf("Error escalando la calificación %d al supervisor %s: %v\n", c.ID, supervisor, err)
+		return
+	}
+	c.EscaladaA = supervisor
+	c.EscaladaEn = &ahora
+}
+
+// repartidorDelPedido es quien capturó la entrega o, si no hay evidencia,
+// el repartidor activo del camión de la ruta.
+func (s *Servicio) repartidorDelPedido(ctx context.Context, pedidoID int) (*store.Repartidor, error) {
+	parada, err := s.store.GetParadaPorPedido(ctx, pedidoID)
+	if err != nil || parada == nil {
+		return nil, err
+	}
+	entregas, err := s.store.GetEntregasRuta(ctx, parada.RutaID)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entregas {
+		if e.PedidoID == pedidoID && e.RepartidorID != 0 {
+			return s.store.GetRepartidor(ctx, e.RepartidorID)
+		}
+	}
+
+	ruta, err := s.store.GetRuta(ctx, parada.RutaID)
+	if err != nil || ruta == nil {
+		return nil, err
+	}
+	repartidores, err := s.store.GetRepartidores(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range repartidores {
+		if r.Activo && r.CamionID == ruta.CamionID {
+			return r, nil
+		}
+	}
+	return nil, nil
+}
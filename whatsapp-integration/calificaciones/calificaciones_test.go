@@ -0,0 +1,203 @@
+package calificaciones
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/whatsapp-integration/store"
+)
+
+// storeCalificaciones solo implementa lo que usa el registro de
+// calificaciones; el resto del Store queda nil y haría panic si se llamara.
+type storeCalificaciones struct {
+	store.Store
+	calificaciones map[int]*store.Calificacion // por pedido
+	entregas       []*store.Entrega
+	repartidores   []*store.Repartidor
+}
+
+func (s *storeCalificaciones) GetCalificacionPedido(ctx context.Context, pedidoID int) (*store.Calificacion, error) {
+	return s.calificaciones[pedidoID], nil
+}
+
+func (s *storeCalificaciones) CrearCalificacion(ctx context.Context, c *store.Calificacion) error {
+	c.ID = len(s.calificaciones) + 1
+	s.calificaciones[c.PedidoID] = c
+	return nil
+}
+
+func (s *storeCalificaciones) ActualizarCalificacion(ctx context.Context, c *store.Calificacion) error {
+	s.calificaciones[c.PedidoID] = c
+	return nil
+}
+
+func (s *storeCalificaciones) GetParadaPorPedido(ctx context.Context, pedidoID int) (*store.RutaParada, error) {
+	return &store.RutaParada{RutaID: 1, PedidoID: pedidoID}, nil
+}
+
+func (s *storeCalificaciones) GetEntregasRuta(ctx context.Context, rutaID int) ([]*store.Entrega, error) {
+	return s.entregas, nil
+}
+
+func (s *storeCalificaciones) GetRepartidor(ctx context.Context, id int) (*store.Repartidor, error) {
+	for _, r := range s.repartidores {
+		if r.ID == id {
+			return r, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *storeCalificaciones) GetRuta(ctx context.Context, id int) (*store.Ruta, error) {
+	return &store.Ruta{ID: id, CamionID: 1}, nil
+}
+
+func (s *storeCalificaciones) GetRepartidores(ctx context.Context) ([]*store.Repartidor, error) {
+	return s.repartidores, nil
+}
+
+type mensaje struct {
+	para, texto string
+}
+
+type senderPrueba struct {
+	mensajes []mensaje
+	err      error
+}
+
+func (s *senderPrueba) SendMessage(to, text string) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.mensajes = append(s.mensajes, mensaje{to, text})
+	return nil
+}
+
+func nuevoStore() *storeCalificaciones {
+	return &storeCalificaciones{
+		calificaciones: make(map[int]*store.Calificacion),
+		entregas:       []*store.Entrega{{PedidoID: 10, RepartidorID: 2}},
+		repartidores: []*store.Repartidor{
+			{ID: 1, Nombre: "Luis", CamionID: 1, Activo: true},
+			{ID: 2, Nombre: "Marta", CamionID: 2, Activo: true},
+		},
+	}
+}
+
+func TestRegistrarEscala(t *testing.T) {
+	ahora := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	cliente := &store.Cliente{ID: 7, Nombre: "Ana", NumeroTelefono: "5215550000007"}
+
+	casos := []struct {
+		nombre       string
+		cfg          Config
+		pedidoID     int
+		puntuacion   int
+		envioFalla   bool
+		supervisor   string
+		repartidorID int
+	}{
+		{"cinco estrellas no se escala", Config{Umbral: 2, Supervisores: []string{"sup1"}}, 10, 5, false, "", 2},
+		{"en el umbral se escala", Config{Umbral: 2, Supervisores: []string{"sup1"}}, 10, 2, false, "sup1", 2},
+		{"arriba del umbral no se escala", Config{Umbral: 2, Supervisores: []string{"sup1"}}, 10, 3, false, "", 2},
+		{"umbral configurado", Config{Umbral: 3, Supervisores: []string{"sup1"}}, 10, 3, false, "sup1", 2},
+		{"reparto por turnos", Config{Umbral: 2, Supervisores: []string{"sup1", "sup2"}}, 10, 1, false, "sup2", 2},
+		{"sin supervisores solo queda en el log", Config{Umbral: 2}, 10, 1, false, "", 2},
+		{"falla el envío", Config{Umbral: 2, Supervisores: []string{"sup1"}}, 10, 1, true, "", 2},
+		{"sin evidencia toma el repartidor del camión", Config{Umbral: 2, Supervisores: []string{"sup1"}}, 11, 1, false, "sup1", 1},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			st := nuevoStore()
+			s := NewServicio(st, c.cfg, time.UTC)
+			sender := &senderPrueba{}
+			if c.envioFalla {
+				sender.err = errors.New("sin red")
+			}
+			s.SetSender(sender)
+
+			cal, err := s.Registrar(context.Background(), cliente, &store.Pedido{ID: c.pedidoID}, c.puntuacion, ahora)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cal.RepartidorID != c.repartidorID {
+				t.Fatalf("repartidor = %d, se esperaba %d", cal.RepartidorID, c.repartidorID)
+			}
+			guardada := st.calificaciones[c.pedidoID]
+			if guardada.EscaladaA != c.supervisor {
+				t.Fatalf("escalada a %q, se esperaba %q", guardada.EscaladaA, c.supervisor)
+			}
+			if c.supervisor == "" {
+				if guardada.EscaladaEn != nil || len(sender.mensajes) != 0 {
+					t.Fatalf("calificación = %+v, avisos = %v; no se esperaba escalamiento", guardada, sender.mensajes)
+				}
+				return
+			}
+			if guardada.EscaladaEn == nil || !guardada.EscaladaEn.Equal(ahora) {
+				t.Fatalf("escalada en %v, se esperaba %v", guardada.EscaladaEn, ahora)
+			}
+			if len(sender.mensajes) != 1 || sender.mensajes[0].para != c.supervisor || !strings.Contains(sender.mensajes[0].texto, cliente.NumeroTelefono) {
+				t.Fatalf("avisos = %v", sender.mensajes)
+			}
+		})
+	}
+}
+
+func TestRegistrarValida(t *testing.T) {
+	st := nuevoStore()
+	s := NewServicio(st, Config{Umbral: 2}, time.UTC)
+	ctx := context.Background()
+	cliente := &store.Cliente{ID: 7}
+
+	for _, puntuacion := range []int{0, 6} {
+		if _, err := s.Registrar(ctx, cliente, &store.Pedido{ID: 10}, puntuacion, time.Now()); !errors.Is(err, ErrPuntuacion) {
+			t.Fatalf("Registrar(%d) = %v, se esperaba ErrPuntuacion", puntuacion, err)
+		}
+	}
+	primera, err := s.Registrar(ctx, cliente, &store.Pedido{ID: 10}, 4, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	existente, err := s.Registrar(ctx, cliente, &store.Pedido{ID: 10}, 1, time.Now())
+	if !errors.Is(err, ErrYaCalificado) || existente.ID != primera.ID || existente.Puntuacion != 4 {
+		t.Fatalf("segunda Registrar() = %+v, %v; se esperaba la primera y ErrYaCalificado", existente, err)
+	}
+}
+
+// TestComentarReenviaAlSupervisor manda el comentario al mismo supervisor
+// que recibió la calificación baja, y a nadie si no se escaló.
+func TestComentarReenviaAlSupervisor(t *testing.T) {
+	st := nuevoStore()
+	s := NewServicio(st, Config{Umbral: 2, Supervisores: []string{"sup1"}}, time.UTC)
+	sender := &senderPrueba{}
+	s.SetSender(sender)
+	ctx := context.Background()
+	cliente := &store.Cliente{ID: 7}
+
+	if _, err := s.Registrar(ctx, cliente, &store.Pedido{ID: 10}, 1, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Registrar(ctx, cliente, &store.Pedido{ID: 11}, 5, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	sender.mensajes = nil
+
+	if _, err := s.Comentar(ctx, 10, "  Llegó tarde  "); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Comentar(ctx, 11, "Todo bien"); err != nil {
+		t.Fatal(err)
+	}
+	if st.calificaciones[10].Comentario != "Llegó tarde" {
+		t.Fatalf("comentario = %q", st.calificaciones[10].Comentario)
+	}
+	if len(sender.mensajes) != 1 || sender.mensajes[0].para != "sup1" || !strings.Contains(sender.mensajes[0].texto, "Llegó tarde") {
+		t.Fatalf("avisos = %v, se esperaba solo el comentario de la calificación baja", sender.mensajes)
+	}
+	if _, err := s.Comentar(ctx, 12, "¿?"); !errors.Is(err, ErrCalificacionNoEncontrada) {
+		t.Fatalf("Comentar() sin calificación = %v, se esperaba ErrCalificacionNoEncontrada", err)
+	}
+}
@@ -0,0 +1,113 @@
+package calificaciones
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// ReporteSemana resume las calificaciones de un repartidor en una semana.
+type ReporteSemana struct {
+	Semana         string  `json:"semana"` // lunes de la semana, AAAA-MM-DD
+	RepartidorID   int     `json:"repartidor_id"`
+	Repartidor     string  `json:"repartidor"`
+	Calificaciones int     `json:"calificaciones"`
+	Promedio       float64 `json:"promedio"`
+	Bajas          int     `json:"bajas"`     // en o bajo el umbral de escalamiento
+	Estrellas      [5]int  `json:"estrellas"` // cuántas de 1, 2, 3, 4 y 5 estrellas
+	Comentarios    int     `json:"comentarios"`
+}
+
+// InicioSemana es el lunes a las 00:00 de la semana de t.
+func (s *Servicio) InicioSemana(t time.Time) time.Time {
+	t = t.In(s.loc)
+	dias := (int(t.Weekday()) + 6) % 7
+	return time.Date(t.Year(), t.Month(), t.Day()-dias, 0, 0, 0, 0, s.loc)
+}
+
+// Reporte agrupa por semana y repartidor las calificaciones hechas entre
+// desde y hasta (ambas fechas incluidas).
+func (s *Servicio) Reporte(ctx context.Context, desde, hasta time.Time) ([]*ReporteSemana, error) {
+	inicio := time.Date(desde.Year(), desde.Month(), desde.Day(), 0, 0, 0, 0, s.loc)
+	fin := time.Date(hasta.Year(), hasta.Month(), hasta.Day()+1, 0, 0, 0, 0, s.loc)
+	lista, err := s.store.GetCalificaciones(ctx, inicio, fin)
+	if err != nil {
+		return nil, err
+	}
+	repartidores, err := s.store.GetRepartidores(ctx)
+	if err != nil {
+		return nil, err
+	}
+	nombres := make(map[int]string, len(repartidores))
+	for _, r := range repartidores {
+		nombres[r.ID] = r.Nombre
+	}
+
+	type clave struct {
+		semana       string
+		repartidorID int
+	}
+	grupos := make(map[clave]*ReporteSemana)
+	var reportes []*ReporteSemana
+	sumas := make(map[*ReporteSemana]int)
+	for _, c := range lista {
+		k := clave{s.InicioSemana(c.CreatedAt).Format("2006-01-02"), c.RepartidorID}
+		r, ok := grupos[k]
+		if !ok {
+			r = &ReporteSemana{Semana: k.semana, RepartidorID: c.RepartidorID, Repartidor: nombres[c.RepartidorID]}
+			if c.RepartidorID == 0 {
+				r.Repartidor = "Sin asignar"
+			}
+			grupos[k] = r
+			reportes = append(reportes, r)
+		}
+		r.Calificaciones++
+		sumas[r] += c.Puntuacion
+		if c.Puntuacion >= 1 && c.Puntuacion <= 5 {
+			r.Estrellas[c.Puntuacion-1]++
+		}
+		if s.Baja(c.Puntuacion) {
+			r.Bajas++
+		}
+		if c.Comentario != "" {
+			r.Comentarios++
+		}
+	}
+	for _, r := range reportes {
+		r.Promedio = math.Round(float64(sumas[r])/float64(r.Calificaciones)*100) / 100
+	}
+	sort.SliceStable(reportes, func(i, j int) bool {
+		if reportes[i].Semana != reportes[j].Semana {
+			return reportes[i].Semana < reportes[j].Semana
+		}
+		return reportes[i].Repartidor < reportes[j].Repartidor
+	})
+	return reportes, nil
+}
+
+// EscribirCSV escribe el reporte con un renglón por semana y repartidor.
+func EscribirCSV(w io.Writer, reportes []*ReporteSemana) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"semana", "repartidor", "calificaciones", "promedio", "bajas", "comentarios", "1_estrella", "2_estrellas", "3_estrellas", "4_estrellas", "5_estrellas"})
+	for _, r := range reportes {
+		renglon := []string{
+			r.Semana,
+			r.Repartidor,
+			strconv.Itoa(r.Calificaciones),
+			fmt.Sprintf("%.2f", r.Promedio),
+			strconv.Itoa(r.Bajas),
+			strconv.Itoa(r.Comentarios),
+		}
+		for _, n := range r.Estrellas {
+			renglon = append(renglon, strconv.Itoa(n))
+		}
+		cw.Write(renglon)
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,169 @@
+package delivery
+
+import (
+	"math"
+	"sort"
+
+	"example.com/whatsapp-integration/store"
+	"example.com/whatsapp-integration/zonas"
+)
+
+// grupoZona son los pedidos con coordenadas de una misma zona.
+type grupoZona struct {
+	zona    string
+	pedidos []*store.Pedido
+}
+
+// AgruparPedidos agrupa los pedidos por zona y proximidad. Dentro de cada
+// zona se usa un barrido angular alrededor de la planta (o del centro de los
+// pedidos si no hay planta) que respeta MaxPedidosPorRuta,
+// DistanciaMaximaRuta y TiempoMaximoRuta. Los pedidos sin coordenadas no se
+// pueden agrupar y se regresan aparte.
+func (s *MapsService) AgruparPedidos(pedidos []*store.Pedido, mapa *zonas.Mapa, config *POSConfig) ([][]*store.Pedido, []*store.Pedido) {
+	var conCoordenadas, sinCoordenadas []*store.Pedido
+	for _, pedido := range pedidos {
+		if tieneCoordenadas(pedido) {
+			conCoordenadas = append(conCoordenadas, pedido)
+		} else {
+			sinCoordenadas = append(sinCoordenadas, pedido)
+		}
+	}
+
+	grupos := make([][]*store.Pedido, 0)
+	for _, zona := range separarPorZona(conCoordenadas, mapa) {
+		centro := centroide(zona.pedidos)
+		if s.depot != nil {
+			centro = *s.depot
+		}
+		grupos = append(grupos, barrido(zona.pedidos, centro, config)...)
+	}
+	return grupos, sinCoordenadas
+}
+
+// separarPorZona reparte los pedidos entre las zonas del operador. Sin zonas
+// configuradas todos quedan en un solo grupo; los que caen fuera de toda zona
+// forman el grupo "".
+func separarPorZona(pedidos []*store.Pedido, mapa *zonas.Mapa) []grupoZona {
+	indice := make(map[string]int)
+	var grupos []grupoZona
+	for _, p := range pedidos {
+		zona := mapa.Zona(p.Latitud, p.Longitud)
+		i, ok := indice[zona]
+		if !ok {
+			i = len(grupos)
+			indice[zona] = i
+			grupos = append(grupos, grupoZona{zona: zona})
+		}
+		grupos[i].pedidos = append(grupos[i].pedidos, p)
+	}
+	return grupos
+}
+
+// barrido agrupa los pedidos por ángulo alrededor del centro (algoritmo de
+// barrido): avanza como las manecillas de un reloj y cierra el grupo cuando el
+// siguiente pedido rebasaría el máximo de paradas, de kilómetros o de minutos.
+func barrido(pedidos []*store.Pedido, centro Location, config *POSConfig) [][]*store.Pedido {
+	ordenados := append([]*store.Pedido(nil), pedidos...)
+	sort.SliceStable(ordenados, func(i, j int) bool {
+		return angulo(centro, ordenados[i]) < angulo(centro, ordenados[j])
+	})
+
+	// Se inicia el barrido en el hueco angular más grande para no partir un
+	// grupo natural entre el final y el principio de la vuelta.
+	if n := len(ordenados); n > 1 {
+		inicio, mayor := 0, 0.0
+		for i := 0; i < n; i++ {
+			a := angulo(centro, ordenados[i])
+			b := angulo(centro, ordenados[(i+1)%n])
+			hueco := b - a
+			if hueco <= 0 {
+				hueco += 2 * math.Pi
+			}
+			if hueco > mayor {
+				inicio, mayor = (i+1)%n, hueco
+			}
+		}
+		ordenados = append(ordenados[inicio:], ordenados[:inicio]...)
+	}
+
+	var grupos [][]*store.Pedido
+	var actual []*store.Pedido
+	for _, p := range ordenados {
+		candidato := append(append([]*store.Pedido(nil), actual...), p)
+		if len(actual) > 0 && !dentroDeLimites(candidato, centro, config) {
+			grupos = append(grupos, actual)
+			candidato = []*store.Pedido{p}
+		}
+		actual = candidato
+	}
+	if len(actual) > 0 {
+		grupos = append(grupos, actual)
+	}
+	return grupos
+}
+
+// dentroDeLimites estima el recorrido del grupo con vecino más cercano desde el
+// centro y lo compara con los límites de la configuración (0 = sin límite).
+func dentroDeLimites(grupo []*store.Pedido, centro Location, config *POSConfig) bool {
+	if config.MaxPedidosPorRuta > 0 && len(grupo) > config.MaxPedidosPorRuta {
+		return false
+	}
+	if config.DistanciaMaximaRuta <= 0 && config.TiempoMaximoRuta <= 0 {
+		return true
+	}
+
+	km := distanciaEstimada(grupo, centro)
+	if config.DistanciaMaximaRuta > 0 && km > config.DistanciaMaximaRuta {
+		return false
+	}
+	minutos := km/DefaultSpeedKmh*60 + float64(len(grupo))*DefaultServiceTime.Minutes()
+	if config.TiempoMaximoRuta > 0 && minutos > float64(config.TiempoMaximoRuta) {
+		return false
+	}
+	return true
+}
+
+func distanciaEstimada(grupo []*store.Pedido, centro Location) float64 {
+	visitado := make([]bool, len(grupo))
+	actual := centro
+	total := 0.0
+	for range grupo {
+		mejor, mejorKm := -1, math.Inf(1)
+		for i, p := range grupo {
+			if visitado[i] {
+				continue
+			}
+			if km := Haversine(actual, ubicacion(p)); km < mejorKm {
+				mejor, mejorKm = i, km
+			}
+		}
+		visitado[mejor] = true
+		total += mejorKm * DefaultRoadFactor
+		actual = ubicacion(grupo[mejor])
+	}
+	return total
+}
+
+// centroide es el punto medio de los pedidos; se usa cuando no hay planta configurada.
+func centroide(pedidos []*store.Pedido) Location {
+	var c Location
+	for _, p := range pedidos {
+		c.Lat += p.Latitud
+		c.Lng += p.Longitud
+	}
+	c.Lat /= float64(len(pedidos))
+	c.Lng /= float64(len(pedidos))
+	return c
+}
+
+func angulo(centro Location, p *store.Pedido) float64 {
+	return math.Atan2(p.Latitud-centro.Lat, (p.Longitud-centro.Lng)*math.Cos(centro.Lat*math.Pi/180))
+}
+
+func ubicacion(p *store.Pedido) Location {
+	return Location{Lat: p.Latitud, Lng: p.Longitud, Address: p.Direccion}
+}
+
+func tieneCoordenadas(p *store.Pedido) bool {
+	return p.Latitud != 0 || p.Longitud != 0
+}
@@ -0,0 +1,123 @@
+package delivery
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"example.com/whatsapp-integration/store"
+	"example.com/whatsapp-integration/zonas"
+)
+
+// pedidoEn coloca un pedido a km kilómetros de la planta (0, 0) hacia el
+// norte (dy) y el este (dx).
+func pedidoEn(id int, dx, dy float64) *store.Pedido {
+	return &store.Pedido{ID: id, Latitud: dy / 111.19, Longitud: dx / 111.19}
+}
+
+// gruposDe regresa los IDs de cada grupo, ordenados para comparar sin depender
+// del punto donde inicia el barrido.
+func gruposDe(grupos [][]*store.Pedido) [][]int {
+	var res [][]int
+	for _, g := range grupos {
+		var ids []int
+		for _, p := range g {
+			ids = append(ids, p.ID)
+		}
+		sort.Ints(ids)
+		res = append(res, ids)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i][0] < res[j][0] })
+	return res
+}
+
+func TestAgruparPedidos(t *testing.T) {
+	norte := []*store.Pedido{pedidoEn(1, -1, 10), pedidoEn(2, 0, 11), pedidoEn(3, 1, 10)}
+	sur := []*store.Pedido{pedidoEn(4, -1, -10), pedidoEn(5, 0, -11), pedidoEn(6, 1, -10)}
+	todos := append(append([]*store.Pedido(nil), norte...), sur...)
+
+	casos := []struct {
+		nombre  string
+		pedidos []*store.Pedido
+		config  POSConfig
+		grupos  [][]int
+	}{
+		{"sin límites todo va junto", todos, POSConfig{}, [][]int{{1, 2, 3, 4, 5, 6}}},
+		{"máximo de paradas separa por dirección", todos, POSConfig{MaxPedidosPorRuta: 3}, [][]int{{1, 2, 3}, {4, 5, 6}}},
+		{"límite de kilómetros", todos, POSConfig{DistanciaMaximaRuta: 40}, [][]int{{1, 2, 3}, {4, 5, 6}}},
+		{"límite de minutos", todos, POSConfig{TiempoMaximoRuta: 90}, [][]int{{1, 2, 3}, {4, 5, 6}}},
+		{"una parada por ruta", norte, POSConfig{MaxPedidosPorRuta: 1}, [][]int{{1}, {2}, {3}}},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			maps := NewMapsService("", nil)
+			maps.SetDepot(Location{})
+			grupos, sinCoordenadas := maps.AgruparPedidos(c.pedidos, nil, &c.config)
+			if len(sinCoordenadas) != 0 {
+				t.Fatalf("sin coordenadas = %d", len(sinCoordenadas))
+			}
+			got := gruposDe(grupos)
+			if len(got) != len(c.grupos) {
+				t.Fatalf("grupos = %v, se esperaba %v", got, c.grupos)
+			}
+			for i := range got {
+				if !mismos(got[i], c.grupos[i]) {
+					t.Fatalf("grupos = %v, se esperaba %v", got, c.grupos)
+				}
+			}
+		})
+	}
+}
+
+func TestAgruparPedidosSinCoordenadas(t *testing.T) {
+	pedidos := []*store.Pedido{pedidoEn(1, 0, 5), {ID: 2, Direccion: "Sin geocodificar"}}
+	grupos, sinCoordenadas := NewMapsService("", nil).AgruparPedidos(pedidos, nil, &POSConfig{})
+	if got := gruposDe(grupos); len(got) != 1 || !mismos(got[0], []int{1}) {
+		t.Fatalf("grupos = %v, se esperaba [[1]]", got)
+	}
+	if len(sinCoordenadas) != 1 || sinCoordenadas[0].ID != 2 {
+		t.Fatalf("sin coordenadas = %v, se esperaba el pedido 2", sinCoordenadas)
+	}
+}
+
+// TestAgruparPedidosPorZona no mezcla zonas aunque los pedidos estén juntos.
+func TestAgruparPedidosPorZona(t *testing.T) {
+	// Dos zonas divididas por el meridiano 0; la zona "este" solo cubre hasta
+	// 0.05° para dejar pedidos fuera de toda zona.
+	geojson := `{"type":"FeatureCollection","features":[
+		{"type":"Feature","properties":{"nombre":"oeste"},"geometry":{"type":"Polygon","coordinates":[[[-1,-1],[0,-1],[0,1],[-1,1],[-1,-1]]]}},
+		{"type":"Feature","properties":{"nombre":"este"},"geometry":{"type":"Polygon","coordinates":[[[0,-1],[0.05,-1],[0.05,1],[0,1],[0,-1]]]}}
+	]}`
+	archivo := filepath.Join(t.TempDir(), "zonas.geojson")
+	if err := os.WriteFile(archivo, []byte(geojson), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	mapa, err := zonas.CargarGeoJSON(archivo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pedidos := []*store.Pedido{
+		pedidoEn(1, -0.5, 3), pedidoEn(2, 0.5, 3), // a un kilómetro, en zonas distintas
+		pedidoEn(3, -2, 4), pedidoEn(4, 2, 4),
+		pedidoEn(5, 20, 3), // fuera de toda zona
+	}
+	if zona := mapa.Zona(pedidos[0].Latitud, pedidos[0].Longitud); zona != "oeste" {
+		t.Fatalf("zona del pedido 1 = %q", zona)
+	}
+
+	maps := NewMapsService("", nil)
+	maps.SetDepot(Location{})
+	grupos, _ := maps.AgruparPedidos(pedidos, mapa, &POSConfig{})
+	got := gruposDe(grupos)
+	esperado := [][]int{{1, 3}, {2, 4}, {5}}
+	if len(got) != len(esperado) {
+		t.Fatalf("grupos = %v, se esperaba %v", got, esperado)
+	}
+	for i := range got {
+		if !mismos(got[i], esperado[i]) {
+			t.Fatalf("grupos = %v, se esperaba %v", got, esperado)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package delivery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// maxElementosMatriz es el límite de orígenes × destinos por solicitud a la API.
+const maxElementosMatriz = 100
+
+// DistanceMatrixCost obtiene distancias y tiempos reales de Google Distance
+// Matrix. Es opcional: el optimizador funciona sin red con HaversineCost.
+type DistanceMatrixCost struct {
+	apiKey     string
+	httpClient *http.Client
+	baseURL    string
+}
+
+// NewDistanceMatrixCost crea la fuente de costos de Google.
+func NewDistanceMatrixCost(apiKey string) *DistanceMatrixCost {
+	return &DistanceMatrixCost{
+		apiKey: apiKey,
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		baseURL: "https://maps.googleapis.com/maps/api/distancematrix/json",
+	}
+}
+
+// Matrix implementa CostSource. Divide la consulta en bloques para respetar el
+// límite de elementos por solicitud.
+func (d *DistanceMatrixCost) Matrix(ctx context.Context, points []Location) (*Matrix, error) {
+	n := len(points)
+	m := &Matrix{Distances: make([][]float64, n), Durations: make([][]float64, n)}
+	for i := range points {
+		m.Distances[i] = make([]float64, n)
+		m.Durations[i] = make([]float64, n)
+	}
+	if n == 0 {
+		return m, nil
+	}
+
+	bloqueDestinos := n
+	if bloqueDestinos > 25 {
+		bloqueDestinos = 25
+	}
+	bloqueOrigenes := maxElementosMatriz / bloqueDestinos
+
+	for o := 0; o < n; o += bloqueOrigenes {
+		oFin := o + bloqueOrigenes
+		if oFin > n {
+			oFin = n
+		}
+		for dst := 0; dst < n; dst += bloqueDestinos {
+			dFin := dst + bloqueDestinos
+			if dFin > n {
+				dFin = n
+			}
+			if err := d.consultar(ctx, points, o, oFin, dst, dFin, m); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return m, nil
+}
+
+func (d *DistanceMatrixCost) consultar(ctx context.Context, points []Location, o, oFin, dst, dFin int, m *Matrix) error {
+	q := url.Values{}
+	q.Set("origins", coordenadas(points[o:oFin]))
+	q.Set("destinations", coordenadas(points[dst:dFin]))
+	q.Set("key", d.apiKey)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.baseURL+"?"+q.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("error creating distance matrix request: %w", err)
+	}
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error getting distance matrix: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Status string `json:"status"`
+		Rows   []struct {
+			Elements []struct {
+				Status   string `json:"status"`
+				Distance struct {
+					Value float64 `json:"value"` // en metros
+				} `json:"distance"`
+				Duration struct {
+					Value float64 `json:"value"` // en segundos
+				} `json:"duration"`
+			} `json:"elements"`
+		} `json:"rows"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("error decoding response: %w", err)
+	}
+	if result.Status != "OK" {
+		return fmt.Errorf("distance matrix status: %s", result.Status)
+	}
+	if len(result.Rows) != oFin-o {
+		return fmt.Errorf("distance matrix returned %d rows, expected %d", len(result.Rows), oFin-o)
+	}
+
+	for i, row := range result.Rows {
+		if len(row.Elements) != dFin-dst {
+			return fmt.Errorf("distance matrix returned %d elements, expected %d", len(row.Elements), dFin-dst)
+		}
+		for j, el := range row.Elements {
+			if el.Status != "OK" {
+				// Sin ruta por calle: se usa la estimación en línea recta.
+				km := Haversine(points[o+i], points[dst+j]) * DefaultRoadFactor
+				m.Distances[o+i][dst+j] = km
+				m.Durations[o+i][dst+j] = km / DefaultSpeedKmh * 60
+				continue
+			}
+			m.Distances[o+i][dst+j] = el.Distance.Value / 1000
+			m.Durations[o+i][dst+j] = el.Duration.Value / 60
+		}
+	}
+	return nil
+}
+
+func coordenadas(points []Location) string {
+	partes := make([]string, len(points))
+	for i, p := range points {
+		partes[i] = fmt.Sprintf("%f,%f", p.Lat, p.Lng)
+	}
+	return strings.Join(partes, "|")
+}
@@ -2,19 +2,25 @@ package delivery
 
 import (
 	"context"
-	"fmt"
-	"time"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/url"
+	"time"
 
 	"example.com/whatsapp-integration/store"
 )
 
 type MapsService struct {
-	apiKey     string
-	httpClient *http.Client
-	store      store.Store
+	apiKey       string
+	httpClient   *http.Client
+	store        store.Store
+	optimizer    *Optimizer
+	depot        *Location
+	sender       MessageSender
+	waitTime     time.Duration
+	cancelNoShow bool
+	observers    []StatusObserver
 }
 
 type Location struct {
@@ -24,23 +30,24 @@ type Location struct {
 }
 
 type RouteInfo struct {
-	Distance      float64 `json:"distance"`      // en kilómetros
-	Duration      int     `json:"duration"`      // en minutos
+	Distance      float64   `json:"distance"` // en kilómetros
+	Duration      int       `json:"duration"` // en minutos
 	EstimatedTime time.Time `json:"estimatedTime"`
 }
 
 type DeliveryRoute struct {
-	DriverID    string       `json:"driverId"`
-	Stops       []RouteStop  `json:"stops"`
-	TotalTime   int         `json:"totalTime"`    // en minutos
-	TotalDistance float64   `json:"totalDistance"` // en kilómetros
+	DriverID      string      `json:"driverId"`
+	Stops         []RouteStop `json:"stops"`
+	TotalTime     int         `json:"totalTime"`            // en minutos
+	TotalDistance float64     `json:"totalDistance"`        // en kilómetros
+	Unassigned    []RouteStop `json:"unassigned,omitempty"` // pedidos que no cupieron o sin ubicación
 }
 
 type RouteStop struct {
-	PedidoID     string    `json:"pedidoId"`
-	Location     Location  `json:"location"`
+	PedidoID      int       `json:"pedidoId"`
+	Location      Location  `json:"location"`
 	EstimatedTime time.Time `json:"estimatedTime"`
-	Status       string    `json:"status"` // pending, arriving, delivered, cancelled
+	Status        string    `json:"status"` // pending, arriving, delivered, cancelled
 }
 
 func NewMapsService(apiKey string, store store.Store) *MapsService {
@@ -49,10 +56,39 @@ func NewMapsService(apiKey string, store store.Store) *MapsService {
 		httpClient: &http.Client{
 			Timeout: 10 * time.Second,
 		},
-		store: store,
+		store:     store,
+		optimizer: NewOptimizer(HaversineCost{}),
+		waitTime:  DefaultWaitTime,
+	}
+}
+
+// DefaultWaitTime es lo que el repartidor espera al cliente en el domicilio.
+const DefaultWaitTime = 10 * time.Minute
+
+// SetWaitTime cambia el tiempo de espera que se anuncia al cliente al llegar.
+func (s *MapsService) SetWaitTime(d time.Duration) {
+	if d > 0 {
+		s.waitTime = d
 	}
 }
 
+// SetCancelNoShow anuncia al cliente que, si no atiende al repartidor, el
+// pedido se cancela en lugar de reagendarse.
+func (s *MapsService) SetCancelNoShow(cancel bool) {
+	s.cancelNoShow = cancel
+}
+
+// SetCostSource cambia la fuente de distancias del optimizador, por ejemplo a
+// Google Distance Matrix. Por defecto se usa haversine sin red.
+func (s *MapsService) SetCostSource(cost CostSource) {
+	s.optimizer = NewOptimizer(cost)
+}
+
+// SetDepot fija la planta de donde salen los camiones.
+func (s *MapsService) SetDepot(depot Location) {
+	s.depot = &depot
+}
+
 // ValidateAddress verifica y normaliza una dirección
 func (s *MapsService) ValidateAddress(ctx context.Context, address string) (*Location, error) {
 	url := fmt.Sprintf(
@@ -70,7 +106,7 @@ func (s *MapsService) ValidateAddress(ctx context.Context, address string) (*Loc
 	var result struct {
 		Results []struct {
 			FormattedAddress string `json:"formatted_address"`
-			Geometry struct {
+			Geometry         struct {
 				Location struct {
 					Lat float64 `json:"lat"`
 					Lng float64 `json:"lng"`
@@ -88,90 +124,84 @@ func (s *MapsService) ValidateAddress(ctx context.Context, address string) (*Loc
 	}
 
 	return &Location{
-		Lat: result.Results[0].Geometry.Location.Lat,
-		Lng: result.Results[0].Geometry.Location.Lng,
+		Lat:     result.Results[0].Geometry.Location.Lat,
+		Lng:     result.Results[0].Geometry.Location.Lng,
 		Address: result.Results[0].FormattedAddress,
 	}, nil
 }
 
-// OptimizeRoute genera una ruta óptima para un conjunto de pedidos
+// OptimizeRoute genera una ruta óptima para un conjunto de pedidos, saliendo
+// ahora y sin límite de capacidad.
 func (s *MapsService) OptimizeRoute(ctx context.Context, pedidos []*store.Pedido) (*DeliveryRoute, error) {
+	return s.OptimizeRouteFor(ctx, pedidos, Vehicle{Departure: time.Now()})
+}
+
+// OptimizeRouteFor ordena los pedidos para el camión indicado respetando su
+// capacidad y los horarios de los clientes. Solo se geocodifican los pedidos
+// sin coordenadas; sin API key esos pedidos quedan fuera de la ruta.
+func (s *MapsService) OptimizeRouteFor(ctx context.Context, pedidos []*store.Pedido, vehicle Vehicle) (*DeliveryRoute, error) {
 	if len(pedidos) == 0 {
 		return nil, fmt.Errorf("no orders to route")
 	}
-
-	// Obtener coordenadas de cada pedido
-	stops := make([]RouteStop, len(pedidos))
-	for i, pedido := range pedidos {
-		loc, err := s.ValidateAddress(ctx, pedido.Direccion)
-		if err != nil {
-			return nil, fmt.Errorf("error validating address for order %s: %w", pedido.ID, err)
-		}
-
-		stops[i] = RouteStop{
-			PedidoID: pedido.ID,
-			Location: *loc,
-			Status:   "pending",
-		}
+	if vehicle.Depot == nil {
+		vehicle.Depot = s.depot
 	}
-
-	// Llamar a la API de Directions para optimizar la ruta
-	optimizedStops, err := s.getOptimizedRoute(stops)
-	if err != nil {
-		return nil, fmt.Errorf("error optimizing route: %w", err)
+	if vehicle.Departure.IsZero() {
+		vehicle.Departure = time.Now()
 	}
 
-	// Calcular tiempos estimados
-	now := time.Now()
-	currentTime := now
-	for i := range optimizedStops {
-		if i > 0 {
-			// Calcular tiempo entre paradas
-			duration, err := s.getRouteDuration(
-				optimizedStops[i-1].Location,
-				optimizedStops[i].Location,
-			)
+	ruta := &DeliveryRoute{}
+	jobs := make([]Job, 0, len(pedidos))
+	for _, pedido := range pedidos {
+		job := JobForPedido(pedido, vehicle.Departure)
+		if pedido.Latitud == 0 && pedido.Longitud == 0 {
+			if s.apiKey == "" {
+				ruta.Unassigned = append(ruta.Unassigned, RouteStop{PedidoID: pedido.ID, Location: job.Location, Status: "pending"})
+				continue
+			}
+			loc, err := s.ValidateAddress(ctx, pedido.Direccion)
 			if err != nil {
-				return nil, err
+				return nil, fmt.Errorf("error validating address for order %d: %w", pedido.ID, err)
 			}
-			currentTime = currentTime.Add(time.Duration(duration) * time.Minute)
+			job.Location = *loc
 		}
-		optimizedStops[i].EstimatedTime = currentTime
+		jobs = append(jobs, job)
 	}
 
-	return &DeliveryRoute{
-		Stops: optimizedStops,
-		TotalTime: int(currentTime.Sub(now).Minutes()),
-	}, nil
-}
-
-// UpdateDeliveryStatus actualiza el estado de entrega y notifica al cliente
-func (s *MapsService) UpdateDeliveryStatus(ctx context.Context, pedidoID string, status string, location Location) error {
-	pedido, err := s.store.GetPedido(ctx, pedidoID)
+	plan, err := s.optimizer.Optimize(ctx, vehicle, jobs)
 	if err != nil {
-		return fmt.Errorf("error getting order: %w", err)
+		return nil, fmt.Errorf("error optimizing route: %w", err)
 	}
 
-	// Actualizar estado
-	pedido.Estado = status
-	if err := s.store.ActualizarPedido(ctx, pedido); err != nil {
-		return fmt.Errorf("error updating order: %w", err)
+	ruta.Stops = plan.Stops
+	ruta.TotalTime = plan.TotalTime
+	ruta.TotalDistance = plan.TotalDistance
+	for _, job := range plan.Unassigned {
+		ruta.Unassigned = append(ruta.Unassigned, RouteStop{PedidoID: job.PedidoID, Location: job.Location, Status: "pending"})
 	}
+	return ruta, nil
+}
 
-	// Obtener tiempo estimado
-	eta, err := s.getRouteDuration(location, Location{
-		Lat: pedido.Lat,
-		Lng: pedido.Lng,
-		Address: pedido.Direccion,
-	})
+// UpdateDeliveryStatus actualiza el estado de entrega y notifica al cliente
+func (s *MapsService) UpdateDeliveryStatus(ctx context.Context, pedidoID int, status string, location Location) error {
+	pedido, err := s.setStatus(ctx, pedidoID, status)
 	if err != nil {
-		return fmt.Errorf("error calculating ETA: %w", err)
+		return err
 	}
 
 	// Enviar notificación según el estado
 	var msg string
 	switch status {
 	case "en_ruta":
+		// Obtener tiempo estimado
+		eta, err := s.estimateDuration(location, Location{
+			Lat:     pedido.Latitud,
+			Lng:     pedido.Longitud,
+			Address: pedido.Direccion,
+		})
+		if err != nil {
+			return fmt.Errorf("error calculating ETA: %w", err)
+		}
 		msg = fmt.Sprintf(
 			"🚛 *Tu pedido está en camino*\n\n"+
 				"Tiempo estimado de llegada: %d minutos\n"+
@@ -184,51 +214,78 @@ func (s *MapsService) UpdateDeliveryStatus(ctx context.Context, pedidoID string,
 			"Por favor ten el pago listo."
 
 	case "esperando":
+		consecuencia := "se reagendará"
+		if s.cancelNoShow {
+			consecuencia = "se cancelará"
+		}
 		msg = "🔔 *¡Hemos llegado!*\n\n" +
 			"El repartidor está esperando.\n" +
-			"Tienes 5 minutos para confirmar la recepción\n" +
-			"o el pedido será cancelado."
+			fmt.Sprintf("Te esperará hasta %d minutos; si no lo atiendes\n", int(s.waitTime.Minutes())) +
+			fmt.Sprintf("se registrará un strike y el pedido %s.", consecuencia)
 
 	case "entregado":
-		msg = "✅ *Entrega Confirmada*\n\n" +
-			"¡Gracias por tu preferencia!\n" +
-			"¿Deseas calificar nuestro servicio?"
+		msg = "✅ *Pedido entregado*\n\n" +
+			"El repartidor registró la entrega de tu pedido.\n" +
+			"¡Gracias por tu preferencia!"
 
 	case "cancelado":
 		msg = "❌ *Pedido Cancelado*\n\n" +
-			"No se recibió confirmación en el tiempo establecido.\n" +
+			fmt.Sprintf("Tu pedido #%d fue cancelado.\n", pedido.ID) +
 			"Por favor contacta a soporte si esto es un error."
 	}
 
-	// TODO: Enviar mensaje vía WhatsApp
+	// Enviar mensaje vía WhatsApp
+	if err := s.notify(ctx, pedido, status, msg); err != nil {
+		return fmt.Errorf("error notifying customer: %w", err)
+	}
 	return nil
 }
 
-// AutoCancelUnconfirmed cancela pedidos no confirmados después de 5 minutos
-func (s *MapsService) AutoCancelUnconfirmed(ctx context.Context) error {
-	pedidos, err := s.store.GetPedidosEstado(ctx, "esperando")
+// CancelNoShow cancela el pedido cuyo cliente no atendió al repartidor y le
+// explica ese motivo en lugar del aviso de pedido sin confirmar.
+func (s *MapsService) CancelNoShow(ctx context.Context, pedidoID int) error {
+	pedido, err := s.setStatus(ctx, pedidoID, "cancelado")
 	if err != nil {
-		return fmt.Errorf("error getting waiting orders: %w", err)
+		return err
+	}
+	msg := "❌ *Pedido Cancelado*\n\n" +
+		fmt.Sprintf("El repartidor te esperó %d minutos en tu domicilio y nadie recibió el pedido.\n", int(s.waitTime.Minutes())) +
+		"Por favor contacta a soporte si esto es un error."
+	if err := s.notify(ctx, pedido, "cancelado", msg); err != nil {
+		return fmt.Errorf("error notifying customer: %w", err)
 	}
+	return nil
+}
 
-	now := time.Now()
-	for _, pedido := range pedidos {
-		if now.Sub(pedido.UltimaActualizacion) > 5*time.Minute {
-			if err := s.UpdateDeliveryStatus(ctx, pedido.ID, "cancelado", Location{}); err != nil {
-				// Log error pero continuar con otros pedidos
-				fmt.Printf("Error canceling order %s: %v\n", pedido.ID, err)
-			}
-		}
+// setStatus guarda el nuevo estado del pedido y avisa a los observadores.
+func (s *MapsService) setStatus(ctx context.Context, pedidoID int, status string) (*store.Pedido, error) {
+	pedido, err := s.store.GetPedido(ctx, pedidoID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting order: %w", err)
+	}
+	if pedido == nil {
+		return nil, fmt.Errorf("order %d not found", pedidoID)
 	}
 
-	return nil
+	// Actualizar estado
+	pedido.Estado = status
+	if err := s.store.ActualizarPedido(ctx, pedido); err != nil {
+		return nil, fmt.Errorf("error updating order: %w", err)
+	}
+	s.notifyObservers(ctx, pedido, status)
+	return pedido, nil
 }
 
-// getOptimizedRoute llama a la API de Directions para optimizar la ruta
-func (s *MapsService) getOptimizedRoute(stops []RouteStop) ([]RouteStop, error) {
-	// TODO: Implementar llamada real a la API
-	// Por ahora retorna las paradas en el mismo orden
-	return stops, nil
+// estimateDuration usa Directions si hay API key; sin red estima con haversine.
+func (s *MapsService) estimateDuration(origin, dest Location) (int, error) {
+	if s.apiKey != "" {
+		return s.getRouteDuration(origin, dest)
+	}
+	m, err := HaversineCost{}.Matrix(context.Background(), []Location{origin, dest})
+	if err != nil {
+		return 0, err
+	}
+	return int(m.Durations[0][1]), nil
 }
 
 // getRouteDuration calcula el tiempo entre dos ubicaciones
@@ -266,4 +323,4 @@ func (s *MapsService) getRouteDuration(origin, dest Location) (int, error) {
 
 	// Convertir segundos a minutos
 	return result.Routes[0].Legs[0].Duration.Value / 60, nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,80 @@
+package delivery
+
+import (
+	"context"
+	"fmt"
+
+	"example.com/whatsapp-integration/store"
+)
+
+// MessageSender envía mensajes de WhatsApp. Lo implementa adapter.WhatsAppClient.
+type MessageSender interface {
+	SendMessage(to string, text string) error
+}
+
+// SetSender habilita los avisos de entrega por WhatsApp. Sin sender los avisos
+// solo se registran en el log.
+func (s *MapsService) SetSender(sender MessageSender) {
+	s.sender = sender
+}
+
+// NotifyCustomer envía al cliente del pedido un aviso que no sale de un cambio
+// de estado, con la misma deduplicación por tipo.
+func (s *MapsService) NotifyCustomer(ctx context.Context, pedidoID int, tipo, msg string) error {
+	pedido, err := s.store.GetPedido(ctx, pedidoID)
+	if err != nil {
+		return fmt.Errorf("error getting order %d: %w", pedidoID, err)
+	}
+	if pedido == nil {
+		return fmt.Errorf("order %d not found", pedidoID)
+	}
+	return s.notify(ctx, pedido, tipo, msg)
+}
+
+// avisosPorIntento son los avisos que se repiten en cada intento de entrega.
+var avisosPorIntento = []string{"ruta_asignada", "en_ruta", "llegando", "esperando"}
+
+// ReiniciarAvisos deja que un pedido reagendado vuelva a recibir los avisos de
+// ruta en su siguiente intento de entrega.
+func (s *MapsService) ReiniciarAvisos(ctx context.Context, pedidoID int) error {
+	return s.store.BorrarNotificaciones(ctx, pedidoID, avisosPorIntento)
+}
+
+// notify envía el aviso al cliente del pedido una sola vez por tipo y lo deja
+// registrado. El teléfono se obtiene del cliente del pedido.
+func (s *MapsService) notify(ctx context.Context, pedido *store.Pedido, tipo, msg string) error {
+	if msg == "" {
+		return nil
+	}
+	if s.sender == nil {
+		fmt.Printf("Aviso %s del pedido %d sin enviar (WhatsApp no configurado)\n", tipo, pedido.ID)
+		return nil
+	}
+
+	enviada, err := s.store.NotificacionEnviada(ctx, pedido.ID, tipo)
+	if err != nil {
+		return err
+	}
+	if enviada {
+		return nil
+	}
+
+	cliente, err := s.store.GetClientePorID(ctx, pedido.ClienteID)
+	if err != nil {
+		return fmt.Errorf("error getting customer for order %d: %w", pedido.ID, err)
+	}
+	if cliente == nil {
+		return fmt.Errorf("customer %d not found for order %d", pedido.ClienteID, pedido.ID)
+	}
+
+	if err := s.sender.SendMessage(cliente.NumeroTelefono, msg); err != nil {
+		return fmt.Errorf("error sending %s notification for order %d: %w", tipo, pedido.ID, err)
+	}
+
+	return s.store.RegistrarNotificacion(ctx, &store.NotificacionPedido{
+		PedidoID: pedido.ID,
+		Tipo:     tipo,
+		Telefono: cliente.NumeroTelefono,
+		Mensaje:  msg,
+	})
+}
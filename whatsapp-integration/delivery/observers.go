@@ -0,0 +1,29 @@
+package delivery
+
+import (
+	"context"
+	"fmt"
+
+	"example.com/whatsapp-integration/store"
+)
+
+// StatusObserver recibe cada cambio de estado de un pedido ya guardado, por
+// ejemplo para acumular puntos al entregar o reponerlos al cancelar.
+type StatusObserver interface {
+	OrderStatusChanged(ctx context.Context, pedido *store.Pedido, status string) error
+}
+
+// AddStatusObserver registra un observador de UpdateDeliveryStatus.
+func (s *MapsService) AddStatusObserver(o StatusObserver) {
+	s.observers = append(s.observers, o)
+}
+
+// notifyObservers avisa a los observadores. Sus errores no revierten el cambio
+// de estado, solo se registran.
+func (s *MapsService) notifyObservers(ctx context.Context, pedido *store.Pedido, status string) {
+	for _, o := range s.observers {
+		if err := o.OrderStatusChanged(ctx, pedido, status); err != nil {
+			fmt.Printf("Error procesando cambio a %s del pedido %d: %v\n", status, pedido.ID, err)
+		}
+	}
+}
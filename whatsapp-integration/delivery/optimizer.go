@@ -0,0 +1,390 @@
+package delivery
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"time"
+
+	"example.com/whatsapp-integration/store"
+)
+
+// Valores por defecto del optimizador sin red.
+const (
+	DefaultSpeedKmh    = 30.0             // velocidad promedio en ciudad
+	DefaultRoadFactor  = 1.3              // la distancia por calle es mayor que la línea recta
+	DefaultServiceTime = 10 * time.Minute // tiempo de descarga en cada parada
+
+	latePenalty = 1000.0 // costo por minuto de retraso sobre la ventana
+)
+
+// TimeWindow es el horario en que el cliente puede recibir. Un valor cero no restringe.
+type TimeWindow struct {
+	Start time.Time
+	End   time.Time
+}
+
+// IsZero indica si la ventana no tiene restricción.
+func (w TimeWindow) IsZero() bool {
+	return w.Start.IsZero() && w.End.IsZero()
+}
+
+// Vehicle describe el camión que recorre la ruta.
+type Vehicle struct {
+	Depot             *Location     // punto de salida; nil = la ruta inicia en la primera parada
+	CapacityLiters    float64       // 0 = sin límite
+	CapacityCylinders int           // 0 = sin límite
+	Departure         time.Time     // hora de salida
+	ServiceTime       time.Duration // tiempo en cada parada; 0 = DefaultServiceTime
+}
+
+// Job es un pedido por visitar.
+type Job struct {
+	PedidoID  int
+	Location  Location
+	Liters    float64
+	Cylinders int
+	Window    TimeWindow
+}
+
+// Plan es el resultado de optimizar: paradas en orden con su ETA y los pedidos
+// que no cupieron en el camión.
+type Plan struct {
+	Stops         []RouteStop
+	Unassigned    []Job
+	TotalTime     int     // en minutos
+	TotalDistance float64 // en kilómetros
+}
+
+// Matrix contiene distancias (km) y tiempos (minutos) entre puntos.
+type Matrix struct {
+	Distances [][]float64
+	Durations [][]float64
+}
+
+// CostSource calcula la matriz de costos entre un conjunto de puntos.
+type CostSource interface {
+	Matrix(ctx context.Context, points []Location) (*Matrix, error)
+}
+
+// HaversineCost estima distancias con la fórmula de haversine. No requiere red.
+type HaversineCost struct {
+	SpeedKmh   float64
+	RoadFactor float64
+}
+
+// Matrix implementa CostSource.
+func (h HaversineCost) Matrix(ctx context.Context, points []Location) (*Matrix, error) {
+	speed := h.SpeedKmh
+	if speed <= 0 {
+		speed = DefaultSpeedKmh
+	}
+	factor := h.RoadFactor
+	if factor <= 0 {
+		factor = DefaultRoadFactor
+	}
+
+	n := len(points)
+	m := &Matrix{Distances: make([][]float64, n), Durations: make([][]float64, n)}
+	for i := range points {
+		m.Distances[i] = make([]float64, n)
+		m.Durations[i] = make([]float64, n)
+		for j := range points {
+			if i == j {
+				continue
+			}
+			km := Haversine(points[i], points[j]) * factor
+			m.Distances[i][j] = km
+			m.Durations[i][j] = km / speed * 60
+		}
+	}
+	return m, nil
+}
+
+// Haversine regresa la distancia en línea recta entre dos puntos, en kilómetros.
+func Haversine(a, b Location) float64 {
+	const radioTierraKm = 6371.0
+	lat1, lat2 := a.Lat*math.Pi/180, b.Lat*math.Pi/180
+	dLat := lat2 - lat1
+	dLng := (b.Lng - a.Lng) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * radioTierraKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// Optimizer ordena las paradas de una ruta: construye con vecino más cercano y
+// mejora con 2-opt y or-opt, penalizando las llegadas fuera de ventana.
+type Optimizer struct {
+	cost CostSource
+}
+
+// NewOptimizer crea un optimizador. Con cost nil usa HaversineCost.
+func NewOptimizer(cost CostSource) *Optimizer {
+	if cost == nil {
+		cost = HaversineCost{}
+	}
+	return &Optimizer{cost: cost}
+}
+
+// Optimize calcula el orden de visita y las ETAs de los pedidos para el camión.
+func (o *Optimizer) Optimize(ctx context.Context, v Vehicle, jobs []Job) (*Plan, error) {
+	plan := &Plan{}
+	if len(jobs) == 0 {
+		return plan, nil
+	}
+	if v.ServiceTime == 0 {
+		v.ServiceTime = DefaultServiceTime
+	}
+	if v.Departure.IsZero() {
+		v.Departure = time.Now()
+	}
+
+	// Índice 0 de la matriz es el depósito si existe; los pedidos van después.
+	offset := 0
+	points := make([]Location, 0, len(jobs)+1)
+	if v.Depot != nil {
+		points = append(points, *v.Depot)
+		offset = 1
+	}
+	for _, j := range jobs {
+		points = append(points, j.Location)
+	}
+	matrix, err := o.cost.Matrix(ctx, points)
+	if err != nil {
+		return nil, fmt.Errorf("error calculando matriz de costos: %w", err)
+	}
+
+	r := &routeEval{matrix: matrix, jobs: jobs, vehicle: v, offset: offset}
+	seq, unassigned := r.nearestNeighbor()
+	seq = r.improve(seq)
+
+	for _, idx := range unassigned {
+		plan.Unassigned = append(plan.Unassigned, jobs[idx])
+	}
+
+	etas, _ := r.simulate(seq)
+	prev := -1
+	for i, idx := range seq {
+		plan.Stops = append(plan.Stops, RouteStop{
+			PedidoID:      jobs[idx].PedidoID,
+			Location:      jobs[idx].Location,
+			EstimatedTime: etas[i],
+			Status:        "pending",
+		})
+		plan.TotalDistance += r.distance(prev, idx)
+		prev = idx
+	}
+	if len(seq) > 0 {
+		fin := etas[len(etas)-1].Add(v.ServiceTime)
+		plan.TotalTime = int(fin.Sub(v.Departure).Minutes())
+	}
+	return plan, nil
+}
+
+// routeEval evalúa secuencias de pedidos sobre una matriz ya calculada.
+type routeEval struct {
+	matrix  *Matrix
+	jobs    []Job
+	vehicle Vehicle
+	offset  int
+}
+
+// travel regresa los minutos de from a to; from = -1 es el depósito.
+func (r *routeEval) travel(from, to int) float64 {
+	if from < 0 {
+		if r.offset == 0 {
+			return 0
+		}
+		return r.matrix.Durations[0][to+r.offset]
+	}
+	return r.matrix.Durations[from+r.offset][to+r.offset]
+}
+
+func (r *routeEval) distance(from, to int) float64 {
+	if from < 0 {
+		if r.offset == 0 {
+			return 0
+		}
+		return r.matrix.Distances[0][to+r.offset]
+	}
+	return r.matrix.Distances[from+r.offset][to+r.offset]
+}
+
+// simulate recorre la secuencia y regresa la hora de llegada a cada parada y el
+// costo total: minutos de recorrido más la penalización por retrasos.
+func (r *routeEval) simulate(seq []int) ([]time.Time, float64) {
+	etas := make([]time.Time, len(seq))
+	t := r.vehicle.Departure
+	cost := 0.0
+	prev := -1
+	for i, idx := range seq {
+		if i > 0 {
+			t = t.Add(r.vehicle.ServiceTime)
+		}
+		minutos := r.travel(prev, idx)
+		t = t.Add(time.Duration(minutos * float64(time.Minute)))
+		cost += minutos
+
+		w := r.jobs[idx].Window
+		if !w.Start.IsZero() && t.Before(w.Start) {
+			cost += w.Start.Sub(t).Minutes()
+			t = w.Start
+		}
+		if !w.End.IsZero() && t.After(w.End) {
+			cost += t.Sub(w.End).Minutes() * latePenalty
+		}
+		etas[i] = t
+		prev = idx
+	}
+	return etas, cost
+}
+
+// nearestNeighbor arma la secuencia inicial eligiendo siempre la parada que se
+// alcanza antes (contando esperas y retrasos). Los pedidos que exceden la
+// capacidad del camión quedan fuera.
+func (r *routeEval) nearestNeighbor() ([]int, []int) {
+	var seq, unassigned []int
+	visitado := make([]bool, len(r.jobs))
+	litros, cilindros := 0.0, 0
+
+	for i, j := range r.jobs {
+		if r.exceeds(j, 0, 0) {
+			visitado[i] = true
+			unassigned = append(unassigned, i)
+		}
+	}
+
+	prev := -1
+	t := r.vehicle.Departure
+	for {
+		mejor, mejorCosto := -1, math.Inf(1)
+		var mejorLlegada time.Time
+		for i, j := range r.jobs {
+			if visitado[i] || r.exceeds(j, litros, cilindros) {
+				continue
+			}
+			llegada := t.Add(time.Duration(r.travel(prev, i) * float64(time.Minute)))
+			costo := llegada.Sub(t).Minutes()
+			if !j.Window.Start.IsZero() && llegada.Before(j.Window.Start) {
+				costo += j.Window.Start.Sub(llegada).Minutes()
+				llegada = j.Window.Start
+			}
+			if !j.Window.End.IsZero() && llegada.After(j.Window.End) {
+				costo += llegada.Sub(j.Window.End).Minutes() * latePenalty
+			}
+			if costo < mejorCosto {
+				mejor, mejorCosto, mejorLlegada = i, costo, llegada
+			}
+		}
+		if mejor < 0 {
+			break
+		}
+		visitado[mejor] = true
+		seq = append(seq, mejor)
+		litros += r.jobs[mejor].Liters
+		cilindros += r.jobs[mejor].Cylinders
+		t = mejorLlegada.Add(r.vehicle.ServiceTime)
+		prev = mejor
+	}
+
+	for i := range r.jobs {
+		if !visitado[i] {
+			unassigned = append(unassigned, i)
+		}
+	}
+	return seq, unassigned
+}
+
+func (r *routeEval) exceeds(j Job, litros float64, cilindros int) bool {
+	if r.vehicle.CapacityLiters > 0 && litros+j.Liters > r.vehicle.CapacityLiters {
+		return true
+	}
+	if r.vehicle.CapacityCylinders > 0 && cilindros+j.Cylinders > r.vehicle.CapacityCylinders {
+		return true
+	}
+	return false
+}
+
+// improve aplica 2-opt (invertir tramos) y or-opt (mover tramos de 1 a 3
+// paradas) mientras alguno reduzca el costo.
+func (r *routeEval) improve(seq []int) []int {
+	if len(seq) < 3 {
+		return seq
+	}
+	_, mejorCosto := r.simulate(seq)
+
+	for mejoro := true; mejoro; {
+		mejoro = false
+
+		for i := 0; i < len(seq)-1; i++ {
+			for k := i + 1; k < len(seq); k++ {
+				candidata := append([]int(nil), seq...)
+				for a, b := i, k; a < b; a, b = a+1, b-1 {
+					candidata[a], candidata[b] = candidata[b], candidata[a]
+				}
+				if _, costo := r.simulate(candidata); costo < mejorCosto-1e-9 {
+					seq, mejorCosto, mejoro = candidata, costo, true
+				}
+			}
+		}
+
+		for largo := 1; largo <= 3 && largo < len(seq); largo++ {
+			for i := 0; i+largo <= len(seq); i++ {
+				tramo := append([]int(nil), seq[i:i+largo]...)
+				resto := append(append([]int(nil), seq[:i]...), seq[i+largo:]...)
+				for pos := 0; pos <= len(resto); pos++ {
+					if pos == i {
+						continue
+					}
+					candidata := make([]int, 0, len(seq))
+					candidata = append(candidata, resto[:pos]...)
+					candidata = append(candidata, tramo...)
+					candidata = append(candidata, resto[pos:]...)
+					if _, costo := r.simulate(candidata); costo < mejorCosto-1e-9 {
+						seq, mejorCosto, mejoro = candidata, costo, true
+						break
+					}
+				}
+			}
+		}
+	}
+	return seq
+}
+
+var reRangoHorario = regexp.MustCompile(`(\d{1,2}):(\d{2})-(\d{1,2}):(\d{2})$`)
+
+// WindowForPedido traduce el horario preferido del pedido a una ventana en el
+// día indicado. Entiende "Mañana", "Tarde" y las etiquetas de slot
+// ("Lun 20/10 09:00-13:00"); cualquier otro valor no restringe.
+func WindowForPedido(pedido *store.Pedido, dia time.Time) TimeWindow {
+	en := func(h, m int) time.Time {
+		return time.Date(dia.Year(), dia.Month(), dia.Day(), h, m, 0, 0, dia.Location())
+	}
+	switch pedido.HorarioPreferido {
+	case "Mañana":
+		return TimeWindow{Start: en(9, 0), End: en(13, 0)}
+	case "Tarde":
+		return TimeWindow{Start: en(14, 0), End: en(18, 0)}
+	}
+	if m := reRangoHorario.FindStringSubmatch(pedido.HorarioPreferido); m != nil {
+		h1, _ := strconv.Atoi(m[1])
+		m1, _ := strconv.Atoi(m[2])
+		h2, _ := strconv.Atoi(m[3])
+		m2, _ := strconv.Atoi(m[4])
+		return TimeWindow{Start: en(h1, m1), End: en(h2, m2)}
+	}
+	return TimeWindow{}
+}
+
+// JobForPedido arma el Job de un pedido con coordenadas.
+func JobForPedido(pedido *store.Pedido, dia time.Time) Job {
+	return Job{
+		PedidoID:  pedido.ID,
+		Location:  Location{Lat: pedido.Latitud, Lng: pedido.Longitud, Address: pedido.Direccion},
+		Liters:    pedido.CantidadLitros,
+		Cylinders: pedido.CantidadCilindros,
+		Window:    WindowForPedido(pedido, dia),
+	}
+}
@@ -0,0 +1,217 @@
+package delivery
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"example.com/whatsapp-integration/store"
+)
+
+func TestHaversine(t *testing.T) {
+	casos := []struct {
+		nombre string
+		a, b   Location
+		km     float64
+	}{
+		{"mismo punto", Location{Lat: 19.43, Lng: -99.13}, Location{Lat: 19.43, Lng: -99.13}, 0},
+		{"un grado de latitud", Location{Lat: 0, Lng: 0}, Location{Lat: 1, Lng: 0}, 111.19},
+		{"un grado de longitud en el ecuador", Location{Lat: 0, Lng: 0}, Location{Lat: 0, Lng: 1}, 111.19},
+		{"Zócalo a Ángel de la Independencia", Location{Lat: 19.4326, Lng: -99.1332}, Location{Lat: 19.4270, Lng: -99.1677}, 3.68},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			if got := Haversine(c.a, c.b); math.Abs(got-c.km) > 0.05 {
+				t.Fatalf("Haversine() = %.3f km, se esperaba %.2f", got, c.km)
+			}
+		})
+	}
+}
+
+func TestWindowForPedido(t *testing.T) {
+	loc := time.FixedZone("CST", -6*3600)
+	dia := time.Date(2026, 3, 2, 0, 0, 0, 0, loc)
+	en := func(h, m int) time.Time { return time.Date(2026, 3, 2, h, m, 0, 0, loc) }
+
+	casos := []struct {
+		horario string
+		ventana TimeWindow
+	}{
+		{"Mañana", TimeWindow{Start: en(9, 0), End: en(13, 0)}},
+		{"Tarde", TimeWindow{Start: en(14, 0), End: en(18, 0)}},
+		{"Lun 02/03 07:30-11:00", TimeWindow{Start: en(7, 30), End: en(11, 0)}},
+		{"cuando sea", TimeWindow{}},
+		{"", TimeWindow{}},
+	}
+	for _, c := range casos {
+		t.Run(c.horario, func(t *testing.T) {
+			got := WindowForPedido(&store.Pedido{HorarioPreferido: c.horario}, dia)
+			if !got.Start.Equal(c.ventana.Start) || !got.End.Equal(c.ventana.End) {
+				t.Fatalf("WindowForPedido() = %v, se esperaba %v", got, c.ventana)
+			}
+		})
+	}
+}
+
+// enLinea coloca un pedido a km kilómetros al norte de la planta.
+func enLinea(id int, km float64) Job {
+	return Job{PedidoID: id, Location: Location{Lat: km / 111.19}}
+}
+
+func ordenDe(plan *Plan) []int {
+	var ids []int
+	for _, s := range plan.Stops {
+		ids = append(ids, s.PedidoID)
+	}
+	return ids
+}
+
+func idsDe(jobs []Job) []int {
+	var ids []int
+	for _, j := range jobs {
+		ids = append(ids, j.PedidoID)
+	}
+	return ids
+}
+
+func mismos(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestOptimize(t *testing.T) {
+	salida := time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC)
+	planta := &Location{}
+	manana := TimeWindow{Start: salida, End: salida.Add(45 * time.Minute)}
+	tarde := TimeWindow{Start: salida.Add(6 * time.Hour), End: salida.Add(10 * time.Hour)}
+	conVentana := func(j Job, w TimeWindow) Job { j.Window = w; return j }
+	conLitros := func(j Job, l float64) Job { j.Liters = l; return j }
+
+	casos := []struct {
+		nombre    string
+		vehiculo  Vehicle
+		jobs      []Job
+		orden     []int
+		sinCamion []int
+	}{
+		{
+			nombre:   "del más cercano al más lejano",
+			vehiculo: Vehicle{Depot: planta, Departure: salida},
+			jobs:     []Job{enLinea(3, 9), enLinea(1, 3), enLinea(2, 6)},
+			orden:    []int{1, 2, 3},
+		},
+		{
+			nombre:   "la ventana de la tarde va al final aunque esté cerca",
+			vehiculo: Vehicle{Depot: planta, Departure: salida},
+			jobs:     []Job{conVentana(enLinea(1, 2), tarde), enLinea(2, 10)},
+			orden:    []int{2, 1},
+		},
+		{
+			nombre:   "la ventana de la mañana va primero aunque esté lejos",
+			vehiculo: Vehicle{Depot: planta, Departure: salida},
+			jobs:     []Job{conVentana(enLinea(1, 20), manana), enLinea(2, 2), conVentana(enLinea(3, 3), tarde)},
+			orden:    []int{1, 2, 3},
+		},
+		{
+			nombre:    "lo que no cabe en el camión queda fuera",
+			vehiculo:  Vehicle{Depot: planta, Departure: salida, CapacityLiters: 1000},
+			jobs:      []Job{conLitros(enLinea(1, 2), 600), conLitros(enLinea(2, 4), 300), conLitros(enLinea(3, 6), 1200), conLitros(enLinea(4, 8), 300)},
+			orden:     []int{1, 2},
+			sinCamion: []int{3, 4},
+		},
+		{
+			nombre:   "sin planta inicia en la primera parada",
+			vehiculo: Vehicle{Departure: salida},
+			jobs:     []Job{enLinea(1, 0), enLinea(2, 1)},
+			orden:    []int{1, 2},
+		},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			plan, err := NewOptimizer(nil).Optimize(context.Background(), c.vehiculo, c.jobs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := ordenDe(plan); !mismos(got, c.orden) {
+				t.Fatalf("orden = %v, se esperaba %v", got, c.orden)
+			}
+			if got := idsDe(plan.Unassigned); !mismos(got, c.sinCamion) {
+				t.Fatalf("sin camión = %v, se esperaba %v", got, c.sinCamion)
+			}
+		})
+	}
+}
+
+func TestOptimizeETAs(t *testing.T) {
+	salida := time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC)
+	// 15 km en línea recta son 19.5 km por calle: 39 minutos a 30 km/h.
+	jobs := []Job{enLinea(1, 15), enLinea(2, 30)}
+	jobs[1].Window = TimeWindow{Start: salida.Add(3 * time.Hour)}
+
+	plan, err := NewOptimizer(nil).Optimize(context.Background(), Vehicle{Depot: &Location{}, Departure: salida}, jobs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	primera := salida.Add(39 * time.Minute)
+	if d := plan.Stops[0].EstimatedTime.Sub(primera); d < -time.Minute || d > time.Minute {
+		t.Fatalf("ETA de la primera parada = %v, se esperaba %v", plan.Stops[0].EstimatedTime, primera)
+	}
+	// La segunda parada llega antes de su ventana y espera a que abra.
+	if !plan.Stops[1].EstimatedTime.Equal(jobs[1].Window.Start) {
+		t.Fatalf("ETA de la segunda parada = %v, se esperaba la apertura %v", plan.Stops[1].EstimatedTime, jobs[1].Window.Start)
+	}
+	if plan.TotalTime != 3*60+int(DefaultServiceTime.Minutes()) {
+		t.Fatalf("TotalTime = %d, se esperaba %d", plan.TotalTime, 3*60+int(DefaultServiceTime.Minutes()))
+	}
+}
+
+// TestImproveNoEmpeora compara con el vecino más cercano en instancias al
+// azar: las mejoras 2-opt y or-opt nunca dan una ruta más cara.
+func TestImproveNoEmpeora(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	salida := time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC)
+	planta := Location{Lat: 19.4, Lng: -99.1}
+
+	mejoradas := 0
+	for n := 0; n < 50; n++ {
+		jobs := make([]Job, 8)
+		puntos := []Location{planta}
+		for i := range jobs {
+			jobs[i] = Job{PedidoID: i + 1, Location: Location{Lat: planta.Lat + rnd.Float64()*0.2 - 0.1, Lng: planta.Lng + rnd.Float64()*0.2 - 0.1}}
+			if rnd.Intn(3) == 0 {
+				inicio := salida.Add(time.Duration(rnd.Intn(6)) * time.Hour)
+				jobs[i].Window = TimeWindow{Start: inicio, End: inicio.Add(2 * time.Hour)}
+			}
+			puntos = append(puntos, jobs[i].Location)
+		}
+		matriz, _ := HaversineCost{}.Matrix(context.Background(), puntos)
+		r := &routeEval{matrix: matriz, jobs: jobs, vehicle: Vehicle{Depot: &planta, Departure: salida, ServiceTime: DefaultServiceTime}, offset: 1}
+
+		inicial, _ := r.nearestNeighbor()
+		_, costoInicial := r.simulate(inicial)
+		mejorada := r.improve(append([]int(nil), inicial...))
+		_, costoMejorado := r.simulate(mejorada)
+
+		if len(mejorada) != len(inicial) {
+			t.Fatalf("instancia %d: la mejora perdió paradas (%v -> %v)", n, inicial, mejorada)
+		}
+		if costoMejorado > costoInicial+1e-9 {
+			t.Fatalf("instancia %d: costo %.2f > %.2f del vecino más cercano", n, costoMejorado, costoInicial)
+		}
+		if costoMejorado < costoInicial-1e-9 {
+			mejoradas++
+		}
+	}
+	if mejoradas == 0 {
+		t.Fatal("las mejoras no mejoraron ninguna instancia")
+	}
+}
@@ -17,8 +17,9 @@ type POSService struct {
 	httpClient *http.Client
 	store      store.Store
 	maps       *MapsService
+	loc        *time.Location
 
-	// Cache de rutas activas
+	// Cache de rutas activas, por "ruta-<id>" de la ruta guardada
 	activeRoutes map[string]*DeliveryRoute
 	routesMutex  sync.RWMutex
 }
@@ -39,109 +40,186 @@ func NewPOSService(endpoint, apiKey string, store store.Store, maps *MapsService
 		},
 		store:        store,
 		maps:         maps,
+		loc:          time.Local,
 		activeRoutes: make(map[string]*DeliveryRoute),
 	}
 }
 
-// SyncWithPOS sincroniza pedidos con la terminal punto de venta
-func (s *POSService) SyncWithPOS(ctx context.Context) error {
-	// Obtener configuración actual
-	config, err := s.getPOSConfig()
-	if err != nil {
-		return fmt.Errorf("error getting POS config: %w", err)
+// SetLocation fija la zona horaria para ubicar las rutas del día y las horas
+// que se avisan a los clientes.
+func (s *POSService) SetLocation(loc *time.Location) {
+	if loc != nil {
+		s.loc = loc
 	}
+}
 
-	// Obtener pedidos pendientes
-	pedidos, err := s.store.GetPedidosEstado(ctx, "pendiente")
+// SyncWithPOS sigue las rutas del día que guardó el corte diario: agrega al
+// seguimiento las que tienen camión y paradas abiertas, avisa a sus clientes
+// la hora estimada de llegada y deja de seguir las que ya terminaron.
+func (s *POSService) SyncWithPOS(ctx context.Context) error {
+	ahora := time.Now().In(s.loc)
+	fecha := time.Date(ahora.Year(), ahora.Month(), ahora.Day(), 0, 0, 0, 0, s.loc)
+	rutas, err := s.store.GetRutasPorFecha(ctx, fecha)
 	if err != nil {
-		return fmt.Errorf("error getting pending orders: %w", err)
+		return fmt.Errorf("error getting routes: %w", err)
 	}
 
-	// Agrupar pedidos por zona/ruta
-	grupos := s.agruparPedidos(pedidos, config)
-
-	// Para cada grupo, optimizar ruta y asignar tiempos
-	for _, grupo := range grupos {
-		ruta, err := s.maps.OptimizeRoute(ctx, grupo)
+	vigentes := make(map[string]bool, len(rutas))
+	for _, ruta := range rutas {
+		if ruta.CamionID == 0 {
+			continue
+		}
+		paradas, err := s.store.GetParadasRuta(ctx, ruta.ID)
+		if err != nil {
+			return fmt.Errorf("error getting stops of route %d: %w", ruta.ID, err)
+		}
+		seguimiento, err := s.rutaDesdeParadas(ctx, ruta, paradas)
 		if err != nil {
-			return fmt.Errorf("error optimizing route: %w", err)
+			return err
+		}
+		if rutaTerminada(seguimiento) {
+			continue
 		}
+		vigentes[seguimiento.DriverID] = true
 
-		// Guardar ruta activa
 		s.routesMutex.Lock()
-		s.activeRoutes[ruta.DriverID] = ruta
+		_, seguida := s.activeRoutes[seguimiento.DriverID]
+		if !seguida {
+			s.activeRoutes[seguimiento.DriverID] = seguimiento
+		}
 		s.routesMutex.Unlock()
+		if !seguida {
+			s.avisarRutaAsignada(ctx, seguimiento)
+		}
+	}
 
-		// Notificar a los clientes
-		for i, stop := range ruta.Stops {
-			pedido, err := s.store.GetPedido(ctx, stop.PedidoID)
-			if err != nil {
-				continue
-			}
+	// Las rutas terminadas y las de días anteriores ya no se siguen.
+	s.routesMutex.Lock()
+	for id := range s.activeRoutes {
+		if !vigentes[id] {
+			delete(s.activeRoutes, id)
+		}
+	}
+	s.routesMutex.Unlock()
+	return nil
+}
 
-			var msg string
-			eta := int(stop.EstimatedTime.Sub(time.Now()).Minutes())
-
-			if i == len(ruta.Stops)-1 {
-				// Último pedido de la ruta
-				msg = fmt.Sprintf(
-					"🚛 *Actualización de tu pedido*\n\n"+
-						"¡Buenas noticias! Serás la última entrega de la ruta.\n"+
-						"Tiempo estimado de llegada: %d minutos\n\n"+
-						"Te mantendremos informado del progreso.",
-					eta)
-			} else {
-				msg = fmt.Sprintf(
-					"🚛 *Actualización de tu pedido*\n\n"+
-						"Tu pedido ha sido asignado a una ruta de entrega.\n"+
-						"Tiempo estimado de llegada: %d minutos\n\n"+
-						"Te avisaremos cuando estemos cerca.",
-					eta)
-			}
+// rutaDesdeParadas arma el seguimiento de una ruta guardada, con las paradas
+// en su orden y la hora estimada del optimizador o del GPS.
+func (s *POSService) rutaDesdeParadas(ctx context.Context, ruta *store.Ruta, paradas []*store.RutaParada) (*DeliveryRoute, error) {
+	seguimiento := &DeliveryRoute{DriverID: fmt.Sprintf("ruta-%d", ruta.ID)}
+	for _, parada := range paradas {
+		pedido, err := s.store.GetPedido(ctx, parada.PedidoID)
+		if err != nil {
+			return nil, fmt.Errorf("error getting order %d: %w", parada.PedidoID, err)
+		}
+		if pedido == nil {
+			continue
+		}
+		stop := RouteStop{PedidoID: pedido.ID, Location: ubicacion(pedido), Status: parada.Estado}
+		if parada.HoraEstimada != nil {
+			stop.EstimatedTime = *parada.HoraEstimada
+		}
+		seguimiento.Stops = append(seguimiento.Stops, stop)
+	}
+	return seguimiento, nil
+}
+
+// avisarRutaAsignada avisa a cada cliente con parada pendiente que su pedido
+// ya va en una ruta. El aviso se manda una sola vez por pedido.
+func (s *POSService) avisarRutaAsignada(ctx context.Context, ruta *DeliveryRoute) {
+	for i, stop := range ruta.Stops {
+		if stop.Status != "pending" {
+			continue
+		}
+		llegada := "Te avisaremos la hora de llegada en cuanto salga el camión."
+		if !stop.EstimatedTime.IsZero() {
+			llegada = "Hora estimada de llegada: " + stop.EstimatedTime.In(s.loc).Format("15:04")
+		}
 
-			// TODO: Enviar mensaje vía WhatsApp
+		var msg string
+		if i == len(ruta.Stops)-1 {
+			// Último pedido de la ruta
+			msg = "🚛 *Actualización de tu pedido*\n\n" +
+				"Tu pedido ha sido asignado a una ruta de entrega; serás la última entrega de la ruta.\n" +
+				llegada + "\n\n" +
+				"Te mantendremos informado del progreso."
+		} else {
+			msg = "🚛 *Actualización de tu pedido*\n\n" +
+				"Tu pedido ha sido asignado a una ruta de entrega.\n" +
+				llegada + "\n\n" +
+				"Te avisaremos cuando estemos cerca."
+		}
+
+		// Enviar mensaje vía WhatsApp
+		if err := s.maps.NotifyCustomer(ctx, stop.PedidoID, "ruta_asignada", msg); err != nil {
+			fmt.Printf("Error notifying order %d: %v\n", stop.PedidoID, err)
 		}
 	}
+}
 
-	return nil
+// rutaTerminada indica si ya se entregaron o cancelaron todas las paradas.
+func rutaTerminada(ruta *DeliveryRoute) bool {
+	for _, stop := range ruta.Stops {
+		if stop.Status != "delivered" && stop.Status != "cancelled" {
+			return false
+		}
+	}
+	return true
+}
+
+// UpdateStopStatus refleja en las rutas activas el avance que captura el
+// repartidor. La ruta deja de seguirse al cerrarse su última parada.
+func (s *POSService) UpdateStopStatus(pedidoID int, status string) {
+	s.routesMutex.Lock()
+	defer s.routesMutex.Unlock()
+	for id, ruta := range s.activeRoutes {
+		for i := range ruta.Stops {
+			if ruta.Stops[i].PedidoID == pedidoID {
+				ruta.Stops[i].Status = status
+			}
+		}
+		if rutaTerminada(ruta) {
+			delete(s.activeRoutes, id)
+		}
+	}
 }
 
-// StartDeliveryTracking inicia el monitoreo de entregas
+// UpdateStopETA actualiza la hora estimada de la parada con la posición real del repartidor.
+func (s *POSService) UpdateStopETA(pedidoID int, eta time.Time) {
+	s.routesMutex.Lock()
+	defer s.routesMutex.Unlock()
+	for _, ruta := range s.activeRoutes {
+		for i := range ruta.Stops {
+			if ruta.Stops[i].PedidoID == pedidoID {
+				ruta.Stops[i].EstimatedTime = eta
+			}
+		}
+	}
+}
+
+// StartDeliveryTracking sincroniza con el POS cada IntervaloSync segundos (o
+// cada minuto si no está configurado). El aviso de "llegando" lo dispara la
+// geocerca del GPS del repartidor, no la ETA.
 func (s *POSService) StartDeliveryTracking(ctx context.Context) {
 	go func() {
-		ticker := time.NewTicker(30 * time.Second)
-		defer ticker.Stop()
+		intervalo := time.Minute
+		if config, err := s.getPOSConfig(); err != nil {
+			fmt.Printf("Error getting POS config: %v\n", err)
+		} else if config.IntervaloSync > 0 {
+			intervalo = time.Duration(config.IntervaloSync) * time.Second
+		}
+		sync := time.NewTicker(intervalo)
+		defer sync.Stop()
 
 		for {
 			select {
 			case <-ctx.Done():
 				return
-			case <-ticker.C:
-				s.routesMutex.RLock()
-				for _, ruta := range s.activeRoutes {
-					for _, stop := range ruta.Stops {
-						if stop.Status != "delivered" && stop.Status != "cancelled" {
-							// Verificar tiempo estimado
-							if time.Now().After(stop.EstimatedTime) {
-								pedido, err := s.store.GetPedido(ctx, stop.PedidoID)
-								if err != nil {
-									continue
-								}
-
-								if time.Now().Sub(stop.EstimatedTime) <= 5*time.Minute {
-									// Menos de 5 minutos para llegar
-									s.maps.UpdateDeliveryStatus(ctx, stop.PedidoID, "llegando", stop.Location)
-								} else if time.Now().Sub(stop.EstimatedTime) > 5*time.Minute {
-									// Más de 5 minutos esperando
-									if pedido.Estado == "esperando" {
-										s.maps.AutoCancelUnconfirmed(ctx)
-									}
-								}
-							}
-						}
-					}
+			case <-sync.C:
+				if err := s.SyncWithPOS(ctx); err != nil {
+					fmt.Printf("Error syncing with POS: %v\n", err)
 				}
-				s.routesMutex.RUnlock()
 			}
 		}
 	}()
@@ -150,13 +228,17 @@ func (s *POSService) StartDeliveryTracking(ctx context.Context) {
 // getPOSConfig obtiene la configuración de la terminal punto de venta
 func (s *POSService) getPOSConfig() (*POSConfig, error) {
 	url := fmt.Sprintf("%s/config?apiKey=%s", s.endpoint, s.apiKey)
-	
+
 	resp, err := s.httpClient.Get(url)
 	if err != nil {
 		return nil, fmt.Errorf("error getting POS config: %w", err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("POS config returned status %d", resp.StatusCode)
+	}
+
 	var config POSConfig
 	if err := json.NewDecoder(resp.Body).Decode(&config); err != nil {
 		return nil, fmt.Errorf("error decoding config: %w", err)
@@ -164,27 +246,3 @@ func (s *POSService) getPOSConfig() (*POSConfig, error) {
 
 	return &config, nil
 }
-
-// agruparPedidos agrupa pedidos por zona/proximidad
-func (s *POSService) agruparPedidos(pedidos []*store.Pedido, config *POSConfig) [][]*store.Pedido {
-	// TODO: Implementar agrupación inteligente por zonas
-	// Por ahora solo divide en grupos del tamaño máximo
-	
-	grupos := make([][]*store.Pedido, 0)
-	grupoActual := make([]*store.Pedido, 0)
-
-	for _, pedido := range pedidos {
-		grupoActual = append(grupoActual, pedido)
-		
-		if len(grupoActual) >= config.MaxPedidosPorRuta {
-			grupos = append(grupos, grupoActual)
-			grupoActual = make([]*store.Pedido, 0)
-		}
-	}
-
-	if len(grupoActual) > 0 {
-		grupos = append(grupos, grupoActual)
-	}
-
-	return grupos
-}
\ No newline at end of file
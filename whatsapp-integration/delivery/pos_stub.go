@@ -0,0 +1,48 @@
+package delivery
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// DefaultPOSConfig es la configuración que responde el POS simulado.
+var DefaultPOSConfig = POSConfig{
+	MaxPedidosPorRuta:   15,
+	TiempoMaximoRuta:    240,
+	DistanciaMaximaRuta: 60,
+	IntervaloSync:       300,
+}
+
+// NewStubPOSHandler simula la API de la terminal punto de venta: responde
+// GET /config con la configuración dada si el apiKey coincide.
+func NewStubPOSHandler(apiKey string, config POSConfig) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/config", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		if r.URL.Query().Get("apiKey") != apiKey {
+			http.Error(w, "API key inválida", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(config)
+	})
+	return mux
+}
+
+// StartStubPOS levanta el POS simulado en un puerto local libre para pruebas o
+// para operar sin terminal. Regresa el endpoint a usar en NewPOSService y una
+// función para detenerlo.
+func StartStubPOS(apiKey string, config POSConfig) (string, func() error, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", nil, fmt.Errorf("error starting POS stub: %w", err)
+	}
+	srv := &http.Server{Handler: NewStubPOSHandler(apiKey, config)}
+	go srv.Serve(ln)
+	return "http://" + ln.Addr().String(), srv.Close, nil
+}
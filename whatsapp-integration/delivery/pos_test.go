@@ -0,0 +1,220 @@
+package delivery
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/whatsapp-integration/store"
+)
+
+func TestStubPOSHandler(t *testing.T) {
+	casos := []struct {
+		nombre string
+		metodo string
+		url    string
+		status int
+	}{
+		{"config", http.MethodGet, "/config?apiKey=clave", http.StatusOK},
+		{"api key inválida", http.MethodGet, "/config?apiKey=otra", http.StatusUnauthorized},
+		{"sin api key", http.MethodGet, "/config", http.StatusUnauthorized},
+		{"método no permitido", http.MethodPost, "/config?apiKey=clave", http.StatusMethodNotAllowed},
+		{"ruta desconocida", http.MethodGet, "/pedidos?apiKey=clave", http.StatusNotFound},
+	}
+	h := NewStubPOSHandler("clave", DefaultPOSConfig)
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(c.metodo, c.url, nil))
+			if rec.Code != c.status {
+				t.Fatalf("status = %d, se esperaba %d", rec.Code, c.status)
+			}
+		})
+	}
+}
+
+func TestGetPOSConfigDelStub(t *testing.T) {
+	endpoint, stop, err := StartStubPOS("clave", DefaultPOSConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+
+	config, err := NewPOSService(endpoint, "clave", nil, nil).getPOSConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *config != DefaultPOSConfig {
+		t.Fatalf("config = %+v, se esperaba %+v", *config, DefaultPOSConfig)
+	}
+	if _, err := NewPOSService(endpoint, "otra", nil, nil).getPOSConfig(); err == nil {
+		t.Fatal("se esperaba error con api key inválida")
+	}
+}
+
+// storeRutas solo implementa lo que usa la sincronización; el resto del Store
+// queda nil y haría panic si se llamara.
+type storeRutas struct {
+	store.Store
+	rutas       []*store.Ruta
+	paradas     map[int][]*store.RutaParada
+	pedidos     map[int]*store.Pedido
+	avisos      map[int]bool
+	fechaPedida time.Time
+}
+
+func (s *storeRutas) GetRutasPorFecha(ctx context.Context, fecha time.Time) ([]*store.Ruta, error) {
+	s.fechaPedida = fecha
+	return s.rutas, nil
+}
+
+func (s *storeRutas) GetParadasRuta(ctx context.Context, rutaID int) ([]*store.RutaParada, error) {
+	return s.paradas[rutaID], nil
+}
+
+func (s *storeRutas) GetPedido(ctx context.Context, id int) (*store.Pedido, error) {
+	return s.pedidos[id], nil
+}
+
+func (s *storeRutas) GetClientePorID(ctx context.Context, id int) (*store.Cliente, error) {
+	return &store.Cliente{ID: id, NumeroTelefono: fmt.Sprintf("tel-%d", id)}, nil
+}
+
+func (s *storeRutas) NotificacionEnviada(ctx context.Context, pedidoID int, tipo string) (bool, error) {
+	return s.avisos[pedidoID], nil
+}
+
+func (s *storeRutas) RegistrarNotificacion(ctx context.Context, n *store.NotificacionPedido) error {
+	s.avisos[n.PedidoID] = true
+	return nil
+}
+
+type senderPrueba struct {
+	mensajes map[string]string
+}
+
+func (s *senderPrueba) SendMessage(to, text string) error {
+	s.mensajes[to] = text
+	return nil
+}
+
+func TestSyncWithPOS(t *testing.T) {
+	loc := time.FixedZone("CST", -6*3600)
+	eta := time.Date(2026, 3, 2, 10, 30, 0, 0, loc)
+	pedido := func(id int) *store.Pedido {
+		return &store.Pedido{ID: id, ClienteID: id, Latitud: 19.4, Longitud: -99.1}
+	}
+
+	casos := []struct {
+		nombre   string
+		rutas    []*store.Ruta
+		paradas  map[int][]*store.RutaParada
+		seguidas []string
+		avisados []string
+	}{
+		{
+			nombre: "ruta con camión",
+			rutas:  []*store.Ruta{{ID: 1, CamionID: 3}},
+			paradas: map[int][]*store.RutaParada{1: {
+				{PedidoID: 1, Estado: "delivered"},
+				{PedidoID: 2, Estado: "pending", HoraEstimada: &eta},
+				{PedidoID: 3, Estado: "pending"},
+			}},
+			seguidas: []string{"ruta-1"},
+			avisados: []string{"tel-2", "tel-3"},
+		},
+		{
+			nombre:  "ruta sin camión",
+			rutas:   []*store.Ruta{{ID: 2}},
+			paradas: map[int][]*store.RutaParada{2: {{PedidoID: 1, Estado: "pending"}}},
+		},
+		{
+			nombre: "ruta terminada",
+			rutas:  []*store.Ruta{{ID: 4, CamionID: 1}},
+			paradas: map[int][]*store.RutaParada{4: {
+				{PedidoID: 1, Estado: "delivered"},
+				{PedidoID: 2, Estado: "cancelled"},
+			}},
+		},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			st := &storeRutas{
+				rutas:   c.rutas,
+				paradas: c.paradas,
+				pedidos: map[int]*store.Pedido{1: pedido(1), 2: pedido(2), 3: pedido(3)},
+				avisos:  make(map[int]bool),
+			}
+			sender := &senderPrueba{mensajes: make(map[string]string)}
+			maps := NewMapsService("", st)
+			maps.SetSender(sender)
+			pos := NewPOSService("", "", st, maps)
+			pos.SetLocation(loc)
+			// Una ruta de un día anterior que ya no debe seguirse.
+			pos.activeRoutes["ruta-99"] = &DeliveryRoute{DriverID: "ruta-99", Stops: []RouteStop{{PedidoID: 9, Status: "pending"}}}
+
+			if err := pos.SyncWithPOS(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if h, m, s := st.fechaPedida.Clock(); h != 0 || m != 0 || s != 0 || st.fechaPedida.Location() != loc {
+				t.Errorf("fecha consultada = %v, se esperaba la medianoche local", st.fechaPedida)
+			}
+			if len(pos.activeRoutes) != len(c.seguidas) {
+				t.Fatalf("rutas seguidas = %v, se esperaba %v", pos.activeRoutes, c.seguidas)
+			}
+			for _, id := range c.seguidas {
+				if pos.activeRoutes[id] == nil {
+					t.Fatalf("no se sigue %s", id)
+				}
+			}
+			if len(sender.mensajes) != len(c.avisados) {
+				t.Fatalf("avisos = %v, se esperaban a %v", sender.mensajes, c.avisados)
+			}
+			for _, tel := range c.avisados {
+				if sender.mensajes[tel] == "" {
+					t.Fatalf("no se avisó a %s", tel)
+				}
+			}
+		})
+	}
+}
+
+func TestSyncWithPOSAvisaUnaVezYSueltaRutaTerminada(t *testing.T) {
+	eta := time.Date(2026, 3, 2, 10, 30, 0, 0, time.UTC)
+	st := &storeRutas{
+		rutas:   []*store.Ruta{{ID: 5, CamionID: 2}},
+		paradas: map[int][]*store.RutaParada{5: {{PedidoID: 1, Estado: "pending", HoraEstimada: &eta}}},
+		pedidos: map[int]*store.Pedido{1: {ID: 1, ClienteID: 1}},
+		avisos:  make(map[int]bool),
+	}
+	sender := &senderPrueba{mensajes: make(map[string]string)}
+	maps := NewMapsService("", st)
+	maps.SetSender(sender)
+	pos := NewPOSService("", "", st, maps)
+	pos.SetLocation(time.UTC)
+
+	ctx := context.Background()
+	if err := pos.SyncWithPOS(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if msg := sender.mensajes["tel-1"]; !strings.Contains(msg, "10:30") {
+		t.Fatalf("aviso = %q, se esperaba la hora estimada", msg)
+	}
+
+	delete(sender.mensajes, "tel-1")
+	if err := pos.SyncWithPOS(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(sender.mensajes) != 0 {
+		t.Fatalf("se volvió a avisar: %v", sender.mensajes)
+	}
+
+	pos.UpdateStopStatus(1, "delivered")
+	if len(pos.activeRoutes) != 0 {
+		t.Fatalf("la ruta terminada se sigue: %v", pos.activeRoutes)
+	}
+}
@@ -0,0 +1,275 @@
+// Package espera controla el tiempo que el repartidor espera al cliente en su
+// domicilio: arranca con la llegada, avisa al cliente antes de que se agote y,
+// si nadie atiende, cierra la entrega como "no atendido", asigna el strike y
+// reagenda o cancela el pedido según la política configurada.
+package espera
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"example.com/whatsapp-integration/delivery"
+	"example.com/whatsapp-integration/store"
+)
+
+// Estados de una espera.
+const (
+	EstadoActiva     = "activa"
+	EstadoAtendida   = "atendida"
+	EstadoNoAtendido = "no_atendido"
+	EstadoCancelada  = "cancelada"
+)
+
+// Políticas para el pedido cuando el cliente no atiende.
+const (
+	PoliticaReagendar = "reagendar" // vuelve a pendiente y entra al siguiente corte diario
+	PoliticaCancelar  = "cancelar"
+)
+
+// Config define los tiempos de espera y qué hacer con el pedido no atendido.
+type Config struct {
+	Espera   time.Duration
+	Aviso    time.Duration // anticipación del aviso de cuenta regresiva
+	Politica string
+}
+
+// ConfigDesdeEnv lee ESPERA_MINUTOS (10), ESPERA_AVISO_MINUTOS (3) y
+// ESPERA_POLITICA ("reagendar" o "cancelar").
+func ConfigDesdeEnv() Config {
+	cfg := Config{
+		Espera:   delivery.DefaultWaitTime,
+		Aviso:    3 * time.Minute,
+		Politica: PoliticaReagendar,
+	}
+	if minutos, err := strconv.Atoi(os.Getenv("ESPERA_MINUTOS")); err == nil && minutos > 0 {
+		cfg.Espera = time.Duration(minutos) * time.Minute
+	}
+	if minutos, err := strconv.Atoi(os.Getenv("ESPERA_AVISO_MINUTOS")); err == nil && minutos >= 0 {
+		cfg.Aviso = time.Duration(minutos) * time.Minute
+	}
+	if p := os.Getenv("ESPERA_POLITICA"); p == PoliticaCancelar {
+		cfg.Politica = p
+	}
+	return cfg
+}
+
+// AsignadorStrikes registra el strike del cliente que no atendió. Lo implementa
+// bot.StateMachine.
+type AsignadorStrikes interface {
+	AsignarStrike(ctx context.Context, telefono, motivo string, pedidoID int) error
+}
+
+// motivoNoAtendido coincide con bot.MotivoNoAtendido.
+const motivoNoAtendido = "no_atendido"
+
+// StopTracker recibe el cierre de la parada para el seguimiento en memoria.
+type StopTracker interface {
+	UpdateStopStatus(pedidoID int, status string)
+}
+
+// Servicio lleva los temporizadores de espera. Se persisten en la base para
+// sobrevivir reinicios; Revisar los evalúa periódicamente.
+type Servicio struct {
+	store   store.Store
+	maps    *delivery.MapsService
+	cfg     Config
+	strikes AsignadorStrikes
+	tracker StopTracker
+	mu      sync.Mutex
+}
+
+func NewServicio(s store.Store, maps *delivery.MapsService, cfg Config) *Servicio {
+	if cfg.Espera <= 0 {
+		cfg.Espera = delivery.DefaultWaitTime
+	}
+	if cfg.Politica == "" {
+		cfg.Politica = PoliticaReagendar
+	}
+	return &Servicio{store: s, maps: maps, cfg: cfg}
+}
+
+// SetStrikes habilita la asignación automática de strikes.
+func (s *Servicio) SetStrikes(a AsignadorStrikes) {
+	s.strikes = a
+}
+
+// SetTracker avisa al seguimiento de rutas cuando una parada se cierra.
+func (s *Servicio) SetTracker(t StopTracker) {
+	s.tracker = t
+}
+
+// Iniciar arranca la espera del pedido. Si ya hay una activa la regresa sin
+// reiniciar el tiempo, para que marcar la llegada dos veces no dé más minutos.
+func (s *Servicio) Iniciar(ctx context.Context, pedidoID, paradaID int) (*store.EsperaEntrega, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	actual, err := s.store.GetEsperaActiva(ctx, pedidoID)
+	if err != nil {
+		return nil, err
+	}
+	if actual != nil {
+		return actual, nil
+	}
+
+	ahora := time.Now()
+	espera := &store.EsperaEntrega{
+		PedidoID: pedidoID,
+		ParadaID: paradaID,
+		Inicio:   ahora,
+		Limite:   ahora.Add(s.cfg.Espera),
+		Estado:   EstadoActiva,
+	}
+	if err := s.store.CrearEspera(ctx, espera); err != nil {
+		return nil, err
+	}
+	log.Printf("Espera del pedido %d iniciada hasta %s\n", pedidoID, espera.Limite.Format("15:04:05"))
+	return espera, nil
+}
+
+// Start revisa las esperas activas cada 30 segundos hasta que se cancele ctx.
+func (s *Servicio) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(30 * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ahora := <-ticker.C:
+				if err := s.Revisar(ctx, ahora); err != nil {
+					log.Printf("Error revisando esperas: %v\n", err)
+				}
+			}
+		}
+	}()
+}
+
+// Revisar cierra las esperas cuyo pedido ya se resolvió, manda la cuenta
+// regresiva y cierra como no atendidas las que llegaron a su límite.
+func (s *Servicio) Revisar(ctx context.Context, ahora time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	esperas, err := s.store.GetEsperasActivas(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, espera := range esperas {
+		pedido, err := s.store.GetPedido(ctx, espera.PedidoID)
+		if err != nil {
+			log.Printf("Error consultando pedido %d en espera: %v\n", espera.PedidoID, err)
+			continue
+		}
+
+		// El repartidor entregó, o alguien más movió el pedido: ya no hay espera.
+		if pedido == nil || pedido.Estado != "esperando" {
+			espera.Estado = EstadoCancelada
+			espera.CerradaPor = "sistema"
+			if pedido != nil && pedido.Estado == "entregado" {
+				espera.Estado = EstadoAtendida
+				espera.CerradaPor = "repartidor"
+			}
+			if err := s.store.ActualizarEspera(ctx, espera); err != nil {
+				log.Printf("Error cerrando espera %d: %v\n", espera.ID, err)
+			}
+			continue
+		}
+
+		if !ahora.Before(espera.Limite) {
+			if err := s.cerrarNoAtendido(ctx, espera, pedido, "sistema"); err != nil {
+				log.Printf("Error cerrando pedido %d como no atendido: %v\n", pedido.ID, err)
+			}
+			continue
+		}
+
+		if !espera.AvisoEnviado && espera.Limite.Sub(ahora) <= s.cfg.Aviso {
+			s.avisarCuentaRegresiva(ctx, espera, ahora)
+		}
+	}
+	return nil
+}
+
+func (s *Servicio) avisarCuentaRegresiva(ctx context.Context, espera *store.EsperaEntrega, ahora time.Time) {
+	minutos := int(espera.Limite.Sub(ahora).Round(time.Minute).Minutes())
+	if minutos < 1 {
+		minutos = 1
+	}
+	msg := fmt.Sprintf("⏳ *El repartidor sigue esperando*\n\n"+
+		"Quedan %d minuto(s) para recibir tu pedido #%d.\n"+
+		"Si no lo atiendes se registrará un strike.", minutos, espera.PedidoID)
+
+	// El tipo lleva la espera y su límite: un pedido reagendado o una espera
+	// extendida por el operador vuelven a recibir su aviso.
+	tipo := fmt.Sprintf("espera_aviso_%d_%d", espera.ID, espera.Limite.Unix())
+	if err := s.maps.NotifyCustomer(ctx, espera.PedidoID, tipo, msg); err != nil {
+		log.Printf("Error enviando cuenta regresiva del pedido %d: %v\n", espera.PedidoID, err)
+		return
+	}
+	espera.AvisoEnviado = true
+	if err := s.store.ActualizarEspera(ctx, espera); err != nil {
+		log.Printf("Error marcando aviso de la espera %d: %v\n", espera.ID, err)
+	}
+}
+
+// cerrarNoAtendido cierra la espera, cancela la parada, asigna el strike y
+// reagenda o cancela el pedido. Los clientes bloqueados por el strike siempre
+// se cancelan.
+func (s *Servicio) cerrarNoAtendido(ctx context.Context, espera *store.EsperaEntrega, pedido *store.Pedido, cerradaPor string) error {
+	espera.Estado = EstadoNoAtendido
+	espera.CerradaPor = cerradaPor
+	if err := s.store.ActualizarEspera(ctx, espera); err != nil {
+		return err
+	}
+
+	if espera.ParadaID != 0 {
+		if err := s.store.ActualizarEstadoParada(ctx, espera.ParadaID, "cancelled"); err != nil {
+			log.Printf("Error cancelando parada %d: %v\n", espera.ParadaID, err)
+		}
+	}
+	if s.tracker != nil {
+		s.tracker.UpdateStopStatus(pedido.ID, "cancelled")
+	}
+
+	cliente, err := s.store.GetClientePorID(ctx, pedido.ClienteID)
+	if err != nil {
+		return fmt.Errorf("error consultando cliente del pedido %d: %w", pedido.ID, err)
+	}
+	if cliente != nil && s.strikes != nil {
+		if err := s.strikes.AsignarStrike(ctx, cliente.NumeroTelefono, motivoNoAtendido, pedido.ID); err != nil {
+			log.Printf("Error asignando strike por el pedido %d: %v\n", pedido.ID, err)
+		}
+		// AsignarStrike puede haber bloqueado al cliente.
+		if cliente, err = s.store.GetClientePorID(ctx, pedido.ClienteID); err != nil {
+			return fmt.Errorf("error consultando cliente del pedido %d: %w", pedido.ID, err)
+		}
+	}
+
+	if s.cfg.Politica == PoliticaCancelar || cliente == nil || cliente.Bloqueado {
+		log.Printf("Pedido %d no atendido: cancelado\n", pedido.ID)
+		return s.maps.CancelNoShow(ctx, pedido.ID)
+	}
+
+	// Reagendar: el pedido vuelve a pendiente y el siguiente corte diario lo
+	// incluye en una ruta nueva.
+	pedido.Estado = "pendiente"
+	if err := s.store.ActualizarPedido(ctx, pedido); err != nil {
+		return err
+	}
+	log.Printf("Pedido %d no atendido: reagendado\n", pedido.ID)
+	if err := s.maps.ReiniciarAvisos(ctx, pedido.ID); err != nil {
+		log.Printf("Error reiniciando avisos del pedido %d: %v\n", pedido.ID, err)
+	}
+	msg := fmt.Sprintf("📅 Tu pedido #%d fue reagendado para la siguiente ruta de reparto.\n"+
+		"Te avisaremos cuando el repartidor vaya en camino.", pedido.ID)
+	if err := s.maps.NotifyCustomer(ctx, pedido.ID, fmt.Sprintf("reagendado_%d", espera.ID), msg); err != nil {
+		log.Printf("Error avisando reagendado del pedido %d: %v\n", pedido.ID, err)
+	}
+	return nil
+}
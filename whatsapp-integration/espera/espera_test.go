@@ -0,0 +1,226 @@
+package espera
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/whatsapp-integration/delivery"
+	"example.com/whatsapp-integration/store"
+)
+
+type senderPrueba struct {
+	mensajes []string
+}
+
+func (s *senderPrueba) SendMessage(to, text string) error {
+	s.mensajes = append(s.mensajes, text)
+	return nil
+}
+
+// contar regresa cuántos mensajes empiezan con prefijo.
+func (s *senderPrueba) contar(prefijo string) int {
+	n := 0
+	for _, m := range s.mensajes {
+		if strings.HasPrefix(m, prefijo) {
+			n++
+		}
+	}
+	return n
+}
+
+type strikesPrueba struct {
+	pedidos []int
+}
+
+func (a *strikesPrueba) AsignarStrike(ctx context.Context, telefono, motivo string, pedidoID int) error {
+	a.pedidos = append(a.pedidos, pedidoID)
+	return nil
+}
+
+// nuevoPedido crea en SQLite un cliente con un pedido en camino.
+func nuevoPedido(t *testing.T) (*store.SQLiteStore, *store.Pedido) {
+	t.Helper()
+	db, err := store.NewSQLiteStore(store.Config{Database: filepath.Join(t.TempDir(), "espera.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	ctx := context.Background()
+	cliente := &store.Cliente{NumeroTelefono: "5215550000001", Nombre: "Ana"}
+	if err := db.CrearCliente(ctx, cliente); err != nil {
+		t.Fatal(err)
+	}
+	pedido := &store.Pedido{ClienteID: cliente.ID, TipoServicio: "estacionario", Estado: "en_ruta", Latitud: 19.43, Longitud: -99.13, Direccion: "Calle 1"}
+	if err := db.CrearPedido(ctx, pedido); err != nil {
+		t.Fatal(err)
+	}
+	return db, pedido
+}
+
+// TestReagendadoVuelveARecibirAvisos cierra una espera sin atender y repite el
+// recorrido: el segundo intento vuelve a avisar que el pedido va en camino.
+func TestReagendadoVuelveARecibirAvisos(t *testing.T) {
+	db, pedido := nuevoPedido(t)
+	ctx := context.Background()
+	avisos := &senderPrueba{}
+	maps := delivery.NewMapsService("", db)
+	maps.SetSender(avisos)
+	strikes := &strikesPrueba{}
+	s := NewServicio(db, maps, Config{Espera: 10 * time.Minute, Aviso: 3 * time.Minute, Politica: PoliticaReagendar})
+	s.SetStrikes(strikes)
+
+	enCamino := "🚛 *Tu pedido está en camino*"
+	hemosLlegado := "🔔 *¡Hemos llegado!*"
+	intento := func() {
+		t.Helper()
+		for _, estado := range []string{"en_ruta", "esperando"} {
+			if err := maps.UpdateDeliveryStatus(ctx, pedido.ID, estado, delivery.Location{}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	intento()
+	if _, err := s.Iniciar(ctx, pedido.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Revisar(ctx, time.Now().Add(11*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	reagendado, err := db.GetPedido(ctx, pedido.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reagendado.Estado != "pendiente" || len(strikes.pedidos) != 1 {
+		t.Fatalf("pedido = %q, strikes = %v; se esperaba pendiente con un strike", reagendado.Estado, strikes.pedidos)
+	}
+	if avisos.contar("📅 Tu pedido #") != 1 {
+		t.Fatalf("avisos = %q, falta el aviso de reagendado", avisos.mensajes)
+	}
+
+	intento()
+	if n := avisos.contar(enCamino); n != 2 {
+		t.Fatalf("avisos en camino = %d, se esperaban 2 (uno por intento)", n)
+	}
+	if n := avisos.contar(hemosLlegado); n != 2 {
+		t.Fatalf("avisos de llegada = %d, se esperaban 2 (uno por intento)", n)
+	}
+
+	// Dentro del mismo intento el aviso no se repite.
+	if err := maps.UpdateDeliveryStatus(ctx, pedido.ID, "en_ruta", delivery.Location{}); err != nil {
+		t.Fatal(err)
+	}
+	if n := avisos.contar(enCamino); n != 2 {
+		t.Fatalf("avisos en camino = %d, el mismo intento no debe repetirlo", n)
+	}
+}
+
+// TestNoAtendidoConPoliticaCancelar anuncia la cancelación al llegar y, al
+// agotarse la espera, explica que el cliente no atendió al repartidor.
+func TestNoAtendidoConPoliticaCancelar(t *testing.T) {
+	db, pedido := nuevoPedido(t)
+	ctx := context.Background()
+	avisos := &senderPrueba{}
+	maps := delivery.NewMapsService("", db)
+	maps.SetSender(avisos)
+	maps.SetCancelNoShow(true)
+	s := NewServicio(db, maps, Config{Espera: 10 * time.Minute, Politica: PoliticaCancelar})
+	s.SetStrikes(&strikesPrueba{})
+
+	if err := maps.UpdateDeliveryStatus(ctx, pedido.ID, "esperando", delivery.Location{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Iniciar(ctx, pedido.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Revisar(ctx, time.Now().Add(11*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	cancelado, err := db.GetPedido(ctx, pedido.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cancelado.Estado != "cancelado" {
+		t.Fatalf("pedido = %q, se esperaba cancelado", cancelado.Estado)
+	}
+	if len(avisos.mensajes) != 2 {
+		t.Fatalf("avisos = %q, se esperaban la llegada y la cancelación", avisos.mensajes)
+	}
+	if llegada := avisos.mensajes[0]; !strings.Contains(llegada, "se cancelará") || strings.Contains(llegada, "reagendará") {
+		t.Fatalf("aviso de llegada = %q, debe anunciar la cancelación", llegada)
+	}
+	if cancelacion := avisos.mensajes[1]; !strings.Contains(cancelacion, "nadie recibió el pedido") || strings.Contains(cancelacion, "fue cancelado.") {
+		t.Fatalf("aviso de cancelación = %q, debe explicar que nadie atendió", cancelacion)
+	}
+}
+
+// TestCuentaRegresivaUnaVez manda el aviso una sola vez por límite, aunque
+// se revise varias veces o el servicio se reinicie, y lo repite si el
+// operador extiende la espera.
+func TestCuentaRegresivaUnaVez(t *testing.T) {
+	db, pedido := nuevoPedido(t)
+	ctx := context.Background()
+	avisos := &senderPrueba{}
+	maps := delivery.NewMapsService("", db)
+	maps.SetSender(avisos)
+	cfg := Config{Espera: 10 * time.Minute, Aviso: 3 * time.Minute, Politica: PoliticaReagendar}
+	s := NewServicio(db, maps, cfg)
+
+	if err := maps.UpdateDeliveryStatus(ctx, pedido.ID, "esperando", delivery.Location{}); err != nil {
+		t.Fatal(err)
+	}
+	espera, err := s.Iniciar(ctx, pedido.ID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	limite := espera.Limite
+
+	cuentaRegresiva := "⏳ *El repartidor sigue esperando*"
+	pasos := []struct {
+		nombre   string
+		antes    func()
+		revision time.Time
+		avisos   int
+		quedan   string // texto del último aviso; vacío si no se revisa
+	}{
+		{"lejos del límite", nil, limite.Add(-5 * time.Minute), 0, ""},
+		{"dentro del aviso", nil, limite.Add(-3 * time.Minute), 1, "Quedan 3 minuto(s)"},
+		{"revisión repetida", nil, limite.Add(-time.Minute), 1, ""},
+		{"tras reiniciar el servicio", func() { s = NewServicio(db, maps, cfg) }, limite.Add(-30 * time.Second), 1, ""},
+		{"espera extendida", func() {
+			if _, err := s.Extender(ctx, pedido.ID, 5*time.Minute, "operador"); err != nil {
+				t.Fatal(err)
+			}
+		}, limite.Add(3 * time.Minute), 2, "Quedan 2 minuto(s)"},
+		{"extendida repetida", nil, limite.Add(4 * time.Minute), 2, ""},
+	}
+	for _, p := range pasos {
+		t.Run(p.nombre, func(t *testing.T) {
+			if p.antes != nil {
+				p.antes()
+			}
+			if err := s.Revisar(ctx, p.revision); err != nil {
+				t.Fatal(err)
+			}
+			if n := avisos.contar(cuentaRegresiva); n != p.avisos {
+				t.Fatalf("avisos de cuenta regresiva = %d, se esperaban %d", n, p.avisos)
+			}
+			if ultimo := avisos.mensajes[len(avisos.mensajes)-1]; p.quedan != "" && !strings.Contains(ultimo, p.quedan) {
+				t.Fatalf("último aviso = %q, debía decir %q", ultimo, p.quedan)
+			}
+		})
+	}
+
+	actual, err := db.GetPedido(ctx, pedido.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual.Estado != "esperando" {
+		t.Fatalf("pedido = %q, la cuenta regresiva no debe cerrar la espera", actual.Estado)
+	}
+}
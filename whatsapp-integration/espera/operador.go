@@ -0,0 +1,177 @@
+package espera
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"example.com/whatsapp-integration/store"
+)
+
+// Extender da más minutos a la espera activa del pedido y permite que se
+// vuelva a mandar la cuenta regresiva.
+func (s *Servicio) Extender(ctx context.Context, pedidoID int, extra time.Duration, operador string) (*store.EsperaEntrega, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	espera, err := s.esperaActiva(ctx, pedidoID)
+	if err != nil {
+		return nil, err
+	}
+	espera.Limite = espera.Limite.Add(extra)
+	espera.AvisoEnviado = false
+	espera.CerradaPor = operador
+	if err := s.store.ActualizarEspera(ctx, espera); err != nil {
+		return nil, err
+	}
+	log.Printf("Espera del pedido %d extendida %s por %s\n", pedidoID, extra, operador)
+	return espera, nil
+}
+
+// Liberar detiene la espera sin strike ni cambios al pedido, por ejemplo si el
+// cliente avisó por teléfono que ya sale.
+func (s *Servicio) Liberar(ctx context.Context, pedidoID int, operador string) (*store.EsperaEntrega, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	espera, err := s.esperaActiva(ctx, pedidoID)
+	if err != nil {
+		return nil, err
+	}
+	espera.Estado = EstadoCancelada
+	espera.CerradaPor = operador
+	if err := s.store.ActualizarEspera(ctx, espera); err != nil {
+		return nil, err
+	}
+	log.Printf("Espera del pedido %d liberada por %s\n", pedidoID, operador)
+	return espera, nil
+}
+
+// CerrarAhora cierra la espera como no atendida sin esperar al límite.
+func (s *Servicio) CerrarAhora(ctx context.Context, pedidoID int, operador string) (*store.EsperaEntrega, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	espera, err := s.esperaActiva(ctx, pedidoID)
+	if err != nil {
+		return nil, err
+	}
+	pedido, err := s.store.GetPedido(ctx, pedidoID)
+	if err != nil {
+		return nil, err
+	}
+	if pedido == nil {
+		return nil, fmt.Errorf("pedido %d no encontrado", pedidoID)
+	}
+	if err := s.cerrarNoAtendido(ctx, espera, pedido, operador); err != nil {
+		return nil, err
+	}
+	return espera, nil
+}
+
+func (s *Servicio) esperaActiva(ctx context.Context, pedidoID int) (*store.EsperaEntrega, error) {
+	espera, err := s.store.GetEsperaActiva(ctx, pedidoID)
+	if err != nil {
+		return nil, err
+	}
+	if espera == nil {
+		return nil, errSinEspera
+	}
+	return espera, nil
+}
+
+var errSinEspera = fmt.Errorf("el pedido no tiene una espera activa")
+
+// Handler expone las intervenciones del operador bajo /operador/esperas:
+//
+//	GET  /operador/esperas           lista las esperas activas
+//	POST /operador/esperas/extender  {"pedido_id", "minutos", "operador"}
+//	POST /operador/esperas/liberar   {"pedido_id", "operador"}
+//	POST /operador/esperas/cerrar    {"pedido_id", "operador"}
+//
+// Todas las peticiones requieren "Authorization: Bearer <token>".
+func (s *Servicio) Handler(token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/operador/esperas", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		esperas, err := s.store.GetEsperasActivas(r.Context())
+		if err != nil {
+			log.Printf("Error consultando esperas activas: %v\n", err)
+			http.Error(w, "Error interno", http.StatusInternalServerError)
+			return
+		}
+		responderJSON(w, map[string]interface{}{"esperas": esperas})
+	})
+	mux.HandleFunc("/operador/esperas/extender", s.intervencion(func(ctx context.Context, p peticionOperador) (*store.EsperaEntrega, error) {
+		if p.Minutos <= 0 {
+			return nil, fmt.Errorf("minutos debe ser mayor a cero")
+		}
+		return s.Extender(ctx, p.PedidoID, time.Duration(p.Minutos)*time.Minute, p.Operador)
+	}))
+	mux.HandleFunc("/operador/esperas/liberar", s.intervencion(func(ctx context.Context, p peticionOperador) (*store.EsperaEntrega, error) {
+		return s.Liberar(ctx, p.PedidoID, p.Operador)
+	}))
+	mux.HandleFunc("/operador/esperas/cerrar", s.intervencion(func(ctx context.Context, p peticionOperador) (*store.EsperaEntrega, error) {
+		return s.CerrarAhora(ctx, p.PedidoID, p.Operador)
+	}))
+	return conToken(token, mux)
+}
+
+type peticionOperador struct {
+	PedidoID int    `json:"pedido_id"`
+	Minutos  int    `json:"minutos"`
+	Operador string `json:"operador"`
+}
+
+func (s *Servicio) intervencion(fn func(ctx context.Context, p peticionOperador) (*store.EsperaEntrega, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		var p peticionOperador
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil || p.PedidoID == 0 {
+			http.Error(w, "Petición inválida", http.StatusBadRequest)
+			return
+		}
+		if p.Operador == "" {
+			p.Operador = "operador"
+		}
+
+		espera, err := fn(r.Context(), p)
+		if err == errSinEspera {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("Error en intervención de espera del pedido %d: %v\n", p.PedidoID, err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		responderJSON(w, espera)
+	}
+}
+
+func conToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recibido := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token == "" || subtle.ConstantTimeCompare([]byte(recibido), []byte(token)) != 1 {
+			http.Error(w, "No autorizado", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func responderJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,80 @@
+package facturacion
+
+// Regimen es una clave del catálogo c_RegimenFiscal del SAT.
+type Regimen struct {
+	Clave       string
+	Descripcion string
+	Fisica      bool // aplica a personas físicas
+	Moral       bool // aplica a personas morales
+}
+
+// Regimenes son los regímenes que se ofrecen en el bot.
+var Regimenes = []Regimen{
+	{"601", "General de Ley Personas Morales", false, true},
+	{"603", "Personas Morales con Fines no Lucrativos", false, true},
+	{"605", "Sueldos y Salarios e Ingresos Asimilados a Salarios", true, false},
+	{"606", "Arrendamiento", true, false},
+	{"612", "Personas Físicas con Actividades Empresariales y Profesionales", true, false},
+	{"616", "Sin obligaciones fiscales", true, false},
+	{"621", "Incorporación Fiscal", true, false},
+	{"625", "Actividades Empresariales con ingresos a través de Plataformas Tecnológicas", true, false},
+	{"626", "Régimen Simplificado de Confianza", true, true},
+}
+
+// RegimenesPara regresa los regímenes que aplican al tipo de persona del RFC.
+func RegimenesPara(rfc string) []Regimen {
+	moral := EsPersonaMoral(rfc)
+	var lista []Regimen
+	for _, r := range Regimenes {
+		if (moral && r.Moral) || (!moral && r.Fisica) {
+			lista = append(lista, r)
+		}
+	}
+	return lista
+}
+
+// UsoCFDI es una clave del catálogo c_UsoCFDI del SAT.
+type UsoCFDI struct {
+	Clave       string
+	Descripcion string
+}
+
+// UsosCFDI son los usos que se ofrecen en el bot; el gas se factura como
+// gasto o como mercancía.
+var UsosCFDI = []UsoCFDI{
+	{"G03", "Gastos en general"},
+	{"G01", "Adquisición de mercancías"},
+	{"S01", "Sin efectos fiscales"},
+}
+
+// DescripcionRegimen regresa la descripción de la clave, o la clave si no
+// está en el catálogo.
+func DescripcionRegimen(clave string) string {
+	for _, r := range Regimenes {
+		if r.Clave == clave {
+			return r.Descripcion
+		}
+	}
+	return clave
+}
+
+// DescripcionUso regresa la descripción de la clave, o la clave si no está en
+// el catálogo.
+func DescripcionUso(clave string) string {
+	for _, u := range UsosCFDI {
+		if u.Clave == clave {
+			return u.Descripcion
+		}
+	}
+	return clave
+}
+
+// Formas de pago del catálogo c_FormaPago según el método de pago del pedido.
+var formasPago = map[string]string{
+	"efectivo":      "01",
+	"transferencia": "03",
+	"tarjeta":       "04",
+	"liga_pago":     "04",
+}
+
+const formaPagoPorDefinir = "99"
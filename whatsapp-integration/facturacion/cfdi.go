@@ -0,0 +1,186 @@
+package facturacion
+
+import (
+	"encoding/xml"
+	"fmt"
+	"math"
+	"time"
+
+	"example.com/whatsapp-integration/store"
+)
+
+// Datos fijos del CFDI para venta de gas LP.
+const (
+	claveProdServGasLP = "15111510" // Gas licuado de petróleo
+	claveUnidadLitro   = "LTR"
+	claveUnidadPieza   = "H87"
+	tasaIVA            = 0.16
+)
+
+// Emisor son los datos fiscales del negocio.
+type Emisor struct {
+	RFC           string
+	Nombre        string
+	RegimenFiscal string
+	CodigoPostal  string // lugar de expedición
+}
+
+// Comprobante es el CFDI 4.0 sin sellar ni timbrar. Los nombres de elementos y
+// atributos siguen el anexo 20 del SAT.
+type Comprobante struct {
+	XMLName           xml.Name   `xml:"cfdi:Comprobante"`
+	XmlnsCfdi         string     `xml:"xmlns:cfdi,attr"`
+	XmlnsXsi          string     `xml:"xmlns:xsi,attr"`
+	SchemaLocation    string     `xml:"xsi:schemaLocation,attr"`
+	Version           string     `xml:"Version,attr"`
+	Serie             string     `xml:"Serie,attr"`
+	Folio             string     `xml:"Folio,attr"`
+	Fecha             string     `xml:"Fecha,attr"`
+	FormaPago         string     `xml:"FormaPago,attr"`
+	SubTotal          string     `xml:"SubTotal,attr"`
+	Descuento         string     `xml:"Descuento,attr,omitempty"`
+	Moneda            string     `xml:"Moneda,attr"`
+	Total             string     `xml:"Total,attr"`
+	TipoDeComprobante string     `xml:"TipoDeComprobante,attr"`
+	Exportacion       string     `xml:"Exportacion,attr"`
+	MetodoPago        string     `xml:"MetodoPago,attr"`
+	LugarExpedicion   string     `xml:"LugarExpedicion,attr"`
+	Emisor            nodoEmisor `xml:"cfdi:Emisor"`
+	Receptor          Receptor   `xml:"cfdi:Receptor"`
+	Conceptos         []Concepto `xml:"cfdi:Conceptos>cfdi:Concepto"`
+	Impuestos         Impuestos  `xml:"cfdi:Impuestos"`
+}
+
+type nodoEmisor struct {
+	Rfc           string `xml:"Rfc,attr"`
+	Nombre        string `xml:"Nombre,attr"`
+	RegimenFiscal string `xml:"RegimenFiscal,attr"`
+}
+
+// Receptor es el cliente que recibe la factura.
+type Receptor struct {
+	Rfc                     string `xml:"Rfc,attr"`
+	Nombre                  string `xml:"Nombre,attr"`
+	DomicilioFiscalReceptor string `xml:"DomicilioFiscalReceptor,attr"`
+	RegimenFiscalReceptor   string `xml:"RegimenFiscalReceptor,attr"`
+	UsoCFDI                 string `xml:"UsoCFDI,attr"`
+}
+
+// Concepto es el renglón del gas vendido.
+type Concepto struct {
+	ClaveProdServ string     `xml:"ClaveProdServ,attr"`
+	Cantidad      string     `xml:"Cantidad,attr"`
+	ClaveUnidad   string     `xml:"ClaveUnidad,attr"`
+	Unidad        string     `xml:"Unidad,attr"`
+	Descripcion   string     `xml:"Descripcion,attr"`
+	ValorUnitario string     `xml:"ValorUnitario,attr"`
+	Importe       string     `xml:"Importe,attr"`
+	Descuento     string     `xml:"Descuento,attr,omitempty"`
+	ObjetoImp     string     `xml:"ObjetoImp,attr"`
+	Traslados     []Traslado `xml:"cfdi:Impuestos>cfdi:Traslados>cfdi:Traslado"`
+}
+
+// Impuestos es el resumen de impuestos del comprobante.
+type Impuestos struct {
+	TotalImpuestosTrasladados string     `xml:"TotalImpuestosTrasladados,attr"`
+	Traslados                 []Traslado `xml:"cfdi:Traslados>cfdi:Traslado"`
+}
+
+// Traslado es el IVA de un concepto o del comprobante.
+type Traslado struct {
+	Base       string `xml:"Base,attr"`
+	Impuesto   string `xml:"Impuesto,attr"`
+	TipoFactor string `xml:"TipoFactor,attr"`
+	TasaOCuota string `xml:"TasaOCuota,attr"`
+	Importe    string `xml:"Importe,attr"`
+}
+
+// NuevoComprobante arma el CFDI de ingreso del pedido. Los precios del pedido
+// incluyen IVA; el total del CFDI es exactamente lo que pagó el cliente.
+func NuevoComprobante(emisor Emisor, datos *store.DatosFiscales, pedido *store.Pedido, serie, folio string, fecha time.Time) *Comprobante {
+	total := redondear(pedido.TotalAPagar())
+	base := redondear(total / (1 + tasaIVA))
+	descuento := redondear(math.Min(pedido.Descuento, pedido.CantidadDinero) / (1 + tasaIVA))
+	subtotal := redondear(base + descuento)
+	iva := redondear(total - base)
+
+	cantidad, unidad, claveUnidad, descripcion := pedido.CantidadLitros, "Litro", claveUnidadLitro, "Gas LP estacionario"
+	if pedido.TipoServicio != "estacionario" && pedido.CantidadCilindros > 0 {
+		cantidad, unidad, claveUnidad = float64(pedido.CantidadCilindros), "Pieza", claveUnidadPieza
+		descripcion = "Gas LP en cilindro"
+	}
+	if cantidad <= 0 {
+		cantidad = 1
+	}
+
+	formaPago, metodoPago := formasPago[pedido.MetodoPago], "PUE"
+	if formaPago == "" {
+		formaPago, metodoPago = formaPagoPorDefinir, "PPD"
+	}
+	traslado := Traslado{
+		Base:       importe(base),
+		Impuesto:   "002", // IVA
+		TipoFactor: "Tasa",
+		TasaOCuota: fmt.Sprintf("%.6f", tasaIVA),
+		Importe:    importe(iva),
+	}
+	c := &Comprobante{
+		XmlnsCfdi:         "http://www.sat.gob.mx/cfd/4",
+		XmlnsXsi:          "http://www.w3.org/2001/XMLSchema-instance",
+		SchemaLocation:    "http://www.sat.gob.mx/cfd/4 http://www.sat.gob.mx/sitio_internet/cfd/4/cfdv40.xsd",
+		Version:           "4.0",
+		Serie:             serie,
+		Folio:             folio,
+		Fecha:             fecha.Format("2006-01-02T15:04:05"),
+		FormaPago:         formaPago,
+		SubTotal:          importe(subtotal),
+		Moneda:            "MXN",
+		Total:             importe(total),
+		TipoDeComprobante: "I",
+		Exportacion:       "01",
+		MetodoPago:        metodoPago,
+		LugarExpedicion:   emisor.CodigoPostal,
+		Emisor:            nodoEmisor{Rfc: emisor.RFC, Nombre: emisor.Nombre, RegimenFiscal: emisor.RegimenFiscal},
+		Receptor: Receptor{
+			Rfc:                     datos.RFC,
+			Nombre:                  datos.RazonSocial,
+			DomicilioFiscalReceptor: datos.CodigoPostal,
+			RegimenFiscalReceptor:   datos.RegimenFiscal,
+			UsoCFDI:                 datos.UsoCFDI,
+		},
+		Conceptos: []Concepto{{
+			ClaveProdServ: claveProdServGasLP,
+			Cantidad:      fmt.Sprintf("%g", cantidad),
+			ClaveUnidad:   claveUnidad,
+			Unidad:        unidad,
+			Descripcion:   descripcion,
+			ValorUnitario: fmt.Sprintf("%.6f", subtotal/cantidad),
+			Importe:       importe(subtotal),
+			ObjetoImp:     "02", // sí objeto de impuesto
+			Traslados:     []Traslado{traslado},
+		}},
+		Impuestos: Impuestos{TotalImpuestosTrasladados: importe(iva), Traslados: []Traslado{traslado}},
+	}
+	if descuento > 0 {
+		c.Descuento = importe(descuento)
+		c.Conceptos[0].Descuento = importe(descuento)
+	}
+	return c
+}
+
+// XML serializa el comprobante con la declaración de UTF-8.
+func (c *Comprobante) XML() ([]byte, error) {
+	data, err := xml.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error generando XML del CFDI: %w", err)
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+func importe(v float64) string {
+	return fmt.Sprintf("%.2f", v)
+}
+
+func redondear(v float64) float64 {
+	return math.Round(v*100) / 100
+}
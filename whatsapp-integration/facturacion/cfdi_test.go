@@ -0,0 +1,120 @@
+package facturacion
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strconv"
+	"testing"
+	"time"
+
+	"example.com/whatsapp-integration/store"
+)
+
+func TestNuevoComprobante(t *testing.T) {
+	emisor := Emisor{RFC: "EKU9003173C9", Nombre: "ESCUELA KEMPER URGATE", RegimenFiscal: "601", CodigoPostal: "26015"}
+	datos := &store.DatosFiscales{RFC: "XIQB891116QE4", RazonSocial: "ANA", RegimenFiscal: "612", CodigoPostal: "06000", UsoCFDI: "G03"}
+
+	casos := []struct {
+		nombre                          string
+		pedido                          store.Pedido
+		subtotal, descuento, iva, total string
+		cantidad, unidad                string
+		formaPago, metodoPago           string
+	}{
+		{"estacionario en efectivo", store.Pedido{TipoServicio: "estacionario", CantidadLitros: 100, CantidadDinero: 2320, MetodoPago: "efectivo"},
+			"2000.00", "", "320.00", "2320.00", "100", claveUnidadLitro, "01", "PUE"},
+		{"con descuento", store.Pedido{TipoServicio: "estacionario", CantidadLitros: 100, CantidadDinero: 2320, Descuento: 116, MetodoPago: "transferencia"},
+			"2000.00", "100.00", "304.00", "2204.00", "100", claveUnidadLitro, "03", "PUE"},
+		{"cilindros con tarjeta", store.Pedido{TipoServicio: "cilindro_canje", CantidadCilindros: 2, CantidadDinero: 928, MetodoPago: "tarjeta"},
+			"800.00", "", "128.00", "928.00", "2", claveUnidadPieza, "04", "PUE"},
+		{"forma de pago por definir", store.Pedido{TipoServicio: "estacionario", CantidadLitros: 50, CantidadDinero: 1160},
+			"1000.00", "", "160.00", "1160.00", "50", claveUnidadLitro, formaPagoPorDefinir, "PPD"},
+		{"descuento mayor al importe", store.Pedido{TipoServicio: "estacionario", CantidadLitros: 10, CantidadDinero: 232, Descuento: 500, MetodoPago: "efectivo"},
+			"200.00", "200.00", "0.00", "0.00", "10", claveUnidadLitro, "01", "PUE"},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			cfdi := NuevoComprobante(emisor, datos, &c.pedido, "A", "7", time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC))
+			if cfdi.SubTotal != c.subtotal || cfdi.Descuento != c.descuento || cfdi.Impuestos.TotalImpuestosTrasladados != c.iva || cfdi.Total != c.total {
+				t.Fatalf("subtotal = %s, descuento = %q, iva = %s, total = %s; se esperaban %s, %q, %s, %s",
+					cfdi.SubTotal, cfdi.Descuento, cfdi.Impuestos.TotalImpuestosTrasladados, cfdi.Total, c.subtotal, c.descuento, c.iva, c.total)
+			}
+			concepto := cfdi.Conceptos[0]
+			if concepto.Cantidad != c.cantidad || concepto.ClaveUnidad != c.unidad || concepto.Importe != c.subtotal || concepto.Descuento != c.descuento {
+				t.Fatalf("concepto = %+v", concepto)
+			}
+			if cfdi.FormaPago != c.formaPago || cfdi.MetodoPago != c.metodoPago {
+				t.Fatalf("forma de pago = %s, método = %s; se esperaban %s, %s", cfdi.FormaPago, cfdi.MetodoPago, c.formaPago, c.metodoPago)
+			}
+			if cfdi.Receptor.Rfc != datos.RFC || cfdi.Receptor.DomicilioFiscalReceptor != datos.CodigoPostal || cfdi.LugarExpedicion != emisor.CodigoPostal {
+				t.Fatalf("receptor = %+v, lugar de expedición = %s", cfdi.Receptor, cfdi.LugarExpedicion)
+			}
+		})
+	}
+}
+
+// TestTotalesCuadran revisa que SubTotal - Descuento + IVA = Total al centavo
+// con importes que no se dividen exacto entre 1.16.
+func TestTotalesCuadran(t *testing.T) {
+	centavos := func(s string) int64 {
+		if s == "" {
+			return 0
+		}
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return int64(v*100 + 0.5)
+	}
+	for _, dinero := range []float64{1, 99.99, 333.33, 1234.57, 4567.89} {
+		for _, descuento := range []float64{0, 0.01, 10.01, 50} {
+			pedido := &store.Pedido{TipoServicio: "estacionario", CantidadLitros: 37.5, CantidadDinero: dinero, Descuento: descuento, MetodoPago: "efectivo"}
+			cfdi := NuevoComprobante(Emisor{}, &store.DatosFiscales{}, pedido, "A", "1", time.Now())
+			suma := centavos(cfdi.SubTotal) - centavos(cfdi.Descuento) + centavos(cfdi.Impuestos.TotalImpuestosTrasladados)
+			if suma != centavos(cfdi.Total) {
+				t.Errorf("$%.2f - $%.2f: %s - %s + %s != %s", dinero, descuento, cfdi.SubTotal, cfdi.Descuento, cfdi.Impuestos.TotalImpuestosTrasladados, cfdi.Total)
+			}
+			if cfdi.Impuestos.Traslados[0].Base != importe(float64(centavos(cfdi.SubTotal)-centavos(cfdi.Descuento))/100) {
+				t.Errorf("$%.2f - $%.2f: base del IVA = %s", dinero, descuento, cfdi.Impuestos.Traslados[0].Base)
+			}
+		}
+	}
+}
+
+func TestComprobanteXML(t *testing.T) {
+	pedido := &store.Pedido{TipoServicio: "estacionario", CantidadLitros: 100, CantidadDinero: 2320, MetodoPago: "efectivo"}
+	datos := &store.DatosFiscales{RFC: "XIQB891116QE4", RazonSocial: "ANA & HIJOS", RegimenFiscal: "612", CodigoPostal: "06000", UsoCFDI: "G03"}
+	data, err := NuevoComprobante(Emisor{RFC: "EKU9003173C9", CodigoPostal: "26015"}, datos, pedido, "A", "7", time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)).XML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, []byte(xml.Header)) {
+		t.Fatalf("XML sin declaración: %s", data)
+	}
+	for _, esperado := range []string{
+		`<cfdi:Comprobante xmlns:cfdi="http://www.sat.gob.mx/cfd/4"`,
+		`Version="4.0"`, `Fecha="2026-03-02T12:00:00"`, `Total="2320.00"`,
+		`<cfdi:Receptor Rfc="XIQB891116QE4" Nombre="ANA &amp; HIJOS"`,
+		`<cfdi:Traslado Base="2000.00" Impuesto="002" TipoFactor="Tasa" TasaOCuota="0.160000" Importe="320.00">`,
+	} {
+		if !bytes.Contains(data, []byte(esperado)) {
+			t.Errorf("XML sin %s:\n%s", esperado, data)
+		}
+	}
+	if bytes.Contains(data, []byte("Descuento=")) {
+		t.Errorf("XML con Descuento sin haber descuento:\n%s", data)
+	}
+
+	var leido struct {
+		Total     string `xml:"Total,attr"`
+		Conceptos []struct {
+			Importe string `xml:"Importe,attr"`
+		} `xml:"Conceptos>Concepto"`
+	}
+	if err := xml.Unmarshal(data, &leido); err != nil {
+		t.Fatalf("el XML no se puede leer: %v", err)
+	}
+	if leido.Total != "2320.00" || len(leido.Conceptos) != 1 || leido.Conceptos[0].Importe != "2000.00" {
+		t.Fatalf("XML leído = %+v", leido)
+	}
+}
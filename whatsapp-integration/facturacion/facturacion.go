@@ -0,0 +1,343 @@
+// Package facturacion emite facturas electrónicas (CFDI 4.0) de los pedidos
+// entregados. Guarda los datos fiscales de cada cliente, arma el comprobante,
+// lo manda timbrar a un PAC y le regresa al cliente el XML y el PDF por
+// WhatsApp.
+package facturacion
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"example.com/whatsapp-integration/store"
+)
+
+// DirectorioPorDefecto es donde se guardan el XML y el PDF timbrados.
+const DirectorioPorDefecto = "facturas"
+
+// DiasParaFacturarPorDefecto es el plazo para pedir la factura de un pedido
+// entregado.
+const DiasParaFacturarPorDefecto = 30
+
+// Errores de Facturar.
+var (
+	ErrPedidoNoEntregado = errors.New("el pedido no ha sido entregado")
+	ErrDatosIncompletos  = errors.New("datos fiscales incompletos")
+)
+
+// Config son los datos del emisor y dónde se publican los archivos.
+type Config struct {
+	Emisor           Emisor
+	Serie            string
+	Directorio       string
+	URLBase          string // URL pública del servidor; sin ella solo se envía el UUID
+	DiasParaFacturar int
+}
+
+// ConfigDesdeEnv lee FACTURACION_RFC, FACTURACION_RAZON_SOCIAL,
+// FACTURACION_REGIMEN, FACTURACION_CP, FACTURACION_SERIE (por defecto "A"),
+// FACTURACION_DIAS, FACTURAS_DIR y FACTURAS_URL_BASE. ok es false si no hay
+// RFC del emisor.
+func ConfigDesdeEnv() (cfg Config, ok bool, err error) {
+	rfc := NormalizarRFC(os.Getenv("FACTURACION_RFC"))
+	if rfc == "" {
+		return cfg, false, nil
+	}
+	cfg = Config{
+		Emisor: Emisor{
+			RFC:           rfc,
+			Nombre:        strings.ToUpper(strings.TrimSpace(os.Getenv("FACTURACION_RAZON_SOCIAL"))),
+			RegimenFiscal: os.Getenv("FACTURACION_REGIMEN"),
+			CodigoPostal:  os.Getenv("FACTURACION_CP"),
+		},
+		Serie:            os.Getenv("FACTURACION_SERIE"),
+		Directorio:       os.Getenv("FACTURAS_DIR"),
+		URLBase:          strings.TrimRight(os.Getenv("FACTURAS_URL_BASE"), "/"),
+		DiasParaFacturar: DiasParaFacturarPorDefecto,
+	}
+	if cfg.Serie == "" {
+		cfg.Serie = "A"
+	}
+	if cfg.Directorio == "" {
+		cfg.Directorio = DirectorioPorDefecto
+	}
+	if dias, err := strconv.Atoi(os.Getenv("FACTURACION_DIAS")); err == nil && dias > 0 {
+		cfg.DiasParaFacturar = dias
+	}
+
+	if err := ValidarRFC(rfc); err != nil {
+		return cfg, true, fmt.Errorf("FACTURACION_RFC: %w", err)
+	}
+	if cfg.Emisor.Nombre == "" || cfg.Emisor.RegimenFiscal == "" {
+		return cfg, true, fmt.Errorf("faltan FACTURACION_RAZON_SOCIAL o FACTURACION_REGIMEN")
+	}
+	if err := ValidarCodigoPostal(cfg.Emisor.CodigoPostal); err != nil {
+		return cfg, true, fmt.Errorf("FACTURACION_CP: %w", err)
+	}
+	return cfg, true, nil
+}
+
+// Enviador manda mensajes y documentos por WhatsApp.
+type Enviador interface {
+	SendMessage(to string, text string) error
+	SendDocument(to string, documentURL string, filename string, caption string) error
+}
+
+// Servicio emite y entrega las facturas.
+type Servicio struct {
+	store  store.Store
+	pac    PAC
+	cfg    Config
+	loc    *time.Location
+	sender Enviador
+}
+
+func NewServicio(s store.Store, pac PAC, cfg Config, loc *time.Location) *Servicio {
+	if loc == nil {
+		loc = time.Local
+	}
+	if cfg.Directorio == "" {
+		cfg.Directorio = DirectorioPorDefecto
+	}
+	if cfg.DiasParaFacturar <= 0 {
+		cfg.DiasParaFacturar = DiasParaFacturarPorDefecto
+	}
+	return &Servicio{store: s, pac: pac, cfg: cfg, loc: loc}
+}
+
+// SetSender habilita el envío de las facturas por WhatsApp.
+func (s *Servicio) SetSender(sender Enviador) {
+	s.sender = sender
+}
+
+// ValidarDatosFiscales revisa los datos capturados contra los catálogos del
+// SAT antes de guardarlos.
+func ValidarDatosFiscales(d *store.DatosFiscales) error {
+	if err := ValidarRFC(d.RFC); err != nil {
+		return err
+	}
+	if strings.TrimSpace(d.RazonSocial) == "" {
+		return fmt.Errorf("%w: falta la razón social", ErrDatosIncompletos)
+	}
+	if err := ValidarCodigoPostal(d.CodigoPostal); err != nil {
+		return err
+	}
+	aplica := false
+	for _, r := range RegimenesPara(d.RFC) {
+		if r.Clave == d.RegimenFiscal {
+			aplica = true
+			break
+		}
+	}
+	if !aplica {
+		return fmt.Errorf("el régimen %s no aplica a este tipo de persona", d.RegimenFiscal)
+	}
+	usoValido := false
+	for _, u := range UsosCFDI {
+		if u.Clave == d.UsoCFDI {
+			usoValido = true
+			break
+		}
+	}
+	if !usoValido {
+		return fmt.Errorf("uso de CFDI %q no válido", d.UsoCFDI)
+	}
+	if d.RegimenFiscal == "616" && d.UsoCFDI != "S01" {
+		return fmt.Errorf("con régimen 616 el uso de CFDI debe ser S01")
+	}
+	return nil
+}
+
+// PedidosFacturables regresa los pedidos entregados del cliente dentro del
+// plazo para facturar que todavía no tienen factura.
+func (s *Servicio) PedidosFacturables(ctx context.Context, clienteID int, ahora time.Time) ([]*store.Pedido, error) {
+	pedidos, err := s.store.GetPedidos(ctx, store.FiltroPedidos{
+		Estados:     []string{"entregado"},
+		ClienteID:   clienteID,
+		CreadoDesde: ahora.AddDate(0, 0, -s.cfg.DiasParaFacturar),
+		Limite:      10,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var facturables []*store.Pedido
+	for _, p := range pedidos {
+		f, err := s.store.GetFacturaPorPedido(ctx, p.ID)
+		if err != nil {
+			return nil, err
+		}
+		if f == nil {
+			facturables = append(facturables, p)
+		}
+	}
+	return facturables, nil
+}
+
+// Facturar emite la factura del pedido entregado. Si el pedido ya tiene
+// factura la regresa sin volver a timbrar.
+func (s *Servicio) Facturar(ctx context.Context, pedido *store.Pedido, datos *store.DatosFiscales, ahora time.Time) (*store.Factura, error) {
+	if pedido.Estado != "entregado" {
+		return nil, ErrPedidoNoEntregado
+	}
+	existente, err := s.store.GetFacturaPorPedido(ctx, pedido.ID)
+	if err != nil {
+		return nil, err
+	}
+	if existente != nil {
+		return existente, nil
+	}
+	if err := ValidarDatosFiscales(datos); err != nil {
+		return nil, err
+	}
+
+	folio := strconv.Itoa(pedido.ID)
+	comprobante := NuevoComprobante(s.cfg.Emisor, datos, pedido, s.cfg.Serie, folio, ahora.In(s.loc))
+	cfdi, err := comprobante.XML()
+	if err != nil {
+		return nil, err
+	}
+	timbre, err := s.pac.Timbrar(ctx, cfdi)
+	if err != nil {
+		return nil, fmt.Errorf("error timbrando factura del pedido %d: %w", pedido.ID, err)
+	}
+
+	if err := os.MkdirAll(s.cfg.Directorio, 0o755); err != nil {
+		return nil, fmt.Errorf("error creando directorio de facturas: %w", err)
+	}
+	base := filepath.Join(s.cfg.Directorio, fmt.Sprintf("%s%s-%s", s.cfg.Serie, folio, timbre.UUID))
+	archivoXML, archivoPDF := base+".xml", base+".pdf"
+	if err := os.WriteFile(archivoXML, timbre.XML, 0o644); err != nil {
+		return nil, fmt.Errorf("error guardando XML de la factura: %w", err)
+	}
+	if err := os.WriteFile(archivoPDF, pdfTexto(representacionImpresa(comprobante, timbre)), 0o644); err != nil {
+		return nil, fmt.Errorf("error guardando PDF de la factura: %w", err)
+	}
+
+	token, err := nuevoToken()
+	if err != nil {
+		return nil, err
+	}
+	factura := &store.Factura{
+		PedidoID:   pedido.ID,
+		ClienteID:  pedido.ClienteID,
+		RFC:        datos.RFC,
+		Serie:      s.cfg.Serie,
+		Folio:      folio,
+		UUID:       timbre.UUID,
+		Total:      pedido.TotalAPagar(),
+		ArchivoXML: archivoXML,
+		ArchivoPDF: archivoPDF,
+		Token:      token,
+	}
+	if err := s.store.CrearFactura(ctx, factura); err != nil {
+		return nil, err
+	}
+	log.Printf("Factura %s%s (UUID %s) emitida para el pedido %d\n", factura.Serie, folio, timbre.UUID, pedido.ID)
+	return factura, nil
+}
+
+// Enviar manda el PDF y el XML de la factura al cliente. Sin URL pública
+// solo se le envía el folio fiscal.
+func (s *Servicio) Enviar(ctx context.Context, telefono string, f *store.Factura) error {
+	if s.sender == nil {
+		return nil
+	}
+	resumen := fmt.Sprintf("Factura %s%s del pedido #%d por $%.2f.\nFolio fiscal (UUID): %s", f.Serie, f.Folio, f.PedidoID, f.Total, f.UUID)
+	if s.cfg.URLBase == "" {
+		return s.sender.SendMessage(telefono, resumen)
+	}
+	nombre := fmt.Sprintf("%s%s", f.Serie, f.Folio)
+	url := s.cfg.URLBase + "/facturas/" + f.Token
+	if err := s.sender.SendDocument(telefono, url+".pdf", nombre+".pdf", resumen); err != nil {
+		return fmt.Errorf("error enviando PDF de la factura: %w", err)
+	}
+	if err := s.sender.SendDocument(telefono, url+".xml", nombre+".xml", ""); err != nil {
+		return fmt.Errorf("error enviando XML de la factura: %w", err)
+	}
+	return nil
+}
+
+// Handler sirve los archivos de las facturas en /facturas/{token}.xml y
+// /facturas/{token}.pdf; WhatsApp los descarga de ahí.
+func (s *Servicio) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nombre := strings.TrimPrefix(r.URL.Path, "/facturas/")
+		ext := filepath.Ext(nombre)
+		token := strings.TrimSuffix(nombre, ext)
+		if token == "" || (ext != ".xml" && ext != ".pdf") {
+			http.NotFound(w, r)
+			return
+		}
+		f, err := s.store.GetFacturaPorToken(r.Context(), token)
+		if err != nil {
+			http.Error(w, "error consultando factura", http.StatusInternalServerError)
+			return
+		}
+		if f == nil {
+			http.NotFound(w, r)
+			return
+		}
+		archivo, tipo := f.ArchivoXML, "application/xml"
+		if ext == ".pdf" {
+			archivo, tipo = f.ArchivoPDF, "application/pdf"
+		}
+		w.Header().Set("Content-Type", tipo)
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s%s%s"`, f.Serie, f.Folio, ext))
+		http.ServeFile(w, r, archivo)
+	})
+}
+
+// representacionImpresa son los renglones del PDF de la factura.
+func representacionImpresa(c *Comprobante, t *Timbre) []string {
+	lineas := []string{
+		c.Emisor.Nombre,
+		"RFC: " + c.Emisor.Rfc + "   Régimen: " + c.Emisor.RegimenFiscal + " " + DescripcionRegimen(c.Emisor.RegimenFiscal),
+		"Lugar de expedición: " + c.LugarExpedicion,
+		"",
+		"Factura " + c.Serie + c.Folio + "   Fecha: " + c.Fecha,
+		"Folio fiscal (UUID): " + t.UUID,
+		"Fecha de timbrado: " + t.FechaTimbrado.Format("2006-01-02T15:04:05"),
+		"",
+		"Receptor: " + c.Receptor.Nombre,
+		"RFC: " + c.Receptor.Rfc + "   C.P.: " + c.Receptor.DomicilioFiscalReceptor,
+		"Régimen: " + c.Receptor.RegimenFiscalReceptor + " " + DescripcionRegimen(c.Receptor.RegimenFiscalReceptor),
+		"Uso del CFDI: " + c.Receptor.UsoCFDI + " " + DescripcionUso(c.Receptor.UsoCFDI),
+		"",
+	}
+	for _, con := range c.Conceptos {
+		lineas = append(lineas,
+			fmt.Sprintf("%s %s  %s (%s)", con.Cantidad, con.Unidad, con.Descripcion, con.ClaveProdServ),
+			"    Valor unitario: $"+con.ValorUnitario+"   Importe: $"+con.Importe,
+		)
+	}
+	lineas = append(lineas, "", "Subtotal: $"+c.SubTotal)
+	if c.Descuento != "" {
+		lineas = append(lineas, "Descuento: $"+c.Descuento)
+	}
+	lineas = append(lineas,
+		"IVA 16%: $"+c.Impuestos.TotalImpuestosTrasladados,
+		"Total: $"+c.Total+" "+c.Moneda,
+		"",
+		"Forma de pago: "+c.FormaPago+"   Método de pago: "+c.MetodoPago,
+		"",
+		"Este documento es una representación impresa de un CFDI.",
+	)
+	return lineas
+}
+
+func nuevoToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("error generando token de factura: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,60 @@
+package facturacion
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// pdfTexto genera un PDF de una página con una línea por renglón en
+// Helvetica. Basta para la representación impresa del CFDI.
+func pdfTexto(lineas []string) []byte {
+	var contenido bytes.Buffer
+	contenido.WriteString("BT\n/F1 10 Tf\n14 TL\n50 780 Td\n")
+	for _, l := range lineas {
+		fmt.Fprintf(&contenido, "(%s) Tj T*\n", escaparPDF(l))
+	}
+	contenido.WriteString("ET\n")
+
+	objetos := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 4 0 R >> >> /Contents 5 0 R >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", contenido.Len(), contenido.String()),
+	}
+
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objetos))
+	for i, obj := range objetos {
+		offsets[i] = pdf.Len()
+		fmt.Fprintf(&pdf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := pdf.Len()
+	fmt.Fprintf(&pdf, "xref\n0 %d\n0000000000 65535 f \n", len(objetos)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&pdf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&pdf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objetos)+1, xref)
+	return pdf.Bytes()
+}
+
+// escaparPDF pasa el texto a Latin-1 (WinAnsi) y escapa los paréntesis.
+func escaparPDF(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x80:
+			b.WriteRune(r)
+		case r <= 0xff:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,99 @@
+package facturacion
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// RFCs genéricos del SAT; no llevan dígito verificador calculado.
+const (
+	RFCPublicoGeneral = "XAXX010101000"
+	RFCExtranjero     = "XEXX010101000"
+)
+
+// patronRFC: 3 letras (persona moral) o 4 (persona física), fecha AAMMDD y
+// homoclave de 3 caracteres, el último es el dígito verificador.
+var patronRFC = regexp.MustCompile(`^([A-ZÑ&]{3,4})(\d{6})([A-Z\d]{2})([A\d])$`)
+
+var patronCodigoPostal = regexp.MustCompile(`^\d{5}$`)
+
+// patronRegimenSocietario reconoce al final del nombre el régimen societario
+// (S.A. de C.V., S. de R.L., A.C., ...), que el CFDI 4.0 no lleva.
+var patronRegimenSocietario = regexp.MustCompile(`[,\s]+(S\.?\s?A\.?\s?P\.?\s?I\.?|S\.?\s?A\.?\s?B\.?|S\.?\s?A\.?|S\.?\s?DE\s?R\.?\s?L\.?|S\.?\s?C\.?|A\.?\s?C\.?)(\s+DE\s+C\.?\s?V\.?)?$`)
+
+// NormalizarRFC quita espacios y guiones y pasa a mayúsculas.
+func NormalizarRFC(rfc string) string {
+	return strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(rfc)))
+}
+
+// ValidarRFC revisa la estructura, la fecha y el dígito verificador del RFC
+// ya normalizado.
+func ValidarRFC(rfc string) error {
+	if rfc == RFCPublicoGeneral || rfc == RFCExtranjero {
+		return nil
+	}
+	m := patronRFC.FindStringSubmatch(rfc)
+	if m == nil {
+		return fmt.Errorf("el RFC debe tener 12 caracteres (persona moral) o 13 (persona física)")
+	}
+	if _, err := time.Parse("060102", m[2]); err != nil {
+		return fmt.Errorf("la fecha del RFC no es válida")
+	}
+	if digitoVerificador(rfc) != rfc[len(rfc)-1] {
+		return fmt.Errorf("el dígito verificador del RFC no coincide")
+	}
+	return nil
+}
+
+// EsPersonaMoral indica si el RFC es de persona moral (12 caracteres).
+func EsPersonaMoral(rfc string) bool {
+	return len([]rune(rfc)) == 12
+}
+
+// NormalizarRazonSocial pasa el nombre a mayúsculas, sin espacios de más ni
+// régimen societario, como aparece en la constancia de situación fiscal.
+func NormalizarRazonSocial(nombre string) string {
+	nombre = strings.ToUpper(strings.Join(strings.Fields(nombre), " "))
+	return strings.TrimSpace(patronRegimenSocietario.ReplaceAllString(nombre, ""))
+}
+
+// ValidarCodigoPostal revisa que el código postal tenga 5 dígitos.
+func ValidarCodigoPostal(cp string) error {
+	if !patronCodigoPostal.MatchString(cp) {
+		return fmt.Errorf("el código postal debe tener 5 dígitos")
+	}
+	return nil
+}
+
+// valoresRFC es el orden de caracteres del algoritmo del SAT.
+const valoresRFC = "0123456789ABCDEFGHIJKLMN&OPQRSTUVWXYZ Ñ"
+
+// digitoVerificador calcula el último carácter del RFC con el módulo 11 del SAT.
+func digitoVerificador(rfc string) byte {
+	base := []rune(rfc)
+	base = base[:len(base)-1]
+	if len(base) == 11 {
+		base = append([]rune{' '}, base...)
+	}
+	suma := 0
+	for i, c := range base {
+		valor := 0
+		for j, v := range []rune(valoresRFC) {
+			if v == c {
+				valor = j
+				break
+			}
+		}
+		suma += valor * (13 - i)
+	}
+	residuo := suma % 11
+	switch {
+	case residuo == 0:
+		return '0'
+	case 11-residuo == 10:
+		return 'A'
+	}
+	return byte('0' + 11 - residuo)
+}
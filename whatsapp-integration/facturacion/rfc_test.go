@@ -0,0 +1,88 @@
+package facturacion
+
+import (
+	"errors"
+	"testing"
+
+	"example.com/whatsapp-integration/store"
+)
+
+func TestValidarRFC(t *testing.T) {
+	casos := []struct {
+		nombre string
+		rfc    string
+		valido bool
+		moral  bool
+	}{
+		{"persona moral", "EKU9003173C9", true, true},
+		{"persona física", "XIQB891116QE4", true, false},
+		{"persona física con homoclave numérica", "CACX7605101P8", true, false},
+		{"público en general", RFCPublicoGeneral, true, false},
+		{"extranjero", RFCExtranjero, true, false},
+		{"dígito verificador equivocado", "EKU9003173C8", false, true},
+		{"homoclave alterada", "XIQB891116QF4", false, false},
+		{"fecha inexistente", "XIQB891316QE4", false, false},
+		{"muy corto", "EKU900317", false, false},
+		{"sin normalizar", "eku9003173c9", false, false},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			if err := ValidarRFC(c.rfc); (err == nil) != c.valido {
+				t.Fatalf("ValidarRFC(%q) = %v, se esperaba válido = %v", c.rfc, err, c.valido)
+			}
+			if c.valido && EsPersonaMoral(c.rfc) != c.moral {
+				t.Fatalf("EsPersonaMoral(%q) = %v, se esperaba %v", c.rfc, !c.moral, c.moral)
+			}
+		})
+	}
+}
+
+func TestNormalizar(t *testing.T) {
+	if got := NormalizarRFC(" eku-900317 3c9 "); got != "EKU9003173C9" {
+		t.Errorf("NormalizarRFC() = %q", got)
+	}
+	casos := []struct {
+		nombre, esperado string
+	}{
+		{"Gas del Valle, S.A. de C.V.", "GAS DEL VALLE"},
+		{"  comercial   norte s de rl de cv", "COMERCIAL NORTE"},
+		{"Amigos del Gas A.C.", "AMIGOS DEL GAS"},
+		{"Juan Pérez López", "JUAN PÉREZ LÓPEZ"},
+	}
+	for _, c := range casos {
+		if got := NormalizarRazonSocial(c.nombre); got != c.esperado {
+			t.Errorf("NormalizarRazonSocial(%q) = %q, se esperaba %q", c.nombre, got, c.esperado)
+		}
+	}
+}
+
+func TestValidarDatosFiscales(t *testing.T) {
+	casos := []struct {
+		nombre string
+		datos  store.DatosFiscales
+		valido bool
+	}{
+		{"persona física con actividad empresarial", store.DatosFiscales{RFC: "XIQB891116QE4", RazonSocial: "ANA", RegimenFiscal: "612", CodigoPostal: "06000", UsoCFDI: "G03"}, true},
+		{"persona moral general de ley", store.DatosFiscales{RFC: "EKU9003173C9", RazonSocial: "ESCUELA KEMPER URGATE", RegimenFiscal: "601", CodigoPostal: "26015", UsoCFDI: "G01"}, true},
+		{"RESICO aplica a ambas", store.DatosFiscales{RFC: "EKU9003173C9", RazonSocial: "ESCUELA KEMPER URGATE", RegimenFiscal: "626", CodigoPostal: "26015", UsoCFDI: "G03"}, true},
+		{"régimen de física para una moral", store.DatosFiscales{RFC: "EKU9003173C9", RazonSocial: "ESCUELA KEMPER URGATE", RegimenFiscal: "612", CodigoPostal: "26015", UsoCFDI: "G03"}, false},
+		{"régimen de moral para una física", store.DatosFiscales{RFC: "XIQB891116QE4", RazonSocial: "ANA", RegimenFiscal: "601", CodigoPostal: "06000", UsoCFDI: "G03"}, false},
+		{"sin obligaciones exige S01", store.DatosFiscales{RFC: "XIQB891116QE4", RazonSocial: "ANA", RegimenFiscal: "616", CodigoPostal: "06000", UsoCFDI: "G03"}, false},
+		{"sin obligaciones con S01", store.DatosFiscales{RFC: "XIQB891116QE4", RazonSocial: "ANA", RegimenFiscal: "616", CodigoPostal: "06000", UsoCFDI: "S01"}, true},
+		{"uso fuera del catálogo", store.DatosFiscales{RFC: "XIQB891116QE4", RazonSocial: "ANA", RegimenFiscal: "612", CodigoPostal: "06000", UsoCFDI: "D01"}, false},
+		{"código postal incompleto", store.DatosFiscales{RFC: "XIQB891116QE4", RazonSocial: "ANA", RegimenFiscal: "612", CodigoPostal: "6000", UsoCFDI: "G03"}, false},
+		{"RFC inválido", store.DatosFiscales{RFC: "XIQB891116QE5", RazonSocial: "ANA", RegimenFiscal: "612", CodigoPostal: "06000", UsoCFDI: "G03"}, false},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			if err := ValidarDatosFiscales(&c.datos); (err == nil) != c.valido {
+				t.Fatalf("ValidarDatosFiscales() = %v, se esperaba válido = %v", err, c.valido)
+			}
+		})
+	}
+
+	sinNombre := store.DatosFiscales{RFC: "XIQB891116QE4", RazonSocial: " ", RegimenFiscal: "612", CodigoPostal: "06000", UsoCFDI: "G03"}
+	if err := ValidarDatosFiscales(&sinNombre); !errors.Is(err, ErrDatosIncompletos) {
+		t.Fatalf("ValidarDatosFiscales() sin razón social = %v, se esperaba ErrDatosIncompletos", err)
+	}
+}
@@ -1,3 +1,8 @@
+//go:build ignore
+
+// Punto de entrada alternativo con logrus y godotenv. Se excluye del build
+// normal porque declara su propio main y sus dependencias no están en go.mod.
+
 package main
 
 import (
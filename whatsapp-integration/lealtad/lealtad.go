@@ -0,0 +1,110 @@
+// Package lealtad define los niveles de cliente (Normal, Premium, ...), las
+// reglas para subir o bajar de nivel y los beneficios de cada uno. El motor se
+// ejecuta cada noche y reevalúa la categoría de todos los clientes.
+package lealtad
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// NivelBase es la categoría de los clientes que no cumplen ninguna regla.
+const NivelBase = "Normal"
+
+// Reglas son las condiciones para pertenecer a un nivel. Los campos en cero no
+// se evalúan.
+type Reglas struct {
+	PedidosEntregados int     `json:"pedidos_entregados"`
+	Meses             int     `json:"meses"` // ventana de pedidos y gasto; 0 = todo el historial
+	GastoMinimo       float64 `json:"gasto_minimo"`
+	MaxStrikes        *int    `json:"max_strikes"` // strikes vigentes permitidos; nil = sin límite
+}
+
+// Beneficios son las ventajas de un nivel. Las consultan el bot y el corte diario.
+type Beneficios struct {
+	ElegirHorario       bool    `json:"elegir_horario"`
+	DescuentoPorcentaje float64 `json:"descuento_porcentaje"`
+	PrioridadRuta       int     `json:"prioridad_ruta"` // mayor = se atiende antes
+}
+
+// Nivel es una categoría de cliente con sus reglas y beneficios.
+type Nivel struct {
+	Nombre     string     `json:"nombre"`
+	Reglas     Reglas     `json:"reglas"`
+	Beneficios Beneficios `json:"beneficios"`
+}
+
+// Programa son los niveles ordenados del más bajo al más alto. El primero es
+// el nivel base y no tiene reglas.
+type Programa struct {
+	Niveles []Nivel `json:"niveles"`
+}
+
+// ProgramaPorDefecto conserva el comportamiento anterior: los Premium eligen
+// horario. Se llega con 5 pedidos entregados en 6 meses y sin strikes.
+func ProgramaPorDefecto() *Programa {
+	cero := 0
+	return &Programa{Niveles: []Nivel{
+		{Nombre: NivelBase},
+		{
+			Nombre:     "Premium",
+			Reglas:     Reglas{PedidosEntregados: 5, Meses: 6, MaxStrikes: &cero},
+			Beneficios: Beneficios{ElegirHorario: true, PrioridadRuta: 1},
+		},
+	}}
+}
+
+// CargarPrograma lee el programa de un JSON con la forma
+// {"niveles": [{"nombre", "reglas": {...}, "beneficios": {...}}]}.
+func CargarPrograma(ruta string) (*Programa, error) {
+	data, err := os.ReadFile(ruta)
+	if err != nil {
+		return nil, fmt.Errorf("error leyendo programa de lealtad %s: %w", ruta, err)
+	}
+	var p Programa
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("error decodificando programa de lealtad %s: %w", ruta, err)
+	}
+	if len(p.Niveles) == 0 {
+		return nil, fmt.Errorf("el programa de lealtad %s no define niveles", ruta)
+	}
+	vistos := make(map[string]bool)
+	for _, n := range p.Niveles {
+		if n.Nombre == "" || vistos[n.Nombre] {
+			return nil, fmt.Errorf("nivel sin nombre o repetido en %s: %q", ruta, n.Nombre)
+		}
+		vistos[n.Nombre] = true
+	}
+	return &p, nil
+}
+
+// Base regresa el nivel más bajo.
+func (p *Programa) Base() *Nivel {
+	return &p.Niveles[0]
+}
+
+// Nivel busca el nivel por nombre; una categoría desconocida cae en el base.
+func (p *Programa) Nivel(nombre string) *Nivel {
+	for i := range p.Niveles {
+		if p.Niveles[i].Nombre == nombre {
+			return &p.Niveles[i]
+		}
+	}
+	return p.Base()
+}
+
+// Beneficios regresa los beneficios de la categoría del cliente.
+func (p *Programa) Beneficios(categoria string) Beneficios {
+	return p.Nivel(categoria).Beneficios
+}
+
+// rango es la posición del nivel; sirve para saber si un cambio es ascenso.
+func (p *Programa) rango(nombre string) int {
+	for i := range p.Niveles {
+		if p.Niveles[i].Nombre == nombre {
+			return i
+		}
+	}
+	return 0
+}
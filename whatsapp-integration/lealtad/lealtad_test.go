@@ -0,0 +1,173 @@
+package lealtad
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/whatsapp-integration/store"
+)
+
+// storeLealtad solo implementa lo que usa el motor; el resto del Store queda
+// nil y haría panic si se llamara.
+type storeLealtad struct {
+	store.Store
+	clientes []*store.Cliente
+	pedidos  []*store.Pedido
+}
+
+func (s *storeLealtad) GetClientes(ctx context.Context) ([]*store.Cliente, error) {
+	return s.clientes, nil
+}
+
+func (s *storeLealtad) GetPedidos(ctx context.Context, filtro store.FiltroPedidos) ([]*store.Pedido, error) {
+	var pedidos []*store.Pedido
+	for _, p := range s.pedidos {
+		if p.ClienteID != filtro.ClienteID || p.Estado != "entregado" {
+			continue
+		}
+		if !filtro.CreadoDesde.IsZero() && p.CreatedAt.Before(filtro.CreadoDesde) {
+			continue
+		}
+		pedidos = append(pedidos, p)
+	}
+	return pedidos, nil
+}
+
+func (s *storeLealtad) ActualizarCliente(ctx context.Context, cliente *store.Cliente) error {
+	return nil
+}
+
+func (s *storeLealtad) RegistrarAuditoria(ctx context.Context, a *store.AuditoriaCliente) error {
+	return nil
+}
+
+type senderPrueba struct {
+	mensajes map[string]string
+}
+
+func (s *senderPrueba) SendMessage(to string, text string) error {
+	s.mensajes[to] = text
+	return nil
+}
+
+// programaPrueba tiene Plata por número de pedidos y Oro por gasto en los
+// últimos 3 meses sin strikes.
+func programaPrueba() *Programa {
+	cero := 0
+	return &Programa{Niveles: []Nivel{
+		{Nombre: NivelBase},
+		{Nombre: "Plata", Reglas: Reglas{PedidosEntregados: 2}, Beneficios: Beneficios{ElegirHorario: true}},
+		{Nombre: "Oro", Reglas: Reglas{Meses: 3, GastoMinimo: 3000, MaxStrikes: &cero}, Beneficios: Beneficios{DescuentoPorcentaje: 5, PrioridadRuta: 2}},
+	}}
+}
+
+func TestNivelPara(t *testing.T) {
+	ahora := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	reciente := ahora.AddDate(0, -1, 0)
+	antiguo := ahora.AddDate(0, -6, 0)
+	casos := []struct {
+		nombre  string
+		strikes int
+		pedidos []store.Pedido
+		nivel   string
+	}{
+		{"sin pedidos", 0, nil, NivelBase},
+		{"un pedido", 0, []store.Pedido{{CantidadDinero: 500, CreatedAt: reciente}}, NivelBase},
+		{"pedidos suficientes", 0, []store.Pedido{{CantidadDinero: 500, CreatedAt: antiguo}, {CantidadDinero: 500, CreatedAt: reciente}}, "Plata"},
+		{"gasto en la ventana", 0, []store.Pedido{{CantidadDinero: 1500, CreatedAt: reciente}, {CantidadDinero: 1500, CreatedAt: reciente}}, "Oro"},
+		{"el descuento no cuenta como gasto", 0, []store.Pedido{{CantidadDinero: 1500, CreatedAt: reciente}, {CantidadDinero: 1500, Descuento: 100, CreatedAt: reciente}}, "Plata"},
+		{"gasto fuera de la ventana", 0, []store.Pedido{{CantidadDinero: 1500, CreatedAt: antiguo}, {CantidadDinero: 1500, CreatedAt: reciente}}, "Plata"},
+		{"strikes bloquean Oro", 1, []store.Pedido{{CantidadDinero: 1500, CreatedAt: reciente}, {CantidadDinero: 1500, CreatedAt: reciente}}, "Plata"},
+		{"pedidos no entregados", 0, []store.Pedido{{CantidadDinero: 1500, Estado: "cancelado", CreatedAt: reciente}, {CantidadDinero: 1500, Estado: "pendiente", CreatedAt: reciente}}, NivelBase},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			cliente := &store.Cliente{ID: 1, Strikes: c.strikes}
+			st := &storeLealtad{}
+			for i := range c.pedidos {
+				p := c.pedidos[i]
+				p.ClienteID = cliente.ID
+				if p.Estado == "" {
+					p.Estado = "entregado"
+				}
+				st.pedidos = append(st.pedidos, &p)
+			}
+			nivel, err := NewMotor(st, programaPrueba(), nil).NivelPara(context.Background(), cliente, ahora)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if nivel.Nombre != c.nivel {
+				t.Fatalf("nivel = %s, se esperaba %s", nivel.Nombre, c.nivel)
+			}
+		})
+	}
+}
+
+// TestEvaluarAvisos anuncia ascensos y descensos, pero no el paso de una
+// categoría desconocida al nivel base ni a quien se queda igual.
+func TestEvaluarAvisos(t *testing.T) {
+	ahora := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	st := &storeLealtad{clientes: []*store.Cliente{
+		{ID: 1, NumeroTelefono: "asciende", Categoria: NivelBase},
+		{ID: 2, NumeroTelefono: "desciende", Categoria: "Oro"},
+		{ID: 3, NumeroTelefono: "desconocida", Categoria: "Premium"},
+		{ID: 4, NumeroTelefono: "igual", Categoria: NivelBase},
+	}}
+	for i := 0; i < 2; i++ {
+		st.pedidos = append(st.pedidos,
+			&store.Pedido{ClienteID: 1, Estado: "entregado", CantidadDinero: 2000, CreatedAt: ahora.AddDate(0, 0, -10)},
+			&store.Pedido{ClienteID: 2, Estado: "entregado", CantidadDinero: 100, CreatedAt: ahora.AddDate(0, 0, -10)},
+		)
+	}
+	sender := &senderPrueba{mensajes: map[string]string{}}
+	if err := NewMotor(st, programaPrueba(), sender).Evaluar(context.Background(), ahora); err != nil {
+		t.Fatal(err)
+	}
+
+	categorias := map[string]string{"asciende": "Oro", "desciende": "Plata", "desconocida": NivelBase, "igual": NivelBase}
+	for _, c := range st.clientes {
+		if c.Categoria != categorias[c.NumeroTelefono] {
+			t.Errorf("%s: categoría %s, se esperaba %s", c.NumeroTelefono, c.Categoria, categorias[c.NumeroTelefono])
+		}
+	}
+	if msg := sender.mensajes["asciende"]; !strings.Contains(msg, "ascendido a Cliente Oro") || !strings.Contains(msg, "5% de descuento") {
+		t.Errorf("aviso de ascenso inesperado: %q", msg)
+	}
+	if msg := sender.mensajes["desciende"]; !strings.Contains(msg, "cambió a Plata") {
+		t.Errorf("aviso de descenso inesperado: %q", msg)
+	}
+	for _, tel := range []string{"desconocida", "igual"} {
+		if msg, ok := sender.mensajes[tel]; ok {
+			t.Errorf("%s no debía recibir aviso, recibió %q", tel, msg)
+		}
+	}
+}
+
+func TestCargarPrograma(t *testing.T) {
+	casos := []struct {
+		nombre string
+		json   string
+		valido bool
+	}{
+		{"válido", `{"niveles": [{"nombre": "Normal"}, {"nombre": "Oro", "reglas": {"pedidos_entregados": 10}}]}`, true},
+		{"sin niveles", `{"niveles": []}`, false},
+		{"nivel repetido", `{"niveles": [{"nombre": "Normal"}, {"nombre": "Normal"}]}`, false},
+		{"nivel sin nombre", `{"niveles": [{"nombre": "Normal"}, {}]}`, false},
+		{"json inválido", `{"niveles": [`, false},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			ruta := filepath.Join(t.TempDir(), "lealtad.json")
+			if err := os.WriteFile(ruta, []byte(c.json), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := CargarPrograma(ruta); (err == nil) != c.valido {
+				t.Fatalf("CargarPrograma() = %v, válido esperado: %v", err, c.valido)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package lealtad
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"example.com/whatsapp-integration/store"
+)
+
+// Notificador envía el aviso de cambio de nivel por WhatsApp.
+type Notificador interface {
+	SendMessage(to string, text string) error
+}
+
+// Motor reevalúa la categoría de los clientes con las reglas del programa.
+type Motor struct {
+	store    store.Store
+	programa *Programa
+	sender   Notificador
+}
+
+func NewMotor(s store.Store, programa *Programa, sender Notificador) *Motor {
+	if programa == nil {
+		programa = ProgramaPorDefecto()
+	}
+	return &Motor{store: s, programa: programa, sender: sender}
+}
+
+// metricas resume el historial del cliente dentro de la ventana de una regla.
+type metricas struct {
+	entregados int
+	gasto      float64
+}
+
+// Evaluar recorre a todos los clientes y los sube o baja de nivel. Tiene la
+// firma de scheduler.Tarea para correr cada noche.
+func (m *Motor) Evaluar(ctx context.Context, ahora time.Time) error {
+	clientes, err := m.store.GetClientes(ctx)
+	if err != nil {
+		return err
+	}
+
+	cambios := 0
+	for _, cliente := range clientes {
+		nivel, err := m.NivelPara(ctx, cliente, ahora)
+		if err != nil {
+			log.Printf("Error evaluando lealtad del cliente %d: %v\n", cliente.ID, err)
+			continue
+		}
+		if nivel.Nombre == cliente.Categoria {
+			continue
+		}
+		if err := m.cambiarNivel(ctx, cliente, nivel); err != nil {
+			log.Printf("Error cambiando nivel del cliente %d: %v\n", cliente.ID, err)
+			continue
+		}
+		cambios++
+	}
+	log.Printf("Lealtad: %d clientes evaluados, %d cambios de nivel\n", len(clientes), cambios)
+	return nil
+}
+
+// NivelPara regresa el nivel más alto cuyas reglas cumple el cliente.
+func (m *Motor) NivelPara(ctx context.Context, cliente *store.Cliente, ahora time.Time) (*Nivel, error) {
+	porVentana := make(map[int]metricas)
+	for i := len(m.programa.Niveles) - 1; i > 0; i-- {
+		nivel := &m.programa.Niveles[i]
+		r := nivel.Reglas
+
+		if r.MaxStrikes != nil && cliente.Strikes > *r.MaxStrikes {
+			continue
+		}
+		met, ok := porVentana[r.Meses]
+		if !ok {
+			var err error
+			if met, err = m.metricas(ctx, cliente.ID, r.Meses, ahora); err != nil {
+				return nil, err
+			}
+			porVentana[r.Meses] = met
+		}
+		if met.entregados < r.PedidosEntregados || met.gasto < r.GastoMinimo {
+			continue
+		}
+		return nivel, nil
+	}
+	return m.programa.Base(), nil
+}
+
+func (m *Motor) metricas(ctx context.Context, clienteID, meses int, ahora time.Time) (metricas, error) {
+	filtro := store.FiltroPedidos{ClienteID: clienteID, Estados: []string{"entregado"}}
+	if meses > 0 {
+		filtro.CreadoDesde = ahora.AddDate(0, -meses, 0)
+	}
+	pedidos, err := m.store.GetPedidos(ctx, filtro)
+	if err != nil {
+		return metricas{}, err
+	}
+	met := metricas{entregados: len(pedidos)}
+	for _, p := range pedidos {
+		met.gasto += p.TotalAPagar()
+	}
+	return met, nil
+}
+
+func (m *Motor) cambiarNivel(ctx context.Context, cliente *store.Cliente, nivel *Nivel) error {
+	anterior := cliente.Categoria
+	asciende := m.programa.rango(nivel.Nombre) > m.programa.rango(anterior)
+
+	cliente.Categoria = nivel.Nombre
+	if err := m.store.ActualizarCliente(ctx, cliente); err != nil {
+		return fmt.Errorf("error al actualizar la categoría del cliente %d: %w", cliente.ID, err)
+	}
+	if err := m.store.RegistrarAuditoria(ctx, &store.AuditoriaCliente{
+		ClienteID: cliente.ID,
+		Accion:    "categoria",
+		Operador:  "lealtad",
+		Detalle:   fmt.Sprintf("%s -> %s", anterior, nivel.Nombre),
+	}); err != nil {
+		log.Printf("Error registrando auditoría de categoría del cliente %d: %v\n", cliente.ID, err)
+	}
+
+	// Pasar de una categoría desconocida al nivel base no se anuncia.
+	if m.sender == nil || (!asciende && m.programa.rango(anterior) == 0) {
+		return nil
+	}
+	if err := m.sender.SendMessage(cliente.NumeroTelefono, mensajeCambio(nivel, asciende)); err != nil {
+		log.Printf("Error avisando cambio de nivel a %s: %v\n", cliente.NumeroTelefono, err)
+	}
+	return nil
+}
+
+func mensajeCambio(nivel *Nivel, asciende bool) string {
+	if !asciende {
+		return fmt.Sprintf("Tu nivel de cliente cambió a %s. Sigue pidiendo con nosotros para recuperar tus beneficios.", nivel.Nombre)
+	}
+	msg := fmt.Sprintf("¡Felicidades! Gracias a tu lealtad, has sido ascendido a Cliente %s.", nivel.Nombre)
+	b := nivel.Beneficios
+	if b.ElegirHorario {
+		msg += "\n- Podrás elegir un horario de entrega preferido."
+	}
+	if b.DescuentoPorcentaje > 0 {
+		msg += fmt.Sprintf("\n- Tienes %.0f%% de descuento en tus pedidos.", b.DescuentoPorcentaje)
+	}
+	if b.PrioridadRuta > 0 {
+		msg += "\n- Tus entregas tienen prioridad en la ruta."
+	}
+	return msg
+}
@@ -0,0 +1,43 @@
+package liquidacion
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// EscribirCSV escribe el reporte del día con un renglón por ruta, para
+// imprimirlo o abrirlo en una hoja de cálculo.
+func EscribirCSV(w io.Writer, reportes []*ReporteRepartidor) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"repartidor", "ruta", "fecha", "camion", "esperado", "declarado", "recibido", "diferencia", "estado", "cajero", "alertas"})
+	for _, r := range reportes {
+		for _, c := range r.Rutas {
+			recibido, diferencia, cajero := "", "", ""
+			if l := c.Liquidacion; l != nil {
+				recibido, diferencia, cajero = importe(l.Recibido), importe(l.Diferencia), l.Cajero
+			}
+			cw.Write([]string{
+				r.Repartidor,
+				strconv.Itoa(c.RutaID),
+				c.Fecha,
+				c.Camion,
+				importe(c.Esperado),
+				importe(c.Declarado),
+				recibido,
+				diferencia,
+				c.Estado,
+				cajero,
+				strings.Join(c.Alertas, " "),
+			})
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func importe(v float64) string {
+	return fmt.Sprintf("%.2f", v)
+}
@@ -0,0 +1,401 @@
+// Package liquidacion hace el corte de caja de fin de día: calcula el
+// efectivo que cada repartidor debe entregar por su ruta, registra lo que
+// recibió el cajero y marca las diferencias.
+package liquidacion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"example.com/whatsapp-integration/repartidores"
+	"example.com/whatsapp-integration/rutas"
+	"example.com/whatsapp-integration/store"
+)
+
+// Estados de una liquidación.
+const (
+	EstadoPendiente = "pendiente" // el cajero aún no recibe el efectivo
+	EstadoCuadrada  = "cuadrada"
+	EstadoFaltante  = "faltante"
+	EstadoSobrante  = "sobrante"
+)
+
+// Motivos por los que un pedido de la ruta no suma al efectivo esperado.
+const (
+	ExcluidoSello       = "sello_violado" // el cliente no debe pagar (ver handleReporteSello)
+	ExcluidoNoEntregado = "no_entregado"
+	ExcluidoDisputa     = "en_disputa" // el cliente dice que no lo recibió
+	ExcluidoOtroMetodo  = "otro_metodo_pago"
+)
+
+// ToleranciaPorDefecto es la diferencia en pesos que se da por cuadrada.
+const ToleranciaPorDefecto = 1.0
+
+var (
+	ErrRutaNoEncontrada = errors.New("ruta no encontrada")
+	ErrYaLiquidada      = errors.New("la ruta ya fue liquidada")
+)
+
+// Config son los parámetros del corte.
+type Config struct {
+	Tolerancia float64
+}
+
+// ConfigDesdeEnv lee LIQUIDACION_TOLERANCIA (pesos, por defecto 1).
+func ConfigDesdeEnv() Config {
+	cfg := Config{Tolerancia: ToleranciaPorDefecto}
+	if v, err := strconv.ParseFloat(os.Getenv("LIQUIDACION_TOLERANCIA"), 64); err == nil && v >= 0 {
+		cfg.Tolerancia = v
+	}
+	return cfg
+}
+
+// PedidoCorte es una parada de la ruta con el efectivo que le corresponde.
+type PedidoCorte struct {
+	PedidoID   int     `json:"pedido_id"`
+	ParadaID   int     `json:"parada_id"`
+	Estado     string  `json:"estado"` // estado de la parada
+	MetodoPago string  `json:"metodo_pago"`
+	Total      float64 `json:"total"`
+	Esperado   float64 `json:"esperado"`  // efectivo que debe cobrarse
+	Declarado  float64 `json:"declarado"` // efectivo que capturó el repartidor
+	Excluido   string  `json:"excluido,omitempty"`
+}
+
+// CorteRuta es el corte de una ruta: lo esperado, lo declarado por el
+// repartidor y, si ya se liquidó, lo que recibió el cajero.
+type CorteRuta struct {
+	RutaID       int                `json:"ruta_id"`
+	Fecha        string             `json:"fecha"`
+	Camion       string             `json:"camion"`
+	RepartidorID int                `json:"repartidor_id"`
+	Repartidor   string             `json:"repartidor"`
+	Esperado     float64            `json:"esperado"`
+	Declarado    float64            `json:"declarado"`
+	Estado       string             `json:"estado"`
+	Liquidacion  *store.Liquidacion `json:"liquidacion,omitempty"`
+	Pedidos      []PedidoCorte      `json:"pedidos"`
+	Alertas      []string           `json:"alertas,omitempty"`
+}
+
+// ReporteRepartidor junta los cortes del día de un repartidor.
+type ReporteRepartidor struct {
+	RepartidorID int          `json:"repartidor_id"`
+	Repartidor   string       `json:"repartidor"`
+	Esperado     float64      `json:"esperado"`
+	Declarado    float64      `json:"declarado"`
+	Recibido     float64      `json:"recibido"`   // solo de las rutas liquidadas
+	Diferencia   float64      `json:"diferencia"` // solo de las rutas liquidadas
+	SinLiquidar  int          `json:"sin_liquidar"`
+	Rutas        []*CorteRuta `json:"rutas"`
+}
+
+// Servicio calcula y registra las liquidaciones.
+type Servicio struct {
+	store store.Store
+	cfg   Config
+	loc   *time.Location
+}
+
+func NewServicio(s store.Store, cfg Config, loc *time.Location) *Servicio {
+	if loc == nil {
+		loc = time.Local
+	}
+	return &Servicio{store: s, cfg: cfg, loc: loc}
+}
+
+// Hoy es la fecha de operación actual, para el reporte del día.
+func (s *Servicio) Hoy() time.Time {
+	ahora := time.Now().In(s.loc)
+	return time.Date(ahora.Year(), ahora.Month(), ahora.Day(), 0, 0, 0, 0, s.loc)
+}
+
+// Reporte regresa el corte de todas las rutas del día agrupado por
+// repartidor.
+func (s *Servicio) Reporte(ctx context.Context, fecha time.Time) ([]*ReporteRepartidor, error) {
+	lista, err := s.store.GetRutasPorFecha(ctx, fecha)
+	if err != nil {
+		return nil, err
+	}
+	porRepartidor := make(map[int]*ReporteRepartidor)
+	var reportes []*ReporteRepartidor
+	for _, ruta := range lista {
+		corte, err := s.CalcularRuta(ctx, ruta)
+		if err != nil {
+			return nil, err
+		}
+		r, ok := porRepartidor[corte.RepartidorID]
+		if !ok {
+			r = &ReporteRepartidor{RepartidorID: corte.RepartidorID, Repartidor: corte.Repartidor}
+			porRepartidor[corte.RepartidorID] = r
+			reportes = append(reportes, r)
+		}
+		r.Rutas = append(r.Rutas, corte)
+		r.Esperado = redondear(r.Esperado + corte.Esperado)
+		r.Declarado = redondear(r.Declarado + corte.Declarado)
+		if corte.Liquidacion == nil {
+			r.SinLiquidar++
+			continue
+		}
+		r.Recibido = redondear(r.Recibido + corte.Liquidacion.Recibido)
+		r.Diferencia = redondear(r.Diferencia + corte.Liquidacion.Diferencia)
+	}
+	sort.SliceStable(reportes, func(i, j int) bool { return reportes[i].Repartidor < reportes[j].Repartidor })
+	return reportes, nil
+}
+
+// CalcularRuta arma el corte de la ruta con el estado actual de sus paradas.
+func (s *Servicio) CalcularRuta(ctx context.Context, ruta *store.Ruta) (*CorteRuta, error) {
+	corte := &CorteRuta{
+		RutaID: ruta.ID,
+		Fecha:  ruta.Fecha.Format("2006-01-02"),
+		Estado: EstadoPendiente,
+	}
+	if err := s.identificar(ctx, ruta, corte); err != nil {
+		return nil, err
+	}
+
+	paradas, err := s.store.GetParadasRuta(ctx, ruta.ID)
+	if err != nil {
+		return nil, err
+	}
+	entregas, err := s.store.GetEntregasRuta(ctx, ruta.ID)
+	if err != nil {
+		return nil, err
+	}
+	declarado := make(map[int]float64) // por parada
+	for _, e := range entregas {
+		declarado[e.ParadaID] += e.EfectivoRecibido
+	}
+	sellos, err := s.pedidosConSello(ctx, ruta)
+	if err != nil {
+		return nil, err
+	}
+
+	sinCerrar := 0
+	for _, parada := range paradas {
+		pedido, err := s.store.GetPedido(ctx, parada.PedidoID)
+		if err != nil {
+			return nil, err
+		}
+		if pedido == nil {
+			continue
+		}
+		metodo, err := s.metodoPago(ctx, pedido)
+		if err != nil {
+			return nil, err
+		}
+		p := PedidoCorte{
+			PedidoID:   pedido.ID,
+			ParadaID:   parada.ID,
+			Estado:     parada.Estado,
+			MetodoPago: metodo,
+			Total:      rutas.TotalPedido(pedido),
+			Declarado:  declarado[parada.ID],
+		}
+		switch {
+		case sellos.incluye(pedido):
+			p.Excluido = ExcluidoSello
+		case pedido.Estado == "en_disputa":
+			p.Excluido = ExcluidoDisputa
+		case parada.Estado != repartidores.ParadaEntregada || pedido.Estado != "entregado":
+			p.Excluido = ExcluidoNoEntregado
+			if parada.Estado == repartidores.ParadaPendiente || parada.Estado == repartidores.ParadaLlegando {
+				sinCerrar++
+			}
+		case metodo != "efectivo":
+			p.Excluido = ExcluidoOtroMetodo
+		default:
+			p.Esperado = p.Total
+		}
+		corte.Esperado = redondear(corte.Esperado + p.Esperado)
+		corte.Declarado = redondear(corte.Declarado + p.Declarado)
+		if alerta := s.alertaPedido(p); alerta != "" {
+			corte.Alertas = append(corte.Alertas, alerta)
+		}
+		corte.Pedidos = append(corte.Pedidos, p)
+	}
+	if sinCerrar > 0 {
+		corte.Alertas = append(corte.Alertas, fmt.Sprintf("%d parada(s) sin cerrar.", sinCerrar))
+	}
+
+	liquidacion, err := s.store.GetLiquidacionRuta(ctx, ruta.ID)
+	if err != nil {
+		return nil, err
+	}
+	if liquidacion != nil {
+		corte.Liquidacion = liquidacion
+		corte.Estado = liquidacion.Estado
+	}
+	return corte, nil
+}
+
+// Liquidar registra el efectivo que recibió el cajero y lo compara con lo
+// esperado de la ruta.
+func (s *Servicio) Liquidar(ctx context.Context, rutaID int, recibido float64, cajero, nota string) (*CorteRuta, error) {
+	ruta, err := s.store.GetRuta(ctx, rutaID)
+	if err != nil {
+		return nil, err
+	}
+	if ruta == nil {
+		return nil, ErrRutaNoEncontrada
+	}
+	corte, err := s.CalcularRuta(ctx, ruta)
+	if err != nil {
+		return nil, err
+	}
+	if corte.Liquidacion != nil {
+		return nil, ErrYaLiquidada
+	}
+
+	diferencia := redondear(recibido - corte.Esperado)
+	liquidacion := &store.Liquidacion{
+		RutaID:       ruta.ID,
+		RepartidorID: corte.RepartidorID,
+		Fecha:        ruta.Fecha,
+		Esperado:     corte.Esperado,
+		Declarado:    corte.Declarado,
+		Recibido:     redondear(recibido),
+		Diferencia:   diferencia,
+		Estado:       s.estado(diferencia),
+		Cajero:       cajero,
+		Nota:         nota,
+	}
+	if err := s.store.CrearLiquidacion(ctx, liquidacion); err != nil {
+		return nil, err
+	}
+	if liquidacion.Estado != EstadoCuadrada {
+		log.Printf("Liquidación de la ruta %d (%s) con %s de $%.2f\n", ruta.ID, corte.Repartidor, liquidacion.Estado, math.Abs(diferencia))
+	}
+	corte.Liquidacion = liquidacion
+	corte.Estado = liquidacion.Estado
+	return corte, nil
+}
+
+func (s *Servicio) estado(diferencia float64) string {
+	switch {
+	case math.Abs(diferencia) <= s.cfg.Tolerancia:
+		return EstadoCuadrada
+	case diferencia < 0:
+		return EstadoFaltante
+	default:
+		return EstadoSobrante
+	}
+}
+
+// alertaPedido señala cobros que no cuadran con lo que debía pagar el
+// cliente.
+func (s *Servicio) alertaPedido(p PedidoCorte) string {
+	switch {
+	case p.Excluido == ExcluidoSello && p.Declarado > 0:
+		return fmt.Sprintf("Pedido #%d: se cobraron $%.2f aunque el cliente reportó sello violado.", p.PedidoID, p.Declarado)
+	case p.Excluido == ExcluidoDisputa && p.Declarado > 0:
+		return fmt.Sprintf("Pedido #%d: se cobraron $%.2f aunque el cliente dice que no lo recibió.", p.PedidoID, p.Declarado)
+	case p.Excluido == "" && math.Abs(p.Declarado-p.Esperado) > s.cfg.Tolerancia:
+		return fmt.Sprintf("Pedido #%d: el repartidor registró $%.2f de $%.2f.", p.PedidoID, p.Declarado, p.Esperado)
+	case p.Excluido == ExcluidoOtroMetodo && p.Declarado > 0:
+		return fmt.Sprintf("Pedido #%d: se registraron $%.2f en efectivo en un pedido pagado con %s.", p.PedidoID, p.Declarado, p.MetodoPago)
+	}
+	return ""
+}
+
+// identificar pone el camión y el repartidor de la ruta: el que capturó las
+// entregas o, si aún no hay, el asignado al camión.
+func (s *Servicio) identificar(ctx context.Context, ruta *store.Ruta, corte *CorteRuta) error {
+	camiones, err := s.store.GetCamiones(ctx)
+	if err != nil {
+		return err
+	}
+	for _, c := range camiones {
+		if c.ID == ruta.CamionID {
+			corte.Camion = c.Nombre
+		}
+	}
+
+	entregas, err := s.store.GetEntregasRuta(ctx, ruta.ID)
+	if err != nil {
+		return err
+	}
+	if len(entregas) > 0 {
+		repartidor, err := s.store.GetRepartidor(ctx, entregas[0].RepartidorID)
+		if err != nil {
+			return err
+		}
+		if repartidor != nil {
+			corte.RepartidorID, corte.Repartidor = repartidor.ID, repartidor.Nombre
+			return nil
+		}
+	}
+	repartidores, err := s.store.GetRepartidores(ctx)
+	if err != nil {
+		return err
+	}
+	for _, r := range repartidores {
+		if r.CamionID == ruta.CamionID {
+			corte.RepartidorID, corte.Repartidor = r.ID, r.Nombre
+			return nil
+		}
+	}
+	corte.Repartidor = "Sin repartidor"
+	return nil
+}
+
+// reportesSello son los pedidos y clientes con reporte de sello violado en
+// el día de la ruta.
+type reportesSello struct {
+	pedidos  map[int]bool
+	clientes map[int]bool // reportes que no traen pedido
+}
+
+func (r reportesSello) incluye(p *store.Pedido) bool {
+	return r.pedidos[p.ID] || r.clientes[p.ClienteID]
+}
+
+// pedidosConSello busca los reportes del día de la ruta. Los que no traen
+// pedido se aplican a los pedidos del cliente en la ruta.
+func (s *Servicio) pedidosConSello(ctx context.Context, ruta *store.Ruta) (reportesSello, error) {
+	sellos := reportesSello{pedidos: make(map[int]bool), clientes: make(map[int]bool)}
+	dia := time.Date(ruta.Fecha.Year(), ruta.Fecha.Month(), ruta.Fecha.Day(), 0, 0, 0, 0, s.loc)
+	reportes, err := s.store.GetReportesSello(ctx, dia, dia.AddDate(0, 0, 1))
+	if err != nil {
+		return sellos, err
+	}
+	for _, r := range reportes {
+		// Los reportes que no proceden y las quejas de otro tipo sí se cobran.
+		if r.Estado == "cancelado" || r.TipoReporte == "otro" {
+			continue
+		}
+		if r.PedidoID != nil {
+			sellos.pedidos[*r.PedidoID] = true
+		} else {
+			sellos.clientes[r.ClienteID] = true
+		}
+	}
+	return sellos, nil
+}
+
+// metodoPago usa el método del pago registrado y, si no hay, el del pedido.
+func (s *Servicio) metodoPago(ctx context.Context, pedido *store.Pedido) (string, error) {
+	pago, err := s.store.GetPagoPorPedido(ctx, pedido.ID)
+	if err != nil {
+		return "", err
+	}
+	if pago != nil && pago.Metodo != "" {
+		return pago.Metodo, nil
+	}
+	if pedido.MetodoPago == "" {
+		return "efectivo", nil
+	}
+	return pedido.MetodoPago, nil
+}
+
+func redondear(v float64) float64 {
+	return math.Round(v*100) / 100
+}
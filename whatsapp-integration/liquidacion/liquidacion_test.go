@@ -0,0 +1,206 @@
+package liquidacion
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/whatsapp-integration/repartidores"
+	"example.com/whatsapp-integration/store"
+)
+
+func TestEstado(t *testing.T) {
+	s := NewServicio(nil, Config{Tolerancia: 1}, time.UTC)
+	casos := []struct {
+		diferencia float64
+		estado     string
+	}{
+		{0, EstadoCuadrada},
+		{1, EstadoCuadrada},
+		{-1, EstadoCuadrada},
+		{-1.01, EstadoFaltante},
+		{1.01, EstadoSobrante},
+		{-250, EstadoFaltante},
+	}
+	for _, c := range casos {
+		if got := s.estado(c.diferencia); got != c.estado {
+			t.Errorf("estado(%.2f) = %q, se esperaba %q", c.diferencia, got, c.estado)
+		}
+	}
+}
+
+// storeCorte solo implementa lo que usa el corte de caja; el resto del Store
+// queda nil y haría panic si se llamara.
+type storeCorte struct {
+	store.Store
+	ruta        *store.Ruta
+	paradas     []*store.RutaParada
+	pedidos     map[int]*store.Pedido
+	pagos       map[int]*store.Pago
+	entregas    []*store.Entrega
+	sellos      []*store.ReporteSello
+	liquidacion *store.Liquidacion
+}
+
+func (s *storeCorte) GetRuta(ctx context.Context, id int) (*store.Ruta, error) {
+	if s.ruta.ID != id {
+		return nil, nil
+	}
+	return s.ruta, nil
+}
+
+func (s *storeCorte) GetCamiones(ctx context.Context) ([]*store.Camion, error) {
+	return []*store.Camion{{ID: 1, Nombre: "Pipa 1"}}, nil
+}
+
+func (s *storeCorte) GetEntregasRuta(ctx context.Context, rutaID int) ([]*store.Entrega, error) {
+	return s.entregas, nil
+}
+
+func (s *storeCorte) GetRepartidor(ctx context.Context, id int) (*store.Repartidor, error) {
+	return &store.Repartidor{ID: id, Nombre: "Luis", CamionID: 1}, nil
+}
+
+func (s *storeCorte) GetParadasRuta(ctx context.Context, rutaID int) ([]*store.RutaParada, error) {
+	return s.paradas, nil
+}
+
+func (s *storeCorte) GetPedido(ctx context.Context, id int) (*store.Pedido, error) {
+	return s.pedidos[id], nil
+}
+
+func (s *storeCorte) GetPagoPorPedido(ctx context.Context, pedidoID int) (*store.Pago, error) {
+	return s.pagos[pedidoID], nil
+}
+
+func (s *storeCorte) GetReportesSello(ctx context.Context, desde, hasta time.Time) ([]*store.ReporteSello, error) {
+	return s.sellos, nil
+}
+
+func (s *storeCorte) GetLiquidacionRuta(ctx context.Context, rutaID int) (*store.Liquidacion, error) {
+	return s.liquidacion, nil
+}
+
+func (s *storeCorte) CrearLiquidacion(ctx context.Context, liquidacion *store.Liquidacion) error {
+	s.liquidacion = liquidacion
+	return nil
+}
+
+// rutaDePrueba tiene un pedido de cada tipo, todos de $500:
+//  1. entregado en efectivo (cuenta)
+//  2. entregado y pagado con liga
+//  3. entregado con sello violado
+//  4. entregado pero el cliente dice que no lo recibió
+//  5. sin entregar
+//  6. entregado en efectivo (cuenta)
+func rutaDePrueba() *storeCorte {
+	pedido := func(id int, estado string) *store.Pedido {
+		return &store.Pedido{ID: id, ClienteID: id, Estado: estado, CantidadLitros: 50, PrecioUnitario: 10}
+	}
+	parada := func(id int, estado string) *store.RutaParada {
+		return &store.RutaParada{ID: id, PedidoID: id, Estado: estado}
+	}
+	entrega := func(id int, efectivo float64) *store.Entrega {
+		return &store.Entrega{ParadaID: id, PedidoID: id, RepartidorID: 4, EfectivoRecibido: efectivo}
+	}
+	tres := 3
+	return &storeCorte{
+		ruta: &store.Ruta{ID: 1, CamionID: 1, Fecha: time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)},
+		paradas: []*store.RutaParada{
+			parada(1, repartidores.ParadaEntregada),
+			parada(2, repartidores.ParadaEntregada),
+			parada(3, repartidores.ParadaEntregada),
+			parada(4, repartidores.ParadaEntregada),
+			parada(5, repartidores.ParadaPendiente),
+			parada(6, repartidores.ParadaEntregada),
+		},
+		pedidos: map[int]*store.Pedido{
+			1: pedido(1, "entregado"),
+			2: pedido(2, "entregado"),
+			3: pedido(3, "entregado"),
+			4: pedido(4, "en_disputa"),
+			5: pedido(5, "en_ruta"),
+			6: pedido(6, "entregado"),
+		},
+		pagos: map[int]*store.Pago{2: {PedidoID: 2, Metodo: "liga_pago", Estado: "pagado"}},
+		entregas: []*store.Entrega{
+			entrega(1, 500),
+			entrega(3, 500), // se cobró aunque el sello venía violado
+			entrega(6, 450),
+		},
+		sellos: []*store.ReporteSello{{ClienteID: 3, PedidoID: &tres, TipoReporte: "sello_violado", Estado: "pendiente"}},
+	}
+}
+
+func TestCalcularRuta(t *testing.T) {
+	st := rutaDePrueba()
+	corte, err := NewServicio(st, Config{Tolerancia: 1}, time.UTC).CalcularRuta(context.Background(), st.ruta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if corte.Esperado != 1000 || corte.Declarado != 1450 {
+		t.Fatalf("esperado = %.2f, declarado = %.2f; se esperaban 1000 y 1450", corte.Esperado, corte.Declarado)
+	}
+	if corte.Repartidor != "Luis" || corte.Camion != "Pipa 1" || corte.Estado != EstadoPendiente {
+		t.Fatalf("corte = %+v", corte)
+	}
+
+	excluidos := map[int]string{2: ExcluidoOtroMetodo, 3: ExcluidoSello, 4: ExcluidoDisputa, 5: ExcluidoNoEntregado}
+	for _, p := range corte.Pedidos {
+		if p.Excluido != excluidos[p.PedidoID] {
+			t.Errorf("pedido %d excluido = %q, se esperaba %q", p.PedidoID, p.Excluido, excluidos[p.PedidoID])
+		}
+	}
+
+	alertas := strings.Join(corte.Alertas, "\n")
+	for _, esperada := range []string{"Pedido #3", "Pedido #6", "1 parada(s) sin cerrar"} {
+		if !strings.Contains(alertas, esperada) {
+			t.Errorf("alertas = %q, falta %q", alertas, esperada)
+		}
+	}
+	if strings.Contains(alertas, "Pedido #1:") {
+		t.Errorf("alertas = %q, el pedido 1 cuadra", alertas)
+	}
+}
+
+func TestLiquidar(t *testing.T) {
+	casos := []struct {
+		nombre     string
+		recibido   float64
+		diferencia float64
+		estado     string
+	}{
+		{"cuadra exacto", 1000, 0, EstadoCuadrada},
+		{"dentro de la tolerancia", 999.5, -0.5, EstadoCuadrada},
+		{"faltan cincuenta", 950, -50, EstadoFaltante},
+		{"sobran cien", 1100, 100, EstadoSobrante},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			st := rutaDePrueba()
+			s := NewServicio(st, Config{Tolerancia: 1}, time.UTC)
+			ctx := context.Background()
+
+			corte, err := s.Liquidar(ctx, 1, c.recibido, "caja", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			l := st.liquidacion
+			if l == nil || l.Diferencia != c.diferencia || l.Estado != c.estado || corte.Estado != c.estado {
+				t.Fatalf("liquidación = %+v, se esperaba diferencia %.2f (%s)", l, c.diferencia, c.estado)
+			}
+			if l.Esperado != 1000 || l.Declarado != 1450 || l.RepartidorID != 4 {
+				t.Fatalf("liquidación = %+v", l)
+			}
+			if _, err := s.Liquidar(ctx, 1, c.recibido, "caja", ""); !errors.Is(err, ErrYaLiquidada) {
+				t.Fatalf("segunda Liquidar() = %v, se esperaba ErrYaLiquidada", err)
+			}
+		})
+	}
+
+	if _, err := NewServicio(rutaDePrueba(), Config{}, time.UTC).Liquidar(context.Background(), 9, 0, "caja", ""); !errors.Is(err, ErrRutaNoEncontrada) {
+		t.Fatalf("Liquidar() de otra ruta = %v, se esperaba ErrRutaNoEncontrada", err)
+	}
+}
@@ -3,21 +3,44 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"io/ioutil"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
 
 	"example.com/whatsapp-integration/adapter"
+	"example.com/whatsapp-integration/admin"
+	"example.com/whatsapp-integration/alertas"
 	"example.com/whatsapp-integration/bot"
+	"example.com/whatsapp-integration/calificaciones"
+	"example.com/whatsapp-integration/delivery"
+	"example.com/whatsapp-integration/espera"
+	"example.com/whatsapp-integration/facturacion"
+	"example.com/whatsapp-integration/lealtad"
+	"example.com/whatsapp-integration/liquidacion"
 	"example.com/whatsapp-integration/maps"
+	"example.com/whatsapp-integration/payments"
+	"example.com/whatsapp-integration/promociones"
+	"example.com/whatsapp-integration/puntos"
+	"example.com/whatsapp-integration/referidos"
+	"example.com/whatsapp-integration/repartidores"
+	"example.com/whatsapp-integration/rutas"
+	"example.com/whatsapp-integration/scheduler"
+	"example.com/whatsapp-integration/sellos"
+	"example.com/whatsapp-integration/slots"
 	"example.com/whatsapp-integration/store"
+	"example.com/whatsapp-integration/zonas"
 )
 
 // IncomingMessage define la estructura del mensaje entrante.
+// MediaURL y MimeType vienen en las fotos y documentos.
 type IncomingMessage struct {
-	From string `json:"from"`
-	Body string `json:"body"`
+	From     string `json:"from"`
+	Body     string `json:"body"`
+	MediaURL string `json:"media_url,omitempty"`
+	MimeType string `json:"mime_type,omitempty"`
 }
 
 // WebhookPayload es la estructura del payload del webhook.
@@ -65,15 +88,254 @@ func main() {
 	// Iniciar el bot (máquina de estados)
 	stateMachine := bot.NewStateMachine(dbStore, waClient, mapsClient)
 
-	// Configurar rutas del servidor web
-	http.HandleFunc("/webhook", webhookHandler(stateMachine))
-	http.HandleFunc("/health", healthCheckHandler)
+	// Programa de lealtad: niveles de cliente, reglas y beneficios.
+	programaLealtad := lealtad.ProgramaPorDefecto()
+	if archivo := os.Getenv("LEALTAD_ARCHIVO"); archivo != "" {
+		if p, err := lealtad.CargarPrograma(archivo); err != nil {
+			log.Printf("ADVERTENCIA: No se cargó el programa de lealtad (%v). Se usan los niveles por defecto.\n", err)
+		} else {
+			programaLealtad = p
+		}
+	}
+	stateMachine.SetLealtad(programaLealtad)
+
+	// Tareas programadas: recordatorios la tarde anterior y materialización de
+	// entregas programadas antes del corte de las 5:00 AM.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sched := scheduler.New(scheduler.ZonaHorariaDesdeEnv())
+	horaRecordatorio, minRecordatorio := scheduler.HoraDesdeEnv("PROGRAMADOS_HORA_RECORDATORIO", 19, 0)
+	sched.Diaria("recordatorios-programados", horaRecordatorio, minRecordatorio, stateMachine.EnviarRecordatoriosProgramados)
+	horaMaterializar, minMaterializar := scheduler.HoraDesdeEnv("PROGRAMADOS_HORA_MATERIALIZACION", 4, 30)
+	sched.Diaria("materializar-programados", horaMaterializar, minMaterializar, stateMachine.MaterializarPedidosProgramados)
+
+	// Corte diario: reparte los pedidos pendientes en rutas por camión y
+	// exporta las hojas de ruta para los repartidores.
+	planificador := rutas.NewPlanificador(dbStore, sched.Location(), os.Getenv("RUTAS_DIR"))
+	planificador.SetLealtad(programaLealtad)
+	if archivo := os.Getenv("CAMIONES_ARCHIVO"); archivo != "" {
+		if err := planificador.CargarCamionesDesdeArchivo(ctx, archivo); err != nil {
+			log.Printf("ADVERTENCIA: No se cargaron los camiones (%v).\n", err)
+		}
+	}
+	horaCorte, minCorte := scheduler.HoraDesdeEnv("HORA_CORTE_DIARIO", 5, 0)
+	sched.Diaria("corte-diario", horaCorte, minCorte, planificador.CorteDiario)
+
+	// Reevaluación nocturna de niveles de lealtad.
+	motorLealtad := lealtad.NewMotor(dbStore, programaLealtad, waClient)
+	horaLealtad, minLealtad := scheduler.HoraDesdeEnv("LEALTAD_HORA", 2, 0)
+	sched.Diaria("lealtad", horaLealtad, minLealtad, motorLealtad.Evaluar)
+
+	// Zonas de reparto definidas por el operador (GeoJSON).
+	var mapaZonas *zonas.Mapa
+	if archivo := os.Getenv("ZONAS_GEOJSON"); archivo != "" {
+		mapaZonas, err = zonas.CargarGeoJSON(archivo)
+		if err != nil {
+			log.Printf("ADVERTENCIA: No se cargaron las zonas de reparto (%v).\n", err)
+		} else {
+			stateMachine.SetZonas(mapaZonas)
+		}
+	}
+
+	// Ventanas de entrega con capacidad: se habilitan cuando hay ventanas configuradas.
+	slotsService := slots.NewService(dbStore, sched.Location())
+	if ruta := os.Getenv("VENTANAS_ENTREGA_ARCHIVO"); ruta != "" {
+		if err := slotsService.CargarVentanasDesdeArchivo(ctx, ruta); err != nil {
+			log.Printf("ADVERTENCIA: No se cargaron las ventanas de entrega (%v).\n", err)
+		}
+	}
+	if ventanas, err := dbStore.GetVentanasEntrega(ctx); err != nil {
+		log.Printf("ADVERTENCIA: No se pudieron consultar las ventanas de entrega (%v).\n", err)
+	} else if len(ventanas) > 0 {
+		stateMachine.SetSlots(slotsService)
+	}
+
+	// Servicio de entregas: actualiza pedidos, optimiza rutas y avisa a los clientes.
+	mapsService := delivery.NewMapsService(os.Getenv("GOOGLE_MAPS_API_KEY"), dbStore)
+	mapsService.SetSender(waClient)
+	stateMachine.SetEstadoPedidos(mapsService)
+	if os.Getenv("RUTAS_DISTANCE_MATRIX") == "true" && os.Getenv("GOOGLE_MAPS_API_KEY") != "" {
+		mapsService.SetCostSource(delivery.NewDistanceMatrixCost(os.Getenv("GOOGLE_MAPS_API_KEY")))
+	}
+	if planta, ok := plantaDesdeEnv(); ok {
+		mapsService.SetDepot(planta)
+	}
+	planificador.SetReparto(mapsService, mapaZonas)
+	sched.Start(ctx)
+
+	// Puntos de lealtad: se acumulan al entregar y se canjean en el bot.
+	monederoPuntos := puntos.NewServicio(dbStore, puntos.ConfigDesdeEnv())
+	monederoPuntos.SetSender(waClient)
+	mapsService.AddStatusObserver(monederoPuntos)
+	stateMachine.SetPuntos(monederoPuntos)
+
+	// Códigos de promoción que el cliente escribe en el resumen del pedido.
+	promos := promociones.NewServicio(dbStore, sched.Location())
+	if archivo := os.Getenv("PROMOCIONES_ARCHIVO"); archivo != "" {
+		if err := promos.CargarPromocionesDesdeArchivo(ctx, archivo); err != nil {
+			log.Printf("ADVERTENCIA: No se cargaron las promociones (%v).\n", err)
+		}
+	}
+	stateMachine.SetPromociones(promos)
+
+	// Programa de referidos: ambos ganan puntos con la primera entrega del invitado.
+	programaReferidos := referidos.NewServicio(dbStore, monederoPuntos, referidos.ConfigDesdeEnv())
+	programaReferidos.SetSender(waClient)
+	mapsService.AddStatusObserver(programaReferidos)
+	stateMachine.SetReferidos(programaReferidos)
 
-	// Iniciar servidor
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
 	}
+
+	// Métodos de pago por zona y estado de pago de cada pedido. Con
+	// PAGOS_LIGA_SIMULADA=true las ligas de pago en línea usan una pasarela
+	// simulada que confirma contra nuestro propio webhook. Escucha en
+	// PAGOS_SIMULADA_ADDR y sus ligas usan PAGOS_SIMULADA_URL, la dirección
+	// pública con la que el cliente la abre desde su teléfono.
+	secretoPagos := os.Getenv("PAGOS_WEBHOOK_SECRETO")
+	var pasarela payments.CheckoutGateway
+	if os.Getenv("PAGOS_LIGA_SIMULADA") == "true" {
+		if secretoPagos == "" {
+			secretoPagos = payments.RandomSecret()
+			log.Println("ADVERTENCIA: PAGOS_WEBHOOK_SECRETO no configurado. Usando un secreto temporal para la pasarela simulada.")
+		}
+		urlSimulada := os.Getenv("PAGOS_SIMULADA_URL")
+		if urlSimulada == "" {
+			log.Println("ADVERTENCIA: PAGOS_SIMULADA_URL no configurada. Las ligas de la pasarela simulada solo abren en este servidor.")
+		}
+		fake, stop, err := payments.StartFakeGateway(os.Getenv("PAGOS_SIMULADA_ADDR"), urlSimulada,
+			"http://127.0.0.1:"+port+"/pagos/webhook", secretoPagos)
+		if err != nil {
+			log.Fatalf("Error iniciando pasarela de pagos simulada: %v", err)
+		}
+		defer stop()
+		pasarela = fake
+	}
+	pagos, err := payments.NewServiceFromEnv(dbStore, pasarela)
+	if err != nil {
+		log.Printf("ADVERTENCIA: Configuración de pagos incompleta (%v).\n", err)
+	}
+	pagos.SetSender(waClient)
+	pagos.SetCanceller(stateMachine)
+	mapsService.AddStatusObserver(pagos)
+	stateMachine.SetPagos(pagos)
+	pagos.StartExpiryCheck(ctx, time.Minute)
+
+	// Facturas CFDI 4.0 de pedidos entregados: se habilita con FACTURACION_RFC.
+	// Por ahora solo hay PAC simulado, sin validez fiscal.
+	var facturas *facturacion.Servicio
+	if cfgFacturas, ok, err := facturacion.ConfigDesdeEnv(); err != nil {
+		log.Printf("ADVERTENCIA: Configuración de facturación inválida (%v). La facturación está deshabilitada.\n", err)
+	} else if ok {
+		log.Println("ADVERTENCIA: Facturación con PAC simulado. Las facturas no tienen validez fiscal.")
+		facturas = facturacion.NewServicio(dbStore, facturacion.FakePAC{}, cfgFacturas, sched.Location())
+		facturas.SetSender(waClient)
+		stateMachine.SetFacturacion(facturas)
+		if cfgFacturas.URLBase == "" {
+			log.Println("ADVERTENCIA: FACTURAS_URL_BASE no configurado. Solo se enviará el folio fiscal de las facturas.")
+		}
+	}
+
+	// Casos de sello violado: supervisor, SLA y aviso al cliente al resolverse.
+	casosSello := sellos.NewServicio(dbStore, sellos.ConfigDesdeEnv(), sched.Location())
+	casosSello.SetSender(waClient)
+	casosSello.SetEstadoPedidos(mapsService)
+	stateMachine.SetSellos(casosSello)
+
+	// Calificaciones de las entregas; las bajas se escalan a un supervisor.
+	servicioCalificaciones := calificaciones.NewServicio(dbStore, calificaciones.ConfigDesdeEnv(), sched.Location())
+	servicioCalificaciones.SetSender(waClient)
+	stateMachine.SetCalificaciones(servicioCalificaciones)
+
+	// Alertas al repartidor y al punto de venta: WhatsApp, webhook firmado y
+	// tablero de despacho; escalan a los supervisores si nadie las reconoce.
+	cfgAlertas := alertas.ConfigDesdeEnv()
+	if cfgAlertas.WebhookURL != "" && cfgAlertas.WebhookSecreto == "" {
+		log.Println("ADVERTENCIA: ALERTAS_WEBHOOK_SECRETO no configurado. El punto de venta no podrá verificar la firma de las alertas.")
+	}
+	servicioAlertas := alertas.NewServicio(dbStore, cfgAlertas, sched.Location())
+	servicioAlertas.SetSender(waClient)
+	servicioAlertas.IniciarEscalamiento(ctx, time.Minute)
+	stateMachine.SetAlertas(servicioAlertas)
+
+	// Aplicación web de repartidores.
+	if archivo := os.Getenv("REPARTIDORES_ARCHIVO"); archivo != "" {
+		if err := repartidores.CargarRepartidoresDesdeArchivo(ctx, dbStore, archivo); err != nil {
+			log.Printf("ADVERTENCIA: No se cargaron los repartidores (%v).\n", err)
+		}
+	}
+	appRepartidores := repartidores.NewApp(dbStore, mapsService, sched.Location(), os.Getenv("EVIDENCIAS_DIR"))
+
+	// Al registrar la entrega se le pregunta al cliente si la recibió; si no
+	// responde en CONFIRMACION_ENTREGA_MINUTOS se da por confirmada.
+	appRepartidores.SetConfirmaciones(stateMachine)
+	stateMachine.IniciarAutoconfirmacion(ctx, time.Minute)
+
+	// Espera en el domicilio: cuenta regresiva, cierre "no atendido" y strikes.
+	esperaCfg := espera.ConfigDesdeEnv()
+	mapsService.SetWaitTime(esperaCfg.Espera)
+	mapsService.SetCancelNoShow(esperaCfg.Politica == espera.PoliticaCancelar)
+	stateMachine.SetEspera(esperaCfg.Espera)
+	esperas := espera.NewServicio(dbStore, mapsService, esperaCfg)
+	esperas.SetStrikes(stateMachine)
+	appRepartidores.SetEsperas(esperas)
+	esperas.Start(ctx)
+
+	// Seguimiento de entregas con el POS: se habilita con DELIVERY_TRACKING=true.
+	// Sin POS_ENDPOINT se usa un POS simulado local.
+	if os.Getenv("DELIVERY_TRACKING") == "true" {
+		posEndpoint, posAPIKey := os.Getenv("POS_ENDPOINT"), os.Getenv("POS_API_KEY")
+		if posEndpoint == "" {
+			endpoint, stop, err := delivery.StartStubPOS(posAPIKey, delivery.DefaultPOSConfig)
+			if err != nil {
+				log.Fatalf("Error iniciando POS simulado: %v", err)
+			}
+			defer stop()
+			posEndpoint = endpoint
+			log.Printf("ADVERTENCIA: POS_ENDPOINT no configurado. Usando POS simulado en %s.\n", posEndpoint)
+		}
+
+		posService := delivery.NewPOSService(posEndpoint, posAPIKey, dbStore, mapsService)
+		posService.SetLocation(sched.Location())
+		appRepartidores.SetTracker(posService)
+		esperas.SetTracker(posService)
+		posService.StartDeliveryTracking(ctx)
+	}
+
+	// Configurar rutas del servidor web
+	http.HandleFunc("/webhook", webhookHandler(stateMachine))
+	http.HandleFunc("/health", healthCheckHandler)
+	http.Handle("/repartidor/", appRepartidores.Handler())
+	if token := os.Getenv("OPERADOR_TOKEN"); token != "" {
+		operador := esperas.Handler(token)
+		http.Handle("/operador/esperas", operador)
+		http.Handle("/operador/esperas/", operador)
+		http.Handle("/despacho/", servicioAlertas.Handler(token))
+	} else {
+		log.Println("ADVERTENCIA: OPERADOR_TOKEN no configurado. Las intervenciones de espera y el tablero de despacho están deshabilitados.")
+	}
+	if secretoPagos != "" {
+		http.Handle("/pagos/webhook", pagos.WebhookHandler(secretoPagos))
+	} else {
+		log.Println("ADVERTENCIA: PAGOS_WEBHOOK_SECRETO no configurado. Las confirmaciones de pago en línea están deshabilitadas.")
+	}
+	if facturas != nil {
+		http.Handle("/facturas/", facturas.Handler())
+	}
+	if token := os.Getenv("ADMIN_TOKEN"); token != "" {
+		adminAPI := admin.NewAPI(dbStore, stateMachine)
+		adminAPI.SetPagos(pagos)
+		adminAPI.SetLiquidaciones(liquidacion.NewServicio(dbStore, liquidacion.ConfigDesdeEnv(), sched.Location()))
+		adminAPI.SetSellos(casosSello)
+		adminAPI.SetCalificaciones(servicioCalificaciones)
+		http.Handle("/admin/", adminAPI.Handler(token))
+	} else {
+		log.Println("ADVERTENCIA: ADMIN_TOKEN no configurado. La API de apelaciones y desbloqueos está deshabilitada.")
+	}
+
+	// Iniciar servidor
 	srv := &http.Server{
 		Addr:         ":" + port,
 		ReadTimeout:  10 * time.Second,
@@ -85,6 +347,16 @@ func main() {
 	}
 }
 
+// plantaDesdeEnv lee la ubicación de la planta de PLANTA_LAT y PLANTA_LNG.
+func plantaDesdeEnv() (delivery.Location, bool) {
+	lat, errLat := strconv.ParseFloat(os.Getenv("PLANTA_LAT"), 64)
+	lng, errLng := strconv.ParseFloat(os.Getenv("PLANTA_LNG"), 64)
+	if errLat != nil || errLng != nil {
+		return delivery.Location{}, false
+	}
+	return delivery.Location{Lat: lat, Lng: lng, Address: "Planta"}, true
+}
+
 func webhookHandler(bot *bot.StateMachine) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodGet {
@@ -140,12 +412,28 @@ func handleWebhookPost(w http.ResponseWriter, r *http.Request, bot *bot.StateMac
 		return
 	}
 
-	if len(payload.Messages) == 0 || payload.Messages[0].Body == "" {
+	if len(payload.Messages) == 0 {
 		w.WriteHeader(http.StatusOK)
 		return
 	}
 
 	msg := payload.Messages[0]
+	if msg.MediaURL != "" {
+		log.Printf("Recibido archivo de %s (%s)\n", msg.From, msg.MimeType)
+		go func(from, mediaURL, mimeType string) {
+			ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+			defer cancel()
+			if err := bot.ProcessAttachment(ctx, from, mediaURL, mimeType); err != nil {
+				log.Printf("Error procesando archivo de %s: %v\n", from, err)
+			}
+		}(msg.From, msg.MediaURL, msg.MimeType)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if msg.Body == "" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
 	log.Printf("Recibido de %s: %s\n", msg.From, msg.Body)
 
 	// Procesar el mensaje de forma asíncrona
@@ -33,25 +33,41 @@ CREATE TABLE IF NOT EXISTS precios (
 CREATE TABLE IF NOT EXISTS pedidos (
     id INTEGER PRIMARY KEY AUTO_INCREMENT,
     cliente_id INTEGER NOT NULL,
-    tipo_servicio ENUM('estacionario', 'cilindro') NOT NULL,
+    tipo_servicio ENUM('estacionario', 'cilindro', 'cilindro_recarga', 'cilindro_canje') NOT NULL,
     subtipo_servicio ENUM('recarga', 'canje') NULL,
+    horario_preferido VARCHAR(20),
     cantidad_litros DECIMAL(10,2),
     cantidad_dinero DECIMAL(10,2),
+    cantidad_cilindros INTEGER,
+    descuento DECIMAL(10,2) DEFAULT 0,
     precio_unitario DECIMAL(10,2) NOT NULL,
-    metodo_pago ENUM('efectivo', 'tarjeta'),
+    metodo_pago ENUM('efectivo', 'tarjeta', 'transferencia', 'liga_pago'),
     direccion TEXT,
     lat_long VARCHAR(50),
+    latitud DECIMAL(10,7),
+    longitud DECIMAL(10,7),
     maps_url TEXT,
+    mapa_url TEXT,
     street_view_url TEXT,
+    streetview_url TEXT,
+    requiere_revision_manual BOOLEAN DEFAULT FALSE,
     color_puerta VARCHAR(50),
     color_fachada VARCHAR(50),
     estado ENUM(
         'pendiente',
+        'pendiente_recoleccion',
+        'asignado',
         'recoleccion_programada',
         'tanque_recogido',
         'en_planta',
+        'en_recarga',
+        'en_ruta',
         'en_ruta_entrega',
+        'llegando',
+        'esperando',
         'entregado',
+        'en_disputa',
+        'no_entregado',
         'cancelado'
     ) NOT NULL DEFAULT 'pendiente',
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
@@ -86,21 +102,452 @@ CREATE TABLE IF NOT EXISTS tanques (
 CREATE TABLE IF NOT EXISTS reportes_sello (
     id INTEGER PRIMARY KEY AUTO_INCREMENT,
     cliente_id INTEGER NOT NULL,
-    pedido_id INTEGER NOT NULL,
-    tanque_id INTEGER NOT NULL,
-    tipo_reporte ENUM('sello_violado', 'tanque_danado', 'otro') NOT NULL,
+    pedido_id INTEGER NULL,
+    tanque_id INTEGER NULL,
+    tipo_reporte ENUM('sello_violado', 'tanque_danado', 'otro') NOT NULL DEFAULT 'sello_violado',
     descripcion TEXT,
     foto_url TEXT,
     estado ENUM('pendiente', 'en_revision', 'resuelto', 'cancelado') NOT NULL DEFAULT 'pendiente',
     resolucion TEXT,
+    supervisor VARCHAR(100) NULL,
+    fecha_reporte TIMESTAMP NULL,
+    vence_sla DATETIME NULL,
+    asignado_en DATETIME NULL,
+    resuelto_en DATETIME NULL,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
     FOREIGN KEY (cliente_id) REFERENCES clientes(id),
     FOREIGN KEY (pedido_id) REFERENCES pedidos(id),
     FOREIGN KEY (tanque_id) REFERENCES tanques(id),
+    INDEX idx_estado (estado),
+    INDEX idx_supervisor (supervisor)
+);
+
+-- Fotos que manda el cliente por WhatsApp para su reporte de sello
+CREATE TABLE IF NOT EXISTS fotos_reporte_sello (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    reporte_id INTEGER NOT NULL,
+    archivo VARCHAR(500) NOT NULL,
+    mime_type VARCHAR(100) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (reporte_id) REFERENCES reportes_sello(id),
+    INDEX idx_reporte (reporte_id)
+);
+
+-- Tabla de entregas programadas y recurrentes
+CREATE TABLE IF NOT EXISTS pedidos_programados (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    cliente_id INTEGER NOT NULL,
+    tipo_servicio ENUM('estacionario', 'cilindro_recarga', 'cilindro_canje') NOT NULL,
+    cantidad_litros DECIMAL(10,2) DEFAULT 0,
+    cantidad_cilindros INTEGER DEFAULT 0,
+    metodo_pago VARCHAR(20),
+    direccion TEXT,
+    latitud DECIMAL(10,7) DEFAULT 0,
+    longitud DECIMAL(10,7) DEFAULT 0,
+    mapa_url TEXT,
+    streetview_url TEXT,
+    color_fachada VARCHAR(50) DEFAULT '',
+    color_puerta VARCHAR(50) DEFAULT '',
+    horario_preferido VARCHAR(20) DEFAULT '',
+    frecuencia ENUM('unica', 'semanal', 'mensual') NOT NULL DEFAULT 'unica',
+    intervalo_semanas INTEGER DEFAULT 0,
+    dia_mes INTEGER DEFAULT 0,
+    proxima_fecha DATE NOT NULL,
+    omitir_proxima BOOLEAN DEFAULT FALSE,
+    recordatorio_enviado BOOLEAN DEFAULT FALSE,
+    activo BOOLEAN DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (cliente_id) REFERENCES clientes(id),
+    INDEX idx_proxima_fecha (activo, proxima_fecha)
+);
+
+-- Ventanas de entrega por día y zona con capacidad por camión
+CREATE TABLE IF NOT EXISTS ventanas_entrega (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    zona VARCHAR(100) NOT NULL DEFAULT '',
+    dia_semana TINYINT NOT NULL,
+    hora_inicio CHAR(5) NOT NULL,
+    hora_fin CHAR(5) NOT NULL,
+    camiones INTEGER NOT NULL DEFAULT 1,
+    max_pedidos_camion INTEGER NOT NULL DEFAULT 0,
+    max_litros_camion DECIMAL(10,2) NOT NULL DEFAULT 0,
+    activo BOOLEAN DEFAULT TRUE,
+    INDEX idx_dia_zona (dia_semana, zona)
+);
+
+-- Reservas de capacidad de cada pedido en una ventana
+CREATE TABLE IF NOT EXISTS reservas_slot (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    ventana_id INTEGER NOT NULL,
+    fecha DATE NOT NULL,
+    pedido_id INTEGER NOT NULL,
+    litros DECIMAL(10,2) NOT NULL DEFAULT 0,
+    estado ENUM('reservada', 'liberada') NOT NULL DEFAULT 'reservada',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (ventana_id) REFERENCES ventanas_entrega(id),
+    FOREIGN KEY (pedido_id) REFERENCES pedidos(id),
+    INDEX idx_ventana_fecha (ventana_id, fecha, estado),
+    INDEX idx_pedido (pedido_id)
+);
+
+-- Camiones de reparto y su capacidad
+CREATE TABLE IF NOT EXISTS camiones (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    nombre VARCHAR(100) NOT NULL,
+    placas VARCHAR(20) NOT NULL DEFAULT '',
+    capacidad_litros DECIMAL(10,2) NOT NULL DEFAULT 0,
+    capacidad_cilindros INTEGER NOT NULL DEFAULT 0,
+    max_pedidos INTEGER NOT NULL DEFAULT 0,
+    activo BOOLEAN DEFAULT TRUE
+);
+
+-- Rutas generadas en el corte diario, una por camión y fecha
+CREATE TABLE IF NOT EXISTS rutas (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    fecha DATE NOT NULL,
+    camion_id INTEGER NOT NULL,
+    estado ENUM('planificada', 'en_curso', 'finalizada') NOT NULL DEFAULT 'planificada',
+    total_litros DECIMAL(10,2) NOT NULL DEFAULT 0,
+    total_cilindros INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (camion_id) REFERENCES camiones(id),
+    INDEX idx_fecha (fecha)
+);
+
+-- Paradas de cada ruta en orden de visita
+CREATE TABLE IF NOT EXISTS ruta_paradas (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    ruta_id INTEGER NOT NULL,
+    pedido_id INTEGER NOT NULL,
+    orden INTEGER NOT NULL,
+    estado ENUM('pending', 'arriving', 'delivered', 'cancelled') NOT NULL DEFAULT 'pending',
+    hora_estimada TIMESTAMP NULL,
+    FOREIGN KEY (ruta_id) REFERENCES rutas(id),
+    FOREIGN KEY (pedido_id) REFERENCES pedidos(id),
+    INDEX idx_ruta (ruta_id),
+    INDEX idx_pedido (pedido_id)
+);
+
+-- Avisos de entrega enviados a los clientes (uno por pedido y tipo)
+CREATE TABLE IF NOT EXISTS notificaciones_pedido (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    pedido_id INTEGER NOT NULL,
+    tipo VARCHAR(30) NOT NULL,
+    telefono VARCHAR(20) NOT NULL,
+    mensaje TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (pedido_id) REFERENCES pedidos(id),
+    UNIQUE KEY uk_pedido_tipo (pedido_id, tipo)
+);
+
+-- Repartidores y el camión que manejan
+CREATE TABLE IF NOT EXISTS repartidores (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    nombre VARCHAR(100) NOT NULL,
+    telefono VARCHAR(20) UNIQUE NOT NULL,
+    pin_hash VARCHAR(100) NOT NULL,
+    camion_id INTEGER NULL,
+    activo BOOLEAN DEFAULT TRUE,
+    FOREIGN KEY (camion_id) REFERENCES camiones(id)
+);
+
+-- Evidencia de entrega capturada por el repartidor
+CREATE TABLE IF NOT EXISTS entregas (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    parada_id INTEGER NOT NULL,
+    pedido_id INTEGER NOT NULL,
+    repartidor_id INTEGER NOT NULL,
+    litros_surtidos DECIMAL(10,2) NOT NULL DEFAULT 0,
+    efectivo_recibido DECIMAL(10,2) NOT NULL DEFAULT 0,
+    foto_path VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (parada_id) REFERENCES ruta_paradas(id),
+    FOREIGN KEY (pedido_id) REFERENCES pedidos(id),
+    FOREIGN KEY (repartidor_id) REFERENCES repartidores(id),
+    INDEX idx_pedido (pedido_id)
+);
+
+-- Posiciones GPS que reporta el repartidor durante la ruta
+CREATE TABLE IF NOT EXISTS posiciones_ruta (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    ruta_id INTEGER NOT NULL,
+    repartidor_id INTEGER NOT NULL,
+    latitud DECIMAL(10,7) NOT NULL,
+    longitud DECIMAL(10,7) NOT NULL,
+    precision_metros DECIMAL(10,2) NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (ruta_id) REFERENCES rutas(id),
+    FOREIGN KEY (repartidor_id) REFERENCES repartidores(id),
+    INDEX idx_ruta_fecha (ruta_id, created_at)
+);
+
+-- Esperas del repartidor en el domicilio del cliente
+CREATE TABLE IF NOT EXISTS esperas_entrega (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    pedido_id INTEGER NOT NULL,
+    parada_id INTEGER NULL,
+    inicio TIMESTAMP NOT NULL,
+    limite TIMESTAMP NOT NULL,
+    aviso_enviado BOOLEAN NOT NULL DEFAULT FALSE,
+    estado ENUM('activa', 'atendida', 'no_atendido', 'cancelada') NOT NULL DEFAULT 'activa',
+    cerrada_por VARCHAR(100) NOT NULL DEFAULT '',
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (pedido_id) REFERENCES pedidos(id),
+    FOREIGN KEY (parada_id) REFERENCES ruta_paradas(id),
+    INDEX idx_estado (estado)
+);
+
+-- Strikes de clientes, con vencimiento y pedido que los originó
+CREATE TABLE IF NOT EXISTS strikes (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    cliente_id INTEGER NOT NULL,
+    pedido_id INTEGER NULL,
+    motivo VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expira_en TIMESTAMP NOT NULL,
+    anulado BOOLEAN NOT NULL DEFAULT FALSE,
+    FOREIGN KEY (cliente_id) REFERENCES clientes(id),
+    FOREIGN KEY (pedido_id) REFERENCES pedidos(id),
+    INDEX idx_cliente (cliente_id)
+);
+
+-- Apelaciones de strikes que revisa el personal
+CREATE TABLE IF NOT EXISTS apelaciones (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    cliente_id INTEGER NOT NULL,
+    strike_id INTEGER NULL,
+    mensaje TEXT NOT NULL,
+    estado ENUM('abierta', 'aceptada', 'rechazada') NOT NULL DEFAULT 'abierta',
+    resolucion TEXT NOT NULL,
+    resuelta_por VARCHAR(100) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    resuelta_en TIMESTAMP NULL,
+    FOREIGN KEY (cliente_id) REFERENCES clientes(id),
+    FOREIGN KEY (strike_id) REFERENCES strikes(id),
     INDEX idx_estado (estado)
 );
 
+-- Bitácora de acciones sobre clientes (strikes, bloqueos, apelaciones)
+CREATE TABLE IF NOT EXISTS auditoria_clientes (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    cliente_id INTEGER NOT NULL,
+    accion VARCHAR(50) NOT NULL,
+    operador VARCHAR(100) NOT NULL,
+    detalle TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (cliente_id) REFERENCES clientes(id),
+    INDEX idx_cliente (cliente_id)
+);
+
+-- Monedero de puntos de lealtad: acumulaciones, canjes y reversos
+CREATE TABLE IF NOT EXISTS movimientos_puntos (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    cliente_id INTEGER NOT NULL,
+    pedido_id INTEGER NULL,
+    tipo VARCHAR(20) NOT NULL,
+    puntos INTEGER NOT NULL,
+    descripcion VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (cliente_id) REFERENCES clientes(id),
+    FOREIGN KEY (pedido_id) REFERENCES pedidos(id),
+    INDEX idx_cliente (cliente_id),
+    INDEX idx_pedido (pedido_id)
+);
+
+-- Códigos de promoción y sus restricciones
+CREATE TABLE IF NOT EXISTS promociones (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    codigo VARCHAR(50) NOT NULL UNIQUE,
+    descripcion VARCHAR(255) NOT NULL DEFAULT '',
+    tipo VARCHAR(20) NOT NULL,
+    valor DECIMAL(10,2) NOT NULL,
+    solo_primer_pedido BOOLEAN NOT NULL DEFAULT FALSE,
+    vigente_desde DATETIME NULL,
+    vigente_hasta DATETIME NULL,
+    max_usos_cliente INTEGER NOT NULL DEFAULT 0,
+    zonas VARCHAR(255) NOT NULL DEFAULT '',
+    activa BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Pedidos en los que se aplicó cada promoción
+CREATE TABLE IF NOT EXISTS usos_promocion (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    promocion_id INTEGER NOT NULL,
+    cliente_id INTEGER NOT NULL,
+    pedido_id INTEGER NOT NULL,
+    descuento DECIMAL(10,2) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (promocion_id) REFERENCES promociones(id),
+    FOREIGN KEY (cliente_id) REFERENCES clientes(id),
+    FOREIGN KEY (pedido_id) REFERENCES pedidos(id),
+    INDEX idx_promocion_cliente (promocion_id, cliente_id)
+);
+
+-- Código personal de referido de cada cliente
+CREATE TABLE IF NOT EXISTS codigos_referido (
+    cliente_id INTEGER PRIMARY KEY,
+    codigo VARCHAR(20) NOT NULL UNIQUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (cliente_id) REFERENCES clientes(id)
+);
+
+-- Clientes que llegaron con el código de otro y su recompensa
+CREATE TABLE IF NOT EXISTS referidos (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    referente_id INTEGER NOT NULL,
+    referido_id INTEGER NOT NULL UNIQUE,
+    codigo VARCHAR(20) NOT NULL,
+    estado VARCHAR(20) NOT NULL DEFAULT 'pendiente',
+    pedido_id INTEGER NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    recompensado_en DATETIME NULL,
+    FOREIGN KEY (referente_id) REFERENCES clientes(id),
+    FOREIGN KEY (referido_id) REFERENCES clientes(id),
+    FOREIGN KEY (pedido_id) REFERENCES pedidos(id),
+    INDEX idx_referente (referente_id)
+);
+
+-- Cobro de cada pedido y su estado (pendiente, pagado, reembolsado, vencido)
+CREATE TABLE IF NOT EXISTS pagos (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    pedido_id INTEGER NOT NULL UNIQUE,
+    metodo VARCHAR(20) NOT NULL,
+    estado ENUM('pendiente', 'pagado', 'reembolsado', 'vencido') NOT NULL DEFAULT 'pendiente',
+    monto DECIMAL(10,2) NOT NULL,
+    referencia VARCHAR(100) NOT NULL DEFAULT '',
+    url TEXT,
+    vence_en DATETIME NULL,
+    pagado_en DATETIME NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (pedido_id) REFERENCES pedidos(id),
+    INDEX idx_estado (estado),
+    INDEX idx_referencia (referencia)
+);
+
+-- Comprobantes de transferencia enviados por WhatsApp, en espera de verificación
+CREATE TABLE IF NOT EXISTS comprobantes_pago (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    pedido_id INTEGER NOT NULL,
+    cliente_id INTEGER NOT NULL,
+    archivo VARCHAR(500) NOT NULL,
+    mime_type VARCHAR(100) NOT NULL DEFAULT '',
+    estado ENUM('pendiente', 'aprobado', 'rechazado') NOT NULL DEFAULT 'pendiente',
+    revisado_por VARCHAR(100) NOT NULL DEFAULT '',
+    nota TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    revisado_en DATETIME NULL,
+    FOREIGN KEY (pedido_id) REFERENCES pedidos(id),
+    FOREIGN KEY (cliente_id) REFERENCES clientes(id),
+    INDEX idx_estado (estado)
+);
+
+-- Datos fiscales del cliente para emitir CFDI
+CREATE TABLE IF NOT EXISTS datos_fiscales (
+    cliente_id INTEGER PRIMARY KEY,
+    rfc VARCHAR(13) NOT NULL,
+    razon_social VARCHAR(255) NOT NULL,
+    regimen_fiscal VARCHAR(3) NOT NULL,
+    codigo_postal VARCHAR(5) NOT NULL,
+    uso_cfdi VARCHAR(4) NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (cliente_id) REFERENCES clientes(id)
+);
+
+-- Facturas (CFDI 4.0) timbradas por pedido
+CREATE TABLE IF NOT EXISTS facturas (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    pedido_id INTEGER NOT NULL UNIQUE,
+    cliente_id INTEGER NOT NULL,
+    rfc VARCHAR(13) NOT NULL,
+    serie VARCHAR(25) NOT NULL,
+    folio VARCHAR(40) NOT NULL,
+    uuid VARCHAR(36) NOT NULL UNIQUE,
+    total DECIMAL(10,2) NOT NULL,
+    archivo_xml VARCHAR(500) NOT NULL,
+    archivo_pdf VARCHAR(500) NOT NULL DEFAULT '',
+    token VARCHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (pedido_id) REFERENCES pedidos(id),
+    FOREIGN KEY (cliente_id) REFERENCES clientes(id)
+);
+
+-- Liquidaciones de efectivo por ruta (corte de caja del repartidor)
+CREATE TABLE IF NOT EXISTS liquidaciones (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    ruta_id INTEGER NOT NULL UNIQUE,
+    repartidor_id INTEGER NULL,
+    fecha DATE NOT NULL,
+    esperado DECIMAL(10,2) NOT NULL,
+    declarado DECIMAL(10,2) NOT NULL,
+    recibido DECIMAL(10,2) NOT NULL,
+    diferencia DECIMAL(10,2) NOT NULL,
+    estado ENUM('cuadrada', 'faltante', 'sobrante') NOT NULL,
+    cajero VARCHAR(100) NOT NULL,
+    nota VARCHAR(500) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (ruta_id) REFERENCES rutas(id),
+    FOREIGN KEY (repartidor_id) REFERENCES repartidores(id),
+    INDEX idx_fecha (fecha)
+);
+
+-- Alertas urgentes al repartidor y al punto de venta (sello violado, cancelación)
+CREATE TABLE IF NOT EXISTS alertas (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    tipo ENUM('sello_violado', 'pedido_cancelado') NOT NULL,
+    pedido_id INTEGER NOT NULL,
+    repartidor_id INTEGER NULL,
+    referencia_id INTEGER NULL,
+    mensaje TEXT NOT NULL,
+    estado ENUM('pendiente', 'escalada', 'reconocida') NOT NULL DEFAULT 'pendiente',
+    enviada_repartidor DATETIME NULL,
+    entregada_pos DATETIME NULL,
+    escalada_en DATETIME NULL,
+    reconocida_por VARCHAR(100) NOT NULL DEFAULT '',
+    reconocida_en DATETIME NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (pedido_id) REFERENCES pedidos(id),
+    FOREIGN KEY (repartidor_id) REFERENCES repartidores(id),
+    INDEX idx_estado (estado)
+);
+
+-- Calificaciones del servicio después de cada entrega
+CREATE TABLE IF NOT EXISTS calificaciones (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    pedido_id INTEGER NOT NULL UNIQUE,
+    cliente_id INTEGER NOT NULL,
+    repartidor_id INTEGER NULL,
+    puntuacion TINYINT NOT NULL,
+    comentario TEXT NOT NULL,
+    escalada_a VARCHAR(20) NOT NULL DEFAULT '',
+    escalada_en DATETIME NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (pedido_id) REFERENCES pedidos(id),
+    FOREIGN KEY (cliente_id) REFERENCES clientes(id),
+    FOREIGN KEY (repartidor_id) REFERENCES repartidores(id),
+    INDEX idx_created_at (created_at)
+);
+
+-- Confirmación del cliente de que recibió el pedido que el repartidor entregó
+CREATE TABLE IF NOT EXISTS confirmaciones_entrega (
+    id INTEGER PRIMARY KEY AUTO_INCREMENT,
+    pedido_id INTEGER NOT NULL,
+    cliente_id INTEGER NOT NULL,
+    estado ENUM('por_enviar', 'pendiente', 'confirmada', 'autoconfirmada', 'rechazada') NOT NULL DEFAULT 'pendiente',
+    vence DATETIME NOT NULL,
+    respondida_en DATETIME NULL,
+    reporte_id INTEGER NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (pedido_id) REFERENCES pedidos(id),
+    FOREIGN KEY (cliente_id) REFERENCES clientes(id),
+    FOREIGN KEY (reporte_id) REFERENCES reportes_sello(id),
+    INDEX idx_cliente_estado (cliente_id, estado),
+    INDEX idx_estado_vence (estado, vence)
+);
+
 -- Tabla de tabulador de capacidades (para cálculos de llenado)
 CREATE TABLE IF NOT EXISTS tabulador_capacidades (
     id INTEGER PRIMARY KEY AUTO_INCREMENT,
@@ -44,6 +44,7 @@ const (
 		color_puerta TEXT,
 		codigo_rojo BOOLEAN,
 		cantidad_cilindros INTEGER,
+		descuento REAL DEFAULT 0,
 		codigos_qr TEXT,
 		estado TEXT,
 		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
@@ -56,21 +57,491 @@ const (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
 		cliente_id INTEGER,
 		pedido_id INTEGER,
+		tanque_id INTEGER,
+		tipo_reporte TEXT DEFAULT 'sello_violado',
 		descripcion TEXT,
 		estado TEXT,
+		resolucion TEXT,
+		supervisor TEXT,
 		fecha_reporte TIMESTAMP,
+		vence_sla TIMESTAMP NULL,
+		asignado_en TIMESTAMP NULL,
+		resuelto_en TIMESTAMP NULL,
 		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP NULL,
 		FOREIGN KEY(cliente_id) REFERENCES clientes(id),
+		FOREIGN KEY(pedido_id) REFERENCES pedidos(id),
+		FOREIGN KEY(tanque_id) REFERENCES tanques(id)
+	);`
+
+	createTanquesTable = `
+	CREATE TABLE IF NOT EXISTS tanques (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		pedido_id INTEGER NOT NULL,
+		codigo_qr TEXT NOT NULL UNIQUE,
+		capacidad REAL,
+		tipo TEXT NOT NULL,
+		estado TEXT NOT NULL DEFAULT 'con_cliente',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY(pedido_id) REFERENCES pedidos(id)
+	);`
+
+	createFotosReporteSelloTable = `
+	CREATE TABLE IF NOT EXISTS fotos_reporte_sello (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		reporte_id INTEGER NOT NULL,
+		archivo TEXT NOT NULL,
+		mime_type TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY(reporte_id) REFERENCES reportes_sello(id)
+	);`
+
+	createPedidosProgramadosTable = `
+	CREATE TABLE IF NOT EXISTS pedidos_programados (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		cliente_id INTEGER NOT NULL,
+		tipo_servicio TEXT,
+		cantidad_litros REAL DEFAULT 0,
+		cantidad_cilindros INTEGER DEFAULT 0,
+		metodo_pago TEXT,
+		direccion TEXT,
+		latitud REAL DEFAULT 0,
+		longitud REAL DEFAULT 0,
+		mapa_url TEXT DEFAULT '',
+		streetview_url TEXT DEFAULT '',
+		color_fachada TEXT DEFAULT '',
+		color_puerta TEXT DEFAULT '',
+		horario_preferido TEXT DEFAULT '',
+		frecuencia TEXT NOT NULL DEFAULT 'unica',
+		intervalo_semanas INTEGER DEFAULT 0,
+		dia_mes INTEGER DEFAULT 0,
+		proxima_fecha DATE NOT NULL,
+		omitir_proxima BOOLEAN DEFAULT FALSE,
+		recordatorio_enviado BOOLEAN DEFAULT FALSE,
+		activo BOOLEAN DEFAULT TRUE,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY(cliente_id) REFERENCES clientes(id)
+	);`
+
+	createVentanasEntregaTable = `
+	CREATE TABLE IF NOT EXISTS ventanas_entrega (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		zona TEXT NOT NULL DEFAULT '',
+		dia_semana INTEGER NOT NULL,
+		hora_inicio TEXT NOT NULL,
+		hora_fin TEXT NOT NULL,
+		camiones INTEGER NOT NULL DEFAULT 1,
+		max_pedidos_camion INTEGER NOT NULL DEFAULT 0,
+		max_litros_camion REAL NOT NULL DEFAULT 0,
+		activo BOOLEAN DEFAULT TRUE
+	);`
+
+	createReservasSlotTable = `
+	CREATE TABLE IF NOT EXISTS reservas_slot (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		ventana_id INTEGER NOT NULL,
+		fecha DATE NOT NULL,
+		pedido_id INTEGER NOT NULL,
+		litros REAL NOT NULL DEFAULT 0,
+		estado TEXT NOT NULL DEFAULT 'reservada',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY(ventana_id) REFERENCES ventanas_entrega(id),
+		FOREIGN KEY(pedido_id) REFERENCES pedidos(id)
+	);`
+
+	createCamionesTable = `
+	CREATE TABLE IF NOT EXISTS camiones (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		nombre TEXT NOT NULL,
+		placas TEXT NOT NULL DEFAULT '',
+		capacidad_litros REAL NOT NULL DEFAULT 0,
+		capacidad_cilindros INTEGER NOT NULL DEFAULT 0,
+		max_pedidos INTEGER NOT NULL DEFAULT 0,
+		activo BOOLEAN DEFAULT TRUE
+	);`
+
+	createRutasTable = `
+	CREATE TABLE IF NOT EXISTS rutas (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		fecha DATE NOT NULL,
+		camion_id INTEGER NOT NULL,
+		estado TEXT NOT NULL DEFAULT 'planificada',
+		total_litros REAL NOT NULL DEFAULT 0,
+		total_cilindros INTEGER NOT NULL DEFAULT 0,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY(camion_id) REFERENCES camiones(id)
+	);`
+
+	createRutaParadasTable = `
+	CREATE TABLE IF NOT EXISTS ruta_paradas (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		ruta_id INTEGER NOT NULL,
+		pedido_id INTEGER NOT NULL,
+		orden INTEGER NOT NULL,
+		estado TEXT NOT NULL DEFAULT 'pending',
+		hora_estimada TIMESTAMP NULL,
+		FOREIGN KEY(ruta_id) REFERENCES rutas(id),
+		FOREIGN KEY(pedido_id) REFERENCES pedidos(id)
+	);`
+
+	createNotificacionesPedidoTable = `
+	CREATE TABLE IF NOT EXISTS notificaciones_pedido (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		pedido_id INTEGER NOT NULL,
+		tipo TEXT NOT NULL,
+		telefono TEXT NOT NULL,
+		mensaje TEXT NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(pedido_id, tipo),
 		FOREIGN KEY(pedido_id) REFERENCES pedidos(id)
 	);`
+
+	createRepartidoresTable = `
+	CREATE TABLE IF NOT EXISTS repartidores (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		nombre TEXT NOT NULL,
+		telefono TEXT UNIQUE NOT NULL,
+		pin_hash TEXT NOT NULL,
+		camion_id INTEGER,
+		activo BOOLEAN DEFAULT TRUE,
+		FOREIGN KEY(camion_id) REFERENCES camiones(id)
+	);`
+
+	createEntregasTable = `
+	CREATE TABLE IF NOT EXISTS entregas (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		parada_id INTEGER NOT NULL,
+		pedido_id INTEGER NOT NULL,
+		repartidor_id INTEGER NOT NULL,
+		litros_surtidos REAL NOT NULL DEFAULT 0,
+		efectivo_recibido REAL NOT NULL DEFAULT 0,
+		foto_path TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY(parada_id) REFERENCES ruta_paradas(id),
+		FOREIGN KEY(pedido_id) REFERENCES pedidos(id),
+		FOREIGN KEY(repartidor_id) REFERENCES repartidores(id)
+	);`
+
+	createPosicionesRutaTable = `
+	CREATE TABLE IF NOT EXISTS posiciones_ruta (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		ruta_id INTEGER NOT NULL,
+		repartidor_id INTEGER NOT NULL,
+		latitud REAL NOT NULL,
+		longitud REAL NOT NULL,
+		precision_metros REAL NOT NULL DEFAULT 0,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY(ruta_id) REFERENCES rutas(id),
+		FOREIGN KEY(repartidor_id) REFERENCES repartidores(id)
+	);`
+
+	createEsperasEntregaTable = `
+	CREATE TABLE IF NOT EXISTS esperas_entrega (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		pedido_id INTEGER NOT NULL,
+		parada_id INTEGER,
+		inicio TIMESTAMP NOT NULL,
+		limite TIMESTAMP NOT NULL,
+		aviso_enviado BOOLEAN NOT NULL DEFAULT 0,
+		estado TEXT NOT NULL DEFAULT 'activa',
+		cerrada_por TEXT NOT NULL DEFAULT '',
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY(pedido_id) REFERENCES pedidos(id),
+		FOREIGN KEY(parada_id) REFERENCES ruta_paradas(id)
+	);`
+
+	createStrikesTable = `
+	CREATE TABLE IF NOT EXISTS strikes (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		cliente_id INTEGER NOT NULL,
+		pedido_id INTEGER,
+		motivo TEXT NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		expira_en TIMESTAMP NOT NULL,
+		anulado BOOLEAN NOT NULL DEFAULT 0,
+		FOREIGN KEY(cliente_id) REFERENCES clientes(id),
+		FOREIGN KEY(pedido_id) REFERENCES pedidos(id)
+	);`
+
+	createApelacionesTable = `
+	CREATE TABLE IF NOT EXISTS apelaciones (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		cliente_id INTEGER NOT NULL,
+		strike_id INTEGER,
+		mensaje TEXT NOT NULL,
+		estado TEXT NOT NULL DEFAULT 'abierta',
+		resolucion TEXT NOT NULL DEFAULT '',
+		resuelta_por TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		resuelta_en TIMESTAMP NULL,
+		FOREIGN KEY(cliente_id) REFERENCES clientes(id),
+		FOREIGN KEY(strike_id) REFERENCES strikes(id)
+	);`
+
+	createAuditoriaClientesTable = `
+	CREATE TABLE IF NOT EXISTS auditoria_clientes (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		cliente_id INTEGER NOT NULL,
+		accion TEXT NOT NULL,
+		operador TEXT NOT NULL,
+		detalle TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY(cliente_id) REFERENCES clientes(id)
+	);`
+
+	createMovimientosPuntosTable = `
+	CREATE TABLE IF NOT EXISTS movimientos_puntos (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		cliente_id INTEGER NOT NULL,
+		pedido_id INTEGER,
+		tipo TEXT NOT NULL,
+		puntos INTEGER NOT NULL,
+		descripcion TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY(cliente_id) REFERENCES clientes(id),
+		FOREIGN KEY(pedido_id) REFERENCES pedidos(id)
+	);`
+
+	createPromocionesTable = `
+	CREATE TABLE IF NOT EXISTS promociones (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		codigo TEXT NOT NULL UNIQUE,
+		descripcion TEXT NOT NULL DEFAULT '',
+		tipo TEXT NOT NULL,
+		valor REAL NOT NULL,
+		solo_primer_pedido BOOLEAN NOT NULL DEFAULT 0,
+		vigente_desde TIMESTAMP NULL,
+		vigente_hasta TIMESTAMP NULL,
+		max_usos_cliente INTEGER NOT NULL DEFAULT 0,
+		zonas TEXT NOT NULL DEFAULT '',
+		activa BOOLEAN NOT NULL DEFAULT 1,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	createUsosPromocionTable = `
+	CREATE TABLE IF NOT EXISTS usos_promocion (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		promocion_id INTEGER NOT NULL,
+		cliente_id INTEGER NOT NULL,
+		pedido_id INTEGER NOT NULL,
+		descuento REAL NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY(promocion_id) REFERENCES promociones(id),
+		FOREIGN KEY(cliente_id) REFERENCES clientes(id),
+		FOREIGN KEY(pedido_id) REFERENCES pedidos(id)
+	);`
+
+	createCodigosReferidoTable = `
+	CREATE TABLE IF NOT EXISTS codigos_referido (
+		cliente_id INTEGER PRIMARY KEY,
+		codigo TEXT NOT NULL UNIQUE,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY(cliente_id) REFERENCES clientes(id)
+	);`
+
+	createReferidosTable = `
+	CREATE TABLE IF NOT EXISTS referidos (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		referente_id INTEGER NOT NULL,
+		referido_id INTEGER NOT NULL UNIQUE,
+		codigo TEXT NOT NULL,
+		estado TEXT NOT NULL DEFAULT 'pendiente',
+		pedido_id INTEGER,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		recompensado_en TIMESTAMP NULL,
+		FOREIGN KEY(referente_id) REFERENCES clientes(id),
+		FOREIGN KEY(referido_id) REFERENCES clientes(id),
+		FOREIGN KEY(pedido_id) REFERENCES pedidos(id)
+	);`
+
+	createPagosTable = `
+	CREATE TABLE IF NOT EXISTS pagos (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		pedido_id INTEGER NOT NULL UNIQUE,
+		metodo TEXT NOT NULL,
+		estado TEXT NOT NULL DEFAULT 'pendiente',
+		monto REAL NOT NULL,
+		referencia TEXT NOT NULL DEFAULT '',
+		url TEXT NOT NULL DEFAULT '',
+		pagado_en TIMESTAMP NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY(pedido_id) REFERENCES pedidos(id)
+	);`
+
+	createComprobantesPagoTable = `
+	CREATE TABLE IF NOT EXISTS comprobantes_pago (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		pedido_id INTEGER NOT NULL,
+		cliente_id INTEGER NOT NULL,
+		archivo TEXT NOT NULL,
+		mime_type TEXT NOT NULL DEFAULT '',
+		estado TEXT NOT NULL DEFAULT 'pendiente',
+		revisado_por TEXT NOT NULL DEFAULT '',
+		nota TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		revisado_en TIMESTAMP NULL,
+		FOREIGN KEY(pedido_id) REFERENCES pedidos(id),
+		FOREIGN KEY(cliente_id) REFERENCES clientes(id)
+	);`
+
+	createDatosFiscalesTable = `
+	CREATE TABLE IF NOT EXISTS datos_fiscales (
+		cliente_id INTEGER PRIMARY KEY,
+		rfc TEXT NOT NULL,
+		razon_social TEXT NOT NULL,
+		regimen_fiscal TEXT NOT NULL,
+		codigo_postal TEXT NOT NULL,
+		uso_cfdi TEXT NOT NULL,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY(cliente_id) REFERENCES clientes(id)
+	);`
+
+	createFacturasTable = `
+	CREATE TABLE IF NOT EXISTS facturas (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		pedido_id INTEGER NOT NULL UNIQUE,
+		cliente_id INTEGER NOT NULL,
+		rfc TEXT NOT NULL,
+		serie TEXT NOT NULL,
+		folio TEXT NOT NULL,
+		uuid TEXT NOT NULL UNIQUE,
+		total REAL NOT NULL,
+		archivo_xml TEXT NOT NULL,
+		archivo_pdf TEXT NOT NULL DEFAULT '',
+		token TEXT NOT NULL UNIQUE,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY(pedido_id) REFERENCES pedidos(id),
+		FOREIGN KEY(cliente_id) REFERENCES clientes(id)
+	);`
+
+	createLiquidacionesTable = `
+	CREATE TABLE IF NOT EXISTS liquidaciones (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		ruta_id INTEGER NOT NULL UNIQUE,
+		repartidor_id INTEGER,
+		fecha DATE NOT NULL,
+		esperado REAL NOT NULL,
+		declarado REAL NOT NULL,
+		recibido REAL NOT NULL,
+		diferencia REAL NOT NULL,
+		estado TEXT NOT NULL,
+		cajero TEXT NOT NULL,
+		nota TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY(ruta_id) REFERENCES rutas(id),
+		FOREIGN KEY(repartidor_id) REFERENCES repartidores(id)
+	);`
+
+	createAlertasTable = `
+	CREATE TABLE IF NOT EXISTS alertas (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		tipo TEXT NOT NULL,
+		pedido_id INTEGER NOT NULL,
+		repartidor_id INTEGER,
+		referencia_id INTEGER,
+		mensaje TEXT NOT NULL,
+		estado TEXT NOT NULL DEFAULT 'pendiente',
+		enviada_repartidor TIMESTAMP NULL,
+		entregada_pos TIMESTAMP NULL,
+		escalada_en TIMESTAMP NULL,
+		reconocida_por TEXT NOT NULL DEFAULT '',
+		reconocida_en TIMESTAMP NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY(pedido_id) REFERENCES pedidos(id),
+		FOREIGN KEY(repartidor_id) REFERENCES repartidores(id)
+	);`
+
+	createCalificacionesTable = `
+	CREATE TABLE IF NOT EXISTS calificaciones (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		pedido_id INTEGER NOT NULL UNIQUE,
+		cliente_id INTEGER NOT NULL,
+		repartidor_id INTEGER,
+		puntuacion INTEGER NOT NULL,
+		comentario TEXT NOT NULL DEFAULT '',
+		escalada_a TEXT NOT NULL DEFAULT '',
+		escalada_en TIMESTAMP NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY(pedido_id) REFERENCES pedidos(id),
+		FOREIGN KEY(cliente_id) REFERENCES clientes(id),
+		FOREIGN KEY(repartidor_id) REFERENCES repartidores(id)
+	);`
+
+	createConfirmacionesEntregaTable = `
+	CREATE TABLE IF NOT EXISTS confirmaciones_entrega (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		pedido_id INTEGER NOT NULL,
+		cliente_id INTEGER NOT NULL,
+		estado TEXT NOT NULL DEFAULT 'pendiente',
+		vence TIMESTAMP NOT NULL,
+		respondida_en TIMESTAMP NULL,
+		reporte_id INTEGER,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY(pedido_id) REFERENCES pedidos(id),
+		FOREIGN KEY(cliente_id) REFERENCES clientes(id),
+		FOREIGN KEY(reporte_id) REFERENCES reportes_sello(id)
+	);`
 )
 
+// columnaNueva es una columna agregada a una tabla que ya existía en bases
+// creadas con versiones anteriores.
+type columnaNueva struct {
+	tabla      string
+	columna    string
+	definicion string
+}
+
+var columnasNuevas = []columnaNueva{
+	{"ruta_paradas", "hora_estimada", "TIMESTAMP NULL"},
+	{"pedidos", "descuento", "REAL DEFAULT 0"},
+	{"pagos", "vence_en", "TIMESTAMP NULL"},
+	{"reportes_sello", "tanque_id", "INTEGER"},
+	{"reportes_sello", "tipo_reporte", "TEXT DEFAULT 'sello_violado'"},
+	{"reportes_sello", "resolucion", "TEXT"},
+	{"reportes_sello", "supervisor", "TEXT"},
+	{"reportes_sello", "vence_sla", "TIMESTAMP NULL"},
+	{"reportes_sello", "asignado_en", "TIMESTAMP NULL"},
+	{"reportes_sello", "resuelto_en", "TIMESTAMP NULL"},
+	{"reportes_sello", "updated_at", "TIMESTAMP NULL"},
+}
+
 // RunSQLiteMigrations ejecuta las migraciones para una base de datos SQLite
 func RunSQLiteMigrations(db *sql.DB) error {
 	tables := []string{
 		createClientesTable,
 		createPedidosTable,
 		createReportesSelloTable,
+		createPedidosProgramadosTable,
+		createVentanasEntregaTable,
+		createReservasSlotTable,
+		createCamionesTable,
+		createRutasTable,
+		createRutaParadasTable,
+		createNotificacionesPedidoTable,
+		createRepartidoresTable,
+		createEntregasTable,
+		createPosicionesRutaTable,
+		createEsperasEntregaTable,
+		createStrikesTable,
+		createApelacionesTable,
+		createAuditoriaClientesTable,
+		createMovimientosPuntosTable,
+		createPromocionesTable,
+		createUsosPromocionTable,
+		createCodigosReferidoTable,
+		createReferidosTable,
+		createPagosTable,
+		createComprobantesPagoTable,
+		createDatosFiscalesTable,
+		createFacturasTable,
+		createLiquidacionesTable,
+		createTanquesTable,
+		createFotosReporteSelloTable,
+		createAlertasTable,
+		createCalificacionesTable,
+		createConfirmacionesEntregaTable,
 	}
 
 	for _, table := range tables {
@@ -79,6 +550,42 @@ func RunSQLiteMigrations(db *sql.DB) error {
 		}
 	}
 
+	for _, c := range columnasNuevas {
+		if err := agregarColumnaSQLite(db, c); err != nil {
+			return err
+		}
+	}
+
 	fmt.Println("Migraciones de SQLite completadas exitosamente.")
 	return nil
 }
+
+// agregarColumnaSQLite agrega la columna si la tabla todavía no la tiene.
+func agregarColumnaSQLite(db *sql.DB, c columnaNueva) error {
+	rows, err := db.Query("PRAGMA table_info(" + c.tabla + ")")
+	if err != nil {
+		return fmt.Errorf("error consultando columnas de %s: %w", c.tabla, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid, notNull, pk int
+		var nombre, tipo string
+		var defecto sql.NullString
+		if err := rows.Scan(&cid, &nombre, &tipo, &notNull, &defecto, &pk); err != nil {
+			return fmt.Errorf("error escaneando columnas de %s: %w", c.tabla, err)
+		}
+		if nombre == c.columna {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	if _, err := db.Exec("ALTER TABLE " + c.tabla + " ADD COLUMN " + c.columna + " " + c.definicion); err != nil {
+		return fmt.Errorf("error agregando columna %s.%s: %w", c.tabla, c.columna, err)
+	}
+	return nil
+}
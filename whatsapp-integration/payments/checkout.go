@@ -0,0 +1,56 @@
+package payments
+
+import (
+	"context"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// VigenciaLigaPorDefecto es el tiempo para pagar una liga si no se configura otro.
+const VigenciaLigaPorDefecto = 60 * time.Minute
+
+// CheckoutRequest es el cobro que se le pide al procesador de pagos en línea.
+type CheckoutRequest struct {
+	PedidoID    int
+	Monto       float64
+	Descripcion string
+	VenceEn     time.Time
+}
+
+// Checkout es la liga que generó el procesador. ID es su folio y regresa en
+// la confirmación de pago.
+type Checkout struct {
+	ID  string
+	URL string
+}
+
+// CheckoutGateway es el procesador que genera las ligas de pago en línea. La
+// confirmación del pago llega después a WebhookHandler.
+type CheckoutGateway interface {
+	CreateCheckout(ctx context.Context, req CheckoutRequest) (*Checkout, error)
+	Refund(ctx context.Context, checkoutID string, monto float64) error
+}
+
+// TemplateGateway arma la liga con una plantilla fija, para procesadores que
+// cobran con una página propia. URLTemplate acepta {pedido} y {monto}, por
+// ejemplo "https://pagos.ejemplo.com/pagar?pedido={pedido}&monto={monto}".
+type TemplateGateway struct {
+	URLTemplate string
+}
+
+func (t TemplateGateway) CreateCheckout(ctx context.Context, req CheckoutRequest) (*Checkout, error) {
+	liga := strings.NewReplacer(
+		"{pedido}", strconv.Itoa(req.PedidoID),
+		"{monto}", url.QueryEscape(strconv.FormatFloat(req.Monto, 'f', 2, 64)),
+	).Replace(t.URLTemplate)
+	return &Checkout{ID: ReferenciaPedido(req.PedidoID), URL: liga}, nil
+}
+
+// Refund con plantilla se hace en el portal del procesador.
+func (TemplateGateway) Refund(ctx context.Context, checkoutID string, monto float64) error {
+	log.Printf("Reembolso de pago en línea pendiente: folio %s, $%.2f\n", checkoutID, monto)
+	return nil
+}
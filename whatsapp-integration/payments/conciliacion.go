@@ -0,0 +1,199 @@
+package payments
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StatementLine es un depósito del estado de cuenta y su resultado.
+type StatementLine struct {
+	Linea    int     `json:"linea"`
+	Fecha    string  `json:"fecha"`
+	Concepto string  `json:"concepto"`
+	Monto    float64 `json:"monto"`
+	PedidoID int     `json:"pedido_id,omitempty"`
+	Motivo   string  `json:"motivo,omitempty"` // por qué no se concilió
+}
+
+// ReconcileResult resume la importación de un estado de cuenta.
+type ReconcileResult struct {
+	Conciliados  []StatementLine `json:"conciliados"`
+	SinConciliar []StatementLine `json:"sin_conciliar"`
+}
+
+// referenciaEnConcepto encuentra referencias de pedido (ReferenciaPedido)
+// dentro del concepto o la referencia del movimiento.
+var referenciaEnConcepto = regexp.MustCompile(`\b\d{7}\b`)
+
+// Columnas reconocidas del CSV, por nombre de encabezado.
+var (
+	columnasFecha    = []string{"fecha", "fecha operacion", "fecha operación"}
+	columnasConcepto = []string{"referencia", "concepto", "descripcion", "descripción", "referencia numerica", "referencia numérica"}
+	columnasMonto    = []string{"abono", "abonos", "deposito", "depósito", "monto", "importe"}
+)
+
+// ImportStatement lee un estado de cuenta en CSV (separado por comas o punto y
+// coma, con encabezados) y marca pagados los pedidos por transferencia cuya
+// referencia y monto coinciden con un depósito. Los cargos se ignoran.
+func (s *Service) ImportStatement(ctx context.Context, r io.Reader, ahora time.Time) (*ReconcileResult, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("error leyendo estado de cuenta: %w", err)
+	}
+	lector := csv.NewReader(strings.NewReader(string(data)))
+	lector.FieldsPerRecord = -1
+	lector.LazyQuotes = true
+	if primera, _, _ := strings.Cut(string(data), "\n"); strings.Count(primera, ";") > strings.Count(primera, ",") {
+		lector.Comma = ';'
+	}
+
+	filas, err := lector.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("error leyendo estado de cuenta: %w", err)
+	}
+	if len(filas) == 0 {
+		return nil, fmt.Errorf("el estado de cuenta está vacío")
+	}
+	fecha, conceptos, monto := indicesEstadoCuenta(filas[0])
+	if len(conceptos) == 0 || monto < 0 {
+		return nil, fmt.Errorf("el estado de cuenta debe tener columnas de referencia o concepto y de abono o monto")
+	}
+
+	res := &ReconcileResult{}
+	for i, fila := range filas[1:] {
+		linea := StatementLine{Linea: i + 2}
+		if fecha >= 0 && fecha < len(fila) {
+			linea.Fecha = strings.TrimSpace(fila[fecha])
+		}
+		var partes []string
+		for _, c := range conceptos {
+			if c < len(fila) && strings.TrimSpace(fila[c]) != "" {
+				partes = append(partes, strings.TrimSpace(fila[c]))
+			}
+		}
+		linea.Concepto = strings.Join(partes, " ")
+		if monto >= len(fila) {
+			continue
+		}
+		importe, err := parseImporte(fila[monto])
+		if err != nil || importe <= 0 {
+			continue // cargos y renglones sin importe
+		}
+		linea.Monto = importe
+
+		if err := s.conciliar(ctx, &linea, ahora); err != nil {
+			return nil, err
+		}
+		if linea.PedidoID != 0 {
+			res.Conciliados = append(res.Conciliados, linea)
+		} else {
+			res.SinConciliar = append(res.SinConciliar, linea)
+		}
+	}
+	log.Printf("Estado de cuenta importado: %d depósitos conciliados, %d sin conciliar\n", len(res.Conciliados), len(res.SinConciliar))
+	return res, nil
+}
+
+// conciliar busca el pago pendiente con la referencia y el monto del depósito.
+func (s *Service) conciliar(ctx context.Context, linea *StatementLine, ahora time.Time) error {
+	referencias := referenciaEnConcepto.FindAllString(linea.Concepto, -1)
+	if len(referencias) == 0 {
+		linea.Motivo = "sin referencia de pedido"
+		return nil
+	}
+
+	linea.Motivo = "referencia desconocida"
+	for _, referencia := range referencias {
+		pago, err := s.store.GetPagoPorReferencia(ctx, MethodTransfer, referencia)
+		if err != nil {
+			return err
+		}
+		if pago == nil {
+			continue
+		}
+		if pago.Estado != StatusPending {
+			linea.Motivo = fmt.Sprintf("el pedido %d ya está %s", pago.PedidoID, pago.Estado)
+			continue
+		}
+		if math.Abs(pago.Monto-linea.Monto) >= 0.01 {
+			linea.Motivo = fmt.Sprintf("monto distinto al del pedido %d ($%.2f)", pago.PedidoID, pago.Monto)
+			continue
+		}
+
+		pagado, err := s.MarkPaid(ctx, pago.PedidoID, "", ahora)
+		if err != nil {
+			return err
+		}
+		if !pagado {
+			continue
+		}
+		linea.PedidoID, linea.Motivo = pago.PedidoID, ""
+		s.aprobarComprobantes(ctx, pago.PedidoID, ahora)
+		s.avisar(ctx, pago.PedidoID, fmt.Sprintf("Recibimos tu transferencia de $%.2f del pedido #%d. ¡Gracias por tu pago!", linea.Monto, pago.PedidoID))
+		return nil
+	}
+	return nil
+}
+
+// aprobarComprobantes cierra los comprobantes en espera de un pedido que ya
+// se concilió con el banco.
+func (s *Service) aprobarComprobantes(ctx context.Context, pedidoID int, ahora time.Time) {
+	pendientes, err := s.store.GetComprobantesPago(ctx, ReceiptPending)
+	if err != nil {
+		log.Printf("Error consultando comprobantes del pedido %d: %v\n", pedidoID, err)
+		return
+	}
+	for _, c := range pendientes {
+		if c.PedidoID != pedidoID {
+			continue
+		}
+		c.Estado = ReceiptApproved
+		c.RevisadoPor = "estado de cuenta"
+		c.RevisadoEn = &ahora
+		if err := s.store.ActualizarComprobantePago(ctx, c); err != nil {
+			log.Printf("Error aprobando comprobante %d: %v\n", c.ID, err)
+		}
+	}
+}
+
+func indicesEstadoCuenta(encabezados []string) (fecha int, conceptos []int, monto int) {
+	fecha, monto = -1, -1
+	for i, e := range encabezados {
+		e = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(e, "\ufeff")))
+		switch {
+		case fecha < 0 && contiene(columnasFecha, e):
+			fecha = i
+		case contiene(columnasConcepto, e):
+			conceptos = append(conceptos, i)
+		case monto < 0 && contiene(columnasMonto, e):
+			monto = i
+		}
+	}
+	return fecha, conceptos, monto
+}
+
+func contiene(lista []string, v string) bool {
+	for _, x := range lista {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// parseImporte acepta importes como "1,500.00" o "$1500".
+func parseImporte(v string) (float64, error) {
+	v = strings.NewReplacer("$", "", ",", "", " ", "").Replace(strings.TrimSpace(v))
+	if v == "" {
+		return 0, fmt.Errorf("importe vacío")
+	}
+	return strconv.ParseFloat(v, 64)
+}
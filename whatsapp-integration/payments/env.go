@@ -0,0 +1,73 @@
+package payments
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"example.com/whatsapp-integration/store"
+)
+
+// NewServiceFromEnv registra los métodos configurados:
+//
+//   - efectivo y tarjeta con terminal siempre;
+//   - transferencia si hay una SPEI_CLABE válida (con SPEI_BANCO y
+//     SPEI_TITULAR); los comprobantes se guardan en COMPROBANTES_DIR;
+//   - liga de pago con el procesador dado o, si es nil, con la plantilla de
+//     PAGOS_LIGA_URL; PAGOS_LIGA_VIGENCIA_MIN (60) es el plazo para pagarla.
+//
+// PAGOS_METODOS (por defecto "efectivo") son los métodos de todas las zonas y
+// PAGOS_ARCHIVO puede definirlos por zona.
+func NewServiceFromEnv(s store.Store, gateway CheckoutGateway) (*Service, error) {
+	svc := NewService(s)
+	svc.Register(CashProvider{})
+	svc.Register(CardTerminalProvider{})
+
+	// Una CLABE inválida no detiene la configuración; solo se omiten las
+	// transferencias y se reporta al final.
+	var errClabe error
+	if clabe := os.Getenv("SPEI_CLABE"); clabe != "" {
+		errClabe = ValidarCLABE(clabe)
+	}
+	if clabe := os.Getenv("SPEI_CLABE"); clabe != "" && errClabe == nil {
+		svc.Register(TransferProvider{
+			Banco:   os.Getenv("SPEI_BANCO"),
+			CLABE:   clabe,
+			Titular: os.Getenv("SPEI_TITULAR"),
+		})
+	}
+	if gateway == nil && os.Getenv("PAGOS_LIGA_URL") != "" {
+		gateway = TemplateGateway{URLTemplate: os.Getenv("PAGOS_LIGA_URL")}
+	}
+	if gateway != nil {
+		vigencia := VigenciaLigaPorDefecto
+		if min, err := strconv.Atoi(os.Getenv("PAGOS_LIGA_VIGENCIA_MIN")); err == nil && min > 0 {
+			vigencia = time.Duration(min) * time.Minute
+		}
+		svc.Register(LinkProvider{Gateway: gateway, Vigencia: vigencia})
+	}
+
+	metodos := []string{MethodCash}
+	if v := os.Getenv("PAGOS_METODOS"); v != "" {
+		metodos = nil
+		for _, m := range strings.Split(v, ",") {
+			if m = strings.TrimSpace(m); m != "" {
+				metodos = append(metodos, m)
+			}
+		}
+	}
+	svc.SetDefaultMethods(metodos)
+	svc.SetReceiptsDir(os.Getenv("COMPROBANTES_DIR"))
+
+	if archivo := os.Getenv("PAGOS_ARCHIVO"); archivo != "" {
+		if err := svc.LoadConfig(archivo); err != nil {
+			return svc, err
+		}
+	}
+	if errClabe != nil {
+		return svc, fmt.Errorf("SPEI_CLABE inválida, transferencias deshabilitadas: %w", errClabe)
+	}
+	return svc, nil
+}
@@ -0,0 +1,119 @@
+package payments
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"example.com/whatsapp-integration/store"
+)
+
+// StartExpiryCheck revisa cada intervalo las ligas vencidas. Se detiene al
+// cancelar ctx.
+func (s *Service) StartExpiryCheck(ctx context.Context, intervalo time.Duration) {
+	go func() {
+		ticker := time.NewTicker(intervalo)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ahora := <-ticker.C:
+				if err := s.CancelExpired(ctx, ahora); err != nil {
+					log.Printf("Error revisando pagos vencidos: %v\n", err)
+				}
+			}
+		}
+	}()
+}
+
+// CancelExpired cancela los pedidos cuyo pago en línea no llegó antes del
+// límite y avisa al cliente. Los pedidos que ya salieron a ruta no se tocan.
+func (s *Service) CancelExpired(ctx context.Context, ahora time.Time) error {
+	pendientes, err := s.store.GetPagosPorEstado(ctx, "", StatusPending)
+	if err != nil {
+		return err
+	}
+	for _, pago := range pendientes {
+		if pago.VenceEn == nil || pago.VenceEn.After(ahora) {
+			continue
+		}
+		pedido, err := s.store.GetPedido(ctx, pago.PedidoID)
+		if err != nil {
+			return err
+		}
+		if pedido == nil || !cancelable(pedido.Estado) {
+			continue
+		}
+
+		vencido, err := s.expirar(ctx, pago.PedidoID)
+		if err != nil {
+			return err
+		}
+		if !vencido || pedido.Estado == "cancelado" {
+			continue
+		}
+		if err := s.cancelar(ctx, pedido); err != nil {
+			log.Printf("Error cancelando pedido %d sin pago: %v\n", pedido.ID, err)
+			continue
+		}
+		log.Printf("Pedido %d cancelado: pago en línea vencido\n", pedido.ID)
+		s.avisar(ctx, pedido.ID, fmt.Sprintf("Tu pedido #%d fue cancelado porque no recibimos el pago en línea a tiempo. Si aún lo necesitas, escríbenos para hacer uno nuevo.", pedido.ID))
+	}
+	return nil
+}
+
+// cancelable indica si el pedido todavía no sale a ruta.
+func cancelable(estado string) bool {
+	switch estado {
+	case "pendiente", "pendiente_recoleccion", "cancelado":
+		return true
+	}
+	return false
+}
+
+// expirar marca vencido el pago si sigue pendiente; una confirmación que llegó
+// mientras tanto gana.
+func (s *Service) expirar(ctx context.Context, pedidoID int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pago, err := s.store.GetPagoPorPedido(ctx, pedidoID)
+	if err != nil || pago == nil || pago.Estado != StatusPending {
+		return false, err
+	}
+	pago.Estado = StatusExpired
+	if err := s.store.ActualizarPago(ctx, pago); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *Service) cancelar(ctx context.Context, pedido *store.Pedido) error {
+	if s.canceller != nil {
+		return s.canceller.CancelarPedido(ctx, pedido, "pago en línea vencido")
+	}
+	pedido.Estado = "cancelado"
+	return s.store.ActualizarPedido(ctx, pedido)
+}
+
+// avisar manda un mensaje al cliente del pedido; los errores solo se registran.
+func (s *Service) avisar(ctx context.Context, pedidoID int, msg string) {
+	if s.sender == nil {
+		return
+	}
+	pedido, err := s.store.GetPedido(ctx, pedidoID)
+	if err != nil || pedido == nil {
+		log.Printf("Error consultando pedido %d para avisar al cliente: %v\n", pedidoID, err)
+		return
+	}
+	cliente, err := s.store.GetClientePorID(ctx, pedido.ClienteID)
+	if err != nil || cliente == nil {
+		log.Printf("Error consultando cliente del pedido %d: %v\n", pedidoID, err)
+		return
+	}
+	if err := s.sender.SendMessage(cliente.NumeroTelefono, msg); err != nil {
+		log.Printf("Error avisando a %s del pago del pedido %d: %v\n", cliente.NumeroTelefono, pedidoID, err)
+	}
+}
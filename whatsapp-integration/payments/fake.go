@@ -0,0 +1,203 @@
+package payments
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FakeGateway simula un procesador de pagos en línea para pruebas o para
+// operar sin proveedor. Sus ligas apuntan a su propio servidor: la página
+// muestra el monto y un botón "Pagar" que manda la confirmación firmada al
+// webhook configurado, igual que lo haría un procesador real.
+type FakeGateway struct {
+	baseURL     string
+	callbackURL string
+	secret      string
+	client      *http.Client
+
+	mu        sync.Mutex
+	seq       int
+	checkouts map[string]*fakeCheckout
+}
+
+type fakeCheckout struct {
+	CheckoutRequest
+	ID     string
+	Estado string // "pendiente", "pagado", "reembolsado"
+}
+
+// NewFakeGateway crea el procesador simulado. baseURL es donde se sirve
+// Handler y callbackURL la ruta de WebhookHandler.
+func NewFakeGateway(baseURL, callbackURL, secret string) *FakeGateway {
+	return &FakeGateway{
+		baseURL:     strings.TrimRight(baseURL, "/"),
+		callbackURL: callbackURL,
+		secret:      secret,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		checkouts:   make(map[string]*fakeCheckout),
+	}
+}
+
+func (g *FakeGateway) CreateCheckout(ctx context.Context, req CheckoutRequest) (*Checkout, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.seq++
+	id := fmt.Sprintf("chk_%d_%d", req.PedidoID, g.seq)
+	g.checkouts[id] = &fakeCheckout{CheckoutRequest: req, ID: id, Estado: StatusPending}
+	return &Checkout{ID: id, URL: g.baseURL + "/checkout/" + id}, nil
+}
+
+func (g *FakeGateway) Refund(ctx context.Context, checkoutID string, monto float64) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	c, ok := g.checkouts[checkoutID]
+	if !ok || c.Estado != StatusPaid {
+		return fmt.Errorf("checkout %s no está pagado", checkoutID)
+	}
+	c.Estado = StatusRefunded
+	log.Printf("Pasarela simulada: checkout %s reembolsado ($%.2f)\n", checkoutID, monto)
+	return nil
+}
+
+// Pay paga el checkout y manda la confirmación firmada al webhook.
+func (g *FakeGateway) Pay(ctx context.Context, checkoutID string) error {
+	g.mu.Lock()
+	c, ok := g.checkouts[checkoutID]
+	if !ok {
+		g.mu.Unlock()
+		return fmt.Errorf("checkout %s no existe", checkoutID)
+	}
+	if c.Estado == StatusPending && time.Now().After(c.VenceEn) {
+		g.mu.Unlock()
+		return fmt.Errorf("la liga %s ya venció", checkoutID)
+	}
+	c.Estado = StatusPaid
+	conf := Confirmation{Referencia: c.ID, PedidoID: c.PedidoID, Estado: StatusPaid, Monto: c.Monto}
+	g.mu.Unlock()
+
+	body, err := json.Marshal(conf)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.callbackURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(g.secret, timestamp, body))
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error enviando confirmación de %s: %w", checkoutID, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("el webhook rechazó la confirmación de %s: %s", checkoutID, resp.Status)
+	}
+	return nil
+}
+
+// Handler sirve la página de pago simulada:
+//
+//	GET  /checkout/{id}         monto y botón de pago
+//	POST /checkout/{id}/pagar   paga y manda la confirmación
+func (g *FakeGateway) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/checkout/", func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, "/checkout/")
+		if r.Method == http.MethodPost && strings.HasSuffix(id, "/pagar") {
+			id = strings.TrimSuffix(id, "/pagar")
+			if err := g.Pay(r.Context(), id); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			g.render(w, id)
+			return
+		}
+		if r.Method != http.MethodGet {
+			http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		g.render(w, id)
+	})
+	return mux
+}
+
+func (g *FakeGateway) render(w http.ResponseWriter, id string) {
+	g.mu.Lock()
+	c, ok := g.checkouts[id]
+	var datos fakeCheckout
+	if ok {
+		datos = *c
+	}
+	g.mu.Unlock()
+	if !ok {
+		http.NotFound(w, nil)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := paginaCheckout.Execute(w, datos); err != nil {
+		log.Printf("Error mostrando checkout %s: %v\n", id, err)
+	}
+}
+
+var paginaCheckout = template.Must(template.New("checkout").Parse(`<!DOCTYPE html>
+<html lang="es"><head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Pago simulado</title></head>
+<body>
+<h1>{{.Descripcion}}</h1>
+<p>Total: ${{printf "%.2f" .Monto}}</p>
+{{if eq .Estado "pendiente"}}
+<form method="post" action="/checkout/{{.ID}}/pagar"><button type="submit">Pagar</button></form>
+{{else}}
+<p>Estado: {{.Estado}}</p>
+{{end}}
+</body></html>
+`))
+
+// StartFakeGateway levanta el procesador simulado en addr (por ejemplo
+// ":8090"; vacío usa un puerto local libre). publicURL es la dirección con la
+// que el cliente abre las ligas desde su teléfono; si está vacía las ligas
+// apuntan a la dirección local, que solo sirve para pruebas en la misma
+// máquina. Regresa el procesador y una función para detenerlo.
+func StartFakeGateway(addr, publicURL, callbackURL, secret string) (*FakeGateway, func() error, error) {
+	if addr == "" {
+		addr = "127.0.0.1:0"
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error starting fake payment gateway: %w", err)
+	}
+	if publicURL == "" {
+		publicURL = "http://" + ln.Addr().String()
+	}
+	g := NewFakeGateway(publicURL, callbackURL, secret)
+	srv := &http.Server{Handler: g.Handler()}
+	go srv.Serve(ln)
+	return g, srv.Close, nil
+}
+
+// RandomSecret genera un secreto de firma temporal para la pasarela simulada.
+func RandomSecret() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("error generando secreto de pagos: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,328 @@
+// Package payments define los métodos de pago que acepta el negocio y lleva el
+// estado de pago de cada pedido. Cada método es un PaymentProvider; el
+// Service decide cuáles se ofrecen en cada zona de reparto.
+package payments
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"example.com/whatsapp-integration/store"
+)
+
+// Métodos de pago. Coinciden con pedidos.metodo_pago.
+const (
+	MethodCash     = "efectivo"
+	MethodCard     = "tarjeta"
+	MethodTransfer = "transferencia"
+	MethodLink     = "liga_pago"
+)
+
+// Estados de pago de un pedido.
+const (
+	StatusPending  = "pendiente"
+	StatusPaid     = "pagado"
+	StatusRefunded = "reembolsado"
+	StatusExpired  = "vencido" // no se pagó a tiempo y el pedido se canceló
+)
+
+// Instructions es lo que se le dice al cliente para pagar.
+type Instructions struct {
+	Message   string
+	Reference string
+	URL       string
+	ExpiresAt *time.Time // límite para pagar; nil si no vence
+}
+
+// PaymentProvider es un método de pago.
+type PaymentProvider interface {
+	// Method es la clave del método (MethodCash, MethodCard, ...).
+	Method() string
+	// Label es el nombre que ve el cliente en el menú.
+	Label() string
+	// OnDelivery indica que se cobra al entregar; el pago se da por hecho con
+	// la entrega.
+	OnDelivery() bool
+	// Start prepara el cobro del pedido y regresa las instrucciones.
+	Start(ctx context.Context, pedido *store.Pedido, monto float64) (*Instructions, error)
+	// Refund devuelve el pago de un pedido cancelado.
+	Refund(ctx context.Context, pago *store.Pago) error
+}
+
+// Notifier avisa al cliente por WhatsApp.
+type Notifier interface {
+	SendMessage(to string, text string) error
+}
+
+// Canceller cancela un pedido junto con sus puntos y su ventana de entrega.
+// Lo implementa bot.StateMachine.
+type Canceller interface {
+	CancelarPedido(ctx context.Context, pedido *store.Pedido, motivo string) error
+}
+
+// Service registra los métodos disponibles y el estado de pago de los pedidos.
+type Service struct {
+	store     store.Store
+	providers map[string]PaymentProvider
+	defaults  []string            // métodos de las zonas sin configuración propia
+	byZone    map[string][]string // métodos por zona de reparto
+	sender    Notifier
+	canceller Canceller
+	client    *http.Client // descarga de comprobantes
+
+	receiptsDir string
+	mu          sync.Mutex
+}
+
+func NewService(s store.Store) *Service {
+	return &Service{
+		store:     s,
+		providers: make(map[string]PaymentProvider),
+		byZone:    make(map[string][]string),
+		client:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// SetSender habilita los avisos al cliente de pago recibido y de pedido
+// cancelado por falta de pago.
+func (s *Service) SetSender(sender Notifier) {
+	s.sender = sender
+}
+
+// SetCanceller define cómo se cancelan los pedidos con liga vencida. Sin él
+// solo se cambia el estado del pedido.
+func (s *Service) SetCanceller(c Canceller) {
+	s.canceller = c
+}
+
+// Register agrega un método de pago. Si no hay métodos por defecto
+// configurados, todos los registrados se ofrecen en todas las zonas.
+func (s *Service) Register(p PaymentProvider) {
+	s.providers[p.Method()] = p
+}
+
+// Provider regresa el método registrado con esa clave.
+func (s *Service) Provider(method string) (PaymentProvider, bool) {
+	p, ok := s.providers[method]
+	return p, ok
+}
+
+// SetDefaultMethods fija los métodos de las zonas sin configuración propia.
+func (s *Service) SetDefaultMethods(methods []string) {
+	s.defaults = methods
+}
+
+// SetZoneMethods fija los métodos que se ofrecen en una zona.
+func (s *Service) SetZoneMethods(zona string, methods []string) {
+	s.byZone[zona] = methods
+}
+
+// LoadConfig lee los métodos por zona de un JSON con la forma
+// {"default": ["efectivo", "tarjeta"], "zonas": {"Centro": ["efectivo", "liga_pago"]}}.
+func (s *Service) LoadConfig(ruta string) error {
+	data, err := os.ReadFile(ruta)
+	if err != nil {
+		return fmt.Errorf("error leyendo métodos de pago: %w", err)
+	}
+	var cfg struct {
+		Default []string            `json:"default"`
+		Zonas   map[string][]string `json:"zonas"`
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return fmt.Errorf("error decodificando métodos de pago: %w", err)
+	}
+	for _, methods := range append([][]string{cfg.Default}, valores(cfg.Zonas)...) {
+		for _, m := range methods {
+			if _, ok := s.providers[m]; !ok {
+				return fmt.Errorf("método de pago no habilitado en %s: %q", ruta, m)
+			}
+		}
+	}
+	if len(cfg.Default) > 0 {
+		s.defaults = cfg.Default
+	}
+	for zona, methods := range cfg.Zonas {
+		s.byZone[zona] = methods
+	}
+	return nil
+}
+
+// Available regresa los métodos que se ofrecen en la zona, en el orden
+// configurado.
+func (s *Service) Available(zona string) []PaymentProvider {
+	methods, ok := s.byZone[zona]
+	if !ok {
+		methods = s.defaults
+	}
+	if len(methods) == 0 {
+		for m := range s.providers {
+			methods = append(methods, m)
+		}
+		sort.Slice(methods, func(i, j int) bool { return orden(methods[i]) < orden(methods[j]) })
+	}
+
+	var disponibles []PaymentProvider
+	for _, m := range methods {
+		if p, ok := s.providers[m]; ok {
+			disponibles = append(disponibles, p)
+		}
+	}
+	return disponibles
+}
+
+// Label regresa el nombre para el cliente del método, o la clave si no está
+// registrado.
+func (s *Service) Label(method string) string {
+	if p, ok := s.providers[method]; ok {
+		return p.Label()
+	}
+	return method
+}
+
+// Start registra el pago pendiente del pedido ya guardado y regresa las
+// instrucciones para el cliente. Es idempotente por pedido.
+func (s *Service) Start(ctx context.Context, pedido *store.Pedido) (*store.Pago, *Instructions, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	provider, ok := s.providers[pedido.MetodoPago]
+	if !ok {
+		return nil, nil, fmt.Errorf("método de pago no habilitado: %q", pedido.MetodoPago)
+	}
+	pago, err := s.store.GetPagoPorPedido(ctx, pedido.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if pago != nil && pago.Metodo == provider.Method() {
+		return pago, &Instructions{Reference: pago.Referencia, URL: pago.URL}, nil
+	}
+
+	monto := pedido.TotalAPagar()
+	instrucciones, err := provider.Start(ctx, pedido, monto)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error iniciando pago %s del pedido %d: %w", provider.Method(), pedido.ID, err)
+	}
+
+	nuevo := pago == nil
+	if nuevo {
+		pago = &store.Pago{PedidoID: pedido.ID}
+	}
+	pago.Metodo = provider.Method()
+	pago.Estado = StatusPending
+	pago.Monto = monto
+	pago.Referencia = instrucciones.Reference
+	pago.URL = instrucciones.URL
+	pago.VenceEn = instrucciones.ExpiresAt
+	if nuevo {
+		err = s.store.CrearPago(ctx, pago)
+	} else {
+		err = s.store.ActualizarPago(ctx, pago)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return pago, instrucciones, nil
+}
+
+// MarkPaid marca pagado el pedido. Si ya estaba pagado no hace nada y regresa
+// false.
+func (s *Service) MarkPaid(ctx context.Context, pedidoID int, referencia string, ahora time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pago, err := s.store.GetPagoPorPedido(ctx, pedidoID)
+	if err != nil {
+		return false, err
+	}
+	if pago == nil {
+		return false, fmt.Errorf("el pedido %d no tiene pago registrado", pedidoID)
+	}
+	if pago.Estado != StatusPending {
+		return false, nil
+	}
+	pago.Estado = StatusPaid
+	pago.PagadoEn = &ahora
+	if referencia != "" {
+		pago.Referencia = referencia
+	}
+	if err := s.store.ActualizarPago(ctx, pago); err != nil {
+		return false, err
+	}
+	log.Printf("Pedido %d pagado con %s\n", pedidoID, pago.Metodo)
+	return true, nil
+}
+
+// Refund devuelve el pago de un pedido que ya estaba pagado.
+func (s *Service) Refund(ctx context.Context, pedidoID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pago, err := s.store.GetPagoPorPedido(ctx, pedidoID)
+	if err != nil || pago == nil || pago.Estado != StatusPaid {
+		return err
+	}
+	provider, ok := s.providers[pago.Metodo]
+	if !ok {
+		return fmt.Errorf("método de pago no habilitado: %q", pago.Metodo)
+	}
+	if err := provider.Refund(ctx, pago); err != nil {
+		return fmt.Errorf("error reembolsando pago del pedido %d: %w", pedidoID, err)
+	}
+	pago.Estado = StatusRefunded
+	if err := s.store.ActualizarPago(ctx, pago); err != nil {
+		return err
+	}
+	log.Printf("Pago del pedido %d reembolsado (%s, $%.2f)\n", pedidoID, pago.Metodo, pago.Monto)
+	return nil
+}
+
+// OrderStatusChanged implementa delivery.StatusObserver: los métodos contra
+// entrega quedan pagados al entregar y los pagos de pedidos cancelados o que
+// nunca llegaron se reembolsan.
+func (s *Service) OrderStatusChanged(ctx context.Context, pedido *store.Pedido, status string) error {
+	switch status {
+	case "entregado":
+		provider, ok := s.providers[pedido.MetodoPago]
+		if !ok || !provider.OnDelivery() {
+			return nil
+		}
+		if _, _, err := s.Start(ctx, pedido); err != nil {
+			return err
+		}
+		_, err := s.MarkPaid(ctx, pedido.ID, "", time.Now())
+		return err
+	case "cancelado", "no_entregado":
+		return s.Refund(ctx, pedido.ID)
+	}
+	return nil
+}
+
+// orden pone primero los métodos contra entrega cuando no hay configuración.
+func orden(method string) int {
+	switch method {
+	case MethodCash:
+		return 0
+	case MethodCard:
+		return 1
+	case MethodTransfer:
+		return 2
+	case MethodLink:
+		return 3
+	}
+	return 4
+}
+
+func valores(m map[string][]string) [][]string {
+	var v [][]string
+	for _, methods := range m {
+		v = append(v, methods)
+	}
+	return v
+}
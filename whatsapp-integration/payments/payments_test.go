@@ -0,0 +1,254 @@
+package payments
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/whatsapp-integration/store"
+)
+
+// storePagos solo implementa lo que usan los pagos en línea; el resto del
+// Store queda nil y haría panic si se llamara.
+type storePagos struct {
+	store.Store
+	pagos   map[int]*store.Pago
+	pedidos map[int]*store.Pedido
+}
+
+func (s *storePagos) GetPagoPorPedido(ctx context.Context, pedidoID int) (*store.Pago, error) {
+	if p, ok := s.pagos[pedidoID]; ok {
+		copia := *p
+		return &copia, nil
+	}
+	return nil, nil
+}
+
+func (s *storePagos) ActualizarPago(ctx context.Context, pago *store.Pago) error {
+	copia := *pago
+	s.pagos[pago.PedidoID] = &copia
+	return nil
+}
+
+func (s *storePagos) GetPagosPorEstado(ctx context.Context, metodo, estado string) ([]*store.Pago, error) {
+	var res []*store.Pago
+	for _, p := range s.pagos {
+		if (metodo == "" || p.Metodo == metodo) && p.Estado == estado {
+			copia := *p
+			res = append(res, &copia)
+		}
+	}
+	return res, nil
+}
+
+func (s *storePagos) GetPedido(ctx context.Context, id int) (*store.Pedido, error) {
+	return s.pedidos[id], nil
+}
+
+func (s *storePagos) ActualizarPedido(ctx context.Context, pedido *store.Pedido) error {
+	s.pedidos[pedido.ID] = pedido
+	return nil
+}
+
+func (s *storePagos) GetClientePorID(ctx context.Context, id int) (*store.Cliente, error) {
+	return &store.Cliente{ID: id, NumeroTelefono: "5215550000" + strconv.Itoa(id)}, nil
+}
+
+type notifierPrueba struct {
+	mensajes []string
+}
+
+func (n *notifierPrueba) SendMessage(to, text string) error {
+	n.mensajes = append(n.mensajes, text)
+	return nil
+}
+
+func TestVerificarFirma(t *testing.T) {
+	ahora := time.Unix(1_700_000_000, 0)
+	body := []byte(`{"referencia":"chk_1_1","pedido_id":1,"estado":"pagado","monto":250}`)
+	ts := func(d time.Duration) string { return strconv.FormatInt(ahora.Add(d).Unix(), 10) }
+
+	casos := []struct {
+		nombre    string
+		timestamp string
+		firma     string
+		body      []byte
+		valida    bool
+	}{
+		{"firma correcta", ts(0), Sign("secreto", ts(0), body), body, true},
+		{"dentro de la tolerancia", ts(-toleranciaFirma), Sign("secreto", ts(-toleranciaFirma), body), body, true},
+		{"reloj del procesador adelantado", ts(toleranciaFirma), Sign("secreto", ts(toleranciaFirma), body), body, true},
+		{"confirmación vieja", ts(-toleranciaFirma - time.Second), Sign("secreto", ts(-toleranciaFirma-time.Second), body), body, false},
+		{"confirmación del futuro", ts(toleranciaFirma + time.Second), Sign("secreto", ts(toleranciaFirma+time.Second), body), body, false},
+		{"otro secreto", ts(0), Sign("otro", ts(0), body), body, false},
+		{"cuerpo alterado", ts(0), Sign("secreto", ts(0), body), bytes.Replace(body, []byte("250"), []byte("1"), 1), false},
+		{"timestamp de otra firma", ts(-time.Second), Sign("secreto", ts(0), body), body, false},
+		{"timestamp inválido", "ayer", Sign("secreto", "ayer", body), body, false},
+		{"sin firma", ts(0), "", body, false},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			err := verificarFirma("secreto", c.timestamp, c.firma, c.body, ahora)
+			if (err == nil) != c.valida {
+				t.Fatalf("verificarFirma() = %v, se esperaba válida=%v", err, c.valida)
+			}
+		})
+	}
+}
+
+func TestMarkPaidEsIdempotente(t *testing.T) {
+	st := &storePagos{pagos: map[int]*store.Pago{
+		1: {PedidoID: 1, Metodo: "liga_pago", Estado: StatusPending, Monto: 250},
+	}}
+	s := NewService(st)
+	ctx := context.Background()
+	primero := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+
+	pagado, err := s.MarkPaid(ctx, 1, "chk_1_1", primero)
+	if err != nil || !pagado {
+		t.Fatalf("MarkPaid() = %v, %v; se esperaba true", pagado, err)
+	}
+	pagado, err = s.MarkPaid(ctx, 1, "chk_1_2", primero.Add(time.Minute))
+	if err != nil || pagado {
+		t.Fatalf("segundo MarkPaid() = %v, %v; se esperaba false", pagado, err)
+	}
+
+	pago := st.pagos[1]
+	if pago.Estado != StatusPaid || pago.Referencia != "chk_1_1" || !pago.PagadoEn.Equal(primero) {
+		t.Fatalf("pago = %+v, se esperaba el primer pago intacto", pago)
+	}
+	if _, err := s.MarkPaid(ctx, 2, "", primero); err == nil {
+		t.Fatal("se esperaba error para un pedido sin pago")
+	}
+}
+
+func TestConfirmPaymentRepetidaAvisaUnaVez(t *testing.T) {
+	st := &storePagos{
+		pagos:   map[int]*store.Pago{1: {PedidoID: 1, Metodo: "liga_pago", Estado: StatusPending, Monto: 250, Referencia: "chk_1_1"}},
+		pedidos: map[int]*store.Pedido{1: {ID: 1, ClienteID: 7, Estado: "pendiente"}},
+	}
+	s := NewService(st)
+	avisos := &notifierPrueba{}
+	s.SetSender(avisos)
+	ctx := context.Background()
+	conf := Confirmation{Referencia: "chk_1_1", PedidoID: 1, Estado: StatusPaid, Monto: 250}
+
+	for i := 0; i < 2; i++ {
+		if err := s.ConfirmPayment(ctx, conf, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(avisos.mensajes) != 1 {
+		t.Fatalf("avisos = %v, se esperaba uno", avisos.mensajes)
+	}
+
+	otroMonto := conf
+	otroMonto.Monto = 1
+	if err := s.ConfirmPayment(ctx, otroMonto, time.Now()); !errors.Is(err, ErrMismatch) {
+		t.Fatalf("ConfirmPayment() = %v, se esperaba ErrMismatch", err)
+	}
+}
+
+func TestCancelExpired(t *testing.T) {
+	ahora := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	vencio := ahora.Add(-time.Minute)
+	vigente := ahora.Add(time.Minute)
+
+	casos := []struct {
+		nombre       string
+		pago         store.Pago
+		estadoPedido string
+		estadoPago   string
+		cancelado    bool
+	}{
+		{"liga vencida", store.Pago{Estado: StatusPending, VenceEn: &vencio}, "pendiente", StatusExpired, true},
+		{"liga vigente", store.Pago{Estado: StatusPending, VenceEn: &vigente}, "pendiente", StatusPending, false},
+		{"sin vencimiento", store.Pago{Estado: StatusPending}, "pendiente", StatusPending, false},
+		{"ya en ruta", store.Pago{Estado: StatusPending, VenceEn: &vencio}, "en_ruta", StatusPending, false},
+		{"pagada a tiempo", store.Pago{Estado: StatusPaid, VenceEn: &vencio}, "pendiente", StatusPaid, false},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			pago := c.pago
+			pago.PedidoID, pago.Metodo, pago.Monto = 1, "liga_pago", 250
+			st := &storePagos{
+				pagos:   map[int]*store.Pago{1: &pago},
+				pedidos: map[int]*store.Pedido{1: {ID: 1, ClienteID: 7, Estado: c.estadoPedido}},
+			}
+			s := NewService(st)
+			avisos := &notifierPrueba{}
+			s.SetSender(avisos)
+
+			if err := s.CancelExpired(context.Background(), ahora); err != nil {
+				t.Fatal(err)
+			}
+			if got := st.pagos[1].Estado; got != c.estadoPago {
+				t.Errorf("estado del pago = %q, se esperaba %q", got, c.estadoPago)
+			}
+			if cancelado := st.pedidos[1].Estado == "cancelado"; cancelado != c.cancelado {
+				t.Errorf("pedido cancelado = %v, se esperaba %v", cancelado, c.cancelado)
+			}
+			if avisado := len(avisos.mensajes) > 0; avisado != c.cancelado {
+				t.Errorf("avisos = %v", avisos.mensajes)
+			}
+		})
+	}
+}
+
+func TestFakeGatewayConfirmaContraWebhook(t *testing.T) {
+	st := &storePagos{
+		pagos:   map[int]*store.Pago{1: {PedidoID: 1, Metodo: "liga_pago", Estado: StatusPending, Monto: 250}},
+		pedidos: map[int]*store.Pedido{1: {ID: 1, ClienteID: 7, Estado: "pendiente"}},
+	}
+	s := NewService(st)
+	webhook := httptest.NewServer(s.WebhookHandler("secreto"))
+	defer webhook.Close()
+
+	g, stop, err := StartFakeGateway("", "https://pagos.ejemplo.com/", webhook.URL, "secreto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+
+	ctx := context.Background()
+	checkout, err := g.CreateCheckout(ctx, CheckoutRequest{PedidoID: 1, Monto: 250, VenceEn: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(checkout.URL, "https://pagos.ejemplo.com/checkout/") {
+		t.Fatalf("liga = %q, se esperaba la dirección pública", checkout.URL)
+	}
+	if err := g.Pay(ctx, checkout.ID); err != nil {
+		t.Fatal(err)
+	}
+	if st.pagos[1].Estado != StatusPaid || st.pagos[1].Referencia != checkout.ID {
+		t.Fatalf("pago = %+v, se esperaba pagado con el folio del checkout", st.pagos[1])
+	}
+
+	vencida, err := g.CreateCheckout(ctx, CheckoutRequest{PedidoID: 2, Monto: 100, VenceEn: time.Now().Add(-time.Minute)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Pay(ctx, vencida.ID); err == nil {
+		t.Fatal("se esperaba error al pagar una liga vencida")
+	}
+}
+
+func TestWebhookRechazaFirmaInvalida(t *testing.T) {
+	s := NewService(&storePagos{})
+	body := `{"referencia":"chk_1_1","pedido_id":1,"estado":"pagado","monto":250}`
+	req := httptest.NewRequest(http.MethodPost, "/pagos/webhook", strings.NewReader(body))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(time.Now().Unix(), 10))
+	req.Header.Set(HeaderSignature, "00")
+	rec := httptest.NewRecorder()
+	s.WebhookHandler("secreto").ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, se esperaba %d", rec.Code, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,119 @@
+package payments
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"example.com/whatsapp-integration/store"
+)
+
+// CashProvider cobra en efectivo al entregar.
+type CashProvider struct{}
+
+func (CashProvider) Method() string   { return MethodCash }
+func (CashProvider) Label() string    { return "Efectivo al recibir" }
+func (CashProvider) OnDelivery() bool { return true }
+
+func (CashProvider) Start(ctx context.Context, pedido *store.Pedido, monto float64) (*Instructions, error) {
+	return &Instructions{Message: fmt.Sprintf("Pagarás $%.2f en efectivo al recibir tu pedido.", monto)}, nil
+}
+
+// Refund de efectivo lo entrega el personal; aquí solo queda registrado.
+func (CashProvider) Refund(ctx context.Context, pago *store.Pago) error {
+	log.Printf("Reembolso en efectivo pendiente de entregar: pedido %d, $%.2f\n", pago.PedidoID, pago.Monto)
+	return nil
+}
+
+// CardTerminalProvider cobra con la terminal del repartidor.
+type CardTerminalProvider struct{}
+
+func (CardTerminalProvider) Method() string   { return MethodCard }
+func (CardTerminalProvider) Label() string    { return "Tarjeta al recibir (terminal)" }
+func (CardTerminalProvider) OnDelivery() bool { return true }
+
+func (CardTerminalProvider) Start(ctx context.Context, pedido *store.Pedido, monto float64) (*Instructions, error) {
+	return &Instructions{Message: fmt.Sprintf("El repartidor llevará terminal para cobrar $%.2f con tarjeta.", monto)}, nil
+}
+
+// Refund de terminal se hace desde el portal del banco adquirente.
+func (CardTerminalProvider) Refund(ctx context.Context, pago *store.Pago) error {
+	log.Printf("Reembolso con tarjeta pendiente en la terminal: pedido %d, $%.2f\n", pago.PedidoID, pago.Monto)
+	return nil
+}
+
+// TransferProvider pide una transferencia SPEI a la cuenta del negocio con una
+// referencia por pedido.
+type TransferProvider struct {
+	Banco   string
+	CLABE   string
+	Titular string
+}
+
+func (TransferProvider) Method() string   { return MethodTransfer }
+func (TransferProvider) Label() string    { return "Transferencia SPEI" }
+func (TransferProvider) OnDelivery() bool { return false }
+
+func (t TransferProvider) Start(ctx context.Context, pedido *store.Pedido, monto float64) (*Instructions, error) {
+	if t.CLABE == "" {
+		return nil, fmt.Errorf("no hay CLABE configurada para transferencias")
+	}
+	referencia := ReferenciaPedido(pedido.ID)
+	msg := fmt.Sprintf("Transfiere $%.2f por SPEI a:\nBanco: %s\nCLABE: %s\nTitular: %s\nReferencia: *%s*\n\nUsa la referencia tal cual y envíanos aquí la foto o PDF de tu comprobante.",
+		monto, t.Banco, t.CLABE, t.Titular, referencia)
+	return &Instructions{Message: msg, Reference: referencia}, nil
+}
+
+// Refund de transferencia lo hace tesorería por SPEI.
+func (TransferProvider) Refund(ctx context.Context, pago *store.Pago) error {
+	log.Printf("Reembolso por transferencia pendiente: pedido %d, referencia %s, $%.2f\n", pago.PedidoID, pago.Referencia, pago.Monto)
+	return nil
+}
+
+// LinkProvider manda una liga de pago en línea generada por el procesador.
+// Si no se paga antes de Vigencia el pedido se cancela (ver CancelExpired).
+type LinkProvider struct {
+	Gateway  CheckoutGateway
+	Vigencia time.Duration
+}
+
+func (LinkProvider) Method() string   { return MethodLink }
+func (LinkProvider) Label() string    { return "Pago en línea (liga)" }
+func (LinkProvider) OnDelivery() bool { return false }
+
+func (l LinkProvider) Start(ctx context.Context, pedido *store.Pedido, monto float64) (*Instructions, error) {
+	if l.Gateway == nil {
+		return nil, fmt.Errorf("no hay procesador configurado para ligas de pago")
+	}
+	vigencia := l.Vigencia
+	if vigencia <= 0 {
+		vigencia = VigenciaLigaPorDefecto
+	}
+	vence := time.Now().Add(vigencia)
+	checkout, err := l.Gateway.CreateCheckout(ctx, CheckoutRequest{
+		PedidoID:    pedido.ID,
+		Monto:       monto,
+		Descripcion: fmt.Sprintf("Pedido #%d", pedido.ID),
+		VenceEn:     vence,
+	})
+	if err != nil {
+		return nil, err
+	}
+	msg := fmt.Sprintf("Paga $%.2f en línea aquí:\n%s\n\nLa liga vence en %d minutos; si no recibimos el pago, el pedido se cancela.",
+		monto, checkout.URL, int(vigencia.Minutes()))
+	return &Instructions{Message: msg, Reference: checkout.ID, URL: checkout.URL, ExpiresAt: &vence}, nil
+}
+
+// Refund de la liga lo pide al procesador con el folio del cobro.
+func (l LinkProvider) Refund(ctx context.Context, pago *store.Pago) error {
+	if l.Gateway == nil {
+		return fmt.Errorf("no hay procesador configurado para ligas de pago")
+	}
+	return l.Gateway.Refund(ctx, pago.Referencia, pago.Monto)
+}
+
+// ReferenciaPedido es la referencia numérica de 7 dígitos del pedido para SPEI.
+func ReferenciaPedido(pedidoID int) string {
+	return fmt.Sprintf("%07d", pedidoID)
+}
@@ -0,0 +1,208 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"example.com/whatsapp-integration/store"
+)
+
+// Estados de un comprobante de transferencia.
+const (
+	ReceiptPending  = "pendiente"
+	ReceiptApproved = "aprobado"
+	ReceiptRejected = "rechazado"
+)
+
+// DirectorioComprobantesPorDefecto es donde se guardan los comprobantes si no
+// se configura COMPROBANTES_DIR.
+const DirectorioComprobantesPorDefecto = "comprobantes"
+
+// maxComprobanteBytes limita el tamaño de la foto o PDF del comprobante.
+const maxComprobanteBytes = 10 << 20
+
+var (
+	// ErrNoPendingTransfer indica que el pedido no espera pago por transferencia.
+	ErrNoPendingTransfer = errors.New("el pedido no tiene una transferencia pendiente")
+	// ErrReceiptType indica un comprobante que no es imagen ni PDF.
+	ErrReceiptType = errors.New("tipo de comprobante no aceptado")
+)
+
+// extensionesComprobante son los tipos de archivo aceptados como comprobante.
+var extensionesComprobante = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/webp":      ".webp",
+	"image/heic":      ".heic",
+	"application/pdf": ".pdf",
+}
+
+// ValidarCLABE revisa que la CLABE tenga 18 dígitos y que el dígito de
+// control sea correcto.
+func ValidarCLABE(clabe string) error {
+	if len(clabe) != 18 {
+		return fmt.Errorf("la CLABE debe tener 18 dígitos")
+	}
+	pesos := [3]int{3, 7, 1}
+	suma := 0
+	for i := 0; i < 17; i++ {
+		d := clabe[i]
+		if d < '0' || d > '9' {
+			return fmt.Errorf("la CLABE solo puede tener dígitos")
+		}
+		suma += int(d-'0') * pesos[i%3] % 10
+	}
+	if control := byte((10-suma%10)%10) + '0'; clabe[17] != control {
+		return fmt.Errorf("dígito de control de la CLABE inválido")
+	}
+	return nil
+}
+
+// SetReceiptsDir fija el directorio de los comprobantes que mandan los clientes.
+func (s *Service) SetReceiptsDir(dir string) {
+	s.receiptsDir = dir
+}
+
+// PendingTransfer regresa el pedido más reciente del cliente que espera pago
+// por transferencia, o nil si no hay.
+func (s *Service) PendingTransfer(ctx context.Context, clienteID int) (*store.Pedido, error) {
+	pendientes, err := s.store.GetPagosPorEstado(ctx, MethodTransfer, StatusPending)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(pendientes) - 1; i >= 0; i-- {
+		pedido, err := s.store.GetPedido(ctx, pendientes[i].PedidoID)
+		if err != nil {
+			return nil, err
+		}
+		if pedido != nil && pedido.ClienteID == clienteID && pedido.Estado != "cancelado" {
+			return pedido, nil
+		}
+	}
+	return nil, nil
+}
+
+// SubmitReceipt descarga el comprobante que mandó el cliente y lo deja en la
+// cola de verificación del personal.
+func (s *Service) SubmitReceipt(ctx context.Context, pedido *store.Pedido, mediaURL, mimeType string) (*store.ComprobantePago, error) {
+	pago, err := s.store.GetPagoPorPedido(ctx, pedido.ID)
+	if err != nil {
+		return nil, err
+	}
+	if pago == nil || pago.Metodo != MethodTransfer || pago.Estado != StatusPending {
+		return nil, ErrNoPendingTransfer
+	}
+	mimeType = strings.ToLower(strings.TrimSpace(strings.Split(mimeType, ";")[0]))
+	ext, ok := extensionesComprobante[mimeType]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrReceiptType, mimeType)
+	}
+
+	archivo, err := s.descargarComprobante(ctx, pedido.ID, mediaURL, ext)
+	if err != nil {
+		return nil, fmt.Errorf("error guardando comprobante del pedido %d: %w", pedido.ID, err)
+	}
+	comprobante := &store.ComprobantePago{
+		PedidoID:  pedido.ID,
+		ClienteID: pedido.ClienteID,
+		Archivo:   archivo,
+		MimeType:  mimeType,
+		Estado:    ReceiptPending,
+	}
+	if err := s.store.CrearComprobantePago(ctx, comprobante); err != nil {
+		return nil, err
+	}
+	log.Printf("Comprobante %d del pedido %d en espera de verificación\n", comprobante.ID, pedido.ID)
+	return comprobante, nil
+}
+
+func (s *Service) descargarComprobante(ctx context.Context, pedidoID int, mediaURL, ext string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, mediaURL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("descarga del comprobante: %s", resp.Status)
+	}
+
+	dir := s.receiptsDir
+	if dir == "" {
+		dir = DirectorioComprobantesPorDefecto
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	ruta := filepath.Join(dir, fmt.Sprintf("comprobante-%d-%d%s", pedidoID, time.Now().UnixNano(), ext))
+	f, err := os.Create(ruta)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	n, err := io.Copy(f, io.LimitReader(resp.Body, maxComprobanteBytes+1))
+	if err == nil && n > maxComprobanteBytes {
+		err = fmt.Errorf("el comprobante excede %d MB", maxComprobanteBytes>>20)
+	}
+	if err != nil {
+		f.Close()
+		os.Remove(ruta)
+		return "", err
+	}
+	return ruta, nil
+}
+
+// ReviewReceipt registra la decisión del personal sobre un comprobante. Al
+// aprobarlo el pedido queda pagado; en ambos casos se avisa al cliente.
+func (s *Service) ReviewReceipt(ctx context.Context, id int, aprobar bool, operador, nota string, ahora time.Time) (*store.ComprobantePago, error) {
+	comprobante, err := s.store.GetComprobantePago(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if comprobante == nil {
+		return nil, fmt.Errorf("comprobante %d no encontrado", id)
+	}
+	if comprobante.Estado != ReceiptPending {
+		return nil, fmt.Errorf("el comprobante %d ya fue revisado (%s)", id, comprobante.Estado)
+	}
+
+	comprobante.Estado = ReceiptRejected
+	if aprobar {
+		comprobante.Estado = ReceiptApproved
+	}
+	comprobante.RevisadoPor = operador
+	comprobante.Nota = nota
+	comprobante.RevisadoEn = &ahora
+	if err := s.store.ActualizarComprobantePago(ctx, comprobante); err != nil {
+		return nil, err
+	}
+
+	if !aprobar {
+		msg := fmt.Sprintf("No pudimos verificar el comprobante de transferencia del pedido #%d.", comprobante.PedidoID)
+		if nota != "" {
+			msg += " Motivo: " + nota + "."
+		}
+		s.avisar(ctx, comprobante.PedidoID, msg+" Por favor, envía otro comprobante o comunícate con nosotros.")
+		return comprobante, nil
+	}
+	pagado, err := s.MarkPaid(ctx, comprobante.PedidoID, "", ahora)
+	if err != nil {
+		return nil, err
+	}
+	if pagado {
+		s.avisar(ctx, comprobante.PedidoID, fmt.Sprintf("Verificamos tu transferencia del pedido #%d. ¡Gracias por tu pago!", comprobante.PedidoID))
+	}
+	return comprobante, nil
+}
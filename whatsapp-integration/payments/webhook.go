@@ -0,0 +1,141 @@
+package payments
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Encabezados de la confirmación firmada. La firma es HMAC-SHA256 en
+// hexadecimal de "<timestamp>.<cuerpo>" con el secreto compartido.
+const (
+	HeaderSignature = "X-Pagos-Firma"
+	HeaderTimestamp = "X-Pagos-Timestamp"
+)
+
+// toleranciaFirma es la antigüedad máxima de una confirmación; evita que se
+// reenvíe una confirmación capturada.
+const toleranciaFirma = 5 * time.Minute
+
+// Confirmation es el aviso del procesador de que una liga se pagó.
+type Confirmation struct {
+	Referencia string  `json:"referencia"` // folio del checkout
+	PedidoID   int     `json:"pedido_id"`
+	Estado     string  `json:"estado"` // solo "pagado" cambia el pedido
+	Monto      float64 `json:"monto"`
+}
+
+var (
+	// ErrUnknownPayment indica que el pedido no tiene pago registrado.
+	ErrUnknownPayment = errors.New("el pedido no tiene pago registrado")
+	// ErrMismatch indica que el folio o el monto no coinciden con el pago.
+	ErrMismatch = errors.New("la confirmación no coincide con el pago")
+)
+
+// Sign calcula la firma de una confirmación.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func verificarFirma(secret, timestamp, firma string, body []byte, ahora time.Time) error {
+	segundos, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("timestamp inválido")
+	}
+	if d := ahora.Sub(time.Unix(segundos, 0)); d > toleranciaFirma || d < -toleranciaFirma {
+		return fmt.Errorf("confirmación fuera de tiempo")
+	}
+	if !hmac.Equal([]byte(firma), []byte(Sign(secret, timestamp, body))) {
+		return fmt.Errorf("firma inválida")
+	}
+	return nil
+}
+
+// ConfirmPayment aplica la confirmación del procesador: valida folio y monto,
+// marca pagado el pedido y avisa al cliente. Las confirmaciones repetidas no
+// hacen nada.
+func (s *Service) ConfirmPayment(ctx context.Context, c Confirmation, ahora time.Time) error {
+	if c.Estado != StatusPaid {
+		log.Printf("Confirmación del pedido %d con estado %q ignorada\n", c.PedidoID, c.Estado)
+		return nil
+	}
+	pago, err := s.store.GetPagoPorPedido(ctx, c.PedidoID)
+	if err != nil {
+		return err
+	}
+	if pago == nil {
+		return ErrUnknownPayment
+	}
+	if pago.Referencia != "" && pago.Referencia != c.Referencia {
+		return fmt.Errorf("%w: folio %q, se esperaba %q", ErrMismatch, c.Referencia, pago.Referencia)
+	}
+	if math.Abs(pago.Monto-c.Monto) >= 0.01 {
+		return fmt.Errorf("%w: monto %.2f, se esperaba %.2f", ErrMismatch, c.Monto, pago.Monto)
+	}
+	if pago.Estado == StatusExpired {
+		log.Printf("ADVERTENCIA: pago tardío del pedido %d ya cancelado (%s, $%.2f); requiere reembolso manual\n", c.PedidoID, c.Referencia, c.Monto)
+		return nil
+	}
+
+	pagado, err := s.MarkPaid(ctx, c.PedidoID, c.Referencia, ahora)
+	if err != nil || !pagado {
+		return err
+	}
+	s.avisar(ctx, c.PedidoID, fmt.Sprintf("¡Recibimos tu pago de $%.2f del pedido #%d! Gracias.", pago.Monto, c.PedidoID))
+	return nil
+}
+
+// WebhookHandler recibe las confirmaciones firmadas del procesador:
+//
+//	POST /pagos/webhook  Confirmation, con HeaderTimestamp y HeaderSignature
+func (s *Service) WebhookHandler(secret string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+		if err != nil {
+			http.Error(w, "Petición inválida", http.StatusBadRequest)
+			return
+		}
+		if err := verificarFirma(secret, r.Header.Get(HeaderTimestamp), r.Header.Get(HeaderSignature), body, time.Now()); err != nil {
+			log.Printf("Confirmación de pago rechazada: %v\n", err)
+			http.Error(w, "No autorizado", http.StatusUnauthorized)
+			return
+		}
+		var c Confirmation
+		if err := json.Unmarshal(body, &c); err != nil || c.PedidoID == 0 {
+			http.Error(w, "Petición inválida", http.StatusBadRequest)
+			return
+		}
+
+		err = s.ConfirmPayment(r.Context(), c, time.Now())
+		switch {
+		case errors.Is(err, ErrUnknownPayment):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, ErrMismatch):
+			log.Printf("Confirmación de pago del pedido %d rechazada: %v\n", c.PedidoID, err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case err != nil:
+			log.Printf("Error confirmando pago del pedido %d: %v\n", c.PedidoID, err)
+			http.Error(w, "Error interno", http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+}
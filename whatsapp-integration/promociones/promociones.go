@@ -0,0 +1,256 @@
+// Package promociones valida los códigos de descuento que el cliente escribe
+// antes de confirmar su pedido y calcula el descuento que le corresponde.
+package promociones
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"example.com/whatsapp-integration/store"
+)
+
+// Tipos de descuento.
+const (
+	TipoPorcentaje = "porcentaje"
+	TipoMontoFijo  = "monto_fijo"
+	TipoPorLitro   = "por_litro"
+)
+
+// ErrNoAplica es el motivo, en palabras para el cliente, por el que un código
+// no se puede usar en el pedido.
+type ErrNoAplica struct {
+	Motivo string
+}
+
+func (e *ErrNoAplica) Error() string {
+	return e.Motivo
+}
+
+func noAplica(motivo string) error {
+	return &ErrNoAplica{Motivo: motivo}
+}
+
+// Servicio consulta las promociones y sus usos.
+type Servicio struct {
+	store store.Store
+	loc   *time.Location // zona horaria de las fechas de vigencia del archivo
+}
+
+func NewServicio(s store.Store, loc *time.Location) *Servicio {
+	if loc == nil {
+		loc = time.Local
+	}
+	return &Servicio{store: s, loc: loc}
+}
+
+// Aplicar valida el código para el cliente y el pedido y regresa la promoción
+// con el descuento sobre base (lo que queda por pagar). zona es la zona de
+// reparto del pedido; vacía si no se pudo determinar. Si el código no aplica
+// el error es *ErrNoAplica.
+func (s *Servicio) Aplicar(ctx context.Context, codigo string, cliente *store.Cliente, pedido *store.Pedido, zona string, base float64, ahora time.Time) (*store.Promocion, float64, error) {
+	promo, err := s.store.GetPromocionPorCodigo(ctx, codigo)
+	if err != nil {
+		return nil, 0, err
+	}
+	if promo == nil || !promo.Activa {
+		return nil, 0, noAplica("El código no existe o ya no está activo.")
+	}
+	if promo.VigenteDesde != nil && ahora.Before(*promo.VigenteDesde) {
+		return nil, 0, noAplica(fmt.Sprintf("El código es válido a partir del %s.", promo.VigenteDesde.Format("02/01/2006")))
+	}
+	if promo.VigenteHasta != nil && ahora.After(*promo.VigenteHasta) {
+		return nil, 0, noAplica("El código ya venció.")
+	}
+	if zonas := listaZonas(promo.Zonas); len(zonas) > 0 && !contiene(zonas, zona) {
+		return nil, 0, noAplica("El código no es válido en tu zona de entrega.")
+	}
+	if promo.SoloPrimerPedido {
+		primero, err := s.esPrimerPedido(ctx, cliente.ID)
+		if err != nil {
+			return nil, 0, err
+		}
+		if !primero {
+			return nil, 0, noAplica("El código solo es válido en tu primer pedido.")
+		}
+	}
+	if promo.MaxUsosCliente > 0 {
+		usos, err := s.store.ContarUsosPromocion(ctx, promo.ID, cliente.ID)
+		if err != nil {
+			return nil, 0, err
+		}
+		if usos >= promo.MaxUsosCliente {
+			return nil, 0, noAplica("Ya usaste este código el máximo de veces permitido.")
+		}
+	}
+
+	descuento := Calcular(promo, pedido, base)
+	if descuento <= 0 {
+		return nil, 0, noAplica("El código no aplica a este tipo de pedido.")
+	}
+	return promo, descuento, nil
+}
+
+// Calcular regresa el descuento de la promoción, sin pasar de base.
+func Calcular(promo *store.Promocion, pedido *store.Pedido, base float64) float64 {
+	var descuento float64
+	switch promo.Tipo {
+	case TipoPorcentaje:
+		descuento = base * promo.Valor / 100
+	case TipoMontoFijo:
+		descuento = promo.Valor
+	case TipoPorLitro:
+		descuento = pedido.CantidadLitros * promo.Valor
+	}
+	descuento = math.Round(descuento*100) / 100
+	if descuento > base {
+		descuento = base
+	}
+	return descuento
+}
+
+// RegistrarUso liga la promoción con el pedido ya guardado. Los usos en
+// pedidos cancelados no cuentan para el límite por cliente.
+func (s *Servicio) RegistrarUso(ctx context.Context, promo *store.Promocion, pedido *store.Pedido, descuento float64) error {
+	return s.store.RegistrarUsoPromocion(ctx, &store.UsoPromocion{
+		PromocionID: promo.ID,
+		ClienteID:   pedido.ClienteID,
+		PedidoID:    pedido.ID,
+		Descuento:   descuento,
+	})
+}
+
+// esPrimerPedido es verdadero si el cliente no tiene pedidos que sigan en pie.
+func (s *Servicio) esPrimerPedido(ctx context.Context, clienteID int) (bool, error) {
+	pedidos, err := s.store.GetPedidos(ctx, store.FiltroPedidos{ClienteID: clienteID})
+	if err != nil {
+		return false, err
+	}
+	for _, p := range pedidos {
+		if p.Estado != "cancelado" {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// CargarPromocionesDesdeArchivo da de alta los códigos del JSON que todavía no
+// existen. Los existentes se administran en la base.
+func (s *Servicio) CargarPromocionesDesdeArchivo(ctx context.Context, ruta string) error {
+	data, err := os.ReadFile(ruta)
+	if err != nil {
+		return fmt.Errorf("error leyendo promociones: %w", err)
+	}
+
+	var promociones []struct {
+		Codigo           string   `json:"codigo"`
+		Descripcion      string   `json:"descripcion"`
+		Tipo             string   `json:"tipo"`
+		Valor            float64  `json:"valor"`
+		SoloPrimerPedido bool     `json:"soloPrimerPedido"`
+		VigenteDesde     string   `json:"vigenteDesde"` // 2006-01-02
+		VigenteHasta     string   `json:"vigenteHasta"`
+		MaxUsosCliente   int      `json:"maxUsosCliente"`
+		Zonas            []string `json:"zonas"`
+	}
+	if err := json.Unmarshal(data, &promociones); err != nil {
+		return fmt.Errorf("error decodificando promociones: %w", err)
+	}
+
+	nuevas := 0
+	for _, p := range promociones {
+		promo := &store.Promocion{
+			Codigo:           strings.ToUpper(strings.TrimSpace(p.Codigo)),
+			Descripcion:      p.Descripcion,
+			Tipo:             p.Tipo,
+			Valor:            p.Valor,
+			SoloPrimerPedido: p.SoloPrimerPedido,
+			MaxUsosCliente:   p.MaxUsosCliente,
+			Zonas:            strings.Join(p.Zonas, ","),
+			Activa:           true,
+		}
+		if err := Validar(promo); err != nil {
+			return err
+		}
+		if promo.VigenteDesde, err = parseFecha(p.VigenteDesde, false, s.loc); err != nil {
+			return fmt.Errorf("vigenteDesde inválida en %s: %w", promo.Codigo, err)
+		}
+		if promo.VigenteHasta, err = parseFecha(p.VigenteHasta, true, s.loc); err != nil {
+			return fmt.Errorf("vigenteHasta inválida en %s: %w", promo.Codigo, err)
+		}
+
+		existente, err := s.store.GetPromocionPorCodigo(ctx, promo.Codigo)
+		if err != nil {
+			return err
+		}
+		if existente != nil {
+			continue
+		}
+		if err := s.store.CrearPromocion(ctx, promo); err != nil {
+			return err
+		}
+		nuevas++
+	}
+	log.Printf("Promociones cargadas desde %s: %d nuevas\n", ruta, nuevas)
+	return nil
+}
+
+// Validar revisa que la promoción tenga código, un tipo conocido y valor.
+func Validar(promo *store.Promocion) error {
+	if promo.Codigo == "" || strings.ContainsAny(promo.Codigo, " \t\n") {
+		return errors.New("el código de promoción no puede estar vacío ni tener espacios")
+	}
+	switch promo.Tipo {
+	case TipoPorcentaje:
+		if promo.Valor <= 0 || promo.Valor > 100 {
+			return fmt.Errorf("porcentaje inválido en %s: %.2f", promo.Codigo, promo.Valor)
+		}
+	case TipoMontoFijo, TipoPorLitro:
+		if promo.Valor <= 0 {
+			return fmt.Errorf("valor inválido en %s: %.2f", promo.Codigo, promo.Valor)
+		}
+	default:
+		return fmt.Errorf("tipo de promoción desconocido en %s: %q", promo.Codigo, promo.Tipo)
+	}
+	return nil
+}
+
+// parseFecha lee una fecha AAAA-MM-DD; la de fin cubre el día completo.
+func parseFecha(valor string, finDelDia bool, loc *time.Location) (*time.Time, error) {
+	if valor == "" {
+		return nil, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", valor, loc)
+	if err != nil {
+		return nil, err
+	}
+	if finDelDia {
+		t = t.Add(24*time.Hour - time.Second)
+	}
+	return &t, nil
+}
+
+func listaZonas(zonas string) []string {
+	var lista []string
+	for _, z := range strings.Split(zonas, ",") {
+		if z = strings.TrimSpace(z); z != "" {
+			lista = append(lista, z)
+		}
+	}
+	return lista
+}
+
+func contiene(lista []string, valor string) bool {
+	for _, v := range lista {
+		if strings.EqualFold(v, valor) {
+			return true
+		}
+	}
+	return false
+}
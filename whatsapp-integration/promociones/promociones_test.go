@@ -0,0 +1,193 @@
+package promociones
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"example.com/whatsapp-integration/store"
+)
+
+func TestCalcular(t *testing.T) {
+	pedido := &store.Pedido{CantidadLitros: 120}
+	casos := []struct {
+		nombre    string
+		promo     store.Promocion
+		base      float64
+		descuento float64
+	}{
+		{"porcentaje", store.Promocion{Tipo: TipoPorcentaje, Valor: 15}, 1000, 150},
+		{"porcentaje redondea a centavos", store.Promocion{Tipo: TipoPorcentaje, Valor: 7}, 333.33, 23.33},
+		{"monto fijo", store.Promocion{Tipo: TipoMontoFijo, Valor: 100}, 1000, 100},
+		{"monto fijo no pasa de la base", store.Promocion{Tipo: TipoMontoFijo, Valor: 500}, 320, 320},
+		{"por litro", store.Promocion{Tipo: TipoPorLitro, Valor: 0.5}, 1000, 60},
+		{"tipo desconocido", store.Promocion{Tipo: "regalo", Valor: 100}, 1000, 0},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			if got := Calcular(&c.promo, pedido, c.base); got != c.descuento {
+				t.Fatalf("Calcular() = %.2f, se esperaba %.2f", got, c.descuento)
+			}
+		})
+	}
+}
+
+func TestValidar(t *testing.T) {
+	casos := []struct {
+		nombre string
+		promo  store.Promocion
+		valida bool
+	}{
+		{"porcentaje válido", store.Promocion{Codigo: "GAS10", Tipo: TipoPorcentaje, Valor: 10}, true},
+		{"porcentaje mayor a 100", store.Promocion{Codigo: "GAS10", Tipo: TipoPorcentaje, Valor: 120}, false},
+		{"monto fijo en cero", store.Promocion{Codigo: "GAS10", Tipo: TipoMontoFijo}, false},
+		{"código con espacios", store.Promocion{Codigo: "GAS 10", Tipo: TipoMontoFijo, Valor: 50}, false},
+		{"código vacío", store.Promocion{Tipo: TipoMontoFijo, Valor: 50}, false},
+		{"tipo desconocido", store.Promocion{Codigo: "GAS10", Tipo: "regalo", Valor: 1}, false},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			if err := Validar(&c.promo); (err == nil) != c.valida {
+				t.Fatalf("Validar() = %v, válida esperada: %v", err, c.valida)
+			}
+		})
+	}
+}
+
+// TestAplicarLimitePorCliente usa el código hasta su límite por cliente; los
+// usos en pedidos cancelados no cuentan y el límite no afecta a otros clientes.
+func TestAplicarLimitePorCliente(t *testing.T) {
+	db, err := store.NewSQLiteStore(store.Config{Database: filepath.Join(t.TempDir(), "promociones.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	ahora := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	promo := &store.Promocion{Codigo: "DOSVECES", Tipo: TipoMontoFijo, Valor: 50, MaxUsosCliente: 2, Activa: true}
+	if err := db.CrearPromocion(ctx, promo); err != nil {
+		t.Fatal(err)
+	}
+	ana := &store.Cliente{NumeroTelefono: "5215550000001", Nombre: "Ana"}
+	beto := &store.Cliente{NumeroTelefono: "5215550000002", Nombre: "Beto"}
+	for _, c := range []*store.Cliente{ana, beto} {
+		if err := db.CrearCliente(ctx, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := NewServicio(db, time.UTC)
+	usar := func(cliente *store.Cliente) (*store.Pedido, error) {
+		pedido := &store.Pedido{ClienteID: cliente.ID, TipoServicio: "estacionario", CantidadLitros: 100, Estado: "pendiente"}
+		p, descuento, err := s.Aplicar(ctx, promo.Codigo, cliente, pedido, "", 1000, ahora)
+		if err != nil {
+			return nil, err
+		}
+		if err := db.CrearPedido(ctx, pedido); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.RegistrarUso(ctx, p, pedido, descuento); err != nil {
+			t.Fatal(err)
+		}
+		return pedido, nil
+	}
+
+	primero, err := usar(ana)
+	if err != nil {
+		t.Fatalf("primer uso: %v", err)
+	}
+	if _, err := usar(ana); err != nil {
+		t.Fatalf("segundo uso: %v", err)
+	}
+	var noAplica *ErrNoAplica
+	if _, err := usar(ana); !errors.As(err, &noAplica) {
+		t.Fatalf("tercer uso: se esperaba ErrNoAplica, se obtuvo %v", err)
+	}
+	if _, err := usar(beto); err != nil {
+		t.Fatalf("el límite de Ana no debe aplicar a Beto: %v", err)
+	}
+
+	primero.Estado = "cancelado"
+	if err := db.ActualizarPedido(ctx, primero); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := usar(ana); err != nil {
+		t.Fatalf("el uso cancelado no debe contar: %v", err)
+	}
+}
+
+func TestAplicarRestricciones(t *testing.T) {
+	db, err := store.NewSQLiteStore(store.Config{Database: filepath.Join(t.TempDir(), "promociones.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	ahora := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	desde := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	hasta := time.Date(2026, 3, 1, 23, 59, 59, 0, time.UTC)
+	for _, p := range []*store.Promocion{
+		{Codigo: "FUTURA", Tipo: TipoMontoFijo, Valor: 50, VigenteDesde: &desde, Activa: true},
+		{Codigo: "VENCIDA", Tipo: TipoMontoFijo, Valor: 50, VigenteHasta: &hasta, Activa: true},
+		{Codigo: "INACTIVA", Tipo: TipoMontoFijo, Valor: 50},
+		{Codigo: "NORTE", Tipo: TipoMontoFijo, Valor: 50, Zonas: "Norte, Centro", Activa: true},
+		{Codigo: "BIENVENIDA", Tipo: TipoPorcentaje, Valor: 10, SoloPrimerPedido: true, Activa: true},
+	} {
+		if err := db.CrearPromocion(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	nuevo := &store.Cliente{NumeroTelefono: "5215550000001", Nombre: "Ana"}
+	frecuente := &store.Cliente{NumeroTelefono: "5215550000002", Nombre: "Beto"}
+	for _, c := range []*store.Cliente{nuevo, frecuente} {
+		if err := db.CrearCliente(ctx, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	anterior := &store.Pedido{ClienteID: frecuente.ID, TipoServicio: "estacionario", CantidadLitros: 100, Estado: "entregado"}
+	if err := db.CrearPedido(ctx, anterior); err != nil {
+		t.Fatal(err)
+	}
+
+	casos := []struct {
+		nombre    string
+		codigo    string
+		cliente   *store.Cliente
+		zona      string
+		descuento float64 // 0 si no aplica
+	}{
+		{"no existe", "NOEXISTE", nuevo, "", 0},
+		{"inactiva", "INACTIVA", nuevo, "", 0},
+		{"antes de su vigencia", "FUTURA", nuevo, "", 0},
+		{"vencida", "VENCIDA", nuevo, "", 0},
+		{"zona permitida sin importar mayúsculas", "NORTE", nuevo, "centro", 50},
+		{"fuera de zona", "NORTE", nuevo, "Sur", 0},
+		{"zona desconocida", "NORTE", nuevo, "", 0},
+		{"primer pedido", "BIENVENIDA", nuevo, "", 100},
+		{"ya tiene pedidos", "BIENVENIDA", frecuente, "", 0},
+	}
+	s := NewServicio(db, time.UTC)
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			pedido := &store.Pedido{ClienteID: c.cliente.ID, TipoServicio: "estacionario", CantidadLitros: 100}
+			_, descuento, err := s.Aplicar(ctx, c.codigo, c.cliente, pedido, c.zona, 1000, ahora)
+			if c.descuento == 0 {
+				var noAplica *ErrNoAplica
+				if !errors.As(err, &noAplica) {
+					t.Fatalf("se esperaba ErrNoAplica, se obtuvo %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if descuento != c.descuento {
+				t.Fatalf("descuento = %.2f, se esperaba %.2f", descuento, c.descuento)
+			}
+		})
+	}
+}
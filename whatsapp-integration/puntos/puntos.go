@@ -0,0 +1,294 @@
+// Package puntos lleva el monedero de puntos de lealtad de cada cliente: se
+// acumulan al entregar un pedido, se canjean como descuento al confirmar uno
+// nuevo y el canje se repone si ese pedido se cancela.
+package puntos
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"sync"
+
+	"example.com/whatsapp-integration/store"
+)
+
+// Tipos de movimiento del monedero.
+const (
+	TipoAcumulacion = "acumulacion"
+	TipoCanje       = "canje"
+	TipoReverso     = "reverso"
+	TipoAjuste      = "ajuste"
+	TipoReferido    = "referido"
+	TipoRetencion   = "retencion" // puntos de un pedido en disputa
+)
+
+// Config define cuántos puntos da cada compra y cuánto vale cada punto.
+type Config struct {
+	PorLitro    float64 // puntos por litro entregado
+	PorPeso     float64 // puntos por peso pagado
+	ValorPunto  float64 // pesos de descuento por punto
+	MinimoCanje int     // saldo mínimo para poder canjear
+}
+
+// ConfigDesdeEnv lee PUNTOS_POR_LITRO (1), PUNTOS_POR_PESO (0), PUNTOS_VALOR
+// (0.10 pesos) y PUNTOS_MINIMO_CANJE (100).
+func ConfigDesdeEnv() Config {
+	cfg := Config{PorLitro: 1, ValorPunto: 0.10, MinimoCanje: 100}
+	if v, err := strconv.ParseFloat(os.Getenv("PUNTOS_POR_LITRO"), 64); err == nil && v >= 0 {
+		cfg.PorLitro = v
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("PUNTOS_POR_PESO"), 64); err == nil && v >= 0 {
+		cfg.PorPeso = v
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("PUNTOS_VALOR"), 64); err == nil && v > 0 {
+		cfg.ValorPunto = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("PUNTOS_MINIMO_CANJE")); err == nil && v >= 0 {
+		cfg.MinimoCanje = v
+	}
+	return cfg
+}
+
+// Notificador avisa al cliente los puntos ganados.
+type Notificador interface {
+	SendMessage(to string, text string) error
+}
+
+// Servicio registra los movimientos del monedero. Los movimientos ligados a un
+// pedido son idempotentes: entregar o cancelar dos veces no duplica puntos.
+type Servicio struct {
+	store  store.Store
+	cfg    Config
+	sender Notificador
+	mu     sync.Mutex
+}
+
+func NewServicio(s store.Store, cfg Config) *Servicio {
+	if cfg.ValorPunto <= 0 {
+		cfg.ValorPunto = 0.10
+	}
+	return &Servicio{store: s, cfg: cfg}
+}
+
+// SetSender habilita el aviso por WhatsApp de los puntos ganados.
+func (s *Servicio) SetSender(sender Notificador) {
+	s.sender = sender
+}
+
+// Config regresa la configuración vigente.
+func (s *Servicio) Config() Config {
+	return s.cfg
+}
+
+// Saldo regresa los puntos disponibles del cliente.
+func (s *Servicio) Saldo(ctx context.Context, clienteID int) (int, error) {
+	return s.store.GetSaldoPuntos(ctx, clienteID)
+}
+
+// Historial regresa los últimos movimientos del cliente.
+func (s *Servicio) Historial(ctx context.Context, clienteID, limite int) ([]*store.MovimientoPuntos, error) {
+	return s.store.GetMovimientosPuntos(ctx, clienteID, limite)
+}
+
+// Calcular regresa los puntos que otorga un pedido entregado.
+func (s *Servicio) Calcular(pedido *store.Pedido) int {
+	puntos := pedido.CantidadLitros*s.cfg.PorLitro + importe(pedido)*s.cfg.PorPeso
+	return int(math.Floor(puntos))
+}
+
+// Descuento regresa cuántos puntos del saldo se usan para cubrir monto y el
+// descuento que representan. Con saldo menor al mínimo no se canjea nada.
+func (s *Servicio) Descuento(saldo int, monto float64) (int, float64) {
+	if saldo <= 0 || saldo < s.cfg.MinimoCanje || monto <= 0 {
+		return 0, 0
+	}
+	puntos := int(math.Ceil(monto / s.cfg.ValorPunto))
+	if puntos > saldo {
+		puntos = saldo
+	}
+	descuento := math.Round(float64(puntos)*s.cfg.ValorPunto*100) / 100
+	if descuento > monto {
+		descuento = monto
+	}
+	return puntos, descuento
+}
+
+// Acumular abona los puntos del pedido entregado una sola vez.
+func (s *Servicio) Acumular(ctx context.Context, pedido *store.Pedido) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	netos, err := s.netosPedido(ctx, pedido.ID)
+	if err != nil {
+		return 0, err
+	}
+	// Un pedido que salió de disputa tiene su acumulación retenida y se
+	// vuelve a abonar.
+	if netos[TipoAcumulacion]+netos[TipoRetencion] > 0 {
+		return 0, nil
+	}
+	puntos := s.Calcular(pedido)
+	if puntos <= 0 {
+		return 0, nil
+	}
+	if err := s.registrar(ctx, pedido, TipoAcumulacion, puntos, fmt.Sprintf("Pedido #%d entregado", pedido.ID)); err != nil {
+		return 0, err
+	}
+	log.Printf("Pedido %d: %d puntos para el cliente %d\n", pedido.ID, puntos, pedido.ClienteID)
+	s.avisar(ctx, pedido, puntos)
+	return puntos, nil
+}
+
+// Canjear descuenta del saldo los puntos usados en el pedido.
+func (s *Servicio) Canjear(ctx context.Context, pedido *store.Pedido, puntos int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	saldo, err := s.store.GetSaldoPuntos(ctx, pedido.ClienteID)
+	if err != nil {
+		return err
+	}
+	if puntos <= 0 || puntos > saldo {
+		return fmt.Errorf("saldo insuficiente: %d puntos disponibles, se pidieron %d", saldo, puntos)
+	}
+	return s.registrar(ctx, pedido, TipoCanje, -puntos, fmt.Sprintf("Descuento en pedido #%d", pedido.ID))
+}
+
+// Retener quita los puntos abonados por un pedido que el cliente dice no
+// haber recibido. Si el caso no procede, Acumular los abona de nuevo.
+func (s *Servicio) Retener(ctx context.Context, pedido *store.Pedido) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	netos, err := s.netosPedido(ctx, pedido.ID)
+	if err != nil {
+		return err
+	}
+	abonados := netos[TipoAcumulacion] + netos[TipoRetencion]
+	if abonados <= 0 {
+		return nil
+	}
+	return s.registrar(ctx, pedido, TipoRetencion, -abonados, fmt.Sprintf("Pedido #%d en disputa", pedido.ID))
+}
+
+// Revertir repone los puntos canjeados en un pedido cancelado.
+func (s *Servicio) Revertir(ctx context.Context, pedido *store.Pedido) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	netos, err := s.netosPedido(ctx, pedido.ID)
+	if err != nil {
+		return err
+	}
+	pendiente := -(netos[TipoCanje] + netos[TipoReverso])
+	if pendiente <= 0 {
+		return nil
+	}
+	return s.registrar(ctx, pedido, TipoReverso, pendiente, fmt.Sprintf("Pedido #%d cancelado", pedido.ID))
+}
+
+// Bonificar abona puntos que no salen de una compra, como la recompensa por
+// referir a un cliente. pedidoID es opcional y sirve de referencia.
+func (s *Servicio) Bonificar(ctx context.Context, clienteID int, pedidoID *int, tipo string, puntos int, descripcion string) error {
+	if puntos <= 0 {
+		return nil
+	}
+	return s.store.RegistrarMovimientoPuntos(ctx, &store.MovimientoPuntos{
+		ClienteID:   clienteID,
+		PedidoID:    pedidoID,
+		Tipo:        tipo,
+		Puntos:      puntos,
+		Descripcion: descripcion,
+	})
+}
+
+// Retirar descuenta puntos que se habían bonificado, por ejemplo la
+// recompensa de un referido cuyo pedido quedó en disputa.
+func (s *Servicio) Retirar(ctx context.Context, clienteID int, pedidoID *int, tipo string, puntos int, descripcion string) error {
+	if puntos <= 0 {
+		return nil
+	}
+	return s.store.RegistrarMovimientoPuntos(ctx, &store.MovimientoPuntos{
+		ClienteID:   clienteID,
+		PedidoID:    pedidoID,
+		Tipo:        tipo,
+		Puntos:      -puntos,
+		Descripcion: descripcion,
+	})
+}
+
+// OrderStatusChanged implementa delivery.StatusObserver. Un pedido en
+// disputa pierde sus puntos; si el caso procede (no_entregado) se reponen
+// los canjeados, como al cancelar.
+func (s *Servicio) OrderStatusChanged(ctx context.Context, pedido *store.Pedido, status string) error {
+	switch status {
+	case "entregado":
+		_, err := s.Acumular(ctx, pedido)
+		return err
+	case "en_disputa":
+		return s.Retener(ctx, pedido)
+	case "cancelado", "no_entregado":
+		return s.Revertir(ctx, pedido)
+	}
+	return nil
+}
+
+// netosPedido suma los puntos del pedido por tipo de movimiento.
+func (s *Servicio) netosPedido(ctx context.Context, pedidoID int) (map[string]int, error) {
+	movimientos, err := s.store.GetMovimientosPuntosPedido(ctx, pedidoID)
+	if err != nil {
+		return nil, err
+	}
+	netos := make(map[string]int)
+	for _, m := range movimientos {
+		netos[m.Tipo] += m.Puntos
+	}
+	return netos, nil
+}
+
+func (s *Servicio) registrar(ctx context.Context, pedido *store.Pedido, tipo string, puntos int, descripcion string) error {
+	pedidoID := pedido.ID
+	return s.store.RegistrarMovimientoPuntos(ctx, &store.MovimientoPuntos{
+		ClienteID:   pedido.ClienteID,
+		PedidoID:    &pedidoID,
+		Tipo:        tipo,
+		Puntos:      puntos,
+		Descripcion: descripcion,
+	})
+}
+
+func (s *Servicio) avisar(ctx context.Context, pedido *store.Pedido, puntos int) {
+	if s.sender == nil {
+		return
+	}
+	cliente, err := s.store.GetClientePorID(ctx, pedido.ClienteID)
+	if err != nil || cliente == nil {
+		log.Printf("No se pudo avisar los puntos del pedido %d: cliente %d no disponible (%v)\n", pedido.ID, pedido.ClienteID, err)
+		return
+	}
+	saldo, err := s.store.GetSaldoPuntos(ctx, cliente.ID)
+	if err != nil {
+		log.Printf("Error consultando saldo de puntos del cliente %d: %v\n", cliente.ID, err)
+		return
+	}
+	msg := fmt.Sprintf("⭐ Ganaste %d puntos con tu pedido #%d. Tu saldo es de %d puntos.\nEscribe PUNTOS para ver tu historial.", puntos, pedido.ID, saldo)
+	if err := s.sender.SendMessage(cliente.NumeroTelefono, msg); err != nil {
+		log.Printf("Error avisando puntos a %s: %v\n", cliente.NumeroTelefono, err)
+	}
+}
+
+// importe es lo pagado por el pedido; los pedidos por litros no siempre
+// guardan cantidad_dinero, así que se calcula con el precio.
+func importe(p *store.Pedido) float64 {
+	if p.CantidadDinero > 0 {
+		return p.TotalAPagar()
+	}
+	subtotal := p.CantidadLitros * p.PrecioUnitario
+	if p.CantidadCilindros > 0 {
+		subtotal = float64(p.CantidadCilindros) * p.PrecioUnitario
+	}
+	return math.Max(subtotal-p.Descuento, 0)
+}
@@ -0,0 +1,150 @@
+package puntos
+
+import (
+	"context"
+	"testing"
+
+	"example.com/whatsapp-integration/store"
+)
+
+// storePuntos solo implementa lo que usa el monedero; el resto del Store queda
+// nil y haría panic si se llamara.
+type storePuntos struct {
+	store.Store
+	movimientos []*store.MovimientoPuntos
+}
+
+func (s *storePuntos) GetSaldoPuntos(ctx context.Context, clienteID int) (int, error) {
+	saldo := 0
+	for _, m := range s.movimientos {
+		if m.ClienteID == clienteID {
+			saldo += m.Puntos
+		}
+	}
+	return saldo, nil
+}
+
+func (s *storePuntos) GetMovimientosPuntosPedido(ctx context.Context, pedidoID int) ([]*store.MovimientoPuntos, error) {
+	var movimientos []*store.MovimientoPuntos
+	for _, m := range s.movimientos {
+		if m.PedidoID != nil && *m.PedidoID == pedidoID {
+			movimientos = append(movimientos, m)
+		}
+	}
+	return movimientos, nil
+}
+
+func (s *storePuntos) RegistrarMovimientoPuntos(ctx context.Context, m *store.MovimientoPuntos) error {
+	s.movimientos = append(s.movimientos, m)
+	return nil
+}
+
+func TestCalcular(t *testing.T) {
+	casos := []struct {
+		nombre string
+		cfg    Config
+		pedido store.Pedido
+		puntos int
+	}{
+		{"por litro", Config{PorLitro: 1}, store.Pedido{CantidadLitros: 120.7}, 120},
+		{"por peso sobre lo pagado", Config{PorPeso: 0.1}, store.Pedido{CantidadDinero: 1000, Descuento: 200}, 80},
+		{"por peso sin cantidad_dinero", Config{PorPeso: 0.1}, store.Pedido{CantidadLitros: 100, PrecioUnitario: 10}, 100},
+		{"cilindros por peso", Config{PorPeso: 0.01}, store.Pedido{CantidadCilindros: 2, PrecioUnitario: 450}, 9},
+		{"litros y pesos", Config{PorLitro: 1, PorPeso: 0.05}, store.Pedido{CantidadLitros: 100, CantidadDinero: 2000}, 200},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			if got := NewServicio(nil, c.cfg).Calcular(&c.pedido); got != c.puntos {
+				t.Fatalf("Calcular() = %d, se esperaban %d", got, c.puntos)
+			}
+		})
+	}
+}
+
+func TestDescuento(t *testing.T) {
+	s := NewServicio(nil, Config{ValorPunto: 0.10, MinimoCanje: 100})
+	casos := []struct {
+		nombre    string
+		saldo     int
+		monto     float64
+		puntos    int
+		descuento float64
+	}{
+		{"saldo bajo el mínimo", 99, 500, 0, 0},
+		{"justo en el mínimo", 100, 500, 100, 10},
+		{"el saldo cubre todo", 10000, 250.05, 2501, 250.05},
+		{"el saldo no alcanza", 300, 500, 300, 30},
+		{"sin monto", 500, 0, 0, 0},
+		{"saldo negativo", -10, 500, 0, 0},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			puntos, descuento := s.Descuento(c.saldo, c.monto)
+			if puntos != c.puntos || descuento != c.descuento {
+				t.Fatalf("Descuento(%d, %.2f) = %d, %.2f; se esperaban %d, %.2f", c.saldo, c.monto, puntos, descuento, c.puntos, c.descuento)
+			}
+		})
+	}
+}
+
+// TestAcumularUnaVez entrega el mismo pedido varias veces y pasa por una
+// disputa: el saldo nunca cuenta dos veces los puntos del pedido.
+func TestAcumularUnaVez(t *testing.T) {
+	st := &storePuntos{}
+	s := NewServicio(st, Config{PorLitro: 1})
+	ctx := context.Background()
+	pedido := &store.Pedido{ID: 7, ClienteID: 1, CantidadLitros: 150}
+
+	pasos := []struct {
+		estado string
+		saldo  int
+	}{
+		{"entregado", 150},
+		{"entregado", 150},
+		{"en_disputa", 0},
+		{"en_disputa", 0},
+		{"entregado", 150}, // la disputa no procedió
+		{"entregado", 150},
+	}
+	for i, p := range pasos {
+		if err := s.OrderStatusChanged(ctx, pedido, p.estado); err != nil {
+			t.Fatal(err)
+		}
+		if saldo, _ := st.GetSaldoPuntos(ctx, 1); saldo != p.saldo {
+			t.Fatalf("paso %d (%s): saldo = %d, se esperaba %d", i+1, p.estado, saldo, p.saldo)
+		}
+	}
+}
+
+// TestRevertirCancelado repone el canje de un pedido cancelado una sola vez.
+func TestRevertirCancelado(t *testing.T) {
+	uno := 1
+	st := &storePuntos{movimientos: []*store.MovimientoPuntos{{ClienteID: 1, PedidoID: &uno, Tipo: TipoAcumulacion, Puntos: 500}}}
+	s := NewServicio(st, Config{PorLitro: 1})
+	ctx := context.Background()
+	pedido := &store.Pedido{ID: 8, ClienteID: 1, CantidadLitros: 100}
+
+	if err := s.Canjear(ctx, pedido, 600); err == nil {
+		t.Fatal("Canjear() con saldo insuficiente debe fallar")
+	}
+	if err := s.Canjear(ctx, pedido, 300); err != nil {
+		t.Fatal(err)
+	}
+	if saldo, _ := st.GetSaldoPuntos(ctx, 1); saldo != 200 {
+		t.Fatalf("saldo tras canjear = %d, se esperaba 200", saldo)
+	}
+	for i := 0; i < 2; i++ {
+		if err := s.OrderStatusChanged(ctx, pedido, "cancelado"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if saldo, _ := st.GetSaldoPuntos(ctx, 1); saldo != 500 {
+		t.Fatalf("saldo tras cancelar = %d, se esperaba 500", saldo)
+	}
+	if err := s.OrderStatusChanged(ctx, pedido, "no_entregado"); err != nil {
+		t.Fatal(err)
+	}
+	if saldo, _ := st.GetSaldoPuntos(ctx, 1); saldo != 500 {
+		t.Fatalf("saldo tras no_entregado = %d, no debe reponerse de nuevo", saldo)
+	}
+}
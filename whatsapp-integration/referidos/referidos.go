@@ -0,0 +1,282 @@
+// Package referidos da a cada cliente un código y una liga de WhatsApp para
+// invitar a otros. Cuando un número nuevo escribe por primera vez con un
+// código se registra quién lo refirió, y con su primer pedido entregado ambos
+// reciben puntos.
+package referidos
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"log"
+	"math/big"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"example.com/whatsapp-integration/puntos"
+	"example.com/whatsapp-integration/store"
+)
+
+// Estados de un referido.
+const (
+	EstadoPendiente    = "pendiente"
+	EstadoRecompensado = "recompensado"
+)
+
+// prefijoCodigo distingue los códigos de referido dentro de un mensaje libre.
+const prefijoCodigo = "REF"
+
+// alfabetoCodigo omite caracteres que se confunden al escribirlos (0/O, 1/I).
+const alfabetoCodigo = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+var patronCodigo = regexp.MustCompile(`(?i)\b` + prefijoCodigo + `[A-Z0-9]{6}\b`)
+
+// Config define las recompensas y el número al que apuntan las ligas.
+type Config struct {
+	NumeroNegocio   string // número de WhatsApp del negocio, con lada y sin "+"
+	PuntosReferente int
+	PuntosReferido  int
+}
+
+// ConfigDesdeEnv lee WHATSAPP_NUMERO, REFERIDOS_PUNTOS_REFERENTE (200) y
+// REFERIDOS_PUNTOS_REFERIDO (200).
+func ConfigDesdeEnv() Config {
+	cfg := Config{
+		NumeroNegocio:   strings.TrimPrefix(os.Getenv("WHATSAPP_NUMERO"), "+"),
+		PuntosReferente: 200,
+		PuntosReferido:  200,
+	}
+	if v, err := strconv.Atoi(os.Getenv("REFERIDOS_PUNTOS_REFERENTE")); err == nil && v >= 0 {
+		cfg.PuntosReferente = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("REFERIDOS_PUNTOS_REFERIDO")); err == nil && v >= 0 {
+		cfg.PuntosReferido = v
+	}
+	return cfg
+}
+
+// Notificador avisa a los clientes de su recompensa.
+type Notificador interface {
+	SendMessage(to string, text string) error
+}
+
+// Servicio administra los códigos, la atribución y las recompensas.
+type Servicio struct {
+	store  store.Store
+	puntos *puntos.Servicio
+	cfg    Config
+	sender Notificador
+	mu     sync.Mutex
+}
+
+func NewServicio(s store.Store, p *puntos.Servicio, cfg Config) *Servicio {
+	return &Servicio{store: s, puntos: p, cfg: cfg}
+}
+
+// SetSender habilita el aviso de recompensas por WhatsApp.
+func (s *Servicio) SetSender(sender Notificador) {
+	s.sender = sender
+}
+
+// Config regresa la configuración vigente.
+func (s *Servicio) Config() Config {
+	return s.cfg
+}
+
+// Codigo regresa el código del cliente y lo genera la primera vez.
+func (s *Servicio) Codigo(ctx context.Context, clienteID int) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	codigo, err := s.store.GetCodigoReferido(ctx, clienteID)
+	if err != nil || codigo != "" {
+		return codigo, err
+	}
+	for intento := 0; intento < 5; intento++ {
+		codigo, err = generarCodigo()
+		if err != nil {
+			return "", err
+		}
+		existente, err := s.store.GetClienteIDPorCodigoReferido(ctx, codigo)
+		if err != nil {
+			return "", err
+		}
+		if existente != 0 {
+			continue
+		}
+		if err := s.store.CrearCodigoReferido(ctx, clienteID, codigo); err != nil {
+			return "", err
+		}
+		return codigo, nil
+	}
+	return "", fmt.Errorf("no se pudo generar un código de referido único para el cliente %d", clienteID)
+}
+
+// Liga arma la liga wa.me con el mensaje de invitación ya escrito. Sin número
+// configurado regresa "" y solo se comparte el código.
+func (s *Servicio) Liga(codigo string) string {
+	if s.cfg.NumeroNegocio == "" {
+		return ""
+	}
+	texto := fmt.Sprintf("Hola, quiero hacer un pedido de gas. Mi código de invitación es %s", codigo)
+	return fmt.Sprintf("https://wa.me/%s?text=%s", s.cfg.NumeroNegocio, url.QueryEscape(texto))
+}
+
+// BuscarCodigo extrae un código de referido del mensaje, si lo hay.
+func BuscarCodigo(mensaje string) string {
+	return strings.ToUpper(patronCodigo.FindString(mensaje))
+}
+
+// Atribuir registra que el cliente nuevo llegó con el código de mensaje.
+// Regresa el cliente que lo refirió, o nil si el mensaje no trae un código
+// válido o el cliente ya tenía referente.
+func (s *Servicio) Atribuir(ctx context.Context, cliente *store.Cliente, mensaje string) (*store.Cliente, error) {
+	codigo := BuscarCodigo(mensaje)
+	if codigo == "" {
+		return nil, nil
+	}
+	referenteID, err := s.store.GetClienteIDPorCodigoReferido(ctx, codigo)
+	if err != nil {
+		return nil, err
+	}
+	if referenteID == 0 || referenteID == cliente.ID {
+		return nil, nil
+	}
+	existente, err := s.store.GetReferidoPorCliente(ctx, cliente.ID)
+	if err != nil || existente != nil {
+		return nil, err
+	}
+
+	if err := s.store.CrearReferido(ctx, &store.Referido{
+		ReferenteID: referenteID,
+		ReferidoID:  cliente.ID,
+		Codigo:      codigo,
+		Estado:      EstadoPendiente,
+	}); err != nil {
+		return nil, err
+	}
+	log.Printf("Cliente %d referido por %d con el código %s\n", cliente.ID, referenteID, codigo)
+	return s.store.GetClientePorID(ctx, referenteID)
+}
+
+// Resumen regresa cuántos clientes invitó el cliente y cuántos ya generaron
+// recompensa.
+func (s *Servicio) Resumen(ctx context.Context, clienteID int) (invitados, recompensados int, err error) {
+	referidos, err := s.store.GetReferidosDe(ctx, clienteID)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, r := range referidos {
+		if r.Estado == EstadoRecompensado {
+			recompensados++
+		}
+	}
+	return len(referidos), recompensados, nil
+}
+
+// OrderStatusChanged implementa delivery.StatusObserver: la primera entrega
+// del cliente referido libera la recompensa de ambos y, si ese pedido queda
+// en disputa, se retira hasta la siguiente entrega.
+func (s *Servicio) OrderStatusChanged(ctx context.Context, pedido *store.Pedido, status string) error {
+	if s.puntos == nil {
+		return nil
+	}
+	switch status {
+	case "entregado":
+		return s.recompensar(ctx, pedido)
+	case "en_disputa":
+		return s.retirarRecompensa(ctx, pedido)
+	}
+	return nil
+}
+
+func (s *Servicio) recompensar(ctx context.Context, pedido *store.Pedido) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	referido, err := s.store.GetReferidoPorCliente(ctx, pedido.ClienteID)
+	if err != nil || referido == nil || referido.Estado != EstadoPendiente {
+		return err
+	}
+
+	pedidoID := pedido.ID
+	ahora := time.Now()
+	referido.Estado = EstadoRecompensado
+	referido.PedidoID = &pedidoID
+	referido.RecompensadoEn = &ahora
+	if err := s.store.ActualizarReferido(ctx, referido); err != nil {
+		return err
+	}
+
+	if err := s.puntos.Bonificar(ctx, referido.ReferenteID, &pedidoID, puntos.TipoReferido, s.cfg.PuntosReferente,
+		fmt.Sprintf("Invitaste a un cliente (pedido #%d)", pedido.ID)); err != nil {
+		return err
+	}
+	if err := s.puntos.Bonificar(ctx, referido.ReferidoID, &pedidoID, puntos.TipoReferido, s.cfg.PuntosReferido,
+		"Bienvenida por invitación"); err != nil {
+		return err
+	}
+
+	s.avisar(ctx, referido.ReferenteID, fmt.Sprintf("🎉 Una persona que invitaste recibió su primer pedido. Ganaste %d puntos.\nEscribe PUNTOS para ver tu saldo.", s.cfg.PuntosReferente))
+	s.avisar(ctx, referido.ReferidoID, fmt.Sprintf("🎉 Por venir invitado ganaste %d puntos de bienvenida.\nEscribe PUNTOS para ver tu saldo.", s.cfg.PuntosReferido))
+	return nil
+}
+
+// retirarRecompensa deja el referido pendiente otra vez si lo recompensó el
+// pedido que el cliente dice no haber recibido.
+func (s *Servicio) retirarRecompensa(ctx context.Context, pedido *store.Pedido) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	referido, err := s.store.GetReferidoPorCliente(ctx, pedido.ClienteID)
+	if err != nil || referido == nil || referido.Estado != EstadoRecompensado ||
+		referido.PedidoID == nil || *referido.PedidoID != pedido.ID {
+		return err
+	}
+
+	pedidoID := pedido.ID
+	referido.Estado = EstadoPendiente
+	referido.PedidoID = nil
+	referido.RecompensadoEn = nil
+	if err := s.store.ActualizarReferido(ctx, referido); err != nil {
+		return err
+	}
+	if err := s.puntos.Retirar(ctx, referido.ReferenteID, &pedidoID, puntos.TipoReferido, s.cfg.PuntosReferente,
+		fmt.Sprintf("Pedido #%d del invitado en disputa", pedido.ID)); err != nil {
+		return err
+	}
+	return s.puntos.Retirar(ctx, referido.ReferidoID, &pedidoID, puntos.TipoReferido, s.cfg.PuntosReferido,
+		fmt.Sprintf("Pedido #%d en disputa", pedido.ID))
+}
+
+func (s *Servicio) avisar(ctx context.Context, clienteID int, msg string) {
+	if s.sender == nil {
+		return
+	}
+	cliente, err := s.store.GetClientePorID(ctx, clienteID)
+	if err != nil || cliente == nil {
+		log.Printf("No se pudo avisar la recompensa de referido al cliente %d (%v)\n", clienteID, err)
+		return
+	}
+	if err := s.sender.SendMessage(cliente.NumeroTelefono, msg); err != nil {
+		log.Printf("Error avisando recompensa de referido a %s: %v\n", cliente.NumeroTelefono, err)
+	}
+}
+
+func generarCodigo() (string, error) {
+	b := make([]byte, 6)
+	max := big.NewInt(int64(len(alfabetoCodigo)))
+	for i := range b {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("error generando código de referido: %w", err)
+		}
+		b[i] = alfabetoCodigo[n.Int64()]
+	}
+	return prefijoCodigo + string(b), nil
+}
@@ -0,0 +1,121 @@
+package referidos
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"example.com/whatsapp-integration/puntos"
+	"example.com/whatsapp-integration/store"
+)
+
+func TestBuscarCodigo(t *testing.T) {
+	casos := []struct {
+		nombre  string
+		mensaje string
+		codigo  string
+	}{
+		{"solo el código", "REFAB23CD", "REFAB23CD"},
+		{"dentro del texto y en minúsculas", "hola, mi código es refab23cd gracias", "REFAB23CD"},
+		{"sin código", "quiero gas", ""},
+		{"código corto", "REFAB23", ""},
+		{"pegado a otra palabra", "XREFAB23CD", ""},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			if got := BuscarCodigo(c.mensaje); got != c.codigo {
+				t.Fatalf("BuscarCodigo(%q) = %q, se esperaba %q", c.mensaje, got, c.codigo)
+			}
+		})
+	}
+}
+
+// TestRecompensaUnaVez atribuye un referido, lo recompensa con su primera
+// entrega una sola vez, lo retira si ese pedido queda en disputa y lo vuelve a
+// dar con la siguiente entrega.
+func TestRecompensaUnaVez(t *testing.T) {
+	db, err := store.NewSQLiteStore(store.Config{Database: filepath.Join(t.TempDir(), "referidos.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	referente := &store.Cliente{NumeroTelefono: "5215550000001", Nombre: "Ana"}
+	referido := &store.Cliente{NumeroTelefono: "5215550000002", Nombre: "Beto"}
+	for _, c := range []*store.Cliente{referente, referido} {
+		if err := db.CrearCliente(ctx, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	monedero := puntos.NewServicio(db, puntos.Config{ValorPunto: 0.10, MinimoCanje: 100})
+	s := NewServicio(db, monedero, Config{PuntosReferente: 200, PuntosReferido: 100})
+
+	codigo, err := s.Codigo(ctx, referente.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if otra, err := s.Codigo(ctx, referente.ID); err != nil || otra != codigo {
+		t.Fatalf("Codigo() cambió de %q a %q (%v)", codigo, otra, err)
+	}
+
+	if quien, err := s.Atribuir(ctx, referente, "Hola "+codigo); err != nil || quien != nil {
+		t.Fatalf("un cliente no puede referirse a sí mismo: %v, %v", quien, err)
+	}
+	quien, err := s.Atribuir(ctx, referido, "Hola, vengo con "+codigo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if quien == nil || quien.ID != referente.ID {
+		t.Fatalf("Atribuir() = %v, se esperaba el cliente %d", quien, referente.ID)
+	}
+	if otro, err := s.Atribuir(ctx, referido, codigo); err != nil || otro != nil {
+		t.Fatalf("un referido no se atribuye dos veces: %v, %v", otro, err)
+	}
+
+	primero := &store.Pedido{ClienteID: referido.ID, TipoServicio: "estacionario", CantidadLitros: 100, Estado: "entregado"}
+	segundo := &store.Pedido{ClienteID: referido.ID, TipoServicio: "estacionario", CantidadLitros: 100, Estado: "entregado"}
+	for _, p := range []*store.Pedido{primero, segundo} {
+		if err := db.CrearPedido(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pasos := []struct {
+		nombre               string
+		pedido               *store.Pedido
+		estado               string
+		saldoReferente       int
+		saldoReferido        int
+		invitados, premiados int
+	}{
+		{"primera entrega", primero, "entregado", 200, 100, 1, 1},
+		{"entrega repetida", primero, "entregado", 200, 100, 1, 1},
+		{"otro pedido entregado", segundo, "entregado", 200, 100, 1, 1},
+		{"disputa de otro pedido", segundo, "en_disputa", 200, 100, 1, 1},
+		{"disputa del pedido premiado", primero, "en_disputa", 0, 0, 1, 0},
+		{"disputa repetida", primero, "en_disputa", 0, 0, 1, 0},
+		{"siguiente entrega", segundo, "entregado", 200, 100, 1, 1},
+	}
+	for _, p := range pasos {
+		t.Run(p.nombre, func(t *testing.T) {
+			if err := s.OrderStatusChanged(ctx, p.pedido, p.estado); err != nil {
+				t.Fatal(err)
+			}
+			if saldo, _ := monedero.Saldo(ctx, referente.ID); saldo != p.saldoReferente {
+				t.Errorf("saldo del referente = %d, se esperaba %d", saldo, p.saldoReferente)
+			}
+			if saldo, _ := monedero.Saldo(ctx, referido.ID); saldo != p.saldoReferido {
+				t.Errorf("saldo del referido = %d, se esperaba %d", saldo, p.saldoReferido)
+			}
+			invitados, premiados, err := s.Resumen(ctx, referente.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if invitados != p.invitados || premiados != p.premiados {
+				t.Errorf("Resumen() = %d, %d; se esperaba %d, %d", invitados, premiados, p.invitados, p.premiados)
+			}
+		})
+	}
+}
@@ -0,0 +1,439 @@
+package repartidores
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"example.com/whatsapp-integration/delivery"
+	"example.com/whatsapp-integration/rutas"
+	"example.com/whatsapp-integration/store"
+)
+
+// Estados de una parada de ruta.
+const (
+	ParadaPendiente = "pending"
+	ParadaLlegando  = "arriving"
+	ParadaEntregada = "delivered"
+	ParadaCancelada = "cancelled"
+)
+
+// DirectorioEvidenciasPorDefecto es donde se guardan las fotos de entrega si no
+// se configura EVIDENCIAS_DIR.
+const DirectorioEvidenciasPorDefecto = "evidencias"
+
+// maxFotoBytes limita el tamaño de la foto de evidencia.
+const maxFotoBytes = 10 << 20
+
+// StopTracker recibe los cambios de estado de las paradas, por ejemplo para
+// las rutas activas del seguimiento con el POS.
+type StopTracker interface {
+	UpdateStopStatus(pedidoID int, status string)
+}
+
+// Esperas arranca el tiempo de espera en el domicilio cuando el repartidor llega.
+type Esperas interface {
+	Iniciar(ctx context.Context, pedidoID, paradaID int) (*store.EsperaEntrega, error)
+}
+
+// Confirmaciones le pregunta al cliente si recibió el pedido entregado.
+type Confirmaciones interface {
+	SolicitarConfirmacion(ctx context.Context, pedidoID int) error
+}
+
+// App es la aplicación web de los repartidores: ingreso con teléfono y PIN,
+// paradas del día en orden, llegada y evidencia de entrega.
+type App struct {
+	store      store.Store
+	maps       *delivery.MapsService
+	loc        *time.Location
+	evidencias string
+	sesiones   *sesiones
+	tracker    StopTracker
+	esperas    Esperas
+
+	confirmaciones Confirmaciones
+}
+
+// NewApp crea la aplicación. maps actualiza el pedido y avisa al cliente en cada acción.
+func NewApp(s store.Store, maps *delivery.MapsService, loc *time.Location, evidencias string) *App {
+	if evidencias == "" {
+		evidencias = DirectorioEvidenciasPorDefecto
+	}
+	return &App{
+		store:      s,
+		maps:       maps,
+		loc:        loc,
+		evidencias: evidencias,
+		sesiones:   newSesiones(),
+	}
+}
+
+// SetTracker registra quién debe enterarse de los cambios de estado de las paradas.
+func (a *App) SetTracker(t StopTracker) {
+	a.tracker = t
+}
+
+// SetEsperas habilita el temporizador de espera al marcar la llegada.
+func (a *App) SetEsperas(e Esperas) {
+	a.esperas = e
+}
+
+// SetConfirmaciones habilita la pregunta al cliente al registrar la entrega.
+func (a *App) SetConfirmaciones(c Confirmaciones) {
+	a.confirmaciones = c
+}
+
+// Handler regresa las rutas HTTP de la aplicación, montadas bajo /repartidor/.
+func (a *App) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repartidor/", a.handleRuta)
+	mux.HandleFunc("/repartidor/login", a.handleLogin)
+	mux.HandleFunc("/repartidor/logout", a.handleLogout)
+	mux.HandleFunc("/repartidor/llegada", a.conRepartidor(a.handleLlegada))
+	mux.HandleFunc("/repartidor/entrega", a.conRepartidor(a.handleEntrega))
+	mux.HandleFunc("/repartidor/gps", a.conRepartidor(a.handleGPS))
+	return mux
+}
+
+// conRepartidor exige sesión y método POST para las acciones sobre paradas.
+func (a *App) conRepartidor(fn func(http.ResponseWriter, *http.Request, *store.Repartidor)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		repartidor, err := a.repartidorDeSesion(r)
+		if err != nil {
+			log.Printf("Error consultando repartidor: %v\n", err)
+			http.Error(w, "Error interno", http.StatusInternalServerError)
+			return
+		}
+		if repartidor == nil {
+			http.Redirect(w, r, "/repartidor/", http.StatusSeeOther)
+			return
+		}
+		fn(w, r, repartidor)
+	}
+}
+
+func (a *App) repartidorDeSesion(r *http.Request) (*store.Repartidor, error) {
+	id := a.sesiones.repartidor(r)
+	if id == 0 {
+		return nil, nil
+	}
+	repartidor, err := a.store.GetRepartidor(r.Context(), id)
+	if err != nil || repartidor == nil || !repartidor.Activo {
+		return nil, err
+	}
+	return repartidor, nil
+}
+
+func (a *App) handleLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/repartidor/", http.StatusSeeOther)
+		return
+	}
+
+	telefono := strings.TrimSpace(r.FormValue("telefono"))
+	repartidor, err := a.store.GetRepartidorPorTelefono(r.Context(), telefono)
+	if err != nil {
+		log.Printf("Error consultando repartidor %s: %v\n", telefono, err)
+		http.Error(w, "Error interno", http.StatusInternalServerError)
+		return
+	}
+	if repartidor == nil || !repartidor.Activo || !pinValido(repartidor.PinHash, r.FormValue("pin")) {
+		a.render(w, plantillaLogin, map[string]string{"Error": "Teléfono o PIN incorrecto."})
+		return
+	}
+
+	if err := a.sesiones.abrir(w, repartidor.ID); err != nil {
+		http.Error(w, "Error interno", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/repartidor/", http.StatusSeeOther)
+}
+
+func (a *App) handleLogout(w http.ResponseWriter, r *http.Request) {
+	a.sesiones.cerrar(w, r)
+	http.Redirect(w, r, "/repartidor/", http.StatusSeeOther)
+}
+
+// handleRuta muestra las paradas del día del camión del repartidor.
+func (a *App) handleRuta(w http.ResponseWriter, r *http.Request) {
+	repartidor, err := a.repartidorDeSesion(r)
+	if err != nil {
+		log.Printf("Error consultando repartidor: %v\n", err)
+		http.Error(w, "Error interno", http.StatusInternalServerError)
+		return
+	}
+	if repartidor == nil {
+		a.render(w, plantillaLogin, nil)
+		return
+	}
+
+	datos := struct {
+		Repartidor *store.Repartidor
+		Hoja       *rutas.HojaRuta
+		Mensaje    string
+	}{Repartidor: repartidor, Mensaje: r.URL.Query().Get("msg")}
+
+	ruta, err := a.rutaDelDia(r.Context(), repartidor)
+	if err != nil {
+		log.Printf("Error consultando ruta del repartidor %d: %v\n", repartidor.ID, err)
+		http.Error(w, "Error interno", http.StatusInternalServerError)
+		return
+	}
+	if ruta != nil {
+		if datos.Hoja, err = rutas.CargarHoja(r.Context(), a.store, ruta); err != nil {
+			log.Printf("Error cargando hoja de la ruta %d: %v\n", ruta.ID, err)
+			http.Error(w, "Error interno", http.StatusInternalServerError)
+			return
+		}
+		for i := range datos.Hoja.Paradas {
+			if eta := datos.Hoja.Paradas[i].HoraEstimada; eta != nil {
+				local := eta.In(a.loc)
+				datos.Hoja.Paradas[i].HoraEstimada = &local
+			}
+		}
+	}
+	a.render(w, plantillaRuta, datos)
+}
+
+func (a *App) rutaDelDia(ctx context.Context, repartidor *store.Repartidor) (*store.Ruta, error) {
+	if repartidor.CamionID == 0 {
+		return nil, nil
+	}
+	ahora := time.Now().In(a.loc)
+	hoy := time.Date(ahora.Year(), ahora.Month(), ahora.Day(), 0, 0, 0, 0, a.loc)
+	return a.store.GetRutaCamion(ctx, repartidor.CamionID, hoy)
+}
+
+// paradaDelRepartidor valida que la parada pertenezca a la ruta de hoy del repartidor.
+func (a *App) paradaDelRepartidor(ctx context.Context, repartidor *store.Repartidor, paradaID int) (*store.RutaParada, error) {
+	parada, err := a.store.GetParada(ctx, paradaID)
+	if err != nil || parada == nil {
+		return nil, err
+	}
+	ruta, err := a.rutaDelDia(ctx, repartidor)
+	if err != nil || ruta == nil || ruta.ID != parada.RutaID {
+		return nil, err
+	}
+	return parada, nil
+}
+
+func (a *App) handleLlegada(w http.ResponseWriter, r *http.Request, repartidor *store.Repartidor) {
+	ctx := r.Context()
+	paradaID, _ := strconv.Atoi(r.FormValue("parada_id"))
+	parada, err := a.paradaDelRepartidor(ctx, repartidor, paradaID)
+	if err != nil {
+		log.Printf("Error consultando parada %d: %v\n", paradaID, err)
+		http.Error(w, "Error interno", http.StatusInternalServerError)
+		return
+	}
+	if parada == nil {
+		http.Error(w, "Parada no encontrada", http.StatusNotFound)
+		return
+	}
+	if parada.Estado != ParadaPendiente && parada.Estado != ParadaLlegando {
+		redirigir(w, r, "La parada ya fue atendida.")
+		return
+	}
+
+	if err := a.marcarLlegada(ctx, parada); err != nil {
+		log.Printf("Error marcando llegada a la parada %d: %v\n", parada.ID, err)
+		http.Error(w, "Error interno", http.StatusInternalServerError)
+		return
+	}
+	redirigir(w, r, fmt.Sprintf("Llegada registrada en el pedido #%d.", parada.PedidoID))
+}
+
+// marcarLlegada deja la parada en camino a cerrarse y pasa el pedido a
+// "esperando", lo que avisa al cliente que el repartidor está en la puerta.
+func (a *App) marcarLlegada(ctx context.Context, parada *store.RutaParada) error {
+	if parada.Estado != ParadaLlegando {
+		if err := a.store.ActualizarEstadoParada(ctx, parada.ID, ParadaLlegando); err != nil {
+			return err
+		}
+		parada.Estado = ParadaLlegando
+	}
+	if err := a.maps.UpdateDeliveryStatus(ctx, parada.PedidoID, "esperando", delivery.Location{}); err != nil {
+		log.Printf("Error actualizando pedido %d a esperando: %v\n", parada.PedidoID, err)
+	}
+	a.notificarTracker(parada.PedidoID, ParadaLlegando)
+	if a.esperas != nil {
+		if _, err := a.esperas.Iniciar(ctx, parada.PedidoID, parada.ID); err != nil {
+			log.Printf("Error iniciando espera del pedido %d: %v\n", parada.PedidoID, err)
+		}
+	}
+	return nil
+}
+
+func (a *App) handleEntrega(w http.ResponseWriter, r *http.Request, repartidor *store.Repartidor) {
+	ctx := r.Context()
+	r.Body = http.MaxBytesReader(w, r.Body, maxFotoBytes+1<<20)
+	if err := r.ParseMultipartForm(maxFotoBytes); err != nil {
+		http.Error(w, "La foto es demasiado grande", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	paradaID, _ := strconv.Atoi(r.FormValue("parada_id"))
+	parada, err := a.paradaDelRepartidor(ctx, repartidor, paradaID)
+	if err != nil {
+		log.Printf("Error consultando parada %d: %v\n", paradaID, err)
+		http.Error(w, "Error interno", http.StatusInternalServerError)
+		return
+	}
+	if parada == nil {
+		http.Error(w, "Parada no encontrada", http.StatusNotFound)
+		return
+	}
+	if parada.Estado == ParadaEntregada || parada.Estado == ParadaCancelada {
+		redirigir(w, r, "La parada ya fue cerrada.")
+		return
+	}
+
+	litros, errLitros := strconv.ParseFloat(strings.TrimSpace(r.FormValue("litros")), 64)
+	efectivo, errEfectivo := strconv.ParseFloat(strings.TrimSpace(r.FormValue("efectivo")), 64)
+	if errLitros != nil || errEfectivo != nil || litros < 0 || efectivo < 0 {
+		redirigir(w, r, "Captura los litros surtidos y el efectivo recibido (usa 0 si no aplica).")
+		return
+	}
+
+	foto, cabecera, err := r.FormFile("foto")
+	if err != nil {
+		redirigir(w, r, "La foto de la entrega es obligatoria.")
+		return
+	}
+	defer foto.Close()
+	rutaFoto, err := a.guardarFoto(parada.PedidoID, filepath.Ext(cabecera.Filename), foto)
+	if err != nil {
+		log.Printf("Error guardando foto del pedido %d: %v\n", parada.PedidoID, err)
+		http.Error(w, "Error interno", http.StatusInternalServerError)
+		return
+	}
+
+	entrega := &store.Entrega{
+		ParadaID:         parada.ID,
+		PedidoID:         parada.PedidoID,
+		RepartidorID:     repartidor.ID,
+		LitrosSurtidos:   litros,
+		EfectivoRecibido: efectivo,
+		FotoPath:         rutaFoto,
+	}
+	if err := a.store.CrearEntrega(ctx, entrega); err != nil {
+		log.Printf("Error registrando entrega del pedido %d: %v\n", parada.PedidoID, err)
+		http.Error(w, "Error interno", http.StatusInternalServerError)
+		return
+	}
+	if err := a.store.ActualizarEstadoParada(ctx, parada.ID, ParadaEntregada); err != nil {
+		log.Printf("Error cerrando parada %d: %v\n", parada.ID, err)
+		http.Error(w, "Error interno", http.StatusInternalServerError)
+		return
+	}
+	if err := a.maps.UpdateDeliveryStatus(ctx, parada.PedidoID, "entregado", delivery.Location{}); err != nil {
+		log.Printf("Error actualizando pedido %d a entregado: %v\n", parada.PedidoID, err)
+	}
+	a.notificarTracker(parada.PedidoID, ParadaEntregada)
+	if a.confirmaciones != nil {
+		if err := a.confirmaciones.SolicitarConfirmacion(ctx, parada.PedidoID); err != nil {
+			log.Printf("Error solicitando confirmación del pedido %d: %v\n", parada.PedidoID, err)
+		}
+	}
+	redirigir(w, r, fmt.Sprintf("Entrega del pedido #%d registrada.", parada.PedidoID))
+}
+
+func (a *App) guardarFoto(pedidoID int, ext string, foto io.Reader) (string, error) {
+	ext = strings.ToLower(ext)
+	if ext != ".jpg" && ext != ".jpeg" && ext != ".png" && ext != ".heic" && ext != ".webp" {
+		ext = ".jpg"
+	}
+	if err := os.MkdirAll(a.evidencias, 0o755); err != nil {
+		return "", err
+	}
+	ruta := filepath.Join(a.evidencias, fmt.Sprintf("entrega-%d-%d%s", pedidoID, time.Now().Unix(), ext))
+
+	f, err := os.Create(ruta)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err := io.Copy(f, foto); err != nil {
+		return "", err
+	}
+	return ruta, nil
+}
+
+func (a *App) notificarTracker(pedidoID int, estado string) {
+	if a.tracker != nil {
+		a.tracker.UpdateStopStatus(pedidoID, estado)
+	}
+}
+
+func redirigir(w http.ResponseWriter, r *http.Request, mensaje string) {
+	http.Redirect(w, r, "/repartidor/?msg="+url.QueryEscape(mensaje), http.StatusSeeOther)
+}
+
+// CargarRepartidoresDesdeArchivo siembra los repartidores desde un JSON cuando
+// la tabla está vacía. El camión se indica por nombre y el PIN se guarda como hash.
+func CargarRepartidoresDesdeArchivo(ctx context.Context, s store.Store, ruta string) error {
+	existentes, err := s.GetRepartidores(ctx)
+	if err != nil {
+		return err
+	}
+	if len(existentes) > 0 {
+		log.Printf("Repartidores ya configurados (%d); se ignora %s\n", len(existentes), ruta)
+		return nil
+	}
+
+	data, err := os.ReadFile(ruta)
+	if err != nil {
+		return fmt.Errorf("error leyendo repartidores: %w", err)
+	}
+	var repartidores []struct {
+		Nombre   string `json:"nombre"`
+		Telefono string `json:"telefono"`
+		Pin      string `json:"pin"`
+		Camion   string `json:"camion"`
+	}
+	if err := json.Unmarshal(data, &repartidores); err != nil {
+		return fmt.Errorf("error decodificando repartidores: %w", err)
+	}
+
+	camiones, err := s.GetCamiones(ctx)
+	if err != nil {
+		return err
+	}
+	for _, r := range repartidores {
+		if r.Telefono == "" || len(r.Pin) < 4 {
+			return fmt.Errorf("el repartidor %s necesita teléfono y un PIN de al menos 4 dígitos", r.Nombre)
+		}
+		hash, err := HashPin(r.Pin)
+		if err != nil {
+			return fmt.Errorf("error generando PIN del repartidor %s: %w", r.Nombre, err)
+		}
+		repartidor := &store.Repartidor{Nombre: r.Nombre, Telefono: r.Telefono, PinHash: hash, Activo: true}
+		for _, c := range camiones {
+			if c.Nombre == r.Camion {
+				repartidor.CamionID = c.ID
+			}
+		}
+		if r.Camion != "" && repartidor.CamionID == 0 {
+			return fmt.Errorf("camión '%s' del repartidor %s no existe", r.Camion, r.Nombre)
+		}
+		if err := s.CrearRepartidor(ctx, repartidor); err != nil {
+			return err
+		}
+	}
+	log.Printf("Se cargaron %d repartidores desde %s\n", len(repartidores), ruta)
+	return nil
+}
@@ -0,0 +1,193 @@
+package repartidores
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"example.com/whatsapp-integration/delivery"
+	"example.com/whatsapp-integration/store"
+)
+
+// Radios de las geocercas alrededor de cada parada, en metros.
+const (
+	RadioLlegando  = 500.0 // se avisa al cliente que el repartidor está por llegar
+	RadioEsperando = 50.0  // el repartidor está en la puerta
+)
+
+// Eventos que dispara una geocerca.
+const (
+	geocercaLlegando = "llegando"
+	geocercaPuerta   = "puerta"
+)
+
+// maxPrecisionGeocerca descarta lecturas demasiado imprecisas para disparar geocercas.
+const maxPrecisionGeocerca = 100.0
+
+// ETAParada es la hora estimada recalculada de una parada abierta.
+type ETAParada struct {
+	ParadaID int       `json:"parada_id"`
+	PedidoID int       `json:"pedido_id"`
+	ETA      time.Time `json:"eta"`
+}
+
+// StopETATracker es opcional para el StopTracker: recibe las ETAs recalculadas.
+type StopETATracker interface {
+	UpdateStopETA(pedidoID int, eta time.Time)
+}
+
+// handleGPS recibe la posición del repartidor ({"lat", "lng", "precision"}),
+// la guarda, revisa las geocercas y regresa las ETAs recalculadas.
+func (a *App) handleGPS(w http.ResponseWriter, r *http.Request, repartidor *store.Repartidor) {
+	ctx := r.Context()
+	var fix struct {
+		Lat       float64 `json:"lat"`
+		Lng       float64 `json:"lng"`
+		Precision float64 `json:"precision"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&fix); err != nil || (fix.Lat == 0 && fix.Lng == 0) {
+		http.Error(w, "Posición inválida", http.StatusBadRequest)
+		return
+	}
+
+	ruta, err := a.rutaDelDia(ctx, repartidor)
+	if err != nil {
+		log.Printf("Error consultando ruta del repartidor %d: %v\n", repartidor.ID, err)
+		http.Error(w, "Error interno", http.StatusInternalServerError)
+		return
+	}
+	if ruta == nil {
+		http.Error(w, "Sin ruta asignada", http.StatusNotFound)
+		return
+	}
+
+	posicion := &store.PosicionRuta{
+		RutaID:       ruta.ID,
+		RepartidorID: repartidor.ID,
+		Latitud:      fix.Lat,
+		Longitud:     fix.Lng,
+		Precision:    fix.Precision,
+	}
+	if err := a.store.RegistrarPosicion(ctx, posicion); err != nil {
+		log.Printf("Error registrando posición de la ruta %d: %v\n", ruta.ID, err)
+		http.Error(w, "Error interno", http.StatusInternalServerError)
+		return
+	}
+
+	etas, err := a.procesarPosicion(ctx, ruta, posicion, time.Now())
+	if err != nil {
+		log.Printf("Error procesando posición de la ruta %d: %v\n", ruta.ID, err)
+		http.Error(w, "Error interno", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"etas": etas})
+}
+
+// paradaAbierta es una parada sin cerrar con los datos de su pedido.
+type paradaAbierta struct {
+	parada *store.RutaParada
+	pedido *store.Pedido
+}
+
+// procesarPosicion aplica las geocercas y recalcula las ETAs de las paradas
+// que faltan, en el orden de la ruta, a partir de la posición actual.
+func (a *App) procesarPosicion(ctx context.Context, ruta *store.Ruta, posicion *store.PosicionRuta, ahora time.Time) ([]ETAParada, error) {
+	paradas, err := a.store.GetParadasRuta(ctx, ruta.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var abiertas []paradaAbierta
+	for _, parada := range paradas {
+		if parada.Estado == ParadaEntregada || parada.Estado == ParadaCancelada {
+			continue
+		}
+		pedido, err := a.store.GetPedido(ctx, parada.PedidoID)
+		if err != nil {
+			return nil, err
+		}
+		if pedido != nil {
+			abiertas = append(abiertas, paradaAbierta{parada: parada, pedido: pedido})
+		}
+	}
+
+	actual := delivery.Location{Lat: posicion.Latitud, Lng: posicion.Longitud}
+	// Las geocercas solo aplican a la siguiente parada de la ruta: pasar
+	// frente a otro cliente no es llegar ni debe iniciar su espera.
+	if len(abiertas) > 0 && posicion.Precision <= maxPrecisionGeocerca && tieneCoordenadas(abiertas[0].pedido) {
+		ab := abiertas[0]
+		metros := delivery.Haversine(actual, ubicacionPedido(ab.pedido)) * 1000
+		switch eventoGeocerca(metros, ab.parada.Estado, ab.pedido.Estado) {
+		case geocercaPuerta:
+			if err := a.marcarLlegada(ctx, ab.parada); err != nil {
+				return nil, err
+			}
+			ab.pedido.Estado = "esperando"
+		case geocercaLlegando:
+			if err := a.store.ActualizarEstadoParada(ctx, ab.parada.ID, ParadaLlegando); err != nil {
+				return nil, err
+			}
+			ab.parada.Estado = ParadaLlegando
+			if err := a.maps.UpdateDeliveryStatus(ctx, ab.pedido.ID, "llegando", actual); err != nil {
+				log.Printf("Error actualizando pedido %d a llegando: %v\n", ab.pedido.ID, err)
+			}
+			a.notificarTracker(ab.pedido.ID, ParadaLlegando)
+		}
+	}
+
+	var etas []ETAParada
+	for i, eta := range calcularETAs(actual, abiertas, ahora) {
+		ab := abiertas[i]
+		if err := a.store.ActualizarETAParada(ctx, ab.parada.ID, eta); err != nil {
+			return nil, err
+		}
+		if tracker, ok := a.tracker.(StopETATracker); ok {
+			tracker.UpdateStopETA(ab.pedido.ID, eta)
+		}
+		etas = append(etas, ETAParada{ParadaID: ab.parada.ID, PedidoID: ab.pedido.ID, ETA: eta})
+	}
+	return etas, nil
+}
+
+// eventoGeocerca decide qué dispara la posición frente a la siguiente
+// parada: geocercaPuerta al llegar, geocercaLlegando al acercarse o "".
+func eventoGeocerca(metros float64, estadoParada, estadoPedido string) string {
+	switch {
+	case metros <= RadioEsperando && estadoPedido != "esperando":
+		return geocercaPuerta
+	case metros <= RadioLlegando && estadoParada == ParadaPendiente:
+		return geocercaLlegando
+	}
+	return ""
+}
+
+// calcularETAs recorre las paradas en orden en línea recta corregida desde
+// la posición actual, más el tiempo de servicio de cada parada.
+func calcularETAs(actual delivery.Location, abiertas []paradaAbierta, ahora time.Time) []time.Time {
+	etas := make([]time.Time, 0, len(abiertas))
+	t := ahora
+	anterior := actual
+	for _, ab := range abiertas {
+		if tieneCoordenadas(ab.pedido) {
+			destino := ubicacionPedido(ab.pedido)
+			km := delivery.Haversine(anterior, destino) * delivery.DefaultRoadFactor
+			t = t.Add(time.Duration(km / delivery.DefaultSpeedKmh * float64(time.Hour)))
+			anterior = destino
+		}
+		etas = append(etas, t)
+		t = t.Add(delivery.DefaultServiceTime)
+	}
+	return etas
+}
+
+func tieneCoordenadas(p *store.Pedido) bool {
+	return p.Latitud != 0 || p.Longitud != 0
+}
+
+func ubicacionPedido(p *store.Pedido) delivery.Location {
+	return delivery.Location{Lat: p.Latitud, Lng: p.Longitud, Address: p.Direccion}
+}
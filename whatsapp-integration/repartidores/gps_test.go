@@ -0,0 +1,183 @@
+package repartidores
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"example.com/whatsapp-integration/delivery"
+	"example.com/whatsapp-integration/store"
+)
+
+func TestEventoGeocerca(t *testing.T) {
+	casos := []struct {
+		nombre       string
+		metros       float64
+		estadoParada string
+		estadoPedido string
+		evento       string
+	}{
+		{"lejos", 2000, ParadaPendiente, "en_ruta", ""},
+		{"entra al radio de aviso", RadioLlegando, ParadaPendiente, "en_ruta", geocercaLlegando},
+		{"aviso ya enviado", 300, ParadaLlegando, "llegando", ""},
+		{"en la puerta", RadioEsperando, ParadaLlegando, "llegando", geocercaPuerta},
+		{"en la puerta sin pasar por el aviso", 10, ParadaPendiente, "en_ruta", geocercaPuerta},
+		{"ya esperando", 10, ParadaLlegando, "esperando", ""},
+		{"justo fuera de la puerta", RadioEsperando + 1, ParadaLlegando, "llegando", ""},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			if got := eventoGeocerca(c.metros, c.estadoParada, c.estadoPedido); got != c.evento {
+				t.Fatalf("eventoGeocerca() = %q, se esperaba %q", got, c.evento)
+			}
+		})
+	}
+}
+
+// abierta coloca un pedido a km kilómetros al norte de (0, 0).
+func abierta(id int, km float64) paradaAbierta {
+	return paradaAbierta{
+		parada: &store.RutaParada{ID: id, PedidoID: id, Estado: ParadaPendiente},
+		pedido: &store.Pedido{ID: id, Latitud: km / 111.19},
+	}
+}
+
+func TestCalcularETAs(t *testing.T) {
+	ahora := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	// 10 km en línea recta son 13 km por calle: 26 minutos a 30 km/h.
+	tramo := 26 * time.Minute
+	sinCoordenadas := paradaAbierta{parada: &store.RutaParada{ID: 9, PedidoID: 9}, pedido: &store.Pedido{ID: 9}}
+
+	casos := []struct {
+		nombre   string
+		abiertas []paradaAbierta
+		etas     []time.Time
+	}{
+		{"sin paradas", nil, nil},
+		{
+			"paradas en orden con tiempo de servicio",
+			[]paradaAbierta{abierta(1, 10), abierta(2, 20)},
+			[]time.Time{ahora.Add(tramo), ahora.Add(2*tramo + delivery.DefaultServiceTime)},
+		},
+		{
+			"la parada sin coordenadas no mueve al camión",
+			[]paradaAbierta{abierta(1, 10), sinCoordenadas, abierta(2, 20)},
+			[]time.Time{
+				ahora.Add(tramo),
+				ahora.Add(tramo + delivery.DefaultServiceTime),
+				ahora.Add(2*tramo + 2*delivery.DefaultServiceTime),
+			},
+		},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			got := calcularETAs(delivery.Location{}, c.abiertas, ahora)
+			if len(got) != len(c.etas) {
+				t.Fatalf("ETAs = %v, se esperaban %v", got, c.etas)
+			}
+			for i := range got {
+				if d := got[i].Sub(c.etas[i]); d < -time.Second || d > time.Second {
+					t.Fatalf("ETA %d = %v, se esperaba %v", i, got[i], c.etas[i])
+				}
+			}
+		})
+	}
+}
+
+// storeGPS solo implementa lo que usa procesarPosicion; el resto del Store
+// queda nil y haría panic si se llamara.
+type storeGPS struct {
+	store.Store
+	paradas []*store.RutaParada
+	pedidos map[int]*store.Pedido
+}
+
+func (s *storeGPS) GetParadasRuta(ctx context.Context, rutaID int) ([]*store.RutaParada, error) {
+	return s.paradas, nil
+}
+
+func (s *storeGPS) GetPedido(ctx context.Context, id int) (*store.Pedido, error) {
+	return s.pedidos[id], nil
+}
+
+func (s *storeGPS) ActualizarPedido(ctx context.Context, pedido *store.Pedido) error {
+	s.pedidos[pedido.ID] = pedido
+	return nil
+}
+
+func (s *storeGPS) ActualizarEstadoParada(ctx context.Context, id int, estado string) error {
+	for _, p := range s.paradas {
+		if p.ID == id {
+			p.Estado = estado
+		}
+	}
+	return nil
+}
+
+func (s *storeGPS) ActualizarETAParada(ctx context.Context, id int, eta time.Time) error {
+	return nil
+}
+
+type trackerPrueba struct {
+	estados map[int]string
+}
+
+func (t *trackerPrueba) UpdateStopStatus(pedidoID int, status string) {
+	t.estados[pedidoID] = status
+}
+
+// TestGeocercaSoloParadaActual pasa frente a la puerta de la segunda parada
+// antes de llegar a la primera: solo la primera puede disparar la llegada.
+func TestGeocercaSoloParadaActual(t *testing.T) {
+	st := &storeGPS{
+		paradas: []*store.RutaParada{
+			{ID: 1, PedidoID: 1, Estado: ParadaPendiente},
+			{ID: 2, PedidoID: 2, Estado: ParadaPendiente},
+		},
+		pedidos: map[int]*store.Pedido{
+			1: {ID: 1, Estado: "en_ruta", Latitud: 5 / 111.19},
+			2: {ID: 2, Estado: "en_ruta", Latitud: 2 / 111.19},
+		},
+	}
+	tracker := &trackerPrueba{estados: make(map[int]string)}
+	a := NewApp(st, delivery.NewMapsService("", st), time.UTC, t.TempDir())
+	a.SetTracker(tracker)
+	ctx := context.Background()
+	ruta := &store.Ruta{ID: 1}
+
+	frenteA2 := &store.PosicionRuta{Latitud: 2 / 111.19, Precision: 10}
+	if _, err := a.procesarPosicion(ctx, ruta, frenteA2, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if st.paradas[1].Estado != ParadaPendiente || st.pedidos[2].Estado != "en_ruta" {
+		t.Fatalf("la parada 2 cambió a %q (pedido %q) sin ser la actual", st.paradas[1].Estado, st.pedidos[2].Estado)
+	}
+	if len(tracker.estados) != 0 {
+		t.Fatalf("tracker = %v, no se esperaban cambios", tracker.estados)
+	}
+
+	cerca := &store.PosicionRuta{Latitud: 4.7 / 111.19, Precision: 10}
+	if _, err := a.procesarPosicion(ctx, ruta, cerca, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if st.paradas[0].Estado != ParadaLlegando || st.pedidos[1].Estado != "llegando" {
+		t.Fatalf("parada 1 = %q, pedido 1 = %q; se esperaba llegando", st.paradas[0].Estado, st.pedidos[1].Estado)
+	}
+
+	enPuerta := &store.PosicionRuta{Latitud: 5 / 111.19, Precision: 10}
+	if _, err := a.procesarPosicion(ctx, ruta, enPuerta, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if st.pedidos[1].Estado != "esperando" || tracker.estados[1] != ParadaLlegando {
+		t.Fatalf("pedido 1 = %q, tracker = %v; se esperaba esperando", st.pedidos[1].Estado, tracker.estados)
+	}
+
+	impreciso := &store.PosicionRuta{Latitud: 2 / 111.19, Precision: maxPrecisionGeocerca + 1}
+	st.pedidos[1].Estado = "llegando"
+	if _, err := a.procesarPosicion(ctx, ruta, impreciso, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if st.pedidos[1].Estado != "llegando" {
+		t.Fatalf("una lectura imprecisa cambió el pedido 1 a %q", st.pedidos[1].Estado)
+	}
+}
@@ -0,0 +1,96 @@
+package repartidores
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+)
+
+func (a *App) render(w http.ResponseWriter, t *template.Template, datos interface{}) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := t.Execute(w, datos); err != nil {
+		log.Printf("Error generando página de repartidor: %v\n", err)
+	}
+}
+
+const estilos = `<meta name="viewport" content="width=device-width, initial-scale=1">
+<style>
+body { font-family: sans-serif; margin: 0; padding: 12px; background: #f4f4f4; font-size: 16px; }
+.tarjeta { background: #fff; border-radius: 8px; padding: 12px; margin-bottom: 12px; box-shadow: 0 1px 3px rgba(0,0,0,.15); }
+.tarjeta.delivered, .tarjeta.cancelled { opacity: .55; }
+h1 { font-size: 20px; margin: 4px 0 12px; }
+h2 { font-size: 17px; margin: 0 0 6px; }
+input, button { font-size: 16px; padding: 10px; width: 100%; box-sizing: border-box; margin: 4px 0; }
+button { background: #0a7d3b; color: #fff; border: 0; border-radius: 6px; }
+button.secundario { background: #555; }
+a.mapa { display: inline-block; margin: 6px 0; }
+.mensaje { background: #fff7d6; padding: 10px; border-radius: 6px; margin-bottom: 12px; }
+.error { color: #b00020; }
+.estado { float: right; font-size: 13px; color: #555; }
+</style>`
+
+var plantillaLogin = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<html lang="es"><head><meta charset="utf-8"><title>Repartidores</title>` + estilos + `</head>
+<body>
+<h1>Ingreso de repartidor</h1>
+{{if .}}{{with .Error}}<p class="error">{{.}}</p>{{end}}{{end}}
+<form class="tarjeta" method="post" action="/repartidor/login">
+<label>Teléfono<input name="telefono" type="tel" autocomplete="username" required></label>
+<label>PIN<input name="pin" type="password" inputmode="numeric" autocomplete="current-password" required></label>
+<button type="submit">Entrar</button>
+</form>
+</body></html>
+`))
+
+var plantillaRuta = template.Must(template.New("ruta").Parse(`<!DOCTYPE html>
+<html lang="es"><head><meta charset="utf-8"><title>Mi ruta</title>` + estilos + `</head>
+<body>
+<form method="post" action="/repartidor/logout" style="float:right;width:auto"><button class="secundario" style="width:auto">Salir</button></form>
+<h1>Hola, {{.Repartidor.Nombre}}</h1>
+{{with .Mensaje}}<div class="mensaje">{{.}}</div>{{end}}
+{{if not .Hoja}}<div class="tarjeta">No tienes ruta asignada para hoy.</div>{{else}}
+<div class="tarjeta">Ruta {{.Hoja.RutaID}} &middot; {{.Hoja.Camion}} &middot; {{len .Hoja.Paradas}} paradas<br>
+Efectivo a cobrar: ${{printf "%.2f" .Hoja.TotalCobrar}}</div>
+{{range .Hoja.Paradas}}
+<div class="tarjeta {{.Estado}}">
+<span class="estado">{{.Estado}}</span>
+<h2>{{.Orden}}. {{.Cliente}}</h2>
+<div>Pedido #{{.PedidoID}}{{with .Horario}} &middot; {{.}}{{end}}{{with .HoraEstimada}} &middot; ETA {{.Format "15:04"}}{{end}}</div>
+<a class="mapa" href="{{.LinkNavegacion}}">📍 {{.Direccion}}</a>
+<div>Fachada: <strong>{{.ColorFachada}}</strong> &middot; Puerta: <strong>{{.ColorPuerta}}</strong></div>
+<div>{{.TipoServicio}}{{if .Cilindros}} &middot; {{.Cilindros}} cilindros{{else}} &middot; {{printf "%.0f" .Litros}} L{{end}}</div>
+<div>Cobrar: ${{printf "%.2f" .Total}} ({{.MetodoPago}})</div>
+{{with .Telefono}}<div><a href="tel:{{.}}">📞 {{.}}</a></div>{{end}}
+{{if eq .Estado "pending"}}
+<form method="post" action="/repartidor/llegada"><input type="hidden" name="parada_id" value="{{.ParadaID}}"><button>Llegué</button></form>
+{{end}}
+{{if or (eq .Estado "pending") (eq .Estado "arriving")}}
+<form method="post" action="/repartidor/entrega" enctype="multipart/form-data">
+<input type="hidden" name="parada_id" value="{{.ParadaID}}">
+<label>Foto de la entrega<input type="file" name="foto" accept="image/*" capture="environment" required></label>
+<label>Litros surtidos<input type="number" name="litros" step="0.1" min="0" value="{{printf "%.1f" .Litros}}" required></label>
+<label>Efectivo recibido<input type="number" name="efectivo" step="0.01" min="0" value="{{if eq .MetodoPago "efectivo"}}{{printf "%.2f" .Total}}{{else}}0{{end}}" required></label>
+<button>Entregado</button>
+</form>
+{{end}}
+</div>
+{{end}}
+<script>
+// Envía la posición del repartidor cada 30 segundos mientras la página esté abierta.
+if (navigator.geolocation) {
+  var ultimoEnvio = 0;
+  navigator.geolocation.watchPosition(function (p) {
+    var ahora = Date.now();
+    if (ahora - ultimoEnvio < 30000) return;
+    ultimoEnvio = ahora;
+    fetch("/repartidor/gps", {
+      method: "POST",
+      headers: {"Content-Type": "application/json"},
+      body: JSON.stringify({lat: p.coords.latitude, lng: p.coords.longitude, precision: p.coords.accuracy})
+    });
+  }, null, {enableHighAccuracy: true, maximumAge: 10000});
+}
+</script>
+{{end}}
+</body></html>
+`))
@@ -0,0 +1,96 @@
+package repartidores
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	cookieSesion   = "repartidor_sesion"
+	duracionSesion = 14 * time.Hour // cubre un turno completo
+)
+
+// HashPin genera el hash que se guarda en repartidores.pin_hash.
+func HashPin(pin string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(pin), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func pinValido(hash, pin string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(pin)) == nil
+}
+
+type sesion struct {
+	repartidorID int
+	expira       time.Time
+}
+
+// sesiones guarda en memoria las sesiones abiertas. Al reiniciar el servidor
+// los repartidores vuelven a ingresar su PIN.
+type sesiones struct {
+	mu    sync.Mutex
+	porID map[string]sesion
+}
+
+func newSesiones() *sesiones {
+	return &sesiones{porID: make(map[string]sesion)}
+}
+
+func (s *sesiones) abrir(w http.ResponseWriter, repartidorID int) error {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	token := hex.EncodeToString(b)
+
+	s.mu.Lock()
+	s.porID[token] = sesion{repartidorID: repartidorID, expira: time.Now().Add(duracionSesion)}
+	s.mu.Unlock()
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     cookieSesion,
+		Value:    token,
+		Path:     "/repartidor/",
+		MaxAge:   int(duracionSesion.Seconds()),
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+	return nil
+}
+
+// repartidor regresa el ID del repartidor de la petición, o 0 si no hay sesión válida.
+func (s *sesiones) repartidor(r *http.Request) int {
+	c, err := r.Cookie(cookieSesion)
+	if err != nil {
+		return 0
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ses, ok := s.porID[c.Value]
+	if !ok {
+		return 0
+	}
+	if time.Now().After(ses.expira) {
+		delete(s.porID, c.Value)
+		return 0
+	}
+	return ses.repartidorID
+}
+
+func (s *sesiones) cerrar(w http.ResponseWriter, r *http.Request) {
+	if c, err := r.Cookie(cookieSesion); err == nil {
+		s.mu.Lock()
+		delete(s.porID, c.Value)
+		s.mu.Unlock()
+	}
+	http.SetCookie(w, &http.Cookie{Name: cookieSesion, Value: "", Path: "/repartidor/", MaxAge: -1})
+}
@@ -0,0 +1,209 @@
+package rutas
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"example.com/whatsapp-integration/store"
+)
+
+// HojaRuta es lo que se entrega al repartidor: la ruta con los datos de cada
+// parada. Se exporta como JSON y como HTML imprimible.
+type HojaRuta struct {
+	RutaID         int          `json:"ruta_id"`
+	Fecha          string       `json:"fecha"`
+	Camion         string       `json:"camion"`
+	Placas         string       `json:"placas"`
+	TotalLitros    float64      `json:"total_litros"`
+	TotalCilindros int          `json:"total_cilindros"`
+	TotalCobrar    float64      `json:"total_cobrar"`
+	Paradas        []ParadaHoja `json:"paradas"`
+}
+
+// ParadaHoja es una parada de la hoja de ruta.
+type ParadaHoja struct {
+	ParadaID     int        `json:"parada_id"`
+	Estado       string     `json:"estado"`
+	HoraEstimada *time.Time `json:"hora_estimada,omitempty"`
+	Orden        int        `json:"orden"`
+	PedidoID     int        `json:"pedido_id"`
+	Cliente      string     `json:"cliente"`
+	Telefono     string     `json:"telefono"`
+	Direccion    string     `json:"direccion"`
+	Latitud      float64    `json:"latitud"`
+	Longitud     float64    `json:"longitud"`
+	MapaURL      string     `json:"mapa_url,omitempty"`
+	ColorFachada string     `json:"color_fachada"`
+	ColorPuerta  string     `json:"color_puerta"`
+	TipoServicio string     `json:"tipo_servicio"`
+	Litros       float64    `json:"litros"`
+	Cilindros    int        `json:"cilindros"`
+	Horario      string     `json:"horario,omitempty"`
+	MetodoPago   string     `json:"metodo_pago"`
+	Total        float64    `json:"total"`
+}
+
+// LinkNavegacion abre la parada en Google Maps; usa coordenadas si las hay.
+func (p ParadaHoja) LinkNavegacion() string {
+	if p.Latitud != 0 || p.Longitud != 0 {
+		return fmt.Sprintf("https://www.google.com/maps/dir/?api=1&destination=%f,%f", p.Latitud, p.Longitud)
+	}
+	return "https://www.google.com/maps/search/?api=1&query=" + template.URLQueryEscaper(p.Direccion)
+}
+
+// CargarHoja arma la hoja de una ruta guardada con los datos actuales de sus
+// pedidos y clientes.
+func CargarHoja(ctx context.Context, s store.Store, ruta *store.Ruta) (*HojaRuta, error) {
+	hoja := &HojaRuta{
+		RutaID:         ruta.ID,
+		Fecha:          ruta.Fecha.Format("2006-01-02"),
+		TotalLitros:    ruta.TotalLitros,
+		TotalCilindros: ruta.TotalCilindros,
+	}
+
+	camiones, err := s.GetCamiones(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range camiones {
+		if c.ID == ruta.CamionID {
+			hoja.Camion, hoja.Placas = c.Nombre, c.Placas
+		}
+	}
+
+	paradas, err := s.GetParadasRuta(ctx, ruta.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, parada := range paradas {
+		pedido, err := s.GetPedido(ctx, parada.PedidoID)
+		if err != nil {
+			return nil, err
+		}
+		if pedido == nil {
+			continue
+		}
+		cliente, err := s.GetClientePorID(ctx, pedido.ClienteID)
+		if err != nil {
+			return nil, err
+		}
+
+		item := ParadaHoja{
+			ParadaID:     parada.ID,
+			Estado:       parada.Estado,
+			HoraEstimada: parada.HoraEstimada,
+			Orden:        parada.Orden,
+			PedidoID:     pedido.ID,
+			Direccion:    pedido.Direccion,
+			Latitud:      pedido.Latitud,
+			Longitud:     pedido.Longitud,
+			MapaURL:      pedido.MapaURL,
+			ColorFachada: pedido.ColorFachada,
+			ColorPuerta:  pedido.ColorPuerta,
+			TipoServicio: pedido.TipoServicio,
+			Litros:       pedido.CantidadLitros,
+			Cilindros:    pedido.CantidadCilindros,
+			Horario:      pedido.HorarioPreferido,
+			MetodoPago:   pedido.MetodoPago,
+			Total:        TotalPedido(pedido),
+		}
+		if cliente != nil {
+			item.Cliente = strings.TrimSpace(cliente.Nombre + " " + cliente.ApellidoPaterno)
+			item.Telefono = cliente.NumeroTelefono
+		}
+		if item.MetodoPago == "efectivo" {
+			hoja.TotalCobrar += item.Total
+		}
+		hoja.Paradas = append(hoja.Paradas, item)
+	}
+	return hoja, nil
+}
+
+func (p *Planificador) exportar(ctx context.Context, ruta *store.Ruta, camion *store.Camion) error {
+	hoja, err := CargarHoja(ctx, p.store, ruta)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(p.directorio, 0o755); err != nil {
+		return fmt.Errorf("error creando directorio de rutas: %w", err)
+	}
+	base := filepath.Join(p.directorio, fmt.Sprintf("ruta-%s-camion-%d", hoja.Fecha, camion.ID))
+
+	data, err := json.MarshalIndent(hoja, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error generando JSON de la ruta: %w", err)
+	}
+	if err := os.WriteFile(base+".json", data, 0o644); err != nil {
+		return fmt.Errorf("error escribiendo JSON de la ruta: %w", err)
+	}
+
+	f, err := os.Create(base + ".html")
+	if err != nil {
+		return fmt.Errorf("error creando HTML de la ruta: %w", err)
+	}
+	defer f.Close()
+	if err := plantillaHoja.Execute(f, hoja); err != nil {
+		return fmt.Errorf("error generando HTML de la ruta: %w", err)
+	}
+	return nil
+}
+
+// TotalPedido es el importe a cobrar ya con descuentos; los pedidos por litros
+// no siempre guardan cantidad_dinero, así que se calcula con el precio del pedido.
+func TotalPedido(p *store.Pedido) float64 {
+	if p.CantidadDinero > 0 {
+		return p.TotalAPagar()
+	}
+	subtotal := p.CantidadLitros * p.PrecioUnitario
+	if p.CantidadCilindros > 0 {
+		subtotal = float64(p.CantidadCilindros) * p.PrecioUnitario
+	}
+	if total := subtotal - p.Descuento; total > 0 {
+		return total
+	}
+	return 0
+}
+
+var plantillaHoja = template.Must(template.New("hoja").Parse(`<!DOCTYPE html>
+<html lang="es">
+<head>
+<meta charset="utf-8">
+<title>Ruta {{.RutaID}} - {{.Camion}} - {{.Fecha}}</title>
+<style>
+body { font-family: sans-serif; font-size: 13px; margin: 16px; }
+h1 { font-size: 18px; margin: 0 0 4px; }
+table { width: 100%; border-collapse: collapse; margin-top: 12px; }
+th, td { border: 1px solid #999; padding: 4px 6px; vertical-align: top; text-align: left; }
+th { background: #eee; }
+.num { text-align: right; }
+@media print { a { color: inherit; text-decoration: none; } }
+</style>
+</head>
+<body>
+<h1>Ruta {{.RutaID}} &middot; {{.Fecha}}</h1>
+<div>Camión: <strong>{{.Camion}}</strong>{{if .Placas}} ({{.Placas}}){{end}}</div>
+<div>Carga: {{printf "%.0f" .TotalLitros}} L &middot; {{.TotalCilindros}} cilindros &middot; Efectivo a cobrar: ${{printf "%.2f" .TotalCobrar}}</div>
+<table>
+<tr><th>#</th><th>Pedido</th><th>Cliente</th><th>Dirección</th><th>Fachada / Puerta</th><th>Servicio</th><th>Horario</th><th class="num">Cobrar</th><th>Entregado</th></tr>
+{{range .Paradas}}<tr>
+<td>{{.Orden}}</td>
+<td>{{.PedidoID}}</td>
+<td>{{.Cliente}}<br>{{.Telefono}}</td>
+<td><a href="{{.LinkNavegacion}}">{{.Direccion}}</a></td>
+<td>{{.ColorFachada}} / {{.ColorPuerta}}</td>
+<td>{{.TipoServicio}}{{if .Cilindros}} &middot; {{.Cilindros}} cil.{{else}} &middot; {{printf "%.0f" .Litros}} L{{end}}</td>
+<td>{{.Horario}}</td>
+<td class="num">${{printf "%.2f" .Total}}<br>{{.MetodoPago}}</td>
+<td></td>
+</tr>
+{{end}}</table>
+</body>
+</html>
+`))
@@ -0,0 +1,399 @@
+package rutas
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"example.com/whatsapp-integration/delivery"
+	"example.com/whatsapp-integration/lealtad"
+	"example.com/whatsapp-integration/store"
+	"example.com/whatsapp-integration/zonas"
+)
+
+// EstadoPedidoAsignado es el estado de un pedido que ya forma parte de una ruta.
+const EstadoPedidoAsignado = "asignado"
+
+// DirectorioPorDefecto es donde se escriben las hojas de ruta si no se configura RUTAS_DIR.
+const DirectorioPorDefecto = "rutas_generadas"
+
+// HoraSalida es cuando los camiones salen de la planta; desde ahí se calculan
+// las horas estimadas de las paradas.
+const HoraSalida = 8
+
+// Planificador arma las rutas del día en el corte diario: agrupa los pedidos
+// pendientes por zona y cercanía, reparte los grupos entre los camiones según
+// su capacidad, ordena cada ruta con el optimizador respetando los horarios de
+// los clientes, guarda las rutas y exporta una hoja por camión para el
+// repartidor.
+type Planificador struct {
+	store      store.Store
+	loc        *time.Location
+	directorio string
+	maps       *delivery.MapsService
+	zonas      *zonas.Mapa       // opcional: los grupos no mezclan zonas
+	lealtad    *lealtad.Programa // opcional: prioridad de ruta por nivel
+}
+
+// NewPlanificador crea el planificador. Las hojas de ruta se escriben en
+// directorio (RUTAS_DIR); si está vacío se usa DirectorioPorDefecto. Sin
+// SetReparto las rutas se optimizan con distancias en línea recta y sin
+// planta.
+func NewPlanificador(s store.Store, loc *time.Location, directorio string) *Planificador {
+	if directorio == "" {
+		directorio = DirectorioPorDefecto
+	}
+	return &Planificador{store: s, loc: loc, directorio: directorio, maps: delivery.NewMapsService("", s)}
+}
+
+// SetReparto usa el servicio de entregas (planta, fuente de distancias) para
+// agrupar y optimizar las rutas, y las zonas del operador para los grupos.
+func (p *Planificador) SetReparto(maps *delivery.MapsService, mapa *zonas.Mapa) {
+	p.maps = maps
+	p.zonas = mapa
+}
+
+// SetLealtad hace que los clientes de niveles con prioridad de ruta se asignen
+// primero a los camiones.
+func (p *Planificador) SetLealtad(programa *lealtad.Programa) {
+	p.lealtad = programa
+}
+
+// cargaCamion acumula lo asignado a un camión durante la planificación.
+type cargaCamion struct {
+	camion    *store.Camion
+	pedidos   []*store.Pedido
+	litros    float64
+	cilindros int
+}
+
+// admiteGrupo indica si todos los pedidos del grupo caben juntos en el camión.
+func (c *cargaCamion) admiteGrupo(grupo []*store.Pedido) bool {
+	litros, cilindros := 0.0, 0
+	for _, p := range grupo {
+		litros += p.CantidadLitros
+		cilindros += p.CantidadCilindros
+	}
+	if c.camion.MaxPedidos > 0 && len(c.pedidos)+len(grupo) > c.camion.MaxPedidos {
+		return false
+	}
+	if c.camion.CapacidadLitros > 0 && c.litros+litros > c.camion.CapacidadLitros {
+		return false
+	}
+	if c.camion.CapacidadCilindros > 0 && c.cilindros+cilindros > c.camion.CapacidadCilindros {
+		return false
+	}
+	return true
+}
+
+func (c *cargaCamion) agregar(p *store.Pedido) {
+	c.pedidos = append(c.pedidos, p)
+	c.litros += p.CantidadLitros
+	c.cilindros += p.CantidadCilindros
+}
+
+// menosCargado es el camión con menos paradas que admite el grupo, para que
+// las rutas queden balanceadas; nil si ninguno lo admite.
+func menosCargado(cargas []*cargaCamion, grupo []*store.Pedido) *cargaCamion {
+	var elegido *cargaCamion
+	for _, c := range cargas {
+		if c.admiteGrupo(grupo) && (elegido == nil || len(c.pedidos) < len(elegido.pedidos)) {
+			elegido = c
+		}
+	}
+	return elegido
+}
+
+// repartir asigna los grupos completos al camión menos cargado que los
+// admite. Un grupo que no cabe entero en ningún camión se reparte pedido por
+// pedido. Regresa los pedidos que no cupieron.
+func repartir(cargas []*cargaCamion, grupos [][]*store.Pedido) []*store.Pedido {
+	var sinCamion []*store.Pedido
+	for _, grupo := range grupos {
+		if elegido := menosCargado(cargas, grupo); elegido != nil {
+			for _, pedido := range grupo {
+				elegido.agregar(pedido)
+			}
+			continue
+		}
+		for _, pedido := range grupo {
+			elegido := menosCargado(cargas, []*store.Pedido{pedido})
+			if elegido == nil {
+				sinCamion = append(sinCamion, pedido)
+				continue
+			}
+			elegido.agregar(pedido)
+		}
+	}
+	return sinCamion
+}
+
+// CorteDiario genera las rutas del día de ahora. Es idempotente: los camiones
+// que ya tienen ruta en la fecha no se vuelven a planificar y solo se reparten
+// los pedidos que siguen pendientes, así que volver a correrlo tras una falla
+// parcial completa las rutas que faltaron. Tiene la firma de scheduler.Tarea.
+func (p *Planificador) CorteDiario(ctx context.Context, ahora time.Time) error {
+	ahora = ahora.In(p.loc)
+	fecha := time.Date(ahora.Year(), ahora.Month(), ahora.Day(), 0, 0, 0, 0, p.loc)
+
+	todos, err := p.store.GetCamiones(ctx)
+	if err != nil {
+		return err
+	}
+	if len(todos) == 0 {
+		return fmt.Errorf("no hay camiones activos para generar las rutas del %s", fecha.Format("2006-01-02"))
+	}
+
+	existentes, err := p.store.GetRutasPorFecha(ctx, fecha)
+	if err != nil {
+		return err
+	}
+	conRuta := make(map[int]bool, len(existentes))
+	for _, r := range existentes {
+		conRuta[r.CamionID] = true
+	}
+	var camiones []*store.Camion
+	for _, c := range todos {
+		if !conRuta[c.ID] {
+			camiones = append(camiones, c)
+		}
+	}
+	if len(camiones) == 0 {
+		log.Printf("Las rutas del %s ya fueron generadas (%d)\n", fecha.Format("2006-01-02"), len(existentes))
+		return nil
+	}
+
+	pedidos, err := p.pedidosDelDia(ctx, fecha)
+	if err != nil {
+		return err
+	}
+	if len(pedidos) == 0 {
+		log.Printf("No hay pedidos pendientes para las rutas del %s\n", fecha.Format("2006-01-02"))
+		return nil
+	}
+
+	cargas := make([]*cargaCamion, len(camiones))
+	for i, c := range camiones {
+		cargas[i] = &cargaCamion{camion: c}
+	}
+	sinCamion := repartir(cargas, p.agrupar(pedidos, camiones))
+
+	// Una ruta que no se guarda deja sus pedidos pendientes para el siguiente
+	// intento; las demás se guardan de todos modos.
+	var fallo error
+	for _, c := range cargas {
+		if len(c.pedidos) == 0 {
+			continue
+		}
+		if err := p.guardarRuta(ctx, fecha, c); err != nil {
+			log.Printf("Error generando ruta del %s: %v\n", fecha.Format("2006-01-02"), err)
+			if fallo == nil {
+				fallo = err
+			}
+		}
+	}
+
+	if len(sinCamion) > 0 {
+		ids := make([]int, len(sinCamion))
+		for i, pedido := range sinCamion {
+			ids[i] = pedido.ID
+		}
+		log.Printf("ADVERTENCIA: %d pedidos no cupieron en los camiones del %s y siguen pendientes: %v\n",
+			len(sinCamion), fecha.Format("2006-01-02"), ids)
+	}
+	return fallo
+}
+
+// pedidosDelDia regresa los pedidos pendientes que deben salir en la fecha.
+// Los que tienen reservado un horario de un día posterior esperan a su corte.
+func (p *Planificador) pedidosDelDia(ctx context.Context, fecha time.Time) ([]*store.Pedido, error) {
+	pendientes, err := p.store.GetPedidosPorEstado(ctx, "pendiente")
+	if err != nil {
+		return nil, err
+	}
+
+	dia := fecha.Format("2006-01-02")
+	var pedidos []*store.Pedido
+	for _, pedido := range pendientes {
+		reserva, err := p.store.GetReservaSlot(ctx, pedido.ID)
+		if err != nil {
+			return nil, err
+		}
+		if reserva != nil && reserva.Fecha.Format("2006-01-02") > dia {
+			continue
+		}
+		pedidos = append(pedidos, pedido)
+	}
+
+	prioridad, err := p.prioridades(ctx, pedidos)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(pedidos, func(i, j int) bool {
+		pi, pj := prioridad[pedidos[i].ClienteID], prioridad[pedidos[j].ClienteID]
+		if pi != pj {
+			return pi > pj
+		}
+		return pedidos[i].ID < pedidos[j].ID
+	})
+	return pedidos, nil
+}
+
+// agrupar junta los pedidos cercanos de una misma zona en grupos que caben
+// en el camión más chico, para que cada ruta recorra una sola área. Los
+// pedidos ya vienen ordenados por prioridad y los grupos conservan ese orden:
+// primero va el grupo del pedido más prioritario. Los pedidos sin coordenadas
+// van al final, cada uno solo.
+func (p *Planificador) agrupar(pedidos []*store.Pedido, camiones []*store.Camion) [][]*store.Pedido {
+	limites := &delivery.POSConfig{}
+	for _, c := range camiones {
+		if c.MaxPedidos > 0 && (limites.MaxPedidosPorRuta == 0 || c.MaxPedidos < limites.MaxPedidosPorRuta) {
+			limites.MaxPedidosPorRuta = c.MaxPedidos
+		}
+	}
+	grupos, sinCoordenadas := p.maps.AgruparPedidos(pedidos, p.zonas, limites)
+
+	posicion := make(map[int]int, len(pedidos))
+	for i, pedido := range pedidos {
+		posicion[pedido.ID] = i
+	}
+	primero := func(grupo []*store.Pedido) int {
+		min := len(pedidos)
+		for _, pedido := range grupo {
+			if posicion[pedido.ID] < min {
+				min = posicion[pedido.ID]
+			}
+		}
+		return min
+	}
+	sort.SliceStable(grupos, func(i, j int) bool {
+		return primero(grupos[i]) < primero(grupos[j])
+	})
+	for _, pedido := range sinCoordenadas {
+		grupos = append(grupos, []*store.Pedido{pedido})
+	}
+	return grupos
+}
+
+// prioridades regresa la prioridad de ruta del nivel de cada cliente.
+func (p *Planificador) prioridades(ctx context.Context, pedidos []*store.Pedido) (map[int]int, error) {
+	prioridad := make(map[int]int)
+	if p.lealtad == nil {
+		return prioridad, nil
+	}
+	for _, pedido := range pedidos {
+		if _, ok := prioridad[pedido.ClienteID]; ok {
+			continue
+		}
+		cliente, err := p.store.GetClientePorID(ctx, pedido.ClienteID)
+		if err != nil {
+			return nil, err
+		}
+		prioridad[pedido.ClienteID] = 0
+		if cliente != nil {
+			prioridad[pedido.ClienteID] = p.lealtad.Beneficios(cliente.Categoria).PrioridadRuta
+		}
+	}
+	return prioridad, nil
+}
+
+// ordenarParadas pone las paradas en el orden del optimizador, que respeta
+// la capacidad del camión y el horario de cada cliente, con la hora estimada
+// de llegada. Los pedidos que no se pudieron ubicar van al final sin hora.
+func (p *Planificador) ordenarParadas(ctx context.Context, fecha time.Time, c *cargaCamion) ([]*store.RutaParada, error) {
+	plan, err := p.maps.OptimizeRouteFor(ctx, c.pedidos, delivery.Vehicle{
+		CapacityLiters:    c.camion.CapacidadLitros,
+		CapacityCylinders: c.camion.CapacidadCilindros,
+		Departure:         time.Date(fecha.Year(), fecha.Month(), fecha.Day(), HoraSalida, 0, 0, 0, p.loc),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	paradas := make([]*store.RutaParada, 0, len(c.pedidos))
+	for _, stop := range plan.Stops {
+		eta := stop.EstimatedTime
+		paradas = append(paradas, &store.RutaParada{PedidoID: stop.PedidoID, Orden: len(paradas) + 1, HoraEstimada: &eta})
+	}
+	for _, stop := range plan.Unassigned {
+		paradas = append(paradas, &store.RutaParada{PedidoID: stop.PedidoID, Orden: len(paradas) + 1})
+	}
+	return paradas, nil
+}
+
+func (p *Planificador) guardarRuta(ctx context.Context, fecha time.Time, c *cargaCamion) error {
+	paradas, err := p.ordenarParadas(ctx, fecha, c)
+	if err != nil {
+		return fmt.Errorf("error ordenando la ruta del camión %s: %w", c.camion.Nombre, err)
+	}
+	ruta := &store.Ruta{
+		Fecha:          fecha,
+		CamionID:       c.camion.ID,
+		TotalLitros:    c.litros,
+		TotalCilindros: c.cilindros,
+	}
+
+	if err := p.store.CrearRuta(ctx, ruta, paradas, EstadoPedidoAsignado); err != nil {
+		return fmt.Errorf("error guardando ruta del camión %s: %w", c.camion.Nombre, err)
+	}
+	log.Printf("Ruta %d del %s: camión %s con %d paradas\n", ruta.ID, fecha.Format("2006-01-02"), c.camion.Nombre, len(paradas))
+
+	// La ruta ya quedó guardada; si falla la exportación se registra y se
+	// puede volver a generar la hoja sin replanificar.
+	if err := p.exportar(ctx, ruta, c.camion); err != nil {
+		log.Printf("Error exportando hoja de la ruta %d: %v\n", ruta.ID, err)
+	}
+	return nil
+}
+
+// CargarCamionesDesdeArchivo siembra los camiones desde un JSON cuando la tabla
+// está vacía. Los camiones existentes se administran en la base.
+func (p *Planificador) CargarCamionesDesdeArchivo(ctx context.Context, ruta string) error {
+	existentes, err := p.store.GetCamiones(ctx)
+	if err != nil {
+		return err
+	}
+	if len(existentes) > 0 {
+		log.Printf("Camiones ya configurados (%d); se ignora %s\n", len(existentes), ruta)
+		return nil
+	}
+
+	data, err := os.ReadFile(ruta)
+	if err != nil {
+		return fmt.Errorf("error leyendo camiones: %w", err)
+	}
+
+	var camiones []struct {
+		Nombre             string  `json:"nombre"`
+		Placas             string  `json:"placas"`
+		CapacidadLitros    float64 `json:"capacidadLitros"`
+		CapacidadCilindros int     `json:"capacidadCilindros"`
+		MaxPedidos         int     `json:"maxPedidos"`
+	}
+	if err := json.Unmarshal(data, &camiones); err != nil {
+		return fmt.Errorf("error decodificando camiones: %w", err)
+	}
+
+	for _, c := range camiones {
+		if c.Nombre == "" {
+			return fmt.Errorf("camión sin nombre en %s", ruta)
+		}
+		camion := &store.Camion{
+			Nombre:             c.Nombre,
+			Placas:             c.Placas,
+			CapacidadLitros:    c.CapacidadLitros,
+			CapacidadCilindros: c.CapacidadCilindros,
+			MaxPedidos:         c.MaxPedidos,
+			Activo:             true,
+		}
+		if err := p.store.CrearCamion(ctx, camion); err != nil {
+			return err
+		}
+	}
+	log.Printf("Se cargaron %d camiones desde %s\n", len(camiones), ruta)
+	return nil
+}
@@ -0,0 +1,90 @@
+package rutas
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"example.com/whatsapp-integration/store"
+)
+
+// storeFallaRuta es SQLite, pero la primera ruta del camión indicado no se
+// puede guardar.
+type storeFallaRuta struct {
+	*store.SQLiteStore
+	camionID int
+	fallo    bool
+}
+
+func (s *storeFallaRuta) CrearRuta(ctx context.Context, ruta *store.Ruta, paradas []*store.RutaParada, estadoPedidos string) error {
+	if ruta.CamionID == s.camionID && !s.fallo {
+		s.fallo = true
+		return errors.New("sin conexión")
+	}
+	return s.SQLiteStore.CrearRuta(ctx, ruta, paradas, estadoPedidos)
+}
+
+// TestCorteDiarioCompletaTrasFallaParcial vuelve a correr el corte después de
+// que una ruta no se guardó: solo se planifica el camión que faltó y ningún
+// pedido queda sin ruta.
+func TestCorteDiarioCompletaTrasFallaParcial(t *testing.T) {
+	db, err := store.NewSQLiteStore(store.Config{Database: filepath.Join(t.TempDir(), "rutas.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	var camiones []*store.Camion
+	for _, nombre := range []string{"Pipa 1", "Pipa 2"} {
+		c := &store.Camion{Nombre: nombre, MaxPedidos: 1, Activo: true}
+		if err := db.CrearCamion(ctx, c); err != nil {
+			t.Fatal(err)
+		}
+		camiones = append(camiones, c)
+	}
+	cliente := &store.Cliente{NumeroTelefono: "5215550000001", Nombre: "Ana"}
+	if err := db.CrearCliente(ctx, cliente); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := db.CrearPedido(ctx, &store.Pedido{ClienteID: cliente.ID, TipoServicio: "estacionario", CantidadLitros: 100, Estado: "pendiente", Direccion: "Calle 1"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	st := &storeFallaRuta{SQLiteStore: db, camionID: camiones[1].ID}
+	p := NewPlanificador(st, time.UTC, t.TempDir())
+	ahora := time.Date(2026, 3, 2, 5, 0, 0, 0, time.UTC)
+
+	casos := []struct {
+		nombre     string
+		falla      bool
+		rutas      int
+		pendientes int
+	}{
+		{"falla la ruta de un camión", true, 1, 1},
+		{"el reintento completa la ruta faltante", false, 2, 0},
+		{"sin camiones libres no replanifica", false, 2, 0},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			if err := p.CorteDiario(ctx, ahora); (err != nil) != c.falla {
+				t.Fatalf("CorteDiario() = %v, se esperaba falla = %v", err, c.falla)
+			}
+			rutas, err := db.GetRutasPorFecha(ctx, ahora)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pendientes, err := db.GetPedidosPorEstado(ctx, "pendiente")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rutas) != c.rutas || len(pendientes) != c.pendientes {
+				t.Fatalf("rutas = %d, pendientes = %d; se esperaban %d y %d", len(rutas), len(pendientes), c.rutas, c.pendientes)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ZonaHorariaPorDefecto es la zona en la que opera la planta si no se configura otra.
+const ZonaHorariaPorDefecto = "America/Mexico_City"
+
+// Tarea es el trabajo que ejecuta el scheduler. Recibe la hora de ejecución en
+// la zona horaria configurada.
+type Tarea func(ctx context.Context, ahora time.Time) error
+
+type tareaDiaria struct {
+	nombre string
+	hora   int
+	minuto int
+	fn     Tarea
+}
+
+// Scheduler ejecuta tareas diarias a una hora fija, al estilo de cron, dentro
+// del mismo proceso del servidor.
+type Scheduler struct {
+	loc    *time.Location
+	tareas []tareaDiaria
+}
+
+// New crea un scheduler que interpreta las horas en la zona horaria indicada.
+func New(loc *time.Location) *Scheduler {
+	if loc == nil {
+		loc = time.Local
+	}
+	return &Scheduler{loc: loc}
+}
+
+// Location regresa la zona horaria del scheduler.
+func (s *Scheduler) Location() *time.Location {
+	return s.loc
+}
+
+// Diaria registra una tarea que se ejecuta todos los días a la hora indicada.
+// Debe llamarse antes de Start.
+func (s *Scheduler) Diaria(nombre string, hora, minuto int, fn Tarea) {
+	s.tareas = append(s.tareas, tareaDiaria{nombre: nombre, hora: hora, minuto: minuto, fn: fn})
+}
+
+// Start lanza una gorrutina por tarea registrada. Se detienen al cancelar ctx.
+func (s *Scheduler) Start(ctx context.Context) {
+	for _, t := range s.tareas {
+		go s.ejecutarDiaria(ctx, t)
+	}
+}
+
+func (s *Scheduler) ejecutarDiaria(ctx context.Context, t tareaDiaria) {
+	for {
+		ahora := time.Now().In(s.loc)
+		siguiente := SiguienteEjecucion(ahora, t.hora, t.minuto)
+		log.Printf("Tarea %s programada para %s\n", t.nombre, siguiente.Format(time.RFC3339))
+
+		timer := time.NewTimer(siguiente.Sub(ahora))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if err := t.fn(ctx, time.Now().In(s.loc)); err != nil {
+			log.Printf("Error ejecutando tarea %s: %v\n", t.nombre, err)
+		}
+	}
+}
+
+// SiguienteEjecucion calcula el próximo instante hora:minuto posterior a ahora,
+// en la zona horaria de ahora.
+func SiguienteEjecucion(ahora time.Time, hora, minuto int) time.Time {
+	siguiente := time.Date(ahora.Year(), ahora.Month(), ahora.Day(), hora, minuto, 0, 0, ahora.Location())
+	if !siguiente.After(ahora) {
+		siguiente = time.Date(ahora.Year(), ahora.Month(), ahora.Day()+1, hora, minuto, 0, 0, ahora.Location())
+	}
+	return siguiente
+}
+
+// ZonaHorariaDesdeEnv lee ZONA_HORARIA; si falta o es inválida usa la zona por defecto.
+func ZonaHorariaDesdeEnv() *time.Location {
+	nombre := os.Getenv("ZONA_HORARIA")
+	if nombre == "" {
+		nombre = ZonaHorariaPorDefecto
+	}
+	loc, err := time.LoadLocation(nombre)
+	if err != nil {
+		log.Printf("ADVERTENCIA: zona horaria inválida '%s' (%v). Usando hora local.\n", nombre, err)
+		return time.Local
+	}
+	return loc
+}
+
+// HoraDesdeEnv lee una hora en formato HH:MM de la variable indicada.
+func HoraDesdeEnv(variable string, horaDefecto, minutoDefecto int) (int, int) {
+	valor := os.Getenv(variable)
+	if valor == "" {
+		return horaDefecto, minutoDefecto
+	}
+	hora, minuto, err := ParseHora(valor)
+	if err != nil {
+		log.Printf("ADVERTENCIA: %s inválida ('%s'): %v. Usando %02d:%02d.\n", variable, valor, err, horaDefecto, minutoDefecto)
+		return horaDefecto, minutoDefecto
+	}
+	return hora, minuto
+}
+
+// ParseHora interpreta una hora en formato HH:MM.
+func ParseHora(valor string) (int, int, error) {
+	partes := strings.SplitN(strings.TrimSpace(valor), ":", 2)
+	if len(partes) != 2 {
+		return 0, 0, fmt.Errorf("formato esperado HH:MM")
+	}
+	hora, err := strconv.Atoi(partes[0])
+	if err != nil || hora < 0 || hora > 23 {
+		return 0, 0, fmt.Errorf("hora fuera de rango")
+	}
+	minuto, err := strconv.Atoi(partes[1])
+	if err != nil || minuto < 0 || minuto > 59 {
+		return 0, 0, fmt.Errorf("minuto fuera de rango")
+	}
+	return hora, minuto, nil
+}
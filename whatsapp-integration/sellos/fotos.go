@@ -0,0 +1,96 @@
+package sellos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"example.com/whatsapp-integration/store"
+)
+
+// DirectorioFotosPorDefecto es donde se guardan las fotos si no se
+// configura FOTOS_SELLO_DIR.
+const DirectorioFotosPorDefecto = "fotos_sello"
+
+// maxFotoBytes limita el tamaño de cada foto.
+const maxFotoBytes = 10 << 20
+
+// ErrTipoFoto indica un archivo que no es imagen.
+var ErrTipoFoto = errors.New("tipo de foto no aceptado")
+
+var extensionesFoto = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+	"image/heic": ".heic",
+}
+
+// AgregarFoto descarga la foto que mandó el cliente y la adjunta al caso.
+func (s *Servicio) AgregarFoto(ctx context.Context, reporteID int, mediaURL, mimeType string) (*store.FotoReporteSello, error) {
+	reporte, err := s.abierto(ctx, reporteID)
+	if err != nil {
+		return nil, err
+	}
+	mimeType = strings.ToLower(strings.TrimSpace(strings.Split(mimeType, ";")[0]))
+	ext, ok := extensionesFoto[mimeType]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrTipoFoto, mimeType)
+	}
+
+	archivo, err := s.descargarFoto(ctx, reporte.ID, mediaURL, ext)
+	if err != nil {
+		return nil, fmt.Errorf("error guardando foto del reporte %d: %w", reporte.ID, err)
+	}
+	foto := &store.FotoReporteSello{ReporteID: reporte.ID, Archivo: archivo, MimeType: mimeType}
+	if err := s.store.AgregarFotoReporteSello(ctx, foto); err != nil {
+		os.Remove(archivo)
+		return nil, err
+	}
+	return foto, nil
+}
+
+func (s *Servicio) descargarFoto(ctx context.Context, reporteID int, mediaURL, ext string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, mediaURL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("descarga de la foto: %s", resp.Status)
+	}
+
+	dir := s.cfg.DirectorioFotos
+	if dir == "" {
+		dir = DirectorioFotosPorDefecto
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	ruta := filepath.Join(dir, fmt.Sprintf("sello-%d-%d%s", reporteID, time.Now().UnixNano(), ext))
+	f, err := os.Create(ruta)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	n, err := io.Copy(f, io.LimitReader(resp.Body, maxFotoBytes+1))
+	if err == nil && n > maxFotoBytes {
+		err = fmt.Errorf("la foto excede %d MB", maxFotoBytes>>20)
+	}
+	if err != nil {
+		f.Close()
+		os.Remove(ruta)
+		return "", err
+	}
+	return ruta, nil
+}
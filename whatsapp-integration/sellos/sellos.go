@@ -0,0 +1,488 @@
+// Package sellos lleva los casos de sello violado o tanque dañado que
+// reportan los clientes: los liga al pedido y al tanque, guarda las fotos,
+// los asigna a un supervisor con un plazo de atención (SLA) y avisa al
+// cliente por WhatsApp cuando el caso se resuelve.
+package sellos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"example.com/whatsapp-integration/delivery"
+	"example.com/whatsapp-integration/store"
+)
+
+// Tipos de reporte (columna tipo_reporte).
+const (
+	TipoSelloViolado = "sello_violado"
+	TipoTanqueDanado = "tanque_danado"
+	TipoOtro         = "otro"
+)
+
+// Estados de un caso.
+const (
+	EstadoPendiente  = "pendiente"   // recién reportado, nadie lo ha tomado
+	EstadoEnRevision = "en_revision" // un supervisor lo está atendiendo
+	EstadoResuelto   = "resuelto"
+	EstadoCancelado  = "cancelado" // el reporte no procede
+)
+
+// Estados del pedido cuyo cliente dice que no lo recibió.
+const (
+	PedidoEnDisputa   = "en_disputa"   // mientras el caso está abierto
+	PedidoNoEntregado = "no_entregado" // el caso procedió
+)
+
+// SLAPorDefecto es el plazo para resolver un caso si no se configura
+// SELLOS_SLA_HORAS.
+const SLAPorDefecto = 4 * time.Hour
+
+// entregaReciente es cuánto tiempo después de entregado un pedido se le
+// puede ligar un reporte; el cliente suele notar el sello al recibirlo.
+const entregaReciente = 24 * time.Hour
+
+var (
+	ErrReporteNoEncontrado = errors.New("reporte de sello no encontrado")
+	ErrReporteCerrado      = errors.New("el reporte ya está cerrado")
+	ErrTipoReporte         = errors.New("tipo de reporte no válido")
+)
+
+var nombresTipo = map[string]string{
+	TipoSelloViolado: "sello violado",
+	TipoTanqueDanado: "tanque dañado",
+	TipoOtro:         "otro",
+}
+
+// Config son los parámetros de atención de los casos.
+type Config struct {
+	SLA             time.Duration
+	Supervisores    []string // asignación automática; vacío deja los casos sin asignar
+	DirectorioFotos string
+}
+
+// ConfigDesdeEnv lee SELLOS_SLA_HORAS (por defecto 4), SELLOS_SUPERVISORES
+// (separados por comas) y FOTOS_SELLO_DIR.
+func ConfigDesdeEnv() Config {
+	cfg := Config{SLA: SLAPorDefecto, DirectorioFotos: os.Getenv("FOTOS_SELLO_DIR")}
+	if v, err := strconv.ParseFloat(os.Getenv("SELLOS_SLA_HORAS"), 64); err == nil && v > 0 {
+		cfg.SLA = time.Duration(v * float64(time.Hour))
+	}
+	for _, s := range strings.Split(os.Getenv("SELLOS_SUPERVISORES"), ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			cfg.Supervisores = append(cfg.Supervisores, s)
+		}
+	}
+	return cfg
+}
+
+// Notificador envía mensajes de WhatsApp al cliente.
+type Notificador interface {
+	SendMessage(to, message string) error
+}
+
+// EstadoPedidos cambia el estado de un pedido y avisa a los observadores
+// (puntos, referidos, pagos). Lo implementa delivery.MapsService.
+type EstadoPedidos interface {
+	UpdateDeliveryStatus(ctx context.Context, pedidoID int, status string, location delivery.Location) error
+}
+
+// Caso es un reporte con sus fotos, como lo ve el supervisor.
+type Caso struct {
+	*store.ReporteSello
+	Fotos      []*store.FotoReporteSello `json:",omitempty"`
+	SLAVencido bool
+}
+
+// Servicio abre, asigna y resuelve los casos.
+type Servicio struct {
+	store   store.Store
+	cfg     Config
+	loc     *time.Location
+	sender  Notificador
+	pedidos EstadoPedidos
+	client  *http.Client // descarga de fotos
+}
+
+func NewServicio(s store.Store, cfg Config, loc *time.Location) *Servicio {
+	if loc == nil {
+		loc = time.Local
+	}
+	if cfg.SLA <= 0 {
+		cfg.SLA = SLAPorDefecto
+	}
+	return &Servicio{store: s, cfg: cfg, loc: loc, client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// SetSender habilita el aviso al cliente cuando se resuelve su caso.
+func (s *Servicio) SetSender(n Notificador) {
+	s.sender = n
+}
+
+// SetEstadoPedidos hace que poner un pedido en disputa y resolverla pase
+// por los observadores de estado. Sin él solo se actualiza el pedido.
+func (s *Servicio) SetEstadoPedidos(e EstadoPedidos) {
+	s.pedidos = e
+}
+
+// NombreTipo es el tipo de reporte para mostrarlo al cliente.
+func NombreTipo(tipo string) string {
+	if n, ok := nombresTipo[tipo]; ok {
+		return n
+	}
+	return tipo
+}
+
+// Abrir registra el reporte del cliente ligado a su pedido activo (o al que
+// se le acaba de entregar) y al tanque del pedido. Si hay supervisores
+// configurados, se asigna al que tenga menos casos abiertos.
+func (s *Servicio) Abrir(ctx context.Context, cliente *store.Cliente, tipo, descripcion string, ahora time.Time) (*store.ReporteSello, error) {
+	pedido, err := s.pedidoDelReporte(ctx, cliente.ID, ahora)
+	if err != nil {
+		return nil, err
+	}
+	return s.AbrirParaPedido(ctx, cliente, pedido, tipo, descripcion, ahora)
+}
+
+// AbrirParaPedido es Abrir cuando ya se sabe de qué pedido se queja el
+// cliente, por ejemplo al negar que lo recibió. pedido puede ser nil.
+func (s *Servicio) AbrirParaPedido(ctx context.Context, cliente *store.Cliente, pedido *store.Pedido, tipo, descripcion string, ahora time.Time) (*store.ReporteSello, error) {
+	if _, ok := nombresTipo[tipo]; !ok {
+		return nil, fmt.Errorf("%w: %q", ErrTipoReporte, tipo)
+	}
+	vence := ahora.Add(s.cfg.SLA)
+	reporte := &store.ReporteSello{
+		ClienteID:    cliente.ID,
+		TipoReporte:  tipo,
+		Descripcion:  descripcion,
+		Estado:       EstadoPendiente,
+		FechaReporte: ahora,
+		VenceSLA:     &vence,
+	}
+
+	if pedido != nil {
+		reporte.PedidoID = &pedido.ID
+		tanque, err := s.tanqueDelReporte(ctx, pedido.ID, descripcion)
+		if err != nil {
+			return nil, err
+		}
+		if tanque != nil {
+			reporte.TanqueID = &tanque.ID
+		}
+	}
+
+	supervisor, err := s.supervisorDisponible(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if supervisor != "" {
+		reporte.Supervisor = supervisor
+		reporte.AsignadoEn = &ahora
+	}
+
+	if err := s.store.CrearReporteSello(ctx, reporte); err != nil {
+		return nil, err
+	}
+	log.Printf("Reporte de sello %d del cliente %d (pedido %v) asignado a %q, vence %s\n",
+		reporte.ID, cliente.ID, pedidoOVacio(reporte.PedidoID), supervisor, vence.In(s.loc).Format("2006-01-02 15:04"))
+	return reporte, nil
+}
+
+// Disputar abre el caso de un pedido que el repartidor marcó como entregado
+// y el cliente dice no haber recibido. El pedido queda en disputa y deja de
+// contar como entregado (puntos, referidos, corte de caja) hasta que se
+// resuelva el caso.
+func (s *Servicio) Disputar(ctx context.Context, cliente *store.Cliente, pedido *store.Pedido, descripcion string, ahora time.Time) (*store.ReporteSello, error) {
+	reporte, err := s.AbrirParaPedido(ctx, cliente, pedido, TipoOtro, descripcion, ahora)
+	if err != nil {
+		return nil, err
+	}
+	if pedido != nil {
+		if err := s.cambiarEstadoPedido(ctx, pedido.ID, PedidoEnDisputa); err != nil {
+			log.Printf("Error poniendo en disputa el pedido %d del reporte %d: %v\n", pedido.ID, reporte.ID, err)
+		}
+	}
+	return reporte, nil
+}
+
+// pedidoDelReporte es el pedido en curso del cliente o, si no tiene, el
+// último que se le entregó hace poco.
+func (s *Servicio) pedidoDelReporte(ctx context.Context, clienteID int, ahora time.Time) (*store.Pedido, error) {
+	pedido, err := s.store.GetUltimoPedidoActivo(ctx, clienteID)
+	if err != nil {
+		return nil, fmt.Errorf("error buscando pedido activo del cliente %d: %w", clienteID, err)
+	}
+	if pedido != nil {
+		return pedido, nil
+	}
+	pedido, err = s.store.GetUltimoPedido(ctx, clienteID)
+	if err != nil {
+		return nil, fmt.Errorf("error buscando último pedido del cliente %d: %w", clienteID, err)
+	}
+	if pedido != nil && pedido.Estado == "entregado" && ahora.Sub(pedido.UpdatedAt) <= entregaReciente {
+		return pedido, nil
+	}
+	return nil, nil
+}
+
+// tanqueDelReporte liga el tanque cuyo código QR mencionó el cliente o, si
+// el pedido trae un solo tanque, ese.
+func (s *Servicio) tanqueDelReporte(ctx context.Context, pedidoID int, descripcion string) (*store.Tanque, error) {
+	tanques, err := s.store.GetTanquesPedido(ctx, pedidoID)
+	if err != nil {
+		return nil, err
+	}
+	texto := strings.ToUpper(descripcion)
+	for _, t := range tanques {
+		if t.CodigoQR != "" && strings.Contains(texto, strings.ToUpper(t.CodigoQR)) {
+			return t, nil
+		}
+	}
+	if len(tanques) == 1 {
+		return tanques[0], nil
+	}
+	return nil, nil
+}
+
+// supervisorDisponible es el supervisor configurado con menos casos
+// abiertos; en empate, el primero de la lista.
+func (s *Servicio) supervisorDisponible(ctx context.Context) (string, error) {
+	if len(s.cfg.Supervisores) == 0 {
+		return "", nil
+	}
+	abiertos, err := s.Abiertos(ctx)
+	if err != nil {
+		return "", err
+	}
+	carga := make(map[string]int)
+	for _, r := range abiertos {
+		carga[r.Supervisor]++
+	}
+	elegido := s.cfg.Supervisores[0]
+	for _, sup := range s.cfg.Supervisores[1:] {
+		if carga[sup] < carga[elegido] {
+			elegido = sup
+		}
+	}
+	return elegido, nil
+}
+
+// Abiertos regresa los casos pendientes y en revisión.
+func (s *Servicio) Abiertos(ctx context.Context) ([]*store.ReporteSello, error) {
+	var abiertos []*store.ReporteSello
+	for _, estado := range []string{EstadoPendiente, EstadoEnRevision} {
+		reportes, err := s.store.GetReportesSelloPorEstado(ctx, estado)
+		if err != nil {
+			return nil, err
+		}
+		abiertos = append(abiertos, reportes...)
+	}
+	return abiertos, nil
+}
+
+// Vencidos regresa los casos abiertos que ya pasaron su SLA.
+func (s *Servicio) Vencidos(ctx context.Context, ahora time.Time) ([]*store.ReporteSello, error) {
+	abiertos, err := s.Abiertos(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var vencidos []*store.ReporteSello
+	for _, r := range abiertos {
+		if slaVencido(r, ahora) {
+			vencidos = append(vencidos, r)
+		}
+	}
+	return vencidos, nil
+}
+
+// Listar regresa los casos en el estado indicado (todos si es "") y, si se
+// indica, solo los del supervisor o los que ya pasaron su SLA.
+func (s *Servicio) Listar(ctx context.Context, estado, supervisor string, soloVencidos bool, ahora time.Time) ([]*Caso, error) {
+	reportes, err := s.store.GetReportesSelloPorEstado(ctx, estado)
+	if err != nil {
+		return nil, err
+	}
+	casos := []*Caso{}
+	for _, r := range reportes {
+		if supervisor != "" && r.Supervisor != supervisor {
+			continue
+		}
+		c := &Caso{ReporteSello: r, SLAVencido: slaVencido(r, ahora)}
+		if soloVencidos && !c.SLAVencido {
+			continue
+		}
+		casos = append(casos, c)
+	}
+	return casos, nil
+}
+
+// Caso regresa el reporte con sus fotos, o nil si no existe.
+func (s *Servicio) Caso(ctx context.Context, id int, ahora time.Time) (*Caso, error) {
+	reporte, err := s.store.GetReporteSello(ctx, id)
+	if err != nil || reporte == nil {
+		return nil, err
+	}
+	fotos, err := s.store.GetFotosReporteSello(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &Caso{ReporteSello: reporte, Fotos: fotos, SLAVencido: slaVencido(reporte, ahora)}, nil
+}
+
+// Asignar pone el caso a cargo del supervisor y lo pasa a revisión. Un tipo
+// vacío conserva el que reportó el cliente.
+func (s *Servicio) Asignar(ctx context.Context, id int, supervisor, tipo string, ahora time.Time) (*store.ReporteSello, error) {
+	reporte, err := s.abierto(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if tipo != "" {
+		if _, ok := nombresTipo[tipo]; !ok {
+			return nil, fmt.Errorf("%w: %q", ErrTipoReporte, tipo)
+		}
+		reporte.TipoReporte = tipo
+	}
+	reporte.Supervisor = supervisor
+	reporte.AsignadoEn = &ahora
+	reporte.Estado = EstadoEnRevision
+	if err := s.store.ActualizarReporteSello(ctx, reporte); err != nil {
+		return nil, err
+	}
+	return reporte, nil
+}
+
+// Resolver cierra el caso como resuelto o, si no procede, cancelado, y le
+// manda la resolución al cliente.
+func (s *Servicio) Resolver(ctx context.Context, id int, procede bool, resolucion, supervisor string, ahora time.Time) (*store.ReporteSello, error) {
+	reporte, err := s.abierto(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	reporte.Estado = EstadoResuelto
+	if !procede {
+		reporte.Estado = EstadoCancelado
+	}
+	reporte.Resolucion = resolucion
+	if supervisor != "" {
+		reporte.Supervisor = supervisor
+	}
+	reporte.ResueltoEn = &ahora
+	if err := s.store.ActualizarReporteSello(ctx, reporte); err != nil {
+		return nil, err
+	}
+	if err := s.cerrarDisputa(ctx, reporte, procede); err != nil {
+		log.Printf("Error cerrando la disputa del reporte %d: %v\n", reporte.ID, err)
+	}
+	s.avisarResolucion(ctx, reporte)
+	return reporte, nil
+}
+
+// cerrarDisputa saca de disputa el pedido del caso: si procede, el pedido
+// no se entregó; si no, vuelve a contar como entregado.
+func (s *Servicio) cerrarDisputa(ctx context.Context, reporte *store.ReporteSello, procede bool) error {
+	if reporte.PedidoID == nil {
+		return nil
+	}
+	pedido, err := s.store.GetPedido(ctx, *reporte.PedidoID)
+	if err != nil || pedido == nil || pedido.Estado != PedidoEnDisputa {
+		return err
+	}
+	estado := "entregado"
+	if procede {
+		estado = PedidoNoEntregado
+	}
+	return s.cambiarEstadoPedido(ctx, pedido.ID, estado)
+}
+
+func (s *Servicio) cambiarEstadoPedido(ctx context.Context, pedidoID int, estado string) error {
+	if s.pedidos != nil {
+		return s.pedidos.UpdateDeliveryStatus(ctx, pedidoID, estado, delivery.Location{})
+	}
+	pedido, err := s.store.GetPedido(ctx, pedidoID)
+	if err != nil || pedido == nil {
+		return err
+	}
+	pedido.Estado = estado
+	return s.store.ActualizarPedido(ctx, pedido)
+}
+
+// Detallar agrega al caso abierto lo que el cliente cuenta después de
+// abrirlo.
+func (s *Servicio) Detallar(ctx context.Context, id int, detalle string) (*store.ReporteSello, error) {
+	reporte, err := s.abierto(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if detalle = strings.TrimSpace(detalle); detalle == "" {
+		return reporte, nil
+	}
+	if reporte.Descripcion != "" {
+		reporte.Descripcion += "\n"
+	}
+	reporte.Descripcion += detalle
+	if err := s.store.ActualizarReporteSello(ctx, reporte); err != nil {
+		return nil, err
+	}
+	return reporte, nil
+}
+
+func (s *Servicio) abierto(ctx context.Context, id int) (*store.ReporteSello, error) {
+	reporte, err := s.store.GetReporteSello(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if reporte == nil {
+		return nil, ErrReporteNoEncontrado
+	}
+	if reporte.Estado == EstadoResuelto || reporte.Estado == EstadoCancelado {
+		return nil, ErrReporteCerrado
+	}
+	return reporte, nil
+}
+
+func (s *Servicio) avisarResolucion(ctx context.Context, reporte *store.ReporteSello) {
+	if s.sender == nil {
+		return
+	}
+	cliente, err := s.store.GetClientePorID(ctx, reporte.ClienteID)
+	if err != nil || cliente == nil {
+		log.Printf("No se pudo avisar la resolución del reporte %d: cliente %d no encontrado (%v)\n", reporte.ID, reporte.ClienteID, err)
+		return
+	}
+
+	var msg string
+	if reporte.Estado == EstadoResuelto {
+		msg = fmt.Sprintf("✅ *Reporte #%d resuelto*\n\nRevisamos tu reporte de %s", reporte.ID, NombreTipo(reporte.TipoReporte))
+	} else {
+		msg = fmt.Sprintf("*Reporte #%d cerrado*\n\nRevisamos tu reporte de %s y no encontramos motivo para proceder", reporte.ID, NombreTipo(reporte.TipoReporte))
+	}
+	if reporte.PedidoID != nil {
+		msg += fmt.Sprintf(" del pedido #%d", *reporte.PedidoID)
+	}
+	msg += "."
+	if reporte.Resolucion != "" {
+		msg += "\n\n" + reporte.Resolucion
+	}
+	msg += "\n\nSi tienes dudas, responde a este mensaje."
+	if err := s.sender.SendMessage(cliente.NumeroTelefono, msg); err != nil {
+		log.Printf("Error avisando la resolución del reporte %d: %v\n", reporte.ID, err)
+	}
+}
+
+func slaVencido(r *store.ReporteSello, ahora time.Time) bool {
+	abierto := r.Estado == EstadoPendiente || r.Estado == EstadoEnRevision
+	return abierto && r.VenceSLA != nil && ahora.After(*r.VenceSLA)
+}
+
+func pedidoOVacio(id *int) interface{} {
+	if id == nil {
+		return "sin pedido"
+	}
+	return *id
+}
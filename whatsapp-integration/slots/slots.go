@@ -0,0 +1,226 @@
+package slots
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"example.com/whatsapp-integration/scheduler"
+	"example.com/whatsapp-integration/store"
+)
+
+// DiasAdelantePorDefecto es cuántos días hacia adelante se ofrecen horarios.
+const DiasAdelantePorDefecto = 7
+
+var diasCortos = []string{"Dom", "Lun", "Mar", "Mié", "Jue", "Vie", "Sáb"}
+
+// Slot es una ventana de entrega en una fecha concreta con su capacidad restante.
+type Slot struct {
+	Ventana       *store.VentanaEntrega
+	Fecha         time.Time
+	PedidosLibres int     // -1 = sin límite
+	LitrosLibres  float64 // -1 = sin límite
+}
+
+// Etiqueta es el texto que ve el cliente y que se guarda en HorarioPreferido.
+func (s Slot) Etiqueta() string {
+	return fmt.Sprintf("%s %s %s-%s",
+		diasCortos[s.Fecha.Weekday()], s.Fecha.Format("02/01"), s.Ventana.HoraInicio, s.Ventana.HoraFin)
+}
+
+// Service calcula la disponibilidad de las ventanas de entrega y reserva
+// capacidad para los pedidos confirmados.
+type Service struct {
+	store        store.Store
+	loc          *time.Location
+	horaCorte    int
+	minutoCorte  int
+	diasAdelante int
+}
+
+// NewService crea el servicio de slots. Los pedidos confirmados después del
+// corte diario (HORA_CORTE_DIARIO, 05:00 por defecto) ya no entran al día actual.
+func NewService(s store.Store, loc *time.Location) *Service {
+	hora, minuto := scheduler.HoraDesdeEnv("HORA_CORTE_DIARIO", 5, 0)
+	return &Service{
+		store:        s,
+		loc:          loc,
+		horaCorte:    hora,
+		minutoCorte:  minuto,
+		diasAdelante: DiasAdelantePorDefecto,
+	}
+}
+
+// Disponibles regresa los slots de la zona con cupo para un pedido de los
+// litros indicados, ordenados por fecha y hora de inicio.
+func (s *Service) Disponibles(ctx context.Context, zona string, litros float64, ahora time.Time) ([]Slot, error) {
+	ventanas, err := s.store.GetVentanasEntrega(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ahora = ahora.In(s.loc)
+	primerDia := time.Date(ahora.Year(), ahora.Month(), ahora.Day(), 0, 0, 0, 0, s.loc)
+	corte := time.Date(ahora.Year(), ahora.Month(), ahora.Day(), s.horaCorte, s.minutoCorte, 0, 0, s.loc)
+	if !ahora.Before(corte) {
+		primerDia = primerDia.AddDate(0, 0, 1)
+	}
+
+	var disponibles []Slot
+	for d := 0; d < s.diasAdelante; d++ {
+		delDia, err := s.conCupo(ctx, ventanas, zona, litros, primerDia.AddDate(0, 0, d))
+		if err != nil {
+			return nil, err
+		}
+		disponibles = append(disponibles, delDia...)
+	}
+
+	sort.SliceStable(disponibles, func(i, j int) bool {
+		if !disponibles[i].Fecha.Equal(disponibles[j].Fecha) {
+			return disponibles[i].Fecha.Before(disponibles[j].Fecha)
+		}
+		return disponibles[i].Ventana.HoraInicio < disponibles[j].Ventana.HoraInicio
+	})
+	return disponibles, nil
+}
+
+// DelDia regresa los slots de la zona con cupo en la fecha indicada, sin
+// aplicar el corte diario. Lo usan las entregas programadas al generarse.
+func (s *Service) DelDia(ctx context.Context, zona string, litros float64, fecha time.Time) ([]Slot, error) {
+	ventanas, err := s.store.GetVentanasEntrega(ctx)
+	if err != nil {
+		return nil, err
+	}
+	fecha = fecha.In(s.loc)
+	disponibles, err := s.conCupo(ctx, ventanas, zona, litros, time.Date(fecha.Year(), fecha.Month(), fecha.Day(), 0, 0, 0, 0, s.loc))
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(disponibles, func(i, j int) bool {
+		return disponibles[i].Ventana.HoraInicio < disponibles[j].Ventana.HoraInicio
+	})
+	return disponibles, nil
+}
+
+// conCupo regresa las ventanas de la zona que ese día admiten el pedido.
+func (s *Service) conCupo(ctx context.Context, ventanas []*store.VentanaEntrega, zona string, litros float64, fecha time.Time) ([]Slot, error) {
+	var disponibles []Slot
+	for _, v := range ventanas {
+		if v.DiaSemana != int(fecha.Weekday()) || (v.Zona != "" && v.Zona != zona) {
+			continue
+		}
+
+		pedidos, ocupados, err := s.store.GetOcupacionSlot(ctx, v.ID, fecha)
+		if err != nil {
+			return nil, err
+		}
+		if !v.Admite(pedidos, ocupados, litros) {
+			continue
+		}
+
+		slot := Slot{Ventana: v, Fecha: fecha, PedidosLibres: -1, LitrosLibres: -1}
+		if max := v.CapacidadPedidos(); max > 0 {
+			slot.PedidosLibres = max - pedidos
+		}
+		if max := v.CapacidadLitros(); max > 0 {
+			slot.LitrosLibres = max - ocupados
+		}
+		disponibles = append(disponibles, slot)
+	}
+	return disponibles, nil
+}
+
+// Reservar aparta el slot para el pedido. Regresa store.ErrSlotSinCapacidad si
+// otro pedido ocupó el último lugar entre la oferta y la confirmación.
+func (s *Service) Reservar(ctx context.Context, slot Slot, pedido *store.Pedido) error {
+	reserva := &store.ReservaSlot{
+		Fecha:    slot.Fecha,
+		PedidoID: pedido.ID,
+		Litros:   pedido.CantidadLitros,
+	}
+	return s.store.ReservarSlot(ctx, reserva, slot.Ventana)
+}
+
+// CrearPedido guarda el pedido junto con su reserva del slot. Si el slot se
+// llenó regresa store.ErrSlotSinCapacidad y el pedido no se guarda.
+func (s *Service) CrearPedido(ctx context.Context, slot Slot, pedido *store.Pedido) error {
+	reserva := &store.ReservaSlot{
+		Fecha:  slot.Fecha,
+		Litros: pedido.CantidadLitros,
+	}
+	return s.store.CrearPedidoConSlot(ctx, pedido, reserva, slot.Ventana)
+}
+
+// Liberar devuelve la capacidad reservada por un pedido cancelado.
+func (s *Service) Liberar(ctx context.Context, pedidoID int) error {
+	return s.store.LiberarSlot(ctx, pedidoID)
+}
+
+// CargarVentanasDesdeArchivo siembra las ventanas de entrega desde un JSON
+// cuando la tabla está vacía. Las ventanas existentes se administran en la base.
+func (s *Service) CargarVentanasDesdeArchivo(ctx context.Context, ruta string) error {
+	existentes, err := s.store.GetVentanasEntrega(ctx)
+	if err != nil {
+		return err
+	}
+	if len(existentes) > 0 {
+		log.Printf("Ventanas de entrega ya configuradas (%d); se ignora %s\n", len(existentes), ruta)
+		return nil
+	}
+
+	data, err := os.ReadFile(ruta)
+	if err != nil {
+		return fmt.Errorf("error leyendo ventanas de entrega: %w", err)
+	}
+
+	var ventanas []struct {
+		Zona             string  `json:"zona"`
+		DiaSemana        int     `json:"diaSemana"`
+		HoraInicio       string  `json:"horaInicio"`
+		HoraFin          string  `json:"horaFin"`
+		Camiones         int     `json:"camiones"`
+		MaxPedidosCamion int     `json:"maxPedidosCamion"`
+		MaxLitrosCamion  float64 `json:"maxLitrosCamion"`
+	}
+	if err := json.Unmarshal(data, &ventanas); err != nil {
+		return fmt.Errorf("error decodificando ventanas de entrega: %w", err)
+	}
+
+	for _, v := range ventanas {
+		if v.DiaSemana < 0 || v.DiaSemana > 6 {
+			return fmt.Errorf("día de la semana inválido en ventana %s-%s: %d", v.HoraInicio, v.HoraFin, v.DiaSemana)
+		}
+		horaInicio, minutoInicio, err := scheduler.ParseHora(v.HoraInicio)
+		if err != nil {
+			return fmt.Errorf("hora de inicio inválida '%s': %w", v.HoraInicio, err)
+		}
+		horaFin, minutoFin, err := scheduler.ParseHora(v.HoraFin)
+		if err != nil {
+			return fmt.Errorf("hora de fin inválida '%s': %w", v.HoraFin, err)
+		}
+		camiones := v.Camiones
+		if camiones < 1 {
+			camiones = 1
+		}
+
+		ventana := &store.VentanaEntrega{
+			Zona:             v.Zona,
+			DiaSemana:        v.DiaSemana,
+			HoraInicio:       fmt.Sprintf("%02d:%02d", horaInicio, minutoInicio),
+			HoraFin:          fmt.Sprintf("%02d:%02d", horaFin, minutoFin),
+			Camiones:         camiones,
+			MaxPedidosCamion: v.MaxPedidosCamion,
+			MaxLitrosCamion:  v.MaxLitrosCamion,
+			Activo:           true,
+		}
+		if err := s.store.CrearVentanaEntrega(ctx, ventana); err != nil {
+			return err
+		}
+	}
+	log.Printf("Se cargaron %d ventanas de entrega desde %s\n", len(ventanas), ruta)
+	return nil
+}
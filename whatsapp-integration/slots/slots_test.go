@@ -0,0 +1,220 @@
+package slots
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"example.com/whatsapp-integration/store"
+)
+
+func TestVentanaAdmite(t *testing.T) {
+	casos := []struct {
+		nombre  string
+		ventana store.VentanaEntrega
+		pedidos int
+		litros  float64
+		nuevos  float64
+		admite  bool
+	}{
+		{"sin límites", store.VentanaEntrega{Camiones: 2}, 100, 10000, 500, true},
+		{"cabe un pedido más", store.VentanaEntrega{Camiones: 2, MaxPedidosCamion: 3}, 5, 0, 0, true},
+		{"pedidos llenos", store.VentanaEntrega{Camiones: 2, MaxPedidosCamion: 3}, 6, 0, 0, false},
+		{"litros justos", store.VentanaEntrega{Camiones: 1, MaxLitrosCamion: 1000}, 2, 800, 200, true},
+		{"litros excedidos", store.VentanaEntrega{Camiones: 1, MaxLitrosCamion: 1000}, 2, 800, 201, false},
+		{"sin camiones no limita", store.VentanaEntrega{MaxPedidosCamion: 3, MaxLitrosCamion: 1000}, 10, 5000, 100, true},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			if got := c.ventana.Admite(c.pedidos, c.litros, c.nuevos); got != c.admite {
+				t.Fatalf("Admite() = %v, se esperaba %v", got, c.admite)
+			}
+		})
+	}
+}
+
+// storeSlots solo implementa lo que usa la disponibilidad; el resto del Store
+// queda nil y haría panic si se llamara.
+type storeSlots struct {
+	store.Store
+	ventanas  []*store.VentanaEntrega
+	ocupacion map[string][2]float64 // "<ventana>|<fecha>" -> pedidos, litros
+}
+
+func (s *storeSlots) GetVentanasEntrega(ctx context.Context) ([]*store.VentanaEntrega, error) {
+	return s.ventanas, nil
+}
+
+func (s *storeSlots) GetOcupacionSlot(ctx context.Context, ventanaID int, fecha time.Time) (int, float64, error) {
+	o := s.ocupacion[clave(ventanaID, fecha)]
+	return int(o[0]), o[1], nil
+}
+
+func clave(ventanaID int, fecha time.Time) string {
+	return fmt.Sprintf("%d|%s", ventanaID, fecha.Format("2006-01-02"))
+}
+
+func TestDisponibles(t *testing.T) {
+	loc := time.FixedZone("CST", -6*3600)
+	// Lunes 2 de marzo de 2026.
+	lunes := time.Date(2026, 3, 2, 0, 0, 0, 0, loc)
+	martes := lunes.AddDate(0, 0, 1)
+	ventanas := []*store.VentanaEntrega{
+		{ID: 1, DiaSemana: 1, HoraInicio: "14:00", HoraFin: "18:00", Camiones: 1, MaxPedidosCamion: 2},
+		{ID: 2, DiaSemana: 1, HoraInicio: "09:00", HoraFin: "13:00", Camiones: 1, MaxLitrosCamion: 1000},
+		{ID: 3, Zona: "norte", DiaSemana: 2, HoraInicio: "09:00", HoraFin: "13:00", Camiones: 1},
+		{ID: 4, DiaSemana: 2, HoraInicio: "09:00", HoraFin: "13:00", Camiones: 1, MaxPedidosCamion: 1},
+	}
+
+	casos := []struct {
+		nombre   string
+		ahora    time.Time
+		zona     string
+		litros   float64
+		esperado []string
+	}{
+		{"antes del corte entra el día", lunes.Add(4 * time.Hour), "", 100, []string{"Lun 02/03 09:00-13:00", "Lun 02/03 14:00-18:00"}},
+		{"después del corte empieza mañana", lunes.Add(6 * time.Hour), "", 100, nil},
+		{"ventana de otra zona", lunes.Add(6 * time.Hour), "norte", 100, []string{"Mar 03/03 09:00-13:00"}},
+		{"sin litros libres", lunes.Add(4 * time.Hour), "", 400, []string{"Lun 02/03 14:00-18:00"}},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			st := &storeSlots{ventanas: ventanas, ocupacion: map[string][2]float64{
+				clave(1, lunes):  {1, 0},
+				clave(2, lunes):  {3, 700},
+				clave(4, martes): {1, 0},
+			}}
+			s := NewService(st, loc)
+			s.horaCorte, s.minutoCorte, s.diasAdelante = 5, 0, 2
+
+			slots, err := s.Disponibles(context.Background(), c.zona, c.litros, c.ahora)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var etiquetas []string
+			for _, sl := range slots {
+				etiquetas = append(etiquetas, sl.Etiqueta())
+			}
+			if len(etiquetas) != len(c.esperado) {
+				t.Fatalf("slots = %v, se esperaba %v", etiquetas, c.esperado)
+			}
+			for i := range etiquetas {
+				if etiquetas[i] != c.esperado[i] {
+					t.Fatalf("slots = %v, se esperaba %v", etiquetas, c.esperado)
+				}
+			}
+		})
+	}
+}
+
+// TestReservarConcurrente reserva contra SQLite desde varios clientes a la
+// vez: solo entran los pedidos que caben en la ventana.
+func TestReservarConcurrente(t *testing.T) {
+	db, err := store.NewSQLiteStore(store.Config{Database: filepath.Join(t.TempDir(), "slots.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	ventana := &store.VentanaEntrega{DiaSemana: 1, HoraInicio: "09:00", HoraFin: "13:00", Camiones: 1, MaxPedidosCamion: 3, Activo: true}
+	if err := db.CrearVentanaEntrega(ctx, ventana); err != nil {
+		t.Fatal(err)
+	}
+	s := NewService(db, time.UTC)
+	slot := Slot{Ventana: ventana, Fecha: time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)}
+
+	const clientes = 10
+	var wg sync.WaitGroup
+	resultados := make(chan error, clientes)
+	for i := 1; i <= clientes; i++ {
+		wg.Add(1)
+		go func(pedidoID int) {
+			defer wg.Done()
+			resultados <- s.Reservar(ctx, slot, &store.Pedido{ID: pedidoID, CantidadLitros: 100})
+		}(i)
+	}
+	wg.Wait()
+	close(resultados)
+
+	reservados := 0
+	for err := range resultados {
+		switch {
+		case err == nil:
+			reservados++
+		case !errors.Is(err, store.ErrSlotSinCapacidad):
+			t.Fatalf("Reservar() = %v", err)
+		}
+	}
+	if reservados != 3 {
+		t.Fatalf("reservados = %d, se esperaban 3", reservados)
+	}
+
+	// Liberar un lugar deja entrar exactamente a un pedido más.
+	pedidos, _, err := db.GetOcupacionSlot(ctx, ventana.ID, slot.Fecha)
+	if err != nil || pedidos != 3 {
+		t.Fatalf("ocupación = %d, %v; se esperaban 3", pedidos, err)
+	}
+	var liberado int
+	for id := 1; id <= clientes && liberado == 0; id++ {
+		if err := s.Liberar(ctx, id); err == nil {
+			if p, _, _ := db.GetOcupacionSlot(ctx, ventana.ID, slot.Fecha); p == 2 {
+				liberado = id
+			}
+		}
+	}
+	if liberado == 0 {
+		t.Fatal("no se liberó ningún lugar")
+	}
+	if err := s.Reservar(ctx, slot, &store.Pedido{ID: 100, CantidadLitros: 100}); err != nil {
+		t.Fatalf("Reservar() tras liberar = %v", err)
+	}
+	if err := s.Reservar(ctx, slot, &store.Pedido{ID: 101, CantidadLitros: 100}); !errors.Is(err, store.ErrSlotSinCapacidad) {
+		t.Fatalf("Reservar() con la ventana llena = %v, se esperaba ErrSlotSinCapacidad", err)
+	}
+}
+
+// TestCrearPedidoSinCapacidad no deja registro del pedido si su slot se llenó.
+func TestCrearPedidoSinCapacidad(t *testing.T) {
+	db, err := store.NewSQLiteStore(store.Config{Database: filepath.Join(t.TempDir(), "slots.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	ventana := &store.VentanaEntrega{DiaSemana: 1, HoraInicio: "09:00", HoraFin: "13:00", Camiones: 1, MaxPedidosCamion: 1, Activo: true}
+	if err := db.CrearVentanaEntrega(ctx, ventana); err != nil {
+		t.Fatal(err)
+	}
+	cliente := &store.Cliente{NumeroTelefono: "5215550000001", Nombre: "Ana"}
+	if err := db.CrearCliente(ctx, cliente); err != nil {
+		t.Fatal(err)
+	}
+	s := NewService(db, time.UTC)
+	slot := Slot{Ventana: ventana, Fecha: time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)}
+
+	primero := &store.Pedido{ClienteID: cliente.ID, Estado: "pendiente", CantidadLitros: 100}
+	if err := s.CrearPedido(ctx, slot, primero); err != nil {
+		t.Fatal(err)
+	}
+	if reserva, err := db.GetReservaSlot(ctx, primero.ID); err != nil || reserva == nil {
+		t.Fatalf("reserva del primer pedido = %+v, %v", reserva, err)
+	}
+
+	segundo := &store.Pedido{ClienteID: cliente.ID, Estado: "pendiente", CantidadLitros: 100}
+	if err := s.CrearPedido(ctx, slot, segundo); !errors.Is(err, store.ErrSlotSinCapacidad) {
+		t.Fatalf("CrearPedido() con el slot lleno = %v, se esperaba ErrSlotSinCapacidad", err)
+	}
+	if segundo.ID != 0 {
+		t.Fatalf("pedido.ID = %d, no debe quedar asignado", segundo.ID)
+	}
+	if activo, err := db.GetUltimoPedidoActivo(ctx, cliente.ID); err != nil || activo == nil || activo.ID != primero.ID {
+		t.Fatalf("pedido activo = %+v, %v; solo debe existir el primero", activo, err)
+	}
+}
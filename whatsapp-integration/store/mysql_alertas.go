@@ -0,0 +1,55 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+func (s *MySQLStore) CrearAlerta(ctx context.Context, a *Alerta) error {
+	result, err := s.db.ExecContext(ctx, `
+		INSERT INTO alertas (tipo, pedido_id, repartidor_id, referencia_id, mensaje, estado)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		a.Tipo, a.PedidoID, idOpcional(a.RepartidorID), idOpcional(a.ReferenciaID), a.Mensaje, a.Estado)
+	if err != nil {
+		return fmt.Errorf("error creando alerta del pedido %d: %w", a.PedidoID, err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("error obteniendo ID insertado: %w", err)
+	}
+	a.ID = int(id)
+	return nil
+}
+
+func (s *MySQLStore) GetAlerta(ctx context.Context, id int) (*Alerta, error) {
+	a, err := scanAlerta(s.db.QueryRowContext(ctx, `SELECT `+columnasAlerta+` FROM alertas WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error consultando alerta %d: %w", id, err)
+	}
+	return a, nil
+}
+
+func (s *MySQLStore) GetAlertasPorEstado(ctx context.Context, estado string) ([]*Alerta, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+columnasAlerta+` FROM alertas WHERE ? = '' OR estado = ? ORDER BY id`, estado, estado)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando alertas: %w", err)
+	}
+	return leerAlertas(rows)
+}
+
+func (s *MySQLStore) ActualizarAlerta(ctx context.Context, a *Alerta) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE alertas SET estado = ?, enviada_repartidor = ?, entregada_pos = ?, escalada_en = ?,
+			reconocida_por = ?, reconocida_en = ?
+		WHERE id = ?`,
+		a.Estado, a.EnviadaRepartidor, a.EntregadaPOS, a.EscaladaEn, a.ReconocidaPor, a.ReconocidaEn, a.ID)
+	if err != nil {
+		return fmt.Errorf("error actualizando alerta %d: %w", a.ID, err)
+	}
+	return nil
+}
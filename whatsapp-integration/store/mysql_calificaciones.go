@@ -0,0 +1,58 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+func (s *MySQLStore) CrearCalificacion(ctx context.Context, c *Calificacion) error {
+	result, err := s.db.ExecContext(ctx, `
+		INSERT INTO calificaciones (pedido_id, cliente_id, repartidor_id, puntuacion, comentario, escalada_a, escalada_en, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		c.PedidoID, c.ClienteID, idOpcional(c.RepartidorID), c.Puntuacion, c.Comentario, c.EscaladaA, c.EscaladaEn, c.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error creando calificación del pedido %d: %w", c.PedidoID, err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("error obteniendo ID insertado: %w", err)
+	}
+	c.ID = int(id)
+	return nil
+}
+
+func (s *MySQLStore) GetCalificacionPedido(ctx context.Context, pedidoID int) (*Calificacion, error) {
+	c, err := scanCalificacion(s.db.QueryRowContext(ctx, `SELECT `+columnasCalificacion+` FROM calificaciones WHERE pedido_id = ?`, pedidoID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error consultando calificación del pedido %d: %w", pedidoID, err)
+	}
+	return c, nil
+}
+
+func (s *MySQLStore) GetCalificaciones(ctx context.Context, desde, hasta time.Time) ([]*Calificacion, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+columnasCalificacion+` FROM calificaciones
+		WHERE created_at >= ? AND created_at < ?
+		ORDER BY created_at, id`, desde, hasta)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando calificaciones: %w", err)
+	}
+	return leerCalificaciones(rows)
+}
+
+func (s *MySQLStore) ActualizarCalificacion(ctx context.Context, c *Calificacion) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE calificaciones SET repartidor_id = ?, puntuacion = ?, comentario = ?, escalada_a = ?, escalada_en = ?
+		WHERE id = ?`,
+		idOpcional(c.RepartidorID), c.Puntuacion, c.Comentario, c.EscaladaA, c.EscaladaEn, c.ID)
+	if err != nil {
+		return fmt.Errorf("error actualizando calificación %d: %w", c.ID, err)
+	}
+	return nil
+}
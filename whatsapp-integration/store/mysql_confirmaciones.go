@@ -0,0 +1,97 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+func (s *MySQLStore) CrearConfirmacionEntrega(ctx context.Context, c *ConfirmacionEntrega) error {
+	result, err := s.db.ExecContext(ctx, `
+		INSERT INTO confirmaciones_entrega (pedido_id, cliente_id, estado, vence)
+		VALUES (?, ?, ?, ?)`,
+		c.PedidoID, c.ClienteID, c.Estado, c.Vence)
+	if err != nil {
+		return fmt.Errorf("error creando confirmación de entrega del pedido %d: %w", c.PedidoID, err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("error obteniendo ID insertado: %w", err)
+	}
+	c.ID = int(id)
+	return nil
+}
+
+func (s *MySQLStore) GetConfirmacionEntrega(ctx context.Context, id int) (*ConfirmacionEntrega, error) {
+	c, err := scanConfirmacionEntrega(s.db.QueryRowContext(ctx, `SELECT `+columnasConfirmacionEntrega+` FROM confirmaciones_entrega WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error consultando confirmación de entrega %d: %w", id, err)
+	}
+	return c, nil
+}
+
+func (s *MySQLStore) GetConfirmacionEntregaPendiente(ctx context.Context, clienteID int) (*ConfirmacionEntrega, error) {
+	c, err := scanConfirmacionEntrega(s.db.QueryRowContext(ctx, `
+		SELECT `+columnasConfirmacionEntrega+` FROM confirmaciones_entrega
+		WHERE cliente_id = ? AND estado = 'pendiente'
+		ORDER BY id DESC LIMIT 1`, clienteID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error consultando confirmación de entrega del cliente %d: %w", clienteID, err)
+	}
+	return c, nil
+}
+
+func (s *MySQLStore) GetConfirmacionEntregaConfirmada(ctx context.Context, clienteID int) (*ConfirmacionEntrega, error) {
+	c, err := scanConfirmacionEntrega(s.db.QueryRowContext(ctx, `
+		SELECT `+columnasConfirmacionEntrega+` FROM confirmaciones_entrega
+		WHERE cliente_id = ? AND estado = 'confirmada'
+		ORDER BY respondida_en DESC, id DESC LIMIT 1`, clienteID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error consultando entrega confirmada del cliente %d: %w", clienteID, err)
+	}
+	return c, nil
+}
+
+func (s *MySQLStore) GetConfirmacionesEntregaVencidas(ctx context.Context, ahora time.Time) ([]*ConfirmacionEntrega, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+columnasConfirmacionEntrega+` FROM confirmaciones_entrega
+		WHERE estado = 'pendiente' AND vence <= ?
+		ORDER BY id`, ahora)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando confirmaciones de entrega vencidas: %w", err)
+	}
+	return leerConfirmacionesEntrega(rows)
+}
+
+func (s *MySQLStore) GetConfirmacionesEntregaPorEnviar(ctx context.Context) ([]*ConfirmacionEntrega, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+columnasConfirmacionEntrega+` FROM confirmaciones_entrega
+		WHERE estado = 'por_enviar'
+		ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando confirmaciones de entrega por enviar: %w", err)
+	}
+	return leerConfirmacionesEntrega(rows)
+}
+
+func (s *MySQLStore) ActualizarConfirmacionEntrega(ctx context.Context, c *ConfirmacionEntrega) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE confirmaciones_entrega SET estado = ?, vence = ?, respondida_en = ?, reporte_id = ?
+		WHERE id = ?`,
+		c.Estado, c.Vence, c.RespondidaEn, idOpcional(c.ReporteID), c.ID)
+	if err != nil {
+		return fmt.Errorf("error actualizando confirmación de entrega %d: %w", c.ID, err)
+	}
+	return nil
+}
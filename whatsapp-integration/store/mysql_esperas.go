@@ -0,0 +1,92 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+func (s *MySQLStore) CrearEspera(ctx context.Context, espera *EsperaEntrega) error {
+	query := `
+		INSERT INTO esperas_entrega (pedido_id, parada_id, inicio, limite, aviso_enviado, estado, cerrada_por)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`
+
+	var paradaID interface{}
+	if espera.ParadaID != 0 {
+		paradaID = espera.ParadaID
+	}
+	result, err := s.db.ExecContext(ctx, query,
+		espera.PedidoID,
+		paradaID,
+		espera.Inicio,
+		espera.Limite,
+		espera.AvisoEnviado,
+		espera.Estado,
+		espera.CerradaPor,
+	)
+	if err != nil {
+		return fmt.Errorf("error creando espera del pedido %d: %w", espera.PedidoID, err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("error obteniendo ID insertado: %w", err)
+	}
+	espera.ID = int(id)
+	return nil
+}
+
+func (s *MySQLStore) GetEsperaActiva(ctx context.Context, pedidoID int) (*EsperaEntrega, error) {
+	query := `SELECT ` + columnasEspera + ` FROM esperas_entrega
+		WHERE pedido_id = ? AND estado = 'activa'
+		ORDER BY id DESC LIMIT 1`
+
+	espera, err := scanEspera(s.db.QueryRowContext(ctx, query, pedidoID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error escaneando espera del pedido %d: %w", pedidoID, err)
+	}
+	return espera, nil
+}
+
+func (s *MySQLStore) GetEsperasActivas(ctx context.Context) ([]*EsperaEntrega, error) {
+	query := `SELECT ` + columnasEspera + ` FROM esperas_entrega
+		WHERE estado = 'activa'
+		ORDER BY limite`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando esperas activas: %w", err)
+	}
+	defer rows.Close()
+
+	var esperas []*EsperaEntrega
+	for rows.Next() {
+		espera, err := scanEspera(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error escaneando espera: %w", err)
+		}
+		esperas = append(esperas, espera)
+	}
+	return esperas, rows.Err()
+}
+
+func (s *MySQLStore) ActualizarEspera(ctx context.Context, espera *EsperaEntrega) error {
+	query := `
+		UPDATE esperas_entrega
+		SET limite = ?, aviso_enviado = ?, estado = ?, cerrada_por = ?
+		WHERE id = ?`
+
+	if _, err := s.db.ExecContext(ctx, query,
+		espera.Limite,
+		espera.AvisoEnviado,
+		espera.Estado,
+		espera.CerradaPor,
+		espera.ID,
+	); err != nil {
+		return fmt.Errorf("error actualizando espera %d: %w", espera.ID, err)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+func (s *MySQLStore) GetDatosFiscales(ctx context.Context, clienteID int) (*DatosFiscales, error) {
+	query := `SELECT ` + columnasDatosFiscales + ` FROM datos_fiscales WHERE cliente_id = ?`
+
+	d, err := scanDatosFiscales(s.db.QueryRowContext(ctx, query, clienteID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error consultando datos fiscales del cliente %d: %w", clienteID, err)
+	}
+	return d, nil
+}
+
+func (s *MySQLStore) GuardarDatosFiscales(ctx context.Context, d *DatosFiscales) error {
+	query := `
+		INSERT INTO datos_fiscales (cliente_id, rfc, razon_social, regimen_fiscal, codigo_postal, uso_cfdi)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			rfc = VALUES(rfc),
+			razon_social = VALUES(razon_social),
+			regimen_fiscal = VALUES(regimen_fiscal),
+			codigo_postal = VALUES(codigo_postal),
+			uso_cfdi = VALUES(uso_cfdi)`
+
+	if _, err := s.db.ExecContext(ctx, query, d.ClienteID, d.RFC, d.RazonSocial, d.RegimenFiscal, d.CodigoPostal, d.UsoCFDI); err != nil {
+		return fmt.Errorf("error guardando datos fiscales del cliente %d: %w", d.ClienteID, err)
+	}
+	return nil
+}
+
+func (s *MySQLStore) CrearFactura(ctx context.Context, f *Factura) error {
+	query := `
+		INSERT INTO facturas (pedido_id, cliente_id, rfc, serie, folio, uuid, total, archivo_xml, archivo_pdf, token)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	result, err := s.db.ExecContext(ctx, query, f.PedidoID, f.ClienteID, f.RFC, f.Serie, f.Folio, f.UUID, f.Total, f.ArchivoXML, f.ArchivoPDF, f.Token)
+	if err != nil {
+		return fmt.Errorf("error creando factura del pedido %d: %w", f.PedidoID, err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("error obteniendo ID insertado: %w", err)
+	}
+	f.ID = int(id)
+	return nil
+}
+
+func (s *MySQLStore) GetFacturaPorPedido(ctx context.Context, pedidoID int) (*Factura, error) {
+	query := `SELECT ` + columnasFactura + ` FROM facturas WHERE pedido_id = ?`
+
+	f, err := scanFactura(s.db.QueryRowContext(ctx, query, pedidoID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error consultando factura del pedido %d: %w", pedidoID, err)
+	}
+	return f, nil
+}
+
+func (s *MySQLStore) GetFacturaPorToken(ctx context.Context, token string) (*Factura, error) {
+	query := `SELECT ` + columnasFactura + ` FROM facturas WHERE token = ?`
+
+	f, err := scanFactura(s.db.QueryRowContext(ctx, query, token))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error consultando factura: %w", err)
+	}
+	return f, nil
+}
@@ -0,0 +1,39 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+func (s *MySQLStore) CrearLiquidacion(ctx context.Context, l *Liquidacion) error {
+	query := `
+		INSERT INTO liquidaciones (ruta_id, repartidor_id, fecha, esperado, declarado, recibido, diferencia, estado, cajero, nota)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	result, err := s.db.ExecContext(ctx, query, l.RutaID, idOpcional(l.RepartidorID), l.Fecha.Format("2006-01-02"),
+		l.Esperado, l.Declarado, l.Recibido, l.Diferencia, l.Estado, l.Cajero, l.Nota)
+	if err != nil {
+		return fmt.Errorf("error creando liquidación de la ruta %d: %w", l.RutaID, err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("error obteniendo ID insertado: %w", err)
+	}
+	l.ID = int(id)
+	return nil
+}
+
+func (s *MySQLStore) GetLiquidacionRuta(ctx context.Context, rutaID int) (*Liquidacion, error) {
+	query := `SELECT ` + columnasLiquidacion + ` FROM liquidaciones WHERE ruta_id = ?`
+
+	l, err := scanLiquidacion(s.db.QueryRowContext(ctx, query, rutaID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error consultando liquidación de la ruta %d: %w", rutaID, err)
+	}
+	return l, nil
+}
@@ -0,0 +1,115 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+func (s *MySQLStore) CrearPedidoProgramado(ctx context.Context, programado *PedidoProgramado) error {
+	query := `
+		INSERT INTO pedidos_programados (
+			cliente_id, tipo_servicio, cantidad_litros, cantidad_cilindros, metodo_pago,
+			direccion, latitud, longitud, mapa_url, streetview_url, color_fachada, color_puerta,
+			horario_preferido, frecuencia, intervalo_semanas, dia_mes, proxima_fecha,
+			omitir_proxima, recordatorio_enviado, activo
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	result, err := s.db.ExecContext(ctx, query,
+		programado.ClienteID,
+		programado.TipoServicio,
+		programado.CantidadLitros,
+		programado.CantidadCilindros,
+		programado.MetodoPago,
+		programado.Direccion,
+		programado.Latitud,
+		programado.Longitud,
+		programado.MapaURL,
+		programado.StreetViewURL,
+		programado.ColorFachada,
+		programado.ColorPuerta,
+		programado.HorarioPreferido,
+		programado.Frecuencia,
+		programado.IntervaloSemanas,
+		programado.DiaMes,
+		programado.ProximaFecha.Format("2006-01-02"),
+		programado.OmitirProxima,
+		programado.RecordatorioEnviado,
+		programado.Activo,
+	)
+	if err != nil {
+		return fmt.Errorf("error insertando pedido programado: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("error obteniendo ID insertado: %w", err)
+	}
+	programado.ID = int(id)
+	return nil
+}
+
+func (s *MySQLStore) ActualizarPedidoProgramado(ctx context.Context, programado *PedidoProgramado) error {
+	query := `
+		UPDATE pedidos_programados
+		SET horario_preferido = ?, frecuencia = ?, intervalo_semanas = ?, dia_mes = ?,
+			proxima_fecha = ?, omitir_proxima = ?, recordatorio_enviado = ?, activo = ?,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?`
+
+	result, err := s.db.ExecContext(ctx, query,
+		programado.HorarioPreferido,
+		programado.Frecuencia,
+		programado.IntervaloSemanas,
+		programado.DiaMes,
+		programado.ProximaFecha.Format("2006-01-02"),
+		programado.OmitirProxima,
+		programado.RecordatorioEnviado,
+		programado.Activo,
+		programado.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("error actualizando pedido programado: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error verificando actualización: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("pedido programado no encontrado: %d", programado.ID)
+	}
+	return nil
+}
+
+func (s *MySQLStore) GetPedidosProgramadosPorCliente(ctx context.Context, clienteID int) ([]*PedidoProgramado, error) {
+	query := `
+		SELECT ` + columnasPedidoProgramado + `
+		FROM pedidos_programados
+		WHERE cliente_id = ? AND activo = TRUE
+		ORDER BY proxima_fecha`
+
+	rows, err := s.db.QueryContext(ctx, query, clienteID)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando pedidos programados: %w", err)
+	}
+	defer rows.Close()
+
+	return scanPedidosProgramados(rows)
+}
+
+func (s *MySQLStore) GetPedidosProgramadosHasta(ctx context.Context, fecha time.Time) ([]*PedidoProgramado, error) {
+	query := `
+		SELECT ` + columnasPedidoProgramado + `
+		FROM pedidos_programados
+		WHERE activo = TRUE AND proxima_fecha <= ?
+		ORDER BY proxima_fecha`
+
+	rows, err := s.db.QueryContext(ctx, query, fecha.Format("2006-01-02"))
+	if err != nil {
+		return nil, fmt.Errorf("error consultando pedidos programados: %w", err)
+	}
+	defer rows.Close()
+
+	return scanPedidosProgramados(rows)
+}
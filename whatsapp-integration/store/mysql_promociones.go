@@ -0,0 +1,128 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+func (s *MySQLStore) CrearPromocion(ctx context.Context, promo *Promocion) error {
+	query := `
+		INSERT INTO promociones (codigo, descripcion, tipo, valor, solo_primer_pedido,
+			vigente_desde, vigente_hasta, max_usos_cliente, zonas, activa)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	result, err := s.db.ExecContext(ctx, query,
+		strings.ToUpper(promo.Codigo),
+		promo.Descripcion,
+		promo.Tipo,
+		promo.Valor,
+		promo.SoloPrimerPedido,
+		promo.VigenteDesde,
+		promo.VigenteHasta,
+		promo.MaxUsosCliente,
+		promo.Zonas,
+		promo.Activa,
+	)
+	if err != nil {
+		return fmt.Errorf("error creando promoción %s: %w", promo.Codigo, err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("error obteniendo ID insertado: %w", err)
+	}
+	promo.ID = int(id)
+	return nil
+}
+
+func (s *MySQLStore) ActualizarPromocion(ctx context.Context, promo *Promocion) error {
+	query := `
+		UPDATE promociones
+		SET descripcion = ?, tipo = ?, valor = ?, solo_primer_pedido = ?, vigente_desde = ?,
+			vigente_hasta = ?, max_usos_cliente = ?, zonas = ?, activa = ?
+		WHERE id = ?`
+
+	_, err := s.db.ExecContext(ctx, query,
+		promo.Descripcion,
+		promo.Tipo,
+		promo.Valor,
+		promo.SoloPrimerPedido,
+		promo.VigenteDesde,
+		promo.VigenteHasta,
+		promo.MaxUsosCliente,
+		promo.Zonas,
+		promo.Activa,
+		promo.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("error actualizando promoción %d: %w", promo.ID, err)
+	}
+	return nil
+}
+
+func (s *MySQLStore) GetPromocionPorCodigo(ctx context.Context, codigo string) (*Promocion, error) {
+	query := `SELECT ` + columnasPromocion + ` FROM promociones WHERE codigo = ?`
+
+	promo, err := scanPromocion(s.db.QueryRowContext(ctx, query, strings.ToUpper(strings.TrimSpace(codigo))))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error consultando promoción %s: %w", codigo, err)
+	}
+	return promo, nil
+}
+
+func (s *MySQLStore) GetPromociones(ctx context.Context) ([]*Promocion, error) {
+	query := `SELECT ` + columnasPromocion + ` FROM promociones ORDER BY id`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando promociones: %w", err)
+	}
+	defer rows.Close()
+
+	var promociones []*Promocion
+	for rows.Next() {
+		promo, err := scanPromocion(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error escaneando promoción: %w", err)
+		}
+		promociones = append(promociones, promo)
+	}
+	return promociones, rows.Err()
+}
+
+func (s *MySQLStore) RegistrarUsoPromocion(ctx context.Context, uso *UsoPromocion) error {
+	query := `
+		INSERT INTO usos_promocion (promocion_id, cliente_id, pedido_id, descuento)
+		VALUES (?, ?, ?, ?)`
+
+	result, err := s.db.ExecContext(ctx, query, uso.PromocionID, uso.ClienteID, uso.PedidoID, uso.Descuento)
+	if err != nil {
+		return fmt.Errorf("error registrando uso de la promoción %d: %w", uso.PromocionID, err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("error obteniendo ID insertado: %w", err)
+	}
+	uso.ID = int(id)
+	return nil
+}
+
+func (s *MySQLStore) ContarUsosPromocion(ctx context.Context, promocionID, clienteID int) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM usos_promocion u
+		JOIN pedidos p ON p.id = u.pedido_id
+		WHERE u.promocion_id = ? AND u.cliente_id = ? AND p.estado <> 'cancelado'`
+
+	var usos int
+	if err := s.db.QueryRowContext(ctx, query, promocionID, clienteID).Scan(&usos); err != nil {
+		return 0, fmt.Errorf("error contando usos de la promoción %d: %w", promocionID, err)
+	}
+	return usos, nil
+}
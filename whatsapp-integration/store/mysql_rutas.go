@@ -0,0 +1,342 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+func (s *MySQLStore) GetCamiones(ctx context.Context) ([]*Camion, error) {
+	query := `
+		SELECT id, nombre, placas, capacidad_litros, capacidad_cilindros, max_pedidos, activo
+		FROM camiones
+		WHERE activo = TRUE
+		ORDER BY id`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando camiones: %w", err)
+	}
+	defer rows.Close()
+
+	var camiones []*Camion
+	for rows.Next() {
+		c := &Camion{}
+		err := rows.Scan(
+			&c.ID,
+			&c.Nombre,
+			&c.Placas,
+			&c.CapacidadLitros,
+			&c.CapacidadCilindros,
+			&c.MaxPedidos,
+			&c.Activo,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error escaneando camión: %w", err)
+		}
+		camiones = append(camiones, c)
+	}
+	return camiones, rows.Err()
+}
+
+func (s *MySQLStore) CrearCamion(ctx context.Context, camion *Camion) error {
+	query := `
+		INSERT INTO camiones (
+			nombre, placas, capacidad_litros, capacidad_cilindros, max_pedidos, activo
+		) VALUES (?, ?, ?, ?, ?, ?)`
+
+	result, err := s.db.ExecContext(ctx, query,
+		camion.Nombre,
+		camion.Placas,
+		camion.CapacidadLitros,
+		camion.CapacidadCilindros,
+		camion.MaxPedidos,
+		camion.Activo,
+	)
+	if err != nil {
+		return fmt.Errorf("error insertando camión: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("error obteniendo ID insertado: %w", err)
+	}
+	camion.ID = int(id)
+	return nil
+}
+
+func (s *MySQLStore) CrearRuta(ctx context.Context, ruta *Ruta, paradas []*RutaParada, estadoPedidos string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error iniciando creación de ruta: %w", err)
+	}
+	defer tx.Rollback()
+
+	if ruta.Estado == "" {
+		ruta.Estado = "planificada"
+	}
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO rutas (fecha, camion_id, estado, total_litros, total_cilindros)
+		VALUES (?, ?, ?, ?, ?)`,
+		ruta.Fecha.Format("2006-01-02"), ruta.CamionID, ruta.Estado, ruta.TotalLitros, ruta.TotalCilindros,
+	)
+	if err != nil {
+		return fmt.Errorf("error insertando ruta: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("error obteniendo ID insertado: %w", err)
+	}
+	ruta.ID = int(id)
+
+	for _, parada := range paradas {
+		if parada.Estado == "" {
+			parada.Estado = "pending"
+		}
+		result, err := tx.ExecContext(ctx, `
+			INSERT INTO ruta_paradas (ruta_id, pedido_id, orden, estado, hora_estimada)
+			VALUES (?, ?, ?, ?, ?)`,
+			ruta.ID, parada.PedidoID, parada.Orden, parada.Estado, parada.HoraEstimada,
+		)
+		if err != nil {
+			return fmt.Errorf("error insertando parada del pedido %d: %w", parada.PedidoID, err)
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("error obteniendo ID insertado: %w", err)
+		}
+		parada.ID = int(id)
+		parada.RutaID = ruta.ID
+
+		if _, err := tx.ExecContext(ctx, `UPDATE pedidos SET estado = ? WHERE id = ?`, estadoPedidos, parada.PedidoID); err != nil {
+			return fmt.Errorf("error asignando pedido %d a la ruta: %w", parada.PedidoID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error confirmando ruta: %w", err)
+	}
+	return nil
+}
+
+func (s *MySQLStore) GetRutasPorFecha(ctx context.Context, fecha time.Time) ([]*Ruta, error) {
+	query := `
+		SELECT id, fecha, camion_id, estado, total_litros, total_cilindros, created_at
+		FROM rutas
+		WHERE fecha = ?
+		ORDER BY camion_id, id`
+
+	rows, err := s.db.QueryContext(ctx, query, fecha.Format("2006-01-02"))
+	if err != nil {
+		return nil, fmt.Errorf("error consultando rutas: %w", err)
+	}
+	defer rows.Close()
+
+	var rutas []*Ruta
+	for rows.Next() {
+		r := &Ruta{}
+		err := rows.Scan(
+			&r.ID,
+			&r.Fecha,
+			&r.CamionID,
+			&r.Estado,
+			&r.TotalLitros,
+			&r.TotalCilindros,
+			&r.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error escaneando ruta: %w", err)
+		}
+		rutas = append(rutas, r)
+	}
+	return rutas, rows.Err()
+}
+
+func (s *MySQLStore) GetParadasRuta(ctx context.Context, rutaID int) ([]*RutaParada, error) {
+	query := `
+		SELECT id, ruta_id, pedido_id, orden, estado, hora_estimada
+		FROM ruta_paradas
+		WHERE ruta_id = ?
+		ORDER BY orden`
+
+	rows, err := s.db.QueryContext(ctx, query, rutaID)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando paradas de la ruta %d: %w", rutaID, err)
+	}
+	defer rows.Close()
+
+	var paradas []*RutaParada
+	for rows.Next() {
+		p := &RutaParada{}
+		var eta sql.NullTime
+		if err := rows.Scan(&p.ID, &p.RutaID, &p.PedidoID, &p.Orden, &p.Estado, &eta); err != nil {
+			return nil, fmt.Errorf("error escaneando parada: %w", err)
+		}
+		if eta.Valid {
+			p.HoraEstimada = &eta.Time
+		}
+		paradas = append(paradas, p)
+	}
+	return paradas, rows.Err()
+}
+
+func (s *MySQLStore) GetRuta(ctx context.Context, id int) (*Ruta, error) {
+	query := `
+		SELECT id, fecha, camion_id, estado, total_litros, total_cilindros, created_at
+		FROM rutas
+		WHERE id = ?`
+
+	return s.scanRuta(s.db.QueryRowContext(ctx, query, id))
+}
+
+func (s *MySQLStore) GetRutaCamion(ctx context.Context, camionID int, fecha time.Time) (*Ruta, error) {
+	query := `
+		SELECT id, fecha, camion_id, estado, total_litros, total_cilindros, created_at
+		FROM rutas
+		WHERE camion_id = ? AND fecha = ?
+		ORDER BY id DESC
+		LIMIT 1`
+
+	return s.scanRuta(s.db.QueryRowContext(ctx, query, camionID, fecha.Format("2006-01-02")))
+}
+
+func (s *MySQLStore) scanRuta(row *sql.Row) (*Ruta, error) {
+	r := &Ruta{}
+	err := row.Scan(
+		&r.ID,
+		&r.Fecha,
+		&r.CamionID,
+		&r.Estado,
+		&r.TotalLitros,
+		&r.TotalCilindros,
+		&r.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error escaneando ruta: %w", err)
+	}
+	return r, nil
+}
+
+func (s *MySQLStore) GetParada(ctx context.Context, id int) (*RutaParada, error) {
+	query := `
+		SELECT id, ruta_id, pedido_id, orden, estado, hora_estimada
+		FROM ruta_paradas
+		WHERE id = ?`
+
+	p := &RutaParada{}
+	var eta sql.NullTime
+	err := s.db.QueryRowContext(ctx, query, id).Scan(&p.ID, &p.RutaID, &p.PedidoID, &p.Orden, &p.Estado, &eta)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error escaneando parada %d: %w", id, err)
+	}
+	if eta.Valid {
+		p.HoraEstimada = &eta.Time
+	}
+	return p, nil
+}
+
+func (s *MySQLStore) GetParadaPorPedido(ctx context.Context, pedidoID int) (*RutaParada, error) {
+	query := `
+		SELECT id, ruta_id, pedido_id, orden, estado, hora_estimada
+		FROM ruta_paradas
+		WHERE pedido_id = ?
+		ORDER BY id DESC LIMIT 1`
+
+	p := &RutaParada{}
+	var eta sql.NullTime
+	err := s.db.QueryRowContext(ctx, query, pedidoID).Scan(&p.ID, &p.RutaID, &p.PedidoID, &p.Orden, &p.Estado, &eta)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error escaneando parada del pedido %d: %w", pedidoID, err)
+	}
+	if eta.Valid {
+		p.HoraEstimada = &eta.Time
+	}
+	return p, nil
+}
+
+func (s *MySQLStore) ActualizarEstadoParada(ctx context.Context, paradaID int, estado string) error {
+	query := `UPDATE ruta_paradas SET estado = ? WHERE id = ?`
+
+	result, err := s.db.ExecContext(ctx, query, estado, paradaID)
+	if err != nil {
+		return fmt.Errorf("error actualizando parada: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error verificando actualización: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("parada no encontrada: %d", paradaID)
+	}
+	return nil
+}
+
+func (s *MySQLStore) ActualizarETAParada(ctx context.Context, paradaID int, eta time.Time) error {
+	query := `UPDATE ruta_paradas SET hora_estimada = ? WHERE id = ?`
+
+	if _, err := s.db.ExecContext(ctx, query, eta, paradaID); err != nil {
+		return fmt.Errorf("error actualizando ETA de la parada %d: %w", paradaID, err)
+	}
+	return nil
+}
+
+func (s *MySQLStore) RegistrarPosicion(ctx context.Context, posicion *PosicionRuta) error {
+	query := `
+		INSERT INTO posiciones_ruta (ruta_id, repartidor_id, latitud, longitud, precision_metros)
+		VALUES (?, ?, ?, ?, ?)`
+
+	result, err := s.db.ExecContext(ctx, query,
+		posicion.RutaID,
+		posicion.RepartidorID,
+		posicion.Latitud,
+		posicion.Longitud,
+		posicion.Precision,
+	)
+	if err != nil {
+		return fmt.Errorf("error registrando posición: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("error obteniendo ID insertado: %w", err)
+	}
+	posicion.ID = int(id)
+	return nil
+}
+
+func (s *MySQLStore) GetUltimaPosicion(ctx context.Context, rutaID int) (*PosicionRuta, error) {
+	query := `
+		SELECT id, ruta_id, repartidor_id, latitud, longitud, precision_metros, created_at
+		FROM posiciones_ruta
+		WHERE ruta_id = ?
+		ORDER BY id DESC
+		LIMIT 1`
+
+	p := &PosicionRuta{}
+	err := s.db.QueryRowContext(ctx, query, rutaID).Scan(
+		&p.ID,
+		&p.RutaID,
+		&p.RepartidorID,
+		&p.Latitud,
+		&p.Longitud,
+		&p.Precision,
+		&p.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error escaneando posición de la ruta %d: %w", rutaID, err)
+	}
+	return p, nil
+}
@@ -0,0 +1,159 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+func (s *MySQLStore) CrearReporteSello(ctx context.Context, reporte *ReporteSello) error {
+	if reporte.FechaReporte.IsZero() {
+		reporte.FechaReporte = time.Now()
+	}
+	if reporte.TipoReporte == "" {
+		reporte.TipoReporte = "sello_violado"
+	}
+
+	query := `
+		INSERT INTO reportes_sello (
+			cliente_id, pedido_id, tanque_id, tipo_reporte, descripcion, estado,
+			supervisor, fecha_reporte, vence_sla, asignado_en
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	result, err := s.db.ExecContext(ctx, query,
+		reporte.ClienteID,
+		reporte.PedidoID,
+		reporte.TanqueID,
+		reporte.TipoReporte,
+		reporte.Descripcion,
+		reporte.Estado,
+		reporte.Supervisor,
+		reporte.FechaReporte,
+		reporte.VenceSLA,
+		reporte.AsignadoEn,
+	)
+	if err != nil {
+		return fmt.Errorf("error insertando reporte: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("error obteniendo ID insertado: %w", err)
+	}
+	reporte.ID = int(id)
+	return nil
+}
+
+func (s *MySQLStore) GetReporteSello(ctx context.Context, id int) (*ReporteSello, error) {
+	query := `SELECT ` + columnasReporteSello + ` FROM reportes_sello WHERE id = ?`
+
+	r, err := scanReporteSello(s.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error consultando reporte de sello %d: %w", id, err)
+	}
+	return r, nil
+}
+
+func (s *MySQLStore) GetUltimoReporteSello(ctx context.Context, clienteID int) (*ReporteSello, error) {
+	query := `SELECT ` + columnasReporteSello + ` FROM reportes_sello WHERE cliente_id = ? ORDER BY id DESC LIMIT 1`
+
+	r, err := scanReporteSello(s.db.QueryRowContext(ctx, query, clienteID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error consultando reporte de sello del cliente %d: %w", clienteID, err)
+	}
+	return r, nil
+}
+
+func (s *MySQLStore) GetReportesSelloPorEstado(ctx context.Context, estado string) ([]*ReporteSello, error) {
+	query := `SELECT ` + columnasReporteSello + ` FROM reportes_sello WHERE ? = '' OR estado = ? ORDER BY id`
+
+	rows, err := s.db.QueryContext(ctx, query, estado, estado)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando reportes de sello: %w", err)
+	}
+	return leerReportesSello(rows)
+}
+
+func (s *MySQLStore) GetReportesSello(ctx context.Context, desde, hasta time.Time) ([]*ReporteSello, error) {
+	query := `
+		SELECT ` + columnasReporteSello + `
+		FROM reportes_sello
+		WHERE COALESCE(fecha_reporte, created_at) >= ? AND COALESCE(fecha_reporte, created_at) < ?
+		ORDER BY id`
+
+	rows, err := s.db.QueryContext(ctx, query, desde, hasta)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando reportes de sello: %w", err)
+	}
+	return leerReportesSello(rows)
+}
+
+func (s *MySQLStore) ActualizarReporteSello(ctx context.Context, r *ReporteSello) error {
+	query := `
+		UPDATE reportes_sello SET
+			pedido_id = ?, tanque_id = ?, tipo_reporte = ?, descripcion = ?, estado = ?, resolucion = ?,
+			supervisor = ?, vence_sla = ?, asignado_en = ?, resuelto_en = ?
+		WHERE id = ?`
+
+	_, err := s.db.ExecContext(ctx, query, r.PedidoID, r.TanqueID, r.TipoReporte, r.Descripcion, r.Estado, r.Resolucion,
+		r.Supervisor, r.VenceSLA, r.AsignadoEn, r.ResueltoEn, r.ID)
+	if err != nil {
+		return fmt.Errorf("error actualizando reporte de sello %d: %w", r.ID, err)
+	}
+	return nil
+}
+
+func (s *MySQLStore) AgregarFotoReporteSello(ctx context.Context, foto *FotoReporteSello) error {
+	result, err := s.db.ExecContext(ctx,
+		`INSERT INTO fotos_reporte_sello (reporte_id, archivo, mime_type) VALUES (?, ?, ?)`,
+		foto.ReporteID, foto.Archivo, foto.MimeType)
+	if err != nil {
+		return fmt.Errorf("error guardando foto del reporte %d: %w", foto.ReporteID, err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("error obteniendo ID insertado: %w", err)
+	}
+	foto.ID = int(id)
+	return nil
+}
+
+func (s *MySQLStore) GetFotoReporteSello(ctx context.Context, id int) (*FotoReporteSello, error) {
+	f := &FotoReporteSello{}
+	err := s.db.QueryRowContext(ctx,
+		`SELECT id, reporte_id, archivo, mime_type, created_at FROM fotos_reporte_sello WHERE id = ?`, id,
+	).Scan(&f.ID, &f.ReporteID, &f.Archivo, &f.MimeType, &f.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error consultando foto %d: %w", id, err)
+	}
+	return f, nil
+}
+
+func (s *MySQLStore) GetFotosReporteSello(ctx context.Context, reporteID int) ([]*FotoReporteSello, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, reporte_id, archivo, mime_type, created_at FROM fotos_reporte_sello WHERE reporte_id = ? ORDER BY id`, reporteID)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando fotos del reporte %d: %w", reporteID, err)
+	}
+	return leerFotosReporteSello(rows)
+}
+
+func (s *MySQLStore) GetTanquesPedido(ctx context.Context, pedidoID int) ([]*Tanque, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, pedido_id, codigo_qr, COALESCE(capacidad, 0), tipo, estado FROM tanques WHERE pedido_id = ? ORDER BY id`, pedidoID)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando tanques del pedido %d: %w", pedidoID, err)
+	}
+	return leerTanques(rows)
+}
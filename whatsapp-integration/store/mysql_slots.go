@@ -0,0 +1,208 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+func (s *MySQLStore) GetVentanasEntrega(ctx context.Context) ([]*VentanaEntrega, error) {
+	query := `
+		SELECT id, zona, dia_semana, hora_inicio, hora_fin, camiones,
+			   max_pedidos_camion, max_litros_camion, activo
+		FROM ventanas_entrega
+		WHERE activo = TRUE
+		ORDER BY dia_semana, hora_inicio`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando ventanas de entrega: %w", err)
+	}
+	defer rows.Close()
+
+	var ventanas []*VentanaEntrega
+	for rows.Next() {
+		v := &VentanaEntrega{}
+		err := rows.Scan(
+			&v.ID,
+			&v.Zona,
+			&v.DiaSemana,
+			&v.HoraInicio,
+			&v.HoraFin,
+			&v.Camiones,
+			&v.MaxPedidosCamion,
+			&v.MaxLitrosCamion,
+			&v.Activo,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error escaneando ventana de entrega: %w", err)
+		}
+		ventanas = append(ventanas, v)
+	}
+	return ventanas, rows.Err()
+}
+
+func (s *MySQLStore) CrearVentanaEntrega(ctx context.Context, ventana *VentanaEntrega) error {
+	query := `
+		INSERT INTO ventanas_entrega (
+			zona, dia_semana, hora_inicio, hora_fin, camiones,
+			max_pedidos_camion, max_litros_camion, activo
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+
+	result, err := s.db.ExecContext(ctx, query,
+		ventana.Zona,
+		ventana.DiaSemana,
+		ventana.HoraInicio,
+		ventana.HoraFin,
+		ventana.Camiones,
+		ventana.MaxPedidosCamion,
+		ventana.MaxLitrosCamion,
+		ventana.Activo,
+	)
+	if err != nil {
+		return fmt.Errorf("error insertando ventana de entrega: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("error obteniendo ID insertado: %w", err)
+	}
+	ventana.ID = int(id)
+	return nil
+}
+
+func (s *MySQLStore) GetOcupacionSlot(ctx context.Context, ventanaID int, fecha time.Time) (int, float64, error) {
+	query := `
+		SELECT COUNT(*), COALESCE(SUM(litros), 0)
+		FROM reservas_slot
+		WHERE ventana_id = ? AND fecha = ? AND estado = 'reservada'`
+
+	var pedidos int
+	var litros float64
+	if err := s.db.QueryRowContext(ctx, query, ventanaID, fecha.Format("2006-01-02")).Scan(&pedidos, &litros); err != nil {
+		return 0, 0, fmt.Errorf("error consultando ocupación de ventana: %w", err)
+	}
+	return pedidos, litros, nil
+}
+
+func (s *MySQLStore) ReservarSlot(ctx context.Context, reserva *ReservaSlot, ventana *VentanaEntrega) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error iniciando reserva: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := s.reservarSlot(ctx, tx, reserva, ventana); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error confirmando reserva: %w", err)
+	}
+	return nil
+}
+
+func (s *MySQLStore) CrearPedidoConSlot(ctx context.Context, pedido *Pedido, reserva *ReservaSlot, ventana *VentanaEntrega) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error iniciando pedido con reserva: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := s.insertarPedido(ctx, tx, pedido); err != nil {
+		return err
+	}
+	reserva.PedidoID = pedido.ID
+	if err := s.reservarSlot(ctx, tx, reserva, ventana); err != nil {
+		pedido.ID = 0
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		pedido.ID = 0
+		return fmt.Errorf("error confirmando pedido con reserva: %w", err)
+	}
+	return nil
+}
+
+// reservarSlot verifica la capacidad e inserta la reserva dentro de tx.
+func (s *MySQLStore) reservarSlot(ctx context.Context, tx *sql.Tx, reserva *ReservaSlot, ventana *VentanaEntrega) error {
+	// Bloquear la ventana para serializar las reservas concurrentes.
+	var bloqueo int
+	err := tx.QueryRowContext(ctx, `SELECT id FROM ventanas_entrega WHERE id = ? FOR UPDATE`, ventana.ID).Scan(&bloqueo)
+	if err != nil {
+		return fmt.Errorf("error bloqueando ventana %d: %w", ventana.ID, err)
+	}
+
+	fecha := reserva.Fecha.Format("2006-01-02")
+	var pedidos int
+	var litros float64
+	err = tx.QueryRowContext(ctx, `
+		SELECT COUNT(*), COALESCE(SUM(litros), 0)
+		FROM reservas_slot
+		WHERE ventana_id = ? AND fecha = ? AND estado = 'reservada'`,
+		ventana.ID, fecha,
+	).Scan(&pedidos, &litros)
+	if err != nil {
+		return fmt.Errorf("error consultando ocupación de ventana: %w", err)
+	}
+	if !ventana.Admite(pedidos, litros, reserva.Litros) {
+		return ErrSlotSinCapacidad
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO reservas_slot (ventana_id, fecha, pedido_id, litros, estado)
+		VALUES (?, ?, ?, ?, 'reservada')`,
+		ventana.ID, fecha, reserva.PedidoID, reserva.Litros,
+	)
+	if err != nil {
+		return fmt.Errorf("error insertando reserva: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("error obteniendo ID insertado: %w", err)
+	}
+	reserva.ID = int(id)
+	reserva.VentanaID = ventana.ID
+	reserva.Estado = "reservada"
+	return nil
+}
+
+func (s *MySQLStore) LiberarSlot(ctx context.Context, pedidoID int) error {
+	query := `
+		UPDATE reservas_slot
+		SET estado = 'liberada'
+		WHERE pedido_id = ? AND estado = 'reservada'`
+
+	if _, err := s.db.ExecContext(ctx, query, pedidoID); err != nil {
+		return fmt.Errorf("error liberando reserva del pedido %d: %w", pedidoID, err)
+	}
+	return nil
+}
+
+func (s *MySQLStore) GetReservaSlot(ctx context.Context, pedidoID int) (*ReservaSlot, error) {
+	query := `
+		SELECT id, ventana_id, fecha, pedido_id, litros, estado, created_at
+		FROM reservas_slot
+		WHERE pedido_id = ? AND estado = 'reservada'
+		ORDER BY id DESC
+		LIMIT 1`
+
+	reserva := &ReservaSlot{}
+	err := s.db.QueryRowContext(ctx, query, pedidoID).Scan(
+		&reserva.ID,
+		&reserva.VentanaID,
+		&reserva.Fecha,
+		&reserva.PedidoID,
+		&reserva.Litros,
+		&reserva.Estado,
+		&reserva.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error escaneando reserva del pedido %d: %w", pedidoID, err)
+	}
+	return reserva, nil
+}
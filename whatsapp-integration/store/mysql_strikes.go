@@ -0,0 +1,189 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+func (s *MySQLStore) CrearStrike(ctx context.Context, strike *Strike) error {
+	query := `
+		INSERT INTO strikes (cliente_id, pedido_id, motivo, expira_en, anulado)
+		VALUES (?, ?, ?, ?, ?)`
+
+	result, err := s.db.ExecContext(ctx, query,
+		strike.ClienteID,
+		strike.PedidoID,
+		strike.Motivo,
+		strike.ExpiraEn,
+		strike.Anulado,
+	)
+	if err != nil {
+		return fmt.Errorf("error creando strike: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("error obteniendo ID insertado: %w", err)
+	}
+	strike.ID = int(id)
+	return nil
+}
+
+func (s *MySQLStore) GetStrikesCliente(ctx context.Context, clienteID int) ([]*Strike, error) {
+	query := `
+		SELECT id, cliente_id, pedido_id, motivo, created_at, expira_en, anulado
+		FROM strikes
+		WHERE cliente_id = ?
+		ORDER BY id DESC`
+
+	rows, err := s.db.QueryContext(ctx, query, clienteID)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando strikes del cliente %d: %w", clienteID, err)
+	}
+	defer rows.Close()
+
+	var strikes []*Strike
+	for rows.Next() {
+		strike, err := scanStrike(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error escaneando strike: %w", err)
+		}
+		strikes = append(strikes, strike)
+	}
+	return strikes, rows.Err()
+}
+
+func (s *MySQLStore) AnularStrike(ctx context.Context, strikeID int) error {
+	if _, err := s.db.ExecContext(ctx, `UPDATE strikes SET anulado = TRUE WHERE id = ?`, strikeID); err != nil {
+		return fmt.Errorf("error anulando strike %d: %w", strikeID, err)
+	}
+	return nil
+}
+
+func (s *MySQLStore) CrearApelacion(ctx context.Context, apelacion *Apelacion) error {
+	query := `
+		INSERT INTO apelaciones (cliente_id, strike_id, mensaje, estado)
+		VALUES (?, ?, ?, ?)`
+
+	result, err := s.db.ExecContext(ctx, query,
+		apelacion.ClienteID,
+		apelacion.StrikeID,
+		apelacion.Mensaje,
+		apelacion.Estado,
+	)
+	if err != nil {
+		return fmt.Errorf("error creando apelación: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("error obteniendo ID insertado: %w", err)
+	}
+	apelacion.ID = int(id)
+	return nil
+}
+
+func (s *MySQLStore) GetApelacion(ctx context.Context, id int) (*Apelacion, error) {
+	query := `SELECT ` + columnasApelacion + ` FROM apelaciones WHERE id = ?`
+
+	apelacion, err := scanApelacion(s.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error escaneando apelación %d: %w", id, err)
+	}
+	return apelacion, nil
+}
+
+func (s *MySQLStore) GetApelaciones(ctx context.Context, estado string) ([]*Apelacion, error) {
+	query := `SELECT ` + columnasApelacion + ` FROM apelaciones`
+	var args []interface{}
+	if estado != "" {
+		query += ` WHERE estado = ?`
+		args = append(args, estado)
+	}
+	query += ` ORDER BY id`
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando apelaciones: %w", err)
+	}
+	defer rows.Close()
+
+	var apelaciones []*Apelacion
+	for rows.Next() {
+		apelacion, err := scanApelacion(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error escaneando apelación: %w", err)
+		}
+		apelaciones = append(apelaciones, apelacion)
+	}
+	return apelaciones, rows.Err()
+}
+
+func (s *MySQLStore) ActualizarApelacion(ctx context.Context, apelacion *Apelacion) error {
+	query := `
+		UPDATE apelaciones
+		SET estado = ?, resolucion = ?, resuelta_por = ?, resuelta_en = ?
+		WHERE id = ?`
+
+	if _, err := s.db.ExecContext(ctx, query,
+		apelacion.Estado,
+		apelacion.Resolucion,
+		apelacion.ResueltaPor,
+		apelacion.ResueltaEn,
+		apelacion.ID,
+	); err != nil {
+		return fmt.Errorf("error actualizando apelación %d: %w", apelacion.ID, err)
+	}
+	return nil
+}
+
+func (s *MySQLStore) RegistrarAuditoria(ctx context.Context, registro *AuditoriaCliente) error {
+	query := `
+		INSERT INTO auditoria_clientes (cliente_id, accion, operador, detalle)
+		VALUES (?, ?, ?, ?)`
+
+	result, err := s.db.ExecContext(ctx, query,
+		registro.ClienteID,
+		registro.Accion,
+		registro.Operador,
+		registro.Detalle,
+	)
+	if err != nil {
+		return fmt.Errorf("error registrando auditoría: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("error obteniendo ID insertado: %w", err)
+	}
+	registro.ID = int(id)
+	return nil
+}
+
+func (s *MySQLStore) GetAuditoriaCliente(ctx context.Context, clienteID int) ([]*AuditoriaCliente, error) {
+	query := `
+		SELECT id, cliente_id, accion, operador, detalle, created_at
+		FROM auditoria_clientes
+		WHERE cliente_id = ?
+		ORDER BY id`
+
+	rows, err := s.db.QueryContext(ctx, query, clienteID)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando auditoría del cliente %d: %w", clienteID, err)
+	}
+	defer rows.Close()
+
+	var registros []*AuditoriaCliente
+	for rows.Next() {
+		r := &AuditoriaCliente{}
+		if err := rows.Scan(&r.ID, &r.ClienteID, &r.Accion, &r.Operador, &r.Detalle, &r.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error escaneando auditoría: %w", err)
+		}
+		registros = append(registros, r)
+	}
+	return registros, rows.Err()
+}
@@ -4,10 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"time"
+	"strings"
 
-	_ "github.com/mattn/go-sqlite3"
 	"example.com/whatsapp-integration/migrations"
+	_ "github.com/mattn/go-sqlite3"
 )
 
 type SQLiteStore struct {
@@ -72,6 +72,75 @@ func (s *SQLiteStore) GetClientePorTelefono(ctx context.Context, telefono string
 	return cliente, nil
 }
 
+func (s *SQLiteStore) GetClientePorID(ctx context.Context, id int) (*Cliente, error) {
+	query := `
+		SELECT id, numero_telefono, nombre, apellido_paterno, apellido_materno,
+			   estado_conversacion, strikes, bloqueado, categoria, created_at, updated_at
+		FROM clientes
+		WHERE id = ?`
+
+	row := s.db.QueryRowContext(ctx, query, id)
+
+	cliente := &Cliente{}
+	err := row.Scan(
+		&cliente.ID,
+		&cliente.NumeroTelefono,
+		&cliente.Nombre,
+		&cliente.ApellidoPaterno,
+		&cliente.ApellidoMaterno,
+		&cliente.EstadoConversacion,
+		&cliente.Strikes,
+		&cliente.Bloqueado,
+		&cliente.Categoria,
+		&cliente.CreatedAt,
+		&cliente.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil // cliente no encontrado
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error escaneando cliente: %w", err)
+	}
+	return cliente, nil
+}
+
+func (s *SQLiteStore) GetClientes(ctx context.Context) ([]*Cliente, error) {
+	query := `
+		SELECT id, numero_telefono, COALESCE(nombre, ''), COALESCE(apellido_paterno, ''), COALESCE(apellido_materno, ''),
+			   COALESCE(estado_conversacion, ''), strikes, bloqueado, COALESCE(categoria, ''), created_at, updated_at
+		FROM clientes
+		ORDER BY id`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando clientes: %w", err)
+	}
+	defer rows.Close()
+
+	var clientes []*Cliente
+	for rows.Next() {
+		cliente := &Cliente{}
+		err := rows.Scan(
+			&cliente.ID,
+			&cliente.NumeroTelefono,
+			&cliente.Nombre,
+			&cliente.ApellidoPaterno,
+			&cliente.ApellidoMaterno,
+			&cliente.EstadoConversacion,
+			&cliente.Strikes,
+			&cliente.Bloqueado,
+			&cliente.Categoria,
+			&cliente.CreatedAt,
+			&cliente.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error escaneando cliente: %w", err)
+		}
+		clientes = append(clientes, cliente)
+	}
+	return clientes, rows.Err()
+}
+
 func (s *SQLiteStore) CrearCliente(ctx context.Context, cliente *Cliente) error {
 	query := `
 		INSERT INTO clientes (
@@ -161,10 +230,11 @@ func (s *SQLiteStore) ActualizarEstadoCliente(ctx context.Context, telefono, est
 func (s *SQLiteStore) GetUltimoPedido(ctx context.Context, clienteID int) (*Pedido, error) {
 	query := `
 		SELECT id, cliente_id, tipo_servicio, cantidad_litros, cantidad_dinero,
-			   metodo_pago, direccion, color_fachada, estado, horario_preferido, latitud, longitud, mapa_url, streetview_url, requiere_revision_manual, created_at, updated_at
+			   metodo_pago, direccion, color_fachada, estado, horario_preferido, latitud, longitud, mapa_url, streetview_url, requiere_revision_manual,
+			   COALESCE(precio_unitario, 0), COALESCE(color_puerta, ''), COALESCE(cantidad_cilindros, 0), COALESCE(descuento, 0), created_at, updated_at
 		FROM pedidos 
 		WHERE cliente_id = ?
-		ORDER BY created_at DESC
+		ORDER BY created_at DESC, id DESC
 		LIMIT 1`
 
 	row := s.db.QueryRowContext(ctx, query, clienteID)
@@ -186,6 +256,10 @@ func (s *SQLiteStore) GetUltimoPedido(ctx context.Context, clienteID int) (*Pedi
 		&pedido.MapaURL,
 		&pedido.StreetViewURL,
 		&pedido.RequiereRevisionManual,
+		&pedido.PrecioUnitario,
+		&pedido.ColorPuerta,
+		&pedido.CantidadCilindros,
+		&pedido.Descuento,
 		&pedido.CreatedAt,
 		&pedido.UpdatedAt,
 	)
@@ -199,14 +273,18 @@ func (s *SQLiteStore) GetUltimoPedido(ctx context.Context, clienteID int) (*Pedi
 }
 
 func (s *SQLiteStore) CrearPedido(ctx context.Context, pedido *Pedido) error {
+	return s.insertarPedido(ctx, s.db, pedido)
+}
+
+func (s *SQLiteStore) insertarPedido(ctx context.Context, db ejecutor, pedido *Pedido) error {
 	query := `
 		INSERT INTO pedidos (
 			cliente_id, tipo_servicio, cantidad_litros, cantidad_dinero,
 			metodo_pago, direccion, color_fachada, estado, horario_preferido, latitud, longitud,
-			mapa_url, streetview_url, requiere_revision_manual
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+			mapa_url, streetview_url, requiere_revision_manual, precio_unitario, color_puerta, cantidad_cilindros, descuento
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
-	result, err := s.db.ExecContext(ctx, query,
+	result, err := db.ExecContext(ctx, query,
 		pedido.ClienteID,
 		pedido.TipoServicio,
 		pedido.CantidadLitros,
@@ -221,6 +299,10 @@ func (s *SQLiteStore) CrearPedido(ctx context.Context, pedido *Pedido) error {
 		pedido.MapaURL,
 		pedido.StreetViewURL,
 		pedido.RequiereRevisionManual,
+		pedido.PrecioUnitario,
+		pedido.ColorPuerta,
+		pedido.CantidadCilindros,
+		pedido.Descuento,
 	)
 	if err != nil {
 		return fmt.Errorf("error insertando pedido: %w", err)
@@ -237,10 +319,11 @@ func (s *SQLiteStore) CrearPedido(ctx context.Context, pedido *Pedido) error {
 func (s *SQLiteStore) GetUltimoPedidoActivo(ctx context.Context, clienteID int) (*Pedido, error) {
 	query := `
 		SELECT id, cliente_id, tipo_servicio, cantidad_litros, cantidad_dinero,
-			   metodo_pago, direccion, color_fachada, estado, horario_preferido, latitud, longitud, mapa_url, streetview_url, requiere_revision_manual, created_at, updated_at
+			   metodo_pago, direccion, color_fachada, estado, horario_preferido, latitud, longitud, mapa_url, streetview_url, requiere_revision_manual,
+			   COALESCE(precio_unitario, 0), COALESCE(color_puerta, ''), COALESCE(cantidad_cilindros, 0), COALESCE(descuento, 0), created_at, updated_at
 		FROM pedidos
-		WHERE cliente_id = ? AND estado NOT IN ('entregado', 'cancelado')
-		ORDER BY created_at DESC
+		WHERE cliente_id = ? AND estado NOT IN ('entregado', 'cancelado', 'en_disputa', 'no_entregado')
+		ORDER BY created_at DESC, id DESC
 		LIMIT 1`
 
 	row := s.db.QueryRowContext(ctx, query, clienteID)
@@ -262,6 +345,10 @@ func (s *SQLiteStore) GetUltimoPedidoActivo(ctx context.Context, clienteID int)
 		&pedido.MapaURL,
 		&pedido.StreetViewURL,
 		&pedido.RequiereRevisionManual,
+		&pedido.PrecioUnitario,
+		&pedido.ColorPuerta,
+		&pedido.CantidadCilindros,
+		&pedido.Descuento,
 		&pedido.CreatedAt,
 		&pedido.UpdatedAt,
 	)
@@ -277,7 +364,8 @@ func (s *SQLiteStore) GetUltimoPedidoActivo(ctx context.Context, clienteID int)
 func (s *SQLiteStore) GetPedidosPorEstado(ctx context.Context, estado string) ([]*Pedido, error) {
 	query := `
 		SELECT id, cliente_id, tipo_servicio, cantidad_litros, cantidad_dinero,
-			   metodo_pago, direccion, color_fachada, estado, horario_preferido, latitud, longitud, mapa_url, streetview_url, requiere_revision_manual, created_at, updated_at
+			   metodo_pago, direccion, color_fachada, estado, horario_preferido, latitud, longitud, mapa_url, streetview_url, requiere_revision_manual,
+			   COALESCE(precio_unitario, 0), COALESCE(color_puerta, ''), COALESCE(cantidad_cilindros, 0), COALESCE(descuento, 0), created_at, updated_at
 		FROM pedidos
 		WHERE estado = ?`
 
@@ -306,6 +394,10 @@ func (s *SQLiteStore) GetPedidosPorEstado(ctx context.Context, estado string) ([
 			&pedido.MapaURL,
 			&pedido.StreetViewURL,
 			&pedido.RequiereRevisionManual,
+			&pedido.PrecioUnitario,
+			&pedido.ColorPuerta,
+			&pedido.CantidadCilindros,
+			&pedido.Descuento,
 			&pedido.CreatedAt,
 			&pedido.UpdatedAt,
 		)
@@ -318,11 +410,130 @@ func (s *SQLiteStore) GetPedidosPorEstado(ctx context.Context, estado string) ([
 	return pedidos, nil
 }
 
+func (s *SQLiteStore) GetPedido(ctx context.Context, id int) (*Pedido, error) {
+	query := `
+		SELECT id, cliente_id, tipo_servicio, cantidad_litros, cantidad_dinero,
+			   metodo_pago, direccion, color_fachada, estado, horario_preferido, latitud, longitud, mapa_url, streetview_url, requiere_revision_manual,
+			   COALESCE(precio_unitario, 0), COALESCE(color_puerta, ''), COALESCE(cantidad_cilindros, 0), COALESCE(descuento, 0), created_at, updated_at
+		FROM pedidos
+		WHERE id = ?`
+
+	pedido := &Pedido{}
+	err := s.db.QueryRowContext(ctx, query, id).Scan(
+		&pedido.ID,
+		&pedido.ClienteID,
+		&pedido.TipoServicio,
+		&pedido.CantidadLitros,
+		&pedido.CantidadDinero,
+		&pedido.MetodoPago,
+		&pedido.Direccion,
+		&pedido.ColorFachada,
+		&pedido.Estado,
+		&pedido.HorarioPreferido,
+		&pedido.Latitud,
+		&pedido.Longitud,
+		&pedido.MapaURL,
+		&pedido.StreetViewURL,
+		&pedido.RequiereRevisionManual,
+		&pedido.PrecioUnitario,
+		&pedido.ColorPuerta,
+		&pedido.CantidadCilindros,
+		&pedido.Descuento,
+		&pedido.CreatedAt,
+		&pedido.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error escaneando pedido %d: %w", id, err)
+	}
+	return pedido, nil
+}
+
+func (s *SQLiteStore) GetPedidos(ctx context.Context, filtro FiltroPedidos) ([]*Pedido, error) {
+	var condiciones []string
+	var args []interface{}
+	if len(filtro.Estados) > 0 {
+		marcas := make([]string, len(filtro.Estados))
+		for i, estado := range filtro.Estados {
+			marcas[i] = "?"
+			args = append(args, estado)
+		}
+		condiciones = append(condiciones, "estado IN ("+strings.Join(marcas, ", ")+")")
+	}
+	if filtro.ClienteID > 0 {
+		condiciones = append(condiciones, "cliente_id = ?")
+		args = append(args, filtro.ClienteID)
+	}
+	if !filtro.ActualizadoAntesDe.IsZero() {
+		condiciones = append(condiciones, "updated_at < ?")
+		args = append(args, filtro.ActualizadoAntesDe.UTC().Format("2006-01-02 15:04:05"))
+	}
+	if !filtro.CreadoDesde.IsZero() {
+		condiciones = append(condiciones, "created_at >= ?")
+		args = append(args, filtro.CreadoDesde.UTC().Format("2006-01-02 15:04:05"))
+	}
+
+	query := `
+		SELECT id, cliente_id, tipo_servicio, cantidad_litros, cantidad_dinero,
+			   metodo_pago, direccion, color_fachada, estado, horario_preferido, latitud, longitud, mapa_url, streetview_url, requiere_revision_manual,
+			   COALESCE(precio_unitario, 0), COALESCE(color_puerta, ''), COALESCE(cantidad_cilindros, 0), COALESCE(descuento, 0), created_at, updated_at
+		FROM pedidos`
+	if len(condiciones) > 0 {
+		query += "\n\t\tWHERE " + strings.Join(condiciones, " AND ")
+	}
+	query += "\n\t\tORDER BY created_at, id"
+	if filtro.Limite > 0 {
+		query += fmt.Sprintf("\n\t\tLIMIT %d", filtro.Limite)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando pedidos: %w", err)
+	}
+	defer rows.Close()
+
+	var pedidos []*Pedido
+	for rows.Next() {
+		pedido := &Pedido{}
+		err := rows.Scan(
+			&pedido.ID,
+			&pedido.ClienteID,
+			&pedido.TipoServicio,
+			&pedido.CantidadLitros,
+			&pedido.CantidadDinero,
+			&pedido.MetodoPago,
+			&pedido.Direccion,
+			&pedido.ColorFachada,
+			&pedido.Estado,
+			&pedido.HorarioPreferido,
+			&pedido.Latitud,
+			&pedido.Longitud,
+			&pedido.MapaURL,
+			&pedido.StreetViewURL,
+			&pedido.RequiereRevisionManual,
+			&pedido.PrecioUnitario,
+			&pedido.ColorPuerta,
+			&pedido.CantidadCilindros,
+			&pedido.Descuento,
+			&pedido.CreatedAt,
+			&pedido.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error escaneando pedido: %w", err)
+		}
+		pedidos = append(pedidos, pedido)
+	}
+	return pedidos, rows.Err()
+}
+
 func (s *SQLiteStore) ActualizarPedido(ctx context.Context, pedido *Pedido) error {
 	query := `
 		UPDATE pedidos 
 		SET tipo_servicio = ?, cantidad_litros = ?, cantidad_dinero = ?,
-			metodo_pago = ?, direccion = ?, color_fachada = ?, estado = ?
+			metodo_pago = ?, direccion = ?, color_fachada = ?, estado = ?, descuento = ?,
+			updated_at = CURRENT_TIMESTAMP
 		WHERE id = ?`
 
 	result, err := s.db.ExecContext(ctx, query,
@@ -333,6 +544,7 @@ func (s *SQLiteStore) ActualizarPedido(ctx context.Context, pedido *Pedido) erro
 		pedido.Direccion,
 		pedido.ColorFachada,
 		pedido.Estado,
+		pedido.Descuento,
 		pedido.ID,
 	)
 	if err != nil {
@@ -348,32 +560,3 @@ func (s *SQLiteStore) ActualizarPedido(ctx context.Context, pedido *Pedido) erro
 	}
 	return nil
 }
-
-func (s *SQLiteStore) CrearReporteSello(ctx context.Context, reporte *ReporteSello) error {
-	// Registrar fecha de reporte en memoria (la columna en BD puede no existir aún)
-	if reporte.FechaReporte.IsZero() {
-		reporte.FechaReporte = time.Now()
-	}
-
-	query := `
-		INSERT INTO reportes_sello (
-			cliente_id, pedido_id, descripcion, estado
-		) VALUES (?, ?, ?, ?)`
-
-	result, err := s.db.ExecContext(ctx, query,
-		reporte.ClienteID,
-		reporte.PedidoID,
-		reporte.Descripcion,
-		reporte.Estado,
-	)
-	if err != nil {
-		return fmt.Errorf("error insertando reporte: %w", err)
-	}
-
-	id, err := result.LastInsertId()
-	if err != nil {
-		return fmt.Errorf("error obteniendo ID insertado: %w", err)
-	}
-	reporte.ID = int(id)
-	return nil
-}
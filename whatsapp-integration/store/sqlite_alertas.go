@@ -0,0 +1,98 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+const columnasAlerta = `id, tipo, pedido_id, repartidor_id, referencia_id, mensaje, estado,
+	enviada_repartidor, entregada_pos, escalada_en, reconocida_por, reconocida_en, created_at`
+
+func scanAlerta(row interface{ Scan(...interface{}) error }) (*Alerta, error) {
+	a := &Alerta{}
+	var repartidorID, referenciaID sql.NullInt64
+	var enviada, entregada, escalada, reconocida sql.NullTime
+	if err := row.Scan(&a.ID, &a.Tipo, &a.PedidoID, &repartidorID, &referenciaID, &a.Mensaje, &a.Estado,
+		&enviada, &entregada, &escalada, &a.ReconocidaPor, &reconocida, &a.CreatedAt); err != nil {
+		return nil, err
+	}
+	a.RepartidorID = int(repartidorID.Int64)
+	a.ReferenciaID = int(referenciaID.Int64)
+	a.EnviadaRepartidor = fechaNula(enviada)
+	a.EntregadaPOS = fechaNula(entregada)
+	a.EscaladaEn = fechaNula(escalada)
+	a.ReconocidaEn = fechaNula(reconocida)
+	return a, nil
+}
+
+// leerAlertas es compartido por SQLite y MySQL.
+func leerAlertas(rows *sql.Rows) ([]*Alerta, error) {
+	defer rows.Close()
+	var alertas []*Alerta
+	for rows.Next() {
+		a, err := scanAlerta(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error escaneando alerta: %w", err)
+		}
+		alertas = append(alertas, a)
+	}
+	return alertas, rows.Err()
+}
+
+// idOpcional guarda NULL en vez de 0 para las llaves opcionales.
+func idOpcional(id int) interface{} {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+func (s *SQLiteStore) CrearAlerta(ctx context.Context, a *Alerta) error {
+	result, err := s.db.ExecContext(ctx, `
+		INSERT INTO alertas (tipo, pedido_id, repartidor_id, referencia_id, mensaje, estado)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		a.Tipo, a.PedidoID, idOpcional(a.RepartidorID), idOpcional(a.ReferenciaID), a.Mensaje, a.Estado)
+	if err != nil {
+		return fmt.Errorf("error creando alerta del pedido %d: %w", a.PedidoID, err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("error obteniendo ID insertado: %w", err)
+	}
+	a.ID = int(id)
+	return nil
+}
+
+func (s *SQLiteStore) GetAlerta(ctx context.Context, id int) (*Alerta, error) {
+	a, err := scanAlerta(s.db.QueryRowContext(ctx, `SELECT `+columnasAlerta+` FROM alertas WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error consultando alerta %d: %w", id, err)
+	}
+	return a, nil
+}
+
+func (s *SQLiteStore) GetAlertasPorEstado(ctx context.Context, estado string) ([]*Alerta, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+columnasAlerta+` FROM alertas WHERE ? = '' OR estado = ? ORDER BY id`, estado, estado)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando alertas: %w", err)
+	}
+	return leerAlertas(rows)
+}
+
+func (s *SQLiteStore) ActualizarAlerta(ctx context.Context, a *Alerta) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE alertas SET estado = ?, enviada_repartidor = ?, entregada_pos = ?, escalada_en = ?,
+			reconocida_por = ?, reconocida_en = ?
+		WHERE id = ?`,
+		a.Estado, fechaOpcionalSQLite(a.EnviadaRepartidor), fechaOpcionalSQLite(a.EntregadaPOS), fechaOpcionalSQLite(a.EscaladaEn),
+		a.ReconocidaPor, fechaOpcionalSQLite(a.ReconocidaEn), a.ID)
+	if err != nil {
+		return fmt.Errorf("error actualizando alerta %d: %w", a.ID, err)
+	}
+	return nil
+}
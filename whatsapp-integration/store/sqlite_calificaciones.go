@@ -0,0 +1,89 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+const columnasCalificacion = `id, pedido_id, cliente_id, repartidor_id, puntuacion, comentario, escalada_a, escalada_en, created_at`
+
+func scanCalificacion(row interface{ Scan(...interface{}) error }) (*Calificacion, error) {
+	c := &Calificacion{}
+	var repartidorID sql.NullInt64
+	var escalada sql.NullTime
+	if err := row.Scan(&c.ID, &c.PedidoID, &c.ClienteID, &repartidorID, &c.Puntuacion, &c.Comentario,
+		&c.EscaladaA, &escalada, &c.CreatedAt); err != nil {
+		return nil, err
+	}
+	c.RepartidorID = int(repartidorID.Int64)
+	c.EscaladaEn = fechaNula(escalada)
+	return c, nil
+}
+
+// leerCalificaciones es compartido por SQLite y MySQL.
+func leerCalificaciones(rows *sql.Rows) ([]*Calificacion, error) {
+	defer rows.Close()
+	var calificaciones []*Calificacion
+	for rows.Next() {
+		c, err := scanCalificacion(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error escaneando calificación: %w", err)
+		}
+		calificaciones = append(calificaciones, c)
+	}
+	return calificaciones, rows.Err()
+}
+
+func (s *SQLiteStore) CrearCalificacion(ctx context.Context, c *Calificacion) error {
+	result, err := s.db.ExecContext(ctx, `
+		INSERT INTO calificaciones (pedido_id, cliente_id, repartidor_id, puntuacion, comentario, escalada_a, escalada_en, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		c.PedidoID, c.ClienteID, idOpcional(c.RepartidorID), c.Puntuacion, c.Comentario, c.EscaladaA,
+		fechaOpcionalSQLite(c.EscaladaEn), c.CreatedAt.UTC().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return fmt.Errorf("error creando calificación del pedido %d: %w", c.PedidoID, err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("error obteniendo ID insertado: %w", err)
+	}
+	c.ID = int(id)
+	return nil
+}
+
+func (s *SQLiteStore) GetCalificacionPedido(ctx context.Context, pedidoID int) (*Calificacion, error) {
+	c, err := scanCalificacion(s.db.QueryRowContext(ctx, `SELECT `+columnasCalificacion+` FROM calificaciones WHERE pedido_id = ?`, pedidoID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error consultando calificación del pedido %d: %w", pedidoID, err)
+	}
+	return c, nil
+}
+
+func (s *SQLiteStore) GetCalificaciones(ctx context.Context, desde, hasta time.Time) ([]*Calificacion, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+columnasCalificacion+` FROM calificaciones
+		WHERE created_at >= ? AND created_at < ?
+		ORDER BY created_at, id`,
+		desde.UTC().Format("2006-01-02 15:04:05"), hasta.UTC().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return nil, fmt.Errorf("error consultando calificaciones: %w", err)
+	}
+	return leerCalificaciones(rows)
+}
+
+func (s *SQLiteStore) ActualizarCalificacion(ctx context.Context, c *Calificacion) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE calificaciones SET repartidor_id = ?, puntuacion = ?, comentario = ?, escalada_a = ?, escalada_en = ?
+		WHERE id = ?`,
+		idOpcional(c.RepartidorID), c.Puntuacion, c.Comentario, c.EscaladaA, fechaOpcionalSQLite(c.EscaladaEn), c.ID)
+	if err != nil {
+		return fmt.Errorf("error actualizando calificación %d: %w", c.ID, err)
+	}
+	return nil
+}
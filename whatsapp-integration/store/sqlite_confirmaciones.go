@@ -0,0 +1,125 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+const columnasConfirmacionEntrega = `id, pedido_id, cliente_id, estado, vence, respondida_en, reporte_id, created_at`
+
+func scanConfirmacionEntrega(row interface{ Scan(...interface{}) error }) (*ConfirmacionEntrega, error) {
+	c := &ConfirmacionEntrega{}
+	var respondida sql.NullTime
+	var reporteID sql.NullInt64
+	if err := row.Scan(&c.ID, &c.PedidoID, &c.ClienteID, &c.Estado, &c.Vence, &respondida, &reporteID, &c.CreatedAt); err != nil {
+		return nil, err
+	}
+	c.RespondidaEn = fechaNula(respondida)
+	c.ReporteID = int(reporteID.Int64)
+	return c, nil
+}
+
+// leerConfirmacionesEntrega es compartido por SQLite y MySQL.
+func leerConfirmacionesEntrega(rows *sql.Rows) ([]*ConfirmacionEntrega, error) {
+	defer rows.Close()
+	var confirmaciones []*ConfirmacionEntrega
+	for rows.Next() {
+		c, err := scanConfirmacionEntrega(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error escaneando confirmación de entrega: %w", err)
+		}
+		confirmaciones = append(confirmaciones, c)
+	}
+	return confirmaciones, rows.Err()
+}
+
+func (s *SQLiteStore) CrearConfirmacionEntrega(ctx context.Context, c *ConfirmacionEntrega) error {
+	result, err := s.db.ExecContext(ctx, `
+		INSERT INTO confirmaciones_entrega (pedido_id, cliente_id, estado, vence)
+		VALUES (?, ?, ?, ?)`,
+		c.PedidoID, c.ClienteID, c.Estado, c.Vence.UTC().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return fmt.Errorf("error creando confirmación de entrega del pedido %d: %w", c.PedidoID, err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("error obteniendo ID insertado: %w", err)
+	}
+	c.ID = int(id)
+	return nil
+}
+
+func (s *SQLiteStore) GetConfirmacionEntrega(ctx context.Context, id int) (*ConfirmacionEntrega, error) {
+	c, err := scanConfirmacionEntrega(s.db.QueryRowContext(ctx, `SELECT `+columnasConfirmacionEntrega+` FROM confirmaciones_entrega WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error consultando confirmación de entrega %d: %w", id, err)
+	}
+	return c, nil
+}
+
+func (s *SQLiteStore) GetConfirmacionEntregaPendiente(ctx context.Context, clienteID int) (*ConfirmacionEntrega, error) {
+	c, err := scanConfirmacionEntrega(s.db.QueryRowContext(ctx, `
+		SELECT `+columnasConfirmacionEntrega+` FROM confirmaciones_entrega
+		WHERE cliente_id = ? AND estado = 'pendiente'
+		ORDER BY id DESC LIMIT 1`, clienteID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error consultando confirmación de entrega del cliente %d: %w", clienteID, err)
+	}
+	return c, nil
+}
+
+func (s *SQLiteStore) GetConfirmacionEntregaConfirmada(ctx context.Context, clienteID int) (*ConfirmacionEntrega, error) {
+	c, err := scanConfirmacionEntrega(s.db.QueryRowContext(ctx, `
+		SELECT `+columnasConfirmacionEntrega+` FROM confirmaciones_entrega
+		WHERE cliente_id = ? AND estado = 'confirmada'
+		ORDER BY respondida_en DESC, id DESC LIMIT 1`, clienteID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error consultando entrega confirmada del cliente %d: %w", clienteID, err)
+	}
+	return c, nil
+}
+
+func (s *SQLiteStore) GetConfirmacionesEntregaVencidas(ctx context.Context, ahora time.Time) ([]*ConfirmacionEntrega, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+columnasConfirmacionEntrega+` FROM confirmaciones_entrega
+		WHERE estado = 'pendiente' AND vence <= ?
+		ORDER BY id`, ahora.UTC().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return nil, fmt.Errorf("error consultando confirmaciones de entrega vencidas: %w", err)
+	}
+	return leerConfirmacionesEntrega(rows)
+}
+
+func (s *SQLiteStore) GetConfirmacionesEntregaPorEnviar(ctx context.Context) ([]*ConfirmacionEntrega, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+columnasConfirmacionEntrega+` FROM confirmaciones_entrega
+		WHERE estado = 'por_enviar'
+		ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando confirmaciones de entrega por enviar: %w", err)
+	}
+	return leerConfirmacionesEntrega(rows)
+}
+
+func (s *SQLiteStore) ActualizarConfirmacionEntrega(ctx context.Context, c *ConfirmacionEntrega) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE confirmaciones_entrega SET estado = ?, vence = ?, respondida_en = ?, reporte_id = ?
+		WHERE id = ?`,
+		c.Estado, c.Vence.UTC().Format("2006-01-02 15:04:05"), fechaOpcionalSQLite(c.RespondidaEn), idOpcional(c.ReporteID), c.ID)
+	if err != nil {
+		return fmt.Errorf("error actualizando confirmación de entrega %d: %w", c.ID, err)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+func scanDatosFiscales(row interface{ Scan(...interface{}) error }) (*DatosFiscales, error) {
+	d := &DatosFiscales{}
+	if err := row.Scan(&d.ClienteID, &d.RFC, &d.RazonSocial, &d.RegimenFiscal, &d.CodigoPostal, &d.UsoCFDI, &d.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+const columnasDatosFiscales = `cliente_id, rfc, razon_social, regimen_fiscal, codigo_postal, uso_cfdi, updated_at`
+
+func scanFactura(row interface{ Scan(...interface{}) error }) (*Factura, error) {
+	f := &Factura{}
+	if err := row.Scan(&f.ID, &f.PedidoID, &f.ClienteID, &f.RFC, &f.Serie, &f.Folio, &f.UUID, &f.Total, &f.ArchivoXML, &f.ArchivoPDF, &f.Token, &f.CreatedAt); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+const columnasFactura = `id, pedido_id, cliente_id, rfc, serie, folio, uuid, total, archivo_xml, archivo_pdf, token, created_at`
+
+func (s *SQLiteStore) GetDatosFiscales(ctx context.Context, clienteID int) (*DatosFiscales, error) {
+	query := `SELECT ` + columnasDatosFiscales + ` FROM datos_fiscales WHERE cliente_id = ?`
+
+	d, err := scanDatosFiscales(s.db.QueryRowContext(ctx, query, clienteID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error consultando datos fiscales del cliente %d: %w", clienteID, err)
+	}
+	return d, nil
+}
+
+func (s *SQLiteStore) GuardarDatosFiscales(ctx context.Context, d *DatosFiscales) error {
+	query := `
+		INSERT INTO datos_fiscales (cliente_id, rfc, razon_social, regimen_fiscal, codigo_postal, uso_cfdi)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(cliente_id) DO UPDATE SET
+			rfc = excluded.rfc,
+			razon_social = excluded.razon_social,
+			regimen_fiscal = excluded.regimen_fiscal,
+			codigo_postal = excluded.codigo_postal,
+			uso_cfdi = excluded.uso_cfdi,
+			updated_at = CURRENT_TIMESTAMP`
+
+	if _, err := s.db.ExecContext(ctx, query, d.ClienteID, d.RFC, d.RazonSocial, d.RegimenFiscal, d.CodigoPostal, d.UsoCFDI); err != nil {
+		return fmt.Errorf("error guardando datos fiscales del cliente %d: %w", d.ClienteID, err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) CrearFactura(ctx context.Context, f *Factura) error {
+	query := `
+		INSERT INTO facturas (pedido_id, cliente_id, rfc, serie, folio, uuid, total, archivo_xml, archivo_pdf, token)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	result, err := s.db.ExecContext(ctx, query, f.PedidoID, f.ClienteID, f.RFC, f.Serie, f.Folio, f.UUID, f.Total, f.ArchivoXML, f.ArchivoPDF, f.Token)
+	if err != nil {
+		return fmt.Errorf("error creando factura del pedido %d: %w", f.PedidoID, err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("error obteniendo ID insertado: %w", err)
+	}
+	f.ID = int(id)
+	return nil
+}
+
+func (s *SQLiteStore) GetFacturaPorPedido(ctx context.Context, pedidoID int) (*Factura, error) {
+	query := `SELECT ` + columnasFactura + ` FROM facturas WHERE pedido_id = ?`
+
+	f, err := scanFactura(s.db.QueryRowContext(ctx, query, pedidoID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error consultando factura del pedido %d: %w", pedidoID, err)
+	}
+	return f, nil
+}
+
+func (s *SQLiteStore) GetFacturaPorToken(ctx context.Context, token string) (*Factura, error) {
+	query := `SELECT ` + columnasFactura + ` FROM facturas WHERE token = ?`
+
+	f, err := scanFactura(s.db.QueryRowContext(ctx, query, token))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error consultando factura: %w", err)
+	}
+	return f, nil
+}
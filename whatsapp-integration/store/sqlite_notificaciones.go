@@ -0,0 +1,59 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+func (s *SQLiteStore) NotificacionEnviada(ctx context.Context, pedidoID int, tipo string) (bool, error) {
+	query := `SELECT COUNT(*) FROM notificaciones_pedido WHERE pedido_id = ? AND tipo = ?`
+
+	var total int
+	if err := s.db.QueryRowContext(ctx, query, pedidoID, tipo).Scan(&total); err != nil {
+		return false, fmt.Errorf("error consultando notificaciones del pedido %d: %w", pedidoID, err)
+	}
+	return total > 0, nil
+}
+
+func (s *SQLiteStore) RegistrarNotificacion(ctx context.Context, notificacion *NotificacionPedido) error {
+	// El índice único (pedido_id, tipo) evita duplicados si dos procesos avisan a la vez.
+	query := `
+		INSERT OR IGNORE INTO notificaciones_pedido (pedido_id, tipo, telefono, mensaje)
+		VALUES (?, ?, ?, ?)`
+
+	result, err := s.db.ExecContext(ctx, query,
+		notificacion.PedidoID,
+		notificacion.Tipo,
+		notificacion.Telefono,
+		notificacion.Mensaje,
+	)
+	if err != nil {
+		return fmt.Errorf("error registrando notificación: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("error obteniendo ID insertado: %w", err)
+	}
+	notificacion.ID = int(id)
+	return nil
+}
+
+func (s *SQLiteStore) BorrarNotificaciones(ctx context.Context, pedidoID int, tipos []string) error {
+	if len(tipos) == 0 {
+		return nil
+	}
+	marcas := make([]string, len(tipos))
+	args := []interface{}{pedidoID}
+	for i, tipo := range tipos {
+		marcas[i] = "?"
+		args = append(args, tipo)
+	}
+	query := `DELETE FROM notificaciones_pedido WHERE pedido_id = ? AND tipo IN (` + strings.Join(marcas, ", ") + `)`
+
+	if _, err := s.db.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("error borrando notificaciones del pedido %d: %w", pedidoID, err)
+	}
+	return nil
+}
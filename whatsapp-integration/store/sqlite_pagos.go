@@ -0,0 +1,194 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+func scanPago(row interface{ Scan(...interface{}) error }) (*Pago, error) {
+	p := &Pago{}
+	var venceEn, pagadoEn sql.NullTime
+	if err := row.Scan(&p.ID, &p.PedidoID, &p.Metodo, &p.Estado, &p.Monto, &p.Referencia, &p.URL, &venceEn, &pagadoEn, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if venceEn.Valid {
+		p.VenceEn = &venceEn.Time
+	}
+	if pagadoEn.Valid {
+		p.PagadoEn = &pagadoEn.Time
+	}
+	return p, nil
+}
+
+const columnasPago = `id, pedido_id, metodo, estado, monto, referencia, url, vence_en, pagado_en, created_at, updated_at`
+
+func (s *SQLiteStore) CrearPago(ctx context.Context, pago *Pago) error {
+	query := `
+		INSERT INTO pagos (pedido_id, metodo, estado, monto, referencia, url, vence_en, pagado_en)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+
+	result, err := s.db.ExecContext(ctx, query, pago.PedidoID, pago.Metodo, pago.Estado, pago.Monto, pago.Referencia, pago.URL, fechaOpcionalSQLite(pago.VenceEn), fechaOpcionalSQLite(pago.PagadoEn))
+	if err != nil {
+		return fmt.Errorf("error creando pago del pedido %d: %w", pago.PedidoID, err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("error obteniendo ID insertado: %w", err)
+	}
+	pago.ID = int(id)
+	return nil
+}
+
+func (s *SQLiteStore) ActualizarPago(ctx context.Context, pago *Pago) error {
+	query := `
+		UPDATE pagos
+		SET metodo = ?, estado = ?, monto = ?, referencia = ?, url = ?, vence_en = ?, pagado_en = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?`
+
+	if _, err := s.db.ExecContext(ctx, query, pago.Metodo, pago.Estado, pago.Monto, pago.Referencia, pago.URL, fechaOpcionalSQLite(pago.VenceEn), fechaOpcionalSQLite(pago.PagadoEn), pago.ID); err != nil {
+		return fmt.Errorf("error actualizando pago %d: %w", pago.ID, err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) GetPagoPorPedido(ctx context.Context, pedidoID int) (*Pago, error) {
+	query := `SELECT ` + columnasPago + ` FROM pagos WHERE pedido_id = ?`
+
+	pago, err := scanPago(s.db.QueryRowContext(ctx, query, pedidoID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error consultando pago del pedido %d: %w", pedidoID, err)
+	}
+	return pago, nil
+}
+
+func (s *SQLiteStore) GetPagosPorEstado(ctx context.Context, metodo, estado string) ([]*Pago, error) {
+	query := `SELECT ` + columnasPago + ` FROM pagos WHERE estado = ?`
+	args := []interface{}{estado}
+	if metodo != "" {
+		query += ` AND metodo = ?`
+		args = append(args, metodo)
+	}
+	query += ` ORDER BY id`
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando pagos %s: %w", estado, err)
+	}
+	defer rows.Close()
+
+	var pagos []*Pago
+	for rows.Next() {
+		pago, err := scanPago(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error escaneando pago: %w", err)
+		}
+		pagos = append(pagos, pago)
+	}
+	return pagos, rows.Err()
+}
+
+func (s *SQLiteStore) GetPagoPorReferencia(ctx context.Context, metodo, referencia string) (*Pago, error) {
+	query := `SELECT ` + columnasPago + ` FROM pagos WHERE metodo = ? AND referencia = ?`
+
+	pago, err := scanPago(s.db.QueryRowContext(ctx, query, metodo, referencia))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error consultando pago con referencia %s: %w", referencia, err)
+	}
+	return pago, nil
+}
+
+func scanComprobantePago(row interface{ Scan(...interface{}) error }) (*ComprobantePago, error) {
+	c := &ComprobantePago{}
+	var revisadoEn sql.NullTime
+	if err := row.Scan(&c.ID, &c.PedidoID, &c.ClienteID, &c.Archivo, &c.MimeType, &c.Estado, &c.RevisadoPor, &c.Nota, &c.CreatedAt, &revisadoEn); err != nil {
+		return nil, err
+	}
+	if revisadoEn.Valid {
+		c.RevisadoEn = &revisadoEn.Time
+	}
+	return c, nil
+}
+
+const columnasComprobantePago = `id, pedido_id, cliente_id, archivo, mime_type, estado, revisado_por, nota, created_at, revisado_en`
+
+// leerComprobantesPago recorre el resultado de una consulta de comprobantes.
+func leerComprobantesPago(rows *sql.Rows) ([]*ComprobantePago, error) {
+	defer rows.Close()
+
+	var comprobantes []*ComprobantePago
+	for rows.Next() {
+		c, err := scanComprobantePago(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error escaneando comprobante de pago: %w", err)
+		}
+		comprobantes = append(comprobantes, c)
+	}
+	return comprobantes, rows.Err()
+}
+
+func (s *SQLiteStore) CrearComprobantePago(ctx context.Context, c *ComprobantePago) error {
+	query := `
+		INSERT INTO comprobantes_pago (pedido_id, cliente_id, archivo, mime_type, estado, revisado_por, nota, revisado_en)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+
+	result, err := s.db.ExecContext(ctx, query, c.PedidoID, c.ClienteID, c.Archivo, c.MimeType, c.Estado, c.RevisadoPor, c.Nota, fechaOpcionalSQLite(c.RevisadoEn))
+	if err != nil {
+		return fmt.Errorf("error creando comprobante del pedido %d: %w", c.PedidoID, err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("error obteniendo ID insertado: %w", err)
+	}
+	c.ID = int(id)
+	return nil
+}
+
+func (s *SQLiteStore) ActualizarComprobantePago(ctx context.Context, c *ComprobantePago) error {
+	query := `
+		UPDATE comprobantes_pago
+		SET estado = ?, revisado_por = ?, nota = ?, revisado_en = ?
+		WHERE id = ?`
+
+	if _, err := s.db.ExecContext(ctx, query, c.Estado, c.RevisadoPor, c.Nota, fechaOpcionalSQLite(c.RevisadoEn), c.ID); err != nil {
+		return fmt.Errorf("error actualizando comprobante %d: %w", c.ID, err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) GetComprobantePago(ctx context.Context, id int) (*ComprobantePago, error) {
+	query := `SELECT ` + columnasComprobantePago + ` FROM comprobantes_pago WHERE id = ?`
+
+	c, err := scanComprobantePago(s.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error consultando comprobante %d: %w", id, err)
+	}
+	return c, nil
+}
+
+func (s *SQLiteStore) GetComprobantesPago(ctx context.Context, estado string) ([]*ComprobantePago, error) {
+	query := `SELECT ` + columnasComprobantePago + ` FROM comprobantes_pago`
+	var args []interface{}
+	if estado != "" {
+		query += ` WHERE estado = ?`
+		args = append(args, estado)
+	}
+	query += ` ORDER BY id`
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando comprobantes de pago: %w", err)
+	}
+	return leerComprobantesPago(rows)
+}
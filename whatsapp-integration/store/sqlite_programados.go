@@ -0,0 +1,159 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+const columnasPedidoProgramado = `id, cliente_id, tipo_servicio, cantidad_litros, cantidad_cilindros, metodo_pago,
+			   direccion, latitud, longitud, mapa_url, streetview_url, color_fachada, color_puerta,
+			   horario_preferido, frecuencia, intervalo_semanas, dia_mes, proxima_fecha,
+			   omitir_proxima, recordatorio_enviado, activo, created_at, updated_at`
+
+func (s *SQLiteStore) CrearPedidoProgramado(ctx context.Context, programado *PedidoProgramado) error {
+	query := `
+		INSERT INTO pedidos_programados (
+			cliente_id, tipo_servicio, cantidad_litros, cantidad_cilindros, metodo_pago,
+			direccion, latitud, longitud, mapa_url, streetview_url, color_fachada, color_puerta,
+			horario_preferido, frecuencia, intervalo_semanas, dia_mes, proxima_fecha,
+			omitir_proxima, recordatorio_enviado, activo
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	result, err := s.db.ExecContext(ctx, query,
+		programado.ClienteID,
+		programado.TipoServicio,
+		programado.CantidadLitros,
+		programado.CantidadCilindros,
+		programado.MetodoPago,
+		programado.Direccion,
+		programado.Latitud,
+		programado.Longitud,
+		programado.MapaURL,
+		programado.StreetViewURL,
+		programado.ColorFachada,
+		programado.ColorPuerta,
+		programado.HorarioPreferido,
+		programado.Frecuencia,
+		programado.IntervaloSemanas,
+		programado.DiaMes,
+		programado.ProximaFecha.Format("2006-01-02"),
+		programado.OmitirProxima,
+		programado.RecordatorioEnviado,
+		programado.Activo,
+	)
+	if err != nil {
+		return fmt.Errorf("error insertando pedido programado: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("error obteniendo ID insertado: %w", err)
+	}
+	programado.ID = int(id)
+	return nil
+}
+
+func (s *SQLiteStore) ActualizarPedidoProgramado(ctx context.Context, programado *PedidoProgramado) error {
+	query := `
+		UPDATE pedidos_programados
+		SET horario_preferido = ?, frecuencia = ?, intervalo_semanas = ?, dia_mes = ?,
+			proxima_fecha = ?, omitir_proxima = ?, recordatorio_enviado = ?, activo = ?,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?`
+
+	result, err := s.db.ExecContext(ctx, query,
+		programado.HorarioPreferido,
+		programado.Frecuencia,
+		programado.IntervaloSemanas,
+		programado.DiaMes,
+		programado.ProximaFecha.Format("2006-01-02"),
+		programado.OmitirProxima,
+		programado.RecordatorioEnviado,
+		programado.Activo,
+		programado.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("error actualizando pedido programado: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error verificando actualización: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("pedido programado no encontrado: %d", programado.ID)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) GetPedidosProgramadosPorCliente(ctx context.Context, clienteID int) ([]*PedidoProgramado, error) {
+	query := `
+		SELECT ` + columnasPedidoProgramado + `
+		FROM pedidos_programados
+		WHERE cliente_id = ? AND activo = 1
+		ORDER BY proxima_fecha`
+
+	rows, err := s.db.QueryContext(ctx, query, clienteID)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando pedidos programados: %w", err)
+	}
+	defer rows.Close()
+
+	return scanPedidosProgramados(rows)
+}
+
+func (s *SQLiteStore) GetPedidosProgramadosHasta(ctx context.Context, fecha time.Time) ([]*PedidoProgramado, error) {
+	query := `
+		SELECT ` + columnasPedidoProgramado + `
+		FROM pedidos_programados
+		WHERE activo = 1 AND proxima_fecha <= ?
+		ORDER BY proxima_fecha`
+
+	rows, err := s.db.QueryContext(ctx, query, fecha.Format("2006-01-02"))
+	if err != nil {
+		return nil, fmt.Errorf("error consultando pedidos programados: %w", err)
+	}
+	defer rows.Close()
+
+	return scanPedidosProgramados(rows)
+}
+
+// scanPedidosProgramados es compartido por SQLite y MySQL porque las columnas son las mismas.
+func scanPedidosProgramados(rows *sql.Rows) ([]*PedidoProgramado, error) {
+	var programados []*PedidoProgramado
+	for rows.Next() {
+		p := &PedidoProgramado{}
+		err := rows.Scan(
+			&p.ID,
+			&p.ClienteID,
+			&p.TipoServicio,
+			&p.CantidadLitros,
+			&p.CantidadCilindros,
+			&p.MetodoPago,
+			&p.Direccion,
+			&p.Latitud,
+			&p.Longitud,
+			&p.MapaURL,
+			&p.StreetViewURL,
+			&p.ColorFachada,
+			&p.ColorPuerta,
+			&p.HorarioPreferido,
+			&p.Frecuencia,
+			&p.IntervaloSemanas,
+			&p.DiaMes,
+			&p.ProximaFecha,
+			&p.OmitirProxima,
+			&p.RecordatorioEnviado,
+			&p.Activo,
+			&p.CreatedAt,
+			&p.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error escaneando pedido programado: %w", err)
+		}
+		programados = append(programados, p)
+	}
+	return programados, rows.Err()
+}
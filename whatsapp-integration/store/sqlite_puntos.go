@@ -0,0 +1,88 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+func scanMovimientoPuntos(row interface{ Scan(...interface{}) error }) (*MovimientoPuntos, error) {
+	m := &MovimientoPuntos{}
+	var pedidoID sql.NullInt64
+	if err := row.Scan(&m.ID, &m.ClienteID, &pedidoID, &m.Tipo, &m.Puntos, &m.Descripcion, &m.CreatedAt); err != nil {
+		return nil, err
+	}
+	if pedidoID.Valid {
+		id := int(pedidoID.Int64)
+		m.PedidoID = &id
+	}
+	return m, nil
+}
+
+const columnasMovimientoPuntos = `id, cliente_id, pedido_id, tipo, puntos, descripcion, created_at`
+
+func (s *SQLiteStore) RegistrarMovimientoPuntos(ctx context.Context, mov *MovimientoPuntos) error {
+	query := `
+		INSERT INTO movimientos_puntos (cliente_id, pedido_id, tipo, puntos, descripcion)
+		VALUES (?, ?, ?, ?, ?)`
+
+	result, err := s.db.ExecContext(ctx, query, mov.ClienteID, mov.PedidoID, mov.Tipo, mov.Puntos, mov.Descripcion)
+	if err != nil {
+		return fmt.Errorf("error registrando movimiento de puntos: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("error obteniendo ID insertado: %w", err)
+	}
+	mov.ID = int(id)
+	return nil
+}
+
+func (s *SQLiteStore) GetSaldoPuntos(ctx context.Context, clienteID int) (int, error) {
+	var saldo sql.NullInt64
+	err := s.db.QueryRowContext(ctx, `SELECT SUM(puntos) FROM movimientos_puntos WHERE cliente_id = ?`, clienteID).Scan(&saldo)
+	if err != nil {
+		return 0, fmt.Errorf("error consultando saldo de puntos del cliente %d: %w", clienteID, err)
+	}
+	return int(saldo.Int64), nil
+}
+
+func (s *SQLiteStore) GetMovimientosPuntos(ctx context.Context, clienteID int, limite int) ([]*MovimientoPuntos, error) {
+	query := `SELECT ` + columnasMovimientoPuntos + ` FROM movimientos_puntos WHERE cliente_id = ? ORDER BY id DESC`
+	args := []interface{}{clienteID}
+	if limite > 0 {
+		query += ` LIMIT ?`
+		args = append(args, limite)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando movimientos de puntos del cliente %d: %w", clienteID, err)
+	}
+	defer rows.Close()
+	return leerMovimientosPuntos(rows)
+}
+
+func (s *SQLiteStore) GetMovimientosPuntosPedido(ctx context.Context, pedidoID int) ([]*MovimientoPuntos, error) {
+	query := `SELECT ` + columnasMovimientoPuntos + ` FROM movimientos_puntos WHERE pedido_id = ? ORDER BY id`
+
+	rows, err := s.db.QueryContext(ctx, query, pedidoID)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando movimientos de puntos del pedido %d: %w", pedidoID, err)
+	}
+	defer rows.Close()
+	return leerMovimientosPuntos(rows)
+}
+
+func leerMovimientosPuntos(rows *sql.Rows) ([]*MovimientoPuntos, error) {
+	var movimientos []*MovimientoPuntos
+	for rows.Next() {
+		m, err := scanMovimientoPuntos(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error escaneando movimiento de puntos: %w", err)
+		}
+		movimientos = append(movimientos, m)
+	}
+	return movimientos, rows.Err()
+}
@@ -0,0 +1,118 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+func scanReferido(row interface{ Scan(...interface{}) error }) (*Referido, error) {
+	r := &Referido{}
+	var pedidoID sql.NullInt64
+	var recompensadoEn sql.NullTime
+	if err := row.Scan(&r.ID, &r.ReferenteID, &r.ReferidoID, &r.Codigo, &r.Estado, &pedidoID, &r.CreatedAt, &recompensadoEn); err != nil {
+		return nil, err
+	}
+	if pedidoID.Valid {
+		id := int(pedidoID.Int64)
+		r.PedidoID = &id
+	}
+	if recompensadoEn.Valid {
+		r.RecompensadoEn = &recompensadoEn.Time
+	}
+	return r, nil
+}
+
+const columnasReferido = `id, referente_id, referido_id, codigo, estado, pedido_id, created_at, recompensado_en`
+
+func (s *SQLiteStore) GetCodigoReferido(ctx context.Context, clienteID int) (string, error) {
+	var codigo string
+	err := s.db.QueryRowContext(ctx, `SELECT codigo FROM codigos_referido WHERE cliente_id = ?`, clienteID).Scan(&codigo)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("error consultando código de referido del cliente %d: %w", clienteID, err)
+	}
+	return codigo, nil
+}
+
+func (s *SQLiteStore) CrearCodigoReferido(ctx context.Context, clienteID int, codigo string) error {
+	_, err := s.db.ExecContext(ctx, `INSERT INTO codigos_referido (cliente_id, codigo) VALUES (?, ?)`, clienteID, codigo)
+	if err != nil {
+		return fmt.Errorf("error creando código de referido del cliente %d: %w", clienteID, err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) GetClienteIDPorCodigoReferido(ctx context.Context, codigo string) (int, error) {
+	var clienteID int
+	err := s.db.QueryRowContext(ctx, `SELECT cliente_id FROM codigos_referido WHERE codigo = ?`, codigo).Scan(&clienteID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error consultando código de referido %s: %w", codigo, err)
+	}
+	return clienteID, nil
+}
+
+func (s *SQLiteStore) CrearReferido(ctx context.Context, referido *Referido) error {
+	query := `
+		INSERT INTO referidos (referente_id, referido_id, codigo, estado)
+		VALUES (?, ?, ?, ?)`
+
+	result, err := s.db.ExecContext(ctx, query, referido.ReferenteID, referido.ReferidoID, referido.Codigo, referido.Estado)
+	if err != nil {
+		return fmt.Errorf("error registrando referido del cliente %d: %w", referido.ReferidoID, err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("error obteniendo ID insertado: %w", err)
+	}
+	referido.ID = int(id)
+	return nil
+}
+
+func (s *SQLiteStore) GetReferidoPorCliente(ctx context.Context, referidoID int) (*Referido, error) {
+	query := `SELECT ` + columnasReferido + ` FROM referidos WHERE referido_id = ?`
+
+	referido, err := scanReferido(s.db.QueryRowContext(ctx, query, referidoID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error consultando referido del cliente %d: %w", referidoID, err)
+	}
+	return referido, nil
+}
+
+func (s *SQLiteStore) GetReferidosDe(ctx context.Context, referenteID int) ([]*Referido, error) {
+	query := `SELECT ` + columnasReferido + ` FROM referidos WHERE referente_id = ? ORDER BY id`
+
+	rows, err := s.db.QueryContext(ctx, query, referenteID)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando referidos del cliente %d: %w", referenteID, err)
+	}
+	defer rows.Close()
+
+	var referidos []*Referido
+	for rows.Next() {
+		referido, err := scanReferido(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error escaneando referido: %w", err)
+		}
+		referidos = append(referidos, referido)
+	}
+	return referidos, rows.Err()
+}
+
+func (s *SQLiteStore) ActualizarReferido(ctx context.Context, referido *Referido) error {
+	query := `UPDATE referidos SET estado = ?, pedido_id = ?, recompensado_en = ? WHERE id = ?`
+
+	if _, err := s.db.ExecContext(ctx, query, referido.Estado, referido.PedidoID, fechaOpcionalSQLite(referido.RecompensadoEn), referido.ID); err != nil {
+		return fmt.Errorf("error actualizando referido %d: %w", referido.ID, err)
+	}
+	return nil
+}
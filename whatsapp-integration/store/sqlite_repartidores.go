@@ -0,0 +1,145 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+func (s *SQLiteStore) GetRepartidorPorTelefono(ctx context.Context, telefono string) (*Repartidor, error) {
+	query := `
+		SELECT id, nombre, telefono, pin_hash, COALESCE(camion_id, 0), activo
+		FROM repartidores
+		WHERE telefono = ?`
+
+	return scanRepartidor(s.db.QueryRowContext(ctx, query, telefono))
+}
+
+func (s *SQLiteStore) GetRepartidor(ctx context.Context, id int) (*Repartidor, error) {
+	query := `
+		SELECT id, nombre, telefono, pin_hash, COALESCE(camion_id, 0), activo
+		FROM repartidores
+		WHERE id = ?`
+
+	return scanRepartidor(s.db.QueryRowContext(ctx, query, id))
+}
+
+func (s *SQLiteStore) GetRepartidores(ctx context.Context) ([]*Repartidor, error) {
+	query := `
+		SELECT id, nombre, telefono, pin_hash, COALESCE(camion_id, 0), activo
+		FROM repartidores
+		WHERE activo = 1
+		ORDER BY id`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando repartidores: %w", err)
+	}
+	defer rows.Close()
+
+	var repartidores []*Repartidor
+	for rows.Next() {
+		r := &Repartidor{}
+		if err := rows.Scan(&r.ID, &r.Nombre, &r.Telefono, &r.PinHash, &r.CamionID, &r.Activo); err != nil {
+			return nil, fmt.Errorf("error escaneando repartidor: %w", err)
+		}
+		repartidores = append(repartidores, r)
+	}
+	return repartidores, rows.Err()
+}
+
+// scanRepartidor es compartido por SQLite y MySQL porque las columnas son las mismas.
+func scanRepartidor(row *sql.Row) (*Repartidor, error) {
+	r := &Repartidor{}
+	err := row.Scan(&r.ID, &r.Nombre, &r.Telefono, &r.PinHash, &r.CamionID, &r.Activo)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error escaneando repartidor: %w", err)
+	}
+	return r, nil
+}
+
+func (s *SQLiteStore) CrearRepartidor(ctx context.Context, repartidor *Repartidor) error {
+	query := `
+		INSERT INTO repartidores (nombre, telefono, pin_hash, camion_id, activo)
+		VALUES (?, ?, ?, ?, ?)`
+
+	var camionID interface{}
+	if repartidor.CamionID > 0 {
+		camionID = repartidor.CamionID
+	}
+	result, err := s.db.ExecContext(ctx, query,
+		repartidor.Nombre,
+		repartidor.Telefono,
+		repartidor.PinHash,
+		camionID,
+		repartidor.Activo,
+	)
+	if err != nil {
+		return fmt.Errorf("error insertando repartidor: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("error obteniendo ID insertado: %w", err)
+	}
+	repartidor.ID = int(id)
+	return nil
+}
+
+func (s *SQLiteStore) CrearEntrega(ctx context.Context, entrega *Entrega) error {
+	query := `
+		INSERT INTO entregas (
+			parada_id, pedido_id, repartidor_id, litros_surtidos, efectivo_recibido, foto_path
+		) VALUES (?, ?, ?, ?, ?, ?)`
+
+	result, err := s.db.ExecContext(ctx, query,
+		entrega.ParadaID,
+		entrega.PedidoID,
+		entrega.RepartidorID,
+		entrega.LitrosSurtidos,
+		entrega.EfectivoRecibido,
+		entrega.FotoPath,
+	)
+	if err != nil {
+		return fmt.Errorf("error insertando entrega: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("error obteniendo ID insertado: %w", err)
+	}
+	entrega.ID = int(id)
+	return nil
+}
+
+const consultaEntregasRuta = `
+		SELECT e.id, e.parada_id, e.pedido_id, e.repartidor_id, e.litros_surtidos, e.efectivo_recibido, e.foto_path, e.created_at
+		FROM entregas e
+		JOIN ruta_paradas p ON p.id = e.parada_id
+		WHERE p.ruta_id = ?
+		ORDER BY e.id`
+
+// leerEntregas es compartido por SQLite y MySQL.
+func leerEntregas(rows *sql.Rows) ([]*Entrega, error) {
+	defer rows.Close()
+	var entregas []*Entrega
+	for rows.Next() {
+		e := &Entrega{}
+		if err := rows.Scan(&e.ID, &e.ParadaID, &e.PedidoID, &e.RepartidorID, &e.LitrosSurtidos, &e.EfectivoRecibido, &e.FotoPath, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error escaneando entrega: %w", err)
+		}
+		entregas = append(entregas, e)
+	}
+	return entregas, rows.Err()
+}
+
+func (s *SQLiteStore) GetEntregasRuta(ctx context.Context, rutaID int) ([]*Entrega, error) {
+	rows, err := s.db.QueryContext(ctx, consultaEntregasRuta, rutaID)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando entregas de la ruta %d: %w", rutaID, err)
+	}
+	return leerEntregas(rows)
+}
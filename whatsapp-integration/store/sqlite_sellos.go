@@ -0,0 +1,241 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+const columnasReporteSello = `id, cliente_id, pedido_id, tanque_id, COALESCE(tipo_reporte, 'sello_violado'), COALESCE(descripcion, ''),
+	COALESCE(estado, ''), COALESCE(resolucion, ''), COALESCE(supervisor, ''), fecha_reporte, vence_sla, asignado_en, resuelto_en, created_at`
+
+func scanReporteSello(row interface{ Scan(...interface{}) error }) (*ReporteSello, error) {
+	r := &ReporteSello{}
+	var pedidoID, tanqueID sql.NullInt64
+	var fechaReporte, venceSLA, asignadoEn, resueltoEn sql.NullTime
+	if err := row.Scan(&r.ID, &r.ClienteID, &pedidoID, &tanqueID, &r.TipoReporte, &r.Descripcion, &r.Estado,
+		&r.Resolucion, &r.Supervisor, &fechaReporte, &venceSLA, &asignadoEn, &resueltoEn, &r.CreatedAt); err != nil {
+		return nil, err
+	}
+	// Los reportes anteriores a fecha_reporte usan la fecha de creación.
+	r.FechaReporte = r.CreatedAt
+	if fechaReporte.Valid {
+		r.FechaReporte = fechaReporte.Time
+	}
+	if pedidoID.Valid {
+		id := int(pedidoID.Int64)
+		r.PedidoID = &id
+	}
+	if tanqueID.Valid {
+		id := int(tanqueID.Int64)
+		r.TanqueID = &id
+	}
+	r.VenceSLA = fechaNula(venceSLA)
+	r.AsignadoEn = fechaNula(asignadoEn)
+	r.ResueltoEn = fechaNula(resueltoEn)
+	return r, nil
+}
+
+func fechaNula(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+// leerReportesSello es compartido por SQLite y MySQL.
+func leerReportesSello(rows *sql.Rows) ([]*ReporteSello, error) {
+	defer rows.Close()
+	var reportes []*ReporteSello
+	for rows.Next() {
+		r, err := scanReporteSello(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error escaneando reporte de sello: %w", err)
+		}
+		reportes = append(reportes, r)
+	}
+	return reportes, rows.Err()
+}
+
+func (s *SQLiteStore) CrearReporteSello(ctx context.Context, reporte *ReporteSello) error {
+	if reporte.FechaReporte.IsZero() {
+		reporte.FechaReporte = time.Now()
+	}
+	if reporte.TipoReporte == "" {
+		reporte.TipoReporte = "sello_violado"
+	}
+
+	query := `
+		INSERT INTO reportes_sello (
+			cliente_id, pedido_id, tanque_id, tipo_reporte, descripcion, estado,
+			supervisor, fecha_reporte, vence_sla, asignado_en
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	result, err := s.db.ExecContext(ctx, query,
+		reporte.ClienteID,
+		reporte.PedidoID,
+		reporte.TanqueID,
+		reporte.TipoReporte,
+		reporte.Descripcion,
+		reporte.Estado,
+		reporte.Supervisor,
+		reporte.FechaReporte.UTC().Format("2006-01-02 15:04:05"),
+		fechaOpcionalSQLite(reporte.VenceSLA),
+		fechaOpcionalSQLite(reporte.AsignadoEn),
+	)
+	if err != nil {
+		return fmt.Errorf("error insertando reporte: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("error obteniendo ID insertado: %w", err)
+	}
+	reporte.ID = int(id)
+	return nil
+}
+
+func (s *SQLiteStore) GetReporteSello(ctx context.Context, id int) (*ReporteSello, error) {
+	query := `SELECT ` + columnasReporteSello + ` FROM reportes_sello WHERE id = ?`
+
+	r, err := scanReporteSello(s.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error consultando reporte de sello %d: %w", id, err)
+	}
+	return r, nil
+}
+
+func (s *SQLiteStore) GetUltimoReporteSello(ctx context.Context, clienteID int) (*ReporteSello, error) {
+	query := `SELECT ` + columnasReporteSello + ` FROM reportes_sello WHERE cliente_id = ? ORDER BY id DESC LIMIT 1`
+
+	r, err := scanReporteSello(s.db.QueryRowContext(ctx, query, clienteID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error consultando reporte de sello del cliente %d: %w", clienteID, err)
+	}
+	return r, nil
+}
+
+func (s *SQLiteStore) GetReportesSelloPorEstado(ctx context.Context, estado string) ([]*ReporteSello, error) {
+	query := `SELECT ` + columnasReporteSello + ` FROM reportes_sello WHERE ? = '' OR estado = ? ORDER BY id`
+
+	rows, err := s.db.QueryContext(ctx, query, estado, estado)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando reportes de sello: %w", err)
+	}
+	return leerReportesSello(rows)
+}
+
+func (s *SQLiteStore) GetReportesSello(ctx context.Context, desde, hasta time.Time) ([]*ReporteSello, error) {
+	query := `
+		SELECT ` + columnasReporteSello + `
+		FROM reportes_sello
+		WHERE COALESCE(fecha_reporte, created_at) >= ? AND COALESCE(fecha_reporte, created_at) < ?
+		ORDER BY id`
+
+	rows, err := s.db.QueryContext(ctx, query, desde.UTC().Format("2006-01-02 15:04:05"), hasta.UTC().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return nil, fmt.Errorf("error consultando reportes de sello: %w", err)
+	}
+	return leerReportesSello(rows)
+}
+
+func (s *SQLiteStore) ActualizarReporteSello(ctx context.Context, r *ReporteSello) error {
+	query := `
+		UPDATE reportes_sello SET
+			pedido_id = ?, tanque_id = ?, tipo_reporte = ?, descripcion = ?, estado = ?, resolucion = ?,
+			supervisor = ?, vence_sla = ?, asignado_en = ?, resuelto_en = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?`
+
+	result, err := s.db.ExecContext(ctx, query, r.PedidoID, r.TanqueID, r.TipoReporte, r.Descripcion, r.Estado, r.Resolucion,
+		r.Supervisor, fechaOpcionalSQLite(r.VenceSLA), fechaOpcionalSQLite(r.AsignadoEn), fechaOpcionalSQLite(r.ResueltoEn), r.ID)
+	if err != nil {
+		return fmt.Errorf("error actualizando reporte de sello %d: %w", r.ID, err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("reporte de sello no encontrado: %d", r.ID)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) AgregarFotoReporteSello(ctx context.Context, foto *FotoReporteSello) error {
+	result, err := s.db.ExecContext(ctx,
+		`INSERT INTO fotos_reporte_sello (reporte_id, archivo, mime_type) VALUES (?, ?, ?)`,
+		foto.ReporteID, foto.Archivo, foto.MimeType)
+	if err != nil {
+		return fmt.Errorf("error guardando foto del reporte %d: %w", foto.ReporteID, err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("error obteniendo ID insertado: %w", err)
+	}
+	foto.ID = int(id)
+	return nil
+}
+
+func (s *SQLiteStore) GetFotoReporteSello(ctx context.Context, id int) (*FotoReporteSello, error) {
+	f := &FotoReporteSello{}
+	err := s.db.QueryRowContext(ctx,
+		`SELECT id, reporte_id, archivo, mime_type, created_at FROM fotos_reporte_sello WHERE id = ?`, id,
+	).Scan(&f.ID, &f.ReporteID, &f.Archivo, &f.MimeType, &f.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error consultando foto %d: %w", id, err)
+	}
+	return f, nil
+}
+
+func (s *SQLiteStore) GetFotosReporteSello(ctx context.Context, reporteID int) ([]*FotoReporteSello, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, reporte_id, archivo, mime_type, created_at FROM fotos_reporte_sello WHERE reporte_id = ? ORDER BY id`, reporteID)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando fotos del reporte %d: %w", reporteID, err)
+	}
+	return leerFotosReporteSello(rows)
+}
+
+// leerFotosReporteSello es compartido por SQLite y MySQL.
+func leerFotosReporteSello(rows *sql.Rows) ([]*FotoReporteSello, error) {
+	defer rows.Close()
+	var fotos []*FotoReporteSello
+	for rows.Next() {
+		f := &FotoReporteSello{}
+		if err := rows.Scan(&f.ID, &f.ReporteID, &f.Archivo, &f.MimeType, &f.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error escaneando foto de reporte: %w", err)
+		}
+		fotos = append(fotos, f)
+	}
+	return fotos, rows.Err()
+}
+
+func (s *SQLiteStore) GetTanquesPedido(ctx context.Context, pedidoID int) ([]*Tanque, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, pedido_id, codigo_qr, COALESCE(capacidad, 0), tipo, estado FROM tanques WHERE pedido_id = ? ORDER BY id`, pedidoID)
+	if err != nil {
+		return nil, fmt.Errorf("error consultando tanques del pedido %d: %w", pedidoID, err)
+	}
+	return leerTanques(rows)
+}
+
+// leerTanques es compartido por SQLite y MySQL.
+func leerTanques(rows *sql.Rows) ([]*Tanque, error) {
+	defer rows.Close()
+	var tanques []*Tanque
+	for rows.Next() {
+		t := &Tanque{}
+		if err := rows.Scan(&t.ID, &t.PedidoID, &t.CodigoQR, &t.Capacidad, &t.Tipo, &t.Estado); err != nil {
+			return nil, fmt.Errorf("error escaneando tanque: %w", err)
+		}
+		tanques = append(tanques, t)
+	}
+	return tanques, rows.Err()
+}
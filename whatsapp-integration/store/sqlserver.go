@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"time"
+
 	_ "github.com/denisenkom/go-mssqldb"
 )
 
@@ -30,6 +32,14 @@ func (s *SQLServerStore) GetClientePorTelefono(ctx context.Context, telefono str
 	return nil, fmt.Errorf("no implementado")
 }
 
+func (s *SQLServerStore) GetClientePorID(ctx context.Context, id int) (*Cliente, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) GetClientes(ctx context.Context) ([]*Cliente, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
 func (s *SQLServerStore) CrearCliente(ctx context.Context, cliente *Cliente) error {
 	return fmt.Errorf("no implementado")
 }
@@ -56,6 +66,14 @@ func (s *SQLServerStore) GetPedidosPorEstado(ctx context.Context, estado string)
 	return nil, fmt.Errorf("no implementado")
 }
 
+func (s *SQLServerStore) GetPedido(ctx context.Context, id int) (*Pedido, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) GetPedidos(ctx context.Context, filtro FiltroPedidos) ([]*Pedido, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
 func (s *SQLServerStore) CrearPedido(ctx context.Context, pedido *Pedido) error {
 	return fmt.Errorf("no implementado")
 }
@@ -69,3 +87,447 @@ func (s *SQLServerStore) ActualizarPedido(ctx context.Context, pedido *Pedido) e
 func (s *SQLServerStore) CrearReporteSello(ctx context.Context, reporte *ReporteSello) error {
 	return fmt.Errorf("no implementado")
 }
+
+func (s *SQLServerStore) GetReporteSello(ctx context.Context, id int) (*ReporteSello, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) GetUltimoReporteSello(ctx context.Context, clienteID int) (*ReporteSello, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) GetReportesSelloPorEstado(ctx context.Context, estado string) ([]*ReporteSello, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) ActualizarReporteSello(ctx context.Context, reporte *ReporteSello) error {
+	return fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) AgregarFotoReporteSello(ctx context.Context, foto *FotoReporteSello) error {
+	return fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) GetFotoReporteSello(ctx context.Context, id int) (*FotoReporteSello, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) GetFotosReporteSello(ctx context.Context, reporteID int) ([]*FotoReporteSello, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) GetTanquesPedido(ctx context.Context, pedidoID int) ([]*Tanque, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
+// --- Métodos de PedidoProgramado (pendientes de implementación) ---
+
+func (s *SQLServerStore) CrearPedidoProgramado(ctx context.Context, programado *PedidoProgramado) error {
+	return fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) ActualizarPedidoProgramado(ctx context.Context, programado *PedidoProgramado) error {
+	return fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) GetPedidosProgramadosPorCliente(ctx context.Context, clienteID int) ([]*PedidoProgramado, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) GetPedidosProgramadosHasta(ctx context.Context, fecha time.Time) ([]*PedidoProgramado, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
+// --- Métodos de ventanas de entrega (pendientes de implementación) ---
+
+func (s *SQLServerStore) GetVentanasEntrega(ctx context.Context) ([]*VentanaEntrega, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) CrearVentanaEntrega(ctx context.Context, ventana *VentanaEntrega) error {
+	return fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) GetOcupacionSlot(ctx context.Context, ventanaID int, fecha time.Time) (int, float64, error) {
+	return 0, 0, fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) ReservarSlot(ctx context.Context, reserva *ReservaSlot, ventana *VentanaEntrega) error {
+	return fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) CrearPedidoConSlot(ctx context.Context, pedido *Pedido, reserva *ReservaSlot, ventana *VentanaEntrega) error {
+	return fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) LiberarSlot(ctx context.Context, pedidoID int) error {
+	return fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) GetReservaSlot(ctx context.Context, pedidoID int) (*ReservaSlot, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
+// --- Métodos de camiones y rutas (pendientes de implementación) ---
+
+func (s *SQLServerStore) GetCamiones(ctx context.Context) ([]*Camion, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) CrearCamion(ctx context.Context, camion *Camion) error {
+	return fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) CrearRuta(ctx context.Context, ruta *Ruta, paradas []*RutaParada, estadoPedidos string) error {
+	return fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) GetRutasPorFecha(ctx context.Context, fecha time.Time) ([]*Ruta, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) GetParadasRuta(ctx context.Context, rutaID int) ([]*RutaParada, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
+// --- Métodos de notificaciones de pedidos (pendientes de implementación) ---
+
+func (s *SQLServerStore) NotificacionEnviada(ctx context.Context, pedidoID int, tipo string) (bool, error) {
+	return false, fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) RegistrarNotificacion(ctx context.Context, notificacion *NotificacionPedido) error {
+	return fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) BorrarNotificaciones(ctx context.Context, pedidoID int, tipos []string) error {
+	return fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) GetRuta(ctx context.Context, id int) (*Ruta, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) GetRutaCamion(ctx context.Context, camionID int, fecha time.Time) (*Ruta, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) GetParada(ctx context.Context, id int) (*RutaParada, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) GetParadaPorPedido(ctx context.Context, pedidoID int) (*RutaParada, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) ActualizarEstadoParada(ctx context.Context, paradaID int, estado string) error {
+	return fmt.Errorf("no implementado")
+}
+
+// --- Métodos de repartidores y entregas (pendientes de implementación) ---
+
+func (s *SQLServerStore) GetRepartidorPorTelefono(ctx context.Context, telefono string) (*Repartidor, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) GetRepartidor(ctx context.Context, id int) (*Repartidor, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) GetRepartidores(ctx context.Context) ([]*Repartidor, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) CrearRepartidor(ctx context.Context, repartidor *Repartidor) error {
+	return fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) CrearEntrega(ctx context.Context, entrega *Entrega) error {
+	return fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) ActualizarETAParada(ctx context.Context, paradaID int, eta time.Time) error {
+	return fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) RegistrarPosicion(ctx context.Context, posicion *PosicionRuta) error {
+	return fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) GetUltimaPosicion(ctx context.Context, rutaID int) (*PosicionRuta, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
+// --- Métodos de esperas en el domicilio (pendientes de implementación) ---
+
+func (s *SQLServerStore) CrearEspera(ctx context.Context, espera *EsperaEntrega) error {
+	return fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) GetEsperaActiva(ctx context.Context, pedidoID int) (*EsperaEntrega, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) GetEsperasActivas(ctx context.Context) ([]*EsperaEntrega, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) ActualizarEspera(ctx context.Context, espera *EsperaEntrega) error {
+	return fmt.Errorf("no implementado")
+}
+
+// --- Métodos de strikes, apelaciones y auditoría (pendientes de implementación) ---
+
+func (s *SQLServerStore) CrearStrike(ctx context.Context, strike *Strike) error {
+	return fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) GetStrikesCliente(ctx context.Context, clienteID int) ([]*Strike, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) AnularStrike(ctx context.Context, strikeID int) error {
+	return fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) CrearApelacion(ctx context.Context, apelacion *Apelacion) error {
+	return fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) GetApelacion(ctx context.Context, id int) (*Apelacion, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) GetApelaciones(ctx context.Context, estado string) ([]*Apelacion, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) ActualizarApelacion(ctx context.Context, apelacion *Apelacion) error {
+	return fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) RegistrarAuditoria(ctx context.Context, registro *AuditoriaCliente) error {
+	return fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) GetAuditoriaCliente(ctx context.Context, clienteID int) ([]*AuditoriaCliente, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
+// --- Métodos de puntos (pendientes de implementación) ---
+
+func (s *SQLServerStore) RegistrarMovimientoPuntos(ctx context.Context, mov *MovimientoPuntos) error {
+	return fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) GetSaldoPuntos(ctx context.Context, clienteID int) (int, error) {
+	return 0, fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) GetMovimientosPuntos(ctx context.Context, clienteID int, limite int) ([]*MovimientoPuntos, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) GetMovimientosPuntosPedido(ctx context.Context, pedidoID int) ([]*MovimientoPuntos, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
+// --- Métodos de promociones (pendientes de implementación) ---
+
+func (s *SQLServerStore) CrearPromocion(ctx context.Context, promo *Promocion) error {
+	return fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) ActualizarPromocion(ctx context.Context, promo *Promocion) error {
+	return fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) GetPromocionPorCodigo(ctx context.Context, codigo string) (*Promocion, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) GetPromociones(ctx context.Context) ([]*Promocion, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) RegistrarUsoPromocion(ctx context.Context, uso *UsoPromocion) error {
+	return fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) ContarUsosPromocion(ctx context.Context, promocionID, clienteID int) (int, error) {
+	return 0, fmt.Errorf("no implementado")
+}
+
+// --- Métodos de referidos (pendientes de implementación) ---
+
+func (s *SQLServerStore) GetCodigoReferido(ctx context.Context, clienteID int) (string, error) {
+	return "", fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) CrearCodigoReferido(ctx context.Context, clienteID int, codigo string) error {
+	return fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) GetClienteIDPorCodigoReferido(ctx context.Context, codigo string) (int, error) {
+	return 0, fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) CrearReferido(ctx context.Context, referido *Referido) error {
+	return fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) GetReferidoPorCliente(ctx context.Context, referidoID int) (*Referido, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) GetReferidosDe(ctx context.Context, referenteID int) ([]*Referido, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) ActualizarReferido(ctx context.Context, referido *Referido) error {
+	return fmt.Errorf("no implementado")
+}
+
+// --- Métodos de pagos (pendientes de implementación) ---
+
+func (s *SQLServerStore) CrearPago(ctx context.Context, pago *Pago) error {
+	return fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) ActualizarPago(ctx context.Context, pago *Pago) error {
+	return fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) GetPagoPorPedido(ctx context.Context, pedidoID int) (*Pago, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) GetPagosPorEstado(ctx context.Context, metodo, estado string) ([]*Pago, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) GetPagoPorReferencia(ctx context.Context, metodo, referencia string) (*Pago, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) CrearComprobantePago(ctx context.Context, comprobante *ComprobantePago) error {
+	return fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) ActualizarComprobantePago(ctx context.Context, comprobante *ComprobantePago) error {
+	return fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) GetComprobantePago(ctx context.Context, id int) (*ComprobantePago, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) GetComprobantesPago(ctx context.Context, estado string) ([]*ComprobantePago, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
+// --- Métodos de facturación (pendientes de implementación) ---
+
+func (s *SQLServerStore) GetDatosFiscales(ctx context.Context, clienteID int) (*DatosFiscales, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) GuardarDatosFiscales(ctx context.Context, datos *DatosFiscales) error {
+	return fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) CrearFactura(ctx context.Context, factura *Factura) error {
+	return fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) GetFacturaPorPedido(ctx context.Context, pedidoID int) (*Factura, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) GetFacturaPorToken(ctx context.Context, token string) (*Factura, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
+// --- Métodos de liquidaciones (pendientes de implementación) ---
+
+func (s *SQLServerStore) GetEntregasRuta(ctx context.Context, rutaID int) ([]*Entrega, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) CrearLiquidacion(ctx context.Context, liquidacion *Liquidacion) error {
+	return fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) GetLiquidacionRuta(ctx context.Context, rutaID int) (*Liquidacion, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) GetReportesSello(ctx context.Context, desde, hasta time.Time) ([]*ReporteSello, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
+// --- Métodos de alertas (pendientes de implementación) ---
+
+func (s *SQLServerStore) CrearAlerta(ctx context.Context, alerta *Alerta) error {
+	return fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) GetAlerta(ctx context.Context, id int) (*Alerta, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) GetAlertasPorEstado(ctx context.Context, estado string) ([]*Alerta, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) ActualizarAlerta(ctx context.Context, alerta *Alerta) error {
+	return fmt.Errorf("no implementado")
+}
+
+// --- Métodos de calificaciones (pendientes de implementación) ---
+
+func (s *SQLServerStore) CrearCalificacion(ctx context.Context, calificacion *Calificacion) error {
+	return fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) GetCalificacionPedido(ctx context.Context, pedidoID int) (*Calificacion, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) GetCalificaciones(ctx context.Context, desde, hasta time.Time) ([]*Calificacion, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) ActualizarCalificacion(ctx context.Context, calificacion *Calificacion) error {
+	return fmt.Errorf("no implementado")
+}
+
+// --- Métodos de confirmaciones de entrega (pendientes de implementación) ---
+
+func (s *SQLServerStore) CrearConfirmacionEntrega(ctx context.Context, confirmacion *ConfirmacionEntrega) error {
+	return fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) GetConfirmacionEntrega(ctx context.Context, id int) (*ConfirmacionEntrega, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) GetConfirmacionEntregaPendiente(ctx context.Context, clienteID int) (*ConfirmacionEntrega, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) GetConfirmacionEntregaConfirmada(ctx context.Context, clienteID int) (*ConfirmacionEntrega, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) GetConfirmacionesEntregaVencidas(ctx context.Context, ahora time.Time) ([]*ConfirmacionEntrega, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) GetConfirmacionesEntregaPorEnviar(ctx context.Context) ([]*ConfirmacionEntrega, error) {
+	return nil, fmt.Errorf("no implementado")
+}
+
+func (s *SQLServerStore) ActualizarConfirmacionEntrega(ctx context.Context, confirmacion *ConfirmacionEntrega) error {
+	return fmt.Errorf("no implementado")
+}
@@ -2,6 +2,8 @@ package store
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 )
@@ -26,45 +28,474 @@ type Cliente struct {
 
 // Pedido representa un pedido en la base de datos
 type Pedido struct {
-	ID                int
-	ClienteID         int
-	TipoServicio      string // "estacionario" o "cilindro"
-	HorarioPreferido  string // "Mañana", "Tarde"
-	Latitud           float64
-	Longitud          float64
-	MapaURL           string
-	StreetViewURL     string
+	ID                     int
+	ClienteID              int
+	TipoServicio           string // "estacionario" o "cilindro"
+	HorarioPreferido       string // "Mañana", "Tarde"
+	Latitud                float64
+	Longitud               float64
+	MapaURL                string
+	StreetViewURL          string
 	RequiereRevisionManual bool
-	CantidadLitros    float64
-	CantidadDinero    float64
-	PrecioUnitario    float64
-	MetodoPago        string
-	Direccion         string
-	ColorFachada      string
-	ColorPuerta       string
-	CodigoRojo        bool
-	CantidadCilindros int
-	CodigosQR         string // comma-separated codes; puede evolucionar a JSON
-	Estado            string
-	CreatedAt         time.Time
-	UpdatedAt         time.Time
+	CantidadLitros         float64
+	CantidadDinero         float64
+	PrecioUnitario         float64
+	MetodoPago             string
+	Direccion              string
+	ColorFachada           string
+	ColorPuerta            string
+	CodigoRojo             bool
+	CantidadCilindros      int
+	CodigosQR              string  // comma-separated codes; puede evolucionar a JSON
+	Descuento              float64 // descuentos aplicados; se restan de CantidadDinero al cobrar
+	Estado                 string
+	CreatedAt              time.Time
+	UpdatedAt              time.Time
 }
 
-// ReporteSello representa un reporte de sello violado
+// TotalAPagar es lo que se cobra al cliente: el importe menos los descuentos.
+func (p *Pedido) TotalAPagar() float64 {
+	total := p.CantidadDinero - p.Descuento
+	if total < 0 {
+		return 0
+	}
+	return total
+}
+
+// ReporteSello representa un reporte de sello violado. Es un caso que un
+// supervisor revisa y resuelve dentro del SLA.
 type ReporteSello struct {
 	ID           int
 	ClienteID    int
-	PedidoID     *int // opcional
+	PedidoID     *int   // opcional
+	TanqueID     *int   // opcional: tanque o cilindro del pedido
+	TipoReporte  string // "sello_violado", "tanque_danado", "otro"
 	Descripcion  string
-	Estado       string
+	Estado       string // "pendiente", "en_revision", "resuelto", "cancelado"
+	Resolucion   string
+	Supervisor   string
 	FechaReporte time.Time
+	VenceSLA     *time.Time
+	AsignadoEn   *time.Time
+	ResueltoEn   *time.Time
+	CreatedAt    time.Time
+}
+
+// Alerta es un aviso urgente sobre un pedido para el repartidor asignado y
+// el punto de venta. Queda abierta hasta que alguien la reconoce.
+type Alerta struct {
+	ID                int
+	Tipo              string // "sello_violado", "pedido_cancelado"
+	PedidoID          int
+	RepartidorID      int // 0 si el pedido no está en una ruta
+	ReferenciaID      int // reporte de sello, si aplica
+	Mensaje           string
+	Estado            string // "pendiente", "escalada", "reconocida"
+	EnviadaRepartidor *time.Time
+	EntregadaPOS      *time.Time // el webhook del punto de venta respondió 2xx
+	EscaladaEn        *time.Time
+	ReconocidaPor     string
+	ReconocidaEn      *time.Time
+	CreatedAt         time.Time
+}
+
+// Calificacion es la opinión del cliente sobre la entrega de un pedido.
+type Calificacion struct {
+	ID           int
+	PedidoID     int
+	ClienteID    int
+	RepartidorID int    // 0 si no se sabe quién entregó
+	Puntuacion   int    // estrellas, de 1 a 5
+	Comentario   string // opcional
+	EscaladaA    string // teléfono del supervisor que atiende una calificación baja
+	EscaladaEn   *time.Time
+	CreatedAt    time.Time
+}
+
+// ConfirmacionEntrega es la pregunta al cliente de si recibió el pedido que
+// el repartidor marcó como entregado. Si no responde antes de Vence, se da
+// por confirmada.
+type ConfirmacionEntrega struct {
+	ID           int
+	PedidoID     int
+	ClienteID    int
+	Estado       string    // "por_enviar", "pendiente", "confirmada", "autoconfirmada", "rechazada"
+	Vence        time.Time // solo cuenta desde que se envió la pregunta
+	RespondidaEn *time.Time
+	ReporteID    int // caso abierto cuando el cliente dice que no lo recibió
+	CreatedAt    time.Time
+}
+
+// FotoReporteSello es una foto que el cliente adjuntó a su reporte.
+type FotoReporteSello struct {
+	ID        int
+	ReporteID int
+	Archivo   string // ruta del archivo guardado
+	MimeType  string
+	CreatedAt time.Time
+}
+
+// Tanque es un tanque estacionario o cilindro identificado por su código QR.
+type Tanque struct {
+	ID        int
+	PedidoID  int
+	CodigoQR  string
+	Capacidad float64
+	Tipo      string
+	Estado    string
+}
+
+// PedidoProgramado representa una entrega futura o recurrente que se convierte
+// en Pedido antes del corte diario
+type PedidoProgramado struct {
+	ID                  int
+	ClienteID           int
+	TipoServicio        string
+	CantidadLitros      float64
+	CantidadCilindros   int
+	MetodoPago          string
+	Direccion           string
+	Latitud             float64
+	Longitud            float64
+	MapaURL             string
+	StreetViewURL       string
+	ColorFachada        string
+	ColorPuerta         string
+	HorarioPreferido    string    // "Mañana", "Tarde"
+	Frecuencia          string    // "unica", "semanal" o "mensual"
+	IntervaloSemanas    int       // para frecuencia semanal
+	DiaMes              int       // para frecuencia mensual
+	ProximaFecha        time.Time // solo se usa la fecha
+	OmitirProxima       bool
+	RecordatorioEnviado bool
+	Activo              bool
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+}
+
+// VentanaEntrega define un horario de entrega para un día de la semana y zona,
+// con la capacidad de cada camión que lo atiende
+type VentanaEntrega struct {
+	ID               int
+	Zona             string // vacío aplica a todas las zonas
+	DiaSemana        int    // 0 = domingo ... 6 = sábado
+	HoraInicio       string // "09:00"
+	HoraFin          string // "13:00"
+	Camiones         int
+	MaxPedidosCamion int     // 0 = sin límite
+	MaxLitrosCamion  float64 // 0 = sin límite
+	Activo           bool
+}
+
+// CapacidadPedidos regresa el máximo de pedidos de la ventana; 0 es sin límite.
+func (v *VentanaEntrega) CapacidadPedidos() int {
+	return v.Camiones * v.MaxPedidosCamion
+}
+
+// CapacidadLitros regresa el máximo de litros de la ventana; 0 es sin límite.
+func (v *VentanaEntrega) CapacidadLitros() float64 {
+	return float64(v.Camiones) * v.MaxLitrosCamion
+}
+
+// Admite indica si con la ocupación dada cabe un pedido más de los litros indicados.
+func (v *VentanaEntrega) Admite(pedidos int, litros, litrosNuevos float64) bool {
+	if max := v.CapacidadPedidos(); max > 0 && pedidos+1 > max {
+		return false
+	}
+	if max := v.CapacidadLitros(); max > 0 && litros+litrosNuevos > max {
+		return false
+	}
+	return true
+}
+
+// ReservaSlot representa el lugar que ocupa un pedido en una ventana y fecha
+type ReservaSlot struct {
+	ID        int
+	VentanaID int
+	Fecha     time.Time // solo se usa la fecha
+	PedidoID  int
+	Litros    float64
+	Estado    string // "reservada" o "liberada"
+	CreatedAt time.Time
+}
+
+// ErrSlotSinCapacidad indica que la ventana ya no admite más pedidos o litros
+var ErrSlotSinCapacidad = errors.New("la ventana de entrega ya no tiene capacidad")
+
+// Camion representa una unidad de reparto y su capacidad
+type Camion struct {
+	ID                 int
+	Nombre             string
+	Placas             string
+	CapacidadLitros    float64 // 0 = sin límite
+	CapacidadCilindros int     // 0 = sin límite
+	MaxPedidos         int     // 0 = sin límite
+	Activo             bool
+}
+
+// Ruta representa el recorrido de un camión en un día, generado en el corte diario
+type Ruta struct {
+	ID             int
+	Fecha          time.Time // solo se usa la fecha
+	CamionID       int
+	Estado         string // "planificada", "en_curso", "finalizada"
+	TotalLitros    float64
+	TotalCilindros int
+	CreatedAt      time.Time
+}
+
+// RutaParada es un pedido dentro de una ruta, en el orden de visita
+type RutaParada struct {
+	ID           int
+	RutaID       int
+	PedidoID     int
+	Orden        int
+	Estado       string     // "pending", "arriving", "delivered", "cancelled"
+	HoraEstimada *time.Time // ETA recalculada con la posición del repartidor
+}
+
+// PosicionRuta es una lectura GPS del repartidor durante su ruta.
+type PosicionRuta struct {
+	ID           int
+	RutaID       int
+	RepartidorID int
+	Latitud      float64
+	Longitud     float64
+	Precision    float64 // en metros
+	CreatedAt    time.Time
+}
+
+// Repartidor es el chofer que ejecuta las rutas de un camión.
+type Repartidor struct {
+	ID       int
+	Nombre   string
+	Telefono string
+	PinHash  string
+	CamionID int
+	Activo   bool
+}
+
+// Entrega es la evidencia que captura el repartidor al entregar un pedido.
+type Entrega struct {
+	ID               int
+	ParadaID         int
+	PedidoID         int
+	RepartidorID     int
+	LitrosSurtidos   float64
+	EfectivoRecibido float64
+	FotoPath         string
+	CreatedAt        time.Time
+}
+
+// EsperaEntrega es el tiempo que el repartidor espera al cliente desde que
+// marca su llegada. Estado: "activa", "atendida", "no_atendido" o "cancelada".
+type EsperaEntrega struct {
+	ID           int
+	PedidoID     int
+	ParadaID     int // 0 si la llegada no vino de una ruta
+	Inicio       time.Time
+	Limite       time.Time
+	AvisoEnviado bool
+	Estado       string
+	CerradaPor   string // "sistema", "repartidor" u operador que intervino
+	UpdatedAt    time.Time
+}
+
+// Strike es una falta del cliente (por ejemplo no atender al repartidor).
+// Deja de contar al vencer o si un administrador la anula.
+type Strike struct {
+	ID        int
+	ClienteID int
+	PedidoID  *int // opcional
+	Motivo    string
+	CreatedAt time.Time
+	ExpiraEn  time.Time
+	Anulado   bool
+}
+
+// Vigente indica si el strike todavía cuenta para el bloqueo.
+func (s *Strike) Vigente(ahora time.Time) bool {
+	return !s.Anulado && ahora.Before(s.ExpiraEn)
+}
+
+// Apelacion es el caso que abre un cliente para disputar un strike.
+// Estado: "abierta", "aceptada" o "rechazada".
+type Apelacion struct {
+	ID          int
+	ClienteID   int
+	StrikeID    *int // opcional
+	Mensaje     string
+	Estado      string
+	Resolucion  string
+	ResueltaPor string
+	CreatedAt   time.Time
+	ResueltaEn  *time.Time
+}
+
+// AuditoriaCliente registra las acciones del personal o del sistema sobre un
+// cliente: strikes, bloqueos, desbloqueos y apelaciones resueltas.
+type AuditoriaCliente struct {
+	ID        int
+	ClienteID int
+	Accion    string
+	Operador  string
+	Detalle   string
+	CreatedAt time.Time
+}
+
+// MovimientoPuntos es un renglón del monedero de puntos del cliente. Puntos es
+// positivo al acumular o reponer y negativo al canjear; el saldo es la suma.
+type MovimientoPuntos struct {
+	ID          int
+	ClienteID   int
+	PedidoID    *int
+	Tipo        string // "acumulacion", "canje", "reverso", "ajuste", "referido", "retencion"
+	Puntos      int
+	Descripcion string
+	CreatedAt   time.Time
+}
+
+// Promocion es un código de descuento. Los límites en cero no se aplican.
+type Promocion struct {
+	ID               int
+	Codigo           string
+	Descripcion      string
+	Tipo             string  // "porcentaje", "monto_fijo", "por_litro"
+	Valor            float64 // porcentaje, pesos o pesos por litro según el tipo
+	SoloPrimerPedido bool
+	VigenteDesde     *time.Time
+	VigenteHasta     *time.Time
+	MaxUsosCliente   int
+	Zonas            string // zonas de reparto separadas por coma; vacío = todas
+	Activa           bool
+	CreatedAt        time.Time
+}
+
+// UsoPromocion liga un código con el pedido en el que se aplicó.
+type UsoPromocion struct {
+	ID          int
+	PromocionID int
+	ClienteID   int
+	PedidoID    int
+	Descuento   float64
+	CreatedAt   time.Time
+}
+
+// Referido registra que un cliente llegó con el código de otro. Estado pasa de
+// "pendiente" a "recompensado" con su primer pedido entregado.
+type Referido struct {
+	ID             int
+	ReferenteID    int
+	ReferidoID     int
+	Codigo         string
+	Estado         string
+	PedidoID       *int
+	CreatedAt      time.Time
+	RecompensadoEn *time.Time
+}
+
+// Pago es el cobro de un pedido con el método que eligió el cliente.
+type Pago struct {
+	ID         int
+	PedidoID   int
+	Metodo     string // "efectivo", "tarjeta", "transferencia", "liga_pago"
+	Estado     string // "pendiente", "pagado", "reembolsado", "vencido"
+	Monto      float64
+	Referencia string     // referencia SPEI o folio del proveedor
+	URL        string     // liga de pago en línea
+	VenceEn    *time.Time // límite para pagar en línea; vencido se cancela el pedido
+	PagadoEn   *time.Time
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// ComprobantePago es la foto o PDF de una transferencia que mandó el cliente.
+// Queda pendiente hasta que el personal la aprueba o la rechaza.
+type ComprobantePago struct {
+	ID          int
+	PedidoID    int
+	ClienteID   int
+	Archivo     string // ruta del archivo guardado
+	MimeType    string
+	Estado      string // "pendiente", "aprobado", "rechazado"
+	RevisadoPor string
+	Nota        string
+	CreatedAt   time.Time
+	RevisadoEn  *time.Time
+}
+
+// DatosFiscales son los datos del receptor para facturar a un cliente.
+type DatosFiscales struct {
+	ClienteID     int
+	RFC           string
+	RazonSocial   string
+	RegimenFiscal string // clave del catálogo c_RegimenFiscal, por ejemplo "612"
+	CodigoPostal  string // domicilio fiscal
+	UsoCFDI       string // clave del catálogo c_UsoCFDI, por ejemplo "G03"
+	UpdatedAt     time.Time
+}
+
+// Liquidacion es el corte de caja de una ruta: el efectivo que el cajero
+// recibió del repartidor contra el que debía entregar.
+type Liquidacion struct {
+	ID           int
+	RutaID       int
+	RepartidorID int // 0 si la ruta no tuvo repartidor identificado
+	Fecha        time.Time
+	Esperado     float64 // efectivo de los pedidos entregados
+	Declarado    float64 // efectivo que capturó el repartidor en cada entrega
+	Recibido     float64 // efectivo que contó el cajero
+	Diferencia   float64 // Recibido - Esperado
+	Estado       string  // "cuadrada", "faltante", "sobrante"
+	Cajero       string
+	Nota         string
 	CreatedAt    time.Time
 }
 
+// Factura es el CFDI timbrado de un pedido. Token da acceso a los archivos
+// sin exponer el ID.
+type Factura struct {
+	ID         int
+	PedidoID   int
+	ClienteID  int
+	RFC        string
+	Serie      string
+	Folio      string
+	UUID       string
+	Total      float64
+	ArchivoXML string
+	ArchivoPDF string
+	Token      string
+	CreatedAt  time.Time
+}
+
+// NotificacionPedido registra un aviso enviado al cliente sobre su pedido.
+// Tipo identifica el aviso (por ejemplo el estado de entrega) y sirve para no
+// repetirlo.
+type NotificacionPedido struct {
+	ID        int
+	PedidoID  int
+	Tipo      string
+	Telefono  string
+	Mensaje   string
+	CreatedAt time.Time
+}
+
+// FiltroPedidos restringe la consulta de pedidos. Los campos en cero no filtran.
+type FiltroPedidos struct {
+	Estados            []string
+	ClienteID          int
+	ActualizadoAntesDe time.Time
+	CreadoDesde        time.Time
+	Limite             int
+}
+
 // Store define la interfaz para acceder a la base de datos
 type Store interface {
 	// Métodos para Cliente
 	GetClientePorTelefono(ctx context.Context, telefono string) (*Cliente, error)
+	GetClientePorID(ctx context.Context, id int) (*Cliente, error)
+	GetClientes(ctx context.Context) ([]*Cliente, error)
 	CrearCliente(ctx context.Context, cliente *Cliente) error
 	ActualizarCliente(ctx context.Context, cliente *Cliente) error
 	ActualizarEstadoCliente(ctx context.Context, telefono, estado string) error
@@ -73,11 +504,172 @@ type Store interface {
 	GetUltimoPedido(ctx context.Context, clienteID int) (*Pedido, error)
 	GetUltimoPedidoActivo(ctx context.Context, clienteID int) (*Pedido, error)
 	GetPedidosPorEstado(ctx context.Context, estado string) ([]*Pedido, error)
+	GetPedido(ctx context.Context, id int) (*Pedido, error)
+	GetPedidos(ctx context.Context, filtro FiltroPedidos) ([]*Pedido, error)
 	CrearPedido(ctx context.Context, pedido *Pedido) error
 	ActualizarPedido(ctx context.Context, pedido *Pedido) error
 
+	// Métodos para PedidoProgramado
+	CrearPedidoProgramado(ctx context.Context, programado *PedidoProgramado) error
+	ActualizarPedidoProgramado(ctx context.Context, programado *PedidoProgramado) error
+	GetPedidosProgramadosPorCliente(ctx context.Context, clienteID int) ([]*PedidoProgramado, error)
+	GetPedidosProgramadosHasta(ctx context.Context, fecha time.Time) ([]*PedidoProgramado, error)
+
+	// Métodos para ventanas de entrega
+	GetVentanasEntrega(ctx context.Context) ([]*VentanaEntrega, error)
+	CrearVentanaEntrega(ctx context.Context, ventana *VentanaEntrega) error
+	GetOcupacionSlot(ctx context.Context, ventanaID int, fecha time.Time) (pedidos int, litros float64, err error)
+	// ReservarSlot inserta la reserva solo si la ventana conserva capacidad;
+	// si no, regresa ErrSlotSinCapacidad. La verificación y el insert son atómicos.
+	ReservarSlot(ctx context.Context, reserva *ReservaSlot, ventana *VentanaEntrega) error
+	// CrearPedidoConSlot guarda el pedido y su reserva en una sola transacción;
+	// si la ventana ya no tiene capacidad no se guarda ninguno de los dos.
+	CrearPedidoConSlot(ctx context.Context, pedido *Pedido, reserva *ReservaSlot, ventana *VentanaEntrega) error
+	LiberarSlot(ctx context.Context, pedidoID int) error
+	GetReservaSlot(ctx context.Context, pedidoID int) (*ReservaSlot, error)
+
+	// Métodos para notificaciones de pedidos
+	NotificacionEnviada(ctx context.Context, pedidoID int, tipo string) (bool, error)
+	RegistrarNotificacion(ctx context.Context, notificacion *NotificacionPedido) error
+	// BorrarNotificaciones quita los avisos de esos tipos para que puedan
+	// volver a enviarse.
+	BorrarNotificaciones(ctx context.Context, pedidoID int, tipos []string) error
+
+	// Métodos para camiones y rutas
+	GetCamiones(ctx context.Context) ([]*Camion, error)
+	CrearCamion(ctx context.Context, camion *Camion) error
+	// CrearRuta guarda la ruta con sus paradas y pasa los pedidos incluidos al
+	// estado indicado, todo en una misma transacción.
+	CrearRuta(ctx context.Context, ruta *Ruta, paradas []*RutaParada, estadoPedidos string) error
+	GetRutasPorFecha(ctx context.Context, fecha time.Time) ([]*Ruta, error)
+	GetParadasRuta(ctx context.Context, rutaID int) ([]*RutaParada, error)
+	GetRuta(ctx context.Context, id int) (*Ruta, error)
+	GetRutaCamion(ctx context.Context, camionID int, fecha time.Time) (*Ruta, error)
+	GetParada(ctx context.Context, id int) (*RutaParada, error)
+	// GetParadaPorPedido regresa la parada más reciente del pedido, o nil si
+	// nunca se incluyó en una ruta.
+	GetParadaPorPedido(ctx context.Context, pedidoID int) (*RutaParada, error)
+	ActualizarEstadoParada(ctx context.Context, paradaID int, estado string) error
+	ActualizarETAParada(ctx context.Context, paradaID int, eta time.Time) error
+	RegistrarPosicion(ctx context.Context, posicion *PosicionRuta) error
+	GetUltimaPosicion(ctx context.Context, rutaID int) (*PosicionRuta, error)
+
+	// Métodos para esperas en el domicilio
+	CrearEspera(ctx context.Context, espera *EsperaEntrega) error
+	GetEsperaActiva(ctx context.Context, pedidoID int) (*EsperaEntrega, error)
+	GetEsperasActivas(ctx context.Context) ([]*EsperaEntrega, error)
+	ActualizarEspera(ctx context.Context, espera *EsperaEntrega) error
+
+	// Métodos para repartidores y entregas
+	GetRepartidorPorTelefono(ctx context.Context, telefono string) (*Repartidor, error)
+	GetRepartidor(ctx context.Context, id int) (*Repartidor, error)
+	GetRepartidores(ctx context.Context) ([]*Repartidor, error)
+	CrearRepartidor(ctx context.Context, repartidor *Repartidor) error
+	CrearEntrega(ctx context.Context, entrega *Entrega) error
+	GetEntregasRuta(ctx context.Context, rutaID int) ([]*Entrega, error)
+
+	// Métodos para strikes, apelaciones y auditoría de clientes
+	CrearStrike(ctx context.Context, strike *Strike) error
+	GetStrikesCliente(ctx context.Context, clienteID int) ([]*Strike, error)
+	AnularStrike(ctx context.Context, strikeID int) error
+	CrearApelacion(ctx context.Context, apelacion *Apelacion) error
+	GetApelacion(ctx context.Context, id int) (*Apelacion, error)
+	GetApelaciones(ctx context.Context, estado string) ([]*Apelacion, error)
+	ActualizarApelacion(ctx context.Context, apelacion *Apelacion) error
+	RegistrarAuditoria(ctx context.Context, registro *AuditoriaCliente) error
+	GetAuditoriaCliente(ctx context.Context, clienteID int) ([]*AuditoriaCliente, error)
+
+	// Métodos para puntos de lealtad
+	RegistrarMovimientoPuntos(ctx context.Context, mov *MovimientoPuntos) error
+	GetSaldoPuntos(ctx context.Context, clienteID int) (int, error)
+	// GetMovimientosPuntos regresa los más recientes primero; limite 0 = todos.
+	GetMovimientosPuntos(ctx context.Context, clienteID int, limite int) ([]*MovimientoPuntos, error)
+	GetMovimientosPuntosPedido(ctx context.Context, pedidoID int) ([]*MovimientoPuntos, error)
+
+	// Métodos para promociones
+	CrearPromocion(ctx context.Context, promo *Promocion) error
+	ActualizarPromocion(ctx context.Context, promo *Promocion) error
+	GetPromocionPorCodigo(ctx context.Context, codigo string) (*Promocion, error)
+	GetPromociones(ctx context.Context) ([]*Promocion, error)
+	RegistrarUsoPromocion(ctx context.Context, uso *UsoPromocion) error
+	// ContarUsosPromocion cuenta los usos del cliente sin contar pedidos cancelados.
+	ContarUsosPromocion(ctx context.Context, promocionID, clienteID int) (int, error)
+
+	// Métodos para referidos
+	GetCodigoReferido(ctx context.Context, clienteID int) (string, error)
+	CrearCodigoReferido(ctx context.Context, clienteID int, codigo string) error
+	// GetClienteIDPorCodigoReferido regresa 0 si el código no existe.
+	GetClienteIDPorCodigoReferido(ctx context.Context, codigo string) (int, error)
+	CrearReferido(ctx context.Context, referido *Referido) error
+	GetReferidoPorCliente(ctx context.Context, referidoID int) (*Referido, error)
+	GetReferidosDe(ctx context.Context, referenteID int) ([]*Referido, error)
+	ActualizarReferido(ctx context.Context, referido *Referido) error
+
+	// Métodos para pagos
+	CrearPago(ctx context.Context, pago *Pago) error
+	ActualizarPago(ctx context.Context, pago *Pago) error
+	GetPagoPorPedido(ctx context.Context, pedidoID int) (*Pago, error)
+	GetPagosPorEstado(ctx context.Context, metodo, estado string) ([]*Pago, error)
+	GetPagoPorReferencia(ctx context.Context, metodo, referencia string) (*Pago, error)
+	CrearComprobantePago(ctx context.Context, comprobante *ComprobantePago) error
+	ActualizarComprobantePago(ctx context.Context, comprobante *ComprobantePago) error
+	GetComprobantePago(ctx context.Context, id int) (*ComprobantePago, error)
+	// GetComprobantesPago filtra por estado; vacío = todos. Los más antiguos primero.
+	GetComprobantesPago(ctx context.Context, estado string) ([]*ComprobantePago, error)
+
+	// Métodos para facturación
+	GetDatosFiscales(ctx context.Context, clienteID int) (*DatosFiscales, error)
+	// GuardarDatosFiscales crea o reemplaza los datos fiscales del cliente.
+	GuardarDatosFiscales(ctx context.Context, datos *DatosFiscales) error
+	CrearFactura(ctx context.Context, factura *Factura) error
+	GetFacturaPorPedido(ctx context.Context, pedidoID int) (*Factura, error)
+	GetFacturaPorToken(ctx context.Context, token string) (*Factura, error)
+
+	// Métodos para liquidaciones de efectivo
+	CrearLiquidacion(ctx context.Context, liquidacion *Liquidacion) error
+	GetLiquidacionRuta(ctx context.Context, rutaID int) (*Liquidacion, error)
+
 	// Métodos para ReporteSello
 	CrearReporteSello(ctx context.Context, reporte *ReporteSello) error
+	GetReporteSello(ctx context.Context, id int) (*ReporteSello, error)
+	// GetUltimoReporteSello regresa el reporte más reciente del cliente.
+	GetUltimoReporteSello(ctx context.Context, clienteID int) (*ReporteSello, error)
+	// GetReportesSelloPorEstado regresa todos los reportes si estado es "".
+	GetReportesSelloPorEstado(ctx context.Context, estado string) ([]*ReporteSello, error)
+	// GetReportesSello regresa los reportes hechos entre desde (incluido) y hasta.
+	GetReportesSello(ctx context.Context, desde, hasta time.Time) ([]*ReporteSello, error)
+	ActualizarReporteSello(ctx context.Context, reporte *ReporteSello) error
+	AgregarFotoReporteSello(ctx context.Context, foto *FotoReporteSello) error
+	GetFotoReporteSello(ctx context.Context, id int) (*FotoReporteSello, error)
+	GetFotosReporteSello(ctx context.Context, reporteID int) ([]*FotoReporteSello, error)
+	GetTanquesPedido(ctx context.Context, pedidoID int) ([]*Tanque, error)
+
+	// Métodos para alertas a repartidores y punto de venta
+	CrearAlerta(ctx context.Context, alerta *Alerta) error
+	GetAlerta(ctx context.Context, id int) (*Alerta, error)
+	// GetAlertasPorEstado regresa todas las alertas si estado es "".
+	GetAlertasPorEstado(ctx context.Context, estado string) ([]*Alerta, error)
+	ActualizarAlerta(ctx context.Context, alerta *Alerta) error
+
+	// Métodos para calificaciones del servicio
+	CrearCalificacion(ctx context.Context, calificacion *Calificacion) error
+	GetCalificacionPedido(ctx context.Context, pedidoID int) (*Calificacion, error)
+	// GetCalificaciones regresa las calificaciones hechas entre desde (incluido) y hasta.
+	GetCalificaciones(ctx context.Context, desde, hasta time.Time) ([]*Calificacion, error)
+	ActualizarCalificacion(ctx context.Context, calificacion *Calificacion) error
+
+	// Métodos para confirmaciones de entrega
+	CrearConfirmacionEntrega(ctx context.Context, confirmacion *ConfirmacionEntrega) error
+	GetConfirmacionEntrega(ctx context.Context, id int) (*ConfirmacionEntrega, error)
+	// GetConfirmacionEntregaPendiente regresa la confirmación pendiente más reciente del cliente.
+	GetConfirmacionEntregaPendiente(ctx context.Context, clienteID int) (*ConfirmacionEntrega, error)
+	// GetConfirmacionEntregaConfirmada regresa la última entrega que el cliente confirmó con "sí".
+	GetConfirmacionEntregaConfirmada(ctx context.Context, clienteID int) (*ConfirmacionEntrega, error)
+	// GetConfirmacionesEntregaVencidas regresa las pendientes que vencieron antes de ahora.
+	GetConfirmacionesEntregaVencidas(ctx context.Context, ahora time.Time) ([]*ConfirmacionEntrega, error)
+	// GetConfirmacionesEntregaPorEnviar regresa las preguntas que esperan a que el cliente termine otra conversación.
+	GetConfirmacionesEntregaPorEnviar(ctx context.Context) ([]*ConfirmacionEntrega, error)
+	ActualizarConfirmacionEntrega(ctx context.Context, confirmacion *ConfirmacionEntrega) error
 
 	// Utilidades
 	Ping(ctx context.Context) error
@@ -108,3 +700,10 @@ func NewStore(cfg Config) (Store, error) {
 		return nil, fmt.Errorf("driver no soportado: %s", cfg.Driver)
 	}
 }
+
+// ejecutor es lo que comparten *sql.DB y *sql.Tx, para que un mismo insert
+// pueda correr dentro o fuera de una transacción.
+type ejecutor interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
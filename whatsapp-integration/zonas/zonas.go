@@ -0,0 +1,123 @@
+package zonas
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Zona es un área de reparto definida por el operador. Cada polígono es una
+// lista de anillos: el primero es el contorno y los siguientes son huecos.
+// Los puntos van como [lng, lat], igual que en GeoJSON.
+type Zona struct {
+	Nombre    string
+	Poligonos [][][][2]float64
+}
+
+// Contiene indica si el punto cae dentro de la zona.
+func (z *Zona) Contiene(lat, lng float64) bool {
+	for _, poligono := range z.Poligonos {
+		if len(poligono) == 0 || !dentroDeAnillo(poligono[0], lat, lng) {
+			continue
+		}
+		enHueco := false
+		for _, hueco := range poligono[1:] {
+			if dentroDeAnillo(hueco, lat, lng) {
+				enHueco = true
+				break
+			}
+		}
+		if !enHueco {
+			return true
+		}
+	}
+	return false
+}
+
+// dentroDeAnillo aplica el algoritmo de cruce de rayos.
+func dentroDeAnillo(anillo [][2]float64, lat, lng float64) bool {
+	dentro := false
+	for i, j := 0, len(anillo)-1; i < len(anillo); j, i = i, i+1 {
+		xi, yi := anillo[i][0], anillo[i][1]
+		xj, yj := anillo[j][0], anillo[j][1]
+		if (yi > lat) != (yj > lat) && lng < (xj-xi)*(lat-yi)/(yj-yi)+xi {
+			dentro = !dentro
+		}
+	}
+	return dentro
+}
+
+// Mapa es el conjunto de zonas de reparto.
+type Mapa struct {
+	zonas []*Zona
+}
+
+// Zona regresa el nombre de la primera zona que contiene el punto, o "" si el
+// punto no cae en ninguna.
+func (m *Mapa) Zona(lat, lng float64) string {
+	if m == nil {
+		return ""
+	}
+	for _, z := range m.zonas {
+		if z.Contiene(lat, lng) {
+			return z.Nombre
+		}
+	}
+	return ""
+}
+
+// Zonas regresa las zonas cargadas en el orden del archivo.
+func (m *Mapa) Zonas() []*Zona {
+	return m.zonas
+}
+
+// CargarGeoJSON lee un FeatureCollection con polígonos (Polygon o MultiPolygon).
+// El nombre de cada zona se toma de la propiedad "nombre" o, si falta, "name".
+func CargarGeoJSON(ruta string) (*Mapa, error) {
+	data, err := os.ReadFile(ruta)
+	if err != nil {
+		return nil, fmt.Errorf("error leyendo zonas: %w", err)
+	}
+
+	var coleccion struct {
+		Features []struct {
+			Properties map[string]interface{} `json:"properties"`
+			Geometry   struct {
+				Type        string          `json:"type"`
+				Coordinates json.RawMessage `json:"coordinates"`
+			} `json:"geometry"`
+		} `json:"features"`
+	}
+	if err := json.Unmarshal(data, &coleccion); err != nil {
+		return nil, fmt.Errorf("error decodificando zonas: %w", err)
+	}
+
+	mapa := &Mapa{}
+	for i, f := range coleccion.Features {
+		nombre, _ := f.Properties["nombre"].(string)
+		if nombre == "" {
+			nombre, _ = f.Properties["name"].(string)
+		}
+		if nombre == "" {
+			return nil, fmt.Errorf("la zona %d no tiene nombre", i+1)
+		}
+
+		zona := &Zona{Nombre: nombre}
+		switch f.Geometry.Type {
+		case "Polygon":
+			var poligono [][][2]float64
+			if err := json.Unmarshal(f.Geometry.Coordinates, &poligono); err != nil {
+				return nil, fmt.Errorf("coordenadas inválidas en la zona %s: %w", nombre, err)
+			}
+			zona.Poligonos = [][][][2]float64{poligono}
+		case "MultiPolygon":
+			if err := json.Unmarshal(f.Geometry.Coordinates, &zona.Poligonos); err != nil {
+				return nil, fmt.Errorf("coordenadas inválidas en la zona %s: %w", nombre, err)
+			}
+		default:
+			return nil, fmt.Errorf("geometría no soportada en la zona %s: %s", nombre, f.Geometry.Type)
+		}
+		mapa.zonas = append(mapa.zonas, zona)
+	}
+	return mapa, nil
+}